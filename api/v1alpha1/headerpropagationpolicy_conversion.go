@@ -0,0 +1,297 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"encoding/json"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/bgruszka/contextforge/api/v1beta1"
+)
+
+// ConversionDataAnnotation holds the v1beta1 fields v1alpha1 can't represent
+// while a policy is read through v1alpha1, so writing it back doesn't drop
+// them.
+const ConversionDataAnnotation = "ctxforge.io/conversion-data"
+
+// hubData is the v1beta1-only configuration of a policy.
+type hubData struct {
+	Rules         []ruleData             `json:"rules,omitempty"`
+	Headers       []headerData           `json:"headers,omitempty"`
+	ResponseRules []v1beta1.ResponseRule `json:"responseRules,omitempty"`
+	Destinations  *v1beta1.Destinations  `json:"destinations,omitempty"`
+	Sampling      *v1beta1.Sampling      `json:"sampling,omitempty"`
+	Sidecar       *v1beta1.SidecarConfig `json:"sidecar,omitempty"`
+	RouteSelector *metav1.LabelSelector  `json:"routeSelector,omitempty"`
+	Mode          string                 `json:"mode,omitempty"`
+}
+
+// ruleData is the v1beta1-only configuration of one propagation rule,
+// identified by its position in the spec.
+type ruleData struct {
+	Rule         int      `json:"rule"`
+	ExcludePaths []string `json:"excludePaths,omitempty"`
+}
+
+// headerData is the v1beta1-only configuration of one header, identified by
+// its position in the spec and its name.
+type headerData struct {
+	Rule        int                      `json:"rule"`
+	Header      int                      `json:"header"`
+	Name        string                   `json:"name"`
+	Rename      string                   `json:"rename,omitempty"`
+	StaticValue string                   `json:"staticValue,omitempty"`
+	Transform   *v1beta1.HeaderTransform `json:"transform,omitempty"`
+	OnExisting  string                   `json:"onExisting,omitempty"`
+}
+
+// ConvertTo converts this HeaderPropagationPolicy to the hub version (v1beta1).
+func (src *HeaderPropagationPolicy) ConvertTo(dstRaw conversion.Hub) error {
+	dst, ok := dstRaw.(*v1beta1.HeaderPropagationPolicy)
+	if !ok {
+		return fmt.Errorf("expected a v1beta1 HeaderPropagationPolicy but got %T", dstRaw)
+	}
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	dst.Spec = convertSpecTo(src.Spec)
+	dst.Status = convertStatusTo(src.Status)
+	return restoreHubData(&dst.ObjectMeta, &dst.Spec)
+}
+
+// ConvertFrom converts from the hub version (v1beta1) to this version.
+func (dst *HeaderPropagationPolicy) ConvertFrom(srcRaw conversion.Hub) error {
+	src, ok := srcRaw.(*v1beta1.HeaderPropagationPolicy)
+	if !ok {
+		return fmt.Errorf("expected a v1beta1 HeaderPropagationPolicy but got %T", srcRaw)
+	}
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	dst.Spec = convertSpecFrom(src.Spec)
+	dst.Status = convertStatusFrom(src.Status)
+	return saveHubData(&dst.ObjectMeta, src.Spec)
+}
+
+// ConvertTo converts this ClusterHeaderPropagationPolicy to the hub version (v1beta1).
+func (src *ClusterHeaderPropagationPolicy) ConvertTo(dstRaw conversion.Hub) error {
+	dst, ok := dstRaw.(*v1beta1.ClusterHeaderPropagationPolicy)
+	if !ok {
+		return fmt.Errorf("expected a v1beta1 ClusterHeaderPropagationPolicy but got %T", dstRaw)
+	}
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	dst.Spec = convertSpecTo(src.Spec)
+	dst.Status = convertStatusTo(src.Status)
+	return restoreHubData(&dst.ObjectMeta, &dst.Spec)
+}
+
+// ConvertFrom converts from the hub version (v1beta1) to this version.
+func (dst *ClusterHeaderPropagationPolicy) ConvertFrom(srcRaw conversion.Hub) error {
+	src, ok := srcRaw.(*v1beta1.ClusterHeaderPropagationPolicy)
+	if !ok {
+		return fmt.Errorf("expected a v1beta1 ClusterHeaderPropagationPolicy but got %T", srcRaw)
+	}
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	dst.Spec = convertSpecFrom(src.Spec)
+	dst.Status = convertStatusFrom(src.Status)
+	return saveHubData(&dst.ObjectMeta, src.Spec)
+}
+
+func convertSpecTo(src HeaderPropagationPolicySpec) v1beta1.HeaderPropagationPolicySpec {
+	dst := v1beta1.HeaderPropagationPolicySpec{
+		PodSelector:       src.PodSelector,
+		NamespaceSelector: src.NamespaceSelector,
+		Priority:          src.Priority,
+		WorkloadSelector:  src.WorkloadSelector,
+		RestartOnChange:   src.RestartOnChange,
+	}
+	if src.PropagationRules != nil {
+		dst.PropagationRules = make([]v1beta1.PropagationRule, len(src.PropagationRules))
+	}
+	for i, rule := range src.PropagationRules {
+		dst.PropagationRules[i] = v1beta1.PropagationRule{PathRegex: rule.PathRegex, Methods: rule.Methods}
+		if rule.Headers != nil {
+			dst.PropagationRules[i].Headers = make([]v1beta1.HeaderConfig, len(rule.Headers))
+		}
+		for j, header := range rule.Headers {
+			dst.PropagationRules[i].Headers[j] = v1beta1.HeaderConfig{
+				Name:          header.Name,
+				Generate:      header.Generate,
+				GeneratorType: header.GeneratorType,
+				Propagate:     header.Propagate,
+			}
+		}
+	}
+	return dst
+}
+
+func convertSpecFrom(src v1beta1.HeaderPropagationPolicySpec) HeaderPropagationPolicySpec {
+	dst := HeaderPropagationPolicySpec{
+		PodSelector:       src.PodSelector,
+		NamespaceSelector: src.NamespaceSelector,
+		Priority:          src.Priority,
+		WorkloadSelector:  src.WorkloadSelector,
+		RestartOnChange:   src.RestartOnChange,
+	}
+	if src.PropagationRules != nil {
+		dst.PropagationRules = make([]PropagationRule, len(src.PropagationRules))
+	}
+	for i, rule := range src.PropagationRules {
+		dst.PropagationRules[i] = PropagationRule{PathRegex: rule.PathRegex, Methods: rule.Methods}
+		if rule.Headers != nil {
+			dst.PropagationRules[i].Headers = make([]HeaderConfig, len(rule.Headers))
+		}
+		for j, header := range rule.Headers {
+			dst.PropagationRules[i].Headers[j] = HeaderConfig{
+				Name:          header.Name,
+				Generate:      header.Generate,
+				GeneratorType: header.GeneratorType,
+				Propagate:     header.Propagate,
+			}
+		}
+	}
+	return dst
+}
+
+func convertStatusTo(src HeaderPropagationPolicyStatus) v1beta1.HeaderPropagationPolicyStatus {
+	dst := v1beta1.HeaderPropagationPolicyStatus{
+		Conditions:           src.Conditions,
+		ObservedGeneration:   src.ObservedGeneration,
+		AppliedToPods:        src.AppliedToPods,
+		AnnotatedPods:        src.AnnotatedPods,
+		MatchedPods:          src.MatchedPods,
+		MatchedPodsTruncated: src.MatchedPodsTruncated,
+		RolloutRevision:      src.RolloutRevision,
+	}
+	if src.Namespaces != nil {
+		dst.Namespaces = make([]v1beta1.NamespacePolicyStatus, len(src.Namespaces))
+	}
+	for i, ns := range src.Namespaces {
+		dst.Namespaces[i] = v1beta1.NamespacePolicyStatus(ns)
+	}
+	return dst
+}
+
+func convertStatusFrom(src v1beta1.HeaderPropagationPolicyStatus) HeaderPropagationPolicyStatus {
+	dst := HeaderPropagationPolicyStatus{
+		Conditions:           src.Conditions,
+		ObservedGeneration:   src.ObservedGeneration,
+		AppliedToPods:        src.AppliedToPods,
+		AnnotatedPods:        src.AnnotatedPods,
+		MatchedPods:          src.MatchedPods,
+		MatchedPodsTruncated: src.MatchedPodsTruncated,
+		RolloutRevision:      src.RolloutRevision,
+	}
+	if src.Namespaces != nil {
+		dst.Namespaces = make([]NamespacePolicyStatus, len(src.Namespaces))
+	}
+	for i, ns := range src.Namespaces {
+		dst.Namespaces[i] = NamespacePolicyStatus(ns)
+	}
+	return dst
+}
+
+// saveHubData records the v1beta1-only fields of spec in the
+// ConversionDataAnnotation of meta.
+func saveHubData(meta *metav1.ObjectMeta, spec v1beta1.HeaderPropagationPolicySpec) error {
+	data := hubData{
+		ResponseRules: spec.ResponseRules,
+		Destinations:  spec.Destinations,
+		Sampling:      spec.Sampling,
+		Sidecar:       spec.Sidecar,
+		RouteSelector: spec.RouteSelector,
+		Mode:          spec.Mode,
+	}
+	for i, rule := range spec.PropagationRules {
+		if len(rule.ExcludePaths) > 0 {
+			data.Rules = append(data.Rules, ruleData{Rule: i, ExcludePaths: rule.ExcludePaths})
+		}
+		for j, header := range rule.Headers {
+			// The API server defaults onExisting again when the policy is
+			// written back
+			onExisting := header.OnExisting
+			if onExisting == "preserve" {
+				onExisting = ""
+			}
+			if header.Rename == "" && header.StaticValue == "" && header.Transform == nil && onExisting == "" {
+				continue
+			}
+			data.Headers = append(data.Headers, headerData{
+				Rule: i, Header: j, Name: header.Name,
+				Rename: header.Rename, StaticValue: header.StaticValue, Transform: header.Transform,
+				OnExisting: onExisting,
+			})
+		}
+	}
+	if len(data.Rules) == 0 && len(data.Headers) == 0 && len(data.ResponseRules) == 0 &&
+		data.Destinations == nil && data.Sampling == nil && data.Sidecar == nil && data.RouteSelector == nil &&
+		data.Mode == "" {
+		return nil
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if meta.Annotations == nil {
+		meta.Annotations = make(map[string]string)
+	}
+	meta.Annotations[ConversionDataAnnotation] = string(raw)
+	return nil
+}
+
+// restoreHubData moves the fields saved by saveHubData back into spec. Fields
+// of headers that were removed or reordered since are dropped; those of
+// rules follow their position.
+func restoreHubData(meta *metav1.ObjectMeta, spec *v1beta1.HeaderPropagationPolicySpec) error {
+	raw, ok := meta.Annotations[ConversionDataAnnotation]
+	if !ok {
+		return nil
+	}
+	delete(meta.Annotations, ConversionDataAnnotation)
+	if len(meta.Annotations) == 0 {
+		meta.Annotations = nil
+	}
+	var data hubData
+	if err := json.Unmarshal([]byte(raw), &data); err != nil {
+		return fmt.Errorf("invalid %s annotation: %w", ConversionDataAnnotation, err)
+	}
+	spec.ResponseRules = data.ResponseRules
+	spec.Destinations = data.Destinations
+	spec.Sampling = data.Sampling
+	spec.Sidecar = data.Sidecar
+	spec.RouteSelector = data.RouteSelector
+	spec.Mode = data.Mode
+	for _, d := range data.Rules {
+		if d.Rule >= 0 && d.Rule < len(spec.PropagationRules) {
+			spec.PropagationRules[d.Rule].ExcludePaths = d.ExcludePaths
+		}
+	}
+	for _, d := range data.Headers {
+		if d.Rule < 0 || d.Rule >= len(spec.PropagationRules) ||
+			d.Header < 0 || d.Header >= len(spec.PropagationRules[d.Rule].Headers) {
+			continue
+		}
+		header := &spec.PropagationRules[d.Rule].Headers[d.Header]
+		if header.Name != d.Name {
+			continue
+		}
+		header.Rename = d.Rename
+		header.StaticValue = d.StaticValue
+		header.Transform = d.Transform
+		header.OnExisting = d.OnExisting
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	"github.com/bgruszka/contextforge/api/v1beta1"
+)
+
+// fullSpec sets every field of the spec, so a field missing from the
+// conversion fails the round trip.
+func fullSpec() HeaderPropagationPolicySpec {
+	return HeaderPropagationPolicySpec{
+		PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}},
+		NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "payments"}},
+		Priority:          10,
+		PropagationRules: []PropagationRule{
+			{
+				Headers: []HeaderConfig{
+					{Name: "x-request-id", Generate: true, GeneratorType: "uuid", Propagate: ptr.To(true)},
+					{Name: "x-debug", Propagate: ptr.To(false)},
+				},
+				PathRegex: "^/api/.*",
+				Methods:   []string{"GET", "POST"},
+			},
+			{Headers: []HeaderConfig{{Name: "x-tenant-id"}}},
+		},
+		WorkloadSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}},
+		RestartOnChange:  true,
+	}
+}
+
+func fullStatus() HeaderPropagationPolicyStatus {
+	return HeaderPropagationPolicyStatus{
+		Conditions: []metav1.Condition{{
+			Type: "Ready", Status: metav1.ConditionTrue, Reason: "Reconciled", Message: "applied to 2 pods",
+			ObservedGeneration: 3, LastTransitionTime: metav1.Unix(1700000000, 0),
+		}},
+		ObservedGeneration:   3,
+		AppliedToPods:        2,
+		AnnotatedPods:        1,
+		MatchedPods:          []string{"api-0", "api-1"},
+		MatchedPodsTruncated: true,
+		Namespaces:           []NamespacePolicyStatus{{Namespace: "default", AppliedToPods: 2, PendingPods: 1}},
+		RolloutRevision:      "0123456789abcdef",
+	}
+}
+
+func TestHeaderPropagationPolicy_RoundTrip(t *testing.T) {
+	original := &HeaderPropagationPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "tracing", Namespace: "default", Labels: map[string]string{"a": "b"}},
+		Spec:       fullSpec(),
+		Status:     fullStatus(),
+	}
+
+	hub := &v1beta1.HeaderPropagationPolicy{}
+	require.NoError(t, original.DeepCopy().ConvertTo(hub))
+	assert.Equal(t, original.ObjectMeta, hub.ObjectMeta)
+	assert.Equal(t, "x-request-id", hub.Spec.PropagationRules[0].Headers[0].Name)
+	assert.Equal(t, []string{"GET", "POST"}, hub.Spec.PropagationRules[0].Methods)
+
+	converted := &HeaderPropagationPolicy{}
+	require.NoError(t, converted.ConvertFrom(hub))
+	assert.Equal(t, original, converted)
+
+	// Converting the hub down and back up must not lose anything either
+	roundTripped := &v1beta1.HeaderPropagationPolicy{}
+	require.NoError(t, converted.ConvertTo(roundTripped))
+	assert.Equal(t, hub, roundTripped)
+}
+
+func TestClusterHeaderPropagationPolicy_RoundTrip(t *testing.T) {
+	original := &ClusterHeaderPropagationPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "tracing"},
+		Spec:       fullSpec(),
+		Status:     fullStatus(),
+	}
+
+	hub := &v1beta1.ClusterHeaderPropagationPolicy{}
+	require.NoError(t, original.DeepCopy().ConvertTo(hub))
+
+	converted := &ClusterHeaderPropagationPolicy{}
+	require.NoError(t, converted.ConvertFrom(hub))
+	assert.Equal(t, original, converted)
+}
+
+func TestConversion_EmptySpec(t *testing.T) {
+	original := &HeaderPropagationPolicy{ObjectMeta: metav1.ObjectMeta{Name: "empty", Namespace: "default"}}
+
+	hub := &v1beta1.HeaderPropagationPolicy{}
+	require.NoError(t, original.ConvertTo(hub))
+	assert.Nil(t, hub.Spec.PropagationRules)
+	assert.Nil(t, hub.Status.Namespaces)
+
+	converted := &HeaderPropagationPolicy{}
+	require.NoError(t, converted.ConvertFrom(hub))
+	assert.Equal(t, original, converted)
+}
+
+func TestConversion_WrongHub(t *testing.T) {
+	assert.Error(t, (&HeaderPropagationPolicy{}).ConvertTo(&v1beta1.ClusterHeaderPropagationPolicy{}))
+	assert.Error(t, (&ClusterHeaderPropagationPolicy{}).ConvertFrom(&v1beta1.HeaderPropagationPolicy{}))
+}
+
+func TestConversion_PreservesHubOnlyRuleFields(t *testing.T) {
+	hub := &v1beta1.ClusterHeaderPropagationPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "platform", Annotations: map[string]string{"team": "platform"}},
+		Spec: v1beta1.HeaderPropagationPolicySpec{
+			PropagationRules: []v1beta1.PropagationRule{{
+				Headers:      []v1beta1.HeaderConfig{{Name: "x-request-id"}},
+				ExcludePaths: []string{"^/healthz$", "^/metrics$"},
+			}},
+			ResponseRules: []v1beta1.ResponseRule{
+				{Echo: []string{"x-request-id"}, Strip: []string{"server"}, PathRegex: "^/api/"},
+			},
+			Destinations: &v1beta1.Destinations{Allow: []string{"*.svc.cluster.local"}},
+			Sampling:     &v1beta1.Sampling{Percentage: 10, KeyHeader: "x-request-id"},
+			Sidecar: &v1beta1.SidecarConfig{Image: "ghcr.io/bgruszka/contextforge-proxy:0.2.0", Resources: &corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("512Mi")},
+			}},
+			RouteSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"ctxforge.io/gateway": "true"}},
+			Mode:          v1beta1.PolicyModeAudit,
+		},
+	}
+
+	spoke := &ClusterHeaderPropagationPolicy{}
+	require.NoError(t, spoke.ConvertFrom(hub))
+	roundTripped := &v1beta1.ClusterHeaderPropagationPolicy{}
+	require.NoError(t, spoke.ConvertTo(roundTripped))
+	assert.Equal(t, hub, roundTripped)
+}
+
+func TestConversion_PreservesHubOnlyHeaderFields(t *testing.T) {
+	hub := &v1beta1.HeaderPropagationPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "tracing", Namespace: "default"},
+		Spec: v1beta1.HeaderPropagationPolicySpec{
+			PropagationRules: []v1beta1.PropagationRule{{
+				Headers: []v1beta1.HeaderConfig{
+					{Name: "x-request-id"},
+					{Name: "x-user-email", Transform: &v1beta1.HeaderTransform{Type: "hash", Length: 16}},
+					{Name: "x-tenant", Rename: "x-tenant-id", StaticValue: "default", OnExisting: "override"},
+				},
+			}},
+		},
+	}
+
+	spoke := &HeaderPropagationPolicy{}
+	require.NoError(t, spoke.ConvertFrom(hub))
+	assert.Contains(t, spoke.Annotations, ConversionDataAnnotation)
+	assert.Nil(t, hub.Annotations, "the hub object is not modified")
+
+	roundTripped := &v1beta1.HeaderPropagationPolicy{}
+	require.NoError(t, spoke.ConvertTo(roundTripped))
+	assert.Equal(t, hub, roundTripped)
+
+	// Fields of a header renamed through v1alpha1 don't carry over to another header
+	spoke.Spec.PropagationRules[0].Headers[1].Name = "x-user-id"
+	require.NoError(t, spoke.ConvertTo(roundTripped))
+	assert.Nil(t, roundTripped.Spec.PropagationRules[0].Headers[1].Transform)
+	assert.Equal(t, "x-tenant-id", roundTripped.Spec.PropagationRules[0].Headers[2].Rename)
+}
+
+func TestConversion_DefaultOnExistingIsNotSaved(t *testing.T) {
+	hub := &v1beta1.HeaderPropagationPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "tracing", Namespace: "default"},
+		Spec: v1beta1.HeaderPropagationPolicySpec{
+			PropagationRules: []v1beta1.PropagationRule{{
+				Headers: []v1beta1.HeaderConfig{{Name: "x-request-id", OnExisting: "preserve"}},
+			}},
+		},
+	}
+
+	spoke := &HeaderPropagationPolicy{}
+	require.NoError(t, spoke.ConvertFrom(hub))
+	assert.NotContains(t, spoke.Annotations, ConversionDataAnnotation)
+}
@@ -62,9 +62,37 @@ type HeaderPropagationPolicySpec struct {
 	// +optional
 	PodSelector *metav1.LabelSelector `json:"podSelector,omitempty"`
 
+	// NamespaceSelector selects the namespaces whose pods a
+	// ClusterHeaderPropagationPolicy applies to; when empty it applies in all
+	// namespaces. A HeaderPropagationPolicy always applies to its own namespace
+	// and ignores this field.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// Priority orders the policies selecting the same pod. When several of
+	// them configure a header, the policy with the highest priority wins;
+	// ties go to a HeaderPropagationPolicy over a
+	// ClusterHeaderPropagationPolicy, then to the lowest name.
+	// +optional
+	Priority int32 `json:"priority,omitempty"`
+
 	// PropagationRules defines the header propagation rules
 	// +kubebuilder:validation:MinItems=1
 	PropagationRules []PropagationRule `json:"propagationRules"`
+
+	// WorkloadSelector selects the Deployments and StatefulSets, in the
+	// policy's namespace or the namespaces a ClusterHeaderPropagationPolicy
+	// selects, whose pods the policy is rolled out to when RestartOnChange is
+	// set. It is usually the workloads whose pods PodSelector matches.
+	// +optional
+	WorkloadSelector *metav1.LabelSelector `json:"workloadSelector,omitempty"`
+
+	// RestartOnChange triggers a rolling restart of the workloads selected by
+	// WorkloadSelector when the policy's selectors, priority or propagation
+	// rules change, so their sidecars are re-injected with the new
+	// configuration
+	// +optional
+	RestartOnChange bool `json:"restartOnChange,omitempty"`
 }
 
 // HeaderPropagationPolicyStatus defines the observed state of HeaderPropagationPolicy
@@ -82,9 +110,56 @@ type HeaderPropagationPolicyStatus struct {
 	// AppliedToPods is the count of pods this policy is applied to
 	// +optional
 	AppliedToPods int32 `json:"appliedToPods,omitempty"`
+
+	// AnnotatedPods is the count of pods in AppliedToPods whose
+	// ctxforge.io/headers and ctxforge.io/header-rules annotations, written by
+	// the operator, include this policy's rules
+	// +optional
+	AnnotatedPods int32 `json:"annotatedPods,omitempty"`
+
+	// MatchedPods names the pods counted in AppliedToPods, sorted, up to
+	// 50 entries. Pods of a ClusterHeaderPropagationPolicy are listed as
+	// namespace/name.
+	// +kubebuilder:validation:MaxItems=50
+	// +optional
+	MatchedPods []string `json:"matchedPods,omitempty"`
+
+	// MatchedPodsTruncated is set when MatchedPods omits pods because more
+	// than 50 matched
+	// +optional
+	MatchedPodsTruncated bool `json:"matchedPodsTruncated,omitempty"`
+
+	// Namespaces breaks AppliedToPods down by namespace, listing the
+	// namespaces with matching pods that have the proxy sidecar
+	// +listType=map
+	// +listMapKey=namespace
+	// +optional
+	Namespaces []NamespacePolicyStatus `json:"namespaces,omitempty"`
+
+	// RolloutRevision identifies the policy configuration last rolled out to
+	// the workloads selected by WorkloadSelector
+	// +optional
+	RolloutRevision string `json:"rolloutRevision,omitempty"`
+}
+
+// NamespacePolicyStatus is the observed state of a policy in one namespace
+type NamespacePolicyStatus struct {
+	// Namespace is the name of the namespace
+	Namespace string `json:"namespace"`
+
+	// AppliedToPods is the count of running pods in the namespace this policy
+	// is applied to
+	// +optional
+	AppliedToPods int32 `json:"appliedToPods,omitempty"`
+
+	// PendingPods is the count of pending pods in the namespace this policy
+	// will apply to once they start
+	// +optional
+	PendingPods int32 `json:"pendingPods,omitempty"`
 }
 
 // +kubebuilder:object:root=true
+// +kubebuilder:resource:shortName=hpp
 // +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 // +kubebuilder:printcolumn:name="Applied To",type="integer",JSONPath=".status.appliedToPods"
@@ -107,6 +182,34 @@ type HeaderPropagationPolicyList struct {
 	Items           []HeaderPropagationPolicy `json:"items"`
 }
 
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,shortName=chpp
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:printcolumn:name="Applied To",type="integer",JSONPath=".status.appliedToPods"
+
+// ClusterHeaderPropagationPolicy is the Schema for the
+// clusterheaderpropagationpolicies API. It applies the same rules as a
+// HeaderPropagationPolicy to pods in every namespace its NamespaceSelector
+// matches.
+type ClusterHeaderPropagationPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HeaderPropagationPolicySpec   `json:"spec,omitempty"`
+	Status HeaderPropagationPolicyStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ClusterHeaderPropagationPolicyList contains a list of ClusterHeaderPropagationPolicy
+type ClusterHeaderPropagationPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterHeaderPropagationPolicy `json:"items"`
+}
+
 func init() {
 	SchemeBuilder.Register(&HeaderPropagationPolicy{}, &HeaderPropagationPolicyList{})
+	SchemeBuilder.Register(&ClusterHeaderPropagationPolicy{}, &ClusterHeaderPropagationPolicyList{})
 }
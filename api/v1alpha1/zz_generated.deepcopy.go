@@ -25,6 +25,65 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterHeaderPropagationPolicy) DeepCopyInto(out *ClusterHeaderPropagationPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterHeaderPropagationPolicy.
+func (in *ClusterHeaderPropagationPolicy) DeepCopy() *ClusterHeaderPropagationPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterHeaderPropagationPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterHeaderPropagationPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterHeaderPropagationPolicyList) DeepCopyInto(out *ClusterHeaderPropagationPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClusterHeaderPropagationPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterHeaderPropagationPolicyList.
+func (in *ClusterHeaderPropagationPolicyList) DeepCopy() *ClusterHeaderPropagationPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterHeaderPropagationPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterHeaderPropagationPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HeaderConfig) DeepCopyInto(out *HeaderConfig) {
 	*out = *in
@@ -112,6 +171,11 @@ func (in *HeaderPropagationPolicySpec) DeepCopyInto(out *HeaderPropagationPolicy
 		*out = new(v1.LabelSelector)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.PropagationRules != nil {
 		in, out := &in.PropagationRules, &out.PropagationRules
 		*out = make([]PropagationRule, len(*in))
@@ -119,6 +183,11 @@ func (in *HeaderPropagationPolicySpec) DeepCopyInto(out *HeaderPropagationPolicy
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.WorkloadSelector != nil {
+		in, out := &in.WorkloadSelector, &out.WorkloadSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HeaderPropagationPolicySpec.
@@ -141,6 +210,16 @@ func (in *HeaderPropagationPolicyStatus) DeepCopyInto(out *HeaderPropagationPoli
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.MatchedPods != nil {
+		in, out := &in.MatchedPods, &out.MatchedPods
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]NamespacePolicyStatus, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HeaderPropagationPolicyStatus.
@@ -153,6 +232,21 @@ func (in *HeaderPropagationPolicyStatus) DeepCopy() *HeaderPropagationPolicyStat
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespacePolicyStatus) DeepCopyInto(out *NamespacePolicyStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespacePolicyStatus.
+func (in *NamespacePolicyStatus) DeepCopy() *NamespacePolicyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespacePolicyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PropagationRule) DeepCopyInto(out *PropagationRule) {
 	*out = *in
@@ -0,0 +1,507 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// HeaderConfig defines a single header to propagate
+type HeaderConfig struct {
+	// Name is the HTTP header name to propagate
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9-]+$`
+	Name string `json:"name"`
+
+	// Generate indicates whether to auto-generate this header if missing
+	// +optional
+	Generate bool `json:"generate,omitempty"`
+
+	// GeneratorType specifies how to generate the header value (uuid, ulid, timestamp)
+	// +kubebuilder:validation:Enum=uuid;ulid;timestamp
+	// +optional
+	GeneratorType string `json:"generatorType,omitempty"`
+
+	// Propagate indicates whether to propagate this header to outbound requests
+	// +kubebuilder:default=true
+	// +optional
+	Propagate *bool `json:"propagate,omitempty"`
+
+	// Rename propagates the header under a different name, replacing the
+	// original on the forwarded request
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9-]+$`
+	// +optional
+	Rename string `json:"rename,omitempty"`
+
+	// StaticValue is propagated when a request doesn't carry the header. It
+	// can't be combined with Generate.
+	// +optional
+	StaticValue string `json:"staticValue,omitempty"`
+
+	// Transform rewrites the propagated value
+	// +optional
+	Transform *HeaderTransform `json:"transform,omitempty"`
+
+	// OnExisting decides what the generated or static value does when a
+	// request already carries the header: preserve keeps the request's
+	// value, override replaces it and append adds to it, comma-separated
+	// +kubebuilder:validation:Enum=preserve;override;append
+	// +kubebuilder:default=preserve
+	// +optional
+	OnExisting string `json:"onExisting,omitempty"`
+}
+
+// HeaderTransform rewrites a propagated header value
+type HeaderTransform struct {
+	// Type is the rewrite: hash replaces the value with its hex-encoded
+	// SHA-256 digest, truncate keeps its first Length bytes, and lowercase
+	// and uppercase change its case
+	// +kubebuilder:validation:Enum=hash;truncate;lowercase;uppercase
+	Type string `json:"type"`
+
+	// Length is the number of bytes truncate keeps. For hash it optionally
+	// shortens the digest.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Length int32 `json:"length,omitempty"`
+}
+
+// PropagationRule defines a set of headers and conditions for propagation
+type PropagationRule struct {
+	// Headers is the list of headers to propagate with this rule
+	// +kubebuilder:validation:MinItems=1
+	Headers []HeaderConfig `json:"headers"`
+
+	// PathRegex is an optional regex pattern to match request paths
+	// +optional
+	PathRegex string `json:"pathRegex,omitempty"`
+
+	// ExcludePaths lists regex patterns of request paths this rule doesn't
+	// apply to, even when they match PathRegex
+	// +optional
+	ExcludePaths []string `json:"excludePaths,omitempty"`
+
+	// Methods is an optional list of HTTP methods this rule applies to
+	// +optional
+	Methods []string `json:"methods,omitempty"`
+}
+
+// ResponseRule defines headers the sidecar sets on or removes from the
+// responses returned to callers
+type ResponseRule struct {
+	// Echo lists request headers copied into the response, with the value
+	// forwarded to the application, such as a generated request ID
+	// +kubebuilder:validation:items:Pattern=`^[a-zA-Z0-9-]+$`
+	// +optional
+	Echo []string `json:"echo,omitempty"`
+
+	// Strip lists headers removed from the response
+	// +kubebuilder:validation:items:Pattern=`^[a-zA-Z0-9-]+$`
+	// +optional
+	Strip []string `json:"strip,omitempty"`
+
+	// PathRegex is an optional regex pattern to match request paths
+	// +optional
+	PathRegex string `json:"pathRegex,omitempty"`
+
+	// Methods is an optional list of HTTP methods this rule applies to
+	// +optional
+	Methods []string `json:"methods,omitempty"`
+}
+
+// Destinations restricts the hosts, as addressed by a request's Host header,
+// that headers are propagated to. Patterns are a host name, "*.domain" for
+// its subdomains, or "*" for every host.
+type Destinations struct {
+	// Allow lists the hosts headers may be propagated to; when empty every
+	// host that isn't denied is allowed
+	// +kubebuilder:validation:items:Pattern=`^(\*|(\*\.)?[a-zA-Z0-9]([a-zA-Z0-9.-]*[a-zA-Z0-9])?)$`
+	// +optional
+	Allow []string `json:"allow,omitempty"`
+
+	// Deny lists hosts headers are never propagated to, overriding Allow
+	// +kubebuilder:validation:items:Pattern=`^(\*|(\*\.)?[a-zA-Z0-9]([a-zA-Z0-9.-]*[a-zA-Z0-9])?)$`
+	// +optional
+	Deny []string `json:"deny,omitempty"`
+}
+
+// Sampling propagates headers for a share of requests
+type Sampling struct {
+	// Percentage is the share of requests, from 0 to 100, the headers are
+	// propagated for
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Percentage int32 `json:"percentage"`
+
+	// KeyHeader makes the decision deterministic: requests with the same
+	// value of this header, such as a trace ID, are sampled alike by every
+	// sidecar. Requests without it are sampled at random.
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9-]+$`
+	// +optional
+	KeyHeader string `json:"keyHeader,omitempty"`
+}
+
+// SidecarConfig customizes the proxy sidecar injected into the pods a policy
+// selects
+type SidecarConfig struct {
+	// Image replaces the operator's proxy image, to pin or canary a proxy
+	// version. It must be allowed by the operator's proxy image allowlist.
+	// +kubebuilder:validation:Pattern=`^[^\s]+$`
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Resources replaces the proxy's default resource requests and limits
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+// Values of a policy's Mode.
+const (
+	// PolicyModeEnforce applies the policy to the pods it selects.
+	PolicyModeEnforce = "Enforce"
+	// PolicyModeAudit leaves the pods the policy selects unchanged and
+	// reports those it doesn't cover yet.
+	PolicyModeAudit = "Audit"
+)
+
+// HeaderPropagationPolicySpec defines the desired state of HeaderPropagationPolicy
+type HeaderPropagationPolicySpec struct {
+	// PodSelector selects pods to apply this policy to
+	// +optional
+	PodSelector *metav1.LabelSelector `json:"podSelector,omitempty"`
+
+	// NamespaceSelector selects the namespaces whose pods a
+	// ClusterHeaderPropagationPolicy applies to; when empty it applies in all
+	// namespaces. A HeaderPropagationPolicy always applies to its own namespace
+	// and ignores this field.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// Priority orders the policies selecting the same pod. When several of
+	// them configure a header, the policy with the highest priority wins;
+	// ties go to a HeaderPropagationPolicy over a
+	// ClusterHeaderPropagationPolicy, then to the lowest name.
+	// +optional
+	Priority int32 `json:"priority,omitempty"`
+
+	// PropagationRules defines the header propagation rules
+	// +kubebuilder:validation:MinItems=1
+	PropagationRules []PropagationRule `json:"propagationRules"`
+
+	// ResponseRules defines the headers echoed into and stripped from
+	// responses
+	// +optional
+	ResponseRules []ResponseRule `json:"responseRules,omitempty"`
+
+	// Destinations restricts where the headers of this policy's propagation
+	// rules are propagated to
+	// +optional
+	Destinations *Destinations `json:"destinations,omitempty"`
+
+	// Sampling propagates the headers of this policy's propagation rules for
+	// a share of requests only, such as large baggage
+	// +optional
+	Sampling *Sampling `json:"sampling,omitempty"`
+
+	// Sidecar customizes the proxy injected into the pods this policy
+	// selects. When several policies select a pod, the one with the highest
+	// precedence that sets a field wins.
+	// +optional
+	Sidecar *SidecarConfig `json:"sidecar,omitempty"`
+
+	// WorkloadSelector selects the Deployments and StatefulSets, in the
+	// policy's namespace or the namespaces a ClusterHeaderPropagationPolicy
+	// selects, whose pods the policy is rolled out to when RestartOnChange is
+	// set. It is usually the workloads whose pods PodSelector matches.
+	// +optional
+	WorkloadSelector *metav1.LabelSelector `json:"workloadSelector,omitempty"`
+
+	// RestartOnChange triggers a rolling restart of the workloads selected by
+	// WorkloadSelector when the policy's selectors, priority, rules,
+	// destinations, sampling or sidecar change, so their sidecars are
+	// re-injected with the new configuration
+	// +optional
+	RestartOnChange bool `json:"restartOnChange,omitempty"`
+
+	// RouteSelector selects the Gateway API HTTPRoutes, in the policy's
+	// namespace or the namespaces a ClusterHeaderPropagationPolicy selects,
+	// that the policy's static and generated headers are attached to, so
+	// external traffic carries them from the gateway on. It requires the
+	// operator's Gateway API integration.
+	// +optional
+	RouteSelector *metav1.LabelSelector `json:"routeSelector,omitempty"`
+
+	// Mode is Enforce, the default, or Audit. An Audit policy changes
+	// nothing: pods are injected and configured as if it didn't exist, and
+	// the operator reports in Status.Audit which of the running pods it
+	// selects lack the proxy sidecar or the headers of its propagation
+	// rules, to measure coverage before enforcing it.
+	// +kubebuilder:validation:Enum=Enforce;Audit
+	// +optional
+	Mode string `json:"mode,omitempty"`
+}
+
+// HeaderPropagationPolicyStatus defines the observed state of HeaderPropagationPolicy
+type HeaderPropagationPolicyStatus struct {
+	// Conditions represent the current state of the HeaderPropagationPolicy resource
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// ObservedGeneration is the most recent generation observed
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Rules is the count of the policy's propagation rules
+	// +optional
+	Rules int32 `json:"rules,omitempty"`
+
+	// AppliedToPods is the count of pods this policy is applied to
+	// +optional
+	AppliedToPods int32 `json:"appliedToPods,omitempty"`
+
+	// AnnotatedPods is the count of pods in AppliedToPods whose
+	// ctxforge.io/headers and ctxforge.io/header-rules annotations, written by
+	// the operator, include this policy's rules
+	// +optional
+	AnnotatedPods int32 `json:"annotatedPods,omitempty"`
+
+	// AdoptedPods is the count of running pods whose ctxforge-proxy container
+	// was added by hand and that the operator labeled with this policy, as
+	// the one of highest precedence selecting them
+	// +optional
+	AdoptedPods int32 `json:"adoptedPods,omitempty"`
+
+	// MatchedPods names the pods counted in AppliedToPods, sorted, up to
+	// 50 entries. Pods of a ClusterHeaderPropagationPolicy are listed as
+	// namespace/name.
+	// +kubebuilder:validation:MaxItems=50
+	// +optional
+	MatchedPods []string `json:"matchedPods,omitempty"`
+
+	// MatchedPodsTruncated is set when MatchedPods omits pods because more
+	// than 50 matched
+	// +optional
+	MatchedPodsTruncated bool `json:"matchedPodsTruncated,omitempty"`
+
+	// Namespaces breaks AppliedToPods down by namespace, listing the
+	// namespaces with matching pods that have the proxy sidecar
+	// +listType=map
+	// +listMapKey=namespace
+	// +optional
+	Namespaces []NamespacePolicyStatus `json:"namespaces,omitempty"`
+
+	// RuleErrors lists the rules the proxy would reject, such as a pathRegex
+	// that doesn't compile or an unknown generator type, while the policy's
+	// Ready condition is False with reason RuleCompileError
+	// +optional
+	RuleErrors []RuleError `json:"ruleErrors,omitempty"`
+
+	// Stats summarizes the traffic of the policy's sidecars over the last
+	// stats window, scraped from their metrics when the operator runs with
+	// --policy-stats-window
+	// +optional
+	Stats *PropagationStats `json:"stats,omitempty"`
+
+	// RolloutRevision identifies the policy configuration last rolled out to
+	// the workloads selected by WorkloadSelector
+	// +optional
+	RolloutRevision string `json:"rolloutRevision,omitempty"`
+
+	// Audit reports the selected pods the policy doesn't cover yet, while
+	// its Mode is Audit
+	// +optional
+	Audit *AuditStatus `json:"audit,omitempty"`
+}
+
+// AuditStatus is the coverage of an Audit policy
+type AuditStatus struct {
+	// CoveredPods is the count of running selected pods whose proxy sidecar
+	// is configured with all the policy's headers
+	// +optional
+	CoveredPods int32 `json:"coveredPods,omitempty"`
+
+	// MissingSidecarPods is the count of running selected pods without the
+	// proxy sidecar
+	// +optional
+	MissingSidecarPods int32 `json:"missingSidecarPods,omitempty"`
+
+	// MissingHeadersPods is the count of running selected pods whose proxy
+	// sidecar lacks some of the policy's headers
+	// +optional
+	MissingHeadersPods int32 `json:"missingHeadersPods,omitempty"`
+
+	// UncoveredPods lists the pods counted in MissingSidecarPods and
+	// MissingHeadersPods, sorted, up to 50 entries. Pods of a
+	// ClusterHeaderPropagationPolicy are listed as namespace/name.
+	// +kubebuilder:validation:MaxItems=50
+	// +optional
+	UncoveredPods []UncoveredPod `json:"uncoveredPods,omitempty"`
+
+	// UncoveredPodsTruncated is set when UncoveredPods omits pods because
+	// more than 50 are uncovered
+	// +optional
+	UncoveredPodsTruncated bool `json:"uncoveredPodsTruncated,omitempty"`
+}
+
+// UncoveredPod is a pod an Audit policy selects but doesn't cover yet
+type UncoveredPod struct {
+	// Name is the name of the pod
+	Name string `json:"name"`
+
+	// Reason is MissingSidecar or MissingHeaders
+	Reason string `json:"reason"`
+
+	// MissingHeaders are the policy's headers the pod's proxy sidecar isn't
+	// configured with, sorted
+	// +optional
+	MissingHeaders []string `json:"missingHeaders,omitempty"`
+}
+
+// RuleError is a rule of the policy the proxy can't apply
+type RuleError struct {
+	// Rule is the index of the rule in propagationRules, or in responseRules
+	// when Response is set
+	Rule int32 `json:"rule"`
+
+	// Response marks a rule of responseRules
+	// +optional
+	Response bool `json:"response,omitempty"`
+
+	// Message describes the first error of the rule
+	Message string `json:"message"`
+}
+
+// PropagationStats counts what the sidecars a policy applies to did during
+// their last complete stats window
+type PropagationStats struct {
+	// Window is the period the counts cover
+	Window metav1.Duration `json:"window"`
+
+	// LastUpdateTime is when the latest counted window ended
+	LastUpdateTime metav1.Time `json:"lastUpdateTime"`
+
+	// Pods is the count of sidecars whose metrics were counted
+	Pods int32 `json:"pods"`
+
+	// Requests is the count of requests the sidecars received
+	Requests int64 `json:"requests"`
+
+	// Headers breaks the counts down by the headers of the policy's
+	// propagation rules. Counts are per header, so policies sharing a header
+	// on the same pods report the same counts.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Headers []HeaderStats `json:"headers,omitempty"`
+}
+
+// HeaderStats counts what the sidecars did with one header
+type HeaderStats struct {
+	// Name is the header name as written in the policy's rules
+	Name string `json:"name"`
+
+	// Propagated is the count of outgoing requests the header was added to
+	// +optional
+	Propagated int64 `json:"propagated,omitempty"`
+
+	// Generated is the count of values generated for the header because an
+	// incoming request lacked it
+	// +optional
+	Generated int64 `json:"generated,omitempty"`
+}
+
+// NamespacePolicyStatus is the observed state of a policy in one namespace
+type NamespacePolicyStatus struct {
+	// Namespace is the name of the namespace
+	Namespace string `json:"namespace"`
+
+	// AppliedToPods is the count of running pods in the namespace this policy
+	// is applied to
+	// +optional
+	AppliedToPods int32 `json:"appliedToPods,omitempty"`
+
+	// PendingPods is the count of pending pods in the namespace this policy
+	// will apply to once they start
+	// +optional
+	PendingPods int32 `json:"pendingPods,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:shortName=hpp
+// +kubebuilder:storageversion
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+// +kubebuilder:printcolumn:name="Rules",type="integer",JSONPath=".status.rules"
+// +kubebuilder:printcolumn:name="Applied To",type="integer",JSONPath=".status.appliedToPods"
+// +kubebuilder:printcolumn:name="Mode",type="string",JSONPath=".spec.mode",priority=1
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// HeaderPropagationPolicy is the Schema for the headerpropagationpolicies API
+type HeaderPropagationPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HeaderPropagationPolicySpec   `json:"spec,omitempty"`
+	Status HeaderPropagationPolicyStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// HeaderPropagationPolicyList contains a list of HeaderPropagationPolicy
+type HeaderPropagationPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HeaderPropagationPolicy `json:"items"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,shortName=chpp
+// +kubebuilder:storageversion
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+// +kubebuilder:printcolumn:name="Rules",type="integer",JSONPath=".status.rules"
+// +kubebuilder:printcolumn:name="Applied To",type="integer",JSONPath=".status.appliedToPods"
+// +kubebuilder:printcolumn:name="Mode",type="string",JSONPath=".spec.mode",priority=1
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// ClusterHeaderPropagationPolicy is the Schema for the
+// clusterheaderpropagationpolicies API. It applies the same rules as a
+// HeaderPropagationPolicy to pods in every namespace its NamespaceSelector
+// matches.
+type ClusterHeaderPropagationPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HeaderPropagationPolicySpec   `json:"spec,omitempty"`
+	Status HeaderPropagationPolicyStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ClusterHeaderPropagationPolicyList contains a list of ClusterHeaderPropagationPolicy
+type ClusterHeaderPropagationPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterHeaderPropagationPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&HeaderPropagationPolicy{}, &HeaderPropagationPolicyList{})
+	SchemeBuilder.Register(&ClusterHeaderPropagationPolicy{}, &ClusterHeaderPropagationPolicyList{})
+}
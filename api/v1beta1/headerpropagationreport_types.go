@@ -0,0 +1,105 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// HeaderPropagationReportName is the name of the report the operator
+// maintains in each namespace
+const HeaderPropagationReportName = "contextforge"
+
+// ReportSummary totals a HeaderPropagationReport
+type ReportSummary struct {
+	// Workloads is the count of workloads in the report
+	Workloads int32 `json:"workloads"`
+
+	// InjectedPods is the count of pods with the proxy sidecar
+	InjectedPods int32 `json:"injectedPods"`
+
+	// MissingSidecarPods is the count of pods selected by a policy that lack
+	// the proxy sidecar
+	MissingSidecarPods int32 `json:"missingSidecarPods"`
+}
+
+// WorkloadReport is the injection state of a workload's pods
+type WorkloadReport struct {
+	// Kind is the kind of the workload owning the pods, such as Deployment,
+	// or Pod for pods without an owner
+	Kind string `json:"kind"`
+
+	// Name is the name of the workload
+	Name string `json:"name"`
+
+	// Pods is the count of the workload's pods in the report
+	Pods int32 `json:"pods"`
+
+	// InjectedPods is the count of pods with the proxy sidecar
+	// +optional
+	InjectedPods int32 `json:"injectedPods,omitempty"`
+
+	// MissingSidecarPods is the count of pods selected by a policy that lack
+	// the proxy sidecar
+	// +optional
+	MissingSidecarPods int32 `json:"missingSidecarPods,omitempty"`
+
+	// Policies are the policies selecting the pods, sorted.
+	// ClusterHeaderPropagationPolicies are listed as
+	// clusterheaderpropagationpolicy/<name>.
+	// +optional
+	Policies []string `json:"policies,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:shortName=hpr
+// +kubebuilder:printcolumn:name="Workloads",type="integer",JSONPath=".summary.workloads"
+// +kubebuilder:printcolumn:name="Injected",type="integer",JSONPath=".summary.injectedPods"
+// +kubebuilder:printcolumn:name="Missing Sidecar",type="integer",JSONPath=".summary.missingSidecarPods"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// HeaderPropagationReport lists the workloads of a namespace that have the
+// proxy sidecar or are selected by a policy, with the policies covering them.
+// The operator maintains one, named contextforge, in every namespace with
+// such pods; it is read-only for users.
+type HeaderPropagationReport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Summary totals the workloads
+	Summary ReportSummary `json:"summary"`
+
+	// Workloads are sorted by kind and name
+	// +listType=map
+	// +listMapKey=kind
+	// +listMapKey=name
+	// +optional
+	Workloads []WorkloadReport `json:"workloads,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// HeaderPropagationReportList contains a list of HeaderPropagationReport
+type HeaderPropagationReportList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HeaderPropagationReport `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&HeaderPropagationReport{}, &HeaderPropagationReportList{})
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// OperatorHealthName is the name of the OperatorHealth the operator maintains
+const OperatorHealthName = "contextforge"
+
+// OperatorHealthStatus is the result of the operator's self-checks
+type OperatorHealthStatus struct {
+	// Conditions report the health of the operator's components, such as
+	// WebhookHealthy for the injection webhook, and Degraded while features
+	// are disabled for lack of permissions
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// LastSelfCheckTime is when the webhook self-check last ran
+	// +optional
+	LastSelfCheckTime *metav1.Time `json:"lastSelfCheckTime,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Webhook",type="string",JSONPath=".status.conditions[?(@.type==\"WebhookHealthy\")].status"
+// +kubebuilder:printcolumn:name="Reason",type="string",JSONPath=".status.conditions[?(@.type==\"WebhookHealthy\")].reason"
+// +kubebuilder:printcolumn:name="Degraded",type="string",JSONPath=".status.conditions[?(@.type==\"Degraded\")].status"
+// +kubebuilder:printcolumn:name="Last Check",type="date",JSONPath=".status.lastSelfCheckTime"
+
+// OperatorHealth reports the health of the operator itself, so that an
+// injection outage shows up even while no pod is being created. The operator
+// maintains one, named contextforge; it is read-only for users.
+type OperatorHealth struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// +optional
+	Status OperatorHealthStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// OperatorHealthList contains a list of OperatorHealth
+type OperatorHealthList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []OperatorHealth `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&OperatorHealth{}, &OperatorHealthList{})
+}
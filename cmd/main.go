@@ -32,27 +32,48 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"crypto/tls"
+	"errors"
 	"flag"
+	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	ctxforgev1alpha1 "github.com/bgruszka/contextforge/api/v1alpha1"
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
 	"github.com/bgruszka/contextforge/internal/controller"
+	"github.com/bgruszka/contextforge/internal/istio"
+	ctxforgepolicy "github.com/bgruszka/contextforge/internal/policy"
+	"github.com/bgruszka/contextforge/internal/rulestream"
 	webhookv1 "github.com/bgruszka/contextforge/internal/webhook/v1"
+	webhookv1beta1 "github.com/bgruszka/contextforge/internal/webhook/v1beta1"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -65,6 +86,7 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
 	utilruntime.Must(ctxforgev1alpha1.AddToScheme(scheme))
+	utilruntime.Must(ctxforgev1beta1.AddToScheme(scheme))
 	// +kubebuilder:scaffold:scheme
 }
 
@@ -73,22 +95,82 @@ func main() {
 	var metricsAddr string
 	var metricsCertPath, metricsCertName, metricsCertKey string
 	var webhookCertPath, webhookCertName, webhookCertKey string
+	var webhookCertExpiryThreshold time.Duration
+	var webhookCertSecret, webhookService string
+	var webhookCertValidity time.Duration
+	var mutatingWebhookConfig, validatingWebhookConfig, webhookExcludedNamespaces string
+	var conversionWebhookService string
+	var selfCheckWebhookConfig, selfCheckNamespace string
+	var selfCheckInterval time.Duration
+	var webhookLabeledPodsOnly bool
+	var policyStatsWindow time.Duration
+	var policyResyncInterval time.Duration
+	var enableGatewayAPI bool
 	var enableLeaderElection bool
+	var leaseDuration, renewDeadline, retryPeriod, shutdownDelay time.Duration
 	var probeAddr string
+	var ruleStreamAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
 	var tlsOpts []func(*tls.Config)
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
+	flag.StringVar(&ruleStreamAddr, "rule-stream-bind-address", "0",
+		"The address the rule stream service, which pushes header rules to proxies, binds to. "+
+			"Use :18000 to serve it, or leave as 0 to disable it.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.DurationVar(&leaseDuration, "leader-elect-lease-duration", 15*time.Second,
+		"How long other replicas wait for the leader to renew its lease before taking over.")
+	flag.DurationVar(&renewDeadline, "leader-elect-renew-deadline", 10*time.Second,
+		"How long the leader retries renewing its lease before it gives up leadership.")
+	flag.DurationVar(&retryPeriod, "leader-elect-retry-period", 2*time.Second,
+		"How often replicas try to acquire or renew the leader lease.")
+	flag.DurationVar(&shutdownDelay, "shutdown-delay", 0,
+		"On termination, report not ready but keep serving webhooks for this long before stopping, "+
+			"so that the webhook Service stops routing admissions to the replica first.")
 	flag.BoolVar(&secureMetrics, "metrics-secure", true,
 		"If set, the metrics endpoint is served securely via HTTPS. Use --metrics-secure=false to use HTTP instead.")
 	flag.StringVar(&webhookCertPath, "webhook-cert-path", "", "The directory that contains the webhook certificate.")
 	flag.StringVar(&webhookCertName, "webhook-cert-name", "tls.crt", "The name of the webhook certificate file.")
 	flag.StringVar(&webhookCertKey, "webhook-cert-key", "tls.key", "The name of the webhook key file.")
+	flag.DurationVar(&webhookCertExpiryThreshold, "webhook-cert-expiry-threshold",
+		webhookv1.DefaultCertExpiryThreshold,
+		"Report the operator as Degraded once the webhook certificate expires within this duration.")
+	flag.StringVar(&webhookCertSecret, "webhook-cert-secret", "",
+		"Secret, as namespace/name, the operator stores a self-managed webhook CA and serving certificate in. "+
+			"Requires --webhook-service. Empty leaves the certificate to cert-manager or --webhook-cert-path.")
+	flag.StringVar(&webhookService, "webhook-service", "",
+		"Service, as namespace/name, in front of the webhook server. "+
+			"The self-managed certificate is issued for it and its webhooks get the CA bundle.")
+	flag.DurationVar(&webhookCertValidity, "webhook-cert-validity", webhookv1.DefaultCertValidity,
+		"Lifetime of self-managed webhook serving certificates. They are reissued after two thirds of it.")
+	flag.StringVar(&mutatingWebhookConfig, "mutating-webhook-configuration", "",
+		"Name of the MutatingWebhookConfiguration whose selectors the operator manages. Empty leaves it unmanaged.")
+	flag.StringVar(&validatingWebhookConfig, "validating-webhook-configuration", "",
+		"Name of the ValidatingWebhookConfiguration whose selectors the operator manages. Empty leaves it unmanaged.")
+	flag.StringVar(&webhookExcludedNamespaces, "webhook-excluded-namespaces", webhookv1.AlwaysExcludedNamespace,
+		"Comma-separated namespaces excluded from the managed webhooks. kube-system is always excluded.")
+	flag.BoolVar(&webhookLabeledPodsOnly, "webhook-labeled-pods-only", false,
+		"Only send pods labeled ctxforge.io/enabled=true to the managed pod webhooks.")
+	flag.StringVar(&selfCheckWebhookConfig, "webhook-self-check-configuration", "",
+		"Name of the MutatingWebhookConfiguration the webhook self-check verifies. Empty disables the self-check.")
+	flag.StringVar(&selfCheckNamespace, "webhook-self-check-namespace", "",
+		"Namespace of the self-check's dry-run HeaderPropagationPolicy. "+
+			"Defaults to the first of WATCH_NAMESPACES, or default.")
+	flag.DurationVar(&selfCheckInterval, "webhook-self-check-interval", 5*time.Minute,
+		"How often the webhook self-check runs.")
+	flag.StringVar(&conversionWebhookService, "conversion-webhook-service", "",
+		"Service, as namespace/name, the policy CRDs reach the conversion webhook through. "+
+			"Empty leaves the CRDs' conversion configuration unmanaged.")
+	flag.DurationVar(&policyStatsWindow, "policy-stats-window", 0,
+		"Scrape the metrics of policies' sidecars and summarize them in policy status over this window. 0 disables it.")
+	flag.DurationVar(&policyResyncInterval, "policy-resync-interval", 0,
+		"Reconcile every policy at this interval on top of pod and policy events. 0 disables it.")
+	flag.BoolVar(&enableGatewayAPI, "enable-gateway-api", false,
+		"Attach the headers of policies with a routeSelector to the Gateway API HTTPRoutes they select.")
 	flag.StringVar(&metricsCertPath, "metrics-cert-path", "",
 		"The directory that contains the metrics server certificate.")
 	flag.StringVar(&metricsCertName, "metrics-cert-name", "tls.crt", "The name of the metrics server certificate file.")
@@ -170,6 +252,54 @@ func main() {
 		metricsServerOptions.KeyName = metricsCertKey
 	}
 
+	// POLICY_DEFAULTS_CONFIGMAP names the ConfigMap of defaults folded into
+	// every policy. Its client is the manager's, set once it exists.
+	policyDefaults, err := ctxforgepolicy.DefaultsFromEnv(nil)
+	if err != nil {
+		setupLog.Error(err, "invalid policy defaults")
+		os.Exit(1)
+	}
+
+	// The operator only reads its rules ConfigMaps; don't cache all others
+	configMaps := cache.ByObject{
+		Field: fields.OneTermEqualSelector("metadata.name", webhookv1.RulesConfigMapName),
+	}
+	// WATCH_NAMESPACES restricts the operator to some namespaces, for clusters
+	// that don't grant it cluster-wide access to pods
+	watchNamespaces := splitNamespaces(os.Getenv("WATCH_NAMESPACES"))
+	if policyDefaults != nil {
+		// The defaults ConfigMap has another name: cache every ConfigMap of
+		// its namespace, and only the rules ConfigMaps elsewhere
+		configMaps.Namespaces = map[string]cache.Config{cache.AllNamespaces: {}}
+		if len(watchNamespaces) > 0 {
+			configMaps.Namespaces = make(map[string]cache.Config, len(watchNamespaces)+1)
+			for _, ns := range watchNamespaces {
+				configMaps.Namespaces[ns] = cache.Config{}
+			}
+		}
+		configMaps.Namespaces[policyDefaults.Key.Namespace] = cache.Config{FieldSelector: fields.Everything()}
+	}
+	cacheOptions := cache.Options{
+		ByObject: map[client.Object]cache.ByObject{
+			&corev1.ConfigMap{}: configMaps,
+		},
+	}
+	if len(watchNamespaces) > 0 {
+		setupLog.Info("Watching namespaces", "namespaces", watchNamespaces)
+		cacheOptions.DefaultNamespaces = make(map[string]cache.Config, len(watchNamespaces))
+		for _, ns := range watchNamespaces {
+			cacheOptions.DefaultNamespaces[ns] = cache.Config{}
+		}
+		// Namespaces are cluster-scoped; keep the others out of the cache so
+		// that nothing tries to list pods in them
+		watched, err := labels.NewRequirement(corev1.LabelMetadataName, selection.In, watchNamespaces)
+		if err != nil {
+			setupLog.Error(err, "invalid WATCH_NAMESPACES")
+			os.Exit(1)
+		}
+		cacheOptions.ByObject[&corev1.Namespace{}] = cache.ByObject{Label: labels.NewSelector().Add(*watched)}
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
 		Metrics:                metricsServerOptions,
@@ -177,36 +307,286 @@ func main() {
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "3d82ab1c.ctxforge.io",
-		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
-		// when the Manager ends. This requires the binary to immediately end when the
-		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
-		// speeds up voluntary leader transitions as the new leader don't have to wait
-		// LeaseDuration time first.
-		//
-		// In the default scaffold provided, the program ends immediately after
-		// the manager stops, so would be fine to enable this option. However,
-		// if you are doing or is intended to do any operation such as perform cleanups
-		// after the manager stops then its usage might be unsafe.
-		// LeaderElectionReleaseOnCancel: true,
+		LeaseDuration:          &leaseDuration,
+		RenewDeadline:          &renewDeadline,
+		RetryPeriod:            &retryPeriod,
+		// Webhooks are served by every replica while reconcilers and the
+		// runnables writing cluster state run on the leader only. The program
+		// ends right after the manager stops, so the leader can hand over its
+		// lease at once instead of other replicas waiting LeaseDuration.
+		LeaderElectionReleaseOnCancel: true,
+		Cache:                         cacheOptions,
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
+	if policyDefaults != nil {
+		policyDefaults.Client = mgr.GetClient()
+	}
+
+	// Reads go straight to the API server: the manager's cache isn't started
+	// yet, and shouldn't hold every Secret in the cluster
+	directClient, err := client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme(), Mapper: mgr.GetRESTMapper()})
+	if err != nil {
+		setupLog.Error(err, "unable to create client")
+		os.Exit(1)
+	}
+	// Locked-down clusters may withhold some permissions; the features
+	// needing them are disabled instead of failing at runtime
+	detectCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	caps := webhookv1.DetectCapabilities(detectCtx, directClient, watchNamespaces,
+		webhookv1.PermissionPatchPods, webhookv1.PermissionUpdatePodStatus, webhookv1.PermissionReadNamespaces)
+	cancel()
+	if err := mgr.Add(caps); err != nil {
+		setupLog.Error(err, "unable to set up capability reporting")
+		os.Exit(1)
+	}
 
+	var policyStats *controller.StatsCollector
+	if policyStatsWindow > 0 {
+		policyStats = controller.NewStatsCollector(policyStatsWindow)
+		if err := mgr.Add(policyStats); err != nil {
+			setupLog.Error(err, "unable to set up policy stats collection")
+			os.Exit(1)
+		}
+	}
 	if err := (&controller.HeaderPropagationPolicyReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:         mgr.GetClient(),
+		Scheme:         mgr.GetScheme(),
+		Recorder:       mgr.GetEventRecorderFor("ctxforge-controller"),
+		Stats:          policyStats,
+		ResyncInterval: policyResyncInterval,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "HeaderPropagationPolicy")
 		os.Exit(1)
 	}
+	// The status of cluster policies spans namespaces a namespace-scoped
+	// operator doesn't see; the webhook still applies their rules
+	if len(watchNamespaces) == 0 && caps.Require("ClusterHeaderPropagationPolicy", webhookv1.PermissionReadNamespaces) {
+		if err := (&controller.ClusterHeaderPropagationPolicyReconciler{
+			Client:         mgr.GetClient(),
+			Scheme:         mgr.GetScheme(),
+			Recorder:       mgr.GetEventRecorderFor("ctxforge-controller"),
+			Stats:          policyStats,
+			ResyncInterval: policyResyncInterval,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "ClusterHeaderPropagationPolicy")
+			os.Exit(1)
+		}
+	}
+	if caps.Require("ProxyConfigSync", webhookv1.PermissionUpdatePodStatus) {
+		if err := (&controller.ProxyConfigSyncReconciler{
+			Client: mgr.GetClient(),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "ProxyConfigSync")
+			os.Exit(1)
+		}
+	}
+	var ruleStreams *rulestream.Server
+	if ruleStreamAddr != "0" {
+		ruleStreams = rulestream.NewServer()
+		ruleStreams.Log = ctrl.Log.WithName("rulestream")
+		// Runs on the leader only, next to the controller publishing the rules
+		if err := mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+			return ruleStreams.Serve(ctx, ruleStreamAddr)
+		})); err != nil {
+			setupLog.Error(err, "unable to set up rule stream")
+			os.Exit(1)
+		}
+	}
+	// Streamed rules reach sidecars without patching their pods
+	patchPods := caps.Require("LiveConfigAnnotation", webhookv1.PermissionPatchPods)
+	if (patchPods || ruleStreams != nil) && caps.Require("LiveConfig", webhookv1.PermissionReadNamespaces) {
+		if err := (&controller.LiveConfigReconciler{
+			Client:     mgr.GetClient(),
+			Syncer:     webhookv1.NewLiveConfigSyncer(mgr.GetClient()),
+			Streams:    ruleStreams,
+			StreamOnly: !patchPods,
+			Defaults:   policyDefaults,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "LiveConfig")
+			os.Exit(1)
+		}
+	}
+	if caps.Require("PolicyAnnotation", webhookv1.PermissionPatchPods, webhookv1.PermissionReadNamespaces) {
+		podSyncer := webhookv1.NewLiveConfigSyncer(mgr.GetClient())
+		if err := (&controller.PolicyAnnotationReconciler{
+			Client:   mgr.GetClient(),
+			Syncer:   podSyncer,
+			Adopter:  podSyncer,
+			Defaults: policyDefaults,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "PolicyAnnotation")
+			os.Exit(1)
+		}
+	}
+	if caps.Require("HeaderPropagationReport", webhookv1.PermissionReadNamespaces) {
+		if err := (&controller.HeaderPropagationReportReconciler{
+			Client: mgr.GetClient(),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "HeaderPropagationReport")
+			os.Exit(1)
+		}
+	}
+	if caps.Require("RevisionAnnotation", webhookv1.PermissionReadNamespaces) {
+		if err := (&controller.RevisionAnnotationReconciler{
+			Client: mgr.GetClient(),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "RevisionAnnotation")
+			os.Exit(1)
+		}
+	}
+	if enableGatewayAPI && caps.Require("GatewayRoute", webhookv1.PermissionReadNamespaces) {
+		if _, err := mgr.GetRESTMapper().RESTMapping(controller.HTTPRouteGVK.GroupKind(),
+			controller.HTTPRouteGVK.Version); err != nil {
+			setupLog.Error(err, "--enable-gateway-api requires the Gateway API HTTPRoute CRD")
+			os.Exit(1)
+		}
+		// Envoy Gateway is optional: without it, gateways generate X-Request-Id on their own terms
+		_, err := mgr.GetRESTMapper().RESTMapping(controller.ClientTrafficPolicyGVK.GroupKind(),
+			controller.ClientTrafficPolicyGVK.Version)
+		envoyGateway := err == nil
+		setupLog.Info("Gateway API integration enabled", "envoyGateway", envoyGateway)
+		if err := (&controller.GatewayRouteReconciler{
+			Client:       mgr.GetClient(),
+			Recorder:     mgr.GetEventRecorderFor("ctxforge-controller"),
+			EnvoyGateway: envoyGateway,
+			Defaults:     policyDefaults,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "GatewayRoute")
+			os.Exit(1)
+		}
+	}
+	if os.Getenv("ISTIO_MODE") == webhookv1.IstioModeEnvoyFilter &&
+		caps.Require("EnvoyFilter", webhookv1.PermissionReadNamespaces) {
+		if _, err := mgr.GetRESTMapper().RESTMapping(istio.EnvoyFilterGVK.GroupKind(),
+			istio.EnvoyFilterGVK.Version); err != nil {
+			setupLog.Error(err, "ISTIO_MODE=envoyfilter requires the Istio EnvoyFilter CRD")
+			os.Exit(1)
+		}
+		if err := (&controller.EnvoyFilterReconciler{
+			Client:   mgr.GetClient(),
+			Defaults: policyDefaults,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "EnvoyFilter")
+			os.Exit(1)
+		}
+	}
+	if caps.Require("RulesConfigMap", webhookv1.PermissionReadNamespaces) {
+		if err := (&controller.RulesConfigMapReconciler{
+			Client:   mgr.GetClient(),
+			Renderer: webhookv1.NewLiveConfigSyncer(mgr.GetClient()),
+			Defaults: policyDefaults,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "RulesConfigMap")
+			os.Exit(1)
+		}
+	}
 	// nolint:goconst
 	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
-		if err := webhookv1.SetupPodWebhookWithManager(mgr); err != nil {
+		if err := webhookv1.SetupPodWebhookWithManager(mgr, caps); err != nil {
 			setupLog.Error(err, "unable to create webhook", "webhook", "Pod")
 			os.Exit(1)
 		}
+		if err := webhookv1beta1.SetupPolicyWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "HeaderPropagationPolicy")
+			os.Exit(1)
+		}
+		// The directory controller-runtime serves the webhook certificate from
+		certDir := webhookCertPath
+		if certDir == "" {
+			certDir = filepath.Join(os.TempDir(), "k8s-webhook-server", "serving-certs")
+		}
+		if webhookCertSecret != "" {
+			secretNamespace, secretName, ok := strings.Cut(webhookCertSecret, "/")
+			if !ok || secretNamespace == "" || secretName == "" {
+				setupLog.Error(nil, "invalid --webhook-cert-secret, expected namespace/name", "value", webhookCertSecret)
+				os.Exit(1)
+			}
+			serviceNamespace, serviceName, ok := strings.Cut(webhookService, "/")
+			if !ok || serviceNamespace == "" || serviceName == "" {
+				setupLog.Error(nil, "invalid --webhook-service, expected namespace/name", "value", webhookService)
+				os.Exit(1)
+			}
+			rotator := &webhookv1.CertRotator{
+				Client:   directClient,
+				Secret:   types.NamespacedName{Namespace: secretNamespace, Name: secretName},
+				Service:  types.NamespacedName{Namespace: serviceNamespace, Name: serviceName},
+				CertDir:  certDir,
+				CertName: webhookCertName,
+				KeyName:  webhookCertKey,
+				Validity: webhookCertValidity,
+			}
+			// The webhook server needs a certificate to start
+			bootstrapCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			err = rotator.Ensure(bootstrapCtx)
+			cancel()
+			if err != nil {
+				setupLog.Error(err, "unable to set up the self-managed webhook certificate")
+				os.Exit(1)
+			}
+			if err := mgr.Add(rotator); err != nil {
+				setupLog.Error(err, "unable to set up webhook certificate rotator")
+				os.Exit(1)
+			}
+		}
+		if mutatingWebhookConfig != "" || validatingWebhookConfig != "" {
+			if err := mgr.Add(&webhookv1.WebhookSelectorManager{
+				Client:                         mgr.GetClient(),
+				MutatingWebhookConfiguration:   mutatingWebhookConfig,
+				ValidatingWebhookConfiguration: validatingWebhookConfig,
+				ExcludedNamespaces:             strings.Split(webhookExcludedNamespaces, ","),
+				WatchNamespaces:                watchNamespaces,
+				LabeledPodsOnly:                webhookLabeledPodsOnly,
+			}); err != nil {
+				setupLog.Error(err, "unable to set up webhook selector manager")
+				os.Exit(1)
+			}
+		}
+		if conversionWebhookService != "" {
+			namespace, name, ok := strings.Cut(conversionWebhookService, "/")
+			if !ok || namespace == "" || name == "" {
+				setupLog.Error(nil, "invalid --conversion-webhook-service, expected namespace/name",
+					"value", conversionWebhookService)
+				os.Exit(1)
+			}
+			if err := mgr.Add(&webhookv1beta1.CRDConversionManager{
+				Client:  mgr.GetClient(),
+				Service: types.NamespacedName{Namespace: namespace, Name: name},
+				CAFile:  filepath.Join(certDir, "ca.crt"),
+			}); err != nil {
+				setupLog.Error(err, "unable to set up CRD conversion manager")
+				os.Exit(1)
+			}
+		}
+		if selfCheckWebhookConfig != "" {
+			if selfCheckNamespace == "" {
+				selfCheckNamespace = metav1.NamespaceDefault
+				if len(watchNamespaces) > 0 {
+					selfCheckNamespace = watchNamespaces[0]
+				}
+			}
+			if err := mgr.Add(&webhookv1.WebhookSelfCheck{
+				Client:                       mgr.GetClient(),
+				MutatingWebhookConfiguration: selfCheckWebhookConfig,
+				CertFile:                     filepath.Join(certDir, webhookCertName),
+				Namespace:                    selfCheckNamespace,
+				Interval:                     selfCheckInterval,
+			}); err != nil {
+				setupLog.Error(err, "unable to set up webhook self-check")
+				os.Exit(1)
+			}
+		}
+		if len(webhookCertPath) > 0 || webhookCertSecret != "" {
+			if err := mgr.Add(&webhookv1.CertExpiryMonitor{
+				CertFile:  filepath.Join(certDir, webhookCertName),
+				Threshold: webhookCertExpiryThreshold,
+			}); err != nil {
+				setupLog.Error(err, "unable to set up webhook certificate expiry monitor")
+				os.Exit(1)
+			}
+		}
 	}
 	// +kubebuilder:scaffold:builder
 
@@ -214,14 +594,56 @@ func main() {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
 	}
-	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
+	var terminating atomic.Bool
+	if err := mgr.AddReadyzCheck("readyz", func(*http.Request) error {
+		if terminating.Load() {
+			return errors.New("shutting down")
+		}
+		return nil
+	}); err != nil {
 		setupLog.Error(err, "unable to set up ready check")
 		os.Exit(1)
 	}
+	// Only receive admissions once the webhook server serves them
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err := mgr.AddReadyzCheck("webhook", mgr.GetWebhookServer().StartedChecker()); err != nil {
+			setupLog.Error(err, "unable to set up webhook ready check")
+			os.Exit(1)
+		}
+	}
 
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	if err := mgr.Start(delayShutdown(ctrl.SetupSignalHandler(), shutdownDelay, &terminating)); err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
 }
+
+// splitNamespaces parses a comma-separated list of namespaces.
+func splitNamespaces(value string) []string {
+	var namespaces []string
+	for _, ns := range strings.Split(value, ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	return namespaces
+}
+
+// delayShutdown returns a context cancelled delay after ctx. Meanwhile
+// terminating is set, failing the readiness check so the replica leaves the
+// webhook Service's endpoints while it still serves admissions.
+func delayShutdown(ctx context.Context, delay time.Duration, terminating *atomic.Bool) context.Context {
+	if delay <= 0 {
+		return ctx
+	}
+	delayed, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-ctx.Done()
+		terminating.Store(true)
+		setupLog.Info("Delaying shutdown until the webhook Service stops routing to this replica", "delay", delay)
+		time.Sleep(delay)
+		cancel()
+	}()
+	return delayed
+}
@@ -22,11 +22,19 @@ import (
 
 	"github.com/bgruszka/contextforge/internal/config"
 	"github.com/bgruszka/contextforge/internal/handler"
+	"github.com/bgruszka/contextforge/internal/metrics"
+	"github.com/bgruszka/contextforge/internal/rulestream"
 	"github.com/bgruszka/contextforge/internal/server"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
+// appWatchInterval is how often the app processes are checked with EXIT_ON_APP_EXIT.
+const appWatchInterval = 2 * time.Second
+
+// liveConfigInterval is how often LIVE_CONFIG_FILE or RULES_FILE is checked for pushed header rules.
+const liveConfigInterval = 2 * time.Second
+
 func main() {
 	setupLogger()
 
@@ -46,6 +54,73 @@ func main() {
 		log.Fatal().Err(err).Msg("Failed to create proxy handler")
 	}
 	srv := server.NewServer(cfg, proxyHandler)
+	if requestLog := proxyHandler.RequestLog(); requestLog != nil {
+		srv.HandleAdmin("/debug/requests", requestLog)
+	}
+
+	if cfg.LiveConfigFile != "" {
+		liveCtx, stopLive := context.WithCancel(context.Background())
+		defer stopLive()
+		server.WatchLiveConfig(liveCtx, cfg.LiveConfigFile, liveConfigInterval, func(value string) {
+			if err := applyLiveConfig(cfg, proxyHandler, srv, value); err != nil {
+				log.Error().Err(err).Msg("Ignoring invalid live header rules")
+			}
+		})
+	}
+	if cfg.RulesFile != "" {
+		rulesCtx, stopRules := context.WithCancel(context.Background())
+		defer stopRules()
+		server.WatchRulesFile(rulesCtx, cfg.RulesFile, liveConfigInterval, func(value string) {
+			if err := applyLiveConfig(cfg, proxyHandler, srv, value); err != nil {
+				log.Error().Err(err).Msg("Ignoring invalid header rules from rules file")
+			}
+		})
+	}
+	if cfg.RuleStreamAddress != "" {
+		streamCtx, stopStream := context.WithCancel(context.Background())
+		defer stopStream()
+		node := rulestream.Node{Namespace: cfg.PodNamespace, Pod: cfg.PodName}
+		err := server.StreamRules(streamCtx, cfg.RuleStreamAddress, node, func(value string) error {
+			return applyLiveConfig(cfg, proxyHandler, srv, value)
+		})
+		if err != nil {
+			log.Fatal().Err(err).Str("address", cfg.RuleStreamAddress).Msg("Failed to connect to the rule stream")
+		}
+		log.Info().Str("address", cfg.RuleStreamAddress).Msg("Subscribed to the operator's rule stream")
+	}
+
+	stopOTLP := func(context.Context) error { return nil }
+	if cfg.OTelMetricsEndpoint != "" {
+		stopOTLP, err = metrics.StartOTLPExporter(context.Background(), metrics.OTLPConfig{
+			Endpoint: cfg.OTelMetricsEndpoint,
+			Interval: cfg.OTelMetricsInterval,
+			Insecure: cfg.OTelMetricsInsecure,
+		})
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to start OTLP metrics exporter")
+		}
+		log.Info().
+			Str("endpoint", cfg.OTelMetricsEndpoint).
+			Dur("interval", cfg.OTelMetricsInterval).
+			Msg("Pushing metrics via OTLP")
+	}
+
+	stopStatsD := func() {}
+	if cfg.StatsDAddress != "" {
+		stopStatsD, err = metrics.StartStatsDEmitter(metrics.StatsDConfig{
+			Address:  cfg.StatsDAddress,
+			Interval: cfg.StatsDInterval,
+			Flavor:   cfg.StatsDFlavor,
+		})
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to start StatsD emitter")
+		}
+		log.Info().
+			Str("address", cfg.StatsDAddress).
+			Str("flavor", cfg.StatsDFlavor).
+			Dur("interval", cfg.StatsDInterval).
+			Msg("Sending metrics to StatsD")
+	}
 
 	go func() {
 		if err := srv.Start(); err != nil && err != http.ErrServerClosed {
@@ -55,9 +130,21 @@ func main() {
 
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
 
-	log.Info().Msg("Received shutdown signal")
+	// A nil channel never fires, leaving shutdown to signals only
+	var appExited <-chan struct{}
+	if cfg.ExitOnAppExit {
+		watchCtx, stopWatch := context.WithCancel(context.Background())
+		defer stopWatch()
+		appExited = server.WatchAppExit(watchCtx, "/proc", appWatchInterval)
+	}
+
+	select {
+	case <-quit:
+		log.Info().Msg("Received shutdown signal")
+	case <-appExited:
+		log.Info().Msg("Shutting down because the app exited")
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -66,9 +153,56 @@ func main() {
 		log.Error().Err(err).Msg("Server forced to shutdown")
 	}
 
+	// Flush metrics recorded during shutdown
+	if err := stopOTLP(ctx); err != nil {
+		log.Error().Err(err).Msg("Failed to flush OTLP metrics")
+	}
+	stopStatsD()
+
+	if cfg.PushgatewayURL != "" {
+		err := metrics.PushToGateway(ctx, metrics.PushgatewayConfig{
+			URL:      cfg.PushgatewayURL,
+			Job:      cfg.PushgatewayJob,
+			Instance: cfg.PodName,
+		})
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to push metrics to Pushgateway")
+		} else {
+			log.Info().Str("url", cfg.PushgatewayURL).Msg("Pushed metrics to Pushgateway")
+		}
+	}
+
+	if err := proxyHandler.Close(); err != nil {
+		log.Error().Err(err).Msg("Failed to close proxy handler")
+	}
+
 	log.Info().Msg("Server exited gracefully")
 }
 
+// applyLiveConfig swaps in header rules pushed by the operator, or the injected
+// ones when value is empty. Invalid rules are returned as an error and leave
+// the current rules in place.
+func applyLiveConfig(cfg *config.ProxyConfig, proxyHandler *handler.ProxyHandler, srv *server.Server, value string) error {
+	rules, checksum := cfg.HeaderRules, cfg.ConfigChecksum
+	if value != "" {
+		parsed, err := config.ParseHeaderRules(value)
+		if err != nil {
+			return err
+		}
+		// Rules matching the injected ones keep its checksum, which the
+		// operator's config sync check expects
+		if !config.EqualRules(parsed, cfg.HeaderRules) {
+			rules, checksum = parsed, config.ConfigChecksum("", value)
+		}
+	}
+	if err := proxyHandler.UpdateRules(rules); err != nil {
+		return err
+	}
+	srv.SetConfigChecksum(checksum)
+	log.Info().Int("rules", len(rules)).Str("checksum", checksum).Msg("Applied live header rules")
+	return nil
+}
+
 // setupLogger configures zerolog based on LOG_LEVEL environment variable.
 func setupLogger() {
 	logLevel := os.Getenv("LOG_LEVEL")
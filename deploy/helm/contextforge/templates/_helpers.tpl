@@ -65,3 +65,137 @@ Namespace to use
 {{- define "contextforge.namespace" -}}
 {{- .Values.namespace.name | default "contextforge-system" }}
 {{- end }}
+
+{{/*
+Webhook entry variants. The default revision receives all pods and skips those
+of other revisions itself. A named revision gets one entry for namespaces
+labeled with it and one for labeled pods in unlabeled namespaces.
+*/}}
+{{- define "contextforge.webhookVariants" -}}
+{{- if .Values.webhook.revision -}}
+namespace,pod
+{{- else -}}
+all
+{{- end -}}
+{{- end }}
+
+{{/*
+Webhook name suffix for a variant
+*/}}
+{{- define "contextforge.webhookSuffix" -}}
+{{- if eq . "pod" }}-labeled{{ end -}}
+{{- end }}
+
+{{/*
+Namespaces excluded from the webhooks: kube-system, the configured ones and the
+release namespace. The operator enforces the same list at runtime.
+*/}}
+{{- define "contextforge.excludedNamespaces" -}}
+{{- $namespaces := concat (list "kube-system") .Values.webhook.excludedNamespaces (list (include "contextforge.namespace" .)) -}}
+{{- $namespaces | uniq | sortAlpha | join "," -}}
+{{- end }}
+
+{{/*
+Comma-separated names of proxy.imagePullSecrets, which accepts the same
+[{name: ...}] entries as a pod spec.
+*/}}
+{{- define "contextforge.proxyImagePullSecrets" -}}
+{{- $names := list -}}
+{{- range . }}{{ $names = append $names .name }}{{ end -}}
+{{- $names | join "," -}}
+{{- end }}
+
+{{/*
+namespaceSelector and objectSelector of a webhook entry.
+Expects a dict with "root" (the chart context), "variant" and "pods" (whether
+the entry matches pods).
+*/}}
+{{- define "contextforge.webhookSelectors" -}}
+{{- $revision := .root.Values.webhook.revision -}}
+namespaceSelector:
+  matchExpressions:
+    - key: ctxforge.io/injection
+      operator: NotIn
+      values: ["disabled"]
+    - key: kubernetes.io/metadata.name
+      operator: NotIn
+      values: [{{ range $i, $ns := splitList "," (include "contextforge.excludedNamespaces" .root) }}{{ if $i }}, {{ end }}{{ $ns | quote }}{{ end }}]
+    {{- with .root.Values.operator.watchNamespaces }}
+    - key: kubernetes.io/metadata.name
+      operator: In
+      values: [{{ range $i, $ns := . | uniq | sortAlpha }}{{ if $i }}, {{ end }}{{ $ns | quote }}{{ end }}]
+    {{- end }}
+    {{- if eq .variant "namespace" }}
+    - key: ctxforge.io/revision
+      operator: In
+      values: [{{ $revision | quote }}]
+    {{- else if eq .variant "pod" }}
+    - key: ctxforge.io/revision
+      operator: DoesNotExist
+    {{- end }}
+objectSelector:
+  matchExpressions:
+    - key: ctxforge.io/exclude
+      operator: NotIn
+      values: ["true"]
+    {{- if eq .variant "pod" }}
+    - key: ctxforge.io/revision
+      operator: In
+      values: [{{ $revision | quote }}]
+    {{- end }}
+    {{- if and .pods .root.Values.webhook.labeledPodsOnly }}
+    - key: ctxforge.io/enabled
+      operator: In
+      values: ["true"]
+    {{- end }}
+{{- end }}
+
+{{/*
+Rules the operator needs in each namespace it watches: cluster-wide without
+operator.watchNamespaces, or in a Role per watched namespace.
+*/}}
+{{- define "contextforge.namespacedRules" -}}
+- apiGroups: [""]
+  resources: ["pods"]
+  verbs: ["get", "list", "watch", "patch"]
+- apiGroups: [""]
+  resources: ["pods/status"]
+  verbs: ["get", "update", "patch"]
+- apiGroups: [""]
+  resources: ["configmaps"]
+  verbs: ["get", "list", "watch", "create", "update", "delete"]
+- apiGroups: ["apps"]
+  resources: ["deployments", "statefulsets", "daemonsets"]
+  verbs: ["get", "list", "watch"]
+- apiGroups: ["apps"]
+  resources: ["deployments", "statefulsets"]
+  verbs: ["patch"]
+- apiGroups: ["ctxforge.ctxforge.io"]
+  resources: ["headerpropagationpolicies"]
+  verbs: ["get", "list", "watch", "create", "update", "patch", "delete"]
+- apiGroups: ["ctxforge.ctxforge.io"]
+  resources: ["headerpropagationpolicies/status"]
+  verbs: ["get", "update", "patch"]
+- apiGroups: ["ctxforge.ctxforge.io"]
+  resources: ["headerpropagationpolicies/finalizers"]
+  verbs: ["update"]
+- apiGroups: ["ctxforge.ctxforge.io"]
+  resources: ["headerpropagationreports"]
+  verbs: ["get", "list", "watch", "create", "update", "delete"]
+- apiGroups: [""]
+  resources: ["events"]
+  verbs: ["create", "patch"]
+{{- if .Values.operator.gatewayAPI.enabled }}
+- apiGroups: ["gateway.networking.k8s.io"]
+  resources: ["httproutes"]
+  verbs: ["get", "list", "watch", "update"]
+- apiGroups: ["gateway.envoyproxy.io"]
+  resources: ["clienttrafficpolicies"]
+  verbs: ["get", "list", "watch", "create", "update", "delete"]
+{{- end }}
+{{- if eq .Values.proxy.istioMode "envoyfilter" }}
+- apiGroups: ["networking.istio.io"]
+  resources: ["envoyfilters"]
+  verbs: ["get", "list", "watch", "create", "update", "delete"]
+{{- end }}
+{{- end }}
@@ -0,0 +1,144 @@
+// Package audit provides a structured audit log of header mutations performed by
+// the ContextForge proxy.
+package audit
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+// Action describes what the proxy did to a header.
+type Action string
+
+const (
+	// ActionAdded means the proxy added a header that was not present.
+	ActionAdded Action = "added"
+	// ActionGenerated means the proxy generated a value for a missing header.
+	ActionGenerated Action = "generated"
+	// ActionOverridden means the proxy replaced an existing header value.
+	ActionOverridden Action = "overridden"
+	// ActionStripped means the proxy removed a header.
+	ActionStripped Action = "stripped"
+	// ActionBlocked means the proxy refused to propagate a header.
+	ActionBlocked Action = "blocked"
+	// ActionRejected means the proxy refused the whole request because of its
+	// headers.
+	ActionRejected Action = "rejected"
+)
+
+// Reasons a header was stripped, blocked or rejected.
+const (
+	// ReasonCredential is a credential header, which is never propagated
+	// unless UNSAFE_PROPAGATE_CREDENTIALS is set.
+	ReasonCredential = "credential"
+	// ReasonDestination is a destination the header's rule doesn't allow.
+	ReasonDestination = "destination"
+	// ReasonExternalHost is a host outside the cluster, with IN_CLUSTER_ONLY.
+	ReasonExternalHost = "external_host"
+	// ReasonSampling is a request left out of the header rule's sample.
+	ReasonSampling = "sampling"
+	// ReasonHeaderCount is a request with more header fields than MAX_HEADER_COUNT.
+	ReasonHeaderCount = "header_count"
+	// ReasonHeaderBytes is a request whose header fields exceed MAX_HEADER_BYTES.
+	ReasonHeaderBytes = "header_bytes"
+	// ReasonUnsigned is a context header no valid signature covers, with
+	// UNSIGNED_CONTEXT set to strip or reject.
+	ReasonUnsigned = "unsigned"
+)
+
+// NoRule is used as the rule of events that were not triggered by a header rule.
+const NoRule = -1
+
+// Event is a single header mutation.
+type Event struct {
+	RequestID string
+	Action    Action
+	Header    string
+	// Rule is the index of the header rule that caused the mutation, or NoRule.
+	Rule int
+	// Reason explains why a header was stripped, blocked or rejected, if the
+	// proxy enforced a control rather than a rule.
+	Reason string
+	Method string
+	Path   string
+}
+
+// Logger writes audit events to a dedicated zerolog stream, separate from the
+// application log. A nil Logger discards all events.
+type Logger struct {
+	logger zerolog.Logger
+	closer io.Closer
+	mu     sync.Mutex
+}
+
+// New creates a Logger writing to the given destination: "stdout", "stderr" or a
+// file path, which is created if needed and appended to. An empty destination
+// disables auditing and returns a nil Logger.
+func New(destination string) (*Logger, error) {
+	var (
+		out    io.Writer
+		closer io.Closer
+	)
+
+	switch destination {
+	case "":
+		return nil, nil
+	case "stdout":
+		out = os.Stdout
+	case "stderr":
+		out = os.Stderr
+	default:
+		f, err := os.OpenFile(destination, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audit log %q: %w", destination, err)
+		}
+		out = f
+		closer = f
+	}
+
+	return NewWithWriter(out, closer), nil
+}
+
+// NewWithWriter creates a Logger writing to w. If closer is not nil it is closed by Close.
+func NewWithWriter(w io.Writer, closer io.Closer) *Logger {
+	return &Logger{
+		logger: zerolog.New(w).With().Timestamp().Str("log", "audit").Logger(),
+		closer: closer,
+	}
+}
+
+// Record writes an audit event. It is safe to call on a nil Logger.
+func (l *Logger) Record(e Event) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ev := l.logger.Log().
+		Str("request_id", e.RequestID).
+		Str("action", string(e.Action)).
+		Str("header", e.Header)
+	if e.Rule != NoRule {
+		ev = ev.Int("rule", e.Rule)
+	}
+	if e.Reason != "" {
+		ev = ev.Str("reason", e.Reason)
+	}
+	ev.Str("method", e.Method).
+		Str("path", e.Path).
+		Send()
+}
+
+// Close closes the underlying file, if any. It is safe to call on a nil Logger.
+func (l *Logger) Close() error {
+	if l == nil || l.closer == nil {
+		return nil
+	}
+	return l.closer.Close()
+}
@@ -0,0 +1,106 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_Disabled(t *testing.T) {
+	l, err := New("")
+	require.NoError(t, err)
+	assert.Nil(t, l)
+
+	// A nil logger must be usable
+	l.Record(Event{Action: ActionAdded, Header: "X-Request-Id"})
+	assert.NoError(t, l.Close())
+}
+
+func TestNew_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	l, err := New(path)
+	require.NoError(t, err)
+
+	l.Record(Event{RequestID: "abc", Action: ActionGenerated, Header: "X-Request-Id", Rule: 0})
+	require.NoError(t, l.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"action":"generated"`)
+}
+
+func TestNew_InvalidPath(t *testing.T) {
+	_, err := New(filepath.Join(t.TempDir(), "missing", "audit.log"))
+	assert.Error(t, err)
+}
+
+func TestLogger_Record(t *testing.T) {
+	tests := []struct {
+		name     string
+		event    Event
+		expected map[string]any
+	}{
+		{
+			name: "rule event",
+			event: Event{
+				RequestID: "abc123",
+				Action:    ActionAdded,
+				Header:    "X-Tenant-Id",
+				Rule:      2,
+				Method:    "GET",
+				Path:      "/api",
+			},
+			expected: map[string]any{
+				"log":        "audit",
+				"request_id": "abc123",
+				"action":     "added",
+				"header":     "X-Tenant-Id",
+				"rule":       float64(2),
+				"method":     "GET",
+				"path":       "/api",
+			},
+		},
+		{
+			name:  "blocked event",
+			event: Event{Action: ActionBlocked, Header: "Authorization", Rule: NoRule, Reason: ReasonCredential},
+			expected: map[string]any{
+				"action": "blocked",
+				"header": "Authorization",
+				"reason": "credential",
+			},
+		},
+		{
+			name:  "event without rule",
+			event: Event{Action: ActionStripped, Header: "X-Ctxforge-Debug", Rule: NoRule},
+			expected: map[string]any{
+				"action": "stripped",
+				"header": "X-Ctxforge-Debug",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			l := NewWithWriter(&buf, nil)
+
+			l.Record(tt.event)
+
+			var entry map[string]any
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+			for k, v := range tt.expected {
+				assert.Equal(t, v, entry[k], k)
+			}
+			assert.NotEmpty(t, entry["time"])
+			if tt.event.Rule == NoRule {
+				assert.NotContains(t, entry, "rule")
+			}
+		})
+	}
+}
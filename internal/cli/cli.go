@@ -0,0 +1,126 @@
+// Package cli implements the ctxforge command, which runs the operator's
+// admission logic on manifests outside the cluster, e.g. in CI.
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+
+	ctxforgev1alpha1 "github.com/bgruszka/contextforge/api/v1alpha1"
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+)
+
+var (
+	scheme = runtime.NewScheme()
+	// codecs decode manifests strictly, rejecting unknown and duplicate
+	// fields as the API server does for kubectl apply.
+	codecs = serializer.NewCodecFactory(scheme, serializer.EnableStrict)
+)
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(ctxforgev1alpha1.AddToScheme(scheme))
+	utilruntime.Must(ctxforgev1beta1.AddToScheme(scheme))
+}
+
+// command is a ctxforge subcommand. It returns the process exit code.
+type command struct {
+	summary string
+	run     func(args []string, stdin io.Reader, stdout, stderr io.Writer) int
+}
+
+var commands = map[string]command{
+	"inject":   {summary: "Inject the proxy sidecar into workload manifests", run: runInject},
+	"validate": {summary: "Validate header propagation policies", run: runValidate},
+}
+
+// Run executes the ctxforge command line args, without the program name, and
+// returns the process exit code: 0 on success, 1 when the input has errors
+// and 2 on usage errors.
+func Run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		usage(stderr)
+		if len(args) == 0 {
+			return 2
+		}
+		return 0
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		_, _ = fmt.Fprintf(stderr, "ctxforge: unknown command %q\n\n", args[0])
+		usage(stderr)
+		return 2
+	}
+	return cmd.run(args[1:], stdin, stdout, stderr)
+}
+
+func usage(w io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	_, _ = fmt.Fprintln(w, "Usage: ctxforge <command> [flags] [file|dir|-]...")
+	_, _ = fmt.Fprintln(w, "\nCommands:")
+	for _, name := range names {
+		_, _ = fmt.Fprintf(w, "  %-10s %s\n", name, commands[name].summary)
+	}
+	_, _ = fmt.Fprintln(w, "\nRun 'ctxforge <command> -h' for the flags of a command.")
+}
+
+// input is a manifest stream to process.
+type input struct {
+	name string
+	open func() (io.ReadCloser, error)
+}
+
+// inputs expands paths into the manifests to read: "-" is stdin, and
+// directories contribute their .yaml, .yml and .json files, recursively.
+func inputs(paths []string, stdin io.Reader) ([]input, error) {
+	var result []input
+	for _, path := range paths {
+		if path == "-" {
+			result = append(result, input{name: "<stdin>", open: func() (io.ReadCloser, error) {
+				return io.NopCloser(stdin), nil
+			}})
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			result = append(result, fileInput(path))
+			continue
+		}
+		err = filepath.WalkDir(path, func(file string, entry os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			switch strings.ToLower(filepath.Ext(file)) {
+			case ".yaml", ".yml", ".json":
+				if !entry.IsDir() {
+					result = append(result, fileInput(file))
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func fileInput(path string) input {
+	return input{name: path, open: func() (io.ReadCloser, error) { return os.Open(path) }}
+}
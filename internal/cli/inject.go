@@ -0,0 +1,332 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/go-logr/logr"
+	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"sigs.k8s.io/yaml"
+
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+	webhookv1 "github.com/bgruszka/contextforge/internal/webhook/v1"
+	webhookv1beta1 "github.com/bgruszka/contextforge/internal/webhook/v1beta1"
+)
+
+func runInject(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("inject", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		_, _ = fmt.Fprintln(stderr, "Usage: ctxforge inject [flags] [file|dir|-]...")
+		_, _ = fmt.Fprintln(stderr, "\nInjects the proxy sidecar into the Pods, Deployments, StatefulSets and DaemonSets")
+		_, _ = fmt.Fprintln(stderr, "of the manifests as the operator's webhook would, and writes all documents to")
+		_, _ = fmt.Fprintln(stderr, "stdout. Policies and Namespaces in the input, or in -policies, are used to")
+		_, _ = fmt.Fprintln(stderr, "configure the sidecar. Reads stdin when no path is given.")
+		_, _ = fmt.Fprintln(stderr, "\nFlags:")
+		flags.PrintDefaults()
+	}
+	image := flags.String("image", webhookv1.DefaultProxyImage, "Proxy sidecar image")
+	namespace := flags.String("namespace", "default", "Namespace of objects that don't set one")
+	policyPaths := flags.String("policies", "",
+		"Comma-separated files or directories of policies and Namespaces to use besides the input's")
+	defaultsDir := flags.String("sidecar-defaults", "",
+		"Directory holding the sidecar defaults ConfigMap keys, one file per key")
+	nativeSidecar := flags.Bool("native-sidecar", false, "Inject the proxy as a native sidecar (an init container)")
+	sidecarOrder := flags.String("sidecar-order", webhookv1.SidecarOrderLast,
+		"Where the proxy goes among the containers: first or last")
+	verbose := flags.Bool("v", false, "Log injection decisions to stderr")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if *sidecarOrder != webhookv1.SidecarOrderFirst && *sidecarOrder != webhookv1.SidecarOrderLast {
+		_, _ = fmt.Fprintf(stderr, "ctxforge inject: invalid -sidecar-order %q: must be one of %s, %s\n",
+			*sidecarOrder, webhookv1.SidecarOrderFirst, webhookv1.SidecarOrderLast)
+		return 2
+	}
+	// The webhook logs why pods are skipped through controller-runtime
+	if *verbose {
+		logf.SetLogger(zap.New(zap.WriteTo(stderr)))
+	} else {
+		logf.SetLogger(logr.Discard())
+	}
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+	files, err := inputs(paths, stdin)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "ctxforge inject: %v\n", err)
+		return 2
+	}
+	var extra []input
+	if *policyPaths != "" {
+		if extra, err = inputs(strings.Split(*policyPaths, ","), stdin); err != nil {
+			_, _ = fmt.Fprintf(stderr, "ctxforge inject: invalid -policies: %v\n", err)
+			return 2
+		}
+	}
+
+	// Every document is read up front: policies may follow the workloads
+	// they select
+	var docs []document
+	for _, file := range files {
+		if err := eachDocument(file, func(_ int, data []byte) { docs = append(docs, decode(file.name, data)) }); err != nil {
+			_, _ = fmt.Fprintf(stderr, "ctxforge inject: %s: %v\n", file.name, err)
+			return 1
+		}
+	}
+	cluster := &manifestReader{namespace: *namespace}
+	for _, doc := range docs {
+		cluster.add(doc.obj)
+	}
+	for _, file := range extra {
+		if err := eachDocument(file, func(_ int, data []byte) { cluster.add(decode(file.name, data).obj) }); err != nil {
+			_, _ = fmt.Fprintf(stderr, "ctxforge inject: %s: %v\n", file.name, err)
+			return 1
+		}
+	}
+
+	pods := &webhookv1.PodCustomDefaulter{
+		ProxyImage:       *image,
+		Client:           cluster,
+		NativeSidecar:    *nativeSidecar,
+		JobNativeSidecar: *nativeSidecar,
+		SidecarOrder:     *sidecarOrder,
+		DrainTimeout:     webhookv1.DefaultDrainTimeout,
+	}
+	if *defaultsDir != "" {
+		pods.Defaults = &webhookv1.SidecarDefaultsSource{Dir: *defaultsDir}
+	}
+	workloads := &webhookv1.WorkloadCustomDefaulter{Pods: pods, Enabled: true}
+
+	failed := false
+	for i, doc := range docs {
+		out, err := doc.inject(*namespace, pods, workloads)
+		if err != nil {
+			_, _ = fmt.Fprintf(stderr, "ctxforge inject: %s: %v\n", doc.name(), err)
+			failed = true
+			out = doc.data
+		}
+		if i > 0 {
+			_, _ = fmt.Fprintln(stdout, "---")
+		}
+		_, _ = stdout.Write(out)
+		if !bytes.HasSuffix(out, []byte("\n")) {
+			_, _ = fmt.Fprintln(stdout)
+		}
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// document is a manifest of the input with the object it decoded to, if it
+// is of a kind the operator knows.
+type document struct {
+	file string
+	data []byte
+	obj  runtime.Object
+	err  error
+}
+
+// decode decodes data as a typed object. Documents of unknown kinds are kept
+// as data only and passed through unchanged.
+func decode(file string, data []byte) document {
+	doc := document{file: file, data: data}
+	obj, gvk, err := codecs.UniversalDeserializer().Decode(data, nil, nil)
+	switch {
+	case runtime.IsNotRegisteredError(err) || runtime.IsMissingKind(err) || runtime.IsMissingVersion(err):
+	case err != nil:
+		// Unknown fields would be lost on output, so strict errors are fatal
+		// too for injected kinds
+		doc.err = err
+		if obj != nil {
+			doc.obj = obj
+			obj.GetObjectKind().SetGroupVersionKind(*gvk)
+		}
+	default:
+		obj.GetObjectKind().SetGroupVersionKind(*gvk)
+		doc.obj = obj
+	}
+	return doc
+}
+
+func (doc document) name() string {
+	if accessor, ok := doc.obj.(metav1.Object); ok {
+		return fmt.Sprintf("%s %s/%s", doc.file, doc.obj.GetObjectKind().GroupVersionKind().Kind, accessor.GetName())
+	}
+	return doc.file
+}
+
+// inject returns the document with the sidecar injected into its pod spec,
+// or its data unchanged when it has no pod spec or isn't opted in.
+func (doc document) inject(namespace string, pods *webhookv1.PodCustomDefaulter, workloads *webhookv1.WorkloadCustomDefaulter) ([]byte, error) {
+	var defaulter admission.CustomDefaulter
+	var meta *metav1.ObjectMeta
+	switch obj := doc.obj.(type) {
+	case *corev1.Pod:
+		defaulter, meta = pods, &obj.ObjectMeta
+	case *appsv1.Deployment:
+		defaulter, meta = workloads, &obj.ObjectMeta
+	case *appsv1.StatefulSet:
+		defaulter, meta = workloads, &obj.ObjectMeta
+	case *appsv1.DaemonSet:
+		defaulter, meta = workloads, &obj.ObjectMeta
+	default:
+		return doc.data, nil
+	}
+	if doc.err != nil {
+		return nil, doc.err
+	}
+
+	// The webhook sees creations, with the namespace in the request when the
+	// object doesn't set it
+	if meta.Namespace != "" {
+		namespace = meta.Namespace
+	}
+	ctx := admission.NewContextWithRequest(context.Background(), admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: admissionv1.Create,
+			Namespace: namespace,
+			Name:      meta.Name,
+		},
+	})
+	before := doc.obj.DeepCopyObject()
+	if err := defaulter.Default(ctx, doc.obj); err != nil {
+		return nil, err
+	}
+	if equality.Semantic.DeepEqual(before, doc.obj) {
+		return doc.data, nil
+	}
+	return marshal(doc.obj)
+}
+
+// marshal renders obj as YAML, leaving out the empty creation timestamps
+// and status typed objects carry, which kubectl would reject or diff on.
+func marshal(obj runtime.Object) ([]byte, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	dropEmpty(content, "metadata", "creationTimestamp")
+	dropEmpty(content, "spec", "template", "metadata", "creationTimestamp")
+	dropEmpty(content, "status")
+	return yaml.Marshal(content)
+}
+
+// dropEmpty removes the field at path when it is null or an empty map.
+func dropEmpty(content map[string]any, path ...string) {
+	for _, key := range path[:len(path)-1] {
+		next, ok := content[key].(map[string]any)
+		if !ok {
+			return
+		}
+		content = next
+	}
+	key := path[len(path)-1]
+	value, ok := content[key]
+	if !ok {
+		return
+	}
+	if m, isMap := value.(map[string]any); value == nil || (isMap && len(m) == 0) {
+		delete(content, key)
+	}
+}
+
+// manifestReader serves the webhook's lookups of Namespaces and policies from
+// the objects of the manifests, as if they had been applied.
+type manifestReader struct {
+	// namespace is the namespace of namespaced objects that don't set one.
+	namespace       string
+	namespaces      []corev1.Namespace
+	policies        []ctxforgev1beta1.HeaderPropagationPolicy
+	clusterPolicies []ctxforgev1beta1.ClusterHeaderPropagationPolicy
+}
+
+var _ client.Reader = &manifestReader{}
+
+// add records obj if it is a Namespace or policy. Policies are converted
+// and defaulted as on admission.
+func (r *manifestReader) add(obj runtime.Object) {
+	switch o := obj.(type) {
+	case nil:
+		return
+	case *corev1.Namespace:
+		r.namespaces = append(r.namespaces, *o)
+		return
+	}
+	hub, err := toHub(obj)
+	if err != nil {
+		return
+	}
+	if err := (&webhookv1beta1.PolicyCustomDefaulter{}).Default(context.Background(), hub); err != nil {
+		return
+	}
+	switch p := hub.(type) {
+	case *ctxforgev1beta1.HeaderPropagationPolicy:
+		if p.Namespace == "" {
+			p.Namespace = r.namespace
+		}
+		r.policies = append(r.policies, *p)
+	case *ctxforgev1beta1.ClusterHeaderPropagationPolicy:
+		r.clusterPolicies = append(r.clusterPolicies, *p)
+	}
+}
+
+// Get implements client.Reader for Namespaces.
+func (r *manifestReader) Get(_ context.Context, key client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+	ns, ok := obj.(*corev1.Namespace)
+	if !ok {
+		return fmt.Errorf("unsupported object %T", obj)
+	}
+	for i := range r.namespaces {
+		if r.namespaces[i].Name == key.Name {
+			r.namespaces[i].DeepCopyInto(ns)
+			return nil
+		}
+	}
+	return apierrors.NewNotFound(schema.GroupResource{Resource: "namespaces"}, key.Name)
+}
+
+// List implements client.Reader for both policy kinds.
+func (r *manifestReader) List(_ context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	options := (&client.ListOptions{}).ApplyOptions(opts)
+	switch l := list.(type) {
+	case *ctxforgev1beta1.HeaderPropagationPolicyList:
+		l.Items = nil
+		for _, p := range r.policies {
+			if options.Namespace == "" || p.Namespace == options.Namespace {
+				l.Items = append(l.Items, *p.DeepCopy())
+			}
+		}
+	case *ctxforgev1beta1.ClusterHeaderPropagationPolicyList:
+		l.Items = nil
+		for _, p := range r.clusterPolicies {
+			l.Items = append(l.Items, *p.DeepCopy())
+		}
+	default:
+		return fmt.Errorf("unsupported list %T", list)
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+
+	webhookv1 "github.com/bgruszka/contextforge/internal/webhook/v1"
+)
+
+const deployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+spec:
+  selector:
+    matchLabels:
+      app: api
+  template:
+    metadata:
+      labels:
+        app: api
+      annotations:
+        ctxforge.io/enabled: "true"
+    spec:
+      containers:
+      - name: app
+        image: example/api:v1
+`
+
+// splitDocuments splits an inject output into its documents.
+func splitDocuments(output string) []string {
+	return strings.Split(output, "---\n")
+}
+
+func containerNames(containers []corev1.Container) []string {
+	var names []string
+	for _, c := range containers {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+func envValue(container corev1.Container, name string) string {
+	for _, env := range container.Env {
+		if env.Name == name {
+			return env.Value
+		}
+	}
+	return ""
+}
+
+func TestInject_Deployment(t *testing.T) {
+	service := "# kept as written\napiVersion: v1\nkind: Service\nmetadata: {name: api}\n"
+	code, stdout, stderr := runCLI(t, deployment+"---\n"+service+`---
+apiVersion: ctxforge.ctxforge.io/v1alpha1
+kind: HeaderPropagationPolicy
+metadata:
+  name: tracing
+spec:
+  podSelector:
+    matchLabels:
+      app: api
+  propagationRules:
+  - headers:
+    - name: X-Request-Id
+      generate: true
+`, "inject", "-image", "example/proxy:v2")
+	require.Equal(t, 0, code, stderr)
+
+	docs := splitDocuments(stdout)
+	require.Len(t, docs, 3)
+	var d appsv1.Deployment
+	require.NoError(t, yaml.UnmarshalStrict([]byte(docs[0]), &d))
+	assert.NotContains(t, docs[0], "creationTimestamp")
+	template := d.Spec.Template
+	assert.Equal(t, "tracing", template.Annotations[webhookv1.AnnotationPolicies],
+		"policies later in the input select the workload")
+	require.Equal(t, []string{"app", webhookv1.ProxyContainerName}, containerNames(template.Spec.Containers))
+	proxy := template.Spec.Containers[1]
+	assert.Equal(t, "example/proxy:v2", proxy.Image)
+	assert.Contains(t, envValue(proxy, "HEADER_RULES"), `"name":"x-request-id"`)
+	assert.Contains(t, envValue(proxy, "HEADER_RULES"), `"generatorType":"uuid"`, "the policy is defaulted")
+	assert.Equal(t, "http://localhost:9090", envValue(template.Spec.Containers[0], "HTTP_PROXY"))
+
+	assert.Equal(t, service, docs[1], "other kinds pass through unchanged")
+	assert.Contains(t, docs[2], "kind: HeaderPropagationPolicy")
+
+	// Injected manifests are left alone by a second run
+	code, again, _ := runCLI(t, stdout, "inject")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, stdout, again)
+}
+
+func TestInject_Pods(t *testing.T) {
+	pods := `apiVersion: v1
+kind: Pod
+metadata:
+  name: annotated
+  annotations:
+    ctxforge.io/enabled: "true"
+    ctxforge.io/headers: x-tenant-id
+spec:
+  containers:
+  - name: app
+    image: example/app:v1
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: plain
+spec:
+  containers:
+  - name: app
+    image: example/app:v1
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: namespaced
+  namespace: team-a
+spec:
+  containers:
+  - name: app
+    image: example/app:v1
+`
+	namespaces := filepath.Join(t.TempDir(), "namespaces.yaml")
+	require.NoError(t, os.WriteFile(namespaces, []byte(`apiVersion: v1
+kind: Namespace
+metadata:
+  name: team-a
+  labels:
+    `+webhookv1.LabelNamespaceInjection+`: `+webhookv1.NamespaceInjectionEnabled+`
+  annotations:
+    ctxforge.io/default-headers: x-team
+`), 0o600))
+
+	code, stdout, stderr := runCLI(t, pods, "inject", "-native-sidecar", "-policies", namespaces)
+	require.Equal(t, 0, code, stderr)
+	docs := splitDocuments(stdout)
+	require.Len(t, docs, 3)
+
+	var annotated corev1.Pod
+	require.NoError(t, yaml.UnmarshalStrict([]byte(docs[0]), &annotated))
+	require.Len(t, annotated.Spec.InitContainers, 1, "-native-sidecar injects an init container")
+	assert.Equal(t, webhookv1.ProxyContainerName, annotated.Spec.InitContainers[0].Name)
+	assert.Equal(t, "x-tenant-id", envValue(annotated.Spec.InitContainers[0], "HEADERS_TO_PROPAGATE"))
+
+	assert.Equal(t, strings.Split(pods, "---\n")[1], docs[1], "pods not opted in are unchanged")
+
+	var namespaced corev1.Pod
+	require.NoError(t, yaml.UnmarshalStrict([]byte(docs[2]), &namespaced))
+	require.Len(t, namespaced.Spec.InitContainers, 1, "the Namespace from -policies enables injection")
+	assert.Equal(t, "x-team", envValue(namespaced.Spec.InitContainers[0], "HEADERS_TO_PROPAGATE"))
+}
+
+func TestInject_Errors(t *testing.T) {
+	typo := strings.Replace(deployment, "    spec:\n", "    spec:\n      containerz: []\n", 1)
+	code, stdout, stderr := runCLI(t, typo, "inject")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, `Deployment/api: strict decoding error: unknown field "spec.template.spec.containerz"`)
+	assert.Equal(t, typo, stdout, "documents that can't be injected are written unchanged")
+
+	code, _, stderr = runCLI(t, deployment, "inject", "-sidecar-order", "middle")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, `invalid -sidecar-order "middle"`)
+}
@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"fmt"
+	"io/fs"
+	"sync"
+
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	structuralschema "k8s.io/apiextensions-apiserver/pkg/apiserver/schema"
+	structuraldefaulting "k8s.io/apiextensions-apiserver/pkg/apiserver/schema/defaulting"
+	apiservervalidation "k8s.io/apiextensions-apiserver/pkg/apiserver/validation"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/yaml"
+
+	"github.com/bgruszka/contextforge/config/crd"
+)
+
+// crdSchema is the OpenAPI schema of a CRD version, as the API server
+// applies it on create.
+type crdSchema struct {
+	structural *structuralschema.Structural
+	validator  apiservervalidation.SchemaValidator
+}
+
+// validate applies the schema's defaults to obj, then checks it against the
+// schema.
+func (s *crdSchema) validate(obj map[string]any) field.ErrorList {
+	structuraldefaulting.Default(obj, s.structural)
+	return apiservervalidation.ValidateCustomResource(nil, obj, s.validator)
+}
+
+var (
+	schemasOnce sync.Once
+	schemas     map[schema.GroupVersionKind]*crdSchema
+	schemasErr  error
+)
+
+// schemaFor returns the schema of gvk from the embedded CRDs, or nil if no
+// CRD serves it.
+func schemaFor(gvk schema.GroupVersionKind) (*crdSchema, error) {
+	schemasOnce.Do(func() { schemas, schemasErr = loadSchemas(crd.Bases) })
+	return schemas[gvk], schemasErr
+}
+
+// loadSchemas reads the schema of every version of the CRDs in fsys.
+func loadSchemas(fsys fs.FS) (map[schema.GroupVersionKind]*crdSchema, error) {
+	files, err := fs.Glob(fsys, "bases/*.yaml")
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[schema.GroupVersionKind]*crdSchema)
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		var definition apiextensionsv1.CustomResourceDefinition
+		if err := yaml.Unmarshal(data, &definition); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		for _, version := range definition.Spec.Versions {
+			if version.Schema == nil || version.Schema.OpenAPIV3Schema == nil {
+				continue
+			}
+			var props apiextensions.JSONSchemaProps
+			if err := apiextensionsv1.Convert_v1_JSONSchemaProps_To_apiextensions_JSONSchemaProps(
+				version.Schema.OpenAPIV3Schema, &props, nil); err != nil {
+				return nil, fmt.Errorf("%s: %s: %w", file, version.Name, err)
+			}
+			structural, err := structuralschema.NewStructural(&props)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s: %w", file, version.Name, err)
+			}
+			validator, _, err := apiservervalidation.NewSchemaValidator(&props)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s: %w", file, version.Name, err)
+			}
+			gvk := schema.GroupVersionKind{Group: definition.Spec.Group, Version: version.Name, Kind: definition.Spec.Names.Kind}
+			result[gvk] = &crdSchema{structural: structural, validator: validator}
+		}
+	}
+	return result, nil
+}
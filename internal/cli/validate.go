@@ -0,0 +1,276 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+
+	ctxforgev1alpha1 "github.com/bgruszka/contextforge/api/v1alpha1"
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+	ctxforgepolicy "github.com/bgruszka/contextforge/internal/policy"
+	webhookv1beta1 "github.com/bgruszka/contextforge/internal/webhook/v1beta1"
+)
+
+// finding is a problem found in a policy document.
+type finding struct {
+	warning bool
+	field   string
+	message string
+}
+
+func (f finding) String() string {
+	severity := "error"
+	if f.warning {
+		severity = "warning"
+	}
+	if f.field == "" {
+		return severity + ": " + f.message
+	}
+	return fmt.Sprintf("%s: %s: %s", severity, f.field, f.message)
+}
+
+func runValidate(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("validate", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		_, _ = fmt.Fprintln(stderr, "Usage: ctxforge validate [flags] [file|dir|-]...")
+		_, _ = fmt.Fprintln(stderr, "\nValidates HeaderPropagationPolicy and ClusterHeaderPropagationPolicy")
+		_, _ = fmt.Fprintln(stderr, "manifests as the operator's admission webhooks and controllers would.")
+		_, _ = fmt.Fprintln(stderr, "Other kinds are skipped. Reads stdin when no path is given.")
+		_, _ = fmt.Fprintln(stderr, "\nFlags:")
+		flags.PrintDefaults()
+	}
+	imageAllowlist := flags.String("image-allowlist", "",
+		"Comma-separated proxy images policies may pin with sidecar.image, as the operator's PROXY_IMAGE_ALLOWLIST")
+	quiet := flags.Bool("quiet", false, "Only print problems, not the policies that passed")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	allowedImages, err := ctxforgepolicy.ParseImageAllowlist(*imageAllowlist)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "ctxforge validate: invalid -image-allowlist: %v\n", err)
+		return 2
+	}
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+	files, err := inputs(paths, stdin)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "ctxforge validate: %v\n", err)
+		return 2
+	}
+
+	validator := &webhookv1beta1.PolicyCustomValidator{AllowedImages: allowedImages}
+	policies, failed := 0, false
+	for _, file := range files {
+		err := eachDocument(file, func(doc int, data []byte) {
+			name, findings, ok := validateDocument(data, validator)
+			if !ok {
+				return
+			}
+			policies++
+			where := fmt.Sprintf("%s:%d %s", file.name, doc, name)
+			if len(findings) == 0 && !*quiet {
+				_, _ = fmt.Fprintf(stdout, "%s: valid\n", where)
+			}
+			for _, f := range findings {
+				failed = failed || !f.warning
+				_, _ = fmt.Fprintf(stdout, "%s: %s\n", where, f)
+			}
+		})
+		if err != nil {
+			_, _ = fmt.Fprintf(stdout, "%s: error: %v\n", file.name, err)
+			failed = true
+		}
+	}
+	if policies == 0 {
+		_, _ = fmt.Fprintln(stderr, "ctxforge validate: no policies found")
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// eachDocument calls fn with each non-empty document of a YAML or JSON
+// stream, numbered from 1.
+func eachDocument(in input, fn func(doc int, data []byte)) error {
+	r, err := in.open()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = r.Close() }()
+
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(r))
+	for doc := 1; ; doc++ {
+		data, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(data) > 0 {
+			fn(doc, data)
+		}
+	}
+}
+
+// validateDocument decodes a policy manifest and runs the policy webhooks'
+// defaulting and validation on it, then the checks the controllers report
+// in the policy status. It returns ok false for documents that aren't
+// policies.
+func validateDocument(data []byte, validator *webhookv1beta1.PolicyCustomValidator) (string, []finding, bool) {
+	var meta metav1.TypeMeta
+	if err := yaml.Unmarshal(data, &meta); err != nil {
+		return "<unknown>", []finding{{message: err.Error()}}, true
+	}
+	if meta.GroupVersionKind().Group != ctxforgev1beta1.GroupVersion.Group ||
+		(meta.Kind != "HeaderPropagationPolicy" && meta.Kind != "ClusterHeaderPropagationPolicy") {
+		return "", nil, false
+	}
+	name := meta.Kind
+
+	// The API server applies the CRD schema's defaults and checks, such as
+	// enums and patterns, before the webhooks see the object
+	var findings []finding
+	crdSchema, err := schemaFor(meta.GroupVersionKind())
+	if err != nil {
+		return name, []finding{{message: fmt.Sprintf("failed to load the CRD schemas: %v", err)}}, true
+	}
+	if crdSchema != nil {
+		content, err := toUnstructured(data)
+		if err != nil {
+			return name, []finding{{message: err.Error()}}, true
+		}
+		for _, fieldErr := range crdSchema.validate(content) {
+			findings = append(findings, finding{field: fieldErr.Field, message: fieldErr.ErrorBody()})
+		}
+		if data, err = json.Marshal(content); err != nil {
+			return name, append(findings, finding{message: err.Error()}), true
+		}
+	}
+
+	obj, _, err := codecs.UniversalDeserializer().Decode(data, nil, nil)
+	if strictErr, ok := runtime.AsStrictDecodingError(err); ok {
+		// The object still decoded, so the remaining checks run too
+		for _, fieldErr := range strictErr.Errors() {
+			findings = append(findings, finding{message: fieldErr.Error()})
+		}
+	} else if err != nil {
+		return name, []finding{{message: err.Error()}}, true
+	}
+
+	hub, err := toHub(obj)
+	if err != nil {
+		return name, append(findings, finding{message: err.Error()}), true
+	}
+	spec := specOf(hub)
+	name = meta.Kind + "/" + hub.(metav1.Object).GetName()
+
+	ctx := context.Background()
+	if err := (&webhookv1beta1.PolicyCustomDefaulter{}).Default(ctx, hub); err != nil {
+		return name, append(findings, finding{message: err.Error()}), true
+	}
+	warnings, invalid := validator.ValidateCreate(ctx, hub)
+	for _, warning := range warnings {
+		findings = append(findings, finding{warning: true, message: warning})
+	}
+	findings = append(findings, causes(invalid)...)
+
+	selectors := []struct {
+		field    string
+		selector *metav1.LabelSelector
+	}{
+		{"spec.podSelector", spec.PodSelector},
+		{"spec.namespaceSelector", spec.NamespaceSelector},
+		{"spec.workloadSelector", spec.WorkloadSelector},
+		{"spec.routeSelector", spec.RouteSelector},
+	}
+	for _, s := range selectors {
+		if _, err := ctxforgepolicy.Selector(s.selector); err != nil {
+			findings = append(findings, finding{field: s.field, message: err.Error()})
+		}
+	}
+	// Rule errors repeat what the webhook rejected, so only policies it
+	// admitted are parsed as the proxy would
+	if invalid == nil {
+		for _, ruleErr := range ctxforgepolicy.RuleErrors(*spec) {
+			field := fmt.Sprintf("spec.propagationRules[%d]", ruleErr.Rule)
+			if ruleErr.Response {
+				field = fmt.Sprintf("spec.responseRules[%d]", ruleErr.Rule)
+			}
+			findings = append(findings, finding{field: field, message: ruleErr.Message})
+		}
+	}
+	return name, findings, true
+}
+
+// toUnstructured decodes a YAML or JSON manifest the way the API server
+// does, with integers kept as int64.
+func toUnstructured(data []byte) (map[string]any, error) {
+	jsonData, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	var content map[string]any
+	if err := utiljson.Unmarshal(jsonData, &content); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// toHub converts a decoded policy to v1beta1, the version the webhooks and
+// controllers work with.
+func toHub(obj runtime.Object) (runtime.Object, error) {
+	switch p := obj.(type) {
+	case *ctxforgev1beta1.HeaderPropagationPolicy, *ctxforgev1beta1.ClusterHeaderPropagationPolicy:
+		return p, nil
+	case *ctxforgev1alpha1.HeaderPropagationPolicy:
+		hub := &ctxforgev1beta1.HeaderPropagationPolicy{}
+		return hub, p.ConvertTo(hub)
+	case *ctxforgev1alpha1.ClusterHeaderPropagationPolicy:
+		hub := &ctxforgev1beta1.ClusterHeaderPropagationPolicy{}
+		return hub, p.ConvertTo(hub)
+	default:
+		return nil, fmt.Errorf("unsupported object %T", obj)
+	}
+}
+
+func specOf(hub runtime.Object) *ctxforgev1beta1.HeaderPropagationPolicySpec {
+	if p, ok := hub.(*ctxforgev1beta1.ClusterHeaderPropagationPolicy); ok {
+		return &p.Spec
+	}
+	return &hub.(*ctxforgev1beta1.HeaderPropagationPolicy).Spec
+}
+
+// causes splits a webhook error into one finding per invalid field.
+func causes(err error) []finding {
+	if err == nil {
+		return nil
+	}
+	var status apierrors.APIStatus
+	if !errors.As(err, &status) || status.Status().Details == nil || len(status.Status().Details.Causes) == 0 {
+		return []finding{{message: err.Error()}}
+	}
+	var findings []finding
+	for _, cause := range status.Status().Details.Causes {
+		findings = append(findings, finding{field: cause.Field, message: cause.Message})
+	}
+	return findings
+}
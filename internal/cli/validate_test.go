@@ -0,0 +1,200 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const validPolicy = `apiVersion: ctxforge.ctxforge.io/v1beta1
+kind: HeaderPropagationPolicy
+metadata:
+  name: tracing
+spec:
+  podSelector:
+    matchLabels:
+      app: api
+  propagationRules:
+  - headers:
+    - name: X-Request-Id
+      generate: true
+`
+
+func runCLI(t *testing.T, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := Run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		args     []string
+		code     int
+		expected []string
+	}{
+		{
+			name:     "valid policy",
+			manifest: validPolicy,
+			expected: []string{"<stdin>:1 HeaderPropagationPolicy/tracing: valid"},
+		},
+		{
+			name: "v1alpha1 policy is converted",
+			manifest: `apiVersion: ctxforge.ctxforge.io/v1alpha1
+kind: ClusterHeaderPropagationPolicy
+metadata:
+  name: platform
+spec:
+  propagationRules:
+  - headers:
+    - name: x-tenant-id
+`,
+			expected: []string{"ClusterHeaderPropagationPolicy/platform: valid"},
+		},
+		{
+			name: "webhook errors are reported per field",
+			manifest: `apiVersion: ctxforge.ctxforge.io/v1beta1
+kind: HeaderPropagationPolicy
+metadata:
+  name: broken
+spec:
+  propagationRules:
+  - pathRegex: "("
+    methods: [FETCH]
+    headers:
+    - name: x-request-id
+`,
+			code: 1,
+			expected: []string{
+				"HeaderPropagationPolicy/broken: error: spec.propagationRules[0].pathRegex: Invalid value",
+				"error: spec.propagationRules[0].methods[0]: Unsupported value",
+			},
+		},
+		{
+			name: "CRD schema violations",
+			manifest: `apiVersion: ctxforge.ctxforge.io/v1beta1
+kind: HeaderPropagationPolicy
+metadata:
+  name: schema
+spec:
+  propagationRules:
+  - headers:
+    - name: x-request-id
+      generatorType: bogus
+`,
+			code: 1,
+			expected: []string{
+				`HeaderPropagationPolicy/schema: error: spec.propagationRules[0].headers[0].generatorType: Unsupported value: "bogus"`,
+			},
+		},
+		{
+			name: "unknown fields and bad selectors",
+			manifest: `apiVersion: ctxforge.ctxforge.io/v1beta1
+kind: HeaderPropagationPolicy
+metadata:
+  name: typo
+spec:
+  podSelecter: {}
+  podSelector:
+    matchExpressions:
+    - {key: app, operator: Bogus}
+`,
+			code: 1,
+			expected: []string{
+				`error: unknown field "spec.podSelecter"`,
+				`error: spec.podSelector: "Bogus" is not a valid label selector operator`,
+			},
+		},
+		{
+			name: "credential headers only warn",
+			manifest: `apiVersion: ctxforge.ctxforge.io/v1beta1
+kind: HeaderPropagationPolicy
+metadata:
+  name: creds
+spec:
+  propagationRules:
+  - headers:
+    - name: Authorization
+`,
+			expected: []string{"HeaderPropagationPolicy/creds: warning:"},
+		},
+		{
+			name: "sidecar image needs the allowlist",
+			manifest: validPolicy + `  sidecar:
+    image: ghcr.io/example/proxy:v1
+`,
+			code:     1,
+			expected: []string{"error: spec.sidecar.image: Forbidden"},
+		},
+		{
+			name: "allowlisted sidecar image",
+			manifest: validPolicy + `  sidecar:
+    image: ghcr.io/example/proxy:v1
+`,
+			args:     []string{"-image-allowlist", "ghcr.io/example/proxy"},
+			expected: []string{"HeaderPropagationPolicy/tracing: valid"},
+		},
+		{
+			name:     "other kinds are skipped",
+			manifest: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cm\n---\n" + validPolicy,
+			expected: []string{"<stdin>:2 HeaderPropagationPolicy/tracing: valid"},
+		},
+		{
+			name:     "malformed documents fail",
+			manifest: "kind: [HeaderPropagationPolicy\n",
+			code:     1,
+			expected: []string{"<stdin>:1 <unknown>: error:"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, stdout, _ := runCLI(t, tt.manifest, append([]string{"validate"}, tt.args...)...)
+			assert.Equal(t, tt.code, code, stdout)
+			for _, expected := range tt.expected {
+				assert.Contains(t, stdout, expected)
+			}
+		})
+	}
+}
+
+func TestValidate_Paths(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "nested"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "policy.yaml"), []byte(validPolicy), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "nested", "other.yml"),
+		[]byte(strings.Replace(validPolicy, "name: tracing", "name: nested", 1)), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a manifest"), 0o600))
+
+	code, stdout, _ := runCLI(t, "", "validate", "-quiet", dir)
+	assert.Equal(t, 0, code)
+	assert.Empty(t, stdout, "-quiet omits valid policies")
+
+	code, stdout, _ = runCLI(t, "", "validate", dir)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout, filepath.Join(dir, "policy.yaml")+":1 HeaderPropagationPolicy/tracing: valid")
+	assert.Contains(t, stdout, filepath.Join(dir, "nested", "other.yml")+":1 HeaderPropagationPolicy/nested: valid")
+
+	code, _, stderr := runCLI(t, "", "validate", filepath.Join(dir, "missing.yaml"))
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "no such file")
+}
+
+func TestRun_Usage(t *testing.T) {
+	code, _, stderr := runCLI(t, "")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "validate")
+
+	code, _, stderr = runCLI(t, "", "lint")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, `unknown command "lint"`)
+
+	code, _, _ = runCLI(t, "", "validate", "-h")
+	assert.Equal(t, 0, code)
+}
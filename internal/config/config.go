@@ -2,10 +2,19 @@
 package config
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
+	mathrand "math/rand/v2"
+	"net"
+	"net/http"
 	"os"
+	"path/filepath"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -30,11 +39,245 @@ type HeaderRule struct {
 	// PathRegex is an optional regex pattern to match request paths.
 	PathRegex string `json:"pathRegex,omitempty"`
 
+	// ExcludePaths are regex patterns of request paths the rule doesn't
+	// apply to, even when they match PathRegex.
+	ExcludePaths []string `json:"excludePaths,omitempty"`
+
 	// Methods is an optional list of HTTP methods this rule applies to.
 	Methods []string `json:"methods,omitempty"`
 
+	// Rename propagates the header under a different name, replacing the
+	// original on the forwarded request.
+	Rename string `json:"rename,omitempty"`
+
+	// StaticValue is propagated when the request doesn't carry the header.
+	StaticValue string `json:"staticValue,omitempty"`
+
+	// Transform rewrites the propagated value.
+	Transform *Transform `json:"transform,omitempty"`
+
+	// OnExisting decides what a generated or static value does to a value
+	// the request already carries (default: preserve).
+	OnExisting OnExisting `json:"onExisting,omitempty"`
+
+	// Destinations restricts the hosts the header is propagated to.
+	Destinations *Destinations `json:"destinations,omitempty"`
+
+	// Sampling propagates the header for a share of requests only.
+	Sampling *Sampling `json:"sampling,omitempty"`
+
+	// Response makes this a response rule: instead of being propagated, the
+	// header is echoed into or stripped from the response.
+	Response ResponseAction `json:"response,omitempty"`
+
 	// CompiledPathRegex is the compiled path regex (set after validation).
 	CompiledPathRegex *regexp.Regexp `json:"-"`
+
+	// CompiledExcludePaths are the compiled ExcludePaths (set after validation).
+	CompiledExcludePaths []*regexp.Regexp `json:"-"`
+}
+
+// TransformType is a rewrite applied to a propagated header value.
+type TransformType string
+
+const (
+	// TransformHash replaces the value with its hex-encoded SHA-256 digest.
+	TransformHash TransformType = "hash"
+	// TransformTruncate keeps the first Length bytes of the value.
+	TransformTruncate TransformType = "truncate"
+	// TransformLowercase lowercases the value.
+	TransformLowercase TransformType = "lowercase"
+	// TransformUppercase uppercases the value.
+	TransformUppercase TransformType = "uppercase"
+)
+
+// OnExisting is how a rule's own value combines with one the request carries.
+type OnExisting string
+
+const (
+	// OnExistingPreserve keeps the request's value; the rule's value is only
+	// used when the header is missing.
+	OnExistingPreserve OnExisting = "preserve"
+	// OnExistingOverride replaces the request's value with the rule's.
+	OnExistingOverride OnExisting = "override"
+	// OnExistingAppend appends the rule's value to the request's, separated
+	// by a comma.
+	OnExistingAppend OnExisting = "append"
+)
+
+// ResponseAction is what a response rule does to its header.
+type ResponseAction string
+
+const (
+	// ResponseEcho copies the request's header, as forwarded to the
+	// application, into the response.
+	ResponseEcho ResponseAction = "echo"
+	// ResponseStrip removes the header from the response.
+	ResponseStrip ResponseAction = "strip"
+)
+
+// Destinations restricts the hosts, as addressed by a request's Host, a header
+// is propagated to. Patterns are a host name, "*.domain" for its subdomains,
+// or "*" for every host.
+type Destinations struct {
+	// Allow lists the hosts the header may be propagated to; empty allows
+	// every host that isn't denied.
+	Allow []string `json:"allow,omitempty"`
+
+	// Deny lists hosts the header is never propagated to, overriding Allow.
+	Deny []string `json:"deny,omitempty"`
+}
+
+// Allows reports whether a header may be propagated to host, which may carry
+// a port.
+func (d *Destinations) Allows(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, pattern := range d.Deny {
+		if matchHost(pattern, host) {
+			return false
+		}
+	}
+	if len(d.Allow) == 0 {
+		return true
+	}
+	for _, pattern := range d.Allow {
+		if matchHost(pattern, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsExplicitly reports whether host matches one of the Allow patterns and
+// none of the Deny patterns. Unlike Allows, an empty Allow list matches nothing.
+func (d *Destinations) AllowsExplicitly(host string) bool {
+	return len(d.Allow) > 0 && d.Allows(host)
+}
+
+// matchHost reports whether host matches a Destinations pattern.
+func matchHost(pattern, host string) bool {
+	pattern = strings.ToLower(pattern)
+	if pattern == "*" {
+		return true
+	}
+	if suffix, ok := strings.CutPrefix(pattern, "*"); ok {
+		return strings.HasSuffix(host, suffix)
+	}
+	return host == pattern
+}
+
+// destinationPattern matches a host name, optionally prefixed with "*.", or "*".
+var destinationPattern = regexp.MustCompile(`^(\*|(\*\.)?[a-zA-Z0-9]([a-zA-Z0-9.-]*[a-zA-Z0-9])?)$`)
+
+// validate checks the destination patterns.
+func (d *Destinations) validate() error {
+	for _, pattern := range append(append([]string(nil), d.Allow...), d.Deny...) {
+		if !destinationPattern.MatchString(pattern) {
+			return fmt.Errorf("invalid destination %q (must be a host name, *.domain or *)", pattern)
+		}
+	}
+	return nil
+}
+
+// Sampling propagates headers for a percentage of requests. Rules with the
+// same sampling configuration share the decision for a request.
+type Sampling struct {
+	// Percentage is the share of requests, from 0 to 100, the header is
+	// propagated for.
+	Percentage int `json:"percentage"`
+
+	// KeyHeader makes the decision deterministic: requests with the same
+	// value of this header, such as a trace ID, are sampled alike by every
+	// proxy. Requests without it are sampled at random.
+	KeyHeader string `json:"keyHeader,omitempty"`
+}
+
+// Sampled reports whether a request whose KeyHeader value is key is sampled.
+func (s *Sampling) Sampled(key string) bool {
+	switch {
+	case s.Percentage >= 100:
+		return true
+	case s.Percentage <= 0:
+		return false
+	}
+	var n uint32
+	if key != "" {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(key))
+		n = h.Sum32()
+	} else {
+		n = mathrand.Uint32()
+	}
+	return n%100 < uint32(s.Percentage)
+}
+
+// validate checks the percentage and key header.
+func (s *Sampling) validate() error {
+	if s.Percentage < 0 || s.Percentage > 100 {
+		return fmt.Errorf("sampling percentage %d must be between 0 and 100", s.Percentage)
+	}
+	if s.KeyHeader != "" {
+		if err := validateHeaderName(s.KeyHeader); err != nil {
+			return fmt.Errorf("sampling keyHeader: %w", err)
+		}
+	}
+	return nil
+}
+
+// Transform rewrites a propagated header value.
+type Transform struct {
+	// Type is the rewrite to apply.
+	Type TransformType `json:"type"`
+
+	// Length is the number of bytes truncate keeps. For hash it optionally
+	// shortens the hex digest.
+	Length int `json:"length,omitempty"`
+}
+
+// Apply returns value rewritten by the transform.
+func (t *Transform) Apply(value string) string {
+	switch t.Type {
+	case TransformHash:
+		sum := sha256.Sum256([]byte(value))
+		value = hex.EncodeToString(sum[:])
+	case TransformLowercase:
+		return strings.ToLower(value)
+	case TransformUppercase:
+		return strings.ToUpper(value)
+	}
+	if t.Length > 0 && len(value) > t.Length {
+		value = value[:t.Length]
+	}
+	return value
+}
+
+// validate checks that the transform is known and has a usable length.
+func (t *Transform) validate() error {
+	switch t.Type {
+	case TransformHash, TransformLowercase, TransformUppercase:
+	case TransformTruncate:
+		if t.Length <= 0 {
+			return fmt.Errorf("transform truncate requires a positive length")
+		}
+	default:
+		return fmt.Errorf("unknown transform type %q (must be one of %s, %s, %s, %s)",
+			t.Type, TransformHash, TransformTruncate, TransformLowercase, TransformUppercase)
+	}
+	if t.Length < 0 {
+		return fmt.Errorf("transform length must not be negative")
+	}
+	return nil
+}
+
+// PropagatedName returns the name the header is propagated under.
+func (r *HeaderRule) PropagatedName() string {
+	if r.Rename != "" {
+		return r.Rename
+	}
+	return r.Name
 }
 
 // MatchesRequest checks if this rule applies to the given request path and method.
@@ -45,6 +288,11 @@ func (r *HeaderRule) MatchesRequest(path, method string) bool {
 			return false
 		}
 	}
+	for _, exclude := range r.CompiledExcludePaths {
+		if exclude.MatchString(path) {
+			return false
+		}
+	}
 
 	// Check methods if specified
 	if len(r.Methods) > 0 {
@@ -73,12 +321,99 @@ type ProxyConfig struct {
 	// HeaderRules defines header propagation rules with generation and filtering options.
 	HeaderRules []HeaderRule
 
+	// SecureMode selects a security profile. SecureModeStrict rejects settings
+	// that weaken the proxy's defaults, redacts every logged header value but
+	// the request and tenant IDs, and requires a token on the admin endpoints
+	// even for local callers.
+	SecureMode string
+
+	// UnsafePropagateCredentials lets rules propagate CredentialHeaders,
+	// which are otherwise ignored.
+	UnsafePropagateCredentials bool
+
+	// RedactHeaders lists headers, in addition to the built-in credential and
+	// token headers, whose values are replaced in logs, the request log and the
+	// audit log.
+	RedactHeaders []string
+
+	// InClusterOnly withholds propagated headers from hosts outside the
+	// cluster unless the header's Destinations explicitly allow the host.
+	InClusterOnly bool
+
+	// SPIFFEEndpointSocket is the address of the SPIFFE Workload API, e.g.
+	// unix:///spiffe-workload-api/spire-agent.sock. When set, the proxy adds
+	// its SPIFFE ID to outbound requests as SPIFFEIDHeader.
+	SPIFFEEndpointSocket string
+
+	// SPIFFEIDHeader is the header carrying the proxy's SPIFFE ID.
+	SPIFFEIDHeader string
+
+	// SPIFFEMTLS makes the proxy present its SVID as client certificate to a
+	// TLS target, and verify the target's SVID instead of its host name.
+	SPIFFEMTLS bool
+
+	// ContextSigningKeyFile holds the HMAC keys, one per line, shared by the
+	// proxies of a mesh. The proxy signs the context headers it forwards with
+	// the first key, as ContextSignatureHeader, and accepts inbound signatures
+	// made with any of them, so keys can be rotated.
+	ContextSigningKeyFile string
+
+	// ContextSigningKeys are the keys read from ContextSigningKeyFile.
+	ContextSigningKeys [][]byte
+
+	// ContextSignatureHeader carries the signature of the context headers.
+	ContextSignatureHeader string
+
+	// UnsignedContext is what the proxy does with inbound context headers
+	// that no valid signature covers: UnsignedContextAllow forwards them,
+	// UnsignedContextStrip removes them and UnsignedContextReject refuses the
+	// request.
+	UnsignedContext string
+
+	// ClusterDomains are the domain suffixes of in-cluster host names.
+	ClusterDomains []string
+
+	// ClusterCIDRs are the networks of in-cluster IP addresses.
+	ClusterCIDRs []string
+
 	// TargetHost is the address of the application container to forward requests to.
 	TargetHost string
 
 	// ProxyPort is the port the proxy listens on for incoming requests.
 	ProxyPort int
 
+	// ConfigChecksum identifies the header configuration the proxy loaded; see
+	// the ConfigChecksum function.
+	ConfigChecksum string
+
+	// LiveConfigFile is a downward API file with the pod's annotations. Header
+	// rules in its LiveHeaderRulesAnnotation replace the configured ones at runtime.
+	LiveConfigFile string
+
+	// RulesFile holds header rules in HEADER_RULES format, such as the proxy's
+	// key of the operator's rules ConfigMap. They replace the configured rules
+	// at runtime; a missing or empty file restores them.
+	RulesFile string
+
+	// RuleStreamAddress is the operator's rule stream service (host:port).
+	// The proxy subscribes to it as PodNamespace/PodName and applies the
+	// header rules it receives at runtime.
+	RuleStreamAddress string
+
+	// PodNamespace identifies the proxy's pod to the rule stream.
+	PodNamespace string
+
+	// TargetTLS forwards requests to the target application over HTTPS.
+	TargetTLS bool
+
+	// TargetCAFile is a PEM bundle trusted, in addition to the system roots,
+	// when verifying the target's certificate.
+	TargetCAFile string
+
+	// TargetServerName overrides the name the target's certificate is verified
+	// against; by default the host of TargetHost.
+	TargetServerName string
+
 	// LogLevel defines the logging verbosity (debug, info, warn, error).
 	LogLevel string
 
@@ -100,6 +435,14 @@ type ProxyConfig struct {
 	// TargetDialTimeout is the timeout for dialing the target application.
 	TargetDialTimeout time.Duration
 
+	// MaxHeaderCount is the number of header fields an inbound request may
+	// carry. Zero disables the limit.
+	MaxHeaderCount int
+
+	// MaxHeaderBytes bounds the total size of an inbound request's header
+	// fields, names and values included. Zero disables the limit.
+	MaxHeaderBytes int
+
 	// RateLimitEnabled enables rate limiting middleware.
 	RateLimitEnabled bool
 
@@ -108,8 +451,202 @@ type ProxyConfig struct {
 
 	// RateLimitBurst is the maximum burst size for rate limiting.
 	RateLimitBurst int
+
+	// SlowRequestThreshold is the latency above which a request is logged at warn level
+	// together with its propagated headers. Zero disables slow-request logging.
+	SlowRequestThreshold time.Duration
+
+	// DebugRequestBufferSize is the number of recent requests kept for the
+	// /debug/requests admin endpoint. Zero disables the buffer.
+	DebugRequestBufferSize int
+
+	// DebugEchoEnabled lets callers send "X-Ctxforge-Debug: true" to receive response
+	// headers describing which rules matched and which headers were propagated.
+	DebugEchoEnabled bool
+
+	// PprofEnabled exposes the net/http/pprof handlers on the admin port.
+	PprofEnabled bool
+
+	// AuditLog is the destination of the header mutation audit log: "stdout",
+	// "stderr" or a file path. Empty disables auditing.
+	AuditLog string
+
+	// OTelMetricsEndpoint is the OTLP gRPC collector endpoint metrics are pushed to.
+	// Empty disables pushing; metrics remain available on /metrics.
+	OTelMetricsEndpoint string
+
+	// OTelMetricsInterval is how often metrics are pushed to the OTLP collector.
+	OTelMetricsInterval time.Duration
+
+	// OTelMetricsInsecure disables TLS for a host:port OTLP endpoint.
+	OTelMetricsInsecure bool
+
+	// StatsDAddress is the host:port of a StatsD/DogStatsD agent metrics are sent to.
+	// Empty disables the StatsD sink.
+	StatsDAddress string
+
+	// StatsDFlavor selects how labels are encoded: "dogstatsd" tags or plain "statsd" names.
+	StatsDFlavor string
+
+	// StatsDInterval is how often metrics are flushed to the StatsD agent.
+	StatsDInterval time.Duration
+
+	// PushgatewayURL is the Prometheus Pushgateway metrics are pushed to on shutdown.
+	// Empty disables pushing.
+	PushgatewayURL string
+
+	// PushgatewayJob is the job label used for the pushed metrics.
+	PushgatewayJob string
+
+	// PodName identifies this proxy instance in pushed metrics.
+	PodName string
+
+	// PathMetricsEnabled records request metrics labeled with the normalized path.
+	PathMetricsEnabled bool
+
+	// PathTemplates are the templates request paths are normalized to, e.g. /api/users/:id.
+	PathTemplates []string
+
+	// PathTemplatesStrict reports paths matching no template as "other" instead of
+	// collapsing identifier-like segments.
+	PathTemplatesStrict bool
+
+	// RequestIDHeader is the header whose value is attached to every log line and
+	// audit event of a request as request_id.
+	RequestIDHeader string
+
+	// TenantIDHeader is an optional header whose value is attached to every log line
+	// of a request as tenant_id.
+	TenantIDHeader string
+
+	// AdminAuthToken is the bearer token required by protected admin endpoints.
+	AdminAuthToken string
+
+	// AdminAuthTokenFile is a file shared with the app containers that holds the
+	// admin token. The proxy generates the token when the file doesn't exist yet.
+	// It takes precedence over AdminAuthToken.
+	AdminAuthTokenFile string
+
+	// AdminOperatorToken is a bearer token that only grants access to /config,
+	// which the operator polls for the config sync readiness gate.
+	AdminOperatorToken string
+
+	// DrainDelay is how long /drain keeps serving before waiting for in-flight
+	// requests, giving endpoint controllers time to stop sending new traffic.
+	DrainDelay time.Duration
+
+	// DrainTimeout bounds how long /drain waits for in-flight requests to finish.
+	DrainTimeout time.Duration
+
+	// ExitOnAppExit stops the proxy once the app processes in a shared process
+	// namespace have exited, so Job pods can complete.
+	ExitOnAppExit bool
+}
+
+// DefaultSPIFFEIDHeader carries the proxy's SPIFFE ID unless SPIFFE_ID_HEADER
+// names another header.
+const DefaultSPIFFEIDHeader = "X-Spiffe-Id"
+
+// DefaultContextSignatureHeader carries the signature of the context headers
+// unless CONTEXT_SIGNATURE_HEADER names another header.
+const DefaultContextSignatureHeader = "X-Ctxforge-Signature"
+
+// UNSIGNED_CONTEXT values.
+const (
+	UnsignedContextAllow  = "allow"
+	UnsignedContextStrip  = "strip"
+	UnsignedContextReject = "reject"
+)
+
+// minContextSigningKeyLength is the shortest HMAC key accepted, in bytes.
+const minContextSigningKeyLength = 32
+
+// SecureModeStrict is the SECURE_MODE value enabling the strict profile.
+const SecureModeStrict = "strict"
+
+// DefaultRequestIDHeader is the header used to correlate proxy logs with application logs.
+const DefaultRequestIDHeader = "X-Request-Id"
+
+// CredentialHeaders are never propagated unless UnsafePropagateCredentials is
+// set: a rule listing one of them by mistake would hand the caller's
+// credentials to every service downstream.
+var CredentialHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "Proxy-Authorization", "X-Api-Key"}
+
+// IsCredentialHeader reports whether name is one of CredentialHeaders,
+// compared case-insensitively.
+func IsCredentialHeader(name string) bool {
+	return slices.Contains(CredentialHeaders, http.CanonicalHeaderKey(strings.TrimSpace(name)))
+}
+
+// DefaultClusterDomains match the fully and partially qualified names of
+// Kubernetes services. Single-label names, such as a service in the same
+// namespace, are always in-cluster.
+var DefaultClusterDomains = []string{"svc", "cluster.local"}
+
+// DefaultClusterCIDRs are the private, shared and loopback address ranges pod
+// and service networks are allocated from.
+var DefaultClusterCIDRs = []string{
+	"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "100.64.0.0/10", "127.0.0.0/8", "fc00::/7", "::1/128",
+}
+
+// ClusterHosts recognizes in-cluster destinations by host name or address.
+type ClusterHosts struct {
+	domains  []string
+	networks []*net.IPNet
+}
+
+// NewClusterHosts returns ClusterHosts matching names under domains and
+// addresses in cidrs.
+func NewClusterHosts(domains, cidrs []string) (*ClusterHosts, error) {
+	c := &ClusterHosts{}
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.Trim(strings.TrimSpace(domain), "."))
+		if !destinationPattern.MatchString(domain) || strings.Contains(domain, "*") {
+			return nil, fmt.Errorf("invalid cluster domain %q (must be a domain name, e.g., cluster.local)", domain)
+		}
+		c.domains = append(c.domains, domain)
+	}
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, fmt.Errorf("invalid cluster CIDR %q (e.g., 10.0.0.0/8)", cidr)
+		}
+		c.networks = append(c.networks, network)
+	}
+	return c, nil
+}
+
+// Contains reports whether host, which may carry a port, is in the cluster.
+// Host names aren't resolved: an address is only recognized when the request
+// is addressed to it.
+func (c *ClusterHosts) Contains(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.TrimSuffix(strings.Trim(host, "[]"), "."))
+	if ip := net.ParseIP(host); ip != nil {
+		for _, network := range c.networks {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+	if host == "localhost" || !strings.Contains(host, ".") {
+		return true
+	}
+	for _, domain := range c.domains {
+		if strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
 }
 
+// LiveHeaderRulesAnnotation is the pod annotation through which the operator
+// pushes updated header rules, in HEADER_RULES format, to a running proxy.
+const LiveHeaderRulesAnnotation = "ctxforge.io/live-header-rules"
+
 // Default timeout values with rationale:
 //
 // ReadTimeout (15s): Maximum time to read the entire request including body.
@@ -137,12 +674,41 @@ const (
 	defaultTargetDialTimeout = 5 * time.Second
 )
 
+// Header limits match Envoy's defaults: far below Go's 1 MiB, which lets a
+// single request make a small app container parse and log a megabyte of
+// headers, yet above what browsers and service meshes send.
+const (
+	defaultMaxHeaderCount = 100
+	defaultMaxHeaderBytes = 60 << 10
+)
+
+// defaultDebugRequestBufferSize keeps enough history to inspect a burst of
+// traffic while holding only a few hundred kilobytes of memory.
+const defaultDebugRequestBufferSize = 100
+
+// defaultOTelMetricsInterval matches the default Prometheus scrape interval so
+// dashboards behave the same regardless of how metrics are collected.
+const defaultOTelMetricsInterval = 30 * time.Second
+
+// defaultStatsDInterval matches the flush interval of the Datadog agent.
+const defaultStatsDInterval = 10 * time.Second
+
+// Drain defaults fit in the default 30s termination grace period: a few
+// seconds for endpoint removal to propagate, then up to 20s of in-flight work.
+const (
+	defaultDrainDelay   = 5 * time.Second
+	defaultDrainTimeout = 20 * time.Second
+)
+
 // Load reads configuration from environment variables and returns a ProxyConfig.
 // Returns an error if required configuration is missing or invalid.
 func Load() (*ProxyConfig, error) {
 	cfg := &ProxyConfig{
 		TargetHost:        getEnv("TARGET_HOST", "localhost:8080"),
 		ProxyPort:         getEnvInt("PROXY_PORT", 9090),
+		TargetTLS:         getEnvBool("TARGET_TLS", false),
+		TargetCAFile:      getEnv("TARGET_CA_FILE", ""),
+		TargetServerName:  getEnv("TARGET_TLS_SERVER_NAME", ""),
 		LogLevel:          getEnv("LOG_LEVEL", "info"),
 		MetricsPort:       getEnvInt("METRICS_PORT", 9091),
 		ReadTimeout:       getEnvDuration("READ_TIMEOUT", defaultReadTimeout),
@@ -150,9 +716,60 @@ func Load() (*ProxyConfig, error) {
 		IdleTimeout:       getEnvDuration("IDLE_TIMEOUT", defaultIdleTimeout),
 		ReadHeaderTimeout: getEnvDuration("READ_HEADER_TIMEOUT", defaultReadHeaderTimeout),
 		TargetDialTimeout: getEnvDuration("TARGET_DIAL_TIMEOUT", defaultTargetDialTimeout),
+		MaxHeaderCount:    getEnvInt("MAX_HEADER_COUNT", defaultMaxHeaderCount),
+		MaxHeaderBytes:    getEnvInt("MAX_HEADER_BYTES", defaultMaxHeaderBytes),
 		RateLimitEnabled:  getEnvBool("RATE_LIMIT_ENABLED", false),
 		RateLimitRPS:      getEnvFloat("RATE_LIMIT_RPS", 1000),
 		RateLimitBurst:    getEnvInt("RATE_LIMIT_BURST", 100),
+
+		SlowRequestThreshold:   getEnvDuration("SLOW_REQUEST_THRESHOLD", 0),
+		DebugRequestBufferSize: getEnvInt("DEBUG_REQUEST_BUFFER_SIZE", defaultDebugRequestBufferSize),
+		DebugEchoEnabled:       getEnvBool("DEBUG_ECHO_ENABLED", false),
+		PprofEnabled:           getEnvBool("PPROF_ENABLED", false),
+		AdminAuthToken:         getEnv("ADMIN_AUTH_TOKEN", ""),
+		AdminAuthTokenFile:     getEnv("ADMIN_AUTH_TOKEN_FILE", ""),
+		AdminOperatorToken:     getEnv("ADMIN_OPERATOR_TOKEN", ""),
+		LiveConfigFile:         getEnv("LIVE_CONFIG_FILE", ""),
+		RulesFile:              getEnv("RULES_FILE", ""),
+		RuleStreamAddress:      getEnv("RULE_STREAM_ADDRESS", ""),
+		PodNamespace:           getEnv("POD_NAMESPACE", ""),
+		DrainDelay:             getEnvDuration("DRAIN_DELAY", defaultDrainDelay),
+		DrainTimeout:           getEnvDuration("DRAIN_TIMEOUT", defaultDrainTimeout),
+		ExitOnAppExit:          getEnvBool("EXIT_ON_APP_EXIT", false),
+		AuditLog:               getEnv("AUDIT_LOG", ""),
+		OTelMetricsEndpoint:    getEnv("OTEL_METRICS_ENDPOINT", ""),
+		OTelMetricsInterval:    getEnvDuration("OTEL_METRICS_INTERVAL", defaultOTelMetricsInterval),
+		OTelMetricsInsecure:    getEnvBool("OTEL_METRICS_INSECURE", false),
+		StatsDAddress:          getEnv("STATSD_ADDRESS", ""),
+		StatsDFlavor:           getEnv("STATSD_FLAVOR", "dogstatsd"),
+		StatsDInterval:         getEnvDuration("STATSD_INTERVAL", defaultStatsDInterval),
+		PushgatewayURL:         getEnv("PUSHGATEWAY_URL", ""),
+		PushgatewayJob:         getEnv("PUSHGATEWAY_JOB", "ctxforge-proxy"),
+		PodName:                getEnv("POD_NAME", getEnv("HOSTNAME", "")),
+		PathMetricsEnabled:     getEnvBool("PATH_METRICS_ENABLED", false),
+		PathTemplates:          getEnvList("PATH_TEMPLATES"),
+		PathTemplatesStrict:    getEnvBool("PATH_TEMPLATES_STRICT", false),
+		RequestIDHeader:        getEnv("REQUEST_ID_HEADER", DefaultRequestIDHeader),
+		TenantIDHeader:         getEnv("TENANT_ID_HEADER", ""),
+
+		SecureMode:                 strings.ToLower(getEnv("SECURE_MODE", "")),
+		UnsafePropagateCredentials: getEnvBool("UNSAFE_PROPAGATE_CREDENTIALS", false),
+		RedactHeaders:              getEnvList("REDACT_HEADERS"),
+		InClusterOnly:              getEnvBool("PROPAGATE_IN_CLUSTER_ONLY", true),
+		SPIFFEEndpointSocket:       getEnv("SPIFFE_ENDPOINT_SOCKET", ""),
+		SPIFFEIDHeader:             getEnv("SPIFFE_ID_HEADER", DefaultSPIFFEIDHeader),
+		SPIFFEMTLS:                 getEnvBool("SPIFFE_MTLS", false),
+		ContextSigningKeyFile:      getEnv("CONTEXT_SIGNING_KEY_FILE", ""),
+		ContextSignatureHeader:     getEnv("CONTEXT_SIGNATURE_HEADER", DefaultContextSignatureHeader),
+		UnsignedContext:            strings.ToLower(getEnv("UNSIGNED_CONTEXT", UnsignedContextAllow)),
+		ClusterDomains:             getEnvList("CLUSTER_DOMAINS"),
+		ClusterCIDRs:               getEnvList("CLUSTER_CIDRS"),
+	}
+	if cfg.ClusterDomains == nil {
+		cfg.ClusterDomains = DefaultClusterDomains
+	}
+	if cfg.ClusterCIDRs == nil {
+		cfg.ClusterCIDRs = DefaultClusterCIDRs
 	}
 
 	// Parse header rules - prefer HEADER_RULES over HEADERS_TO_PROPAGATE
@@ -161,14 +778,18 @@ func Load() (*ProxyConfig, error) {
 
 	if headerRulesStr != "" {
 		// Parse JSON header rules
-		rules, err := parseHeaderRules(headerRulesStr)
+		rules, err := ParseHeaderRules(headerRulesStr)
 		if err != nil {
 			return nil, fmt.Errorf("invalid HEADER_RULES: %w", err)
 		}
 		cfg.HeaderRules = rules
-		// Also populate HeadersToPropagate for backward compatibility
+		// Also populate HeadersToPropagate for backward compatibility, once per
+		// header even if several rules scope it to different paths
+		seen := make(map[string]bool)
 		for _, rule := range rules {
-			if rule.Propagate {
+			key := http.CanonicalHeaderKey(rule.Name)
+			if rule.Propagate && rule.Response == "" && !seen[key] {
+				seen[key] = true
 				cfg.HeadersToPropagate = append(cfg.HeadersToPropagate, rule.Name)
 			}
 		}
@@ -193,6 +814,23 @@ func Load() (*ProxyConfig, error) {
 	if len(cfg.HeaderRules) == 0 {
 		return nil, fmt.Errorf("at least one header must be specified (e.g., HEADERS_TO_PROPAGATE=x-request-id,x-correlation-id)")
 	}
+	cfg.ConfigChecksum = ConfigChecksum(headersStr, headerRulesStr)
+
+	if cfg.AdminAuthTokenFile != "" {
+		token, err := loadOrCreateAdminToken(cfg.AdminAuthTokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ADMIN_AUTH_TOKEN_FILE: %w", err)
+		}
+		cfg.AdminAuthToken = token
+	}
+
+	if cfg.ContextSigningKeyFile != "" {
+		keys, err := loadContextSigningKeys(cfg.ContextSigningKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CONTEXT_SIGNING_KEY_FILE: %w", err)
+		}
+		cfg.ContextSigningKeys = keys
+	}
 
 	if err := cfg.Validate(); err != nil {
 		return nil, fmt.Errorf("configuration validation failed: %w", err)
@@ -201,9 +839,78 @@ func Load() (*ProxyConfig, error) {
 	return cfg, nil
 }
 
-// parseHeaderRules parses a JSON array of header rules.
+// loadContextSigningKeys reads the HMAC keys stored at path, one per line.
+// Blank lines are skipped.
+func loadContextSigningKeys(path string) ([][]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var keys [][]byte
+	for _, line := range strings.Split(string(data), "\n") {
+		key := strings.TrimSpace(line)
+		if key == "" {
+			continue
+		}
+		if len(key) < minContextSigningKeyLength {
+			return nil, fmt.Errorf("%s: key %d is shorter than %d bytes", path, len(keys)+1, minContextSigningKeyLength)
+		}
+		keys = append(keys, []byte(key))
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%s holds no keys", path)
+	}
+	return keys, nil
+}
+
+// loadOrCreateAdminToken returns the token stored at path, generating and
+// writing a random one first if the file doesn't exist. The token survives proxy
+// restarts, so apps that already read it keep working. The file is written
+// atomically so apps never read a partial token.
+func loadOrCreateAdminToken(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		token := strings.TrimSpace(string(data))
+		if token == "" {
+			return "", fmt.Errorf("%s is empty", path)
+		}
+		return token, nil
+	}
+	if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generating token: %w", err)
+	}
+	token := hex.EncodeToString(buf)
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".admin-token-*")
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.WriteString(token); err != nil {
+		_ = tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	// Readable by app containers running as other users
+	if err := os.Chmod(tmp.Name(), 0o444); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// ParseHeaderRules parses a JSON array of header rules.
 // Format: [{"name":"x-request-id","generate":true,"generatorType":"uuid"},{"name":"x-tenant-id"}]
-func parseHeaderRules(input string) ([]HeaderRule, error) {
+func ParseHeaderRules(input string) ([]HeaderRule, error) {
 	var rules []HeaderRule
 	if err := json.Unmarshal([]byte(input), &rules); err != nil {
 		return nil, fmt.Errorf("invalid JSON: %w (expected format: [{\"name\":\"x-request-id\",\"generate\":true,\"generatorType\":\"uuid\"}])", err)
@@ -222,6 +929,58 @@ func parseHeaderRules(input string) ([]HeaderRule, error) {
 			rules[i].Propagate = true
 		}
 
+		switch rules[i].Response {
+		case "":
+		case ResponseEcho, ResponseStrip:
+			if rules[i].Generate || rules[i].Rename != "" || rules[i].StaticValue != "" || rules[i].Transform != nil ||
+				rules[i].Destinations != nil || rules[i].Sampling != nil || rules[i].OnExisting != "" {
+				return nil, fmt.Errorf("header %q: response rules support only name, pathRegex and methods", rules[i].Name)
+			}
+		default:
+			return nil, fmt.Errorf("header %q: unknown response action %q (must be %s or %s)",
+				rules[i].Name, rules[i].Response, ResponseEcho, ResponseStrip)
+		}
+
+		switch rules[i].OnExisting {
+		case "":
+			if rules[i].Response == "" {
+				rules[i].OnExisting = OnExistingPreserve
+			}
+		case OnExistingPreserve, OnExistingOverride, OnExistingAppend:
+		default:
+			return nil, fmt.Errorf("header %q: unknown onExisting %q (must be one of %s, %s, %s)",
+				rules[i].Name, rules[i].OnExisting, OnExistingPreserve, OnExistingOverride, OnExistingAppend)
+		}
+
+		if rules[i].Rename != "" {
+			if err := validateHeaderName(rules[i].Rename); err != nil {
+				return nil, fmt.Errorf("header %q: rename: %w", rules[i].Name, err)
+			}
+		}
+		if rules[i].StaticValue != "" {
+			if rules[i].Generate {
+				return nil, fmt.Errorf("header %q: staticValue and generate are mutually exclusive", rules[i].Name)
+			}
+			if strings.ContainsAny(rules[i].StaticValue, "\r\n\x00") {
+				return nil, fmt.Errorf("header %q: staticValue must not contain CR, LF or NUL", rules[i].Name)
+			}
+		}
+		if rules[i].Transform != nil {
+			if err := rules[i].Transform.validate(); err != nil {
+				return nil, fmt.Errorf("header %q: %w", rules[i].Name, err)
+			}
+		}
+		if rules[i].Destinations != nil {
+			if err := rules[i].Destinations.validate(); err != nil {
+				return nil, fmt.Errorf("header %q: %w", rules[i].Name, err)
+			}
+		}
+		if rules[i].Sampling != nil {
+			if err := rules[i].Sampling.validate(); err != nil {
+				return nil, fmt.Errorf("header %q: %w", rules[i].Name, err)
+			}
+		}
+
 		// Validate generator type if generation is enabled
 		if rules[i].Generate {
 			if rules[i].GeneratorType == "" {
@@ -240,6 +999,14 @@ func parseHeaderRules(input string) ([]HeaderRule, error) {
 			}
 			rules[i].CompiledPathRegex = compiled
 		}
+		rules[i].CompiledExcludePaths = nil
+		for _, exclude := range rules[i].ExcludePaths {
+			compiled, err := regexp.Compile(exclude)
+			if err != nil {
+				return nil, fmt.Errorf("header %q: invalid exclude path regex %q: %w", rules[i].Name, exclude, err)
+			}
+			rules[i].CompiledExcludePaths = append(rules[i].CompiledExcludePaths, compiled)
+		}
 
 		// Validate HTTP methods if specified
 		validMethods := map[string]bool{
@@ -300,7 +1067,122 @@ func (c *ProxyConfig) Validate() error {
 	if c.TargetDialTimeout <= 0 {
 		return fmt.Errorf("invalid target dial timeout: %v (must be positive, e.g., 2s)", c.TargetDialTimeout)
 	}
+	if c.MaxHeaderCount < 0 || c.MaxHeaderBytes < 0 {
+		return fmt.Errorf("invalid header limits: count %d, bytes %d (must be zero to disable or positive, e.g., MAX_HEADER_BYTES=61440)", c.MaxHeaderCount, c.MaxHeaderBytes)
+	}
+	if c.SlowRequestThreshold < 0 {
+		return fmt.Errorf("invalid slow request threshold: %v (must be zero to disable or positive, e.g., 1s)", c.SlowRequestThreshold)
+	}
+	if c.DebugRequestBufferSize < 0 {
+		return fmt.Errorf("invalid debug request buffer size: %d (must be zero to disable or positive, e.g., DEBUG_REQUEST_BUFFER_SIZE=100)", c.DebugRequestBufferSize)
+	}
+	if c.OTelMetricsEndpoint != "" && c.OTelMetricsInterval <= 0 {
+		return fmt.Errorf("invalid OTLP metrics interval: %v (must be positive, e.g., OTEL_METRICS_INTERVAL=30s)", c.OTelMetricsInterval)
+	}
+	if c.StatsDAddress != "" {
+		if c.StatsDFlavor != "dogstatsd" && c.StatsDFlavor != "statsd" {
+			return fmt.Errorf("invalid StatsD flavor: %s (must be dogstatsd or statsd)", c.StatsDFlavor)
+		}
+		if c.StatsDInterval <= 0 {
+			return fmt.Errorf("invalid StatsD interval: %v (must be positive, e.g., STATSD_INTERVAL=10s)", c.StatsDInterval)
+		}
+	}
+	if c.RuleStreamAddress != "" && (c.PodName == "" || c.PodNamespace == "") {
+		return fmt.Errorf("rule stream requires the pod's identity (set POD_NAME and POD_NAMESPACE when RULE_STREAM_ADDRESS is set)")
+	}
+	if c.PushgatewayURL != "" && c.PodName == "" {
+		return fmt.Errorf("pushgateway requires a pod name to group metrics (set POD_NAME when PUSHGATEWAY_URL is set)")
+	}
+	for _, template := range c.PathTemplates {
+		if !strings.HasPrefix(template, "/") {
+			return fmt.Errorf("invalid path template %q (must start with /, e.g., PATH_TEMPLATES=/api/users/:id)", template)
+		}
+	}
+	if err := validateHeaderName(c.RequestIDHeader); c.RequestIDHeader != "" && err != nil {
+		return fmt.Errorf("invalid REQUEST_ID_HEADER: %w", err)
+	}
+	if err := validateHeaderName(c.TenantIDHeader); c.TenantIDHeader != "" && err != nil {
+		return fmt.Errorf("invalid TENANT_ID_HEADER: %w", err)
+	}
+	for _, name := range c.RedactHeaders {
+		if err := validateHeaderName(name); err != nil {
+			return fmt.Errorf("invalid REDACT_HEADERS: %w", err)
+		}
+	}
+	if c.PprofEnabled && c.AdminAuthToken == "" {
+		return fmt.Errorf("pprof requires an admin auth token (set ADMIN_AUTH_TOKEN when PPROF_ENABLED=true)")
+	}
+	if !c.TargetTLS && (c.TargetCAFile != "" || c.TargetServerName != "") {
+		return fmt.Errorf("TARGET_CA_FILE and TARGET_TLS_SERVER_NAME require TARGET_TLS=true")
+	}
+	if c.DrainDelay < 0 || c.DrainTimeout < 0 {
+		return fmt.Errorf("invalid drain settings: delay %v, timeout %v (must not be negative, e.g., DRAIN_TIMEOUT=20s)", c.DrainDelay, c.DrainTimeout)
+	}
+	if _, err := NewClusterHosts(c.ClusterDomains, c.ClusterCIDRs); err != nil {
+		return err
+	}
+	if c.SPIFFEEndpointSocket != "" {
+		if !strings.HasPrefix(c.SPIFFEEndpointSocket, "unix:///") && !strings.HasPrefix(c.SPIFFEEndpointSocket, "tcp://") {
+			return fmt.Errorf("invalid SPIFFE_ENDPOINT_SOCKET: %s (must be unix:///path or tcp://ip:port, e.g., unix:///spiffe-workload-api/spire-agent.sock)", c.SPIFFEEndpointSocket)
+		}
+		if err := validateHeaderName(c.SPIFFEIDHeader); err != nil {
+			return fmt.Errorf("invalid SPIFFE_ID_HEADER: %w", err)
+		}
+	}
+	if c.SPIFFEMTLS {
+		if c.SPIFFEEndpointSocket == "" || !c.TargetTLS {
+			return fmt.Errorf("SPIFFE_MTLS requires SPIFFE_ENDPOINT_SOCKET and TARGET_TLS=true")
+		}
+		if c.TargetCAFile != "" || c.TargetServerName != "" {
+			return fmt.Errorf("SPIFFE_MTLS verifies the target's SVID; remove TARGET_CA_FILE and TARGET_TLS_SERVER_NAME")
+		}
+	}
+	switch c.UnsignedContext {
+	case "", UnsignedContextAllow:
+	case UnsignedContextStrip, UnsignedContextReject:
+		if len(c.ContextSigningKeys) == 0 {
+			return fmt.Errorf("UNSIGNED_CONTEXT=%s requires signing keys (set CONTEXT_SIGNING_KEY_FILE)", c.UnsignedContext)
+		}
+	default:
+		return fmt.Errorf("invalid UNSIGNED_CONTEXT: %s (must be %s, %s or %s)",
+			c.UnsignedContext, UnsignedContextAllow, UnsignedContextStrip, UnsignedContextReject)
+	}
+	if len(c.ContextSigningKeys) > 0 {
+		if err := validateHeaderName(c.ContextSignatureHeader); err != nil {
+			return fmt.Errorf("invalid CONTEXT_SIGNATURE_HEADER: %w", err)
+		}
+	}
+	if err := c.validateSecureMode(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Strict reports whether SECURE_MODE=strict is set.
+func (c *ProxyConfig) Strict() bool {
+	return c.SecureMode == SecureModeStrict
+}
 
+// validateSecureMode rejects the settings that turn off a protection the
+// strict profile guarantees.
+func (c *ProxyConfig) validateSecureMode() error {
+	switch c.SecureMode {
+	case "":
+		return nil
+	case SecureModeStrict:
+	default:
+		return fmt.Errorf("invalid secure mode: %s (must be empty or strict, e.g., SECURE_MODE=strict)", c.SecureMode)
+	}
+	if c.UnsafePropagateCredentials {
+		return fmt.Errorf("SECURE_MODE=strict forbids UNSAFE_PROPAGATE_CREDENTIALS=true")
+	}
+	if !c.InClusterOnly {
+		return fmt.Errorf("SECURE_MODE=strict forbids PROPAGATE_IN_CLUSTER_ONLY=false (allow external hosts per header with destinations instead)")
+	}
+	if c.MaxHeaderCount == 0 || c.MaxHeaderBytes == 0 {
+		return fmt.Errorf("SECURE_MODE=strict requires header limits (MAX_HEADER_COUNT and MAX_HEADER_BYTES must not be 0)")
+	}
 	return nil
 }
 
@@ -343,6 +1225,14 @@ func parseHeaders(input string) ([]string, error) {
 	return headers, nil
 }
 
+// ConfigChecksum returns a checksum of the raw HEADERS_TO_PROPAGATE and
+// HEADER_RULES values. The injecting webhook computes it from the values it
+// sets, so the operator can confirm a sidecar runs the intended configuration.
+func ConfigChecksum(headersToPropagate, headerRules string) string {
+	sum := sha256.Sum256([]byte(headersToPropagate + "\x00" + headerRules))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
 // getEnv returns the value of an environment variable or a default value if not set.
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -351,6 +1241,18 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvList returns the comma-separated values of an environment variable, trimmed
+// and with empty values removed. Returns nil if the variable is not set.
+func getEnvList(key string) []string {
+	var values []string
+	for _, part := range strings.Split(os.Getenv(key), ",") {
+		if value := strings.TrimSpace(part); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
 // getEnvInt returns the integer value of an environment variable or a default value.
 func getEnvInt(key string, defaultValue int) int {
 	valueStr := os.Getenv(key)
@@ -414,3 +1316,70 @@ func getEnvFloat(key string, defaultValue float64) float64 {
 
 	return value
 }
+
+// ReadLiveHeaderRules returns the LiveHeaderRulesAnnotation from a downward API
+// annotations file, which holds one key="quoted value" pair per line. It
+// returns "" if the pod doesn't carry the annotation.
+func ReadLiveHeaderRules(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || key != LiveHeaderRulesAnnotation {
+			continue
+		}
+		rules, err := strconv.Unquote(value)
+		if err != nil {
+			return "", fmt.Errorf("invalid %s in %s: %w", LiveHeaderRulesAnnotation, path, err)
+		}
+		return rules, nil
+	}
+	return "", nil
+}
+
+// ReadRulesFile returns the header rules in a rules file, or "" if the file
+// doesn't exist, e.g. while the ConfigMap it is mounted from has no key for
+// this pod.
+func ReadRulesFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// EqualRules reports whether two parsed rule sets configure the proxy alike.
+func EqualRules(a, b []HeaderRule) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name != b[i].Name || a[i].Generate != b[i].Generate ||
+			a[i].GeneratorType != b[i].GeneratorType || a[i].Propagate != b[i].Propagate ||
+			a[i].PathRegex != b[i].PathRegex || !slices.Equal(a[i].ExcludePaths, b[i].ExcludePaths) ||
+			!slices.Equal(a[i].Methods, b[i].Methods) ||
+			a[i].Rename != b[i].Rename || a[i].StaticValue != b[i].StaticValue ||
+			a[i].Response != b[i].Response || a[i].OnExisting != b[i].OnExisting {
+			return false
+		}
+		if (a[i].Sampling == nil) != (b[i].Sampling == nil) ||
+			a[i].Sampling != nil && *a[i].Sampling != *b[i].Sampling {
+			return false
+		}
+		if (a[i].Transform == nil) != (b[i].Transform == nil) ||
+			a[i].Transform != nil && *a[i].Transform != *b[i].Transform {
+			return false
+		}
+		if (a[i].Destinations == nil) != (b[i].Destinations == nil) ||
+			a[i].Destinations != nil && (!slices.Equal(a[i].Destinations.Allow, b[i].Destinations.Allow) ||
+				!slices.Equal(a[i].Destinations.Deny, b[i].Destinations.Deny)) {
+			return false
+		}
+	}
+	return true
+}
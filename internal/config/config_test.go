@@ -1,7 +1,11 @@
 package config
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
 	"regexp"
+	"strings"
 	"testing"
 	"time"
 
@@ -98,6 +102,16 @@ func TestLoad_HeaderRulesWithPathAndMethods(t *testing.T) {
 	assert.Equal(t, []string{"GET", "POST"}, cfg.HeaderRules[0].Methods)
 }
 
+func TestLoad_HeaderRulesScopedHeader(t *testing.T) {
+	t.Setenv("HEADER_RULES", `[{"name":"x-tenant-id","pathRegex":"^/api/"},{"name":"X-Tenant-Id","methods":["POST"]}]`)
+
+	cfg, err := Load()
+
+	require.NoError(t, err)
+	assert.Len(t, cfg.HeaderRules, 2)
+	assert.Equal(t, []string{"x-tenant-id"}, cfg.HeadersToPropagate, "a header scoped by several rules is listed once")
+}
+
 func TestLoad_HeaderRulesInvalidJSON(t *testing.T) {
 	t.Setenv("HEADER_RULES", `not valid json`)
 
@@ -138,6 +152,146 @@ func TestLoad_HeaderRulesInvalidMethod(t *testing.T) {
 	assert.Contains(t, err.Error(), "invalid HTTP method")
 }
 
+func TestLoad_HeaderRulesTransformations(t *testing.T) {
+	t.Setenv("HEADER_RULES", `[{"name":"x-tenant","rename":"x-tenant-id","transform":{"type":"lowercase"}},`+
+		`{"name":"x-environment","staticValue":"production"}]`)
+
+	cfg, err := Load()
+
+	require.NoError(t, err)
+	assert.Equal(t, "x-tenant-id", cfg.HeaderRules[0].PropagatedName())
+	assert.Equal(t, &Transform{Type: TransformLowercase}, cfg.HeaderRules[0].Transform)
+	assert.Equal(t, "production", cfg.HeaderRules[1].StaticValue)
+}
+
+func TestParseHeaderRules_InvalidTransformations(t *testing.T) {
+	tests := map[string]struct {
+		rules string
+		err   string
+	}{
+		"invalid rename":       {`[{"name":"x-a","rename":"x a"}]`, "rename"},
+		"static and generate":  {`[{"name":"x-a","generate":true,"staticValue":"v"}]`, "mutually exclusive"},
+		"static with newline":  {`[{"name":"x-a","staticValue":"a\r\nx-b: c"}]`, "CR, LF or NUL"},
+		"unknown transform":    {`[{"name":"x-a","transform":{"type":"reverse"}}]`, "unknown transform type"},
+		"truncate no length":   {`[{"name":"x-a","transform":{"type":"truncate"}}]`, "positive length"},
+		"hash negative length": {`[{"name":"x-a","transform":{"type":"hash","length":-1}}]`, "must not be negative"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseHeaderRules(tt.rules)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func TestLoad_ResponseRulesAreNotPropagated(t *testing.T) {
+	t.Setenv("HEADER_RULES", `[{"name":"x-request-id","generate":true},{"name":"x-request-id","response":"echo"},`+
+		`{"name":"server","response":"strip","pathRegex":"^/api/"}]`)
+
+	cfg, err := Load()
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"x-request-id"}, cfg.HeadersToPropagate)
+	assert.Equal(t, ResponseEcho, cfg.HeaderRules[1].Response)
+	assert.Equal(t, ResponseStrip, cfg.HeaderRules[2].Response)
+	assert.NotNil(t, cfg.HeaderRules[2].CompiledPathRegex)
+}
+
+func TestParseHeaderRules_InvalidResponseRules(t *testing.T) {
+	tests := map[string]struct {
+		rules string
+		err   string
+	}{
+		"unknown action":     {`[{"name":"x-a","response":"copy"}]`, "unknown response action"},
+		"bad exclude path":   {`[{"name":"x-a","excludePaths":["^/(health"]}]`, "invalid exclude path regex"},
+		"bad destination":    {`[{"name":"x-a","destinations":{"allow":["http://a"]}}]`, "invalid destination"},
+		"inner wildcard":     {`[{"name":"x-a","destinations":{"deny":["a.*.com"]}}]`, "invalid destination"},
+		"strip with dests":   {`[{"name":"x-a","response":"strip","destinations":{"deny":["*"]}}]`, "response rules support only"},
+		"echo onExisting":    {`[{"name":"x-a","response":"echo","onExisting":"override"}]`, "response rules support only"},
+		"unknown onExisting": {`[{"name":"x-a","onExisting":"replace"}]`, "unknown onExisting"},
+		"sampling over 100":  {`[{"name":"x-a","sampling":{"percentage":101}}]`, "between 0 and 100"},
+		"bad sampling key":   {`[{"name":"x-a","sampling":{"percentage":10,"keyHeader":"x a"}}]`, "keyHeader"},
+		"echo and generate":  {`[{"name":"x-a","response":"echo","generate":true}]`, "response rules support only"},
+		"strip and rename":   {`[{"name":"x-a","response":"strip","rename":"x-b"}]`, "response rules support only"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseHeaderRules(tt.rules)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func TestDestinations_Allows(t *testing.T) {
+	d := &Destinations{Allow: []string{"*.svc.cluster.local", "api.example.com"}, Deny: []string{"billing.prod.svc.cluster.local"}}
+
+	assert.True(t, d.Allows("orders.prod.svc.cluster.local"))
+	assert.True(t, d.Allows("orders.prod.svc.cluster.local:8080"))
+	assert.True(t, d.Allows("API.example.com"))
+	assert.False(t, d.Allows("billing.prod.svc.cluster.local:80"))
+	assert.False(t, d.Allows("example.com"))
+	assert.False(t, d.Allows("svc.cluster.local"), "a wildcard only matches subdomains")
+
+	assert.True(t, (&Destinations{Deny: []string{"*.example.com"}}).Allows("orders"))
+	assert.False(t, (&Destinations{Deny: []string{"*"}}).Allows("orders"))
+}
+
+func TestClusterHosts_Contains(t *testing.T) {
+	c, err := NewClusterHosts(DefaultClusterDomains, DefaultClusterCIDRs)
+	require.NoError(t, err)
+
+	for _, host := range []string{
+		"orders", "orders:8080", "localhost", "orders.prod.svc", "orders.prod.svc.cluster.local.",
+		"10.96.0.1", "127.0.0.1:9090", "[::1]:8080", "[fd00::1]",
+	} {
+		assert.True(t, c.Contains(host), host)
+	}
+	for _, host := range []string{
+		"api.stripe.com", "orders.prod", "svc.example.com:443", "8.8.8.8", "169.254.169.254", "[2001:db8::1]:80",
+	} {
+		assert.False(t, c.Contains(host), host)
+	}
+
+	_, err = NewClusterHosts([]string{"*.example.com"}, nil)
+	assert.ErrorContains(t, err, "invalid cluster domain")
+	_, err = NewClusterHosts(nil, []string{"10.0.0.0"})
+	assert.ErrorContains(t, err, "invalid cluster CIDR")
+}
+
+func TestDestinations_AllowsExplicitly(t *testing.T) {
+	d := &Destinations{Allow: []string{"*.partner.com"}, Deny: []string{"billing.partner.com"}}
+	assert.True(t, d.AllowsExplicitly("api.partner.com:443"))
+	assert.False(t, d.AllowsExplicitly("billing.partner.com"))
+	assert.False(t, (&Destinations{Deny: []string{"billing.partner.com"}}).AllowsExplicitly("api.partner.com"))
+}
+
+func TestSampling_Sampled(t *testing.T) {
+	assert.True(t, (&Sampling{Percentage: 100}).Sampled(""))
+	assert.False(t, (&Sampling{Percentage: 0}).Sampled("trace-1"))
+
+	s := &Sampling{Percentage: 30, KeyHeader: "x-trace-id"}
+	sampled := 0
+	for i := range 1000 {
+		key := fmt.Sprintf("trace-%d", i)
+		if s.Sampled(key) {
+			sampled++
+		}
+		assert.Equal(t, s.Sampled(key), s.Sampled(key), "the decision is deterministic")
+	}
+	assert.InDelta(t, 300, sampled, 60)
+}
+
+func TestTransform_Apply(t *testing.T) {
+	assert.Equal(t, "ab", (&Transform{Type: TransformTruncate, Length: 2}).Apply("abc"))
+	assert.Equal(t, "abc", (&Transform{Type: TransformTruncate, Length: 5}).Apply("abc"))
+	assert.Equal(t, "ABC", (&Transform{Type: TransformUppercase}).Apply("aBc"))
+	assert.Equal(t, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+		(&Transform{Type: TransformHash}).Apply("abc"))
+	assert.Equal(t, "ba7816bf", (&Transform{Type: TransformHash, Length: 8}).Apply("abc"))
+}
+
 func TestHeaderRule_MatchesRequest(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -214,6 +368,37 @@ func TestHeaderRule_MatchesRequest(t *testing.T) {
 			method:   "POST",
 			expected: false,
 		},
+		{
+			name: "excluded path does not match",
+			rule: HeaderRule{
+				Name:         "x-request-id",
+				ExcludePaths: []string{"^/healthz$", "^/metrics$"},
+			},
+			path:     "/metrics",
+			method:   "GET",
+			expected: false,
+		},
+		{
+			name: "other paths match despite exclusions",
+			rule: HeaderRule{
+				Name:         "x-request-id",
+				ExcludePaths: []string{"^/healthz$", "^/metrics$"},
+			},
+			path:     "/metrics/custom",
+			method:   "GET",
+			expected: true,
+		},
+		{
+			name: "exclusions apply within the path regex",
+			rule: HeaderRule{
+				Name:         "x-request-id",
+				PathRegex:    "^/api/",
+				ExcludePaths: []string{"^/api/internal/"},
+			},
+			path:     "/api/internal/status",
+			method:   "GET",
+			expected: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -225,6 +410,9 @@ func TestHeaderRule_MatchesRequest(t *testing.T) {
 				require.NoError(t, err)
 				rule.CompiledPathRegex = compiled
 			}
+			for _, exclude := range rule.ExcludePaths {
+				rule.CompiledExcludePaths = append(rule.CompiledExcludePaths, regexp.MustCompile(exclude))
+			}
 			result := rule.MatchesRequest(tt.path, tt.method)
 			assert.Equal(t, tt.expected, result)
 		})
@@ -505,3 +693,537 @@ func TestValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestLoad_SlowRequestThreshold(t *testing.T) {
+	t.Setenv("HEADERS_TO_PROPAGATE", "x-request-id")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Zero(t, cfg.SlowRequestThreshold, "slow request logging is disabled by default")
+
+	t.Setenv("SLOW_REQUEST_THRESHOLD", "750ms")
+
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, 750*time.Millisecond, cfg.SlowRequestThreshold)
+}
+
+func TestValidate_RuleStreamRequiresPodIdentity(t *testing.T) {
+	cfg := ProxyConfig{
+		HeadersToPropagate: []string{"x-request-id"},
+		TargetHost:         "localhost:8080",
+		ProxyPort:          9090,
+		LogLevel:           "info",
+		MetricsPort:        9091,
+		ReadTimeout:        15 * time.Second,
+		WriteTimeout:       15 * time.Second,
+		IdleTimeout:        60 * time.Second,
+		ReadHeaderTimeout:  5 * time.Second,
+		TargetDialTimeout:  2 * time.Second,
+		RuleStreamAddress:  "contextforge-rule-stream.contextforge-system.svc:18000",
+		PodName:            "api",
+	}
+	assert.ErrorContains(t, cfg.Validate(), "POD_NAMESPACE")
+
+	cfg.PodNamespace = "default"
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestValidate_NegativeSlowRequestThreshold(t *testing.T) {
+	cfg := ProxyConfig{
+		TargetHost:           "localhost:8080",
+		ProxyPort:            9090,
+		LogLevel:             "info",
+		MetricsPort:          9091,
+		ReadTimeout:          15 * time.Second,
+		WriteTimeout:         15 * time.Second,
+		IdleTimeout:          60 * time.Second,
+		ReadHeaderTimeout:    5 * time.Second,
+		TargetDialTimeout:    2 * time.Second,
+		SlowRequestThreshold: -time.Second,
+	}
+
+	err := cfg.Validate()
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "slow request threshold")
+}
+
+func TestLoad_DebugRequestBufferSize(t *testing.T) {
+	t.Setenv("HEADERS_TO_PROPAGATE", "x-request-id")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, 100, cfg.DebugRequestBufferSize)
+
+	t.Setenv("DEBUG_REQUEST_BUFFER_SIZE", "0")
+
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Zero(t, cfg.DebugRequestBufferSize)
+
+	t.Setenv("DEBUG_REQUEST_BUFFER_SIZE", "-1")
+
+	_, err = Load()
+	assert.ErrorContains(t, err, "invalid debug request buffer size")
+}
+
+func TestLoad_PprofRequiresAdminToken(t *testing.T) {
+	t.Setenv("HEADERS_TO_PROPAGATE", "x-request-id")
+	t.Setenv("PPROF_ENABLED", "true")
+
+	_, err := Load()
+	assert.ErrorContains(t, err, "ADMIN_AUTH_TOKEN")
+
+	t.Setenv("ADMIN_AUTH_TOKEN", "s3cret")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.True(t, cfg.PprofEnabled)
+	assert.Equal(t, "s3cret", cfg.AdminAuthToken)
+}
+
+func TestLoad_HeaderLimits(t *testing.T) {
+	t.Setenv("HEADERS_TO_PROPAGATE", "x-request-id")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, 100, cfg.MaxHeaderCount)
+	assert.Equal(t, 60<<10, cfg.MaxHeaderBytes)
+
+	t.Setenv("MAX_HEADER_COUNT", "0")
+	t.Setenv("MAX_HEADER_BYTES", "2097152")
+
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Zero(t, cfg.MaxHeaderCount)
+	assert.Equal(t, 2<<20, cfg.MaxHeaderBytes)
+
+	t.Setenv("MAX_HEADER_BYTES", "-1")
+
+	_, err = Load()
+	assert.ErrorContains(t, err, "invalid header limits")
+}
+
+func TestLoad_AdminOperatorToken(t *testing.T) {
+	t.Setenv("HEADERS_TO_PROPAGATE", "x-request-id")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Empty(t, cfg.AdminOperatorToken)
+
+	t.Setenv("ADMIN_OPERATOR_TOKEN", "operator")
+
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, "operator", cfg.AdminOperatorToken)
+}
+
+func TestLoad_OTelMetrics(t *testing.T) {
+	t.Setenv("HEADERS_TO_PROPAGATE", "x-request-id")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Empty(t, cfg.OTelMetricsEndpoint)
+	assert.Equal(t, 30*time.Second, cfg.OTelMetricsInterval)
+
+	t.Setenv("OTEL_METRICS_ENDPOINT", "otel-collector.observability:4317")
+	t.Setenv("OTEL_METRICS_INTERVAL", "10s")
+	t.Setenv("OTEL_METRICS_INSECURE", "true")
+
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, "otel-collector.observability:4317", cfg.OTelMetricsEndpoint)
+	assert.Equal(t, 10*time.Second, cfg.OTelMetricsInterval)
+	assert.True(t, cfg.OTelMetricsInsecure)
+
+	t.Setenv("OTEL_METRICS_INTERVAL", "0s")
+
+	_, err = Load()
+	assert.ErrorContains(t, err, "invalid OTLP metrics interval")
+}
+
+func TestLoad_StatsD(t *testing.T) {
+	t.Setenv("HEADERS_TO_PROPAGATE", "x-request-id")
+	t.Setenv("STATSD_ADDRESS", "127.0.0.1:8125")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1:8125", cfg.StatsDAddress)
+	assert.Equal(t, "dogstatsd", cfg.StatsDFlavor)
+	assert.Equal(t, 10*time.Second, cfg.StatsDInterval)
+
+	t.Setenv("STATSD_FLAVOR", "graphite")
+
+	_, err = Load()
+	assert.ErrorContains(t, err, "invalid StatsD flavor")
+}
+
+func TestLoad_Pushgateway(t *testing.T) {
+	t.Setenv("HEADERS_TO_PROPAGATE", "x-request-id")
+	t.Setenv("PUSHGATEWAY_URL", "http://pushgateway.monitoring:9091")
+	t.Setenv("POD_NAME", "batch-job-abc12")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, "http://pushgateway.monitoring:9091", cfg.PushgatewayURL)
+	assert.Equal(t, "ctxforge-proxy", cfg.PushgatewayJob)
+	assert.Equal(t, "batch-job-abc12", cfg.PodName)
+}
+
+func TestLoad_CorrelationHeaders(t *testing.T) {
+	t.Setenv("HEADERS_TO_PROPAGATE", "x-request-id")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, "X-Request-Id", cfg.RequestIDHeader)
+	assert.Empty(t, cfg.TenantIDHeader)
+
+	t.Setenv("REQUEST_ID_HEADER", "x-correlation-id")
+	t.Setenv("TENANT_ID_HEADER", "x-tenant-id")
+
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, "x-correlation-id", cfg.RequestIDHeader)
+	assert.Equal(t, "x-tenant-id", cfg.TenantIDHeader)
+
+	t.Setenv("TENANT_ID_HEADER", "x tenant")
+
+	_, err = Load()
+	assert.ErrorContains(t, err, "invalid TENANT_ID_HEADER")
+}
+
+func TestLoad_UnsafePropagateCredentials(t *testing.T) {
+	t.Setenv("HEADERS_TO_PROPAGATE", "x-request-id,authorization")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.False(t, cfg.UnsafePropagateCredentials)
+
+	t.Setenv("UNSAFE_PROPAGATE_CREDENTIALS", "true")
+
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.True(t, cfg.UnsafePropagateCredentials)
+}
+
+func TestLoad_InClusterOnly(t *testing.T) {
+	t.Setenv("HEADERS_TO_PROPAGATE", "x-request-id")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.True(t, cfg.InClusterOnly)
+	assert.Equal(t, DefaultClusterDomains, cfg.ClusterDomains)
+	assert.Equal(t, DefaultClusterCIDRs, cfg.ClusterCIDRs)
+
+	t.Setenv("PROPAGATE_IN_CLUSTER_ONLY", "false")
+	t.Setenv("CLUSTER_DOMAINS", "svc, corp.internal")
+	t.Setenv("CLUSTER_CIDRS", "10.0.0.0/8")
+
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.False(t, cfg.InClusterOnly)
+	assert.Equal(t, []string{"svc", "corp.internal"}, cfg.ClusterDomains)
+	assert.Equal(t, []string{"10.0.0.0/8"}, cfg.ClusterCIDRs)
+
+	t.Setenv("CLUSTER_CIDRS", "10.0.0.0/33")
+	_, err = Load()
+	assert.ErrorContains(t, err, "invalid cluster CIDR")
+}
+
+func TestLoad_SPIFFE(t *testing.T) {
+	t.Setenv("HEADERS_TO_PROPAGATE", "x-request-id")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Empty(t, cfg.SPIFFEEndpointSocket)
+	assert.Equal(t, DefaultSPIFFEIDHeader, cfg.SPIFFEIDHeader)
+
+	t.Setenv("SPIFFE_ENDPOINT_SOCKET", "unix:///spiffe-workload-api/spire-agent.sock")
+	t.Setenv("SPIFFE_ID_HEADER", "x-caller-id")
+
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, "unix:///spiffe-workload-api/spire-agent.sock", cfg.SPIFFEEndpointSocket)
+	assert.Equal(t, "x-caller-id", cfg.SPIFFEIDHeader)
+
+	t.Setenv("SPIFFE_MTLS", "true")
+	_, err = Load()
+	assert.ErrorContains(t, err, "SPIFFE_MTLS requires SPIFFE_ENDPOINT_SOCKET and TARGET_TLS=true")
+
+	t.Setenv("TARGET_TLS", "true")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.True(t, cfg.SPIFFEMTLS)
+
+	t.Setenv("TARGET_TLS_SERVER_NAME", "app.local")
+	_, err = Load()
+	assert.ErrorContains(t, err, "remove TARGET_CA_FILE and TARGET_TLS_SERVER_NAME")
+
+	t.Setenv("SPIFFE_ENDPOINT_SOCKET", "/spiffe-workload-api/spire-agent.sock")
+	_, err = Load()
+	assert.ErrorContains(t, err, "invalid SPIFFE_ENDPOINT_SOCKET")
+}
+
+func TestLoad_SecureMode(t *testing.T) {
+	t.Setenv("HEADERS_TO_PROPAGATE", "x-request-id")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.False(t, cfg.Strict())
+
+	t.Setenv("SECURE_MODE", "Strict")
+
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.True(t, cfg.Strict())
+
+	tests := []struct {
+		name, env, value, expectedErr string
+	}{
+		{name: "credentials", env: "UNSAFE_PROPAGATE_CREDENTIALS", value: "true", expectedErr: "forbids UNSAFE_PROPAGATE_CREDENTIALS"},
+		{name: "external hosts", env: "PROPAGATE_IN_CLUSTER_ONLY", value: "false", expectedErr: "forbids PROPAGATE_IN_CLUSTER_ONLY"},
+		{name: "header count limit", env: "MAX_HEADER_COUNT", value: "0", expectedErr: "requires header limits"},
+		{name: "header size limit", env: "MAX_HEADER_BYTES", value: "0", expectedErr: "requires header limits"},
+		{name: "unknown mode", env: "SECURE_MODE", value: "paranoid", expectedErr: "invalid secure mode"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(tt.env, tt.value)
+			_, err := Load()
+			assert.ErrorContains(t, err, tt.expectedErr)
+		})
+	}
+}
+
+func TestLoad_RedactHeaders(t *testing.T) {
+	t.Setenv("HEADERS_TO_PROPAGATE", "x-request-id")
+	t.Setenv("REDACT_HEADERS", "x-tenant-token, x-session-id")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"x-tenant-token", "x-session-id"}, cfg.RedactHeaders)
+
+	t.Setenv("REDACT_HEADERS", "x-tenant token")
+	_, err = Load()
+	assert.ErrorContains(t, err, "invalid REDACT_HEADERS")
+}
+
+func TestIsCredentialHeader(t *testing.T) {
+	for _, name := range []string{"Authorization", "cookie", "SET-COOKIE", " proxy-authorization", "x-api-key"} {
+		assert.True(t, IsCredentialHeader(name), name)
+	}
+	for _, name := range []string{"", "x-request-id", "x-authorization", "cookies"} {
+		assert.False(t, IsCredentialHeader(name), name)
+	}
+}
+
+func TestLoad_PathTemplates(t *testing.T) {
+	t.Setenv("HEADERS_TO_PROPAGATE", "x-request-id")
+	t.Setenv("PATH_METRICS_ENABLED", "true")
+	t.Setenv("PATH_TEMPLATES", "/api/users/:id, /static/* ,")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.True(t, cfg.PathMetricsEnabled)
+	assert.Equal(t, []string{"/api/users/:id", "/static/*"}, cfg.PathTemplates)
+
+	t.Setenv("PATH_TEMPLATES", "api/users/:id")
+
+	_, err = Load()
+	assert.ErrorContains(t, err, "invalid path template")
+}
+
+func TestLoad_Drain(t *testing.T) {
+	t.Setenv("HEADERS_TO_PROPAGATE", "x-request-id")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Second, cfg.DrainDelay)
+	assert.Equal(t, 20*time.Second, cfg.DrainTimeout)
+
+	t.Setenv("DRAIN_DELAY", "0s")
+	t.Setenv("DRAIN_TIMEOUT", "45s")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Zero(t, cfg.DrainDelay)
+	assert.Equal(t, 45*time.Second, cfg.DrainTimeout)
+
+	t.Setenv("DRAIN_TIMEOUT", "-1s")
+	_, err = Load()
+	assert.ErrorContains(t, err, "DRAIN_TIMEOUT")
+}
+
+func TestLoad_TargetTLS(t *testing.T) {
+	t.Setenv("HEADERS_TO_PROPAGATE", "x-request-id")
+	t.Setenv("TARGET_CA_FILE", "/etc/ctxforge/target-ca/ca.crt")
+
+	_, err := Load()
+	assert.ErrorContains(t, err, "TARGET_TLS")
+
+	t.Setenv("TARGET_TLS", "true")
+	t.Setenv("TARGET_TLS_SERVER_NAME", "api.default.svc")
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.True(t, cfg.TargetTLS)
+	assert.Equal(t, "/etc/ctxforge/target-ca/ca.crt", cfg.TargetCAFile)
+	assert.Equal(t, "api.default.svc", cfg.TargetServerName)
+}
+
+func TestLoad_ConfigChecksum(t *testing.T) {
+	t.Setenv("HEADERS_TO_PROPAGATE", "x-request-id")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, ConfigChecksum("x-request-id", ""), cfg.ConfigChecksum)
+	assert.Contains(t, cfg.ConfigChecksum, "sha256:")
+
+	// Moving a value between the two variables changes the checksum
+	assert.NotEqual(t, ConfigChecksum("a", ""), ConfigChecksum("", "a"))
+}
+
+func TestLoad_AdminAuthTokenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	t.Setenv("HEADERS_TO_PROPAGATE", "x-request-id")
+	t.Setenv("ADMIN_AUTH_TOKEN", "from-env")
+	t.Setenv("ADMIN_AUTH_TOKEN_FILE", path)
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Len(t, cfg.AdminAuthToken, 64)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, cfg.AdminAuthToken, string(data))
+
+	// A restarted proxy keeps the token the app already read
+	restarted, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, cfg.AdminAuthToken, restarted.AdminAuthToken)
+
+	require.NoError(t, os.Chmod(path, 0o644))
+	require.NoError(t, os.WriteFile(path, []byte("\n"), 0o644))
+	_, err = Load()
+	assert.Error(t, err)
+}
+
+func TestLoad_ContextSigning(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key")
+	current := strings.Repeat("a", 32)
+	previous := strings.Repeat("b", 40)
+	require.NoError(t, os.WriteFile(path, []byte(current+"\n\n"+previous+"\n"), 0o600))
+	t.Setenv("HEADERS_TO_PROPAGATE", "x-request-id")
+	t.Setenv("CONTEXT_SIGNING_KEY_FILE", path)
+	t.Setenv("UNSIGNED_CONTEXT", "Reject")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte(current), []byte(previous)}, cfg.ContextSigningKeys)
+	assert.Equal(t, DefaultContextSignatureHeader, cfg.ContextSignatureHeader)
+	assert.Equal(t, UnsignedContextReject, cfg.UnsignedContext)
+
+	tests := []struct {
+		name    string
+		key     string
+		mode    string
+		wantErr string
+	}{
+		{name: "short key", key: "secret\n", mode: UnsignedContextAllow, wantErr: "shorter than 32 bytes"},
+		{name: "no keys", key: "\n", mode: UnsignedContextAllow, wantErr: "holds no keys"},
+		{name: "unknown mode", key: current, mode: "drop", wantErr: "invalid UNSIGNED_CONTEXT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, os.WriteFile(path, []byte(tt.key), 0o600))
+			t.Setenv("UNSIGNED_CONTEXT", tt.mode)
+			_, err := Load()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+
+	t.Run("strip without keys", func(t *testing.T) {
+		t.Setenv("CONTEXT_SIGNING_KEY_FILE", "")
+		t.Setenv("UNSIGNED_CONTEXT", UnsignedContextStrip)
+		_, err := Load()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "requires signing keys")
+	})
+}
+
+func TestReadLiveHeaderRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "annotations")
+	require.NoError(t, os.WriteFile(path, []byte(
+		`ctxforge.io/injected="true"`+"\n"+
+			`ctxforge.io/live-header-rules="[{\"name\":\"x-tenant-id\"}]"`+"\n"), 0o644))
+
+	rules, err := ReadLiveHeaderRules(path)
+	require.NoError(t, err)
+	assert.Equal(t, `[{"name":"x-tenant-id"}]`, rules)
+
+	require.NoError(t, os.WriteFile(path, []byte(`ctxforge.io/injected="true"`+"\n"), 0o644))
+	rules, err = ReadLiveHeaderRules(path)
+	require.NoError(t, err)
+	assert.Empty(t, rules)
+
+	_, err = ReadLiveHeaderRules(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
+
+func TestReadRulesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api-7d9f")
+	require.NoError(t, os.WriteFile(path, []byte(`[{"name":"x-tenant-id"}]`+"\n"), 0o644))
+
+	rules, err := ReadRulesFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, `[{"name":"x-tenant-id"}]`, rules)
+
+	rules, err = ReadRulesFile(filepath.Join(t.TempDir(), "missing"))
+	require.NoError(t, err, "a pod without a key in the ConfigMap keeps its rules")
+	assert.Empty(t, rules)
+}
+
+func TestEqualRules(t *testing.T) {
+	injected, err := ParseHeaderRules(`[{"name":"x-request-id","generate":true},{"name":"x-tenant-id","pathRegex":"^/api/","methods":["GET"]}]`)
+	require.NoError(t, err)
+
+	same, err := ParseHeaderRules(`[{"name":"x-request-id","generate":true,"generatorType":"uuid"},{"name":"x-tenant-id","pathRegex":"^/api/","methods":["GET"],"propagate":true}]`)
+	require.NoError(t, err)
+	assert.True(t, EqualRules(injected, same))
+
+	changed, err := ParseHeaderRules(`[{"name":"x-request-id","generate":true},{"name":"x-tenant-id","pathRegex":"^/api/","methods":["POST"]}]`)
+	require.NoError(t, err)
+	assert.False(t, EqualRules(injected, changed))
+	assert.False(t, EqualRules(injected, injected[:1]))
+
+	transformed, err := ParseHeaderRules(`[{"name":"x-request-id","generate":true},{"name":"x-tenant-id","pathRegex":"^/api/","methods":["GET"],"transform":{"type":"hash"}}]`)
+	require.NoError(t, err)
+	assert.False(t, EqualRules(injected, transformed))
+	sameTransform, err := ParseHeaderRules(`[{"name":"x-request-id","generate":true},{"name":"x-tenant-id","pathRegex":"^/api/","methods":["GET"],"transform":{"type":"hash"}}]`)
+	require.NoError(t, err)
+	assert.True(t, EqualRules(transformed, sameTransform))
+
+	echoed, err := ParseHeaderRules(`[{"name":"x-request-id","generate":true},{"name":"x-tenant-id","pathRegex":"^/api/","methods":["GET"],"response":"echo"}]`)
+	require.NoError(t, err)
+	assert.False(t, EqualRules(injected, echoed))
+
+	excluded, err := ParseHeaderRules(`[{"name":"x-request-id","generate":true},{"name":"x-tenant-id","pathRegex":"^/api/","methods":["GET"],"excludePaths":["^/api/health$"]}]`)
+	require.NoError(t, err)
+	assert.False(t, EqualRules(injected, excluded))
+
+	restricted, err := ParseHeaderRules(`[{"name":"x-request-id","generate":true},{"name":"x-tenant-id","pathRegex":"^/api/","methods":["GET"],"destinations":{"deny":["*.example.com"]}}]`)
+	require.NoError(t, err)
+	assert.False(t, EqualRules(injected, restricted))
+	assert.True(t, EqualRules(restricted, restricted))
+
+	overriding, err := ParseHeaderRules(`[{"name":"x-request-id","generate":true,"onExisting":"override"},{"name":"x-tenant-id","pathRegex":"^/api/","methods":["GET"]}]`)
+	require.NoError(t, err)
+	assert.False(t, EqualRules(injected, overriding))
+	preserving, err := ParseHeaderRules(`[{"name":"x-request-id","generate":true,"onExisting":"preserve"},{"name":"x-tenant-id","pathRegex":"^/api/","methods":["GET"]}]`)
+	require.NoError(t, err)
+	assert.True(t, EqualRules(injected, preserving), "preserve is the default")
+
+	sampledRules, err := ParseHeaderRules(`[{"name":"x-request-id","generate":true,"sampling":{"percentage":10}},{"name":"x-tenant-id","pathRegex":"^/api/","methods":["GET"]}]`)
+	require.NoError(t, err)
+	assert.False(t, EqualRules(injected, sampledRules))
+}
@@ -0,0 +1,194 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+	"github.com/bgruszka/contextforge/internal/config"
+	webhookv1 "github.com/bgruszka/contextforge/internal/webhook/v1"
+)
+
+// Reasons of the pods a policy in Audit mode doesn't cover, also used as the
+// "reason" label of policyUncoveredPods.
+const (
+	UncoveredReasonMissingSidecar = "MissingSidecar"
+	UncoveredReasonMissingHeaders = "MissingHeaders"
+)
+
+// Reasons of the events recorded on a policy in Audit mode when its coverage
+// changes.
+const (
+	ReasonUncoveredPods = "UncoveredPods"
+	ReasonPodsCovered   = "PodsCovered"
+)
+
+// auditPods reports which of the running pods an Audit policy selects lack
+// the proxy sidecar, or the headers of the policy's propagation rules in the
+// sidecar's configuration. Pods are named namespace/name if qualified.
+func auditPods(pods []corev1.Pod, spec ctxforgev1beta1.HeaderPropagationPolicySpec, qualified bool) *ctxforgev1beta1.AuditStatus {
+	audit := &ctxforgev1beta1.AuditStatus{}
+	required := policyHeaderNames(spec)
+
+	var uncovered []ctxforgev1beta1.UncoveredPod
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		name := pod.Name
+		if qualified {
+			name = pod.Namespace + "/" + name
+		}
+
+		sidecar := proxySidecar(pod)
+		if sidecar == nil {
+			audit.MissingSidecarPods++
+			uncovered = append(uncovered, ctxforgev1beta1.UncoveredPod{Name: name, Reason: UncoveredReasonMissingSidecar})
+			continue
+		}
+		configured := proxyHeaders(pod, sidecar)
+		var missing []string
+		for _, header := range required {
+			if !configured[http.CanonicalHeaderKey(header)] {
+				missing = append(missing, header)
+			}
+		}
+		if len(missing) == 0 {
+			audit.CoveredPods++
+			continue
+		}
+		audit.MissingHeadersPods++
+		uncovered = append(uncovered, ctxforgev1beta1.UncoveredPod{
+			Name: name, Reason: UncoveredReasonMissingHeaders, MissingHeaders: missing,
+		})
+	}
+
+	sort.Slice(uncovered, func(i, j int) bool { return uncovered[i].Name < uncovered[j].Name })
+	if len(uncovered) > MaxMatchedPods {
+		uncovered, audit.UncoveredPodsTruncated = uncovered[:MaxMatchedPods], true
+	}
+	audit.UncoveredPods = uncovered
+	return audit
+}
+
+// policyHeaderNames returns the sorted names of the headers of the policy's
+// propagation rules, once per header regardless of case.
+func policyHeaderNames(spec ctxforgev1beta1.HeaderPropagationPolicySpec) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, rule := range spec.PropagationRules {
+		for _, header := range rule.Headers {
+			key := http.CanonicalHeaderKey(header.Name)
+			if !seen[key] {
+				seen[key] = true
+				names = append(names, header.Name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// proxySidecar returns the pod's ctxforge-proxy container, regular or native,
+// or nil.
+func proxySidecar(pod *corev1.Pod) *corev1.Container {
+	for _, containers := range [][]corev1.Container{pod.Spec.Containers, pod.Spec.InitContainers} {
+		for i := range containers {
+			if containers[i].Name == webhookv1.ProxyContainerName {
+				return &containers[i]
+			}
+		}
+	}
+	return nil
+}
+
+// proxyHeaders returns the canonical names of the headers the pod's sidecar
+// is configured with: the live header rules the operator pushed to it, or
+// else its HEADER_RULES or HEADERS_TO_PROPAGATE env var, as the proxy reads
+// them. Rules distributed by the rule stream or the rules ConfigMap are seen
+// as of injection.
+func proxyHeaders(pod *corev1.Pod, sidecar *corev1.Container) map[string]bool {
+	env := make(map[string]string)
+	for _, e := range sidecar.Env {
+		env[e.Name] = e.Value
+	}
+
+	headers := make(map[string]bool)
+	rules := pod.Annotations[config.LiveHeaderRulesAnnotation]
+	if rules == "" {
+		rules = env["HEADER_RULES"]
+	}
+	if rules != "" {
+		parsed, err := config.ParseHeaderRules(rules)
+		if err == nil {
+			for _, rule := range parsed {
+				headers[http.CanonicalHeaderKey(rule.Name)] = true
+			}
+		}
+		return headers
+	}
+	for _, header := range strings.Split(env["HEADERS_TO_PROPAGATE"], ",") {
+		if header = strings.TrimSpace(header); header != "" {
+			headers[http.CanonicalHeaderKey(header)] = true
+		}
+	}
+	return headers
+}
+
+// clearHealthConditions removes the Progressing and Degraded conditions from
+// the status of a policy in Audit mode, whose coverage is reported in its
+// Audit status instead.
+func clearHealthConditions(policyStatus *ctxforgev1beta1.HeaderPropagationPolicyStatus) {
+	meta.RemoveStatusCondition(&policyStatus.Conditions, ConditionTypeProgressing)
+	meta.RemoveStatusCondition(&policyStatus.Conditions, ConditionTypeDegraded)
+}
+
+// auditMessage summarizes an audit for the events of the policy.
+func auditMessage(audit *ctxforgev1beta1.AuditStatus) string {
+	total := audit.CoveredPods + audit.MissingSidecarPods + audit.MissingHeadersPods
+	return fmt.Sprintf("Auditing %d running selected pods: %d covered, %d missing the proxy sidecar, %d missing headers",
+		total, audit.CoveredPods, audit.MissingSidecarPods, audit.MissingHeadersPods)
+}
+
+// recordAuditEvent emits an event on the policy when the counts of its audit
+// changed since the previous one, which is nil on the first audit. A nil
+// recorder discards it.
+func recordAuditEvent(recorder record.EventRecorder, obj runtime.Object, previous, current *ctxforgev1beta1.AuditStatus) {
+	if recorder == nil {
+		return
+	}
+	if previous != nil && previous.CoveredPods == current.CoveredPods &&
+		previous.MissingSidecarPods == current.MissingSidecarPods &&
+		previous.MissingHeadersPods == current.MissingHeadersPods {
+		return
+	}
+	if current.MissingSidecarPods+current.MissingHeadersPods > 0 {
+		recorder.Event(obj, corev1.EventTypeWarning, ReasonUncoveredPods, auditMessage(current))
+	} else {
+		recorder.Event(obj, corev1.EventTypeNormal, ReasonPodsCovered, auditMessage(current))
+	}
+}
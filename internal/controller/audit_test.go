@@ -0,0 +1,88 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+	"github.com/bgruszka/contextforge/internal/config"
+)
+
+var _ = Describe("Policy audit mode", func() {
+	spec := ctxforgev1beta1.HeaderPropagationPolicySpec{
+		Mode: ctxforgev1beta1.PolicyModeAudit,
+		PropagationRules: []ctxforgev1beta1.PropagationRule{
+			{Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-request-id", Generate: true}, {Name: "x-tenant-id"}}},
+			{Headers: []ctxforgev1beta1.HeaderConfig{{Name: "X-Request-Id"}}, PathRegex: "^/api/"},
+		},
+	}
+	pod := func(name string, phase corev1.PodPhase, env ...corev1.EnvVar) corev1.Pod {
+		p := corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop"},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+			Status:     corev1.PodStatus{Phase: phase},
+		}
+		if env != nil {
+			p.Spec.Containers = append(p.Spec.Containers, corev1.Container{Name: "ctxforge-proxy", Env: env})
+		}
+		return p
+	}
+
+	It("should report the selected pods lacking the sidecar or the policy's headers", func() {
+		pods := []corev1.Pod{
+			pod("covered", corev1.PodRunning, corev1.EnvVar{Name: "HEADERS_TO_PROPAGATE", Value: "X-Request-ID, x-tenant-id,baggage"}),
+			pod("partial", corev1.PodRunning, corev1.EnvVar{Name: "HEADER_RULES", Value: `[{"name":"x-request-id","generate":true}]`}),
+			pod("bare", corev1.PodRunning),
+			pod("pending", corev1.PodPending),
+		}
+
+		audit := auditPods(pods, spec, true)
+		Expect(audit.CoveredPods).To(Equal(int32(1)))
+		Expect(audit.MissingSidecarPods).To(Equal(int32(1)))
+		Expect(audit.MissingHeadersPods).To(Equal(int32(1)))
+		Expect(audit.UncoveredPods).To(Equal([]ctxforgev1beta1.UncoveredPod{
+			{Name: "shop/bare", Reason: UncoveredReasonMissingSidecar},
+			{Name: "shop/partial", Reason: UncoveredReasonMissingHeaders, MissingHeaders: []string{"x-tenant-id"}},
+		}))
+		Expect(auditMessage(audit)).To(Equal(
+			"Auditing 3 running selected pods: 1 covered, 1 missing the proxy sidecar, 1 missing headers"))
+
+		By("reading the header rules pushed to a running sidecar")
+		pods[1].Annotations = map[string]string{
+			config.LiveHeaderRulesAnnotation: `[{"name":"x-request-id"},{"name":"x-tenant-id","propagate":true}]`,
+		}
+		Expect(auditPods(pods, spec, false).MissingHeadersPods).To(BeZero())
+	})
+
+	It("should record an event only when the coverage changes", func() {
+		recorder := record.NewFakeRecorder(4)
+		policy := &ctxforgev1beta1.HeaderPropagationPolicy{}
+		uncovered := &ctxforgev1beta1.AuditStatus{CoveredPods: 1, MissingSidecarPods: 2}
+
+		recordAuditEvent(recorder, policy, nil, uncovered)
+		recordAuditEvent(recorder, policy, uncovered, uncovered.DeepCopy())
+		recordAuditEvent(recorder, policy, uncovered, &ctxforgev1beta1.AuditStatus{CoveredPods: 3})
+		Expect(recorder.Events).To(HaveLen(2))
+		Expect(<-recorder.Events).To(HavePrefix("Warning UncoveredPods"))
+		Expect(<-recorder.Events).To(HavePrefix("Normal PodsCovered"))
+	})
+})
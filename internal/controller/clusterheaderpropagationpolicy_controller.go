@@ -0,0 +1,347 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+	ctxforgepolicy "github.com/bgruszka/contextforge/internal/policy"
+)
+
+// ClusterHeaderPropagationPolicyReconciler reconciles a
+// ClusterHeaderPropagationPolicy object
+type ClusterHeaderPropagationPolicyReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// Recorder emits events on policies when their Ready condition changes.
+	Recorder record.EventRecorder
+
+	// Stats summarizes the traffic of the policy's sidecars in its status;
+	// nil disables it.
+	Stats *StatsCollector
+
+	// ResyncInterval reconciles each policy periodically on top of the
+	// events of its pods and policies; zero disables it.
+	ResyncInterval time.Duration
+}
+
+// +kubebuilder:rbac:groups=ctxforge.ctxforge.io,resources=clusterheaderpropagationpolicies,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=ctxforge.ctxforge.io,resources=clusterheaderpropagationpolicies/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=ctxforge.ctxforge.io,resources=clusterheaderpropagationpolicies/finalizers,verbs=update
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+// Reconcile counts the pods with the proxy sidecar that a
+// ClusterHeaderPropagationPolicy selects in the namespaces matching its
+// NamespaceSelector, and records them in the policy's status, in total and
+// per namespace.
+func (r *ClusterHeaderPropagationPolicyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	start := time.Now()
+	defer func() { reconcileDuration.Observe(time.Since(start).Seconds()) }()
+
+	policy := &ctxforgev1beta1.ClusterHeaderPropagationPolicy{}
+	if err := r.Get(ctx, req.NamespacedName, policy); err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Info("ClusterHeaderPropagationPolicy resource not found, likely deleted")
+			forgetPolicy(req.NamespacedName)
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to fetch ClusterHeaderPropagationPolicy")
+		reconcileErrorsTotal.WithLabelValues(ReconcileErrorFetchPolicy).Inc()
+		return ctrl.Result{}, err
+	}
+
+	// Counted for the Rules column of kubectl get
+	policy.Status.Rules = int32(len(policy.Spec.PropagationRules))
+
+	podSelector, err := ctxforgepolicy.Selector(policy.Spec.PodSelector)
+	if err != nil {
+		log.Error(err, "Failed to parse PodSelector")
+		reconcileErrorsTotal.WithLabelValues(ReconcileErrorInvalidSelector).Inc()
+		r.updateStatusCondition(ctx, policy, metav1.ConditionFalse, ReasonInvalidSelector,
+			"Failed to parse PodSelector: "+err.Error())
+		return ctrl.Result{}, err
+	}
+	namespaceSelector, err := ctxforgepolicy.Selector(policy.Spec.NamespaceSelector)
+	if err != nil {
+		log.Error(err, "Failed to parse NamespaceSelector")
+		reconcileErrorsTotal.WithLabelValues(ReconcileErrorInvalidSelector).Inc()
+		r.updateStatusCondition(ctx, policy, metav1.ConditionFalse, ReasonInvalidSelector,
+			"Failed to parse NamespaceSelector: "+err.Error())
+		return ctrl.Result{}, err
+	}
+
+	if _, err := ctxforgepolicy.Selector(policy.Spec.WorkloadSelector); err != nil {
+		log.Error(err, "Failed to parse WorkloadSelector")
+		reconcileErrorsTotal.WithLabelValues(ReconcileErrorInvalidSelector).Inc()
+		r.updateStatusCondition(ctx, policy, metav1.ConditionFalse, ReasonInvalidSelector,
+			"Failed to parse WorkloadSelector: "+err.Error())
+		return ctrl.Result{}, err
+	}
+
+	if _, err := ctxforgepolicy.Selector(policy.Spec.RouteSelector); err != nil {
+		log.Error(err, "Failed to parse RouteSelector")
+		reconcileErrorsTotal.WithLabelValues(ReconcileErrorInvalidSelector).Inc()
+		r.updateStatusCondition(ctx, policy, metav1.ConditionFalse, ReasonInvalidSelector,
+			"Failed to parse RouteSelector: "+err.Error())
+		return ctrl.Result{}, err
+	}
+
+	if err := ctxforgepolicy.Validate(policy.Spec); err != nil {
+		log.Info("Policy has invalid propagation rules", "error", err.Error())
+		reconcileErrorsTotal.WithLabelValues(ReconcileErrorRuleCompile).Inc()
+		policy.Status.RuleErrors = ctxforgepolicy.RuleErrors(policy.Spec)
+		r.updateStatusCondition(ctx, policy, metav1.ConditionFalse, ReasonRuleCompileError, "Invalid propagation rules: "+err.Error())
+		return ctrl.Result{}, nil
+	}
+	policy.Status.RuleErrors = nil
+
+	namespaceList := &corev1.NamespaceList{}
+	if err := r.List(ctx, namespaceList, client.MatchingLabelsSelector{Selector: namespaceSelector}); err != nil {
+		log.Error(err, "Failed to list namespaces")
+		reconcileErrorsTotal.WithLabelValues(ReconcileErrorListPods).Inc()
+		return ctrl.Result{}, err
+	}
+
+	var pods []corev1.Pod
+	var selected []string
+	for _, namespace := range namespaceList.Items {
+		selected = append(selected, namespace.Name)
+		podList := &corev1.PodList{}
+		if err := r.List(ctx, podList,
+			client.InNamespace(namespace.Name),
+			client.MatchingLabelsSelector{Selector: podSelector},
+		); err != nil {
+			log.Error(err, "Failed to list pods", "namespace", namespace.Name)
+			reconcileErrorsTotal.WithLabelValues(ReconcileErrorListPods).Inc()
+			r.updateStatusCondition(ctx, policy, metav1.ConditionFalse, ReasonListPodsFailed,
+				"Failed to list pods: "+err.Error())
+			return ctrl.Result{}, err
+		}
+		pods = append(pods, podList.Items...)
+	}
+
+	namespaces, matchedPods, pendingPods, totalSelectorMatches := sidecarPodCounts(pods)
+
+	policy.Status.ObservedGeneration = policy.Generation
+	policy.Status.AppliedToPods = matchedPods
+	policy.Status.Namespaces = namespaces
+	policy.Status.MatchedPods, policy.Status.MatchedPodsTruncated = matchedPodNames(pods, true)
+
+	// A policy in Audit mode applies to no pod; it reports those it would
+	// leave uncovered instead
+	self := ctxforgepolicy.FromClusterPolicy(policy)
+	if self.Audit() {
+		audit := auditPods(pods, policy.Spec, true)
+		recordAuditEvent(r.Recorder, policy, policy.Status.Audit, audit)
+		policy.Status.Audit = audit
+		matchedPods = 0
+		policy.Status.AppliedToPods = 0
+		policy.Status.Namespaces = nil
+		policy.Status.MatchedPods, policy.Status.MatchedPodsTruncated = nil, false
+	} else {
+		policy.Status.Audit = nil
+	}
+
+	if self.Audit() {
+		r.setReadyCondition(policy, metav1.ConditionTrue, ReasonAuditing,
+			"Policy is in Audit mode and changes no pods; see status.audit for the pods it doesn't cover")
+	} else if matchedPods > 0 {
+		r.setReadyCondition(policy, metav1.ConditionTrue, ReasonPolicyApplied,
+			"Policy is applied to pods with contextforge-proxy sidecar")
+	} else {
+		r.setReadyCondition(policy, metav1.ConditionFalse, ReasonNoMatchingPods,
+			"No running pods with contextforge-proxy sidecar match the selectors")
+	}
+
+	now := time.Now()
+	health := assessPods(pods, now)
+	if self.Audit() {
+		health = podHealth{}
+		clearHealthConditions(&policy.Status)
+	} else {
+		setHealthConditions(&policy.Status, policy.Generation, health)
+	}
+
+	// Surface headers this policy shares with others selecting the same pods
+	conflicts, err := policyConflicts(ctx, r.Client, self, pods)
+	if err != nil {
+		log.Error(err, "Failed to evaluate policy conflicts")
+		reconcileErrorsTotal.WithLabelValues(ReconcileErrorListPods).Inc()
+		return ctrl.Result{}, err
+	}
+	setConflictCondition(&policy.Status, policy.Generation, self, conflicts)
+	policy.Status.AnnotatedPods = annotatedPodCount(pods, self)
+	policy.Status.AdoptedPods = adoptedPodCount(pods, self)
+
+	// Restart the selected workloads when the policy changed materially
+	revision, err := restartWorkloads(ctx, r.Client, r.Recorder, policy, self, policy.Status.RolloutRevision, selected)
+	if err != nil {
+		log.Error(err, "Failed to restart workloads")
+		reconcileErrorsTotal.WithLabelValues(ReconcileErrorRestart).Inc()
+		return ctrl.Result{}, err
+	}
+	policy.Status.RolloutRevision = revision
+
+	if r.Stats != nil {
+		policy.Status.Stats = r.Stats.Stats(ctx, policy.Spec, pods)
+	}
+
+	if err := r.Status().Update(ctx, policy); err != nil {
+		log.Error(err, "Failed to update ClusterHeaderPropagationPolicy status")
+		reconcileErrorsTotal.WithLabelValues(ReconcileErrorUpdateStatus).Inc()
+		return ctrl.Result{}, err
+	}
+
+	recordPolicyApplied(req.NamespacedName, matchedPods)
+	recordPolicyAudit(req.NamespacedName, policy.Status.Audit)
+
+	log.Info("Reconciled ClusterHeaderPropagationPolicy",
+		"appliedToPods", matchedPods,
+		"pendingPods", pendingPods,
+		"totalWithSidecar", totalSelectorMatches,
+		"namespaces", len(namespaces),
+		"namespaceSelector", namespaceSelector.String(),
+		"podSelector", podSelector.String())
+
+	return ctrl.Result{RequeueAfter: requeueAfter(health, now, r.Stats, r.ResyncInterval)}, nil
+}
+
+// updateStatusCondition records a failed reconcile in the policy's Ready
+// condition; the reconcile error is returned by the caller.
+func (r *ClusterHeaderPropagationPolicyReconciler) updateStatusCondition(ctx context.Context, policy *ctxforgev1beta1.ClusterHeaderPropagationPolicy, status metav1.ConditionStatus, reason, message string) {
+	r.setReadyCondition(policy, status, reason, message)
+	policy.Status.ObservedGeneration = policy.Generation
+	if err := r.Status().Update(ctx, policy); err != nil {
+		logf.FromContext(ctx).Error(err, "Failed to update ClusterHeaderPropagationPolicy status")
+		reconcileErrorsTotal.WithLabelValues(ReconcileErrorUpdateStatus).Inc()
+	}
+}
+
+// setReadyCondition sets the Ready condition on the policy and records an
+// event if it changed.
+func (r *ClusterHeaderPropagationPolicyReconciler) setReadyCondition(policy *ctxforgev1beta1.ClusterHeaderPropagationPolicy, status metav1.ConditionStatus, reason, message string) {
+	recordReadyEvent(r.Recorder, policy, policy.Status.Conditions, status, reason, message)
+	setPolicyReadyCondition(&policy.Status, policy.Generation, status, reason, message)
+}
+
+// setPolicyReadyCondition sets the Ready condition on a policy status.
+func setPolicyReadyCondition(policyStatus *ctxforgev1beta1.HeaderPropagationPolicyStatus, generation int64, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&policyStatus.Conditions, metav1.Condition{
+		Type:               ConditionTypeReady,
+		Status:             status,
+		ObservedGeneration: generation,
+		LastTransitionTime: metav1.Now(),
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// findClusterPoliciesForPod enqueues the ClusterHeaderPropagationPolicies
+// whose PodSelector matches a changed pod. The NamespaceSelector is checked by
+// Reconcile, sparing a namespace lookup per pod event.
+func (r *ClusterHeaderPropagationPolicyReconciler) findClusterPoliciesForPod(ctx context.Context, obj client.Object) []reconcile.Request {
+	policyList := &ctxforgev1beta1.ClusterHeaderPropagationPolicyList{}
+	if err := r.List(ctx, policyList); err != nil {
+		logf.FromContext(ctx).Error(err, "Failed to list ClusterHeaderPropagationPolicies for pod", "pod", obj.GetName())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, policy := range policyList.Items {
+		selector, err := ctxforgepolicy.Selector(policy.Spec.PodSelector)
+		if err != nil {
+			continue
+		}
+		if selector.Matches(labels.Set(obj.GetLabels())) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: client.ObjectKeyFromObject(&policy),
+			})
+		}
+	}
+	return requests
+}
+
+// findAllClusterPolicies enqueues all ClusterHeaderPropagationPolicies when a
+// namespace changes, since relabeling it may select or deselect it, and when
+// a policy changes, since it may conflict with them.
+func (r *ClusterHeaderPropagationPolicyReconciler) findAllClusterPolicies(ctx context.Context, obj client.Object) []reconcile.Request {
+	policyList := &ctxforgev1beta1.ClusterHeaderPropagationPolicyList{}
+	if err := r.List(ctx, policyList); err != nil {
+		logf.FromContext(ctx).Error(err, "Failed to list ClusterHeaderPropagationPolicies", "trigger", obj.GetName())
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(policyList.Items))
+	for _, policy := range policyList.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: client.ObjectKeyFromObject(&policy),
+		})
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ClusterHeaderPropagationPolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// Conflicts only change with other policies' specs, not their status
+	specChanged := builder.WithPredicates(predicate.GenerationChangedPredicate{})
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&ctxforgev1beta1.ClusterHeaderPropagationPolicy{}).
+		Watches(
+			&corev1.Pod{},
+			handler.EnqueueRequestsFromMapFunc(r.findClusterPoliciesForPod),
+		).
+		Watches(
+			&corev1.Namespace{},
+			handler.EnqueueRequestsFromMapFunc(r.findAllClusterPolicies),
+		).
+		Watches(
+			&ctxforgev1beta1.HeaderPropagationPolicy{},
+			handler.EnqueueRequestsFromMapFunc(r.findAllClusterPolicies),
+			specChanged,
+		).
+		Watches(
+			&ctxforgev1beta1.ClusterHeaderPropagationPolicy{},
+			handler.EnqueueRequestsFromMapFunc(r.findAllClusterPolicies),
+			specChanged,
+		).
+		Named("clusterheaderpropagationpolicy").
+		WithOptions(controller.Options{RateLimiter: policyRateLimiter()}).
+		Complete(r)
+}
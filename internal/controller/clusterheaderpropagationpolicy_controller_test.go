@@ -0,0 +1,142 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+)
+
+var _ = Describe("ClusterHeaderPropagationPolicy Controller", func() {
+	const policyName = "test-cluster-policy"
+
+	ctx := context.Background()
+	policyKey := types.NamespacedName{Name: policyName}
+	namespaces := []string{"cluster-policy-a", "cluster-policy-b", "cluster-policy-other"}
+
+	newSidecarPod := func(name, namespace string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels:    map[string]string{"app": "test-app"},
+			},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{
+				{Name: "main-app", Image: "nginx:latest"},
+				{Name: "ctxforge-proxy", Image: "ghcr.io/bgruszka/contextforge-proxy:0.1.0"},
+			}},
+		}
+	}
+
+	BeforeEach(func() {
+		By("creating labeled namespaces with sidecar pods")
+		for _, name := range namespaces {
+			ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+			if name != "cluster-policy-other" {
+				ns.Labels = map[string]string{"ctxforge.io/tier": "backend"}
+			}
+			Expect(client.IgnoreAlreadyExists(k8sClient.Create(ctx, ns))).To(Succeed())
+		}
+
+		running := newSidecarPod("running", "cluster-policy-a")
+		Expect(k8sClient.Create(ctx, running)).To(Succeed())
+		running.Status.Phase = corev1.PodRunning
+		Expect(k8sClient.Status().Update(ctx, running)).To(Succeed())
+
+		pending := newSidecarPod("pending", "cluster-policy-b")
+		Expect(k8sClient.Create(ctx, pending)).To(Succeed())
+		pending.Status.Phase = corev1.PodPending
+		Expect(k8sClient.Status().Update(ctx, pending)).To(Succeed())
+
+		unselected := newSidecarPod("unselected", "cluster-policy-other")
+		Expect(k8sClient.Create(ctx, unselected)).To(Succeed())
+		unselected.Status.Phase = corev1.PodRunning
+		Expect(k8sClient.Status().Update(ctx, unselected)).To(Succeed())
+
+		By("creating a cluster policy selecting the labeled namespaces")
+		policy := &ctxforgev1beta1.ClusterHeaderPropagationPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: policyName},
+			Spec: ctxforgev1beta1.HeaderPropagationPolicySpec{
+				NamespaceSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{"ctxforge.io/tier": "backend"},
+				},
+				PodSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{"app": "test-app"},
+				},
+				PropagationRules: []ctxforgev1beta1.PropagationRule{{
+					Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-request-id"}},
+				}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, policy)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		policy := &ctxforgev1beta1.ClusterHeaderPropagationPolicy{}
+		if err := k8sClient.Get(ctx, policyKey, policy); err == nil {
+			Expect(k8sClient.Delete(ctx, policy)).To(Succeed())
+		}
+		for _, name := range namespaces {
+			Expect(k8sClient.DeleteAllOf(ctx, &corev1.Pod{}, client.InNamespace(name))).To(Succeed())
+		}
+	})
+
+	It("should break the applied pods down by selected namespace", func() {
+		controllerReconciler := &ClusterHeaderPropagationPolicyReconciler{
+			Client: k8sClient,
+			Scheme: k8sClient.Scheme(),
+		}
+
+		result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: policyKey})
+		Expect(err).NotTo(HaveOccurred())
+		// The pending pod's start is an event of its own
+		Expect(result.RequeueAfter).To(BeZero())
+
+		policy := &ctxforgev1beta1.ClusterHeaderPropagationPolicy{}
+		Expect(k8sClient.Get(ctx, policyKey, policy)).To(Succeed())
+		Expect(policy.Status.AppliedToPods).To(Equal(int32(1)))
+		Expect(policy.Status.Rules).To(Equal(int32(1)))
+		Expect(policy.Status.Namespaces).To(Equal([]ctxforgev1beta1.NamespacePolicyStatus{
+			{Namespace: "cluster-policy-a", AppliedToPods: 1},
+			{Namespace: "cluster-policy-b", PendingPods: 1},
+		}))
+		Expect(policy.Status.MatchedPods).To(Equal([]string{"cluster-policy-a/running"}))
+
+		readyCondition := meta.FindStatusCondition(policy.Status.Conditions, ConditionTypeReady)
+		Expect(readyCondition).NotTo(BeNil())
+		Expect(readyCondition.Status).To(Equal(metav1.ConditionTrue))
+	})
+
+	It("should enqueue the policy for pods it may select", func() {
+		controllerReconciler := &ClusterHeaderPropagationPolicyReconciler{Client: k8sClient}
+
+		Expect(controllerReconciler.findClusterPoliciesForPod(ctx, newSidecarPod("any", "default"))).
+			To(ContainElement(reconcile.Request{NamespacedName: policyKey}))
+		Expect(controllerReconciler.findClusterPoliciesForPod(ctx, &corev1.Pod{})).
+			NotTo(ContainElement(reconcile.Request{NamespacedName: policyKey}))
+	})
+})
@@ -0,0 +1,167 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+	webhookv1 "github.com/bgruszka/contextforge/internal/webhook/v1"
+)
+
+const (
+	// ConditionTypeProgressing indicates that selected pods are still being
+	// configured: starting, or not yet running the injected configuration
+	ConditionTypeProgressing = "Progressing"
+
+	// ConditionTypeDegraded indicates that some selected pods are not covered
+	// by the policy: they lack the sidecar or their proxy did not load the
+	// injected configuration
+	ConditionTypeDegraded = "Degraded"
+
+	// ConfigSyncGracePeriod is how long a running pod's proxy may take to
+	// report the injected configuration before the policy is Degraded.
+	ConfigSyncGracePeriod = 2 * time.Minute
+)
+
+// Reasons of the Progressing and Degraded policy conditions.
+const (
+	ReasonPodsPending        = "PodsPending"
+	ReasonConfigSyncing      = "ConfigSyncing"
+	ReasonPodsConfigured     = "PodsConfigured"
+	ReasonPodsWithoutSidecar = "PodsWithoutSidecar"
+	ReasonConfigRejected     = "ConfigRejected"
+)
+
+// podHealth counts the selected pods that keep a policy Progressing or
+// Degraded.
+type podHealth struct {
+	// pending pods with the sidecar have not started yet
+	pending int32
+	// syncing pods run the sidecar but have not reported the injected
+	// configuration within ConfigSyncGracePeriod yet
+	syncing int32
+	// withoutSidecar pods are running without the sidecar
+	withoutSidecar int32
+	// rejected pods have not reported the injected configuration for
+	// longer than ConfigSyncGracePeriod
+	rejected int32
+	// nextRejection is when the grace period of the first syncing pod ends
+	nextRejection time.Time
+}
+
+// assessPods counts the selected pods by the state of their sidecar at now.
+func assessPods(pods []corev1.Pod, now time.Time) podHealth {
+	var health podHealth
+	for i := range pods {
+		pod := &pods[i]
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		sidecar := hasProxySidecar(pod)
+		switch {
+		case pod.Status.Phase == corev1.PodPending && sidecar:
+			health.pending++
+		case pod.Status.Phase != corev1.PodRunning:
+		case !sidecar:
+			health.withoutSidecar++
+		default:
+			// Pods without the readiness gate never report their config
+			cond := configSyncCondition(pod)
+			if cond == nil || cond.Status == corev1.ConditionTrue {
+				continue
+			}
+			if deadline := cond.LastTransitionTime.Add(ConfigSyncGracePeriod); now.Before(deadline) {
+				health.syncing++
+				if health.nextRejection.IsZero() || deadline.Before(health.nextRejection) {
+					health.nextRejection = deadline
+				}
+			} else {
+				health.rejected++
+			}
+		}
+	}
+	return health
+}
+
+// configSyncCondition returns the pod's ctxforge.io/proxy-config-synced
+// condition, if it has one.
+func configSyncCondition(pod *corev1.Pod) *corev1.PodCondition {
+	for i := range pod.Status.Conditions {
+		if pod.Status.Conditions[i].Type == webhookv1.ConditionProxyConfigSynced {
+			return &pod.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// setHealthConditions sets the Progressing and Degraded conditions on a
+// policy status.
+func setHealthConditions(policyStatus *ctxforgev1beta1.HeaderPropagationPolicyStatus, generation int64, health podHealth) {
+	progressing := metav1.Condition{
+		Type:               ConditionTypeProgressing,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+	}
+	switch {
+	case health.pending > 0:
+		progressing.Reason = ReasonPodsPending
+		progressing.Message = fmt.Sprintf("%d pods with contextforge-proxy sidecar are pending", health.pending)
+	case health.syncing > 0:
+		progressing.Reason = ReasonConfigSyncing
+		progressing.Message = fmt.Sprintf("%d pods have not reported the injected configuration yet", health.syncing)
+	default:
+		progressing.Status = metav1.ConditionFalse
+		progressing.Reason = ReasonPodsConfigured
+		progressing.Message = "All selected pods with contextforge-proxy sidecar are configured"
+	}
+	meta.SetStatusCondition(&policyStatus.Conditions, progressing)
+
+	degraded := metav1.Condition{
+		Type:               ConditionTypeDegraded,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+	}
+	var problems []string
+	if health.rejected > 0 {
+		problems = append(problems, fmt.Sprintf(
+			"%d pods have not loaded the injected configuration within %s, see their %s condition",
+			health.rejected, ConfigSyncGracePeriod, webhookv1.ConditionProxyConfigSynced))
+	}
+	if health.withoutSidecar > 0 {
+		problems = append(problems, fmt.Sprintf("%d running pods match the selector but lack the contextforge-proxy sidecar",
+			health.withoutSidecar))
+	}
+	switch {
+	case health.rejected > 0:
+		degraded.Reason = ReasonConfigRejected
+	case health.withoutSidecar > 0:
+		degraded.Reason = ReasonPodsWithoutSidecar
+	default:
+		degraded.Status = metav1.ConditionFalse
+		degraded.Reason = ReasonPodsConfigured
+		problems = append(problems, "All selected running pods have the sidecar and its configuration")
+	}
+	degraded.Message = strings.Join(problems, "; ")
+	meta.SetStatusCondition(&policyStatus.Conditions, degraded)
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+	webhookv1 "github.com/bgruszka/contextforge/internal/webhook/v1"
+)
+
+var _ = Describe("Policy health conditions", func() {
+	now := time.Now()
+
+	pod := func(phase corev1.PodPhase, sidecar bool, synced corev1.ConditionStatus, since time.Duration) corev1.Pod {
+		p := corev1.Pod{Status: corev1.PodStatus{Phase: phase}}
+		p.Spec.Containers = []corev1.Container{{Name: "app"}}
+		if sidecar {
+			p.Spec.Containers = append(p.Spec.Containers, corev1.Container{Name: webhookv1.ProxyContainerName})
+		}
+		if synced != "" {
+			p.Status.Conditions = []corev1.PodCondition{{
+				Type:               webhookv1.ConditionProxyConfigSynced,
+				Status:             synced,
+				LastTransitionTime: metav1.NewTime(now.Add(-since)),
+			}}
+		}
+		return p
+	}
+
+	It("should count pods by the state of their sidecar", func() {
+		health := assessPods([]corev1.Pod{
+			pod(corev1.PodPending, true, "", 0),
+			pod(corev1.PodRunning, true, corev1.ConditionTrue, time.Hour),
+			pod(corev1.PodRunning, true, "", 0),
+			pod(corev1.PodRunning, true, corev1.ConditionFalse, time.Second),
+			pod(corev1.PodRunning, true, corev1.ConditionFalse, ConfigSyncGracePeriod+time.Second),
+			pod(corev1.PodRunning, false, "", 0),
+			pod(corev1.PodSucceeded, false, "", 0),
+		}, now)
+
+		Expect(health).To(Equal(podHealth{
+			pending: 1, syncing: 1, withoutSidecar: 1, rejected: 1,
+			nextRejection: now.Add(ConfigSyncGracePeriod - time.Second),
+		}))
+	})
+
+	It("should requeue only when time changes the policy's status", func() {
+		Expect(requeueAfter(podHealth{}, now, nil, 0)).To(BeZero())
+		Expect(requeueAfter(podHealth{pending: 2}, now, nil, 0)).To(BeZero())
+		Expect(requeueAfter(podHealth{}, now, nil, 10*time.Minute)).To(Equal(10 * time.Minute))
+
+		syncing := podHealth{syncing: 1, nextRejection: now.Add(20 * time.Second)}
+		Expect(requeueAfter(syncing, now, nil, 10*time.Minute)).To(Equal(20 * time.Second))
+		Expect(requeueAfter(syncing, now, NewStatsCollector(5*time.Second), 0)).To(Equal(5 * time.Second))
+	})
+
+	It("should report Progressing and Degraded with alertable reasons", func() {
+		status := &ctxforgev1beta1.HeaderPropagationPolicyStatus{}
+
+		setHealthConditions(status, 1, podHealth{syncing: 2, withoutSidecar: 1})
+		progressing := meta.FindStatusCondition(status.Conditions, ConditionTypeProgressing)
+		Expect(progressing.Status).To(Equal(metav1.ConditionTrue))
+		Expect(progressing.Reason).To(Equal(ReasonConfigSyncing))
+		degraded := meta.FindStatusCondition(status.Conditions, ConditionTypeDegraded)
+		Expect(degraded.Status).To(Equal(metav1.ConditionTrue))
+		Expect(degraded.Reason).To(Equal(ReasonPodsWithoutSidecar))
+
+		setHealthConditions(status, 1, podHealth{rejected: 1, withoutSidecar: 1})
+		degraded = meta.FindStatusCondition(status.Conditions, ConditionTypeDegraded)
+		Expect(degraded.Reason).To(Equal(ReasonConfigRejected))
+		Expect(degraded.Message).To(ContainSubstring("lack the contextforge-proxy sidecar"))
+
+		setHealthConditions(status, 2, podHealth{})
+		Expect(meta.IsStatusConditionFalse(status.Conditions, ConditionTypeProgressing)).To(BeTrue())
+		Expect(meta.IsStatusConditionFalse(status.Conditions, ConditionTypeDegraded)).To(BeTrue())
+	})
+})
@@ -0,0 +1,163 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+	"github.com/bgruszka/contextforge/internal/policy"
+)
+
+// ConditionTypeConflict indicates whether another policy selecting the same
+// pods configures one of the policy's headers
+const ConditionTypeConflict = "Conflict"
+
+// policyConflicts returns the conflicts between self and the other policies
+// selecting any of the given pods with the proxy sidecar, each reported once.
+// Policies in Audit mode configure no headers and have no conflicts.
+func policyConflicts(ctx context.Context, c client.Reader, self policy.Source, pods []corev1.Pod) ([]policy.Conflict, error) {
+	if self.Audit() {
+		return nil, nil
+	}
+	clusterPolicyList := &ctxforgev1beta1.ClusterHeaderPropagationPolicyList{}
+	if err := c.List(ctx, clusterPolicyList); err != nil {
+		return nil, fmt.Errorf("failed to list ClusterHeaderPropagationPolicies: %w", err)
+	}
+
+	// Sources and namespace labels are looked up once per namespace
+	sources := make(map[string][]policy.Source)
+	namespaceLabels := make(map[string]labels.Set)
+	candidates := func(namespace string) ([]policy.Source, labels.Set, error) {
+		if candidates, ok := sources[namespace]; ok {
+			return candidates, namespaceLabels[namespace], nil
+		}
+		policyList := &ctxforgev1beta1.HeaderPropagationPolicyList{}
+		if err := c.List(ctx, policyList, client.InNamespace(namespace)); err != nil {
+			return nil, nil, fmt.Errorf("failed to list HeaderPropagationPolicies: %w", err)
+		}
+		ns := &corev1.Namespace{}
+		if err := c.Get(ctx, client.ObjectKey{Name: namespace}, ns); err != nil {
+			return nil, nil, fmt.Errorf("failed to get namespace %s: %w", namespace, err)
+		}
+
+		var candidates []policy.Source
+		for i := range policyList.Items {
+			candidates = append(candidates, policy.FromPolicy(&policyList.Items[i]))
+		}
+		for i := range clusterPolicyList.Items {
+			candidates = append(candidates, policy.FromClusterPolicy(&clusterPolicyList.Items[i]))
+		}
+		sources[namespace] = candidates
+		namespaceLabels[namespace] = ns.Labels
+		return candidates, ns.Labels, nil
+	}
+
+	seen := make(map[string]bool)
+	var conflicts []policy.Conflict
+	for i := range pods {
+		pod := &pods[i]
+		if !hasProxySidecar(pod) {
+			continue
+		}
+		candidates, nsLabels, err := candidates(pod.Namespace)
+		if err != nil {
+			return nil, err
+		}
+
+		// self is evaluated as reconciled, not as listed from the cache
+		selected := []policy.Source{self}
+		for _, candidate := range candidates {
+			if sameSource(candidate, self) || candidate.Audit() {
+				continue
+			}
+			if ok, err := candidate.Selects(pod, nsLabels); err == nil && ok {
+				selected = append(selected, candidate)
+			}
+		}
+
+		for _, conflict := range policy.Conflicts(selected) {
+			if !sameSource(conflict.Winner, self) && !sameSource(conflict.Loser, self) {
+				continue
+			}
+			key := strings.ToLower(conflict.Header) + "/" + displayName(conflict.Winner, policy.Source{}) + "/" + displayName(conflict.Loser, policy.Source{})
+			if !seen[key] {
+				seen[key] = true
+				conflicts = append(conflicts, conflict)
+			}
+		}
+	}
+	return conflicts, nil
+}
+
+// setConflictCondition sets the Conflict condition on a policy status from
+// the conflicts returned by policyConflicts for self.
+func setConflictCondition(policyStatus *ctxforgev1beta1.HeaderPropagationPolicyStatus, generation int64, self policy.Source, conflicts []policy.Conflict) {
+	var overridden, overrides []string
+	for _, conflict := range conflicts {
+		if conflict.Loser.Name == self.Name && conflict.Loser.Namespace == self.Namespace {
+			overridden = append(overridden, fmt.Sprintf("%s by %s", conflict.Header, displayName(conflict.Winner, self)))
+		} else {
+			overrides = append(overrides, fmt.Sprintf("%s of %s", conflict.Header, displayName(conflict.Loser, self)))
+		}
+	}
+	sort.Strings(overridden)
+	sort.Strings(overrides)
+
+	condition := metav1.Condition{
+		Type:               ConditionTypeConflict,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+	}
+	switch {
+	case len(overridden) > 0:
+		condition.Reason = "HeadersOverridden"
+		condition.Message = "Headers configured with higher precedence by other policies: " + strings.Join(overridden, ", ")
+	case len(overrides) > 0:
+		condition.Reason = "OverridesHeaders"
+		condition.Message = "Headers overridden in other policies: " + strings.Join(overrides, ", ")
+	default:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "NoConflicts"
+		condition.Message = "No other policy selecting the same pods configures these headers"
+	}
+	meta.SetStatusCondition(&policyStatus.Conditions, condition)
+}
+
+// sameSource reports whether a and b are the same policy.
+func sameSource(a, b policy.Source) bool {
+	return a.Name == b.Name && a.Namespace == b.Namespace
+}
+
+// displayName names a policy as seen from self: namespaced policies outside
+// self's namespace, as seen by cluster policies, are qualified as
+// namespace/name.
+func displayName(source, self policy.Source) string {
+	if source.Namespace != "" && source.Namespace != self.Namespace {
+		return source.Namespace + "/" + source.Name
+	}
+	return source.Name
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+)
+
+var _ = Describe("Policy conflicts", func() {
+	const namespace = "policy-conflicts"
+
+	ctx := context.Background()
+	winnerKey := types.NamespacedName{Name: "tracing", Namespace: namespace}
+	loserKey := types.NamespacedName{Name: "tenant", Namespace: namespace}
+
+	newConflictingPolicy := func(key types.NamespacedName, priority int32, headers ...string) *ctxforgev1beta1.HeaderPropagationPolicy {
+		rule := ctxforgev1beta1.PropagationRule{}
+		for _, header := range headers {
+			rule.Headers = append(rule.Headers, ctxforgev1beta1.HeaderConfig{Name: header})
+		}
+		return &ctxforgev1beta1.HeaderPropagationPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+			Spec: ctxforgev1beta1.HeaderPropagationPolicySpec{
+				Priority:         priority,
+				PropagationRules: []ctxforgev1beta1.PropagationRule{rule},
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}
+		Expect(client.IgnoreAlreadyExists(k8sClient.Create(ctx, ns))).To(Succeed())
+
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: namespace},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{
+				{Name: "main-app", Image: "nginx:latest"},
+				{Name: "ctxforge-proxy", Image: "ghcr.io/bgruszka/contextforge-proxy:0.1.0"},
+			}},
+		}
+		Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+		pod.Status.Phase = corev1.PodRunning
+		Expect(k8sClient.Status().Update(ctx, pod)).To(Succeed())
+
+		Expect(k8sClient.Create(ctx, newConflictingPolicy(winnerKey, 10, "x-request-id"))).To(Succeed())
+		Expect(k8sClient.Create(ctx, newConflictingPolicy(loserKey, 0, "X-Request-Id", "x-tenant-id"))).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(k8sClient.DeleteAllOf(ctx, &ctxforgev1beta1.HeaderPropagationPolicy{}, client.InNamespace(namespace))).To(Succeed())
+		Expect(k8sClient.DeleteAllOf(ctx, &corev1.Pod{}, client.InNamespace(namespace))).To(Succeed())
+	})
+
+	It("should report the overridden headers on both policies", func() {
+		controllerReconciler := &HeaderPropagationPolicyReconciler{
+			Client: k8sClient,
+			Scheme: k8sClient.Scheme(),
+		}
+
+		for _, key := range []types.NamespacedName{winnerKey, loserKey} {
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		loser := &ctxforgev1beta1.HeaderPropagationPolicy{}
+		Expect(k8sClient.Get(ctx, loserKey, loser)).To(Succeed())
+		conflict := meta.FindStatusCondition(loser.Status.Conditions, ConditionTypeConflict)
+		Expect(conflict).NotTo(BeNil())
+		Expect(conflict.Status).To(Equal(metav1.ConditionTrue))
+		Expect(conflict.Reason).To(Equal("HeadersOverridden"))
+		Expect(conflict.Message).To(ContainSubstring("x-request-id by tracing"))
+
+		winner := &ctxforgev1beta1.HeaderPropagationPolicy{}
+		Expect(k8sClient.Get(ctx, winnerKey, winner)).To(Succeed())
+		conflict = meta.FindStatusCondition(winner.Status.Conditions, ConditionTypeConflict)
+		Expect(conflict).NotTo(BeNil())
+		Expect(conflict.Status).To(Equal(metav1.ConditionTrue))
+		Expect(conflict.Reason).To(Equal("OverridesHeaders"))
+		Expect(conflict.Message).To(ContainSubstring("x-request-id of tenant"))
+	})
+})
@@ -0,0 +1,158 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+	"github.com/bgruszka/contextforge/internal/istio"
+	ctxforgepolicy "github.com/bgruszka/contextforge/internal/policy"
+)
+
+// EnvoyFilterReconciler keeps an Istio EnvoyFilter for each policy applying in
+// a namespace whose rules Envoy can implement (see istio.Translatable), so
+// that the pod webhook can leave the sidecar out of Istio pods. Requests are
+// keyed by namespace name.
+type EnvoyFilterReconciler struct {
+	client.Client
+	// Defaults are folded into the policies; nil if there are none.
+	Defaults *ctxforgepolicy.DefaultsSource
+}
+
+// +kubebuilder:rbac:groups=networking.istio.io,resources=envoyfilters,verbs=get;list;watch;create;update;delete
+
+// Reconcile creates, updates and deletes the EnvoyFilters of the namespace's
+// policies.
+func (r *EnvoyFilterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	ns := &corev1.Namespace{}
+	if err := r.Get(ctx, types.NamespacedName{Name: req.Name}, ns); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !ns.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	sources, err := namespaceSources(ctx, r.Client, ns.Name)
+	if err != nil {
+		log.Error(err, "Failed to list policies for EnvoyFilters")
+		return ctrl.Result{}, err
+	}
+	desired := make(map[string]*unstructured.Unstructured)
+	sources = r.Defaults.Get(ctx).ApplyAll(sources)
+	for _, source := range envoyFilterSources(sources, ns.Name, labels.Set(ns.Labels)) {
+		filter := istio.EnvoyFilter(source, ns.Name)
+		filter.SetLabels(map[string]string{"app.kubernetes.io/managed-by": "contextforge"})
+		desired[filter.GetName()] = filter
+	}
+
+	existing := &unstructured.UnstructuredList{}
+	existing.SetGroupVersionKind(istio.EnvoyFilterGVK.GroupVersion().WithKind(istio.EnvoyFilterGVK.Kind + "List"))
+	if err := r.List(ctx, existing, client.InNamespace(ns.Name),
+		client.MatchingLabels{"app.kubernetes.io/managed-by": "contextforge"}); err != nil {
+		log.Error(err, "Failed to list EnvoyFilters")
+		return ctrl.Result{}, err
+	}
+	for i := range existing.Items {
+		filter := &existing.Items[i]
+		want, ok := desired[filter.GetName()]
+		delete(desired, filter.GetName())
+		switch {
+		case !ok:
+			if err := r.Delete(ctx, filter); client.IgnoreNotFound(err) != nil {
+				log.Error(err, "Failed to delete EnvoyFilter", "envoyFilter", filter.GetName())
+				return ctrl.Result{}, err
+			}
+			log.Info("Deleted EnvoyFilter of a policy no longer applying", "envoyFilter", filter.GetName())
+		case !equality.Semantic.DeepEqual(filter.Object["spec"], want.Object["spec"]):
+			filter.Object["spec"] = want.Object["spec"]
+			if err := r.Update(ctx, filter); err != nil {
+				log.Error(err, "Failed to update EnvoyFilter", "envoyFilter", filter.GetName())
+				return ctrl.Result{}, err
+			}
+			log.Info("Updated EnvoyFilter", "envoyFilter", filter.GetName())
+		}
+	}
+	for _, filter := range desired {
+		if err := r.Create(ctx, filter); err != nil {
+			log.Error(err, "Failed to create EnvoyFilter", "envoyFilter", filter.GetName())
+			return ctrl.Result{}, err
+		}
+		log.Info("Created EnvoyFilter", "envoyFilter", filter.GetName())
+	}
+	return ctrl.Result{}, nil
+}
+
+// envoyFilterSources returns the sources applying in the namespace that an
+// EnvoyFilter can implement. nsLabels are the namespace's labels. Sources
+// in Audit mode or with invalid selectors or rules are skipped, as the pod
+// webhook ignores them too.
+func envoyFilterSources(sources []ctxforgepolicy.Source, namespace string, nsLabels labels.Set) []ctxforgepolicy.Source {
+	var translated []ctxforgepolicy.Source
+	for _, source := range ctxforgepolicy.Enforced(sources) {
+		if source.Cluster() {
+			namespaceSelector, err := ctxforgepolicy.Selector(source.Spec.NamespaceSelector)
+			if err != nil || !namespaceSelector.Matches(nsLabels) {
+				continue
+			}
+		} else if source.Namespace != namespace {
+			continue
+		}
+		if _, err := ctxforgepolicy.Selector(source.Spec.PodSelector); err != nil {
+			continue
+		}
+		if ctxforgepolicy.Validate(source.Spec) != nil || istio.Translatable(source.Spec) != nil {
+			continue
+		}
+		translated = append(translated, source)
+	}
+	return translated
+}
+
+// SetupWithManager sets up the controller with the Manager. A namespace is
+// reconciled when it, one of its policies, any ClusterHeaderPropagationPolicy,
+// the policy defaults or one of its EnvoyFilters managed by the operator
+// change.
+func (r *EnvoyFilterReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	managed := builder.WithPredicates(predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return obj.GetLabels()["app.kubernetes.io/managed-by"] == "contextforge"
+	}))
+	specChanged := builder.WithPredicates(predicate.GenerationChangedPredicate{})
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("envoyfilter").
+		Watches(&corev1.Namespace{}, handler.EnqueueRequestsFromMapFunc(namespaceRequest)).
+		Watches(newUnstructured(istio.EnvoyFilterGVK), handler.EnqueueRequestsFromMapFunc(namespaceRequest), managed).
+		Watches(&ctxforgev1beta1.HeaderPropagationPolicy{}, handler.EnqueueRequestsFromMapFunc(namespaceRequest), specChanged).
+		Watches(&ctxforgev1beta1.ClusterHeaderPropagationPolicy{}, handler.EnqueueRequestsFromMapFunc(allNamespaceRequests(r.Client)), specChanged).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(allNamespaceRequests(r.Client)),
+			builder.WithPredicates(predicate.NewPredicateFuncs(r.Defaults.IsDefaults))).
+		Complete(r)
+}
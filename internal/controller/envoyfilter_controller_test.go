@@ -0,0 +1,60 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+	ctxforgepolicy "github.com/bgruszka/contextforge/internal/policy"
+)
+
+var _ = Describe("Istio EnvoyFilters", func() {
+	It("should translate the policies applying in a namespace that Envoy can implement", func() {
+		rules := []ctxforgev1beta1.PropagationRule{{Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-request-id", Generate: true}}}}
+		sources := []ctxforgepolicy.Source{
+			{Name: "tracing", Namespace: "shop", Spec: ctxforgev1beta1.HeaderPropagationPolicySpec{PropagationRules: rules}},
+			{Name: "tracing", Namespace: "other", Spec: ctxforgev1beta1.HeaderPropagationPolicySpec{PropagationRules: rules}},
+			{Name: "audited", Namespace: "shop", Spec: ctxforgev1beta1.HeaderPropagationPolicySpec{
+				PropagationRules: rules,
+				Mode:             ctxforgev1beta1.PolicyModeAudit,
+			}},
+			{Name: "sampled", Namespace: "shop", Spec: ctxforgev1beta1.HeaderPropagationPolicySpec{
+				PropagationRules: rules,
+				Sampling:         &ctxforgev1beta1.Sampling{Percentage: 10},
+			}},
+			{Name: ctxforgepolicy.ClusterPrefix + "frontend", Spec: ctxforgev1beta1.HeaderPropagationPolicySpec{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "frontend"}},
+				PropagationRules:  rules,
+			}},
+			{Name: ctxforgepolicy.ClusterPrefix + "backend", Spec: ctxforgev1beta1.HeaderPropagationPolicySpec{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "backend"}},
+				PropagationRules:  rules,
+			}},
+		}
+
+		translated := envoyFilterSources(sources, "shop", labels.Set{"tier": "frontend"})
+		var names []string
+		for _, source := range translated {
+			names = append(names, source.Name)
+		}
+		Expect(names).To(Equal([]string{"tracing", ctxforgepolicy.ClusterPrefix + "frontend"}))
+	})
+})
@@ -0,0 +1,53 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+// Reasons of the Ready policy condition, also recorded as events on the policy.
+const (
+	ReasonPolicyApplied    = "PolicyApplied"
+	ReasonNoMatchingPods   = "NoMatchingPods"
+	ReasonInvalidSelector  = "InvalidSelector"
+	ReasonRuleCompileError = "RuleCompileError"
+	ReasonListPodsFailed   = "ListPodsFailed"
+	ReasonAuditing         = "Auditing"
+)
+
+// recordReadyEvent emits an event on the policy when its Ready condition is
+// about to change from the given conditions to status and reason, so that
+// reconciling an unchanged policy stays quiet. A nil recorder discards it.
+func recordReadyEvent(recorder record.EventRecorder, obj runtime.Object, conditions []metav1.Condition, status metav1.ConditionStatus, reason, message string) {
+	if recorder == nil {
+		return
+	}
+	if current := meta.FindStatusCondition(conditions, ConditionTypeReady); current != nil &&
+		current.Status == status && current.Reason == reason {
+		return
+	}
+	eventType := corev1.EventTypeNormal
+	if status != metav1.ConditionTrue {
+		eventType = corev1.EventTypeWarning
+	}
+	recorder.Event(obj, eventType, reason, message)
+}
@@ -0,0 +1,524 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+	"github.com/bgruszka/contextforge/internal/config"
+	ctxforgepolicy "github.com/bgruszka/contextforge/internal/policy"
+)
+
+// AnnotationGatewayHeaders lists the headers the operator manages in the
+// RequestHeaderModifier filters of an HTTPRoute, so that headers of policies
+// no longer selecting the route are removed again.
+const AnnotationGatewayHeaders = "ctxforge.io/gateway-headers"
+
+// annotationGatewayRoutes lists the HTTPRoutes, as namespace/name, whose
+// policies have a generated ClientTrafficPolicy generate X-Request-Id. The
+// ClientTrafficPolicy is deleted once the list is empty.
+const annotationGatewayRoutes = "ctxforge.io/routes"
+
+// ReasonGatewayHeadersSkipped is the reason of the event recorded on an
+// HTTPRoute whose policies configure headers the gateway can't attach.
+const ReasonGatewayHeadersSkipped = "GatewayHeadersSkipped"
+
+// requestIDHeader is the only header Envoy-based gateways generate.
+const requestIDHeader = "X-Request-Id"
+
+var (
+	// HTTPRouteGVK is the Gateway API HTTPRoute, read as unstructured so the
+	// operator doesn't depend on a Gateway API release.
+	HTTPRouteGVK = schema.GroupVersionKind{Group: "gateway.networking.k8s.io", Version: "v1", Kind: "HTTPRoute"}
+	// ClientTrafficPolicyGVK is the Envoy Gateway policy generated to have a
+	// Gateway generate X-Request-Id.
+	ClientTrafficPolicyGVK = schema.GroupVersionKind{Group: "gateway.envoyproxy.io", Version: "v1alpha1", Kind: "ClientTrafficPolicy"}
+)
+
+// GatewayRouteReconciler attaches the headers of the policies selecting an
+// HTTPRoute through their RouteSelector to the route, so external traffic
+// enters the mesh carrying them. Static headers are set by a
+// RequestHeaderModifier filter on each of the route's rules. Gateway API
+// filters can't generate values, so a generated X-Request-Id is left to the
+// gateway: with EnvoyGateway set, a ClientTrafficPolicy is generated for each
+// of the route's Gateways. Other headers are skipped and reported in an event.
+type GatewayRouteReconciler struct {
+	client.Client
+	Recorder record.EventRecorder
+	// EnvoyGateway generates Envoy Gateway ClientTrafficPolicies, set when
+	// their CRD is installed.
+	EnvoyGateway bool
+	// Defaults are folded into the policies; nil if there are none.
+	Defaults *ctxforgepolicy.DefaultsSource
+}
+
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=httproutes,verbs=get;list;watch;update
+// +kubebuilder:rbac:groups=gateway.envoyproxy.io,resources=clienttrafficpolicies,verbs=get;list;watch;create;update;delete
+
+// Reconcile attaches the headers of the route's policies to it.
+func (r *GatewayRouteReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	route := newUnstructured(HTTPRouteGVK)
+	if err := r.Get(ctx, req.NamespacedName, route); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, r.syncRequestID(ctx, req.NamespacedName, nil)
+	}
+	if !route.GetDeletionTimestamp().IsZero() {
+		return ctrl.Result{}, r.syncRequestID(ctx, req.NamespacedName, nil)
+	}
+
+	ns := &corev1.Namespace{}
+	if err := r.Get(ctx, types.NamespacedName{Name: route.GetNamespace()}, ns); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	sources, err := namespaceSources(ctx, r.Client, ns.Name)
+	if err != nil {
+		log.Error(err, "Failed to list policies for the HTTPRoute")
+		return ctrl.Result{}, err
+	}
+	var selecting []ctxforgepolicy.Source
+	for _, source := range ctxforgepolicy.Enforced(sources) {
+		if selectsRoute(source, route, labels.Set(ns.Labels)) {
+			selecting = append(selecting, source)
+		}
+	}
+
+	selecting = r.Defaults.Get(ctx).ApplyAll(selecting)
+	headers := gatewayHeadersFor(ctxforgepolicy.Merge(selecting).Rules)
+	if headers.requestID && !r.EnvoyGateway {
+		headers.skipped = append(headers.skipped, requestIDHeader)
+		sort.Strings(headers.skipped)
+		headers.requestID = false
+	}
+
+	changed, err := applyGatewayHeaders(route, headers)
+	if err != nil {
+		// A malformed route is rejected by the Gateway API's validation
+		log.Info("Skipping HTTPRoute: unexpected spec", "error", err.Error())
+		return ctrl.Result{}, nil
+	}
+	if changed {
+		if err := r.Update(ctx, route); err != nil {
+			if apierrors.IsConflict(err) {
+				return ctrl.Result{Requeue: true}, nil
+			}
+			log.Error(err, "Failed to update the HTTPRoute")
+			return ctrl.Result{}, err
+		}
+		log.Info("Attached policy headers to HTTPRoute", "headers", route.GetAnnotations()[AnnotationGatewayHeaders])
+	}
+	// Repeated events are aggregated by the recorder
+	if len(headers.skipped) > 0 && r.Recorder != nil {
+		r.Recorder.Eventf(route, corev1.EventTypeWarning, ReasonGatewayHeadersSkipped,
+			"Headers not attached at the gateway, left to the sidecars: %s", strings.Join(headers.skipped, ", "))
+	}
+
+	var gateways []types.NamespacedName
+	if headers.requestID {
+		gateways = parentGateways(route)
+	}
+	return ctrl.Result{}, r.syncRequestID(ctx, req.NamespacedName, gateways)
+}
+
+// selectsRoute reports whether the policy attaches its headers to the route.
+// nsLabels are the labels of the route's namespace. Policies with invalid
+// selectors or rules are skipped, as the pod webhook ignores them too.
+func selectsRoute(source ctxforgepolicy.Source, route client.Object, nsLabels labels.Set) bool {
+	if source.Spec.RouteSelector == nil || ctxforgepolicy.Validate(source.Spec) != nil {
+		return false
+	}
+	if source.Cluster() {
+		namespaceSelector, err := ctxforgepolicy.Selector(source.Spec.NamespaceSelector)
+		if err != nil || !namespaceSelector.Matches(nsLabels) {
+			return false
+		}
+	} else if source.Namespace != route.GetNamespace() {
+		return false
+	}
+	selector, err := ctxforgepolicy.Selector(source.Spec.RouteSelector)
+	return err == nil && selector.Matches(labels.Set(route.GetLabels()))
+}
+
+// gatewayHeader is a header set or added by a RequestHeaderModifier filter.
+type gatewayHeader struct {
+	name, value string
+}
+
+// gatewayHeaders are the headers of merged policy rules that a gateway can
+// attach to the requests of a route.
+type gatewayHeaders struct {
+	// set replaces the request's value, for static headers with onExisting
+	// override.
+	set []gatewayHeader
+	// add appends to the request's value, for static headers with
+	// onExisting append.
+	add []gatewayHeader
+	// requestID is set when X-Request-Id is generated.
+	requestID bool
+	// skipped are the other static and generated headers, sorted.
+	skipped []string
+}
+
+// gatewayHeadersFor sorts the static and generated headers of the rules into
+// what a gateway can attach. Filters apply to every request of a route rule,
+// so rules scoped to paths or methods are skipped, as are static headers that
+// preserve the request's value, which Gateway API filters can't express.
+func gatewayHeadersFor(rules []ctxforgepolicy.Rule) gatewayHeaders {
+	var headers gatewayHeaders
+	seen := make(map[string]bool)
+	for _, rule := range rules {
+		if rule.Response != "" || (!rule.Generate && rule.StaticValue == "") {
+			continue
+		}
+		key := http.CanonicalHeaderKey(rule.Name)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		scoped := rule.PathRegex != "" || len(rule.ExcludePaths) > 0 || len(rule.Methods) > 0
+		switch {
+		case scoped:
+			headers.skipped = append(headers.skipped, key)
+		case rule.Generate:
+			if key == requestIDHeader {
+				headers.requestID = true
+			} else {
+				headers.skipped = append(headers.skipped, key)
+			}
+		case rule.OnExisting == config.OnExistingOverride:
+			headers.set = append(headers.set, gatewayHeader{name: key, value: rule.StaticValue})
+		case rule.OnExisting == config.OnExistingAppend:
+			headers.add = append(headers.add, gatewayHeader{name: key, value: rule.StaticValue})
+		default:
+			headers.skipped = append(headers.skipped, key)
+		}
+	}
+	sort.Strings(headers.skipped)
+	return headers
+}
+
+// applyGatewayHeaders sets the headers in the RequestHeaderModifier filter of
+// each of the route's rules, replacing the headers it managed before, and
+// records them in AnnotationGatewayHeaders. Other entries of the filters are
+// kept. It reports whether the route changed.
+func applyGatewayHeaders(route *unstructured.Unstructured, headers gatewayHeaders) (bool, error) {
+	managed := make(map[string]bool)
+	for _, name := range strings.Split(route.GetAnnotations()[AnnotationGatewayHeaders], ",") {
+		if name != "" {
+			managed[http.CanonicalHeaderKey(name)] = true
+		}
+	}
+	var names []string
+	for _, header := range slices.Concat(headers.set, headers.add) {
+		managed[header.name] = true
+		names = append(names, header.name)
+	}
+	sort.Strings(names)
+
+	original := route.DeepCopy()
+	rules, _, err := unstructured.NestedSlice(route.Object, "spec", "rules")
+	if err != nil {
+		return false, err
+	}
+	for i, rule := range rules {
+		ruleMap, ok := rule.(map[string]any)
+		if !ok {
+			continue
+		}
+		filters, _, err := unstructured.NestedSlice(ruleMap, "filters")
+		if err != nil {
+			return false, err
+		}
+		filters = withHeaderModifier(filters, managed, headers)
+		if len(filters) == 0 {
+			delete(ruleMap, "filters")
+		} else {
+			ruleMap["filters"] = filters
+		}
+		rules[i] = ruleMap
+	}
+	if len(rules) > 0 {
+		if err := unstructured.SetNestedSlice(route.Object, rules, "spec", "rules"); err != nil {
+			return false, err
+		}
+	}
+
+	annotations := route.GetAnnotations()
+	if len(names) > 0 {
+		if annotations == nil {
+			annotations = make(map[string]string)
+		}
+		annotations[AnnotationGatewayHeaders] = strings.Join(names, ",")
+	} else {
+		delete(annotations, AnnotationGatewayHeaders)
+	}
+	route.SetAnnotations(annotations)
+	return !equality.Semantic.DeepEqual(original.Object, route.Object), nil
+}
+
+// withHeaderModifier returns the filters of a route rule with the managed
+// headers dropped from its RequestHeaderModifier filter and the headers added,
+// removing the filter once it's empty.
+func withHeaderModifier(filters []any, managed map[string]bool, headers gatewayHeaders) []any {
+	index := slices.IndexFunc(filters, func(filter any) bool {
+		filterMap, ok := filter.(map[string]any)
+		return ok && filterMap["type"] == "RequestHeaderModifier"
+	})
+	modifier := map[string]any{}
+	if index >= 0 {
+		if existing, ok := filters[index].(map[string]any)["requestHeaderModifier"].(map[string]any); ok {
+			modifier = existing
+		}
+	}
+
+	for field, add := range map[string][]gatewayHeader{"set": headers.set, "add": headers.add} {
+		var entries []any
+		if existing, ok := modifier[field].([]any); ok {
+			for _, entry := range existing {
+				entryMap, ok := entry.(map[string]any)
+				if name, _ := entryMap["name"].(string); ok && managed[http.CanonicalHeaderKey(name)] {
+					continue
+				}
+				entries = append(entries, entry)
+			}
+		}
+		for _, header := range add {
+			entries = append(entries, map[string]any{"name": header.name, "value": header.value})
+		}
+		if len(entries) == 0 {
+			delete(modifier, field)
+		} else {
+			modifier[field] = entries
+		}
+	}
+
+	switch {
+	case len(modifier) == 0 && index >= 0:
+		return slices.Delete(filters, index, index+1)
+	case len(modifier) == 0:
+		return filters
+	case index >= 0:
+		filters[index].(map[string]any)["requestHeaderModifier"] = modifier
+		return filters
+	default:
+		return append(filters, map[string]any{"type": "RequestHeaderModifier", "requestHeaderModifier": modifier})
+	}
+}
+
+// parentGateways returns the Gateways the route attaches to.
+func parentGateways(route *unstructured.Unstructured) []types.NamespacedName {
+	parentRefs, _, _ := unstructured.NestedSlice(route.Object, "spec", "parentRefs")
+	var gateways []types.NamespacedName
+	for _, ref := range parentRefs {
+		refMap, ok := ref.(map[string]any)
+		if !ok {
+			continue
+		}
+		group, _, _ := unstructured.NestedString(refMap, "group")
+		kind, _, _ := unstructured.NestedString(refMap, "kind")
+		if (group != "" && group != HTTPRouteGVK.Group) || (kind != "" && kind != "Gateway") {
+			continue
+		}
+		gateway := types.NamespacedName{Namespace: route.GetNamespace()}
+		gateway.Name, _, _ = unstructured.NestedString(refMap, "name")
+		if namespace, _, _ := unstructured.NestedString(refMap, "namespace"); namespace != "" {
+			gateway.Namespace = namespace
+		}
+		if gateway.Name != "" && !slices.Contains(gateways, gateway) {
+			gateways = append(gateways, gateway)
+		}
+	}
+	return gateways
+}
+
+// clientTrafficPolicyName is the name of the ClientTrafficPolicy generated
+// for a Gateway, in the Gateway's namespace.
+func clientTrafficPolicyName(gateway string) string {
+	return "ctxforge-" + gateway
+}
+
+// syncRequestID records the route on the generated ClientTrafficPolicies of
+// the gateways, creating them as needed, and removes it from the others,
+// deleting those no route needs anymore.
+func (r *GatewayRouteReconciler) syncRequestID(ctx context.Context, route types.NamespacedName, gateways []types.NamespacedName) error {
+	if !r.EnvoyGateway {
+		return nil
+	}
+	log := logf.FromContext(ctx)
+
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(ClientTrafficPolicyGVK.GroupVersion().WithKind(ClientTrafficPolicyGVK.Kind + "List"))
+	if err := r.List(ctx, list, client.MatchingLabels{"app.kubernetes.io/managed-by": "contextforge"}); err != nil {
+		log.Error(err, "Failed to list ClientTrafficPolicies")
+		return err
+	}
+
+	pending := slices.Clone(gateways)
+	for i := range list.Items {
+		policy := &list.Items[i]
+		gateway := types.NamespacedName{
+			Namespace: policy.GetNamespace(),
+			Name:      strings.TrimPrefix(policy.GetName(), clientTrafficPolicyName("")),
+		}
+		wanted := slices.Contains(gateways, gateway)
+		pending = slices.DeleteFunc(pending, func(g types.NamespacedName) bool { return g == gateway })
+
+		routes := splitRoutes(policy.GetAnnotations()[annotationGatewayRoutes])
+		has := slices.Contains(routes, route.String())
+		switch {
+		case wanted && !has:
+			routes = append(routes, route.String())
+		case !wanted && has:
+			routes = slices.DeleteFunc(routes, func(name string) bool { return name == route.String() })
+		default:
+			continue
+		}
+
+		if len(routes) == 0 {
+			if err := r.Delete(ctx, policy); client.IgnoreNotFound(err) != nil {
+				log.Error(err, "Failed to delete the ClientTrafficPolicy", "gateway", gateway.String())
+				return err
+			}
+			log.Info("Deleted the ClientTrafficPolicy: no route generates X-Request-Id", "gateway", gateway.String())
+			continue
+		}
+		sort.Strings(routes)
+		annotations := policy.GetAnnotations()
+		annotations[annotationGatewayRoutes] = strings.Join(routes, ",")
+		policy.SetAnnotations(annotations)
+		if err := r.Update(ctx, policy); err != nil {
+			log.Error(err, "Failed to update the ClientTrafficPolicy", "gateway", gateway.String())
+			return err
+		}
+	}
+
+	for _, gateway := range pending {
+		policy := newClientTrafficPolicy(gateway, route)
+		if err := r.Create(ctx, policy); err != nil {
+			log.Error(err, "Failed to create the ClientTrafficPolicy", "gateway", gateway.String())
+			return err
+		}
+		log.Info("Created a ClientTrafficPolicy generating X-Request-Id", "gateway", gateway.String())
+	}
+	return nil
+}
+
+// newClientTrafficPolicy returns the ClientTrafficPolicy having the Gateway
+// generate X-Request-Id for requests without one, on behalf of the route.
+func newClientTrafficPolicy(gateway, route types.NamespacedName) *unstructured.Unstructured {
+	policy := newUnstructured(ClientTrafficPolicyGVK)
+	policy.SetNamespace(gateway.Namespace)
+	policy.SetName(clientTrafficPolicyName(gateway.Name))
+	policy.SetLabels(map[string]string{"app.kubernetes.io/managed-by": "contextforge"})
+	policy.SetAnnotations(map[string]string{annotationGatewayRoutes: route.String()})
+	policy.Object["spec"] = map[string]any{
+		"targetRefs": []any{map[string]any{
+			"group": HTTPRouteGVK.Group,
+			"kind":  "Gateway",
+			"name":  gateway.Name,
+		}},
+		"headers": map[string]any{"requestID": "PreserveOrGenerate"},
+	}
+	return policy
+}
+
+// splitRoutes splits the value of annotationGatewayRoutes.
+func splitRoutes(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// newUnstructured returns an empty object of the kind.
+func newUnstructured(gvk schema.GroupVersionKind) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	return obj
+}
+
+// routesInNamespace enqueues the HTTPRoutes of the object's namespace, for a
+// changed HeaderPropagationPolicy or namespace.
+func (r *GatewayRouteReconciler) routesInNamespace(ctx context.Context, obj client.Object) []reconcile.Request {
+	namespace := obj.GetNamespace()
+	if _, ok := obj.(*corev1.Namespace); ok {
+		namespace = obj.GetName()
+	}
+	return r.routeRequests(ctx, client.InNamespace(namespace))
+}
+
+// allRoutes enqueues every HTTPRoute, for a changed
+// ClusterHeaderPropagationPolicy or policy defaults.
+func (r *GatewayRouteReconciler) allRoutes(ctx context.Context, _ client.Object) []reconcile.Request {
+	return r.routeRequests(ctx)
+}
+
+func (r *GatewayRouteReconciler) routeRequests(ctx context.Context, opts ...client.ListOption) []reconcile.Request {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(HTTPRouteGVK.GroupVersion().WithKind(HTTPRouteGVK.Kind + "List"))
+	if err := r.List(ctx, list, opts...); err != nil {
+		logf.FromContext(ctx).Error(err, "Failed to list HTTPRoutes")
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(list.Items))
+	for _, route := range list.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&route)})
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager. An HTTPRoute is
+// reconciled when it, its namespace, a policy in its namespace, any
+// ClusterHeaderPropagationPolicy or the policy defaults change.
+func (r *GatewayRouteReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	specChanged := builder.WithPredicates(predicate.GenerationChangedPredicate{})
+	return ctrl.NewControllerManagedBy(mgr).
+		For(newUnstructured(HTTPRouteGVK)).
+		Watches(&corev1.Namespace{}, handler.EnqueueRequestsFromMapFunc(r.routesInNamespace),
+			builder.WithPredicates(predicate.LabelChangedPredicate{})).
+		Watches(&ctxforgev1beta1.HeaderPropagationPolicy{}, handler.EnqueueRequestsFromMapFunc(r.routesInNamespace), specChanged).
+		Watches(&ctxforgev1beta1.ClusterHeaderPropagationPolicy{}, handler.EnqueueRequestsFromMapFunc(r.allRoutes), specChanged).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.allRoutes),
+			builder.WithPredicates(predicate.NewPredicateFuncs(r.Defaults.IsDefaults))).
+		Named("gatewayroute").
+		Complete(r)
+}
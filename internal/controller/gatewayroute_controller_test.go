@@ -0,0 +1,154 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+	"github.com/bgruszka/contextforge/internal/config"
+	ctxforgepolicy "github.com/bgruszka/contextforge/internal/policy"
+)
+
+var _ = Describe("Gateway API routes", func() {
+	route := func() *unstructured.Unstructured {
+		r := newUnstructured(HTTPRouteGVK)
+		r.SetNamespace("shop")
+		r.SetName("storefront")
+		r.SetLabels(map[string]string{"ctxforge.io/gateway": "true"})
+		r.Object["spec"] = map[string]any{
+			"parentRefs": []any{
+				map[string]any{"name": "edge"},
+				map[string]any{"name": "internal", "namespace": "infra"},
+				map[string]any{"name": "edge"},
+				map[string]any{"group": "", "kind": "Service", "name": "storefront"},
+			},
+			"rules": []any{
+				map[string]any{
+					"filters": []any{map[string]any{
+						"type": "RequestHeaderModifier",
+						"requestHeaderModifier": map[string]any{
+							"set": []any{map[string]any{"name": "X-Owner", "value": "shop"}},
+						},
+					}},
+				},
+				map[string]any{"backendRefs": []any{map[string]any{"name": "storefront"}}},
+			},
+		}
+		return r
+	}
+	modifier := func(r *unstructured.Unstructured, rule int) map[string]any {
+		rules, _, _ := unstructured.NestedSlice(r.Object, "spec", "rules")
+		filters, _, _ := unstructured.NestedSlice(rules[rule].(map[string]any), "filters")
+		Expect(filters).To(HaveLen(1))
+		return filters[0].(map[string]any)["requestHeaderModifier"].(map[string]any)
+	}
+
+	It("should sort policy headers into what the gateway can attach", func() {
+		headers := gatewayHeadersFor([]ctxforgepolicy.Rule{
+			{Name: "x-request-id", Generate: true},
+			{Name: "x-tenant", StaticValue: "acme", OnExisting: config.OnExistingOverride},
+			{Name: "x-tenant", StaticValue: "other", OnExisting: config.OnExistingOverride},
+			{Name: "baggage", StaticValue: "env=prod", OnExisting: config.OnExistingAppend},
+			{Name: "x-env", StaticValue: "prod", OnExisting: config.OnExistingPreserve},
+			{Name: "x-correlation-id", Generate: true, GeneratorType: "ulid"},
+			{Name: "x-api-version", StaticValue: "2", OnExisting: config.OnExistingOverride, PathRegex: "^/api/"},
+			{Name: "x-user-id"},
+			{Name: "x-request-id", Response: config.ResponseEcho},
+		})
+		Expect(headers.requestID).To(BeTrue())
+		Expect(headers.set).To(Equal([]gatewayHeader{{name: "X-Tenant", value: "acme"}}))
+		Expect(headers.add).To(Equal([]gatewayHeader{{name: "Baggage", value: "env=prod"}}))
+		Expect(headers.skipped).To(Equal([]string{"X-Api-Version", "X-Correlation-Id", "X-Env"}))
+	})
+
+	It("should attach headers to every rule of the route and remove them again", func() {
+		r := route()
+		changed, err := applyGatewayHeaders(r, gatewayHeaders{
+			set: []gatewayHeader{{name: "X-Tenant", value: "acme"}},
+			add: []gatewayHeader{{name: "Baggage", value: "env=prod"}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changed).To(BeTrue())
+		Expect(r.GetAnnotations()).To(HaveKeyWithValue(AnnotationGatewayHeaders, "Baggage,X-Tenant"))
+		Expect(modifier(r, 0)["set"]).To(Equal([]any{
+			map[string]any{"name": "X-Owner", "value": "shop"},
+			map[string]any{"name": "X-Tenant", "value": "acme"},
+		}))
+		Expect(modifier(r, 1)["add"]).To(Equal([]any{map[string]any{"name": "Baggage", "value": "env=prod"}}))
+
+		By("leaving an attached route unchanged")
+		changed, err = applyGatewayHeaders(r, gatewayHeaders{
+			set: []gatewayHeader{{name: "X-Tenant", value: "acme"}},
+			add: []gatewayHeader{{name: "Baggage", value: "env=prod"}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changed).To(BeFalse())
+
+		By("removing the headers once no policy selects the route")
+		changed, err = applyGatewayHeaders(r, gatewayHeaders{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changed).To(BeTrue())
+		Expect(r.GetAnnotations()).NotTo(HaveKey(AnnotationGatewayHeaders))
+		Expect(modifier(r, 0)).To(Equal(map[string]any{
+			"set": []any{map[string]any{"name": "X-Owner", "value": "shop"}},
+		}))
+		rules, _, _ := unstructured.NestedSlice(r.Object, "spec", "rules")
+		Expect(rules[1]).NotTo(HaveKey("filters"))
+	})
+
+	It("should select routes by the policy's RouteSelector", func() {
+		spec := ctxforgev1beta1.HeaderPropagationPolicySpec{
+			RouteSelector:    &metav1.LabelSelector{MatchLabels: map[string]string{"ctxforge.io/gateway": "true"}},
+			PropagationRules: []ctxforgev1beta1.PropagationRule{{Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-request-id", Generate: true}}}},
+		}
+		r := route()
+		Expect(selectsRoute(ctxforgepolicy.Source{Name: "edge", Namespace: "shop", Spec: spec}, r, nil)).To(BeTrue())
+		Expect(selectsRoute(ctxforgepolicy.Source{Name: "edge", Namespace: "other", Spec: spec}, r, nil)).To(BeFalse())
+
+		cluster := spec.DeepCopy()
+		cluster.NamespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "frontend"}}
+		Expect(selectsRoute(ctxforgepolicy.Source{Name: ctxforgepolicy.ClusterPrefix + "edge", Spec: *cluster}, r,
+			labels.Set{"tier": "frontend"})).To(BeTrue())
+		Expect(selectsRoute(ctxforgepolicy.Source{Name: ctxforgepolicy.ClusterPrefix + "edge", Spec: *cluster}, r,
+			labels.Set{"tier": "backend"})).To(BeFalse())
+
+		By("ignoring policies without a RouteSelector")
+		spec.RouteSelector = nil
+		Expect(selectsRoute(ctxforgepolicy.Source{Name: "edge", Namespace: "shop", Spec: spec}, r, nil)).To(BeFalse())
+	})
+
+	It("should generate a ClientTrafficPolicy for each Gateway of the route", func() {
+		gateways := parentGateways(route())
+		Expect(gateways).To(Equal([]types.NamespacedName{
+			{Namespace: "shop", Name: "edge"},
+			{Namespace: "infra", Name: "internal"},
+		}))
+
+		policy := newClientTrafficPolicy(gateways[1], types.NamespacedName{Namespace: "shop", Name: "storefront"})
+		Expect(policy.GetNamespace()).To(Equal("infra"))
+		Expect(policy.GetName()).To(Equal("ctxforge-internal"))
+		Expect(policy.GetAnnotations()).To(HaveKeyWithValue(annotationGatewayRoutes, "shop/storefront"))
+		requestID, _, _ := unstructured.NestedString(policy.Object, "spec", "headers", "requestID")
+		Expect(requestID).To(Equal("PreserveOrGenerate"))
+	})
+})
@@ -18,46 +18,66 @@ package controller
 
 import (
 	"context"
+	"sort"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
-	ctxforgev1alpha1 "github.com/bgruszka/contextforge/api/v1alpha1"
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+	ctxforgepolicy "github.com/bgruszka/contextforge/internal/policy"
 )
 
 const (
 	// ConditionTypeReady indicates whether the policy is ready and applied
 	ConditionTypeReady = "Ready"
 
-	// RequeueAfterNoMatches is the requeue interval when no pods match the selector.
-	// We check periodically in case pods are created outside of our watch events.
-	RequeueAfterNoMatches = 30 * time.Second
+	// ReconcileBackoffBase is the delay before retrying a policy whose
+	// reconcile failed; it doubles with each consecutive failure.
+	ReconcileBackoffBase = time.Second
 
-	// RequeueAfterPendingPods is the requeue interval when pods exist but aren't ready.
-	// Shorter interval to quickly detect when pods become ready.
-	RequeueAfterPendingPods = 10 * time.Second
+	// ReconcileBackoffMax caps the delay between retries of a failing policy.
+	ReconcileBackoffMax = 5 * time.Minute
+
+	// MaxMatchedPods caps the pod names listed in a policy's status
+	MaxMatchedPods = 50
 )
 
 // HeaderPropagationPolicyReconciler reconciles a HeaderPropagationPolicy object
 type HeaderPropagationPolicyReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	// Recorder emits events on policies when their Ready condition changes.
+	Recorder record.EventRecorder
+
+	// Stats summarizes the traffic of the policy's sidecars in its status;
+	// nil disables it.
+	Stats *StatsCollector
+
+	// ResyncInterval reconciles each policy periodically on top of the
+	// events of its pods and policies; zero disables it.
+	ResyncInterval time.Duration
 }
 
 // +kubebuilder:rbac:groups=ctxforge.ctxforge.io,resources=headerpropagationpolicies,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=ctxforge.ctxforge.io,resources=headerpropagationpolicies/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=ctxforge.ctxforge.io,resources=headerpropagationpolicies/finalizers,verbs=update
 // +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -73,18 +93,26 @@ type HeaderPropagationPolicyReconciler struct {
 func (r *HeaderPropagationPolicyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := logf.FromContext(ctx)
 
+	start := time.Now()
+	defer func() { reconcileDuration.Observe(time.Since(start).Seconds()) }()
+
 	// Fetch the HeaderPropagationPolicy instance
-	policy := &ctxforgev1alpha1.HeaderPropagationPolicy{}
+	policy := &ctxforgev1beta1.HeaderPropagationPolicy{}
 	if err := r.Get(ctx, req.NamespacedName, policy); err != nil {
 		if apierrors.IsNotFound(err) {
 			// Policy was deleted, nothing to do
 			log.Info("HeaderPropagationPolicy resource not found, likely deleted")
+			forgetPolicy(req.NamespacedName)
 			return ctrl.Result{}, nil
 		}
 		log.Error(err, "Failed to fetch HeaderPropagationPolicy")
+		reconcileErrorsTotal.WithLabelValues(ReconcileErrorFetchPolicy).Inc()
 		return ctrl.Result{}, err
 	}
 
+	// Counted for the Rules column of kubectl get
+	policy.Status.Rules = int32(len(policy.Spec.PropagationRules))
+
 	// Build label selector from PodSelector
 	var selector labels.Selector
 	var err error
@@ -92,7 +120,8 @@ func (r *HeaderPropagationPolicyReconciler) Reconcile(ctx context.Context, req c
 		selector, err = metav1.LabelSelectorAsSelector(policy.Spec.PodSelector)
 		if err != nil {
 			log.Error(err, "Failed to parse PodSelector")
-			r.setReadyCondition(ctx, policy, metav1.ConditionFalse, "InvalidSelector", "Failed to parse PodSelector: "+err.Error())
+			reconcileErrorsTotal.WithLabelValues(ReconcileErrorInvalidSelector).Inc()
+			r.updateStatusCondition(ctx, policy, metav1.ConditionFalse, ReasonInvalidSelector, "Failed to parse PodSelector: "+err.Error())
 			return ctrl.Result{}, err
 		}
 	} else {
@@ -100,6 +129,32 @@ func (r *HeaderPropagationPolicyReconciler) Reconcile(ctx context.Context, req c
 		selector = labels.Everything()
 	}
 
+	if _, err := ctxforgepolicy.Selector(policy.Spec.WorkloadSelector); err != nil {
+		log.Error(err, "Failed to parse WorkloadSelector")
+		reconcileErrorsTotal.WithLabelValues(ReconcileErrorInvalidSelector).Inc()
+		r.updateStatusCondition(ctx, policy, metav1.ConditionFalse, ReasonInvalidSelector,
+			"Failed to parse WorkloadSelector: "+err.Error())
+		return ctrl.Result{}, err
+	}
+
+	if _, err := ctxforgepolicy.Selector(policy.Spec.RouteSelector); err != nil {
+		log.Error(err, "Failed to parse RouteSelector")
+		reconcileErrorsTotal.WithLabelValues(ReconcileErrorInvalidSelector).Inc()
+		r.updateStatusCondition(ctx, policy, metav1.ConditionFalse, ReasonInvalidSelector,
+			"Failed to parse RouteSelector: "+err.Error())
+		return ctrl.Result{}, err
+	}
+
+	// Rules the proxy would reject are not retried until the policy changes
+	if err := ctxforgepolicy.Validate(policy.Spec); err != nil {
+		log.Info("Policy has invalid propagation rules", "error", err.Error())
+		reconcileErrorsTotal.WithLabelValues(ReconcileErrorRuleCompile).Inc()
+		policy.Status.RuleErrors = ctxforgepolicy.RuleErrors(policy.Spec)
+		r.updateStatusCondition(ctx, policy, metav1.ConditionFalse, ReasonRuleCompileError, "Invalid propagation rules: "+err.Error())
+		return ctrl.Result{}, nil
+	}
+	policy.Status.RuleErrors = nil
+
 	// List pods matching the selector in the same namespace
 	podList := &corev1.PodList{}
 	listOpts := []client.ListOption{
@@ -108,35 +163,13 @@ func (r *HeaderPropagationPolicyReconciler) Reconcile(ctx context.Context, req c
 	}
 	if err := r.List(ctx, podList, listOpts...); err != nil {
 		log.Error(err, "Failed to list pods")
-		r.setReadyCondition(ctx, policy, metav1.ConditionFalse, "ListPodsFailed", "Failed to list pods: "+err.Error())
+		reconcileErrorsTotal.WithLabelValues(ReconcileErrorListPods).Inc()
+		r.setReadyCondition(ctx, policy, metav1.ConditionFalse, ReasonListPodsFailed, "Failed to list pods: "+err.Error())
 		return ctrl.Result{}, err
 	}
 
 	// Count pods by state
-	var matchedPods int32
-	var pendingPods int32
-	var totalSelectorMatches int32
-
-	for _, pod := range podList.Items {
-		// Check if the pod has the ctxforge sidecar
-		hasSidecar := false
-		for _, container := range pod.Spec.Containers {
-			if container.Name == "ctxforge-proxy" {
-				hasSidecar = true
-				break
-			}
-		}
-
-		if hasSidecar {
-			totalSelectorMatches++
-			switch pod.Status.Phase {
-			case corev1.PodRunning:
-				matchedPods++
-			case corev1.PodPending:
-				pendingPods++
-			}
-		}
-	}
+	namespaces, matchedPods, pendingPods, totalSelectorMatches := sidecarPodCounts(podList.Items)
 
 	// Determine if status changed
 	statusChanged := policy.Status.AppliedToPods != matchedPods ||
@@ -145,22 +178,79 @@ func (r *HeaderPropagationPolicyReconciler) Reconcile(ctx context.Context, req c
 	// Update status
 	policy.Status.ObservedGeneration = policy.Generation
 	policy.Status.AppliedToPods = matchedPods
+	policy.Status.Namespaces = namespaces
+	policy.Status.MatchedPods, policy.Status.MatchedPodsTruncated = matchedPodNames(podList.Items, false)
+
+	// A policy in Audit mode applies to no pod; it reports those it would
+	// leave uncovered instead
+	self := ctxforgepolicy.FromPolicy(policy)
+	if self.Audit() {
+		audit := auditPods(podList.Items, policy.Spec, false)
+		recordAuditEvent(r.Recorder, policy, policy.Status.Audit, audit)
+		policy.Status.Audit = audit
+		matchedPods = 0
+		policy.Status.AppliedToPods = 0
+		policy.Status.Namespaces = nil
+		policy.Status.MatchedPods, policy.Status.MatchedPodsTruncated = nil, false
+	} else {
+		policy.Status.Audit = nil
+	}
 
 	// Set Ready condition
-	if matchedPods > 0 {
-		r.setReadyCondition(ctx, policy, metav1.ConditionTrue, "PolicyApplied",
+	if self.Audit() {
+		r.setReadyCondition(ctx, policy, metav1.ConditionTrue, ReasonAuditing,
+			"Policy is in Audit mode and changes no pods; see status.audit for the pods it doesn't cover")
+	} else if matchedPods > 0 {
+		r.setReadyCondition(ctx, policy, metav1.ConditionTrue, ReasonPolicyApplied,
 			"Policy is applied to pods with contextforge-proxy sidecar")
 	} else {
-		r.setReadyCondition(ctx, policy, metav1.ConditionFalse, "NoMatchingPods",
+		r.setReadyCondition(ctx, policy, metav1.ConditionFalse, ReasonNoMatchingPods,
 			"No running pods with contextforge-proxy sidecar match the selector")
 	}
 
+	now := time.Now()
+	health := assessPods(podList.Items, now)
+	if self.Audit() {
+		health = podHealth{}
+		clearHealthConditions(&policy.Status)
+	} else {
+		setHealthConditions(&policy.Status, policy.Generation, health)
+	}
+
+	// Surface headers this policy shares with others selecting the same pods
+	conflicts, err := policyConflicts(ctx, r.Client, self, podList.Items)
+	if err != nil {
+		log.Error(err, "Failed to evaluate policy conflicts")
+		reconcileErrorsTotal.WithLabelValues(ReconcileErrorListPods).Inc()
+		return ctrl.Result{}, err
+	}
+	setConflictCondition(&policy.Status, policy.Generation, self, conflicts)
+	policy.Status.AnnotatedPods = annotatedPodCount(podList.Items, self)
+	policy.Status.AdoptedPods = adoptedPodCount(podList.Items, self)
+
+	// Restart the selected workloads when the policy changed materially
+	revision, err := restartWorkloads(ctx, r.Client, r.Recorder, policy, self, policy.Status.RolloutRevision, []string{policy.Namespace})
+	if err != nil {
+		log.Error(err, "Failed to restart workloads")
+		reconcileErrorsTotal.WithLabelValues(ReconcileErrorRestart).Inc()
+		return ctrl.Result{}, err
+	}
+	policy.Status.RolloutRevision = revision
+
+	if r.Stats != nil {
+		policy.Status.Stats = r.Stats.Stats(ctx, policy.Spec, podList.Items)
+	}
+
 	// Update the status
 	if err := r.Status().Update(ctx, policy); err != nil {
 		log.Error(err, "Failed to update HeaderPropagationPolicy status")
+		reconcileErrorsTotal.WithLabelValues(ReconcileErrorUpdateStatus).Inc()
 		return ctrl.Result{}, err
 	}
 
+	recordPolicyApplied(req.NamespacedName, matchedPods)
+	recordPolicyAudit(req.NamespacedName, policy.Status.Audit)
+
 	log.Info("Reconciled HeaderPropagationPolicy",
 		"appliedToPods", matchedPods,
 		"pendingPods", pendingPods,
@@ -168,42 +258,127 @@ func (r *HeaderPropagationPolicyReconciler) Reconcile(ctx context.Context, req c
 		"selector", selector.String(),
 		"statusChanged", statusChanged)
 
-	// Determine requeue strategy based on current state
-	//
-	// Event-driven reconciliation handles most changes (pod create/delete/update),
-	// but periodic requeue is needed for edge cases:
-	// 1. Pod phase transitions (Pending -> Running) may not trigger events
-	// 2. Pods created in other namespaces that match a cluster-wide policy
-	// 3. Recovery from controller restarts
-	//
-	// Optimization: Only requeue when there's a reason to check again
-	if pendingPods > 0 {
-		// Pods are starting up, check again soon
-		return ctrl.Result{RequeueAfter: RequeueAfterPendingPods}, nil
+	// Pod and policy changes trigger reconciles, failures are retried with
+	// backoff; only the passing of time needs a requeue
+	return ctrl.Result{RequeueAfter: requeueAfter(health, now, r.Stats, r.ResyncInterval)}, nil
+}
+
+// requeueAfter returns when a policy must be reconciled again without any
+// event: once the grace period of a syncing pod ends, once the stats window
+// elapsed, or at the resync interval, whichever comes first. Zero waits for
+// the next event.
+func requeueAfter(health podHealth, now time.Time, stats *StatsCollector, resync time.Duration) time.Duration {
+	var after time.Duration
+	shorten := func(d time.Duration) {
+		if d > 0 && (after == 0 || d < after) {
+			after = d
+		}
+	}
+	if !health.nextRejection.IsZero() {
+		shorten(health.nextRejection.Sub(now))
+	}
+	if stats != nil {
+		shorten(stats.Window)
+	}
+	shorten(resync)
+	return after
+}
+
+// policyRateLimiter backs off the retries of a failing policy exponentially,
+// from ReconcileBackoffBase up to ReconcileBackoffMax, instead of retrying
+// it within milliseconds.
+func policyRateLimiter() workqueue.TypedRateLimiter[reconcile.Request] {
+	return workqueue.NewTypedItemExponentialFailureRateLimiter[reconcile.Request](ReconcileBackoffBase, ReconcileBackoffMax)
+}
+
+// hasProxySidecar checks if the pod has the ctxforge sidecar, either as a
+// regular container or as a native sidecar init container.
+func hasProxySidecar(pod *corev1.Pod) bool {
+	for _, container := range pod.Spec.Containers {
+		if container.Name == "ctxforge-proxy" {
+			return true
+		}
+	}
+	for _, container := range pod.Spec.InitContainers {
+		if container.Name == "ctxforge-proxy" {
+			return true
+		}
 	}
+	return false
+}
 
-	if matchedPods == 0 && totalSelectorMatches == 0 {
-		// No matching pods at all - periodic check as fallback
-		// This catches pods created outside our watch events
-		return ctrl.Result{RequeueAfter: RequeueAfterNoMatches}, nil
+// sidecarPodCounts counts the pods with the ctxforge sidecar. It returns
+// the counts per namespace, sorted by namespace, and the totals of running
+// pods, pending pods and all pods with the sidecar.
+func sidecarPodCounts(pods []corev1.Pod) (namespaces []ctxforgev1beta1.NamespacePolicyStatus, running, pending, total int32) {
+	byNamespace := map[string]*ctxforgev1beta1.NamespacePolicyStatus{}
+	for i := range pods {
+		pod := &pods[i]
+		if !hasProxySidecar(pod) {
+			continue
+		}
+		total++
+		status, ok := byNamespace[pod.Namespace]
+		if !ok {
+			status = &ctxforgev1beta1.NamespacePolicyStatus{Namespace: pod.Namespace}
+			byNamespace[pod.Namespace] = status
+		}
+		switch pod.Status.Phase {
+		case corev1.PodRunning:
+			running++
+			status.AppliedToPods++
+		case corev1.PodPending:
+			pending++
+			status.PendingPods++
+		}
+	}
+
+	for _, status := range byNamespace {
+		namespaces = append(namespaces, *status)
+	}
+	sort.Slice(namespaces, func(i, j int) bool { return namespaces[i].Namespace < namespaces[j].Namespace })
+	return namespaces, running, pending, total
+}
+
+// matchedPodNames returns the sorted names of the running pods with the
+// ctxforge sidecar, as namespace/name if qualified, capped at MaxMatchedPods,
+// and whether names were left out.
+func matchedPodNames(pods []corev1.Pod, qualified bool) ([]string, bool) {
+	var names []string
+	for i := range pods {
+		pod := &pods[i]
+		if !hasProxySidecar(pod) || pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		if qualified {
+			names = append(names, pod.Namespace+"/"+pod.Name)
+		} else {
+			names = append(names, pod.Name)
+		}
+	}
+	sort.Strings(names)
+	if len(names) > MaxMatchedPods {
+		return names[:MaxMatchedPods], true
 	}
+	return names, false
+}
 
-	// Pods are running and stable - rely on event-driven reconciliation
-	// No periodic requeue needed; pod events will trigger reconciliation
-	return ctrl.Result{}, nil
+// setReadyCondition sets the Ready condition on the policy and records an
+// event if it changed
+func (r *HeaderPropagationPolicyReconciler) setReadyCondition(_ context.Context, policy *ctxforgev1beta1.HeaderPropagationPolicy, status metav1.ConditionStatus, reason, message string) {
+	recordReadyEvent(r.Recorder, policy, policy.Status.Conditions, status, reason, message)
+	setPolicyReadyCondition(&policy.Status, policy.Generation, status, reason, message)
 }
 
-// setReadyCondition sets the Ready condition on the policy
-func (r *HeaderPropagationPolicyReconciler) setReadyCondition(_ context.Context, policy *ctxforgev1alpha1.HeaderPropagationPolicy, status metav1.ConditionStatus, reason, message string) {
-	condition := metav1.Condition{
-		Type:               ConditionTypeReady,
-		Status:             status,
-		ObservedGeneration: policy.Generation,
-		LastTransitionTime: metav1.Now(),
-		Reason:             reason,
-		Message:            message,
+// updateStatusCondition records a failed reconcile in the policy's Ready
+// condition; the reconcile error is returned by the caller.
+func (r *HeaderPropagationPolicyReconciler) updateStatusCondition(ctx context.Context, policy *ctxforgev1beta1.HeaderPropagationPolicy, status metav1.ConditionStatus, reason, message string) {
+	r.setReadyCondition(ctx, policy, status, reason, message)
+	policy.Status.ObservedGeneration = policy.Generation
+	if err := r.Status().Update(ctx, policy); err != nil {
+		logf.FromContext(ctx).Error(err, "Failed to update HeaderPropagationPolicy status")
+		reconcileErrorsTotal.WithLabelValues(ReconcileErrorUpdateStatus).Inc()
 	}
-	meta.SetStatusCondition(&policy.Status.Conditions, condition)
 }
 
 // findPoliciesForPod returns a list of reconcile requests for all policies
@@ -217,7 +392,7 @@ func (r *HeaderPropagationPolicyReconciler) findPoliciesForPod(ctx context.Conte
 	}
 
 	// List all policies in the pod's namespace
-	policyList := &ctxforgev1alpha1.HeaderPropagationPolicyList{}
+	policyList := &ctxforgev1beta1.HeaderPropagationPolicyList{}
 	if err := r.List(ctx, policyList, client.InNamespace(pod.Namespace)); err != nil {
 		log.Error(err, "Failed to list HeaderPropagationPolicies for pod", "pod", pod.Name)
 		return nil
@@ -247,14 +422,49 @@ func (r *HeaderPropagationPolicyReconciler) findPoliciesForPod(ctx context.Conte
 	return requests
 }
 
+// findPoliciesForPolicy enqueues the policies whose conflicts may change with
+// the given policy: those in its namespace, or all of them for a
+// ClusterHeaderPropagationPolicy.
+func (r *HeaderPropagationPolicyReconciler) findPoliciesForPolicy(ctx context.Context, obj client.Object) []reconcile.Request {
+	policyList := &ctxforgev1beta1.HeaderPropagationPolicyList{}
+	if err := r.List(ctx, policyList, client.InNamespace(obj.GetNamespace())); err != nil {
+		logf.FromContext(ctx).Error(err, "Failed to list HeaderPropagationPolicies for policy", "policy", obj.GetName())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, policy := range policyList.Items {
+		if policy.Namespace == obj.GetNamespace() && policy.Name == obj.GetName() {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: client.ObjectKeyFromObject(&policy),
+		})
+	}
+	return requests
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *HeaderPropagationPolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// Conflicts only change with other policies' specs, not their status
+	specChanged := builder.WithPredicates(predicate.GenerationChangedPredicate{})
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&ctxforgev1alpha1.HeaderPropagationPolicy{}).
+		For(&ctxforgev1beta1.HeaderPropagationPolicy{}).
 		Watches(
 			&corev1.Pod{},
 			handler.EnqueueRequestsFromMapFunc(r.findPoliciesForPod),
 		).
+		Watches(
+			&ctxforgev1beta1.HeaderPropagationPolicy{},
+			handler.EnqueueRequestsFromMapFunc(r.findPoliciesForPolicy),
+			specChanged,
+		).
+		Watches(
+			&ctxforgev1beta1.ClusterHeaderPropagationPolicy{},
+			handler.EnqueueRequestsFromMapFunc(r.findPoliciesForPolicy),
+			specChanged,
+		).
 		Named("headerpropagationpolicy").
+		WithOptions(controller.Options{RateLimiter: policyRateLimiter()}).
 		Complete(r)
 }
@@ -18,21 +18,24 @@ package controller
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
-	ctxforgev1alpha1 "github.com/bgruszka/contextforge/api/v1alpha1"
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
 )
 
 var _ = Describe("HeaderPropagationPolicy Controller", func() {
@@ -48,18 +51,18 @@ var _ = Describe("HeaderPropagationPolicy Controller", func() {
 
 		BeforeEach(func() {
 			By("creating the custom resource for the Kind HeaderPropagationPolicy")
-			headerpropagationpolicy := &ctxforgev1alpha1.HeaderPropagationPolicy{}
+			headerpropagationpolicy := &ctxforgev1beta1.HeaderPropagationPolicy{}
 			err := k8sClient.Get(ctx, typeNamespacedName, headerpropagationpolicy)
 			if err != nil && errors.IsNotFound(err) {
-				resource := &ctxforgev1alpha1.HeaderPropagationPolicy{
+				resource := &ctxforgev1beta1.HeaderPropagationPolicy{
 					ObjectMeta: metav1.ObjectMeta{
 						Name:      resourceName,
 						Namespace: "default",
 					},
-					Spec: ctxforgev1alpha1.HeaderPropagationPolicySpec{
-						PropagationRules: []ctxforgev1alpha1.PropagationRule{
+					Spec: ctxforgev1beta1.HeaderPropagationPolicySpec{
+						PropagationRules: []ctxforgev1beta1.PropagationRule{
 							{
-								Headers: []ctxforgev1alpha1.HeaderConfig{
+								Headers: []ctxforgev1beta1.HeaderConfig{
 									{Name: "x-request-id"},
 								},
 							},
@@ -71,7 +74,7 @@ var _ = Describe("HeaderPropagationPolicy Controller", func() {
 		})
 
 		AfterEach(func() {
-			resource := &ctxforgev1alpha1.HeaderPropagationPolicy{}
+			resource := &ctxforgev1beta1.HeaderPropagationPolicy{}
 			err := k8sClient.Get(ctx, typeNamespacedName, resource)
 			if err == nil {
 				By("Cleanup the specific resource instance HeaderPropagationPolicy")
@@ -97,13 +100,14 @@ var _ = Describe("HeaderPropagationPolicy Controller", func() {
 				NamespacedName: typeNamespacedName,
 			})
 			Expect(err).NotTo(HaveOccurred())
-			// No matching pods - should requeue to check periodically
-			Expect(result.RequeueAfter).To(Equal(RequeueAfterNoMatches))
+			// Pods created later are picked up from their events
+			Expect(result.RequeueAfter).To(BeZero())
 
 			By("Verifying the status was updated")
-			policy := &ctxforgev1alpha1.HeaderPropagationPolicy{}
+			policy := &ctxforgev1beta1.HeaderPropagationPolicy{}
 			Expect(k8sClient.Get(ctx, typeNamespacedName, policy)).To(Succeed())
 			Expect(policy.Status.AppliedToPods).To(Equal(int32(0)))
+			Expect(policy.Status.Rules).To(Equal(int32(1)))
 			Expect(policy.Status.ObservedGeneration).To(Equal(policy.Generation))
 
 			By("Verifying the Ready condition is False due to no matching pods")
@@ -113,9 +117,40 @@ var _ = Describe("HeaderPropagationPolicy Controller", func() {
 			Expect(readyCondition.Reason).To(Equal("NoMatchingPods"))
 		})
 
+		It("should export policy metrics and remove them when the policy is deleted", func() {
+			controllerReconciler := &HeaderPropagationPolicyReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			By("Reconciling the created resource")
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(testutil.ToFloat64(policyAppliedPods.WithLabelValues(resourceName, "default"))).To(Equal(0.0))
+			Expect(testutil.ToFloat64(policiesTotal)).To(BeNumerically(">=", 1))
+			policiesBefore := testutil.ToFloat64(policiesTotal)
+
+			By("Deleting the resource and reconciling again")
+			resource := &ctxforgev1beta1.HeaderPropagationPolicy{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			Eventually(func() bool {
+				return errors.IsNotFound(k8sClient.Get(ctx, typeNamespacedName, resource))
+			}, time.Second*5, time.Millisecond*100).Should(BeTrue())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(testutil.ToFloat64(policiesTotal)).To(Equal(policiesBefore - 1))
+			Expect(policyAppliedPods.DeleteLabelValues(resourceName, "default")).To(BeFalse(), "series should already be removed")
+		})
+
 		It("should return no error for deleted resource", func() {
 			By("Deleting the resource first")
-			resource := &ctxforgev1alpha1.HeaderPropagationPolicy{}
+			resource := &ctxforgev1beta1.HeaderPropagationPolicy{}
 			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
 			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
 
@@ -152,20 +187,20 @@ var _ = Describe("HeaderPropagationPolicy Controller", func() {
 
 		BeforeEach(func() {
 			By("creating a policy with a pod selector")
-			policy := &ctxforgev1alpha1.HeaderPropagationPolicy{
+			policy := &ctxforgev1beta1.HeaderPropagationPolicy{
 				ObjectMeta: metav1.ObjectMeta{
 					Name:      policyName,
 					Namespace: "default",
 				},
-				Spec: ctxforgev1alpha1.HeaderPropagationPolicySpec{
+				Spec: ctxforgev1beta1.HeaderPropagationPolicySpec{
 					PodSelector: &metav1.LabelSelector{
 						MatchLabels: map[string]string{
 							"app": "test-app",
 						},
 					},
-					PropagationRules: []ctxforgev1alpha1.PropagationRule{
+					PropagationRules: []ctxforgev1beta1.PropagationRule{
 						{
-							Headers: []ctxforgev1alpha1.HeaderConfig{
+							Headers: []ctxforgev1beta1.HeaderConfig{
 								{Name: "x-request-id"},
 							},
 						},
@@ -217,7 +252,7 @@ var _ = Describe("HeaderPropagationPolicy Controller", func() {
 
 		AfterEach(func() {
 			By("cleaning up the policy")
-			policy := &ctxforgev1alpha1.HeaderPropagationPolicy{}
+			policy := &ctxforgev1beta1.HeaderPropagationPolicy{}
 			if err := k8sClient.Get(ctx, policyNamespacedName, policy); err == nil {
 				Expect(k8sClient.Delete(ctx, policy)).To(Succeed())
 			}
@@ -245,9 +280,14 @@ var _ = Describe("HeaderPropagationPolicy Controller", func() {
 			Expect(result.RequeueAfter).To(BeZero())
 
 			By("Verifying the status shows 1 applied pod")
-			policy := &ctxforgev1alpha1.HeaderPropagationPolicy{}
+			policy := &ctxforgev1beta1.HeaderPropagationPolicy{}
 			Expect(k8sClient.Get(ctx, policyNamespacedName, policy)).To(Succeed())
 			Expect(policy.Status.AppliedToPods).To(Equal(int32(1)))
+			Expect(policy.Status.Namespaces).To(Equal([]ctxforgev1beta1.NamespacePolicyStatus{
+				{Namespace: "default", AppliedToPods: 1},
+			}))
+			Expect(policy.Status.MatchedPods).To(Equal([]string{podName}))
+			Expect(policy.Status.MatchedPodsTruncated).To(BeFalse())
 
 			By("Verifying the Ready condition is True")
 			readyCondition := meta.FindStatusCondition(policy.Status.Conditions, ConditionTypeReady)
@@ -256,4 +296,113 @@ var _ = Describe("HeaderPropagationPolicy Controller", func() {
 			Expect(readyCondition.Reason).To(Equal("PolicyApplied"))
 		})
 	})
+
+	Context("When listing matched pods", func() {
+		It("should cap the names and flag the truncation", func() {
+			var pods []corev1.Pod
+			for i := range MaxMatchedPods + 2 {
+				pods = append(pods, corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("pod-%03d", i), Namespace: "default"},
+					Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "ctxforge-proxy"}}},
+					Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+				})
+			}
+			pods = append(pods, corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "pod-pending", Namespace: "default"},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "ctxforge-proxy"}}},
+				Status:     corev1.PodStatus{Phase: corev1.PodPending},
+			})
+
+			names, truncated := matchedPodNames(pods, true)
+			Expect(names).To(HaveLen(MaxMatchedPods))
+			Expect(names[0]).To(Equal("default/pod-000"))
+			Expect(truncated).To(BeTrue())
+
+			names, truncated = matchedPodNames(pods[:2], false)
+			Expect(names).To(Equal([]string{"pod-000", "pod-001"}))
+			Expect(truncated).To(BeFalse())
+		})
+	})
+
+	Context("When recording events", func() {
+		const policyName = "test-policy-events"
+
+		ctx := context.Background()
+		policyKey := types.NamespacedName{Name: policyName, Namespace: "default"}
+
+		AfterEach(func() {
+			policy := &ctxforgev1beta1.HeaderPropagationPolicy{}
+			if err := k8sClient.Get(ctx, policyKey, policy); err == nil {
+				Expect(k8sClient.Delete(ctx, policy)).To(Succeed())
+			}
+		})
+
+		createPolicy := func(rules ...ctxforgev1beta1.PropagationRule) {
+			Expect(k8sClient.Create(ctx, &ctxforgev1beta1.HeaderPropagationPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: policyName, Namespace: "default"},
+				Spec: ctxforgev1beta1.HeaderPropagationPolicySpec{
+					PodSelector:      &metav1.LabelSelector{MatchLabels: map[string]string{"app": "no-such-app"}},
+					PropagationRules: rules,
+				},
+			})).To(Succeed())
+		}
+
+		It("should record an event when the Ready condition changes", func() {
+			createPolicy(ctxforgev1beta1.PropagationRule{
+				Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-request-id"}},
+			})
+			recorder := record.NewFakeRecorder(10)
+			controllerReconciler := &HeaderPropagationPolicyReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: recorder,
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: policyKey})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recorder.Events).To(Receive(HavePrefix("Warning NoMatchingPods")))
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: policyKey})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recorder.Events).NotTo(Receive(), "an unchanged condition is not recorded again")
+		})
+
+		It("should report rules the proxy would reject", func() {
+			createPolicy(
+				ctxforgev1beta1.PropagationRule{Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-tenant-id"}}},
+				ctxforgev1beta1.PropagationRule{
+					PathRegex: "^/api/(",
+					Headers:   []ctxforgev1beta1.HeaderConfig{{Name: "x-request-id"}},
+				},
+			)
+			recorder := record.NewFakeRecorder(10)
+			controllerReconciler := &HeaderPropagationPolicyReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: recorder,
+			}
+
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: policyKey})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeZero())
+			Expect(recorder.Events).To(Receive(HavePrefix("Warning RuleCompileError")))
+
+			policy := &ctxforgev1beta1.HeaderPropagationPolicy{}
+			Expect(k8sClient.Get(ctx, policyKey, policy)).To(Succeed())
+			readyCondition := meta.FindStatusCondition(policy.Status.Conditions, ConditionTypeReady)
+			Expect(readyCondition).NotTo(BeNil())
+			Expect(readyCondition.Reason).To(Equal(ReasonRuleCompileError))
+			Expect(readyCondition.Message).To(ContainSubstring("invalid path regex"))
+			Expect(policy.Status.RuleErrors).To(HaveLen(1))
+			Expect(policy.Status.RuleErrors[0].Rule).To(Equal(int32(1)))
+			Expect(policy.Status.RuleErrors[0].Message).To(ContainSubstring("invalid path regex"))
+
+			policy.Spec.PropagationRules[1].PathRegex = "^/api/"
+			Expect(k8sClient.Update(ctx, policy)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: policyKey})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, policyKey, policy)).To(Succeed())
+			Expect(policy.Status.RuleErrors).To(BeEmpty(), "fixed rules are no longer reported")
+		})
+	})
 })
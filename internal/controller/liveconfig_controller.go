@@ -0,0 +1,213 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+	ctxforgepolicy "github.com/bgruszka/contextforge/internal/policy"
+	"github.com/bgruszka/contextforge/internal/rulestream"
+	webhookv1 "github.com/bgruszka/contextforge/internal/webhook/v1"
+)
+
+// RequeueAfterLiveConfigResync is how often pods with live config are
+// re-checked, to pick up changes that raise no watch event, such as header
+// profiles in the sidecar defaults ConfigMap.
+const RequeueAfterLiveConfigResync = 5 * time.Minute
+
+// LiveConfigSyncer resolves the header rules to push to a running sidecar.
+type LiveConfigSyncer interface {
+	// SyncLiveConfig updates the pod's ctxforge.io/live-header-rules
+	// annotation and reports whether it changed.
+	SyncLiveConfig(ctx context.Context, pod *corev1.Pod) (bool, error)
+	// LiveHeaderRules returns the rules to push, or "" to revert the
+	// sidecar to its injected configuration.
+	LiveHeaderRules(ctx context.Context, pod *corev1.Pod) (string, error)
+}
+
+// LiveConfigReconciler pushes header configuration changes to running
+// sidecars. Pods injected with live config get the rules resolved from their
+// annotations, namespace and (Cluster)HeaderPropagationPolicies written to an
+// annotation their sidecar watches through the downward API, or published on
+// the rule stream their sidecar subscribes to.
+type LiveConfigReconciler struct {
+	client.Client
+	Syncer LiveConfigSyncer
+
+	// Streams serves the rule stream; nil leaves streamed pods alone.
+	Streams *rulestream.Server
+	// StreamOnly leaves pods watching the annotation alone, for operators
+	// not allowed to patch pods.
+	StreamOnly bool
+	// Defaults is the policy defaults ConfigMap, whose changes re-sync every
+	// pod; nil if there is none.
+	Defaults *ctxforgepolicy.DefaultsSource
+}
+
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch;patch
+
+// Reconcile patches the pod's live header rules when its configuration no
+// longer matches what the sidecar was injected with.
+func (r *LiveConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	node := rulestream.Node{Namespace: req.Namespace, Pod: req.Name}
+	pod := &corev1.Pod{}
+	if err := r.Get(ctx, req.NamespacedName, pod); err != nil {
+		if apierrors.IsNotFound(err) && r.Streams != nil {
+			r.Streams.Delete(node)
+		}
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !usesLiveConfig(pod) || pod.DeletionTimestamp != nil {
+		return ctrl.Result{}, nil
+	}
+
+	if sidecarEnv(pod, "RULE_STREAM_ADDRESS") {
+		if r.Streams == nil {
+			return ctrl.Result{}, nil
+		}
+		rules, err := r.Syncer.LiveHeaderRules(ctx, pod)
+		if err != nil {
+			log.Info("Skipping live config sync: configuration is invalid", "error", err.Error())
+			return ctrl.Result{RequeueAfter: RequeueAfterLiveConfigResync}, nil
+		}
+		r.Streams.SetRules(node, rules)
+		return ctrl.Result{RequeueAfter: RequeueAfterLiveConfigResync}, nil
+	}
+
+	if r.StreamOnly {
+		return ctrl.Result{}, nil
+	}
+
+	updated := pod.DeepCopy()
+	changed, err := r.Syncer.SyncLiveConfig(ctx, updated)
+	if err != nil {
+		// Invalid configuration is rejected at admission; wait for a fix
+		log.Info("Skipping live config sync: configuration is invalid", "error", err.Error())
+		return ctrl.Result{RequeueAfter: RequeueAfterLiveConfigResync}, nil
+	}
+	if changed {
+		if err := r.Patch(ctx, updated, client.MergeFrom(pod)); err != nil {
+			if apierrors.IsNotFound(err) {
+				return ctrl.Result{}, nil
+			}
+			log.Error(err, "Failed to push live header rules")
+			return ctrl.Result{}, err
+		}
+		log.Info("Pushed header configuration to the sidecar",
+			"live", updated.Annotations[webhookv1.AnnotationLiveHeaderRules] != "")
+	}
+	return ctrl.Result{RequeueAfter: RequeueAfterLiveConfigResync}, nil
+}
+
+// usesLiveConfig reports whether the pod's sidecar picks up header rules at
+// runtime, from the annotation or the rule stream.
+func usesLiveConfig(pod *corev1.Pod) bool {
+	return sidecarEnv(pod, "LIVE_CONFIG_FILE") || sidecarEnv(pod, "RULE_STREAM_ADDRESS")
+}
+
+// sidecarEnv reports whether the pod's proxy container sets the env var.
+func sidecarEnv(pod *corev1.Pod, name string) bool {
+	for _, containers := range [][]corev1.Container{pod.Spec.InitContainers, pod.Spec.Containers} {
+		for _, container := range containers {
+			if container.Name != webhookv1.ProxyContainerName {
+				continue
+			}
+			for _, env := range container.Env {
+				if env.Name == name {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// findLiveConfigPods enqueues the pods with live config in the namespace of a
+// changed HeaderPropagationPolicy, or in a changed Namespace. A changed
+// ClusterHeaderPropagationPolicy has no namespace and enqueues them all, as
+// does a change of the policy defaults.
+func (r *LiveConfigReconciler) findLiveConfigPods(ctx context.Context, obj client.Object) []reconcile.Request {
+	namespace := obj.GetNamespace()
+	switch obj.(type) {
+	case *corev1.Namespace:
+		namespace = obj.GetName()
+	case *corev1.ConfigMap:
+		// The policy defaults apply in every namespace
+		namespace = ""
+	}
+
+	podList := &corev1.PodList{}
+	if err := r.List(ctx, podList, client.InNamespace(namespace)); err != nil {
+		logf.FromContext(ctx).Error(err, "Failed to list pods for live config", "namespace", namespace)
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for i := range podList.Items {
+		if usesLiveConfig(&podList.Items[i]) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: client.ObjectKeyFromObject(&podList.Items[i]),
+			})
+		}
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager. Pods are
+// reconciled when they, their namespace, a HeaderPropagationPolicy in their
+// namespace, any ClusterHeaderPropagationPolicy or the policy defaults change.
+func (r *LiveConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	live := predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		pod, ok := obj.(*corev1.Pod)
+		return ok && usesLiveConfig(pod)
+	})
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&corev1.Pod{}, builder.WithPredicates(live)).
+		Watches(
+			&ctxforgev1beta1.HeaderPropagationPolicy{},
+			handler.EnqueueRequestsFromMapFunc(r.findLiveConfigPods),
+		).
+		Watches(
+			&ctxforgev1beta1.ClusterHeaderPropagationPolicy{},
+			handler.EnqueueRequestsFromMapFunc(r.findLiveConfigPods),
+		).
+		Watches(
+			&corev1.Namespace{},
+			handler.EnqueueRequestsFromMapFunc(r.findLiveConfigPods),
+		).
+		Watches(
+			&corev1.ConfigMap{},
+			handler.EnqueueRequestsFromMapFunc(r.findLiveConfigPods),
+			builder.WithPredicates(predicate.NewPredicateFuncs(r.Defaults.IsDefaults)),
+		).
+		Named("liveconfig").
+		Complete(r)
+}
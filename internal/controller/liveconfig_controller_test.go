@@ -0,0 +1,141 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/bgruszka/contextforge/internal/rulestream"
+	webhookv1 "github.com/bgruszka/contextforge/internal/webhook/v1"
+)
+
+// liveRulesSyncer sets fixed live header rules on every pod.
+type liveRulesSyncer string
+
+func (s liveRulesSyncer) SyncLiveConfig(_ context.Context, pod *corev1.Pod) (bool, error) {
+	if pod.Annotations[webhookv1.AnnotationLiveHeaderRules] == string(s) {
+		return false, nil
+	}
+	pod.Annotations[webhookv1.AnnotationLiveHeaderRules] = string(s)
+	return true, nil
+}
+
+func (s liveRulesSyncer) LiveHeaderRules(context.Context, *corev1.Pod) (string, error) {
+	return string(s), nil
+}
+
+var _ = Describe("LiveConfig Controller", func() {
+	const rules = `[{"name":"x-tenant-id","propagate":true}]`
+
+	ctx := context.Background()
+	key := types.NamespacedName{Name: "live-pod", Namespace: "default"}
+
+	BeforeEach(func() {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        key.Name,
+				Namespace:   key.Namespace,
+				Annotations: map[string]string{webhookv1.AnnotationHeaders: "x-tenant-id"},
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{Name: "app", Image: "nginx"},
+					{
+						Name:  webhookv1.ProxyContainerName,
+						Image: webhookv1.DefaultProxyImage,
+						Env:   []corev1.EnvVar{{Name: "LIVE_CONFIG_FILE", Value: webhookv1.LiveConfigFile}},
+					},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		pod := &corev1.Pod{}
+		if err := k8sClient.Get(ctx, key, pod); err == nil {
+			Expect(k8sClient.Delete(ctx, pod, client.GracePeriodSeconds(0))).To(Succeed())
+		}
+	})
+
+	It("should patch the pushed rules onto the pod", func() {
+		r := &LiveConfigReconciler{Client: k8sClient, Syncer: liveRulesSyncer(rules)}
+		result, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(RequeueAfterLiveConfigResync))
+
+		pod := &corev1.Pod{}
+		Expect(k8sClient.Get(ctx, key, pod)).To(Succeed())
+		Expect(pod.Annotations).To(HaveKeyWithValue(webhookv1.AnnotationLiveHeaderRules, rules))
+		Expect(pod.Annotations).To(HaveKeyWithValue(webhookv1.AnnotationHeaders, "x-tenant-id"))
+	})
+
+	It("should publish rules of streamed pods on the rule stream", func() {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "streamed-pod", Namespace: key.Namespace},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{
+					Name:  webhookv1.ProxyContainerName,
+					Image: webhookv1.DefaultProxyImage,
+					Env:   []corev1.EnvVar{{Name: "RULE_STREAM_ADDRESS", Value: "operator:18000"}},
+				}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+		streamedKey := client.ObjectKeyFromObject(pod)
+		node := rulestream.Node{Namespace: streamedKey.Namespace, Pod: streamedKey.Name}
+
+		streams := rulestream.NewServer()
+		r := &LiveConfigReconciler{Client: k8sClient, Syncer: liveRulesSyncer(rules), Streams: streams}
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: streamedKey})
+		Expect(err).NotTo(HaveOccurred())
+		snapshot, ok := streams.Snapshot(node)
+		Expect(ok).To(BeTrue())
+		Expect(snapshot.HeaderRules).To(Equal(rules))
+
+		Expect(k8sClient.Get(ctx, streamedKey, pod)).To(Succeed())
+		Expect(pod.Annotations).NotTo(HaveKey(webhookv1.AnnotationLiveHeaderRules))
+
+		// The snapshot is dropped with the pod
+		Expect(k8sClient.Delete(ctx, pod, client.GracePeriodSeconds(0))).To(Succeed())
+		Eventually(func() error {
+			_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: streamedKey})
+			if err != nil {
+				return err
+			}
+			if _, ok := streams.Snapshot(node); ok {
+				return errors.New("snapshot not deleted")
+			}
+			return nil
+		}).Should(Succeed())
+	})
+
+	It("should enqueue pods with live config for a changed namespace", func() {
+		r := &LiveConfigReconciler{Client: k8sClient}
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: key.Namespace}}
+		Expect(r.findLiveConfigPods(ctx, ns)).To(ContainElement(reconcile.Request{NamespacedName: key}))
+	})
+})
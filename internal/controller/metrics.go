@@ -0,0 +1,129 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+)
+
+// Reasons used as the "reason" label of reconcileErrorsTotal.
+const (
+	ReconcileErrorFetchPolicy     = "fetch_policy"
+	ReconcileErrorInvalidSelector = "invalid_selector"
+	ReconcileErrorListPods        = "list_pods"
+	ReconcileErrorUpdateStatus    = "update_status"
+	ReconcileErrorRuleCompile     = "rule_compile"
+	ReconcileErrorRestart         = "restart_workloads"
+)
+
+var (
+	// policiesTotal is the number of HeaderPropagationPolicies known to the controller.
+	policiesTotal = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "ctxforge_policies_total",
+		Help: "Number of HeaderPropagationPolicies reconciled by the operator.",
+	})
+
+	// policyAppliedPods is the number of running pods with the sidecar each policy applies to.
+	policyAppliedPods = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ctxforge_policy_applied_pods",
+		Help: "Number of running pods with the proxy sidecar matched by a HeaderPropagationPolicy.",
+	}, []string{"policy", "namespace"})
+
+	// policyUncoveredPods is the number of running pods each policy in Audit
+	// mode selects but doesn't cover, by reason.
+	policyUncoveredPods = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ctxforge_policy_uncovered_pods",
+		Help: "Number of running pods selected by a policy in Audit mode that lack the proxy sidecar or the policy's headers, by reason.",
+	}, []string{"policy", "namespace", "reason"})
+
+	// reconcileErrorsTotal counts failed reconciliations by reason.
+	reconcileErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ctxforge_policy_reconcile_errors_total",
+		Help: "Total number of failed HeaderPropagationPolicy reconciliations, by reason.",
+	}, []string{"reason"})
+
+	// reconcileDuration tracks how long a policy reconciliation takes.
+	reconcileDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "ctxforge_policy_reconcile_duration_seconds",
+		Help:    "Duration of HeaderPropagationPolicy reconciliations in seconds.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	// workloadRestartsTotal counts the workloads restarted to roll out policy changes.
+	workloadRestartsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ctxforge_workload_restarts_total",
+		Help: "Total number of workloads restarted to roll out policy changes, by kind.",
+	}, []string{"kind"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		policiesTotal,
+		policyAppliedPods,
+		policyUncoveredPods,
+		reconcileErrorsTotal,
+		reconcileDuration,
+		workloadRestartsTotal,
+	)
+}
+
+// knownPolicies tracks the policies that have been reconciled, to keep
+// policiesTotal in sync as policies are created and deleted.
+var knownPolicies = struct {
+	sync.Mutex
+	set map[types.NamespacedName]struct{}
+}{set: make(map[types.NamespacedName]struct{})}
+
+// recordPolicyApplied records the number of pods a policy is applied to.
+func recordPolicyApplied(policy types.NamespacedName, appliedPods int32) {
+	knownPolicies.Lock()
+	defer knownPolicies.Unlock()
+
+	knownPolicies.set[policy] = struct{}{}
+	policiesTotal.Set(float64(len(knownPolicies.set)))
+	policyAppliedPods.WithLabelValues(policy.Name, policy.Namespace).Set(float64(appliedPods))
+}
+
+// recordPolicyAudit records the pods a policy in Audit mode doesn't cover, or
+// removes them once audit is nil because the policy is enforced.
+func recordPolicyAudit(policy types.NamespacedName, audit *ctxforgev1beta1.AuditStatus) {
+	if audit == nil {
+		policyUncoveredPods.DeletePartialMatch(prometheus.Labels{"policy": policy.Name, "namespace": policy.Namespace})
+		return
+	}
+	policyUncoveredPods.WithLabelValues(policy.Name, policy.Namespace, UncoveredReasonMissingSidecar).
+		Set(float64(audit.MissingSidecarPods))
+	policyUncoveredPods.WithLabelValues(policy.Name, policy.Namespace, UncoveredReasonMissingHeaders).
+		Set(float64(audit.MissingHeadersPods))
+}
+
+// forgetPolicy removes the metrics of a deleted policy.
+func forgetPolicy(policy types.NamespacedName) {
+	knownPolicies.Lock()
+	defer knownPolicies.Unlock()
+
+	delete(knownPolicies.set, policy)
+	policiesTotal.Set(float64(len(knownPolicies.set)))
+	policyAppliedPods.DeleteLabelValues(policy.Name, policy.Namespace)
+	policyUncoveredPods.DeletePartialMatch(prometheus.Labels{"policy": policy.Name, "namespace": policy.Namespace})
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+	ctxforgepolicy "github.com/bgruszka/contextforge/internal/policy"
+	webhookv1 "github.com/bgruszka/contextforge/internal/webhook/v1"
+)
+
+// PolicyAnnotationSyncer resolves the header configuration that policies give
+// a pod.
+type PolicyAnnotationSyncer interface {
+	// SyncPolicyAnnotations updates the pod's policy-managed
+	// ctxforge.io/headers and ctxforge.io/header-rules annotations and
+	// reports whether they changed.
+	SyncPolicyAnnotations(ctx context.Context, pod *corev1.Pod) (bool, error)
+}
+
+// PodAdopter takes pods whose sidecar was added by hand over on behalf of the
+// policies selecting them.
+type PodAdopter interface {
+	// AdoptPod labels a manually injected pod with the policy owning it and
+	// reports drift of its sidecar from the policies, returning whether the
+	// pod changed.
+	AdoptPod(ctx context.Context, pod *corev1.Pod) (bool, error)
+}
+
+// PolicyAnnotationReconciler keeps the ctxforge.io annotations of injected
+// pods in line with the (Cluster)HeaderPropagationPolicies selecting them, so
+// a pod shows the header configuration its policies currently resolve to.
+type PolicyAnnotationReconciler struct {
+	client.Client
+	Syncer PolicyAnnotationSyncer
+
+	// Adopter adopts manually injected pods; nil leaves them alone.
+	Adopter PodAdopter
+	// Defaults is the policy defaults ConfigMap, whose changes re-sync every
+	// pod; nil if there is none.
+	Defaults *ctxforgepolicy.DefaultsSource
+}
+
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch;patch
+
+// Reconcile patches the pod's policy annotations when they no longer match
+// its policies, and adopts the pod if its sidecar was added by hand.
+func (r *PolicyAnnotationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	pod := &corev1.Pod{}
+	if err := r.Get(ctx, req.NamespacedName, pod); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !hasProxySidecar(pod) || pod.DeletionTimestamp != nil {
+		return ctrl.Result{}, nil
+	}
+
+	updated := pod.DeepCopy()
+	changed, err := r.Syncer.SyncPolicyAnnotations(ctx, updated)
+	if err != nil {
+		// Invalid policies are reported on the policy; wait for a fix
+		log.Info("Skipping policy annotations: configuration is invalid", "error", err.Error())
+		return ctrl.Result{}, nil
+	}
+	if r.Adopter != nil {
+		adopted, err := r.Adopter.AdoptPod(ctx, updated)
+		if err != nil {
+			log.Info("Skipping adoption: configuration is invalid", "error", err.Error())
+		}
+		changed = changed || adopted
+	}
+	if !changed {
+		return ctrl.Result{}, nil
+	}
+	if err := r.Patch(ctx, updated, client.MergeFrom(pod)); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to update policy annotations")
+		return ctrl.Result{}, err
+	}
+	log.Info("Updated pod annotations from policies", "policies", updated.Annotations[webhookv1.AnnotationPolicies])
+	return ctrl.Result{}, nil
+}
+
+// annotatedPodCount counts the running pods with the sidecar whose
+// policy-managed annotations list the policy among their sources.
+func annotatedPodCount(pods []corev1.Pod, self ctxforgepolicy.Source) int32 {
+	var count int32
+	for i := range pods {
+		pod := &pods[i]
+		if !hasProxySidecar(pod) || pod.Status.Phase != corev1.PodRunning ||
+			pod.Annotations[webhookv1.AnnotationPolicyManaged] != webhookv1.AnnotationValueTrue {
+			continue
+		}
+		for _, name := range strings.Split(pod.Annotations[webhookv1.AnnotationPolicies], ",") {
+			if name == self.Name {
+				count++
+				break
+			}
+		}
+	}
+	return count
+}
+
+// adoptedPodCount counts the running pods whose hand-written sidecar the
+// policy adopted.
+func adoptedPodCount(pods []corev1.Pod, self ctxforgepolicy.Source) int32 {
+	owner := webhookv1.AdoptedByLabelValue(self.Name)
+	var count int32
+	for i := range pods {
+		if pods[i].Status.Phase == corev1.PodRunning && pods[i].Labels[webhookv1.LabelAdoptedBy] == owner {
+			count++
+		}
+	}
+	return count
+}
+
+// findSidecarPods enqueues the pods with the sidecar in the namespace of a
+// changed HeaderPropagationPolicy, or in a changed Namespace. A changed
+// ClusterHeaderPropagationPolicy has no namespace and enqueues them all, as
+// does a change of the policy defaults.
+func (r *PolicyAnnotationReconciler) findSidecarPods(ctx context.Context, obj client.Object) []reconcile.Request {
+	namespace := obj.GetNamespace()
+	switch obj.(type) {
+	case *corev1.Namespace:
+		namespace = obj.GetName()
+	case *corev1.ConfigMap:
+		// The policy defaults apply in every namespace
+		namespace = ""
+	}
+
+	podList := &corev1.PodList{}
+	if err := r.List(ctx, podList, client.InNamespace(namespace)); err != nil {
+		logf.FromContext(ctx).Error(err, "Failed to list pods for policy annotations", "namespace", namespace)
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for i := range podList.Items {
+		if hasProxySidecar(&podList.Items[i]) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: client.ObjectKeyFromObject(&podList.Items[i]),
+			})
+		}
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager. Pods with the
+// sidecar are reconciled when they, their namespace, a HeaderPropagationPolicy
+// in their namespace, any ClusterHeaderPropagationPolicy or the policy
+// defaults change.
+func (r *PolicyAnnotationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	injected := predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		pod, ok := obj.(*corev1.Pod)
+		return ok && hasProxySidecar(pod)
+	})
+	specChanged := builder.WithPredicates(predicate.GenerationChangedPredicate{})
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&corev1.Pod{}, builder.WithPredicates(injected)).
+		Watches(
+			&ctxforgev1beta1.HeaderPropagationPolicy{},
+			handler.EnqueueRequestsFromMapFunc(r.findSidecarPods),
+			specChanged,
+		).
+		Watches(
+			&ctxforgev1beta1.ClusterHeaderPropagationPolicy{},
+			handler.EnqueueRequestsFromMapFunc(r.findSidecarPods),
+			specChanged,
+		).
+		Watches(
+			&corev1.Namespace{},
+			handler.EnqueueRequestsFromMapFunc(r.findSidecarPods),
+		).
+		Watches(
+			&corev1.ConfigMap{},
+			handler.EnqueueRequestsFromMapFunc(r.findSidecarPods),
+			builder.WithPredicates(predicate.NewPredicateFuncs(r.Defaults.IsDefaults)),
+		).
+		Named("policyannotation").
+		Complete(r)
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	ctxforgepolicy "github.com/bgruszka/contextforge/internal/policy"
+	webhookv1 "github.com/bgruszka/contextforge/internal/webhook/v1"
+)
+
+// policyHeadersSyncer writes fixed policy-managed headers onto every pod.
+type policyHeadersSyncer string
+
+func (s policyHeadersSyncer) SyncPolicyAnnotations(_ context.Context, pod *corev1.Pod) (bool, error) {
+	if pod.Annotations[webhookv1.AnnotationHeaders] == string(s) {
+		return false, nil
+	}
+	if pod.Annotations == nil {
+		pod.Annotations = make(map[string]string)
+	}
+	pod.Annotations[webhookv1.AnnotationHeaders] = string(s)
+	pod.Annotations[webhookv1.AnnotationPolicies] = "tracing"
+	pod.Annotations[webhookv1.AnnotationPolicyManaged] = webhookv1.AnnotationValueTrue
+	return true, nil
+}
+
+// ownerAdopter labels every pod as adopted by a fixed policy.
+type ownerAdopter string
+
+func (a ownerAdopter) AdoptPod(_ context.Context, pod *corev1.Pod) (bool, error) {
+	if pod.Labels[webhookv1.LabelAdoptedBy] == string(a) {
+		return false, nil
+	}
+	if pod.Labels == nil {
+		pod.Labels = make(map[string]string)
+	}
+	pod.Labels[webhookv1.LabelAdoptedBy] = string(a)
+	return true, nil
+}
+
+var _ = Describe("PolicyAnnotation Controller", func() {
+	ctx := context.Background()
+	key := types.NamespacedName{Name: "annotated-pod", Namespace: "default"}
+
+	BeforeEach(func() {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{Name: "app", Image: "nginx"},
+					{Name: webhookv1.ProxyContainerName, Image: webhookv1.DefaultProxyImage},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		pod := &corev1.Pod{}
+		if err := k8sClient.Get(ctx, key, pod); err == nil {
+			Expect(k8sClient.Delete(ctx, pod, client.GracePeriodSeconds(0))).To(Succeed())
+		}
+	})
+
+	It("should patch the policy annotations onto the pod and count it on the policy", func() {
+		r := &PolicyAnnotationReconciler{Client: k8sClient, Syncer: policyHeadersSyncer("x-request-id")}
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		pod := &corev1.Pod{}
+		Expect(k8sClient.Get(ctx, key, pod)).To(Succeed())
+		Expect(pod.Annotations).To(HaveKeyWithValue(webhookv1.AnnotationHeaders, "x-request-id"))
+		Expect(pod.Annotations).To(HaveKeyWithValue(webhookv1.AnnotationPolicyManaged, "true"))
+
+		pod.Status.Phase = corev1.PodRunning
+		Expect(annotatedPodCount([]corev1.Pod{*pod}, ctxforgepolicy.Source{Name: "tracing", Namespace: "default"})).To(Equal(int32(1)))
+		Expect(annotatedPodCount([]corev1.Pod{*pod}, ctxforgepolicy.Source{Name: "tenant", Namespace: "default"})).To(BeZero())
+	})
+
+	It("should label an adopted pod and count it on its owning policy", func() {
+		r := &PolicyAnnotationReconciler{
+			Client:  k8sClient,
+			Syncer:  policyHeadersSyncer("x-request-id"),
+			Adopter: ownerAdopter("cluster_tenant"),
+		}
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		pod := &corev1.Pod{}
+		Expect(k8sClient.Get(ctx, key, pod)).To(Succeed())
+		Expect(pod.Labels).To(HaveKeyWithValue(webhookv1.LabelAdoptedBy, "cluster_tenant"))
+
+		pod.Status.Phase = corev1.PodRunning
+		tenant := ctxforgepolicy.Source{Name: ctxforgepolicy.ClusterPrefix + "tenant"}
+		Expect(adoptedPodCount([]corev1.Pod{*pod}, tenant)).To(Equal(int32(1)))
+		Expect(adoptedPodCount([]corev1.Pod{*pod}, ctxforgepolicy.Source{Name: "tenant", Namespace: "default"})).To(BeZero())
+	})
+
+	It("should enqueue pods with the sidecar for a changed namespace", func() {
+		r := &PolicyAnnotationReconciler{Client: k8sClient}
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: key.Namespace}}
+		Expect(r.findSidecarPods(ctx, ns)).To(ContainElement(reconcile.Request{NamespacedName: key}))
+	})
+})
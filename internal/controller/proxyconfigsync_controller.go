@@ -0,0 +1,218 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	webhookv1 "github.com/bgruszka/contextforge/internal/webhook/v1"
+)
+
+const (
+	// RequeueAfterConfigPending is the requeue interval while a pod's sidecar
+	// has not yet reported the expected configuration.
+	RequeueAfterConfigPending = 10 * time.Second
+
+	// proxyConfigTimeout bounds a single request to a proxy's /config endpoint.
+	proxyConfigTimeout = 2 * time.Second
+)
+
+// Reasons of the ctxforge.io/proxy-config-synced pod condition.
+const (
+	ReasonConfigSynced     = "Synced"
+	ReasonChecksumMismatch = "ChecksumMismatch"
+	ReasonProxyUnreachable = "Unreachable"
+)
+
+// ProxyConfigFetcher returns the config checksum reported by a pod's proxy.
+type ProxyConfigFetcher func(ctx context.Context, pod *corev1.Pod) (string, error)
+
+// ProxyConfigSyncReconciler sets the ctxforge.io/proxy-config-synced readiness
+// gate condition of injected pods once their sidecar reports the config
+// checksum the webhook recorded in the ctxforge.io/config-checksum annotation.
+type ProxyConfigSyncReconciler struct {
+	client.Client
+
+	// Fetch queries the proxy; nil uses fetchProxyConfig.
+	Fetch ProxyConfigFetcher
+}
+
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=pods/status,verbs=get;update;patch
+
+// Reconcile compares the checksum reported by the pod's proxy with the one
+// it was injected with and records the result as the pod's readiness gate
+// condition. Pods are polled until their sidecar reports the expected checksum.
+func (r *ProxyConfigSyncReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	pod := &corev1.Pod{}
+	if err := r.Get(ctx, req.NamespacedName, pod); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	expected, ok := pod.Annotations[webhookv1.AnnotationConfigChecksum]
+	if !ok || !hasConfigSyncGate(pod) || pod.DeletionTimestamp != nil {
+		return ctrl.Result{}, nil
+	}
+	// The gate only holds back the pod's first readiness; rules pushed to a
+	// running sidecar must not take it out of service while they apply.
+	if configSynced(pod) {
+		return ctrl.Result{}, nil
+	}
+	if pod.Status.PodIP == "" {
+		return ctrl.Result{RequeueAfter: RequeueAfterConfigPending}, nil
+	}
+
+	fetch := r.Fetch
+	if fetch == nil {
+		fetch = fetchProxyConfig
+	}
+
+	status, reason, message := corev1.ConditionTrue, ReasonConfigSynced, "Proxy loaded the injected configuration"
+	reported, err := fetch(ctx, pod)
+	switch {
+	case err != nil:
+		status, reason = corev1.ConditionFalse, ReasonProxyUnreachable
+		message = "Failed to query proxy config: " + err.Error()
+	case reported != expected:
+		status, reason = corev1.ConditionFalse, ReasonChecksumMismatch
+		message = fmt.Sprintf("Proxy reports config %s, expected %s", reported, expected)
+	}
+
+	if setConfigSyncCondition(pod, status, reason, message) {
+		if err := r.Status().Update(ctx, pod); err != nil {
+			if apierrors.IsConflict(err) {
+				return ctrl.Result{Requeue: true}, nil
+			}
+			log.Error(err, "Failed to update proxy config sync condition")
+			return ctrl.Result{}, err
+		}
+		log.V(1).Info("Updated proxy config sync condition", "status", status, "reason", reason)
+	}
+
+	if status != corev1.ConditionTrue {
+		return ctrl.Result{RequeueAfter: RequeueAfterConfigPending}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// hasConfigSyncGate reports whether the pod's readiness waits on the proxy
+// config sync condition.
+func hasConfigSyncGate(pod *corev1.Pod) bool {
+	for _, gate := range pod.Spec.ReadinessGates {
+		if gate.ConditionType == webhookv1.ConditionProxyConfigSynced {
+			return true
+		}
+	}
+	return false
+}
+
+// configSynced reports whether the proxy config sync condition is already true.
+func configSynced(pod *corev1.Pod) bool {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == webhookv1.ConditionProxyConfigSynced {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// setConfigSyncCondition sets the proxy config sync condition on the pod and
+// reports whether it changed.
+func setConfigSyncCondition(pod *corev1.Pod, status corev1.ConditionStatus, reason, message string) bool {
+	for i := range pod.Status.Conditions {
+		cond := &pod.Status.Conditions[i]
+		if cond.Type != webhookv1.ConditionProxyConfigSynced {
+			continue
+		}
+		if cond.Status == status && cond.Reason == reason && cond.Message == message {
+			return false
+		}
+		if cond.Status != status {
+			cond.LastTransitionTime = metav1.Now()
+		}
+		cond.Status, cond.Reason, cond.Message = status, reason, message
+		return true
+	}
+	pod.Status.Conditions = append(pod.Status.Conditions, corev1.PodCondition{
+		Type:               webhookv1.ConditionProxyConfigSynced,
+		Status:             status,
+		LastTransitionTime: metav1.Now(),
+		Reason:             reason,
+		Message:            message,
+	})
+	return true
+}
+
+// fetchProxyConfig reads the checksum from the proxy's admin /config endpoint.
+func fetchProxyConfig(ctx context.Context, pod *corev1.Pod) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, proxyConfigTimeout)
+	defer cancel()
+
+	url := "http://" + net.JoinHostPort(pod.Status.PodIP, strconv.Itoa(webhookv1.ProxyAdminPort)) + "/config"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	if token := webhookv1.OperatorToken(pod); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	var body struct {
+		Checksum string `json:"checksum"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("decoding response: %w", err)
+	}
+	return body.Checksum, nil
+}
+
+// SetupWithManager sets up the controller with the Manager. Only pods carrying
+// the readiness gate are reconciled.
+func (r *ProxyConfigSyncReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	gated := predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		pod, ok := obj.(*corev1.Pod)
+		return ok && hasConfigSyncGate(pod)
+	})
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&corev1.Pod{}, builder.WithPredicates(gated)).
+		Named("proxyconfigsync").
+		Complete(r)
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	webhookv1 "github.com/bgruszka/contextforge/internal/webhook/v1"
+)
+
+var _ = Describe("ProxyConfigSync Controller", func() {
+	const checksum = "sha256:abc"
+
+	ctx := context.Background()
+	key := types.NamespacedName{Name: "gated-pod", Namespace: "default"}
+
+	BeforeEach(func() {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        key.Name,
+				Namespace:   key.Namespace,
+				Annotations: map[string]string{webhookv1.AnnotationConfigChecksum: checksum},
+			},
+			Spec: corev1.PodSpec{
+				Containers:     []corev1.Container{{Name: "app", Image: "nginx"}},
+				ReadinessGates: []corev1.PodReadinessGate{{ConditionType: webhookv1.ConditionProxyConfigSynced}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+		pod.Status.PodIP = "10.0.0.1"
+		Expect(k8sClient.Status().Update(ctx, pod)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		pod := &corev1.Pod{}
+		if err := k8sClient.Get(ctx, key, pod); err == nil {
+			Expect(k8sClient.Delete(ctx, pod, client.GracePeriodSeconds(0))).To(Succeed())
+		}
+	})
+
+	reconcileWith := func(fetch ProxyConfigFetcher) (reconcile.Result, *corev1.PodCondition) {
+		r := &ProxyConfigSyncReconciler{Client: k8sClient, Fetch: fetch}
+		result, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		pod := &corev1.Pod{}
+		Expect(k8sClient.Get(ctx, key, pod)).To(Succeed())
+		for i := range pod.Status.Conditions {
+			if pod.Status.Conditions[i].Type == webhookv1.ConditionProxyConfigSynced {
+				return result, &pod.Status.Conditions[i]
+			}
+		}
+		return result, nil
+	}
+
+	It("should mark the pod synced when the proxy reports the injected checksum", func() {
+		result, cond := reconcileWith(func(context.Context, *corev1.Pod) (string, error) {
+			return checksum, nil
+		})
+		Expect(cond).NotTo(BeNil())
+		Expect(cond.Status).To(Equal(corev1.ConditionTrue))
+		Expect(cond.Reason).To(Equal(ReasonConfigSynced))
+		Expect(result.RequeueAfter).To(BeZero())
+	})
+
+	It("should keep a synced pod ready when its checksum changes later", func() {
+		_, cond := reconcileWith(func(context.Context, *corev1.Pod) (string, error) {
+			return checksum, nil
+		})
+		Expect(cond.Status).To(Equal(corev1.ConditionTrue))
+
+		result, cond := reconcileWith(func(context.Context, *corev1.Pod) (string, error) {
+			return "sha256:pushed", nil
+		})
+		Expect(cond.Status).To(Equal(corev1.ConditionTrue))
+		Expect(result.RequeueAfter).To(BeZero())
+	})
+
+	It("should keep polling while the proxy reports another checksum", func() {
+		result, cond := reconcileWith(func(context.Context, *corev1.Pod) (string, error) {
+			return "sha256:old", nil
+		})
+		Expect(cond).NotTo(BeNil())
+		Expect(cond.Status).To(Equal(corev1.ConditionFalse))
+		Expect(cond.Reason).To(Equal(ReasonChecksumMismatch))
+		Expect(result.RequeueAfter).To(Equal(RequeueAfterConfigPending))
+	})
+
+	It("should report an unreachable proxy", func() {
+		_, cond := reconcileWith(func(context.Context, *corev1.Pod) (string, error) {
+			return "", errors.New("connection refused")
+		})
+		Expect(cond).NotTo(BeNil())
+		Expect(cond.Status).To(Equal(corev1.ConditionFalse))
+		Expect(cond.Reason).To(Equal(ReasonProxyUnreachable))
+	})
+})
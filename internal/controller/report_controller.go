@@ -0,0 +1,262 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+	ctxforgepolicy "github.com/bgruszka/contextforge/internal/policy"
+)
+
+// HeaderPropagationReportReconciler maintains the HeaderPropagationReport of
+// each namespace. Requests are keyed by namespace name.
+type HeaderPropagationReportReconciler struct {
+	client.Client
+}
+
+// +kubebuilder:rbac:groups=ctxforge.ctxforge.io,resources=headerpropagationreports,verbs=get;list;watch;create;update;delete
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+
+// Reconcile writes the namespace's report, or deletes it once no pod in the
+// namespace has the sidecar or is selected by a policy.
+func (r *HeaderPropagationReportReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	ns := &corev1.Namespace{}
+	if err := r.Get(ctx, types.NamespacedName{Name: req.Name}, ns); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !ns.DeletionTimestamp.IsZero() {
+		// The report goes with the namespace
+		return ctrl.Result{}, nil
+	}
+
+	podList := &corev1.PodList{}
+	if err := r.List(ctx, podList, client.InNamespace(ns.Name)); err != nil {
+		return ctrl.Result{}, err
+	}
+	sources, err := namespaceSources(ctx, r.Client, ns.Name)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	summary, workloads := buildReport(podList.Items, sources, labels.Set(ns.Labels))
+
+	report := &ctxforgev1beta1.HeaderPropagationReport{}
+	key := types.NamespacedName{Namespace: ns.Name, Name: ctxforgev1beta1.HeaderPropagationReportName}
+	err = r.Get(ctx, key, report)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+	exists := err == nil
+
+	if len(workloads) == 0 {
+		if exists {
+			log.Info("Deleting HeaderPropagationReport", "namespace", ns.Name)
+			return ctrl.Result{}, client.IgnoreNotFound(r.Delete(ctx, report))
+		}
+		return ctrl.Result{}, nil
+	}
+
+	if !exists {
+		report = &ctxforgev1beta1.HeaderPropagationReport{}
+		report.Namespace, report.Name = key.Namespace, key.Name
+		report.Summary, report.Workloads = summary, workloads
+		log.Info("Creating HeaderPropagationReport", "namespace", ns.Name, "workloads", summary.Workloads)
+		return ctrl.Result{}, client.IgnoreAlreadyExists(r.Create(ctx, report))
+	}
+	if equality.Semantic.DeepEqual(report.Summary, summary) && equality.Semantic.DeepEqual(report.Workloads, workloads) {
+		return ctrl.Result{}, nil
+	}
+	report.Summary, report.Workloads = summary, workloads
+	return ctrl.Result{}, r.Update(ctx, report)
+}
+
+// namespaceSources lists the HeaderPropagationPolicies of the namespace and
+// all ClusterHeaderPropagationPolicies.
+func namespaceSources(ctx context.Context, c client.Reader, namespace string) ([]ctxforgepolicy.Source, error) {
+	policyList := &ctxforgev1beta1.HeaderPropagationPolicyList{}
+	if err := c.List(ctx, policyList, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	clusterPolicyList := &ctxforgev1beta1.ClusterHeaderPropagationPolicyList{}
+	if err := c.List(ctx, clusterPolicyList); err != nil {
+		return nil, err
+	}
+	sources := make([]ctxforgepolicy.Source, 0, len(policyList.Items)+len(clusterPolicyList.Items))
+	for i := range policyList.Items {
+		sources = append(sources, ctxforgepolicy.FromPolicy(&policyList.Items[i]))
+	}
+	for i := range clusterPolicyList.Items {
+		sources = append(sources, ctxforgepolicy.FromClusterPolicy(&clusterPolicyList.Items[i]))
+	}
+	return sources, nil
+}
+
+// buildReport groups the pods that have the sidecar or are selected by one of
+// the sources into workloads. Sources with invalid selectors or rules are
+// skipped, as the pod webhook ignores them too. Finished pods are not counted.
+func buildReport(pods []corev1.Pod, sources []ctxforgepolicy.Source, nsLabels labels.Set) (ctxforgev1beta1.ReportSummary, []ctxforgev1beta1.WorkloadReport) {
+	var valid []ctxforgepolicy.Source
+	for _, source := range sources {
+		if ctxforgepolicy.Validate(source.Spec) == nil {
+			valid = append(valid, source)
+		}
+	}
+
+	type workloadKey struct{ kind, name string }
+	byKey := make(map[workloadKey]*ctxforgev1beta1.WorkloadReport)
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+
+		var policies []string
+		for _, source := range valid {
+			if ok, err := source.Selects(pod, nsLabels); err == nil && ok {
+				policies = append(policies, source.Name)
+			}
+		}
+		injected := hasProxySidecar(pod)
+		if !injected && len(policies) == 0 {
+			continue
+		}
+
+		kind, name := podWorkload(pod)
+		workload, ok := byKey[workloadKey{kind, name}]
+		if !ok {
+			workload = &ctxforgev1beta1.WorkloadReport{Kind: kind, Name: name}
+			byKey[workloadKey{kind, name}] = workload
+		}
+		workload.Pods++
+		if injected {
+			workload.InjectedPods++
+		} else {
+			workload.MissingSidecarPods++
+		}
+		workload.Policies = appendMissing(workload.Policies, policies...)
+	}
+
+	var summary ctxforgev1beta1.ReportSummary
+	workloads := make([]ctxforgev1beta1.WorkloadReport, 0, len(byKey))
+	for _, workload := range byKey {
+		sort.Strings(workload.Policies)
+		summary.Workloads++
+		summary.InjectedPods += workload.InjectedPods
+		summary.MissingSidecarPods += workload.MissingSidecarPods
+		workloads = append(workloads, *workload)
+	}
+	sort.Slice(workloads, func(i, j int) bool {
+		if workloads[i].Kind != workloads[j].Kind {
+			return workloads[i].Kind < workloads[j].Kind
+		}
+		return workloads[i].Name < workloads[j].Name
+	})
+	return summary, workloads
+}
+
+// appendMissing appends the values not in list yet.
+func appendMissing(list []string, values ...string) []string {
+	for _, value := range values {
+		if !slices.Contains(list, value) {
+			list = append(list, value)
+		}
+	}
+	return list
+}
+
+// podWorkload returns the kind and name of the workload owning the pod. A
+// ReplicaSet created by a Deployment is reported as the Deployment, derived
+// from the pod-template-hash suffix of its name; pods without a controller
+// are reported as themselves.
+func podWorkload(pod *corev1.Pod) (string, string) {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return "Pod", pod.Name
+	}
+	if owner.Kind == "ReplicaSet" {
+		if hash := pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey]; hash != "" {
+			if name, ok := strings.CutSuffix(owner.Name, "-"+hash); ok {
+				return "Deployment", name
+			}
+		}
+	}
+	return owner.Kind, owner.Name
+}
+
+// namespaceRequest enqueues the namespace of a changed object, or the
+// namespace itself, for reconcilers keyed by namespace name.
+func namespaceRequest(_ context.Context, obj client.Object) []reconcile.Request {
+	name := obj.GetNamespace()
+	if _, ok := obj.(*corev1.Namespace); ok {
+		name = obj.GetName()
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: name}}}
+}
+
+// allNamespaceRequests returns a map func enqueueing every namespace, for a
+// changed ClusterHeaderPropagationPolicy or policy defaults.
+func allNamespaceRequests(c client.Reader) handler.MapFunc {
+	return func(ctx context.Context, _ client.Object) []reconcile.Request {
+		namespaceList := &corev1.NamespaceList{}
+		if err := c.List(ctx, namespaceList); err != nil {
+			logf.FromContext(ctx).Error(err, "Failed to list namespaces")
+			return nil
+		}
+		requests := make([]reconcile.Request, 0, len(namespaceList.Items))
+		for _, ns := range namespaceList.Items {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: ns.Name}})
+		}
+		return requests
+	}
+}
+
+// SetupWithManager sets up the controller with the Manager. A namespace's
+// report is reconciled when the namespace, its pods, its policies, its report
+// or any ClusterHeaderPropagationPolicy change.
+func (r *HeaderPropagationReportReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	specChanged := builder.WithPredicates(predicate.GenerationChangedPredicate{})
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("headerpropagationreport").
+		Watches(&corev1.Namespace{}, handler.EnqueueRequestsFromMapFunc(namespaceRequest)).
+		Watches(&corev1.Pod{}, handler.EnqueueRequestsFromMapFunc(namespaceRequest)).
+		Watches(&ctxforgev1beta1.HeaderPropagationPolicy{}, handler.EnqueueRequestsFromMapFunc(namespaceRequest), specChanged).
+		Watches(&ctxforgev1beta1.ClusterHeaderPropagationPolicy{}, handler.EnqueueRequestsFromMapFunc(allNamespaceRequests(r.Client)), specChanged).
+		Watches(&ctxforgev1beta1.HeaderPropagationReport{}, handler.EnqueueRequestsFromMapFunc(namespaceRequest)).
+		Complete(r)
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+	ctxforgepolicy "github.com/bgruszka/contextforge/internal/policy"
+	webhookv1 "github.com/bgruszka/contextforge/internal/webhook/v1"
+)
+
+var _ = Describe("HeaderPropagationReport Controller", func() {
+	ctx := context.Background()
+	const namespace = "report-test"
+
+	reportPod := func(name, app string, sidecar bool, owner *metav1.OwnerReference) *corev1.Pod {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: map[string]string{"app": app}},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "nginx"}}},
+		}
+		if sidecar {
+			pod.Spec.Containers = append(pod.Spec.Containers,
+				corev1.Container{Name: webhookv1.ProxyContainerName, Image: webhookv1.DefaultProxyImage})
+		}
+		if owner != nil {
+			pod.OwnerReferences = []metav1.OwnerReference{*owner}
+			pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey] = "5d8f7c9b"
+		}
+		return pod
+	}
+	replicaSet := &metav1.OwnerReference{
+		APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "api-5d8f7c9b", UID: "rs-uid", Controller: ptr.To(true),
+	}
+
+	It("groups pods into workloads with the policies selecting them", func() {
+		policy := &ctxforgev1beta1.HeaderPropagationPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "tracing", Namespace: namespace},
+			Spec: ctxforgev1beta1.HeaderPropagationPolicySpec{
+				PodSelector:      &metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}},
+				PropagationRules: []ctxforgev1beta1.PropagationRule{{Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-request-id"}}}},
+			},
+		}
+		cluster := policy.DeepCopy()
+		cluster.Name, cluster.Namespace = "tenant", ""
+		cluster.Spec.PodSelector = nil
+		sources := []ctxforgepolicy.Source{
+			ctxforgepolicy.FromPolicy(policy),
+			ctxforgepolicy.FromClusterPolicy(&ctxforgev1beta1.ClusterHeaderPropagationPolicy{ObjectMeta: cluster.ObjectMeta, Spec: cluster.Spec}),
+		}
+		pods := []corev1.Pod{
+			*reportPod("api-5d8f7c9b-a", "api", true, replicaSet),
+			*reportPod("api-5d8f7c9b-b", "api", false, replicaSet),
+			*reportPod("debug", "debug", false, nil),
+		}
+		finished := reportPod("job", "api", true, nil)
+		finished.Status.Phase = corev1.PodSucceeded
+		pods = append(pods, *finished)
+
+		summary, workloads := buildReport(pods, sources, labels.Set{})
+		Expect(summary).To(Equal(ctxforgev1beta1.ReportSummary{Workloads: 2, InjectedPods: 1, MissingSidecarPods: 2}))
+		Expect(workloads).To(Equal([]ctxforgev1beta1.WorkloadReport{
+			{Kind: "Deployment", Name: "api", Pods: 2, InjectedPods: 1, MissingSidecarPods: 1, Policies: []string{"clusterheaderpropagationpolicy/tenant", "tracing"}},
+			{Kind: "Pod", Name: "debug", Pods: 1, MissingSidecarPods: 1, Policies: []string{"clusterheaderpropagationpolicy/tenant"}},
+		}))
+
+		summary, workloads = buildReport(pods[2:], sources[:1], labels.Set{})
+		Expect(summary.Workloads).To(BeZero())
+		Expect(workloads).To(BeEmpty())
+	})
+
+	It("creates the namespace's report and deletes it once nothing is left to report", func() {
+		Expect(k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})).To(Succeed())
+		pod := reportPod("api-5d8f7c9b-a", "api", true, replicaSet)
+		Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+
+		r := &HeaderPropagationReportReconciler{Client: k8sClient}
+		req := reconcile.Request{NamespacedName: types.NamespacedName{Name: namespace}}
+		_, err := r.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+
+		report := &ctxforgev1beta1.HeaderPropagationReport{}
+		key := types.NamespacedName{Namespace: namespace, Name: ctxforgev1beta1.HeaderPropagationReportName}
+		Expect(k8sClient.Get(ctx, key, report)).To(Succeed())
+		Expect(report.Summary).To(Equal(ctxforgev1beta1.ReportSummary{Workloads: 1, InjectedPods: 1}))
+		Expect(report.Workloads).To(HaveLen(1))
+		Expect(report.Workloads[0].Name).To(Equal("api"))
+
+		Expect(k8sClient.Delete(ctx, pod, client.GracePeriodSeconds(0))).To(Succeed())
+		_, err = r.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(apierrors.IsNotFound(k8sClient.Get(ctx, key, report))).To(BeTrue())
+	})
+
+	It("enqueues the namespace of a changed object", func() {
+		pod := reportPod("debug", "debug", false, nil)
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}
+		want := []reconcile.Request{{NamespacedName: types.NamespacedName{Name: namespace}}}
+		Expect(namespaceRequest(ctx, pod)).To(Equal(want))
+		Expect(namespaceRequest(ctx, ns)).To(Equal(want))
+	})
+})
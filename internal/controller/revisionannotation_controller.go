@@ -0,0 +1,160 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+	ctxforgepolicy "github.com/bgruszka/contextforge/internal/policy"
+)
+
+// RevisionAnnotationReconciler removes the policy revision annotations that
+// policies with RestartOnChange record on workloads, once the policy is
+// deleted or no longer rolls out to the workload. Left behind, they would make
+// a later policy of the same name restart the workload right away. Requests
+// are keyed by namespace name.
+type RevisionAnnotationReconciler struct {
+	client.Client
+}
+
+// +kubebuilder:rbac:groups=apps,resources=deployments;statefulsets,verbs=get;list;watch;patch
+
+// Reconcile removes the stale revision annotations of the namespace's
+// workloads.
+func (r *RevisionAnnotationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	ns := &corev1.Namespace{}
+	if err := r.Get(ctx, types.NamespacedName{Name: req.Name}, ns); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !ns.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	sources, err := namespaceSources(ctx, r.Client, ns.Name)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	workloads, err := namespaceWorkloads(ctx, r.Client, ns.Name, labels.Everything())
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	for _, w := range workloads {
+		stale := staleRevisionAnnotations(w.obj, ctxforgepolicy.Enforced(sources), labels.Set(ns.Labels))
+		if len(stale) == 0 {
+			continue
+		}
+		patch := client.MergeFrom(w.obj.DeepCopyObject().(client.Object))
+		annotations := w.obj.GetAnnotations()
+		for _, key := range stale {
+			delete(annotations, key)
+		}
+		w.obj.SetAnnotations(annotations)
+		if err := r.Patch(ctx, w.obj, patch); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return ctrl.Result{}, err
+		}
+		log.Info("Removed revision annotations of policies no longer rolling out to workload",
+			"kind", w.kind, "workload", client.ObjectKeyFromObject(w.obj).String(), "annotations", stale)
+	}
+	return ctrl.Result{}, nil
+}
+
+// staleRevisionAnnotations returns the sorted revision annotations of the
+// workload that none of the sources rolls out to it anymore.
+func staleRevisionAnnotations(obj client.Object, sources []ctxforgepolicy.Source, nsLabels labels.Set) []string {
+	live := make(map[string]bool)
+	for _, source := range sources {
+		if rollsOutTo(source, obj, nsLabels) {
+			live[revisionAnnotation(source)] = true
+		}
+	}
+
+	var stale []string
+	for key := range obj.GetAnnotations() {
+		if hasRevisionPrefix(key) && !live[key] {
+			stale = append(stale, key)
+		}
+	}
+	sort.Strings(stale)
+	return stale
+}
+
+// rollsOutTo reports whether the policy restarts the workload on changes.
+// nsLabels are the labels of the workload's namespace.
+func rollsOutTo(source ctxforgepolicy.Source, obj client.Object, nsLabels labels.Set) bool {
+	if !source.Spec.RestartOnChange || source.Spec.WorkloadSelector == nil {
+		return false
+	}
+	if source.Cluster() {
+		namespaceSelector, err := ctxforgepolicy.Selector(source.Spec.NamespaceSelector)
+		if err != nil || !namespaceSelector.Matches(nsLabels) {
+			return false
+		}
+	} else if source.Namespace != obj.GetNamespace() {
+		return false
+	}
+	selector, err := ctxforgepolicy.Selector(source.Spec.WorkloadSelector)
+	return err == nil && selector.Matches(labels.Set(obj.GetLabels()))
+}
+
+// hasRevisionPrefix reports whether the annotation records a policy revision.
+func hasRevisionPrefix(key string) bool {
+	return strings.HasPrefix(key, policyRevisionPrefix) || strings.HasPrefix(key, clusterPolicyRevisionPrefix)
+}
+
+// SetupWithManager sets up the controller with the Manager. A namespace is
+// reconciled when it, one of its policies, any ClusterHeaderPropagationPolicy,
+// or one of its workloads carrying revision annotations change.
+func (r *RevisionAnnotationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	annotated := builder.WithPredicates(predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		for key := range obj.GetAnnotations() {
+			if hasRevisionPrefix(key) {
+				return true
+			}
+		}
+		return false
+	}))
+	specChanged := builder.WithPredicates(predicate.GenerationChangedPredicate{})
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("revisionannotation").
+		Watches(&corev1.Namespace{}, handler.EnqueueRequestsFromMapFunc(namespaceRequest)).
+		Watches(&appsv1.Deployment{}, handler.EnqueueRequestsFromMapFunc(namespaceRequest), annotated).
+		Watches(&appsv1.StatefulSet{}, handler.EnqueueRequestsFromMapFunc(namespaceRequest), annotated).
+		Watches(&ctxforgev1beta1.HeaderPropagationPolicy{}, handler.EnqueueRequestsFromMapFunc(namespaceRequest), specChanged).
+		Watches(&ctxforgev1beta1.ClusterHeaderPropagationPolicy{}, handler.EnqueueRequestsFromMapFunc(allNamespaceRequests(r.Client)), specChanged).
+		Complete(r)
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+	ctxforgepolicy "github.com/bgruszka/contextforge/internal/policy"
+)
+
+var _ = Describe("Revision annotation cleanup", func() {
+	const namespace = "revision-gc"
+	ctx := context.Background()
+
+	rollout := func(name string) *ctxforgev1beta1.HeaderPropagationPolicy {
+		return &ctxforgev1beta1.HeaderPropagationPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec: ctxforgev1beta1.HeaderPropagationPolicySpec{
+				WorkloadSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}},
+				RestartOnChange:  true,
+				PropagationRules: []ctxforgev1beta1.PropagationRule{{Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-request-id"}}}},
+			},
+		}
+	}
+	labeledDeployment := func(annotations map[string]string) *appsv1.Deployment {
+		podLabels := map[string]string{"app": "api"}
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: namespace, Labels: podLabels, Annotations: annotations},
+			Spec: appsv1.DeploymentSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: podLabels},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: podLabels},
+					Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "nginx"}}},
+				},
+			},
+		}
+	}
+
+	It("finds the revisions of policies no longer rolling out to the workload", func() {
+		tracing := rollout("tracing")
+		paused := rollout("paused")
+		paused.Spec.RestartOnChange = false
+		tenant := rollout("tenant")
+		tenant.Namespace = ""
+		tenant.Spec.NamespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}}
+		sources := []ctxforgepolicy.Source{
+			ctxforgepolicy.FromPolicy(tracing),
+			ctxforgepolicy.FromPolicy(paused),
+			ctxforgepolicy.FromClusterPolicy(&ctxforgev1beta1.ClusterHeaderPropagationPolicy{ObjectMeta: tenant.ObjectMeta, Spec: tenant.Spec}),
+		}
+		deployment := labeledDeployment(map[string]string{
+			"policy.ctxforge.io/tracing":       "a1",
+			"policy.ctxforge.io/paused":        "b2",
+			"policy.ctxforge.io/deleted":       "c3",
+			"clusterpolicy.ctxforge.io/tenant": "d4",
+			"example.com/unrelated":            "e5",
+		})
+
+		Expect(staleRevisionAnnotations(deployment, sources, labels.Set{"team": "a"})).To(Equal([]string{
+			"policy.ctxforge.io/deleted", "policy.ctxforge.io/paused",
+		}))
+		Expect(staleRevisionAnnotations(deployment, sources, labels.Set{"team": "b"})).To(Equal([]string{
+			"clusterpolicy.ctxforge.io/tenant", "policy.ctxforge.io/deleted", "policy.ctxforge.io/paused",
+		}))
+	})
+
+	It("removes the revision of a deleted policy from the namespace's workloads", func() {
+		Expect(client.IgnoreAlreadyExists(k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}))).To(Succeed())
+		Expect(k8sClient.Create(ctx, rollout("tracing"))).To(Succeed())
+		Expect(k8sClient.Create(ctx, labeledDeployment(map[string]string{
+			"policy.ctxforge.io/tracing": "a1",
+			"policy.ctxforge.io/deleted": "c3",
+		}))).To(Succeed())
+		DeferCleanup(func() {
+			Expect(k8sClient.DeleteAllOf(ctx, &ctxforgev1beta1.HeaderPropagationPolicy{}, client.InNamespace(namespace))).To(Succeed())
+			Expect(k8sClient.DeleteAllOf(ctx, &appsv1.Deployment{}, client.InNamespace(namespace))).To(Succeed())
+		})
+
+		r := &RevisionAnnotationReconciler{Client: k8sClient}
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: namespace}})
+		Expect(err).NotTo(HaveOccurred())
+
+		deployment := &appsv1.Deployment{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "api", Namespace: namespace}, deployment)).To(Succeed())
+		Expect(deployment.Annotations).To(HaveKey("policy.ctxforge.io/tracing"))
+		Expect(deployment.Annotations).NotTo(HaveKey("policy.ctxforge.io/deleted"))
+	})
+})
@@ -0,0 +1,198 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+	ctxforgepolicy "github.com/bgruszka/contextforge/internal/policy"
+	webhookv1 "github.com/bgruszka/contextforge/internal/webhook/v1"
+)
+
+const (
+	// ReasonWorkloadRestarted is the reason of the event recorded on a policy
+	// for each workload it restarts.
+	ReasonWorkloadRestarted = "WorkloadRestarted"
+
+	// Prefixes of the workload annotations that record the revision of each
+	// policy last rolled out to the workload.
+	policyRevisionPrefix        = "policy.ctxforge.io/"
+	clusterPolicyRevisionPrefix = "clusterpolicy.ctxforge.io/"
+)
+
+// workload is a Deployment or StatefulSet selected by a policy's
+// WorkloadSelector.
+type workload struct {
+	kind     string
+	obj      client.Object
+	template *corev1.PodTemplateSpec
+}
+
+// policyRevision hashes the parts of a policy spec that shape the sidecars it
+// is injected into, so that changing anything else does not restart pods.
+func policyRevision(spec ctxforgev1beta1.HeaderPropagationPolicySpec) string {
+	data, _ := json.Marshal(struct {
+		PodSelector       any
+		NamespaceSelector any
+		Priority          int32
+		PropagationRules  []ctxforgev1beta1.PropagationRule
+		ResponseRules     []ctxforgev1beta1.ResponseRule `json:",omitempty"`
+		Destinations      *ctxforgev1beta1.Destinations  `json:",omitempty"`
+		Sampling          *ctxforgev1beta1.Sampling      `json:",omitempty"`
+		Sidecar           *ctxforgev1beta1.SidecarConfig `json:",omitempty"`
+	}{spec.PodSelector, spec.NamespaceSelector, spec.Priority, spec.PropagationRules, spec.ResponseRules,
+		spec.Destinations, spec.Sampling, spec.Sidecar})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// revisionAnnotation returns the workload annotation recording the policy's
+// revision. Names longer than an annotation name allows are shortened and
+// suffixed with a hash to stay unique.
+func revisionAnnotation(self ctxforgepolicy.Source) string {
+	prefix, name := policyRevisionPrefix, self.Name
+	if self.Cluster() {
+		prefix, name = clusterPolicyRevisionPrefix, strings.TrimPrefix(self.Name, ctxforgepolicy.ClusterPrefix)
+	}
+	if len(name) > 63 {
+		sum := sha256.Sum256([]byte(name))
+		name = name[:54] + "-" + hex.EncodeToString(sum[:4])
+	}
+	return prefix + name
+}
+
+// selectedWorkloads lists the Deployments and StatefulSets in the namespaces
+// that the policy's WorkloadSelector matches.
+func selectedWorkloads(ctx context.Context, c client.Reader, self ctxforgepolicy.Source, namespaces []string) ([]workload, error) {
+	selector, err := ctxforgepolicy.Selector(self.Spec.WorkloadSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid WorkloadSelector: %w", err)
+	}
+
+	var workloads []workload
+	for _, namespace := range namespaces {
+		found, err := namespaceWorkloads(ctx, c, namespace, selector)
+		if err != nil {
+			return nil, err
+		}
+		workloads = append(workloads, found...)
+	}
+	return workloads, nil
+}
+
+// namespaceWorkloads lists the Deployments and StatefulSets in the namespace
+// that the selector matches.
+func namespaceWorkloads(ctx context.Context, c client.Reader, namespace string, selector labels.Selector) ([]workload, error) {
+	var workloads []workload
+	opts := []client.ListOption{client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: selector}}
+	deployments := &appsv1.DeploymentList{}
+	if err := c.List(ctx, deployments, opts...); err != nil {
+		return nil, err
+	}
+	for i := range deployments.Items {
+		d := &deployments.Items[i]
+		workloads = append(workloads, workload{kind: "Deployment", obj: d, template: &d.Spec.Template})
+	}
+	statefulSets := &appsv1.StatefulSetList{}
+	if err := c.List(ctx, statefulSets, opts...); err != nil {
+		return nil, err
+	}
+	for i := range statefulSets.Items {
+		s := &statefulSets.Items[i]
+		workloads = append(workloads, workload{kind: "StatefulSet", obj: s, template: &s.Spec.Template})
+	}
+	return workloads, nil
+}
+
+// +kubebuilder:rbac:groups=apps,resources=deployments;statefulsets,verbs=get;list;watch;patch
+
+// restartWorkloads rolls the policy's current revision out to the workloads
+// in namespaces selected by its WorkloadSelector. A workload that recorded an
+// older revision is restarted by stamping its pod template; one without a
+// record is restarted only if the policy changed since its last rollout,
+// previous, and otherwise just stamped with the revision, since its pods were
+// injected with the current configuration. It returns the revision to record
+// as the policy's RolloutRevision, which is empty while RestartOnChange is
+// off or the policy is in Audit mode.
+func restartWorkloads(ctx context.Context, c client.Client, recorder record.EventRecorder, obj client.Object, self ctxforgepolicy.Source, previous string, namespaces []string) (string, error) {
+	if !self.Spec.RestartOnChange || self.Spec.WorkloadSelector == nil || self.Audit() {
+		return "", nil
+	}
+	log := logf.FromContext(ctx)
+
+	revision := policyRevision(self.Spec)
+	workloads, err := selectedWorkloads(ctx, c, self, namespaces)
+	if err != nil {
+		return "", err
+	}
+
+	key := revisionAnnotation(self)
+	for _, w := range workloads {
+		recorded := w.obj.GetAnnotations()[key]
+		if recorded == revision {
+			continue
+		}
+		restart := recorded != "" || (previous != "" && previous != revision)
+
+		patch := client.MergeFrom(w.obj.DeepCopyObject().(client.Object))
+		annotations := w.obj.GetAnnotations()
+		if annotations == nil {
+			annotations = make(map[string]string)
+		}
+		annotations[key] = revision
+		w.obj.SetAnnotations(annotations)
+		if restart {
+			if w.template.Annotations == nil {
+				w.template.Annotations = make(map[string]string)
+			}
+			w.template.Annotations[webhookv1.AnnotationRestartedAt] = time.Now().UTC().Format(time.RFC3339)
+		}
+		if err := c.Patch(ctx, w.obj, patch); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return "", fmt.Errorf("failed to patch %s %s/%s: %w", w.kind, w.obj.GetNamespace(), w.obj.GetName(), err)
+		}
+		if !restart {
+			continue
+		}
+
+		workloadRestartsTotal.WithLabelValues(w.kind).Inc()
+		log.Info("Restarted workload to roll out policy changes",
+			"kind", w.kind, "workload", client.ObjectKeyFromObject(w.obj).String(), "revision", revision)
+		if recorder != nil {
+			recorder.Eventf(obj, corev1.EventTypeNormal, ReasonWorkloadRestarted,
+				"Restarted %s %s/%s to roll out policy changes", w.kind, w.obj.GetNamespace(), w.obj.GetName())
+		}
+	}
+	return revision, nil
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+	ctxforgepolicy "github.com/bgruszka/contextforge/internal/policy"
+	webhookv1 "github.com/bgruszka/contextforge/internal/webhook/v1"
+)
+
+var _ = Describe("Workload rollout", func() {
+	const namespace = "policy-rollout"
+
+	ctx := context.Background()
+	policyKey := types.NamespacedName{Name: "tracing", Namespace: namespace}
+	deploymentKey := types.NamespacedName{Name: "api", Namespace: namespace}
+
+	BeforeEach(func() {
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}
+		Expect(client.IgnoreAlreadyExists(k8sClient.Create(ctx, ns))).To(Succeed())
+
+		labels := map[string]string{"app": "api"}
+		deployment := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: deploymentKey.Name, Namespace: namespace, Labels: labels},
+			Spec: appsv1.DeploymentSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: labels},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: labels},
+					Spec: corev1.PodSpec{Containers: []corev1.Container{
+						{Name: "main-app", Image: "nginx:latest"},
+					}},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, deployment)).To(Succeed())
+
+		policy := &ctxforgev1beta1.HeaderPropagationPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: policyKey.Name, Namespace: namespace},
+			Spec: ctxforgev1beta1.HeaderPropagationPolicySpec{
+				WorkloadSelector: &metav1.LabelSelector{MatchLabels: labels},
+				RestartOnChange:  true,
+				PropagationRules: []ctxforgev1beta1.PropagationRule{{
+					Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-request-id"}},
+				}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, policy)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(k8sClient.DeleteAllOf(ctx, &ctxforgev1beta1.HeaderPropagationPolicy{}, client.InNamespace(namespace))).To(Succeed())
+		Expect(k8sClient.DeleteAllOf(ctx, &appsv1.Deployment{}, client.InNamespace(namespace))).To(Succeed())
+	})
+
+	It("should restart selected workloads only when the policy changes materially", func() {
+		controllerReconciler := &HeaderPropagationPolicyReconciler{
+			Client: k8sClient,
+			Scheme: k8sClient.Scheme(),
+		}
+		reconcilePolicy := func() {
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: policyKey})
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		By("Recording the revision without restarting on first sight")
+		reconcilePolicy()
+		policy := &ctxforgev1beta1.HeaderPropagationPolicy{}
+		Expect(k8sClient.Get(ctx, policyKey, policy)).To(Succeed())
+		Expect(policy.Status.RolloutRevision).NotTo(BeEmpty())
+		deployment := &appsv1.Deployment{}
+		Expect(k8sClient.Get(ctx, deploymentKey, deployment)).To(Succeed())
+		Expect(deployment.Annotations).To(HaveKeyWithValue("policy.ctxforge.io/tracing", policy.Status.RolloutRevision))
+		Expect(deployment.Spec.Template.Annotations).NotTo(HaveKey(webhookv1.AnnotationRestartedAt))
+
+		By("Leaving workloads alone when only the restart settings change")
+		policy.Spec.WorkloadSelector.MatchExpressions = []metav1.LabelSelectorRequirement{
+			{Key: "app", Operator: metav1.LabelSelectorOpExists},
+		}
+		Expect(k8sClient.Update(ctx, policy)).To(Succeed())
+		reconcilePolicy()
+		Expect(k8sClient.Get(ctx, deploymentKey, deployment)).To(Succeed())
+		Expect(deployment.Spec.Template.Annotations).NotTo(HaveKey(webhookv1.AnnotationRestartedAt))
+
+		By("Restarting when the propagation rules change")
+		Expect(k8sClient.Get(ctx, policyKey, policy)).To(Succeed())
+		policy.Spec.PropagationRules[0].Headers = append(policy.Spec.PropagationRules[0].Headers,
+			ctxforgev1beta1.HeaderConfig{Name: "x-tenant-id"})
+		Expect(k8sClient.Update(ctx, policy)).To(Succeed())
+		reconcilePolicy()
+		Expect(k8sClient.Get(ctx, policyKey, policy)).To(Succeed())
+		Expect(k8sClient.Get(ctx, deploymentKey, deployment)).To(Succeed())
+		Expect(deployment.Annotations).To(HaveKeyWithValue("policy.ctxforge.io/tracing", policy.Status.RolloutRevision))
+		Expect(deployment.Spec.Template.Annotations).To(HaveKey(webhookv1.AnnotationRestartedAt))
+	})
+
+	It("should keep revision annotation names within the annotation name limit", func() {
+		long := strings.Repeat("a", 100)
+		key := revisionAnnotation(ctxforgepolicy.Source{Name: ctxforgepolicy.ClusterPrefix + long})
+		Expect(key).To(HavePrefix("clusterpolicy.ctxforge.io/"))
+		Expect(len(strings.TrimPrefix(key, "clusterpolicy.ctxforge.io/"))).To(Equal(63))
+		Expect(key).NotTo(Equal(revisionAnnotation(ctxforgepolicy.Source{Name: ctxforgepolicy.ClusterPrefix + long + "b"})))
+	})
+})
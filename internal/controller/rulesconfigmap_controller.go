@@ -0,0 +1,198 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"maps"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+	ctxforgepolicy "github.com/bgruszka/contextforge/internal/policy"
+	webhookv1 "github.com/bgruszka/contextforge/internal/webhook/v1"
+)
+
+// RulesRenderer resolves the header rules of a pod for the rules ConfigMap.
+type RulesRenderer interface {
+	// EffectiveHeaderRules returns the pod's header rules in HEADER_RULES
+	// format, or "" if it has none.
+	EffectiveHeaderRules(ctx context.Context, pod *corev1.Pod) (string, error)
+}
+
+// RulesConfigMapReconciler renders the header rules of each namespace's pods
+// that load them from a file into the namespace's ctxforge-rules ConfigMap,
+// keyed by pod name. The sidecars mount the ConfigMap and reload their key,
+// so configuration changes reach them without mutating the pods.
+type RulesConfigMapReconciler struct {
+	client.Client
+	Renderer RulesRenderer
+	// Defaults is the policy defaults ConfigMap, whose changes re-render
+	// every namespace; nil if there is none.
+	Defaults *ctxforgepolicy.DefaultsSource
+}
+
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;delete
+
+// Reconcile renders the rules ConfigMap of the namespace named by the request,
+// deleting it once no pod reads it.
+func (r *RulesConfigMapReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+	namespace := req.Name
+
+	podList := &corev1.PodList{}
+	if err := r.List(ctx, podList, client.InNamespace(namespace)); err != nil {
+		log.Error(err, "Failed to list pods for the rules ConfigMap")
+		return ctrl.Result{}, err
+	}
+	data := map[string]string{}
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if !usesRulesConfigMap(pod) || pod.DeletionTimestamp != nil {
+			continue
+		}
+		rules, err := r.Renderer.EffectiveHeaderRules(ctx, pod)
+		if err != nil {
+			// Invalid configuration is rejected at admission; keep the pod's injected rules
+			log.Info("Skipping pod in the rules ConfigMap: configuration is invalid", "pod", pod.Name, "error", err.Error())
+			continue
+		}
+		if rules != "" {
+			data[pod.Name] = rules
+		}
+	}
+
+	key := types.NamespacedName{Namespace: namespace, Name: webhookv1.RulesConfigMapName}
+	configMap := &corev1.ConfigMap{}
+	err := r.Get(ctx, key, configMap)
+	switch {
+	case apierrors.IsNotFound(err):
+		if len(data) == 0 {
+			return ctrl.Result{}, nil
+		}
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      key.Name,
+				Namespace: key.Namespace,
+				Labels:    map[string]string{"app.kubernetes.io/managed-by": "contextforge"},
+			},
+			Data: data,
+		}
+		if err := r.Create(ctx, configMap); err != nil {
+			log.Error(err, "Failed to create the rules ConfigMap")
+			return ctrl.Result{}, err
+		}
+		log.Info("Created the rules ConfigMap", "pods", len(data))
+	case err != nil:
+		log.Error(err, "Failed to fetch the rules ConfigMap")
+		return ctrl.Result{}, err
+	case len(data) == 0:
+		if err := r.Delete(ctx, configMap); client.IgnoreNotFound(err) != nil {
+			log.Error(err, "Failed to delete the rules ConfigMap")
+			return ctrl.Result{}, err
+		}
+		log.Info("Deleted the rules ConfigMap: no pod reads it")
+		return ctrl.Result{}, nil
+	case !maps.Equal(configMap.Data, data):
+		configMap.Data = data
+		if err := r.Update(ctx, configMap); err != nil {
+			log.Error(err, "Failed to update the rules ConfigMap")
+			return ctrl.Result{}, err
+		}
+		log.Info("Updated the rules ConfigMap", "pods", len(data))
+	}
+
+	// Header profiles in the sidecar defaults raise no watch event
+	return ctrl.Result{RequeueAfter: RequeueAfterLiveConfigResync}, nil
+}
+
+// usesRulesConfigMap reports whether the pod's sidecar loads its header rules
+// from the rules ConfigMap.
+func usesRulesConfigMap(pod *corev1.Pod) bool {
+	return sidecarEnv(pod, "RULES_FILE")
+}
+
+// namespaceOf enqueues the namespace of a changed object.
+func namespaceOf(_ context.Context, obj client.Object) []reconcile.Request {
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: obj.GetNamespace()}}}
+}
+
+// findAllNamespaces enqueues every namespace, for a changed
+// ClusterHeaderPropagationPolicy or policy defaults.
+func (r *RulesConfigMapReconciler) findAllNamespaces(ctx context.Context, _ client.Object) []reconcile.Request {
+	namespaceList := &corev1.NamespaceList{}
+	if err := r.List(ctx, namespaceList); err != nil {
+		logf.FromContext(ctx).Error(err, "Failed to list namespaces for the rules ConfigMap")
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(namespaceList.Items))
+	for _, namespace := range namespaceList.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: namespace.Name}})
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager. A namespace is
+// reconciled when it, its pods reading the rules ConfigMap, its
+// HeaderPropagationPolicies, its rules ConfigMap, any
+// ClusterHeaderPropagationPolicy or the policy defaults change.
+func (r *RulesConfigMapReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	readsRules := predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		pod, ok := obj.(*corev1.Pod)
+		return ok && usesRulesConfigMap(pod)
+	})
+	rulesConfigMap := predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return obj.GetName() == webhookv1.RulesConfigMapName
+	})
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&corev1.Namespace{}).
+		Watches(
+			&corev1.Pod{},
+			handler.EnqueueRequestsFromMapFunc(namespaceOf),
+			builder.WithPredicates(readsRules),
+		).
+		Watches(
+			&corev1.ConfigMap{},
+			handler.EnqueueRequestsFromMapFunc(namespaceOf),
+			builder.WithPredicates(rulesConfigMap),
+		).
+		Watches(
+			&ctxforgev1beta1.HeaderPropagationPolicy{},
+			handler.EnqueueRequestsFromMapFunc(namespaceOf),
+		).
+		Watches(
+			&ctxforgev1beta1.ClusterHeaderPropagationPolicy{},
+			handler.EnqueueRequestsFromMapFunc(r.findAllNamespaces),
+		).
+		Watches(
+			&corev1.ConfigMap{},
+			handler.EnqueueRequestsFromMapFunc(r.findAllNamespaces),
+			builder.WithPredicates(predicate.NewPredicateFuncs(r.Defaults.IsDefaults)),
+		).
+		Named("rulesconfigmap").
+		Complete(r)
+}
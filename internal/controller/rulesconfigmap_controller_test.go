@@ -0,0 +1,92 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	webhookv1 "github.com/bgruszka/contextforge/internal/webhook/v1"
+)
+
+// staticRulesRenderer renders the same rules for every pod.
+type staticRulesRenderer string
+
+func (s staticRulesRenderer) EffectiveHeaderRules(context.Context, *corev1.Pod) (string, error) {
+	return string(s), nil
+}
+
+var _ = Describe("RulesConfigMap Controller", func() {
+	const (
+		namespace = "rules-configmap"
+		rules     = `[{"name":"x-tenant-id","propagate":true}]`
+	)
+
+	ctx := context.Background()
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Name: namespace}}
+	configMapKey := types.NamespacedName{Name: webhookv1.RulesConfigMapName, Namespace: namespace}
+
+	newPod := func(name string, rulesFile bool) *corev1.Pod {
+		sidecar := corev1.Container{Name: webhookv1.ProxyContainerName, Image: webhookv1.DefaultProxyImage}
+		if rulesFile {
+			sidecar.Env = []corev1.EnvVar{{Name: "RULES_FILE", Value: "/etc/ctxforge/rules/$(POD_NAME)"}}
+		}
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "nginx"}, sidecar}},
+		}
+	}
+
+	BeforeEach(func() {
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}
+		Expect(client.IgnoreAlreadyExists(k8sClient.Create(ctx, ns))).To(Succeed())
+		Expect(k8sClient.Create(ctx, newPod("reads-rules", true))).To(Succeed())
+		Expect(k8sClient.Create(ctx, newPod("injected-env", false))).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(k8sClient.DeleteAllOf(ctx, &corev1.Pod{}, client.InNamespace(namespace), client.GracePeriodSeconds(0))).To(Succeed())
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: configMapKey.Name, Namespace: namespace},
+		}))).To(Succeed())
+	})
+
+	It("should render the rules of pods reading the ConfigMap and delete it when none do", func() {
+		r := &RulesConfigMapReconciler{Client: k8sClient, Renderer: staticRulesRenderer(rules)}
+		_, err := r.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+
+		configMap := &corev1.ConfigMap{}
+		Expect(k8sClient.Get(ctx, configMapKey, configMap)).To(Succeed())
+		Expect(configMap.Data).To(Equal(map[string]string{"reads-rules": rules}))
+
+		Expect(k8sClient.Delete(ctx, newPod("reads-rules", true), client.GracePeriodSeconds(0))).To(Succeed())
+		Eventually(func() bool {
+			_, err := r.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			return apierrors.IsNotFound(k8sClient.Get(ctx, configMapKey, &corev1.ConfigMap{}))
+		}).Should(BeTrue())
+	})
+})
@@ -0,0 +1,399 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+	webhookv1 "github.com/bgruszka/contextforge/internal/webhook/v1"
+)
+
+const (
+	// sidecarMetricsTimeout bounds a scrape of a sidecar's metrics
+	sidecarMetricsTimeout = 2 * time.Second
+
+	// statsScrapeInterval is how often the collector looks for pods due for
+	// a scrape, at most; shorter windows are checked once per window
+	statsScrapeInterval = 10 * time.Second
+
+	// statsScrapeConcurrency bounds the sidecars scraped at once
+	statsScrapeConcurrency = 16
+
+	// statsRetention is how many windows a pod's counts are kept after it
+	// was last seen, so a pod selected by several policies isn't forgotten
+	// between their reconciles
+	statsRetention = 3
+
+	metricRequests   = "ctxforge_proxy_requests_total"
+	metricPropagated = "ctxforge_proxy_header_propagated_total"
+	metricGenerated  = "ctxforge_proxy_header_generated_total"
+)
+
+// SidecarMetrics are the counters a proxy sidecar reports on its metrics
+// endpoint. Header counters are keyed by canonical header name.
+type SidecarMetrics struct {
+	Requests   int64
+	Propagated map[string]int64
+	Generated  map[string]int64
+}
+
+// since returns the increase of the counters from previous. A counter lower
+// than before means the proxy restarted, so its whole value is the increase.
+func (m SidecarMetrics) since(previous SidecarMetrics) SidecarMetrics {
+	return SidecarMetrics{
+		Requests:   counterIncrease(m.Requests, previous.Requests),
+		Propagated: counterIncreases(m.Propagated, previous.Propagated),
+		Generated:  counterIncreases(m.Generated, previous.Generated),
+	}
+}
+
+func counterIncrease(current, previous int64) int64 {
+	if current < previous {
+		return current
+	}
+	return current - previous
+}
+
+func counterIncreases(current, previous map[string]int64) map[string]int64 {
+	increases := make(map[string]int64, len(current))
+	for name, value := range current {
+		increases[name] = counterIncrease(value, previous[name])
+	}
+	return increases
+}
+
+// SidecarMetricsFetcher reads the counters of a pod's proxy sidecar.
+type SidecarMetricsFetcher func(ctx context.Context, pod *corev1.Pod) (SidecarMetrics, error)
+
+// StatsCollector scrapes the metrics of policies' sidecars and keeps, for
+// each pod, the increase of its counters over its last complete window. It is
+// shared by the policy reconcilers so a pod selected by several policies is
+// scraped at most once per window. The reconcilers only register pods and
+// read the cached counts; the scrapes run in Start, which the manager calls.
+type StatsCollector struct {
+	// Window is the period the reported counts cover.
+	Window time.Duration
+
+	// Fetch reads a sidecar's counters; nil scrapes the proxy's admin port.
+	Fetch SidecarMetricsFetcher
+
+	// client scrapes the sidecars when Fetch is nil.
+	client *http.Client
+
+	// now is overridden in tests.
+	now func() time.Time
+
+	mu   sync.Mutex
+	pods map[types.UID]*podStats
+}
+
+// podStats is the scrape state of one pod.
+type podStats struct {
+	// target is what a scrape needs of the pod: its name and IP
+	target     *corev1.Pod
+	baseline   SidecarMetrics
+	baselineAt time.Time
+	// last is the increase over the last complete window, nil until one
+	// completed
+	last    *SidecarMetrics
+	lastEnd time.Time
+	seen    time.Time
+}
+
+// NewStatsCollector returns a StatsCollector reporting counts over window.
+func NewStatsCollector(window time.Duration) *StatsCollector {
+	return &StatsCollector{
+		Window: window,
+		client: &http.Client{
+			Timeout: sidecarMetricsTimeout,
+			// Pod IPs are reached directly, never through the operator's
+			// HTTP_PROXY
+			Transport: &http.Transport{
+				DialContext:         (&net.Dialer{Timeout: sidecarMetricsTimeout}).DialContext,
+				MaxIdleConnsPerHost: 1,
+				IdleConnTimeout:     2 * window,
+			},
+		},
+	}
+}
+
+// Start scrapes the registered sidecars whose window elapsed until ctx is
+// done. It implements manager.Runnable.
+func (c *StatsCollector) Start(ctx context.Context) error {
+	interval := min(c.Window, statsScrapeInterval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			c.scrape(ctx)
+		}
+	}
+}
+
+// scrape fetches the counters of the pods due for a scrape, at most
+// statsScrapeConcurrency at a time, and rolls their windows over.
+func (c *StatsCollector) scrape(ctx context.Context) {
+	log := logf.FromContext(ctx)
+	now := c.clock()
+
+	var due []*corev1.Pod
+	c.mu.Lock()
+	for _, entry := range c.pods {
+		if entry.baselineAt.IsZero() || now.Sub(entry.baselineAt) >= c.Window {
+			due = append(due, entry.target)
+		}
+	}
+	c.mu.Unlock()
+
+	fetch := c.Fetch
+	if fetch == nil {
+		fetch = c.fetchSidecarMetrics
+	}
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, statsScrapeConcurrency)
+	for _, pod := range due {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case slots <- struct{}{}:
+		}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			metrics, err := fetch(ctx, pod)
+			if err != nil {
+				log.V(1).Info("Failed to scrape sidecar metrics", "pod", pod.Name, "namespace", pod.Namespace, "error", err.Error())
+				return
+			}
+			c.record(pod.UID, metrics, now)
+		}()
+	}
+	wg.Wait()
+}
+
+// record stores the counters of a pod scraped at now, starting its first
+// window or completing the current one.
+func (c *StatsCollector) record(uid types.UID, metrics SidecarMetrics, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.pods[uid]
+	switch {
+	case !ok:
+		// Pruned while being scraped
+	case entry.baselineAt.IsZero():
+		entry.baseline, entry.baselineAt = metrics, now
+	case now.Sub(entry.baselineAt) >= c.Window:
+		increase := metrics.since(entry.baseline)
+		entry.last, entry.lastEnd = &increase, now
+		entry.baseline, entry.baselineAt = metrics, now
+	}
+}
+
+// Stats registers the running sidecars among pods for scraping and returns
+// the policy's counts over their last complete windows, or nil until a pod
+// completed one. It reads only the cached scrape results.
+func (c *StatsCollector) Stats(_ context.Context, spec ctxforgev1beta1.HeaderPropagationPolicySpec, pods []corev1.Pod) *ctxforgev1beta1.PropagationStats {
+	now := c.clock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pods == nil {
+		c.pods = make(map[types.UID]*podStats)
+	}
+	var running []*podStats
+	for i := range pods {
+		pod := &pods[i]
+		if !hasProxySidecar(pod) || pod.Status.Phase != corev1.PodRunning || pod.Status.PodIP == "" {
+			continue
+		}
+		entry, ok := c.pods[pod.UID]
+		if !ok {
+			entry = &podStats{}
+			c.pods[pod.UID] = entry
+		}
+		entry.target = scrapeTarget(pod)
+		entry.seen = now
+		running = append(running, entry)
+	}
+	c.prune(now)
+
+	headers := statsHeaders(spec)
+	stats := &ctxforgev1beta1.PropagationStats{
+		Window:  metav1.Duration{Duration: c.Window},
+		Headers: make([]ctxforgev1beta1.HeaderStats, len(headers)),
+	}
+	for i, header := range headers {
+		stats.Headers[i].Name = header.name
+	}
+	var lastEnd time.Time
+	for _, entry := range running {
+		if entry.last == nil {
+			continue
+		}
+		stats.Pods++
+		stats.Requests += entry.last.Requests
+		for i, header := range headers {
+			stats.Headers[i].Propagated += entry.last.Propagated[header.propagatedAs]
+			stats.Headers[i].Generated += entry.last.Generated[header.canonical]
+		}
+		if entry.lastEnd.After(lastEnd) {
+			lastEnd = entry.lastEnd
+		}
+	}
+	if stats.Pods == 0 {
+		return nil
+	}
+	stats.LastUpdateTime = metav1.NewTime(lastEnd)
+	return stats
+}
+
+// scrapeTarget copies the fields of pod a scrape uses, so the collector
+// doesn't hold on to whole pods.
+func scrapeTarget(pod *corev1.Pod) *corev1.Pod {
+	target := &corev1.Pod{}
+	target.Name, target.Namespace, target.UID = pod.Name, pod.Namespace, pod.UID
+	target.Status.PodIP = pod.Status.PodIP
+	return target
+}
+
+// prune forgets pods not seen for statsRetention windows. Callers hold c.mu.
+func (c *StatsCollector) prune(now time.Time) {
+	for uid, entry := range c.pods {
+		if now.Sub(entry.seen) > statsRetention*c.Window {
+			delete(c.pods, uid)
+		}
+	}
+}
+
+func (c *StatsCollector) clock() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+	return time.Now()
+}
+
+// statsHeader is a header of a policy's rules and the names its counters
+// carry.
+type statsHeader struct {
+	name         string
+	canonical    string
+	propagatedAs string
+}
+
+// statsHeaders returns the headers of the spec's propagation rules in rule
+// order, once each. A header is counted as propagated under its rename.
+func statsHeaders(spec ctxforgev1beta1.HeaderPropagationPolicySpec) []statsHeader {
+	var headers []statsHeader
+	seen := make(map[string]bool)
+	for _, rule := range spec.PropagationRules {
+		for _, header := range rule.Headers {
+			canonical := http.CanonicalHeaderKey(header.Name)
+			if seen[canonical] {
+				continue
+			}
+			seen[canonical] = true
+			propagatedAs := canonical
+			if header.Rename != "" {
+				propagatedAs = http.CanonicalHeaderKey(header.Rename)
+			}
+			headers = append(headers, statsHeader{name: header.Name, canonical: canonical, propagatedAs: propagatedAs})
+		}
+	}
+	return headers
+}
+
+// fetchSidecarMetrics scrapes the proxy's admin /metrics endpoint.
+func (c *StatsCollector) fetchSidecarMetrics(ctx context.Context, pod *corev1.Pod) (SidecarMetrics, error) {
+	ctx, cancel := context.WithTimeout(ctx, sidecarMetricsTimeout)
+	defer cancel()
+
+	url := "http://" + net.JoinHostPort(pod.Status.PodIP, strconv.Itoa(webhookv1.ProxyAdminPort)) + "/metrics"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return SidecarMetrics{}, err
+	}
+	client := c.client
+	if client == nil {
+		client = &http.Client{Timeout: sidecarMetricsTimeout}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return SidecarMetrics{}, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return SidecarMetrics{}, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return parseSidecarMetrics(resp.Body)
+}
+
+// parseSidecarMetrics reads the proxy counters from the Prometheus text
+// exposition format.
+func parseSidecarMetrics(r io.Reader) (SidecarMetrics, error) {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(r)
+	if err != nil {
+		return SidecarMetrics{}, fmt.Errorf("parsing metrics: %w", err)
+	}
+
+	metrics := SidecarMetrics{
+		Propagated: headerCounters(families[metricPropagated]),
+		Generated:  headerCounters(families[metricGenerated]),
+	}
+	if family := families[metricRequests]; family != nil {
+		for _, metric := range family.GetMetric() {
+			metrics.Requests += int64(metric.GetCounter().GetValue())
+		}
+	}
+	return metrics, nil
+}
+
+// headerCounters returns the family's counters keyed by their header label.
+func headerCounters(family *dto.MetricFamily) map[string]int64 {
+	counters := make(map[string]int64)
+	for _, metric := range family.GetMetric() {
+		for _, label := range metric.GetLabel() {
+			if label.GetName() == "header" {
+				counters[http.CanonicalHeaderKey(label.GetValue())] += int64(metric.GetCounter().GetValue())
+			}
+		}
+	}
+	return counters
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+	webhookv1 "github.com/bgruszka/contextforge/internal/webhook/v1"
+)
+
+var _ = Describe("Policy propagation stats", func() {
+	spec := ctxforgev1beta1.HeaderPropagationPolicySpec{
+		PropagationRules: []ctxforgev1beta1.PropagationRule{{
+			Headers: []ctxforgev1beta1.HeaderConfig{
+				{Name: "x-request-id", Generate: true, GeneratorType: "uuid"},
+				{Name: "x-tenant-id", Rename: "x-org-id"},
+			},
+		}},
+	}
+
+	sidecarPod := func(uid string) corev1.Pod {
+		p := corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodRunning, PodIP: "10.0.0.1"}}
+		p.UID = types.UID(uid)
+		p.Name = uid
+		p.Spec.Containers = []corev1.Container{{Name: "app"}, {Name: webhookv1.ProxyContainerName}}
+		return p
+	}
+
+	It("reports the increase over each pod's last complete window", func() {
+		now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+		counters := map[types.UID]SidecarMetrics{
+			"a": {Requests: 10, Propagated: map[string]int64{"X-Request-Id": 10, "X-Org-Id": 4}, Generated: map[string]int64{"X-Request-Id": 2}},
+			"b": {Requests: 5, Propagated: map[string]int64{"X-Request-Id": 5}},
+		}
+		var fetches atomic.Int32
+		collector := NewStatsCollector(time.Minute)
+		collector.now = func() time.Time { return now }
+		collector.Fetch = func(_ context.Context, pod *corev1.Pod) (SidecarMetrics, error) {
+			fetches.Add(1)
+			return counters[pod.UID], nil
+		}
+		pods := []corev1.Pod{sidecarPod("a"), sidecarPod("b"), {Status: corev1.PodStatus{Phase: corev1.PodRunning}}}
+
+		ctx := context.Background()
+
+		By("only registering the pods when reconciling")
+		Expect(collector.Stats(ctx, spec, pods)).To(BeNil())
+		Expect(fetches.Load()).To(BeEquivalentTo(0))
+		Expect(collector.pods).To(HaveLen(2))
+
+		By("taking a baseline on the first scrape")
+		collector.scrape(ctx)
+		Expect(fetches.Load()).To(BeEquivalentTo(2))
+		Expect(collector.Stats(ctx, spec, pods)).To(BeNil())
+
+		By("not scraping again within the window")
+		now = now.Add(30 * time.Second)
+		collector.scrape(ctx)
+		Expect(fetches.Load()).To(BeEquivalentTo(2))
+		Expect(collector.Stats(ctx, spec, pods)).To(BeNil())
+
+		By("summing the increases once the window elapsed")
+		now = now.Add(30 * time.Second)
+		counters["a"] = SidecarMetrics{Requests: 30, Propagated: map[string]int64{"X-Request-Id": 28, "X-Org-Id": 10}, Generated: map[string]int64{"X-Request-Id": 5}}
+		// b restarted, so its counters start over
+		counters["b"] = SidecarMetrics{Requests: 3, Propagated: map[string]int64{"X-Request-Id": 3}}
+		collector.scrape(ctx)
+		Expect(fetches.Load()).To(BeEquivalentTo(4))
+		stats := collector.Stats(ctx, spec, pods)
+		Expect(stats).NotTo(BeNil())
+		Expect(fetches.Load()).To(BeEquivalentTo(4))
+		Expect(stats.Window.Duration).To(Equal(time.Minute))
+		Expect(stats.LastUpdateTime.Time).To(Equal(now))
+		Expect(stats.Pods).To(Equal(int32(2)))
+		Expect(stats.Requests).To(Equal(int64(23)))
+		Expect(stats.Headers).To(Equal([]ctxforgev1beta1.HeaderStats{
+			{Name: "x-request-id", Propagated: 21, Generated: 3},
+			{Name: "x-tenant-id", Propagated: 6},
+		}))
+
+		By("counting only the pods given")
+		stats = collector.Stats(ctx, spec, pods[:1])
+		Expect(stats.Pods).To(Equal(int32(1)))
+		Expect(stats.Requests).To(Equal(int64(20)))
+	})
+
+	It("keeps no baseline for sidecars it can't scrape", func() {
+		collector := NewStatsCollector(time.Minute)
+		collector.Fetch = func(context.Context, *corev1.Pod) (SidecarMetrics, error) {
+			return SidecarMetrics{}, errors.New("connection refused")
+		}
+		pods := []corev1.Pod{sidecarPod("a")}
+		Expect(collector.Stats(context.Background(), spec, pods)).To(BeNil())
+		collector.scrape(context.Background())
+		Expect(collector.Stats(context.Background(), spec, pods)).To(BeNil())
+		Expect(collector.pods["a"].baselineAt.IsZero()).To(BeTrue())
+	})
+
+	It("scrapes a bounded number of sidecars at once", func() {
+		var mu sync.Mutex
+		inFlight, maxInFlight := 0, 0
+		collector := NewStatsCollector(time.Minute)
+		collector.Fetch = func(context.Context, *corev1.Pod) (SidecarMetrics, error) {
+			mu.Lock()
+			inFlight++
+			maxInFlight = max(maxInFlight, inFlight)
+			mu.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+			return SidecarMetrics{}, nil
+		}
+		var pods []corev1.Pod
+		for i := range 3 * statsScrapeConcurrency {
+			pods = append(pods, sidecarPod(fmt.Sprintf("pod-%d", i)))
+		}
+		collector.Stats(context.Background(), spec, pods)
+		collector.scrape(context.Background())
+
+		Expect(maxInFlight).To(BeNumerically(">", 1))
+		Expect(maxInFlight).To(BeNumerically("<=", statsScrapeConcurrency))
+		for _, entry := range collector.pods {
+			Expect(entry.baselineAt.IsZero()).To(BeFalse())
+		}
+	})
+
+	It("forgets pods no longer seen", func() {
+		now := time.Now()
+		collector := NewStatsCollector(time.Minute)
+		collector.now = func() time.Time { return now }
+		collector.Fetch = func(context.Context, *corev1.Pod) (SidecarMetrics, error) { return SidecarMetrics{}, nil }
+
+		collector.Stats(context.Background(), spec, []corev1.Pod{sidecarPod("a")})
+		collector.scrape(context.Background())
+		Expect(collector.pods).To(HaveKey(types.UID("a")))
+		now = now.Add(statsRetention*time.Minute + time.Second)
+		collector.Stats(context.Background(), spec, nil)
+		Expect(collector.pods).To(BeEmpty())
+	})
+
+	It("parses the proxy's counters", func() {
+		metrics, err := parseSidecarMetrics(strings.NewReader(`# TYPE ctxforge_proxy_requests_total counter
+ctxforge_proxy_requests_total{method="GET",status="200"} 7
+ctxforge_proxy_requests_total{method="POST",status="500"} 2
+# TYPE ctxforge_proxy_header_propagated_total counter
+ctxforge_proxy_header_propagated_total{header="X-Request-Id"} 9
+# TYPE ctxforge_proxy_header_generated_total counter
+ctxforge_proxy_header_generated_total{header="x-request-id"} 1
+`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(metrics.Requests).To(Equal(int64(9)))
+		Expect(metrics.Propagated).To(Equal(map[string]int64{"X-Request-Id": 9}))
+		Expect(metrics.Generated).To(Equal(map[string]int64{"X-Request-Id": 1}))
+	})
+})
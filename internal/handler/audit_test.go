@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bgruszka/contextforge/internal/audit"
+	"github.com/bgruszka/contextforge/internal/config"
+	"github.com/bgruszka/contextforge/internal/generator"
+	"github.com/bgruszka/contextforge/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readAuditEvents(t *testing.T, path string) []map[string]any {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var events []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if line == "" {
+			continue
+		}
+		var event map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &event))
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestProxyHandler_AuditsGeneratedAndStrippedHeaders(t *testing.T) {
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer targetServer.Close()
+
+	auditPath := filepath.Join(t.TempDir(), "audit.log")
+	cfg := testConfig(targetServer.Listener.Addr().String(), nil)
+	cfg.HeaderRules = []config.HeaderRule{
+		{Name: "x-tenant-id", Propagate: true},
+		{Name: "x-request-id", Propagate: true, Generate: true, GeneratorType: generator.TypeUUID},
+	}
+	cfg.DebugEchoEnabled = true
+	cfg.AuditLog = auditPath
+
+	handler, err := NewProxyHandler(cfg)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/api", nil)
+	req.Header.Set(DebugHeader, "true")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	require.NoError(t, handler.Close())
+
+	events := readAuditEvents(t, auditPath)
+	require.Len(t, events, 2)
+
+	assert.Equal(t, "generated", events[0]["action"])
+	assert.Equal(t, "X-Request-Id", events[0]["header"])
+	assert.Equal(t, float64(1), events[0]["rule"])
+	assert.Equal(t, req.Header.Get("X-Request-Id"), events[0]["request_id"])
+
+	assert.Equal(t, "stripped", events[1]["action"])
+	assert.Equal(t, DebugHeader, events[1]["header"])
+	assert.NotContains(t, events[1], "rule")
+}
+
+func TestProxyHandler_AuditRedactsSensitiveRequestID(t *testing.T) {
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer targetServer.Close()
+
+	auditPath := filepath.Join(t.TempDir(), "audit.log")
+	cfg := testConfig(targetServer.Listener.Addr().String(), nil)
+	cfg.HeaderRules = []config.HeaderRule{
+		{Name: "x-session-token", Propagate: true, Generate: true, GeneratorType: generator.TypeUUID},
+	}
+	cfg.RequestIDHeader = "x-session-token"
+	cfg.RedactHeaders = []string{"x-session-token"}
+	cfg.AuditLog = auditPath
+
+	handler, err := NewProxyHandler(cfg)
+	require.NoError(t, err)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api", nil))
+	require.NoError(t, handler.Close())
+
+	events := readAuditEvents(t, auditPath)
+	require.Len(t, events, 1)
+	assert.Equal(t, "generated", events[0]["action"])
+	assert.Equal(t, redactedValue, events[0]["request_id"])
+}
+
+func TestHeaderPropagatingTransport_AuditsAddedHeaders(t *testing.T) {
+	var buf bytes.Buffer
+	mockTransport := &mockRoundTripper{
+		fn: func(r *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+		},
+	}
+	transport := NewHeaderPropagatingTransport([]string{"x-tenant-id"}, mockTransport)
+	transport.audit = audit.NewWithWriter(&buf, nil)
+	transport.ruleIndex = map[string]int{"X-Tenant-Id": 0}
+
+	ctx := context.WithValue(context.Background(), ContextKeyHeaders, map[string]string{"X-Tenant-Id": "acme"})
+	req := httptest.NewRequest(http.MethodGet, "http://backend/orders", nil).WithContext(ctx)
+	req.Header.Set("X-Request-Id", "abc123")
+
+	resp, err := transport.RoundTrip(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	var event map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &event))
+	assert.Equal(t, "added", event["action"])
+	assert.Equal(t, "X-Tenant-Id", event["header"])
+	assert.Equal(t, "abc123", event["request_id"])
+	assert.Equal(t, float64(0), event["rule"])
+}
+
+func TestProxyHandler_AuditsBlockedCredentialHeaders(t *testing.T) {
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer targetServer.Close()
+
+	auditPath := filepath.Join(t.TempDir(), "audit.log")
+	cfg := testConfig(targetServer.Listener.Addr().String(), nil)
+	cfg.HeaderRules = []config.HeaderRule{
+		{Name: "x-tenant-id", Propagate: true},
+		{Name: "authorization", Propagate: true},
+	}
+	cfg.AuditLog = auditPath
+
+	handler, err := NewProxyHandler(cfg)
+	require.NoError(t, err)
+	before := testutil.ToFloat64(metrics.HeaderBlockedTotal.WithLabelValues("Authorization", audit.ReasonCredential))
+
+	req := httptest.NewRequest(http.MethodGet, "/api", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	// Requests without the header don't report it
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api", nil))
+	require.NoError(t, handler.Close())
+
+	events := readAuditEvents(t, auditPath)
+	require.Len(t, events, 1)
+	assert.Equal(t, "blocked", events[0]["action"])
+	assert.Equal(t, "Authorization", events[0]["header"])
+	assert.Equal(t, audit.ReasonCredential, events[0]["reason"])
+	assert.NotContains(t, events[0], "rule")
+	assert.Equal(t, before+1, testutil.ToFloat64(metrics.HeaderBlockedTotal.WithLabelValues("Authorization", audit.ReasonCredential)))
+}
+
+func TestHeaderPropagatingTransport_AuditsBlockedHeaders(t *testing.T) {
+	mockTransport := &mockRoundTripper{
+		fn: func(r *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+		},
+	}
+	cluster, err := config.NewClusterHosts(config.DefaultClusterDomains, config.DefaultClusterCIDRs)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		url       string
+		forwarded bool
+		action    string
+		reason    string
+	}{
+		{name: "denied destination", url: "http://billing.internal.svc/orders", action: "blocked", reason: audit.ReasonDestination},
+		{name: "denied destination, forwarded copy", url: "http://billing.internal.svc/orders", forwarded: true,
+			action: "stripped", reason: audit.ReasonDestination},
+		{name: "external host", url: "http://api.example.com/orders", action: "blocked", reason: audit.ReasonExternalHost},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			transport := NewHeaderPropagatingTransport([]string{"x-tenant-id"}, mockTransport)
+			transport.audit = audit.NewWithWriter(&buf, nil)
+			transport.ruleIndex = map[string]int{"X-Tenant-Id": 0}
+			transport.cluster = cluster
+			before := testutil.ToFloat64(metrics.HeaderBlockedTotal.WithLabelValues("X-Tenant-Id", tt.reason))
+
+			ctx := context.WithValue(context.Background(), ContextKeyHeaders, map[string]string{"X-Tenant-Id": "acme"})
+			ctx = context.WithValue(ctx, contextKeyDestinations, map[string]*config.Destinations{
+				"X-Tenant-Id": {Deny: []string{"billing.internal.svc"}},
+			})
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil).WithContext(ctx)
+			if tt.forwarded {
+				req.Header.Set("X-Tenant-Id", "acme")
+			}
+
+			resp, err := transport.RoundTrip(req)
+			require.NoError(t, err)
+			_ = resp.Body.Close()
+
+			assert.Empty(t, req.Header.Get("X-Tenant-Id"))
+			var event map[string]any
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &event))
+			assert.Equal(t, tt.action, event["action"])
+			assert.Equal(t, "X-Tenant-Id", event["header"])
+			assert.Equal(t, tt.reason, event["reason"])
+			assert.Equal(t, before+1, testutil.ToFloat64(metrics.HeaderBlockedTotal.WithLabelValues("X-Tenant-Id", tt.reason)))
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"io"
+	"net"
+
+	"github.com/bgruszka/contextforge/internal/metrics"
+)
+
+// countingReadCloser wraps a request body and counts the bytes read from it.
+type countingReadCloser struct {
+	io.ReadCloser
+	n int64
+}
+
+// Read counts the bytes read from the underlying body.
+func (c *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// countingConn wraps a hijacked connection and records traffic in both
+// directions as it happens, so long-lived streams show up in the byte
+// counters before they close.
+type countingConn struct {
+	net.Conn
+}
+
+// Read records bytes received from the caller.
+func (c *countingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	metrics.RecordBytes(metrics.DirectionInbound, int64(n))
+	return n, err
+}
+
+// Write records bytes sent to the caller.
+func (c *countingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	metrics.RecordBytes(metrics.DirectionOutbound, int64(n))
+	return n, err
+}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bgruszka/contextforge/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyHandler_CountsBytes(t *testing.T) {
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, "hello proxy", string(body))
+		_, _ = w.Write([]byte("response body"))
+	}))
+	defer targetServer.Close()
+
+	handler, err := NewProxyHandler(testConfig(targetServer.Listener.Addr().String(), []string{"x-request-id"}))
+	require.NoError(t, err)
+
+	inbound := metrics.BytesTotal.WithLabelValues(metrics.DirectionInbound)
+	outbound := metrics.BytesTotal.WithLabelValues(metrics.DirectionOutbound)
+	inBefore := testutil.ToFloat64(inbound)
+	outBefore := testutil.ToFloat64(outbound)
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("hello proxy"))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, inBefore+float64(len("hello proxy")), testutil.ToFloat64(inbound))
+	assert.Equal(t, outBefore+float64(len("response body")), testutil.ToFloat64(outbound))
+}
+
+func TestCountingReadCloser(t *testing.T) {
+	body := &countingReadCloser{ReadCloser: io.NopCloser(strings.NewReader("12345"))}
+
+	data, err := io.ReadAll(body)
+
+	require.NoError(t, err)
+	assert.Equal(t, "12345", string(data))
+	assert.Equal(t, int64(5), body.n)
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// DebugHeader is the request header that asks the proxy to describe how the
+// header rules were applied to the request.
+const DebugHeader = "X-Ctxforge-Debug"
+
+// Response headers written in debug echo mode.
+const (
+	DebugMatchedRulesHeader = "X-Ctxforge-Matched-Rules"
+	DebugSkippedRulesHeader = "X-Ctxforge-Skipped-Rules"
+	DebugGeneratedHeader    = "X-Ctxforge-Generated"
+	DebugPropagatedHeader   = "X-Ctxforge-Propagated"
+	DebugStrippedHeader     = "X-Ctxforge-Stripped"
+)
+
+// isDebugRequest reports whether the request asks for debug echo output.
+func isDebugRequest(r *http.Request) bool {
+	return strings.EqualFold(strings.TrimSpace(r.Header.Get(DebugHeader)), "true")
+}
+
+// writeDebugEcho describes the rule evaluation in the response headers. Only
+// header names are reported, never their values.
+func writeDebugEcho(h http.Header, result ruleResult) {
+	propagated := make([]string, 0, len(result.headers))
+	for name := range result.headers {
+		// Generated headers are reported separately
+		if !slices.Contains(result.generated, name) {
+			propagated = append(propagated, name)
+		}
+	}
+	sort.Strings(propagated)
+
+	h.Set(DebugMatchedRulesHeader, joinOrNone(result.matched))
+	h.Set(DebugSkippedRulesHeader, joinOrNone(result.skipped))
+	h.Set(DebugGeneratedHeader, joinOrNone(result.generated))
+	h.Set(DebugPropagatedHeader, joinOrNone(propagated))
+	h.Set(DebugStrippedHeader, joinOrNone(result.withheld))
+}
+
+// joinOrNone joins header names for a debug header, using "none" for an empty list
+// so that the header is still present in the response.
+func joinOrNone(names []string) string {
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bgruszka/contextforge/internal/config"
+	"github.com/bgruszka/contextforge/internal/generator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func debugEchoConfig(targetHost string) *config.ProxyConfig {
+	cfg := testConfig(targetHost, nil)
+	cfg.HeaderRules = []config.HeaderRule{
+		{Name: "x-request-id", Propagate: true, Generate: true, GeneratorType: generator.TypeUUID},
+		{Name: "x-tenant-id", Propagate: true},
+		{Name: "x-internal", Propagate: false},
+		{Name: "x-api-version", Propagate: true, Methods: []string{"POST"}},
+	}
+	cfg.DebugEchoEnabled = true
+	return cfg
+}
+
+func TestProxyHandler_DebugEcho(t *testing.T) {
+	var forwarded http.Header
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer targetServer.Close()
+
+	handler, err := NewProxyHandler(debugEchoConfig(targetServer.Listener.Addr().String()))
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/api", nil)
+	req.Header.Set(DebugHeader, "true")
+	req.Header.Set("X-Tenant-Id", "acme")
+	req.Header.Set("X-Internal", "secret")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "X-Request-Id, X-Tenant-Id, X-Internal", rr.Header().Get(DebugMatchedRulesHeader))
+	assert.Equal(t, "X-Api-Version", rr.Header().Get(DebugSkippedRulesHeader))
+	assert.Equal(t, "X-Request-Id", rr.Header().Get(DebugGeneratedHeader))
+	assert.Equal(t, "X-Tenant-Id", rr.Header().Get(DebugPropagatedHeader))
+	assert.Equal(t, "X-Internal", rr.Header().Get(DebugStrippedHeader))
+	assert.Empty(t, forwarded.Get(DebugHeader), "debug header must not reach the application")
+}
+
+func TestProxyHandler_DebugEchoNotRequested(t *testing.T) {
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer targetServer.Close()
+
+	handler, err := NewProxyHandler(debugEchoConfig(targetServer.Listener.Addr().String()))
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api", nil))
+
+	assert.Empty(t, rr.Header().Get(DebugMatchedRulesHeader))
+}
+
+func TestProxyHandler_DebugEchoDisabled(t *testing.T) {
+	var forwarded http.Header
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer targetServer.Close()
+
+	cfg := debugEchoConfig(targetServer.Listener.Addr().String())
+	cfg.DebugEchoEnabled = false
+	handler, err := NewProxyHandler(cfg)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/api", nil)
+	req.Header.Set(DebugHeader, "true")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Empty(t, rr.Header().Get(DebugMatchedRulesHeader))
+	assert.Equal(t, "true", forwarded.Get(DebugHeader))
+}
+
+func TestWriteDebugEcho_EmptyLists(t *testing.T) {
+	h := http.Header{}
+
+	writeDebugEcho(h, ruleResult{headers: map[string]string{}})
+
+	assert.Equal(t, "none", h.Get(DebugMatchedRulesHeader))
+	assert.Equal(t, "none", h.Get(DebugStrippedHeader))
+}
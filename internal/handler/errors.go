@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+)
+
+// Upstream error classes used as the "class" label of the upstream error metric.
+// They let alerts distinguish an application that is down (connection_refused)
+// from one that is slow (response_timeout).
+const (
+	ErrorClassDialTimeout       = "dial_timeout"
+	ErrorClassConnectionRefused = "connection_refused"
+	ErrorClassTLS               = "tls"
+	ErrorClassReset             = "reset"
+	ErrorClassResponseTimeout   = "response_timeout"
+	ErrorClassCanceled          = "canceled"
+	ErrorClassOther             = "other"
+)
+
+// classifyUpstreamError maps an error returned while forwarding a request to the
+// target application onto one of the ErrorClass* constants.
+func classifyUpstreamError(err error) string {
+	if err == nil {
+		return ErrorClassOther
+	}
+
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return ErrorClassConnectionRefused
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return ErrorClassReset
+	}
+
+	if isTLSError(err) {
+		return ErrorClassTLS
+	}
+
+	// A timeout during dial means the target never accepted the connection;
+	// any other timeout happened while waiting for the response.
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" && opErr.Timeout() {
+		return ErrorClassDialTimeout
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrorClassResponseTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrorClassResponseTimeout
+	}
+
+	// The client went away before the target responded.
+	if errors.Is(err, context.Canceled) {
+		return ErrorClassCanceled
+	}
+
+	return ErrorClassOther
+}
+
+// isTLSError reports whether err originates from a TLS handshake or certificate verification.
+func isTLSError(err error) bool {
+	var recordErr tls.RecordHeaderError
+	var alertErr tls.AlertError
+	var verifyErr *tls.CertificateVerificationError
+	var unknownAuthErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var certInvalidErr x509.CertificateInvalidError
+
+	return errors.As(err, &recordErr) ||
+		errors.As(err, &alertErr) ||
+		errors.As(err, &verifyErr) ||
+		errors.As(err, &unknownAuthErr) ||
+		errors.As(err, &hostnameErr) ||
+		errors.As(err, &certInvalidErr)
+}
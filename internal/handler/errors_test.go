@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/bgruszka/contextforge/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// timeoutError is a net.Error that always reports a timeout.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestClassifyUpstreamError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{
+			name:     "connection refused",
+			err:      &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)},
+			expected: ErrorClassConnectionRefused,
+		},
+		{
+			name:     "dial timeout",
+			err:      &net.OpError{Op: "dial", Net: "tcp", Err: timeoutError{}},
+			expected: ErrorClassDialTimeout,
+		},
+		{
+			name:     "connection reset",
+			err:      &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)},
+			expected: ErrorClassReset,
+		},
+		{
+			name:     "unexpected EOF",
+			err:      fmt.Errorf("readLoop: %w", io.ErrUnexpectedEOF),
+			expected: ErrorClassReset,
+		},
+		{
+			name:     "tls record header error",
+			err:      tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"},
+			expected: ErrorClassTLS,
+		},
+		{
+			name:     "read timeout",
+			err:      &net.OpError{Op: "read", Net: "tcp", Err: timeoutError{}},
+			expected: ErrorClassResponseTimeout,
+		},
+		{
+			name:     "context deadline exceeded",
+			err:      fmt.Errorf("awaiting headers: %w", context.DeadlineExceeded),
+			expected: ErrorClassResponseTimeout,
+		},
+		{
+			name:     "client canceled",
+			err:      context.Canceled,
+			expected: ErrorClassCanceled,
+		},
+		{
+			name:     "unknown error",
+			err:      errors.New("something else"),
+			expected: ErrorClassOther,
+		},
+		{
+			name:     "nil error",
+			err:      nil,
+			expected: ErrorClassOther,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, classifyUpstreamError(tt.err))
+		})
+	}
+}
+
+func TestProxyHandler_RecordsUpstreamErrorClass(t *testing.T) {
+	// Grab a free port and close it so the dial is refused.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	targetHost := listener.Addr().String()
+	_ = listener.Close()
+
+	handler, err := NewProxyHandler(testConfig(targetHost, []string{"x-request-id"}))
+	require.NoError(t, err)
+
+	counter := metrics.UpstreamErrorsTotal.WithLabelValues(ErrorClassConnectionRefused)
+	before := testutil.ToFloat64(counter)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+	assert.Equal(t, http.StatusBadGateway, rr.Code)
+	assert.Equal(t, before+1, testutil.ToFloat64(counter))
+}
@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// requestLogger returns a logger carrying the request and tenant IDs of the request,
+// so every line logged while handling it can be correlated with application logs.
+func (h *ProxyHandler) requestLogger(r *http.Request) zerolog.Logger {
+	logCtx := log.Logger.With()
+	if id := r.Header.Get(h.requestIDHeader); id != "" {
+		logCtx = logCtx.Str("request_id", h.redact.value(h.requestIDHeader, id))
+	}
+	if h.tenantIDHeader != "" {
+		if tenant := r.Header.Get(h.tenantIDHeader); tenant != "" {
+			logCtx = logCtx.Str("tenant_id", h.redact.value(h.tenantIDHeader, tenant))
+		}
+	}
+	return logCtx.Logger()
+}
+
+// loggerFromContext returns the request-scoped logger stored in ctx by ProxyHandler,
+// falling back to the global logger for contexts without one.
+func loggerFromContext(ctx context.Context) *zerolog.Logger {
+	logger := zerolog.Ctx(ctx)
+	if logger.GetLevel() == zerolog.Disabled {
+		return &log.Logger
+	}
+	return logger
+}
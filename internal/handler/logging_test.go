@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bgruszka/contextforge/internal/config"
+	"github.com/bgruszka/contextforge/internal/generator"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyHandler_ErrorLogCarriesRequestAndTenantID(t *testing.T) {
+	buf := captureLogs(t)
+
+	// Reserve a port with nothing listening on it
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	target := listener.Addr().String()
+	_ = listener.Close()
+
+	cfg := testConfig(target, []string{"x-request-id", "x-tenant-id"})
+	cfg.RequestIDHeader = "x-request-id"
+	cfg.TenantIDHeader = "x-tenant-id"
+	handler, err := NewProxyHandler(cfg)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/api", nil)
+	req.Header.Set("X-Request-Id", "abc123")
+	req.Header.Set("X-Tenant-Id", "acme")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "Proxy error forwarding request", entry["message"])
+	assert.Equal(t, "abc123", entry["request_id"])
+	assert.Equal(t, "acme", entry["tenant_id"])
+}
+
+func TestProxyHandler_RequestLoggerUsesGeneratedID(t *testing.T) {
+	cfg := testConfig("localhost:8080", nil)
+	cfg.HeaderRules = []config.HeaderRule{
+		{Name: "x-correlation-id", Propagate: true, Generate: true, GeneratorType: generator.TypeUUID},
+	}
+	cfg.RequestIDHeader = "x-correlation-id"
+	handler, err := NewProxyHandler(cfg)
+	require.NoError(t, err)
+
+	buf := captureLogs(t)
+	req := httptest.NewRequest(http.MethodGet, "/api", nil)
+	handler.applyRules(req)
+	logger := handler.requestLogger(req)
+	logger.Info().Msg("test")
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, req.Header.Get("X-Correlation-Id"), entry["request_id"])
+	assert.NotEmpty(t, entry["request_id"])
+	assert.NotContains(t, entry, "tenant_id")
+}
+
+func TestProxyHandler_GeneratedHeaderLogCarriesRequestAndTenantID(t *testing.T) {
+	cfg := testConfig("localhost:8080", nil)
+	cfg.HeaderRules = []config.HeaderRule{
+		{Name: "x-request-id", Propagate: true},
+		{Name: "x-tenant-id", Propagate: true},
+		{Name: "x-trace-id", Propagate: true, Generate: true, GeneratorType: generator.TypeUUID},
+	}
+	cfg.RequestIDHeader = "x-request-id"
+	cfg.TenantIDHeader = "x-tenant-id"
+	handler, err := NewProxyHandler(cfg)
+	require.NoError(t, err)
+
+	buf := captureLogs(t)
+	log.Logger = log.Logger.Level(zerolog.DebugLevel)
+	req := httptest.NewRequest(http.MethodGet, "/api", nil)
+	req.Header.Set("X-Request-Id", "abc123")
+	req.Header.Set("X-Tenant-Id", "acme")
+	handler.applyRules(req)
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "Generated header value", entry["message"])
+	assert.Equal(t, "abc123", entry["request_id"])
+	assert.Equal(t, "acme", entry["tenant_id"])
+}
+
+func TestLoggerFromContext_FallsBackToGlobalLogger(t *testing.T) {
+	assert.Equal(t, &log.Logger, loggerFromContext(context.Background()))
+}
+
+func TestProxyHandler_DebugLogsRedactSensitiveValues(t *testing.T) {
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer targetServer.Close()
+
+	cfg := testConfig(targetServer.Listener.Addr().String(), []string{"x-request-id", "x-tenant-token"})
+	cfg.TenantIDHeader = "x-tenant-token"
+	cfg.RedactHeaders = []string{"X-Tenant-Token"}
+	handler, err := NewProxyHandler(cfg)
+	require.NoError(t, err)
+
+	buf := captureLogs(t)
+	log.Logger = log.Logger.Level(zerolog.DebugLevel)
+	req := httptest.NewRequest(http.MethodGet, "/api", nil)
+	req.Header.Set("X-Request-Id", "abc123")
+	req.Header.Set("X-Tenant-Token", "t0k3n")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.Contains(t, buf.String(), "Proxying request")
+	assert.NotContains(t, buf.String(), "t0k3n")
+	assert.Contains(t, buf.String(), `"X-Tenant-Token":"[REDACTED]"`)
+	assert.Contains(t, buf.String(), `"tenant_id":"[REDACTED]"`)
+	assert.Contains(t, buf.String(), `"X-Request-Id":"abc123"`)
+}
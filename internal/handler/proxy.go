@@ -3,16 +3,21 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"slices"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/bgruszka/contextforge/internal/audit"
 	"github.com/bgruszka/contextforge/internal/config"
 	"github.com/bgruszka/contextforge/internal/generator"
 	"github.com/bgruszka/contextforge/internal/metrics"
+	"github.com/bgruszka/contextforge/internal/spiffe"
 	"github.com/rs/zerolog/log"
 )
 
@@ -22,31 +27,180 @@ type contextKey string
 // ContextKeyHeaders is the key used to store propagated headers in the request context.
 const ContextKeyHeaders contextKey = "ctxforge-headers"
 
+// contextKeyUpstreamTiming is the key used to store the upstream timing of a request.
+const contextKeyUpstreamTiming contextKey = "ctxforge-upstream-timing"
+
+// contextKeyDestinations is the key used to store the destinations the
+// propagated headers are restricted to.
+const contextKeyDestinations contextKey = "ctxforge-destinations"
+
+// contextKeyResponseHeaders is the key used to store the response rules that
+// matched a request.
+const contextKeyResponseHeaders contextKey = "ctxforge-response-headers"
+
+// contextKeyDestinationHost is the key used to store the host a request was
+// addressed to, before the reverse proxy points it at the target.
+const contextKeyDestinationHost contextKey = "ctxforge-destination-host"
+
+// upstreamTiming records how long the target application took to return response headers.
+type upstreamTiming struct {
+	duration time.Duration
+}
+
 // headerGenerator holds a generator instance for a header rule.
 type headerGenerator struct {
 	rule      config.HeaderRule
 	generator generator.Generator
 }
 
+// ruleSet is the set of header rules a handler applies. It is replaced as a
+// whole when the rules are updated at runtime.
+type ruleSet struct {
+	rules      []config.HeaderRule
+	generators map[string]headerGenerator // header name -> generator
+	index      map[string]int             // header name -> index in rules
+	// credentials are the headers of the credential rules that were dropped,
+	// reported when a request carries them
+	credentials []string
+}
+
+// newRuleSet initializes the generators and index of the given rules. Unless
+// allowCredentials is set, rules propagating one of config.CredentialHeaders,
+// under its own name or renamed, are dropped with a warning.
+func newRuleSet(rules []config.HeaderRule, allowCredentials bool) (*ruleSet, error) {
+	var credentials []string
+	if !allowCredentials {
+		rules, credentials = withoutCredentialRules(rules)
+	}
+	generators := make(map[string]headerGenerator)
+	for _, rule := range rules {
+		if rule.Response != "" {
+			continue
+		}
+		// A header scoped by several rules has one generator, from its first
+		// generating rule, as the operator merges policies
+		if _, ok := generators[http.CanonicalHeaderKey(rule.Name)]; ok {
+			continue
+		}
+		if rule.Generate {
+			gen, err := generator.New(rule.GeneratorType)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create generator for header %q: %w", rule.Name, err)
+			}
+			generators[http.CanonicalHeaderKey(rule.Name)] = headerGenerator{
+				rule:      rule,
+				generator: gen,
+			}
+			log.Info().
+				Str("header", rule.Name).
+				Str("type", string(rule.GeneratorType)).
+				Msg("Header generator initialized")
+		}
+	}
+
+	index := make(map[string]int, len(rules))
+	for i, rule := range rules {
+		if rule.Response != "" {
+			continue
+		}
+		index[http.CanonicalHeaderKey(strings.TrimSpace(rule.Name))] = i
+		if rule.Rename != "" {
+			index[http.CanonicalHeaderKey(rule.Rename)] = i
+		}
+	}
+	return &ruleSet{rules: rules, generators: generators, index: index, credentials: credentials}, nil
+}
+
+// withoutCredentialRules returns rules without the propagation rules of
+// credential headers, and the headers those rules read. Response rules are
+// kept: stripping Set-Cookie from responses is legitimate.
+func withoutCredentialRules(rules []config.HeaderRule) ([]config.HeaderRule, []string) {
+	kept := make([]config.HeaderRule, 0, len(rules))
+	var dropped []string
+	for _, rule := range rules {
+		if rule.Response == "" && (config.IsCredentialHeader(rule.Name) || config.IsCredentialHeader(rule.Rename)) {
+			log.Warn().
+				Str("header", rule.Name).
+				Msg("Ignoring header rule: credential headers are never propagated unless UNSAFE_PROPAGATE_CREDENTIALS is set")
+			if name := http.CanonicalHeaderKey(strings.TrimSpace(rule.Name)); !slices.Contains(dropped, name) {
+				dropped = append(dropped, name)
+			}
+			continue
+		}
+		kept = append(kept, rule)
+	}
+	return kept, dropped
+}
+
 // ProxyHandler handles incoming HTTP requests, extracts configured headers,
 // stores them in the request context, and forwards the request to the target application.
 type ProxyHandler struct {
 	config       *config.ProxyConfig
 	reverseProxy *httputil.ReverseProxy
 	headers      []string
-	rules        []config.HeaderRule
-	generators   map[string]headerGenerator // header name -> generator
+	rules        atomic.Pointer[ruleSet]
+	requestLog   *RequestLog
+	audit        *audit.Logger
+	identity     *spiffe.Source
+	signer       *contextSigner
+
+	requestIDHeader string
+	tenantIDHeader  string
+	paths           *metrics.PathNormalizer
+	redact          redactor
+}
+
+// ruleResult is the outcome of applying the header rules to a request.
+type ruleResult struct {
+	headers    map[string]string // propagated header name -> value
+	matched    []string          // names of the rules that applied to the request
+	skipped    []string          // names of the rules excluded by path or method filters
+	generated  []string          // names of the headers generated by the proxy
+	overridden []string          // names of headers whose incoming value was replaced or appended to
+	unsampled  []string          // names of headers removed from requests left out of their rule's sample
+	withheld   []string          // names of headers present on the request but not propagated
+	blocked    []string          // names of credential headers present on the request, whose rules were dropped
+	index      map[string]int    // header name -> index of the rule set that was applied
+	response   []responseHeader  // response rules that matched the request
+
+	destinations map[string]*config.Destinations // propagated header name -> allowed destinations
+}
+
+// responseHeader is a header a response rule sets on, or with an empty value
+// strips from, the response.
+type responseHeader struct {
+	name  string
+	value string
+	rule  int // index of the response rule
 }
 
 // NewProxyHandler creates a new ProxyHandler with the given configuration.
 // Returns an error if the target host URL is invalid.
 func NewProxyHandler(cfg *config.ProxyConfig) (*ProxyHandler, error) {
-	targetURL, err := url.Parse("http://" + cfg.TargetHost)
+	scheme := "http"
+	if cfg.TargetTLS {
+		scheme = "https"
+	}
+	targetURL, err := url.Parse(scheme + "://" + cfg.TargetHost)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse target host URL %q: %w", cfg.TargetHost, err)
 	}
 
-	transport := NewHeaderPropagatingTransport(cfg.HeadersToPropagate, http.DefaultTransport)
+	var identity *spiffe.Source
+	if cfg.SPIFFEEndpointSocket != "" {
+		if identity, err = spiffe.NewSource(cfg.SPIFFEEndpointSocket); err != nil {
+			return nil, fmt.Errorf("failed to connect to the SPIFFE workload API: %w", err)
+		}
+	}
+	base, err := targetTransport(cfg, identity)
+	if err != nil {
+		return nil, err
+	}
+	transport := NewHeaderPropagatingTransport(cfg.HeadersToPropagate, base)
+	if identity != nil {
+		transport.identity = identity
+		transport.identityHeader = http.CanonicalHeaderKey(cfg.SPIFFEIDHeader)
+	}
 
 	proxy := httputil.NewSingleHostReverseProxy(targetURL)
 	proxy.Transport = transport
@@ -57,8 +211,11 @@ func NewProxyHandler(cfg *config.ProxyConfig) (*ProxyHandler, error) {
 	}
 
 	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
-		log.Error().
+		class := classifyUpstreamError(err)
+		metrics.RecordUpstreamError(class)
+		loggerFromContext(r.Context()).Error().
 			Err(err).
+			Str("error_class", class).
 			Str("method", r.Method).
 			Str("path", r.URL.Path).
 			Msg("Proxy error forwarding request")
@@ -66,31 +223,102 @@ func NewProxyHandler(cfg *config.ProxyConfig) (*ProxyHandler, error) {
 	}
 
 	// Initialize generators for rules that have generation enabled
-	generators := make(map[string]headerGenerator)
-	for _, rule := range cfg.HeaderRules {
-		if rule.Generate {
-			gen, err := generator.New(rule.GeneratorType)
-			if err != nil {
-				return nil, fmt.Errorf("failed to create generator for header %q: %w", rule.Name, err)
-			}
-			generators[http.CanonicalHeaderKey(rule.Name)] = headerGenerator{
-				rule:      rule,
-				generator: gen,
-			}
-			log.Info().
-				Str("header", rule.Name).
-				Str("type", string(rule.GeneratorType)).
-				Msg("Header generator initialized")
+	rules, err := newRuleSet(cfg.HeaderRules, cfg.UnsafePropagateCredentials)
+	if err != nil {
+		return nil, err
+	}
+
+	auditLogger, err := audit.New(cfg.AuditLog)
+	if err != nil {
+		return nil, err
+	}
+	requestIDHeader := http.CanonicalHeaderKey(cfg.RequestIDHeader)
+	if requestIDHeader == "" {
+		requestIDHeader = config.DefaultRequestIDHeader
+	}
+	transport.audit = auditLogger
+	transport.requestIDHeader = requestIDHeader
+	transport.allowCredentials = cfg.UnsafePropagateCredentials
+	transport.redact = newRedactor(cfg.RedactHeaders)
+	if cfg.Strict() {
+		transport.redact = transport.redact.redactAll(requestIDHeader, cfg.TenantIDHeader)
+	}
+	if cfg.InClusterOnly {
+		if transport.cluster, err = config.NewClusterHosts(cfg.ClusterDomains, cfg.ClusterCIDRs); err != nil {
+			return nil, err
+		}
+	}
+	signer := newContextSigner(cfg)
+	transport.signer = signer
+
+	var paths *metrics.PathNormalizer
+	if cfg.PathMetricsEnabled {
+		paths, err = metrics.NewPathNormalizer(cfg.PathTemplates, cfg.PathTemplatesStrict)
+		if err != nil {
+			return nil, err
 		}
 	}
 
-	return &ProxyHandler{
+	var requestLog *RequestLog
+	if cfg.DebugRequestBufferSize > 0 {
+		requestLog = NewRequestLog(cfg.DebugRequestBufferSize)
+	}
+
+	h := &ProxyHandler{
 		config:       cfg,
 		reverseProxy: proxy,
 		headers:      cfg.HeadersToPropagate,
-		rules:        cfg.HeaderRules,
-		generators:   generators,
-	}, nil
+		requestLog:   requestLog,
+		audit:        auditLogger,
+		identity:     identity,
+		signer:       signer,
+
+		requestIDHeader: requestIDHeader,
+		tenantIDHeader:  http.CanonicalHeaderKey(cfg.TenantIDHeader),
+		paths:           paths,
+		redact:          transport.redact,
+	}
+	h.rules.Store(rules)
+	transport.rules = &h.rules
+	proxy.ModifyResponse = func(resp *http.Response) error {
+		h.applyResponseRules(resp.Request, resp.Header)
+		return nil
+	}
+	// Callers get the echoed headers, such as the request ID, with errors too
+	errorHandler := proxy.ErrorHandler
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		h.applyResponseRules(r, w.Header())
+		errorHandler(w, r, err)
+	}
+	return h, nil
+}
+
+// UpdateRules replaces the header rules applied to new requests, for example
+// when the operator pushes a policy change. Requests in flight finish with the
+// rules they started with.
+func (h *ProxyHandler) UpdateRules(rules []config.HeaderRule) error {
+	set, err := newRuleSet(rules, h.config.UnsafePropagateCredentials)
+	if err != nil {
+		return err
+	}
+	h.rules.Store(set)
+	return nil
+}
+
+// Close releases resources held by the handler, such as the audit log file
+// and the workload API connection.
+func (h *ProxyHandler) Close() error {
+	return errors.Join(h.identity.Close(), h.audit.Close())
+}
+
+// AuditLog returns the audit log, or nil if it is disabled.
+func (h *ProxyHandler) AuditLog() *audit.Logger {
+	return h.audit
+}
+
+// RequestLog returns the buffer of recent requests, or nil if it is disabled.
+func (h *ProxyHandler) RequestLog() *RequestLog {
+	return h.requestLog
 }
 
 // ServeHTTP implements the http.Handler interface.
@@ -100,32 +328,161 @@ func (h *ProxyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	metrics.ActiveConnections.Inc()
 	defer metrics.ActiveConnections.Dec()
 
-	headerMap := h.extractHeaders(r)
+	// Unsigned context is dealt with before rules read or generate headers
+	if !h.checkContextSignature(w, r) {
+		return
+	}
+
+	result := h.applyRules(r)
+	headerMap := result.headers
+
+	// A transform or a header that bypassed the server's parser must not
+	// smuggle extra header lines into every downstream request
+	for name, value := range headerMap {
+		if strings.ContainsAny(value, "\x00\r\n") {
+			metrics.RecordRequestRejected(metrics.RejectInvalidValue)
+			logger := h.requestLogger(r)
+			logger.Warn().
+				Str("header", name).
+				Msg("Rejecting request: propagated header value contains NUL, CR or LF")
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+	}
 
 	// Record propagated headers metric
 	if len(headerMap) > 0 {
 		metrics.RecordHeadersPropagated(len(headerMap))
+		for name := range headerMap {
+			metrics.RecordHeaderPropagated(name)
+		}
+	}
+	for _, name := range result.generated {
+		metrics.RecordHeaderGenerated(name)
 	}
 
+	logger := h.requestLogger(r)
+	timing := &upstreamTiming{}
 	ctx := context.WithValue(r.Context(), ContextKeyHeaders, headerMap)
+	ctx = context.WithValue(ctx, contextKeyUpstreamTiming, timing)
+	ctx = context.WithValue(ctx, contextKeyDestinationHost, r.Host)
+	if len(result.destinations) > 0 {
+		ctx = context.WithValue(ctx, contextKeyDestinations, result.destinations)
+	}
+	if len(result.response) > 0 {
+		ctx = context.WithValue(ctx, contextKeyResponseHeaders, result.response)
+	}
+	ctx = logger.WithContext(ctx)
 	r = r.WithContext(ctx)
 
-	if log.Debug().Enabled() {
-		log.Debug().
+	if logger.Debug().Enabled() {
+		logger.Debug().
 			Str("method", r.Method).
 			Str("path", r.URL.Path).
 			Str("remote_addr", r.RemoteAddr).
-			Interface("propagated_headers", headerMap).
+			Interface("propagated_headers", h.redact.headers(headerMap)).
 			Msg("Proxying request")
 	}
 
-	// Wrap response writer to capture status code
+	for _, name := range result.generated {
+		h.auditHeader(r, audit.ActionGenerated, name, result.index[name], "")
+	}
+	for _, name := range result.overridden {
+		h.auditHeader(r, audit.ActionOverridden, name, result.index[name], "")
+	}
+	for _, name := range result.unsampled {
+		h.auditHeader(r, audit.ActionStripped, name, result.index[name], audit.ReasonSampling)
+	}
+	for _, name := range result.blocked {
+		metrics.RecordHeaderBlocked(name, audit.ReasonCredential)
+		h.auditHeader(r, audit.ActionBlocked, name, audit.NoRule, audit.ReasonCredential)
+	}
+
+	if h.config.DebugEchoEnabled && isDebugRequest(r) {
+		writeDebugEcho(w.Header(), result)
+		r.Header.Del(DebugHeader)
+		h.auditHeader(r, audit.ActionStripped, DebugHeader, audit.NoRule, "")
+	}
+
+	// Count request body bytes received from the caller
+	var body *countingReadCloser
+	if r.Body != nil && r.Body != http.NoBody {
+		body = &countingReadCloser{ReadCloser: r.Body}
+		r.Body = body
+	}
+
+	// Wrap response writer to capture status code and detect long-lived streams
 	rw := metrics.NewResponseWriter(w)
-	h.reverseProxy.ServeHTTP(rw, r)
+	sw := newStreamTrackingWriter(rw, r)
+	// The reverse proxy aborts with a panic when the client goes away
+	// mid-response, so the request is accounted for on the way out
+	defer func() {
+		sw.finish()
+
+		// Hijacked connections never call WriteHeader on the wrapper
+		statusCode := rw.StatusCode
+		if sw.hijacked {
+			statusCode = http.StatusSwitchingProtocols
+		}
+
+		// Record request metrics
+		duration := time.Since(start)
+		metrics.RecordRequest(r.Method, statusCode, duration)
+		var route string
+		if h.paths != nil {
+			route = h.paths.Normalize(r.URL.Path)
+			metrics.RecordPathRequest(r.Method, route, statusCode, duration)
+		}
+		if body != nil {
+			metrics.RecordBytes(metrics.DirectionInbound, body.n)
+		}
+		metrics.RecordBytes(metrics.DirectionOutbound, rw.BytesWritten)
+
+		if h.config.SlowRequestThreshold > 0 && duration > h.config.SlowRequestThreshold {
+			event := logger.Warn().
+				Str("method", r.Method).
+				Str("path", r.URL.Path)
+			if route != "" {
+				event = event.Str("route", route)
+			}
+			event.
+				Int("status", statusCode).
+				Dur("duration", duration).
+				Dur("upstream_duration", timing.duration).
+				Dur("threshold", h.config.SlowRequestThreshold).
+				Interface("propagated_headers", h.redact.headers(headerMap)).
+				Msg("Slow request")
+		}
+
+		if h.requestLog != nil {
+			h.requestLog.Add(RequestRecord{
+				Time:             start.UTC(),
+				Method:           r.Method,
+				Path:             r.URL.Path,
+				Status:           statusCode,
+				DurationMs:       float64(duration) / float64(time.Millisecond),
+				Headers:          h.redact.headers(headerMap),
+				MatchedRules:     result.matched,
+				GeneratedHeaders: result.generated,
+			})
+		}
+	}()
+
+	h.reverseProxy.ServeHTTP(sw, r)
+}
 
-	// Record request metrics
-	duration := time.Since(start)
-	metrics.RecordRequest(r.Method, rw.StatusCode, duration)
+// auditHeader records a header mutation in the audit log, with the reason of
+// the control that caused it, if any.
+func (h *ProxyHandler) auditHeader(r *http.Request, action audit.Action, header string, rule int, reason string) {
+	h.audit.Record(audit.Event{
+		RequestID: h.redact.value(h.requestIDHeader, r.Header.Get(h.requestIDHeader)),
+		Action:    action,
+		Header:    header,
+		Rule:      rule,
+		Reason:    reason,
+		Method:    r.Method,
+		Path:      r.URL.Path,
+	})
 }
 
 // extractHeaders extracts the configured headers from the incoming request.
@@ -133,42 +490,189 @@ func (h *ProxyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 // If a header is missing and has generation enabled, it will be generated.
 // Path and method filtering is applied to determine which rules apply.
 func (h *ProxyHandler) extractHeaders(r *http.Request) map[string]string {
-	headerMap := make(map[string]string)
+	return h.applyRules(r).headers
+}
+
+// applyRules evaluates the header rules against the request, generating missing
+// headers where configured, and reports which rules matched.
+func (h *ProxyHandler) applyRules(r *http.Request) ruleResult {
+	set := h.rules.Load()
+	result := ruleResult{headers: make(map[string]string), index: set.index}
 	path := r.URL.Path
 	method := r.Method
+	propagated := make(map[string]bool)
+	var sampled map[config.Sampling]bool
 
-	for _, rule := range h.rules {
+	for _, rule := range set.rules {
+		if rule.Response != "" {
+			continue
+		}
 		// Check if this rule applies to the current request
+		canonicalName := http.CanonicalHeaderKey(strings.TrimSpace(rule.Name))
 		if !rule.MatchesRequest(path, method) {
+			result.skipped = append(result.skipped, canonicalName)
 			continue
 		}
 
-		canonicalName := http.CanonicalHeaderKey(strings.TrimSpace(rule.Name))
 		value := r.Header.Get(canonicalName)
+		result.matched = append(result.matched, canonicalName)
+		// An earlier rule for the header already propagated it, possibly
+		// transformed or renamed
+		if propagated[canonicalName] {
+			continue
+		}
 
-		// If header is missing and generation is enabled, generate it
-		if value == "" && rule.Generate {
-			if gen, ok := h.generators[canonicalName]; ok {
-				value = gen.generator.Generate()
+		// Requests left out of the rule's sample don't carry the header on
+		if rule.Sampling != nil {
+			if sampled == nil {
+				sampled = make(map[config.Sampling]bool)
+			}
+			in, decided := sampled[*rule.Sampling]
+			if !decided {
+				in = rule.Sampling.Sampled(r.Header.Get(rule.Sampling.KeyHeader))
+				sampled[*rule.Sampling] = in
+			}
+			if !in {
+				if value != "" {
+					r.Header.Del(canonicalName)
+					result.unsampled = append(result.unsampled, canonicalName)
+				}
+				continue
+			}
+		}
+
+		// Generate or fill in the rule's own value, by default only when the
+		// header is missing
+		if value == "" || rule.OnExisting == config.OnExistingOverride || rule.OnExisting == config.OnExistingAppend {
+			var own string
+			if rule.Generate {
+				if gen, ok := set.generators[canonicalName]; ok {
+					own = gen.generator.Generate()
+					result.generated = append(result.generated, canonicalName)
+					if log.Debug().Enabled() {
+						logger := h.requestLogger(r)
+						logger.Debug().
+							Str("header", canonicalName).
+							Str("value", h.redact.value(canonicalName, own)).
+							Str("type", string(rule.GeneratorType)).
+							Msg("Generated header value")
+					}
+				}
+			} else {
+				own = rule.StaticValue
+			}
+			if own != "" {
+				switch {
+				case value == "":
+					value = own
+				case rule.OnExisting == config.OnExistingAppend:
+					value += ", " + own
+					result.overridden = append(result.overridden, canonicalName)
+				default:
+					value = own
+					result.overridden = append(result.overridden, canonicalName)
+				}
 				// Also set it on the request for downstream processing
 				r.Header.Set(canonicalName, value)
-				if log.Debug().Enabled() {
-					log.Debug().
-						Str("header", canonicalName).
-						Str("value", value).
-						Str("type", string(rule.GeneratorType)).
-						Msg("Generated header value")
-				}
 			}
 		}
 
-		// Add to header map if we have a value and propagation is enabled
-		if value != "" && rule.Propagate {
-			headerMap[canonicalName] = value
+		if value == "" {
+			continue
+		}
+		if !rule.Propagate {
+			result.withheld = append(result.withheld, canonicalName)
+			continue
+		}
+
+		// Transform and rename on the forwarded request too, as the
+		// propagating transport only adds headers the request lacks
+		if rule.Transform != nil {
+			value = rule.Transform.Apply(value)
+			r.Header.Set(canonicalName, value)
+		}
+		name := canonicalName
+		if rule.Rename != "" {
+			name = http.CanonicalHeaderKey(rule.Rename)
+			r.Header.Del(canonicalName)
+			r.Header.Set(name, value)
+		}
+		result.headers[name] = value
+		propagated[canonicalName] = true
+		if rule.Destinations != nil {
+			if result.destinations == nil {
+				result.destinations = make(map[string]*config.Destinations)
+			}
+			result.destinations[name] = rule.Destinations
+		}
+	}
+
+	for _, name := range set.credentials {
+		if r.Header.Get(name) != "" {
+			result.blocked = append(result.blocked, name)
+		}
+	}
+
+	// Response rules see the request as forwarded to the application
+	for i, rule := range set.rules {
+		if rule.Response == "" || !rule.MatchesRequest(path, method) {
+			continue
+		}
+		header := responseHeader{name: http.CanonicalHeaderKey(strings.TrimSpace(rule.Name)), rule: i}
+		if rule.Response == config.ResponseEcho {
+			if header.value = r.Header.Get(header.name); header.value == "" {
+				continue
+			}
+		}
+		result.response = append(result.response, header)
+	}
+
+	return result
+}
+
+// applyResponseRules echoes and strips the response headers configured for
+// the request by the response rules.
+func (h *ProxyHandler) applyResponseRules(r *http.Request, header http.Header) {
+	headers, _ := r.Context().Value(contextKeyResponseHeaders).([]responseHeader)
+	for _, rh := range headers {
+		if rh.value != "" {
+			header.Set(rh.name, rh.value)
+			h.auditHeader(r, audit.ActionAdded, rh.name, rh.rule, "")
+			continue
+		}
+		if _, ok := header[rh.name]; ok {
+			header.Del(rh.name)
+			h.auditHeader(r, audit.ActionStripped, rh.name, rh.rule, "")
 		}
 	}
+}
+
+// getDestinationsFromContext retrieves the destinations the propagated headers
+// are restricted to from a request context. Headers without an entry may be
+// propagated anywhere.
+func getDestinationsFromContext(ctx context.Context) map[string]*config.Destinations {
+	destinations, _ := ctx.Value(contextKeyDestinations).(map[string]*config.Destinations)
+	return destinations
+}
+
+// destinationHost returns the host a request is addressed to: the incoming
+// Host recorded by ProxyHandler, as the reverse proxy has since pointed the
+// URL at the target, or else the request's own.
+func destinationHost(req *http.Request) string {
+	if host, _ := req.Context().Value(contextKeyDestinationHost).(string); host != "" {
+		return host
+	}
+	if req.Host != "" {
+		return req.Host
+	}
+	return req.URL.Host
+}
 
-	return headerMap
+// getUpstreamTimingFromContext retrieves the upstream timing recorder from a request context.
+// Returns nil if the request was not created by ProxyHandler.
+func getUpstreamTimingFromContext(ctx context.Context) *upstreamTiming {
+	timing, _ := ctx.Value(contextKeyUpstreamTiming).(*upstreamTiming)
+	return timing
 }
 
 // GetHeadersFromContext retrieves the propagated headers from a request context.
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -9,7 +10,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/bgruszka/contextforge/internal/audit"
 	"github.com/bgruszka/contextforge/internal/config"
+	"github.com/bgruszka/contextforge/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -256,6 +260,23 @@ func TestProxyHandler_HeaderGeneration(t *testing.T) {
 	assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`, headers["X-Request-Id"])
 }
 
+func TestProxyHandler_HeaderGenerationFirstRuleWins(t *testing.T) {
+	cfg := &config.ProxyConfig{
+		HeadersToPropagate: []string{"x-request-id"},
+		HeaderRules: []config.HeaderRule{
+			{Name: "x-request-id", Generate: true, GeneratorType: "uuid", Propagate: true},
+			{Name: "X-Request-Id", Generate: true, GeneratorType: "timestamp", Propagate: true},
+		},
+		TargetHost: "localhost:8080",
+	}
+
+	handler, err := NewProxyHandler(cfg)
+	require.NoError(t, err)
+
+	headers := handler.extractHeaders(httptest.NewRequest(http.MethodGet, "/test", nil))
+	assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`, headers["X-Request-Id"])
+}
+
 func TestProxyHandler_HeaderGenerationPreservesExisting(t *testing.T) {
 	cfg := &config.ProxyConfig{
 		HeadersToPropagate: []string{"x-request-id"},
@@ -329,6 +350,28 @@ func TestProxyHandler_PathFiltering(t *testing.T) {
 	assert.Len(t, headersNoMatch, 0)
 }
 
+func TestProxyHandler_ExcludePaths(t *testing.T) {
+	cfg := testConfig("localhost:8080", nil)
+	cfg.HeaderRules = []config.HeaderRule{{
+		Name:                 "x-request-id",
+		Generate:             true,
+		GeneratorType:        "uuid",
+		Propagate:            true,
+		CompiledExcludePaths: []*regexp.Regexp{mustCompileRegex("^/healthz$"), mustCompileRegex("^/metrics$")},
+	}}
+	handler, err := NewProxyHandler(cfg)
+	require.NoError(t, err)
+
+	for _, path := range []string{"/healthz", "/metrics"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		assert.Empty(t, handler.extractHeaders(req), path)
+		assert.Empty(t, req.Header.Get("X-Request-Id"), "no ID is generated for %s", path)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+	assert.NotEmpty(t, handler.extractHeaders(req)["X-Request-Id"])
+}
+
 func TestProxyHandler_MethodFiltering(t *testing.T) {
 	cfg := &config.ProxyConfig{
 		HeadersToPropagate: []string{"x-request-id"},
@@ -369,3 +412,415 @@ func TestProxyHandler_MethodFiltering(t *testing.T) {
 func mustCompileRegex(pattern string) *regexp.Regexp {
 	return regexp.MustCompile(pattern)
 }
+
+func TestProxyHandler_PathMetrics(t *testing.T) {
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer targetServer.Close()
+
+	cfg := testConfig(targetServer.Listener.Addr().String(), []string{"x-request-id"})
+	cfg.PathMetricsEnabled = true
+	cfg.PathTemplates = []string{"/api/users/:id"}
+	handler, err := NewProxyHandler(cfg)
+	require.NoError(t, err)
+
+	counter := metrics.PathRequestsTotal.WithLabelValues(http.MethodGet, "/api/users/:id", "200")
+	before := testutil.ToFloat64(counter)
+
+	for _, path := range []string{"/api/users/1", "/api/users/2"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	assert.Equal(t, before+2, testutil.ToFloat64(counter))
+}
+
+func TestProxyHandler_HeaderMetrics(t *testing.T) {
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer targetServer.Close()
+
+	cfg := testConfig(targetServer.Listener.Addr().String(), nil)
+	cfg.HeaderRules = []config.HeaderRule{
+		{Name: "x-request-id", Propagate: true, Generate: true, GeneratorType: "uuid"},
+		{Name: "x-tenant", Rename: "x-tenant-id", Propagate: true},
+	}
+	handler, err := NewProxyHandler(cfg)
+	require.NoError(t, err)
+
+	propagated := metrics.HeaderPropagatedTotal.WithLabelValues("X-Request-Id")
+	generated := metrics.HeaderGeneratedTotal.WithLabelValues("X-Request-Id")
+	renamed := metrics.HeaderPropagatedTotal.WithLabelValues("X-Tenant-Id")
+	propagatedBefore, generatedBefore, renamedBefore := testutil.ToFloat64(propagated), testutil.ToFloat64(generated), testutil.ToFloat64(renamed)
+
+	req := httptest.NewRequest(http.MethodGet, "/api", nil)
+	req.Header.Set("X-Request-Id", "abc123")
+	req.Header.Set("X-Tenant", "acme")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api", nil))
+
+	assert.Equal(t, propagatedBefore+2, testutil.ToFloat64(propagated))
+	assert.Equal(t, generatedBefore+1, testutil.ToFloat64(generated))
+	assert.Equal(t, renamedBefore+1, testutil.ToFloat64(renamed), "renamed headers are counted under their new name")
+}
+
+func TestProxyHandler_UpdateRules(t *testing.T) {
+	handler, err := NewProxyHandler(testConfig("localhost:8080", []string{"x-request-id"}))
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("X-Request-Id", "abc123")
+	req.Header.Set("X-Tenant-Id", "acme")
+	assert.Equal(t, map[string]string{"X-Request-Id": "abc123"}, handler.extractHeaders(req))
+
+	require.NoError(t, handler.UpdateRules([]config.HeaderRule{
+		{Name: "x-tenant-id", Propagate: true},
+		{Name: "x-correlation-id", Propagate: true, Generate: true, GeneratorType: "uuid"},
+	}))
+
+	headers := handler.extractHeaders(req)
+	assert.Equal(t, "acme", headers["X-Tenant-Id"])
+	assert.NotEmpty(t, headers["X-Correlation-Id"])
+	assert.NotContains(t, headers, "X-Request-Id")
+
+	err = handler.UpdateRules([]config.HeaderRule{{Name: "x-id", Generate: true, GeneratorType: "random"}})
+	assert.Error(t, err)
+	assert.Contains(t, handler.extractHeaders(req), "X-Tenant-Id", "invalid rules are not applied")
+}
+
+func TestProxyHandler_Transformations(t *testing.T) {
+	var receivedHeaders http.Header
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedHeaders = r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer targetServer.Close()
+
+	cfg := testConfig(targetServer.Listener.Addr().String(), nil)
+	cfg.HeaderRules = []config.HeaderRule{
+		{Name: "x-user-email", Propagate: true, Transform: &config.Transform{Type: config.TransformHash, Length: 16}},
+		{Name: "x-tenant", Rename: "x-tenant-id", Propagate: true, Transform: &config.Transform{Type: config.TransformLowercase}},
+		{Name: "x-environment", StaticValue: "production", Propagate: true},
+		{Name: "x-session-id", Propagate: true, Transform: &config.Transform{Type: config.TransformTruncate, Length: 8}},
+		// A second matching rule must not transform the header again
+		{Name: "x-session-id", Propagate: true, Transform: &config.Transform{Type: config.TransformTruncate, Length: 8}},
+	}
+	handler, err := NewProxyHandler(cfg)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/api", nil)
+	req.Header.Set("X-User-Email", "jane@example.com")
+	req.Header.Set("X-Tenant", "ACME")
+	req.Header.Set("X-Session-Id", "0123456789abcdef")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	// First 16 hex digits of the SHA-256 of jane@example.com
+	assert.Equal(t, "8c87b489ce35cf2e", receivedHeaders.Get("X-User-Email"))
+	assert.Equal(t, "acme", receivedHeaders.Get("X-Tenant-Id"))
+	assert.Empty(t, receivedHeaders.Get("X-Tenant"))
+	assert.Equal(t, "production", receivedHeaders.Get("X-Environment"))
+	assert.Equal(t, "01234567", receivedHeaders.Get("X-Session-Id"))
+
+	// The static value doesn't replace one sent by the caller
+	req = httptest.NewRequest(http.MethodGet, "/api", nil)
+	req.Header.Set("X-Environment", "staging")
+	assert.Equal(t, "staging", handler.extractHeaders(req)["X-Environment"])
+}
+
+func TestProxyHandler_IgnoresCredentialRules(t *testing.T) {
+	var receivedHeaders http.Header
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedHeaders = r.Header.Clone()
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer targetServer.Close()
+
+	cfg := testConfig(targetServer.Listener.Addr().String(), nil)
+	cfg.HeaderRules = []config.HeaderRule{
+		{Name: "x-request-id", Propagate: true},
+		{Name: "authorization", Propagate: true},
+		{Name: "x-api-key", StaticValue: "secret", Propagate: true},
+		{Name: "x-user-token", Rename: "cookie", Propagate: true},
+		{Name: "set-cookie", Response: config.ResponseStrip},
+	}
+	handler, err := NewProxyHandler(cfg)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/api", nil)
+	req.Header.Set("X-Request-Id", "req-1")
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("X-User-Token", "token")
+	assert.Equal(t, map[string]string{"X-Request-Id": "req-1"}, handler.extractHeaders(req))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, receivedHeaders.Get("X-Api-Key"), "static credentials are never set")
+	assert.Empty(t, receivedHeaders.Get("Cookie"), "headers are never renamed to credentials")
+	assert.NotContains(t, rr.Header(), "Set-Cookie", "response rules still strip credentials")
+
+	cfg.UnsafePropagateCredentials = true
+	require.NoError(t, handler.UpdateRules(cfg.HeaderRules))
+	assert.Equal(t, "Bearer secret", handler.extractHeaders(req)["Authorization"])
+}
+
+func TestProxyHandler_RejectsInjectedValues(t *testing.T) {
+	targetCalled := false
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		targetCalled = true
+	}))
+	defer targetServer.Close()
+
+	handler, err := NewProxyHandler(testConfig(targetServer.Listener.Addr().String(), []string{"x-request-id"}))
+	require.NoError(t, err)
+	rejected := testutil.ToFloat64(metrics.RequestsRejectedTotal.WithLabelValues(metrics.RejectInvalidValue))
+
+	for _, value := range []string{"abc\r\nX-Injected: 1", "abc\nX-Injected: 1", "abc\x00"} {
+		req := httptest.NewRequest(http.MethodGet, "/api", nil)
+		req.Header["X-Request-Id"] = []string{value}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, "value %q", value)
+	}
+	assert.False(t, targetCalled)
+	assert.Equal(t, rejected+3, testutil.ToFloat64(metrics.RequestsRejectedTotal.WithLabelValues(metrics.RejectInvalidValue)))
+}
+
+func TestProxyHandler_ResponseRules(t *testing.T) {
+	var receivedHeaders http.Header
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedHeaders = r.Header.Clone()
+		w.Header().Set("Server", "app/1.2.3")
+		w.Header().Set("X-Powered-By", "framework")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer targetServer.Close()
+
+	cfg := testConfig(targetServer.Listener.Addr().String(), nil)
+	cfg.HeaderRules = []config.HeaderRule{
+		{Name: "x-request-id", Generate: true, GeneratorType: "uuid", Propagate: true},
+		{Name: "x-request-id", Response: config.ResponseEcho},
+		{Name: "x-tenant-id", Response: config.ResponseEcho},
+		{Name: "server", Response: config.ResponseStrip},
+		{Name: "x-powered-by", Response: config.ResponseStrip, CompiledPathRegex: mustCompileRegex("^/public/")},
+	}
+	handler, err := NewProxyHandler(cfg)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/api", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.NotEmpty(t, rr.Header().Get("X-Request-Id"))
+	assert.Equal(t, receivedHeaders.Get("X-Request-Id"), rr.Header().Get("X-Request-Id"))
+	assert.NotContains(t, rr.Header(), "X-Tenant-Id", "absent request headers aren't echoed")
+	assert.NotContains(t, rr.Header(), "Server")
+	assert.Equal(t, "framework", rr.Header().Get("X-Powered-By"))
+	assert.NotContains(t, handler.extractHeaders(req), "Server", "response rules aren't propagated")
+
+	req = httptest.NewRequest(http.MethodGet, "/public/logo.png", nil)
+	req.Header.Set("X-Tenant-Id", "acme")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, "acme", rr.Header().Get("X-Tenant-Id"))
+	assert.NotContains(t, rr.Header(), "X-Powered-By")
+}
+
+func TestProxyHandler_ResponseRulesOnUpstreamError(t *testing.T) {
+	cfg := testConfig("127.0.0.1:1", nil)
+	cfg.HeaderRules = []config.HeaderRule{
+		{Name: "x-request-id", Propagate: true},
+		{Name: "x-request-id", Response: config.ResponseEcho},
+	}
+	handler, err := NewProxyHandler(cfg)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/api", nil)
+	req.Header.Set("X-Request-Id", "req-1")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadGateway, rr.Code)
+	assert.Equal(t, "req-1", rr.Header().Get("X-Request-Id"))
+}
+
+func TestProxyHandler_OnExisting(t *testing.T) {
+	cfg := testConfig("localhost:8080", nil)
+	cfg.HeaderRules = []config.HeaderRule{
+		{Name: "x-request-id", Generate: true, GeneratorType: "timestamp", Propagate: true, OnExisting: config.OnExistingOverride},
+		{Name: "x-environment", StaticValue: "production", Propagate: true, OnExisting: config.OnExistingOverride},
+		{Name: "x-forwarded-for-service", StaticValue: "edge", Propagate: true, OnExisting: config.OnExistingAppend},
+		{Name: "x-tenant-id", StaticValue: "default", Propagate: true, OnExisting: config.OnExistingPreserve},
+	}
+	handler, err := NewProxyHandler(cfg)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/api", nil)
+	req.Header.Set("X-Request-Id", "from-client")
+	req.Header.Set("X-Environment", "staging")
+	req.Header.Set("X-Forwarded-For-Service", "gateway")
+	req.Header.Set("X-Tenant-Id", "acme")
+	result := handler.applyRules(req)
+
+	assert.NotEqual(t, "from-client", result.headers["X-Request-Id"])
+	assert.NotEmpty(t, result.headers["X-Request-Id"])
+	assert.Equal(t, result.headers["X-Request-Id"], req.Header.Get("X-Request-Id"), "the forwarded request carries the new value")
+	assert.Equal(t, "production", result.headers["X-Environment"])
+	assert.Equal(t, "gateway, edge", result.headers["X-Forwarded-For-Service"])
+	assert.Equal(t, "acme", result.headers["X-Tenant-Id"])
+	assert.Equal(t, []string{"X-Request-Id", "X-Environment", "X-Forwarded-For-Service"}, result.overridden)
+
+	// Missing headers get the rule's value whatever the strategy
+	req = httptest.NewRequest(http.MethodGet, "/api", nil)
+	result = handler.applyRules(req)
+	assert.Equal(t, "edge", result.headers["X-Forwarded-For-Service"])
+	assert.Equal(t, "default", result.headers["X-Tenant-Id"])
+	assert.Empty(t, result.overridden)
+}
+
+func TestProxyHandler_Sampling(t *testing.T) {
+	cfg := testConfig("localhost:8080", nil)
+	cfg.HeaderRules = []config.HeaderRule{
+		{Name: "x-request-id", Propagate: true},
+		{Name: "baggage", Propagate: true, Sampling: &config.Sampling{Percentage: 50, KeyHeader: "x-request-id"}},
+		{Name: "x-debug-context", Propagate: true, Sampling: &config.Sampling{Percentage: 50, KeyHeader: "x-request-id"}},
+		{Name: "x-never", Propagate: true, Sampling: &config.Sampling{Percentage: 0}},
+	}
+	handler, err := NewProxyHandler(cfg)
+	require.NoError(t, err)
+
+	var in, out int
+	for i := range 200 {
+		req := httptest.NewRequest(http.MethodGet, "/api", nil)
+		req.Header.Set("X-Request-Id", fmt.Sprintf("req-%d", i))
+		req.Header.Set("Baggage", "k=v")
+		req.Header.Set("X-Debug-Context", "on")
+		req.Header.Set("X-Never", "1")
+		result := handler.applyRules(req)
+
+		assert.Equal(t, result.headers["Baggage"] != "", result.headers["X-Debug-Context"] != "",
+			"rules with the same sampling share the decision")
+		assert.NotContains(t, result.headers, "X-Never")
+		assert.Empty(t, req.Header.Get("X-Never"), "unsampled headers are not forwarded")
+		if result.headers["Baggage"] != "" {
+			in++
+		} else {
+			out++
+			assert.Empty(t, req.Header.Get("Baggage"))
+		}
+
+		// The decision is deterministic for a key
+		again := httptest.NewRequest(http.MethodGet, "/api", nil)
+		again.Header.Set("X-Request-Id", fmt.Sprintf("req-%d", i))
+		again.Header.Set("Baggage", "k=v")
+		assert.Equal(t, result.headers["Baggage"], handler.applyRules(again).headers["Baggage"])
+	}
+	assert.Positive(t, in)
+	assert.Positive(t, out)
+}
+
+func TestProxyHandler_Destinations(t *testing.T) {
+	var received http.Header
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer targetServer.Close()
+
+	cfg := testConfig(targetServer.Listener.Addr().String(), []string{"x-request-id"})
+	cfg.HeaderRules = append(cfg.HeaderRules, config.HeaderRule{
+		Name:         "x-tenant-id",
+		Propagate:    true,
+		Destinations: &config.Destinations{Allow: []string{"*.svc.cluster.local"}, Deny: []string{"billing.shop.svc.cluster.local"}},
+	})
+	handler, err := NewProxyHandler(cfg)
+	require.NoError(t, err)
+
+	tests := []struct {
+		host       string
+		wantTenant string
+	}{
+		{host: "orders.shop.svc.cluster.local", wantTenant: "acme"},
+		{host: "orders.shop.svc.cluster.local:8080", wantTenant: "acme"},
+		{host: "billing.shop.svc.cluster.local"},
+		{host: "api.example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			before := testutil.ToFloat64(metrics.HeaderBlockedTotal.WithLabelValues("X-Tenant-Id", audit.ReasonDestination))
+			req := httptest.NewRequest(http.MethodGet, "/api", nil)
+			req.Host = tt.host
+			req.Header.Set("X-Request-Id", "abc123")
+			req.Header.Set("X-Tenant-Id", "acme")
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, tt.wantTenant, received.Get("X-Tenant-Id"))
+			assert.Equal(t, "abc123", received.Get("X-Request-Id"), "unrestricted headers go everywhere")
+			blocked := testutil.ToFloat64(metrics.HeaderBlockedTotal.WithLabelValues("X-Tenant-Id", audit.ReasonDestination)) - before
+			if tt.wantTenant == "" {
+				assert.Equal(t, float64(1), blocked)
+			} else {
+				assert.Zero(t, blocked)
+			}
+		})
+	}
+}
+
+func TestProxyHandler_InClusterOnly(t *testing.T) {
+	var received http.Header
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer targetServer.Close()
+
+	cfg := testConfig(targetServer.Listener.Addr().String(), []string{"x-request-id"})
+	cfg.InClusterOnly = true
+	cfg.ClusterDomains = config.DefaultClusterDomains
+	cfg.ClusterCIDRs = config.DefaultClusterCIDRs
+	handler, err := NewProxyHandler(cfg)
+	require.NoError(t, err)
+	transport := handler.reverseProxy.Transport.(*HeaderPropagatingTransport)
+	transport.identity = staticIdentity("spiffe://example.org/api")
+	transport.identityHeader = config.DefaultSPIFFEIDHeader
+
+	tests := []struct {
+		host         string
+		wantHeaders  bool
+		wantIdentity string
+	}{
+		{host: "orders.shop.svc.cluster.local", wantHeaders: true, wantIdentity: "spiffe://example.org/api"},
+		{host: "api.stripe.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			before := testutil.ToFloat64(metrics.HeaderBlockedTotal.WithLabelValues("X-Request-Id", audit.ReasonExternalHost))
+			req := httptest.NewRequest(http.MethodGet, "/api", nil)
+			req.Host = tt.host
+			req.Header.Set("X-Request-Id", "abc123")
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+			blocked := testutil.ToFloat64(metrics.HeaderBlockedTotal.WithLabelValues("X-Request-Id", audit.ReasonExternalHost)) - before
+			if tt.wantHeaders {
+				assert.Equal(t, "abc123", received.Get("X-Request-Id"))
+				assert.Zero(t, blocked)
+			} else {
+				assert.Empty(t, received.Get("X-Request-Id"))
+				assert.Equal(t, float64(1), blocked)
+			}
+			assert.Equal(t, tt.wantIdentity, received.Get(config.DefaultSPIFFEIDHeader))
+		})
+	}
+}
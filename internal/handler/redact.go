@@ -0,0 +1,76 @@
+package handler
+
+import "net/http"
+
+// redactedValue replaces the value of sensitive headers in log output.
+const redactedValue = "[REDACTED]"
+
+// sensitiveHeaders lists canonical header names whose values are never logged,
+// whatever REDACT_HEADERS adds.
+var sensitiveHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-Api-Key",
+	"X-Auth-Token",
+	"X-Access-Token",
+	"X-Csrf-Token",
+	"X-Xsrf-Token",
+	"X-Amz-Security-Token",
+}
+
+// redactor replaces the values of sensitive headers in logs, the request log
+// and the audit log. The zero redactor redacts nothing.
+type redactor struct {
+	names map[string]bool
+	// all redacts every header except those in keep, for SECURE_MODE=strict.
+	all  bool
+	keep map[string]bool
+}
+
+// newRedactor returns a redactor for sensitiveHeaders and the given names,
+// compared case-insensitively.
+func newRedactor(names []string) redactor {
+	r := redactor{names: make(map[string]bool, len(sensitiveHeaders)+len(names))}
+	for _, name := range append(append([]string(nil), sensitiveHeaders...), names...) {
+		r.names[http.CanonicalHeaderKey(name)] = true
+	}
+	return r
+}
+
+// redactAll returns a copy of r that also redacts every header but keep,
+// which names the correlation headers logs are searched by. Headers r
+// redacts stay redacted even when kept.
+func (r redactor) redactAll(keep ...string) redactor {
+	r.all = true
+	r.keep = make(map[string]bool, len(keep))
+	for _, name := range keep {
+		if name != "" {
+			r.keep[http.CanonicalHeaderKey(name)] = true
+		}
+	}
+	return r
+}
+
+// value returns value, or redactedValue if the header name is sensitive.
+func (r redactor) value(name, value string) string {
+	if value == "" {
+		return value
+	}
+	key := http.CanonicalHeaderKey(name)
+	if r.names[key] || (r.all && !r.keep[key]) {
+		return redactedValue
+	}
+	return value
+}
+
+// headers returns a copy of headers with the values of sensitive headers replaced,
+// suitable for logging.
+func (r redactor) headers(headers map[string]string) map[string]string {
+	redacted := make(map[string]string, len(headers))
+	for name, value := range headers {
+		redacted[name] = r.value(name, value)
+	}
+	return redacted
+}
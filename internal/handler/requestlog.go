@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RequestRecord describes a single proxied request kept in the RequestLog.
+type RequestRecord struct {
+	Time             time.Time         `json:"time"`
+	Method           string            `json:"method"`
+	Path             string            `json:"path"`
+	Status           int               `json:"status"`
+	DurationMs       float64           `json:"durationMs"`
+	Headers          map[string]string `json:"headers,omitempty"`
+	MatchedRules     []string          `json:"matchedRules,omitempty"`
+	GeneratedHeaders []string          `json:"generatedHeaders,omitempty"`
+}
+
+// RequestLogResponse represents the JSON response of the /debug/requests endpoint.
+type RequestLogResponse struct {
+	Capacity int             `json:"capacity"`
+	Requests []RequestRecord `json:"requests"`
+}
+
+// RequestLog is a fixed-size ring buffer of the most recent proxied requests.
+// It is safe for concurrent use.
+type RequestLog struct {
+	mu      sync.Mutex
+	records []RequestRecord
+	next    int
+	full    bool
+}
+
+// NewRequestLog creates a RequestLog that keeps the last size requests.
+func NewRequestLog(size int) *RequestLog {
+	return &RequestLog{records: make([]RequestRecord, size)}
+}
+
+// Add stores a record, overwriting the oldest one when the buffer is full.
+func (l *RequestLog) Add(record RequestRecord) {
+	if len(l.records) == 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.records[l.next] = record
+	l.next = (l.next + 1) % len(l.records)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+// Records returns the stored records, newest first.
+func (l *RequestLog) Records() []RequestRecord {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	count := l.next
+	if l.full {
+		count = len(l.records)
+	}
+
+	records := make([]RequestRecord, 0, count)
+	for i := 1; i <= count; i++ {
+		idx := (l.next - i + len(l.records)) % len(l.records)
+		records = append(records, l.records[idx])
+	}
+	return records
+}
+
+// ServeHTTP writes the stored records as JSON.
+func (l *RequestLog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	response := RequestLogResponse{
+		Capacity: len(l.records),
+		Requests: l.Records(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(response)
+}
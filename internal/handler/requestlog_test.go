@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestLog_KeepsNewestFirst(t *testing.T) {
+	l := NewRequestLog(3)
+
+	assert.Empty(t, l.Records())
+
+	for _, path := range []string{"/a", "/b"} {
+		l.Add(RequestRecord{Path: path})
+	}
+	records := l.Records()
+	require.Len(t, records, 2)
+	assert.Equal(t, "/b", records[0].Path)
+	assert.Equal(t, "/a", records[1].Path)
+}
+
+func TestRequestLog_OverwritesOldest(t *testing.T) {
+	l := NewRequestLog(3)
+
+	for _, path := range []string{"/a", "/b", "/c", "/d", "/e"} {
+		l.Add(RequestRecord{Path: path})
+	}
+
+	records := l.Records()
+	require.Len(t, records, 3)
+	assert.Equal(t, "/e", records[0].Path)
+	assert.Equal(t, "/d", records[1].Path)
+	assert.Equal(t, "/c", records[2].Path)
+}
+
+func TestRequestLog_ServeHTTP(t *testing.T) {
+	l := NewRequestLog(10)
+	l.Add(RequestRecord{Method: http.MethodGet, Path: "/api", Status: http.StatusOK})
+
+	rr := httptest.NewRecorder()
+	l.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/debug/requests", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+	var response RequestLogResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+	assert.Equal(t, 10, response.Capacity)
+	require.Len(t, response.Requests, 1)
+	assert.Equal(t, "/api", response.Requests[0].Path)
+
+	rr = httptest.NewRecorder()
+	l.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/debug/requests", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
+
+func TestProxyHandler_RecordsRequests(t *testing.T) {
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer targetServer.Close()
+
+	cfg := testConfig(targetServer.Listener.Addr().String(), []string{"x-request-id", "authorization", "x-tenant-id"})
+	cfg.DebugRequestBufferSize = 5
+	// Credentials are only propagated with the override, and still redacted
+	cfg.UnsafePropagateCredentials = true
+	handler, err := NewProxyHandler(cfg)
+	require.NoError(t, err)
+	require.NotNil(t, handler.RequestLog())
+
+	req := httptest.NewRequest(http.MethodPost, "/orders", nil)
+	req.Header.Set("X-Request-Id", "abc123")
+	req.Header.Set("Authorization", "Bearer secret")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	records := handler.RequestLog().Records()
+	require.Len(t, records, 1)
+	record := records[0]
+	assert.Equal(t, http.MethodPost, record.Method)
+	assert.Equal(t, "/orders", record.Path)
+	assert.Equal(t, http.StatusAccepted, record.Status)
+	assert.Equal(t, "abc123", record.Headers["X-Request-Id"])
+	assert.Equal(t, redactedValue, record.Headers["Authorization"])
+	assert.Equal(t, []string{"X-Request-Id", "Authorization", "X-Tenant-Id"}, record.MatchedRules)
+	assert.False(t, record.Time.IsZero())
+}
+
+func TestProxyHandler_RequestLogDisabled(t *testing.T) {
+	handler, err := NewProxyHandler(testConfig("localhost:8080", []string{"x-request-id"}))
+	require.NoError(t, err)
+
+	assert.Nil(t, handler.RequestLog())
+}
@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/bgruszka/contextforge/internal/audit"
+	"github.com/bgruszka/contextforge/internal/config"
+	"github.com/bgruszka/contextforge/internal/metrics"
+)
+
+// Parts of a context signature: the signed header names and the HMAC-SHA256
+// of their values, e.g. "h=x-request-id,x-tenant-id;v1=<base64url>".
+const (
+	signatureHeadersPart = "h="
+	signatureValuePart   = "v1="
+)
+
+// contextSigner signs the context headers a proxy forwards and verifies the
+// signatures of those it receives, with HMAC keys shared by the proxies of a
+// mesh. A signature vouches that the headers it names were forwarded by a
+// proxy holding a key, with these values.
+type contextSigner struct {
+	// keys verify signatures; the first one also signs
+	keys   [][]byte
+	header string
+}
+
+// newContextSigner returns a signer for the configured keys, or nil when
+// context signing is disabled.
+func newContextSigner(cfg *config.ProxyConfig) *contextSigner {
+	if len(cfg.ContextSigningKeys) == 0 {
+		return nil
+	}
+	return &contextSigner{
+		keys:   cfg.ContextSigningKeys,
+		header: http.CanonicalHeaderKey(cfg.ContextSignatureHeader),
+	}
+}
+
+// sign returns the signature of the named headers' values in header.
+func (s *contextSigner) sign(header http.Header, names []string) string {
+	lower := make([]string, len(names))
+	for i, name := range names {
+		lower[i] = strings.ToLower(name)
+	}
+	return signatureHeadersPart + strings.Join(lower, ",") + ";" +
+		signatureValuePart + base64.RawURLEncoding.EncodeToString(contextMAC(s.keys[0], header, lower))
+}
+
+// verified returns the canonical names of the headers a valid signature in
+// header covers, or nil if it carries none.
+func (s *contextSigner) verified(header http.Header) map[string]bool {
+	var names []string
+	var signature []byte
+	for _, part := range strings.Split(header.Get(s.header), ";") {
+		part = strings.TrimSpace(part)
+		switch {
+		case strings.HasPrefix(part, signatureHeadersPart):
+			names = strings.Split(strings.TrimPrefix(part, signatureHeadersPart), ",")
+		case strings.HasPrefix(part, signatureValuePart):
+			var err error
+			if signature, err = base64.RawURLEncoding.DecodeString(strings.TrimPrefix(part, signatureValuePart)); err != nil {
+				return nil
+			}
+		}
+	}
+	if len(names) == 0 || signature == nil {
+		return nil
+	}
+	for _, key := range s.keys {
+		if hmac.Equal(signature, contextMAC(key, header, names)) {
+			covered := make(map[string]bool, len(names))
+			for _, name := range names {
+				covered[http.CanonicalHeaderKey(name)] = true
+			}
+			return covered
+		}
+	}
+	return nil
+}
+
+// contextMAC authenticates the names and values of the named headers, in
+// order. Names are lower case and values are joined as they would be folded.
+func contextMAC(key []byte, header http.Header, names []string) []byte {
+	mac := hmac.New(sha256.New, key)
+	for _, name := range names {
+		mac.Write([]byte(name + ":" + strings.Join(header.Values(name), ",") + "\n"))
+	}
+	return mac.Sum(nil)
+}
+
+// signContext replaces the request's context signature with one covering
+// the propagated headers it carries. Only the proxy signs: a signature sent
+// by the caller is dropped, and external hosts get none, as they hold no key
+// to verify it with.
+func (t *HeaderPropagatingTransport) signContext(req *http.Request, headerMap map[string]string, external bool) {
+	req.Header.Del(t.signer.header)
+	if external {
+		return
+	}
+	names := make([]string, 0, len(headerMap))
+	for name := range headerMap {
+		if req.Header.Get(name) != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+	slices.Sort(names)
+	req.Header.Set(t.signer.header, t.signer.sign(req.Header, names))
+}
+
+// checkContextSignature applies UNSIGNED_CONTEXT to the inbound headers the
+// propagation rules read that no valid signature covers: they are removed,
+// or the request is answered with 403. It returns false if the request was
+// refused.
+func (h *ProxyHandler) checkContextSignature(w http.ResponseWriter, r *http.Request) bool {
+	if h.signer == nil || h.config.UnsignedContext == "" || h.config.UnsignedContext == config.UnsignedContextAllow {
+		return true
+	}
+	set := h.rules.Load()
+	var covered map[string]bool
+	var unsigned []string
+	for _, rule := range set.rules {
+		if rule.Response != "" || !rule.Propagate {
+			continue
+		}
+		name := http.CanonicalHeaderKey(strings.TrimSpace(rule.Name))
+		if r.Header.Get(name) == "" || slices.Contains(unsigned, name) {
+			continue
+		}
+		if covered == nil {
+			if covered = h.signer.verified(r.Header); covered == nil {
+				covered = map[string]bool{}
+			}
+		}
+		if !covered[name] {
+			unsigned = append(unsigned, name)
+		}
+	}
+	if len(unsigned) == 0 {
+		return true
+	}
+
+	logger := h.requestLogger(r)
+	if h.config.UnsignedContext == config.UnsignedContextReject {
+		metrics.RecordRequestRejected(metrics.RejectUnsignedContext)
+		for _, name := range unsigned {
+			h.auditHeader(r, audit.ActionRejected, name, set.index[name], audit.ReasonUnsigned)
+		}
+		logger.Warn().
+			Strs("headers", unsigned).
+			Str("remote_addr", r.RemoteAddr).
+			Msg("Rejecting request: context headers lack a valid signature")
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return false
+	}
+
+	for _, name := range unsigned {
+		r.Header.Del(name)
+		metrics.RecordHeaderBlocked(name, audit.ReasonUnsigned)
+		h.auditHeader(r, audit.ActionStripped, name, set.index[name], audit.ReasonUnsigned)
+	}
+	logger.Warn().
+		Strs("headers", unsigned).
+		Str("remote_addr", r.RemoteAddr).
+		Msg("Stripped context headers lacking a valid signature")
+	return true
+}
@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bgruszka/contextforge/internal/audit"
+	"github.com/bgruszka/contextforge/internal/config"
+	"github.com/bgruszka/contextforge/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testSigningKey = []byte(strings.Repeat("k", 32))
+	testRotatedKey = []byte(strings.Repeat("r", 32))
+)
+
+func TestContextSigner_Verified(t *testing.T) {
+	signer := &contextSigner{keys: [][]byte{testSigningKey}, header: config.DefaultContextSignatureHeader}
+	signed := http.Header{}
+	signed.Set("X-Request-Id", "abc123")
+	signed.Set("X-Tenant-Id", "acme")
+	signed.Set("X-Other", "unsigned")
+	signed.Set(signer.header, signer.sign(signed, []string{"X-Request-Id", "X-Tenant-Id"}))
+
+	tests := []struct {
+		name   string
+		modify func(h http.Header)
+		keys   [][]byte
+		want   map[string]bool
+	}{
+		{
+			name: "valid",
+			keys: [][]byte{testSigningKey},
+			want: map[string]bool{"X-Request-Id": true, "X-Tenant-Id": true},
+		},
+		{
+			name: "signed with a key being rotated out",
+			keys: [][]byte{testRotatedKey, testSigningKey},
+			want: map[string]bool{"X-Request-Id": true, "X-Tenant-Id": true},
+		},
+		{
+			name:   "tampered value",
+			modify: func(h http.Header) { h.Set("X-Tenant-Id", "globex") },
+			keys:   [][]byte{testSigningKey},
+		},
+		{
+			name: "header list widened",
+			modify: func(h http.Header) {
+				h.Set(signer.header, strings.Replace(h.Get(signer.header), "h=", "h=x-other,", 1))
+			},
+			keys: [][]byte{testSigningKey},
+		},
+		{
+			name: "unknown key",
+			keys: [][]byte{testRotatedKey},
+		},
+		{
+			name:   "malformed",
+			modify: func(h http.Header) { h.Set(signer.header, "h=x-tenant-id;v1=!!") },
+			keys:   [][]byte{testSigningKey},
+		},
+		{
+			name:   "missing",
+			modify: func(h http.Header) { h.Del(signer.header) },
+			keys:   [][]byte{testSigningKey},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := signed.Clone()
+			if tt.modify != nil {
+				tt.modify(header)
+			}
+			verifier := &contextSigner{keys: tt.keys, header: signer.header}
+			assert.Equal(t, tt.want, verifier.verified(header))
+		})
+	}
+}
+
+// signingConfig returns a proxy configuration forwarding to target with
+// context signing enabled.
+func signingConfig(target, unsignedContext string) *config.ProxyConfig {
+	cfg := testConfig(target, []string{"x-request-id", "x-tenant-id"})
+	cfg.ContextSigningKeys = [][]byte{testSigningKey}
+	cfg.ContextSignatureHeader = config.DefaultContextSignatureHeader
+	cfg.UnsignedContext = unsignedContext
+	return cfg
+}
+
+func TestProxyHandler_SignedContext(t *testing.T) {
+	var received http.Header
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	// The downstream sidecar only admits context signed by a proxy
+	downstream, err := NewProxyHandler(signingConfig(backend.Listener.Addr().String(), config.UnsignedContextReject))
+	require.NoError(t, err)
+	downstreamServer := httptest.NewServer(downstream)
+	defer downstreamServer.Close()
+
+	upstream, err := NewProxyHandler(signingConfig(downstreamServer.Listener.Addr().String(), config.UnsignedContextAllow))
+	require.NoError(t, err)
+
+	t.Run("signed by the upstream proxy", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api", nil)
+		req.Header.Set("X-Request-Id", "abc123")
+		req.Header.Set("X-Tenant-Id", "acme")
+		// A caller's own signature is never passed on
+		req.Header.Set(config.DefaultContextSignatureHeader, "h=x-tenant-id;v1=forged")
+		rr := httptest.NewRecorder()
+
+		upstream.ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "acme", received.Get("X-Tenant-Id"))
+		assert.Equal(t, "abc123", received.Get("X-Request-Id"))
+	})
+
+	t.Run("unsigned", func(t *testing.T) {
+		before := testutil.ToFloat64(metrics.RequestsRejectedTotal.WithLabelValues(metrics.RejectUnsignedContext))
+		received = nil
+		req := httptest.NewRequest(http.MethodGet, "/api", nil)
+		req.Header.Set("X-Tenant-Id", "acme")
+		rr := httptest.NewRecorder()
+
+		downstream.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Nil(t, received, "the request never reaches the app")
+		assert.Equal(t, before+1, testutil.ToFloat64(metrics.RequestsRejectedTotal.WithLabelValues(metrics.RejectUnsignedContext)))
+	})
+
+	t.Run("without context headers", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		downstream.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api", nil))
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+}
+
+func TestProxyHandler_StripsUnsignedContext(t *testing.T) {
+	var received http.Header
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	cfg := signingConfig(backend.Listener.Addr().String(), config.UnsignedContextStrip)
+	signer := newContextSigner(cfg)
+	handler, err := NewProxyHandler(cfg)
+	require.NoError(t, err)
+	before := testutil.ToFloat64(metrics.HeaderBlockedTotal.WithLabelValues("X-Tenant-Id", audit.ReasonUnsigned))
+
+	// Only the request ID is covered by the signature
+	req := httptest.NewRequest(http.MethodGet, "/api", nil)
+	req.Header.Set("X-Request-Id", "abc123")
+	req.Header.Set(signer.header, signer.sign(req.Header, []string{"X-Request-Id"}))
+	req.Header.Set("X-Tenant-Id", "acme")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "abc123", received.Get("X-Request-Id"))
+	assert.Empty(t, received.Get("X-Tenant-Id"))
+	assert.Equal(t, before+1, testutil.ToFloat64(metrics.HeaderBlockedTotal.WithLabelValues("X-Tenant-Id", audit.ReasonUnsigned)))
+	assert.NotEmpty(t, received.Get(signer.header), "the app's own outbound calls are signed again")
+}
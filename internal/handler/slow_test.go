@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// captureLogs redirects the global logger into a buffer for the duration of the test.
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	original := log.Logger
+	log.Logger = zerolog.New(&buf).Level(zerolog.InfoLevel)
+	t.Cleanup(func() { log.Logger = original })
+	return &buf
+}
+
+func TestProxyHandler_LogsSlowRequests(t *testing.T) {
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer targetServer.Close()
+
+	cfg := testConfig(targetServer.Listener.Addr().String(), []string{"x-request-id", "authorization"})
+	cfg.SlowRequestThreshold = 10 * time.Millisecond
+	// Credentials are only propagated with the override, and still redacted
+	cfg.UnsafePropagateCredentials = true
+	handler, err := NewProxyHandler(cfg)
+	require.NoError(t, err)
+
+	logs := captureLogs(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/slow", nil)
+	req.Header.Set("X-Request-Id", "req-1")
+	req.Header.Set("Authorization", "Bearer secret")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	var entry map[string]interface{}
+	for _, line := range bytes.Split(bytes.TrimSpace(logs.Bytes()), []byte("\n")) {
+		var e map[string]interface{}
+		require.NoError(t, json.Unmarshal(line, &e))
+		if e["message"] == "Slow request" {
+			entry = e
+		}
+	}
+	require.NotNil(t, entry, "slow request should be logged")
+
+	assert.Equal(t, "warn", entry["level"])
+	assert.Equal(t, "POST", entry["method"])
+	assert.Equal(t, "/slow", entry["path"])
+	assert.Equal(t, float64(http.StatusAccepted), entry["status"])
+	assert.NotZero(t, entry["upstream_duration"])
+
+	headers, ok := entry["propagated_headers"].(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, "req-1", headers["X-Request-Id"])
+	assert.Equal(t, redactedValue, headers["Authorization"])
+	assert.NotContains(t, logs.String(), "Bearer secret")
+}
+
+func TestProxyHandler_DoesNotLogFastRequests(t *testing.T) {
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer targetServer.Close()
+
+	cfg := testConfig(targetServer.Listener.Addr().String(), []string{"x-request-id"})
+	cfg.SlowRequestThreshold = time.Minute
+	handler, err := NewProxyHandler(cfg)
+	require.NoError(t, err)
+
+	logs := captureLogs(t)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fast", nil))
+
+	assert.NotContains(t, logs.String(), "Slow request")
+}
+
+func TestRedactHeaders(t *testing.T) {
+	headers := map[string]string{
+		"X-Request-Id":  "abc",
+		"Authorization": "Bearer token",
+		"cookie":        "session=1",
+	}
+
+	redacted := newRedactor(nil).headers(headers)
+
+	assert.Equal(t, "abc", redacted["X-Request-Id"])
+	assert.Equal(t, redactedValue, redacted["Authorization"])
+	assert.Equal(t, redactedValue, redacted["cookie"])
+	assert.Equal(t, "Bearer token", headers["Authorization"], "input must not be modified")
+
+	redacted = newRedactor([]string{"x-tenant-token"}).headers(map[string]string{"X-Tenant-Token": "t0k3n", "Authorization": "Bearer token"})
+	assert.Equal(t, redactedValue, redacted["X-Tenant-Token"])
+	assert.Equal(t, redactedValue, redacted["Authorization"], "configured names add to the defaults")
+	assert.Empty(t, newRedactor(nil).value("Authorization", ""), "missing values stay empty")
+
+	strict := newRedactor([]string{"x-tenant-id"}).redactAll("x-request-id", "X-Tenant-Id", "")
+	redacted = strict.headers(map[string]string{"X-Request-Id": "abc", "X-Tenant-Id": "acme", "X-User-Id": "42"})
+	assert.Equal(t, "abc", redacted["X-Request-Id"], "correlation headers are kept")
+	assert.Equal(t, redactedValue, redacted["X-Tenant-Id"], "configured names stay redacted")
+	assert.Equal(t, redactedValue, redacted["X-User-Id"])
+	assert.Equal(t, "42", newRedactor(nil).value("X-User-Id", "42"), "redactAll returns a copy")
+}
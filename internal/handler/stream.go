@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"bufio"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bgruszka/contextforge/internal/metrics"
+)
+
+// Stream types used as the "type" label of the stream metrics.
+const (
+	StreamTypeWebSocket = "websocket"
+	StreamTypeUpgrade   = "upgrade"
+	StreamTypeSSE       = "sse"
+)
+
+// streamTrackingWriter wraps a ResponseWriter to detect responses that turn into
+// long-lived connections: protocol upgrades (which hijack the connection) and
+// server-sent event streams. Tracked streams are reported through the stream
+// metrics until finish is called.
+type streamTrackingWriter struct {
+	http.ResponseWriter
+	upgradeType string
+	streamType  string
+	started     time.Time
+	hijacked    bool
+}
+
+// newStreamTrackingWriter creates a streamTrackingWriter for the given request.
+func newStreamTrackingWriter(w http.ResponseWriter, r *http.Request) *streamTrackingWriter {
+	return &streamTrackingWriter{
+		ResponseWriter: w,
+		upgradeType:    requestUpgradeType(r.Header),
+	}
+}
+
+// WriteHeader starts tracking the response as a stream if it is an event stream.
+func (s *streamTrackingWriter) WriteHeader(code int) {
+	if code == http.StatusOK && isEventStream(s.Header()) {
+		s.begin(StreamTypeSSE)
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+// Hijack takes over the underlying connection for a protocol upgrade and starts
+// tracking it as a stream.
+func (s *streamTrackingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := http.NewResponseController(s.ResponseWriter).Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	s.hijacked = true
+	if strings.EqualFold(s.upgradeType, "websocket") {
+		s.begin(StreamTypeWebSocket)
+	} else {
+		s.begin(StreamTypeUpgrade)
+	}
+	return &countingConn{Conn: conn}, brw, nil
+}
+
+// Unwrap returns the underlying http.ResponseWriter for http.ResponseController.
+func (s *streamTrackingWriter) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// begin marks the start of a stream of the given type.
+func (s *streamTrackingWriter) begin(streamType string) {
+	if s.streamType != "" {
+		return
+	}
+	s.streamType = streamType
+	s.started = time.Now()
+	metrics.RecordStreamStarted(streamType)
+}
+
+// finish records the end of the stream, if one was started.
+func (s *streamTrackingWriter) finish() {
+	if s.streamType == "" {
+		return
+	}
+	metrics.RecordStreamFinished(s.streamType, time.Since(s.started))
+	s.streamType = ""
+}
+
+// requestUpgradeType returns the protocol requested in the Upgrade header,
+// or an empty string if the request does not ask for a protocol upgrade.
+func requestUpgradeType(h http.Header) string {
+	for _, v := range h.Values("Connection") {
+		for _, token := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return h.Get("Upgrade")
+			}
+		}
+	}
+	return ""
+}
+
+// isEventStream reports whether the response is a server-sent event stream.
+func isEventStream(h http.Header) bool {
+	mediaType, _, err := mime.ParseMediaType(h.Get("Content-Type"))
+	return err == nil && mediaType == "text/event-stream"
+}
@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bgruszka/contextforge/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestUpgradeType(t *testing.T) {
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		expected string
+	}{
+		{
+			name:     "websocket upgrade",
+			headers:  map[string]string{"Connection": "Upgrade", "Upgrade": "websocket"},
+			expected: "websocket",
+		},
+		{
+			name:     "connection with multiple tokens",
+			headers:  map[string]string{"Connection": "keep-alive, upgrade", "Upgrade": "h2c"},
+			expected: "h2c",
+		},
+		{
+			name:     "upgrade header without connection token",
+			headers:  map[string]string{"Upgrade": "websocket"},
+			expected: "",
+		},
+		{
+			name:     "plain request",
+			headers:  map[string]string{},
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			for k, v := range tt.headers {
+				h.Set(k, v)
+			}
+			assert.Equal(t, tt.expected, requestUpgradeType(h))
+		})
+	}
+}
+
+func TestProxyHandler_TracksServerSentEvents(t *testing.T) {
+	release := make(chan struct{})
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_, _ = fmt.Fprint(w, "data: hello\n\n")
+		_ = http.NewResponseController(w).Flush()
+		<-release
+	}))
+	defer targetServer.Close()
+
+	handler, err := NewProxyHandler(testConfig(targetServer.Listener.Addr().String(), []string{"x-request-id"}))
+	require.NoError(t, err)
+	proxyServer := httptest.NewServer(handler)
+	defer proxyServer.Close()
+
+	gauge := metrics.ActiveStreams.WithLabelValues(StreamTypeSSE)
+	before := testutil.ToFloat64(gauge)
+
+	resp, err := http.Get(proxyServer.URL + "/events")
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "data: hello\n", line)
+	assert.Equal(t, before+1, testutil.ToFloat64(gauge))
+
+	close(release)
+
+	assert.Eventually(t, func() bool {
+		return testutil.ToFloat64(gauge) == before
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestProxyHandler_TracksWebSocketUpgrade(t *testing.T) {
+	// Backend that accepts the upgrade and echoes lines until the client disconnects.
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, brw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		_, _ = brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n")
+		_ = brw.Flush()
+		for {
+			line, err := brw.ReadString('\n')
+			if err != nil {
+				return
+			}
+			_, _ = brw.WriteString(line)
+			_ = brw.Flush()
+		}
+	}))
+	defer targetServer.Close()
+
+	handler, err := NewProxyHandler(testConfig(targetServer.Listener.Addr().String(), []string{"x-request-id"}))
+	require.NoError(t, err)
+	proxyServer := httptest.NewServer(handler)
+	defer proxyServer.Close()
+
+	gauge := metrics.ActiveStreams.WithLabelValues(StreamTypeWebSocket)
+	before := testutil.ToFloat64(gauge)
+
+	conn, err := net.Dial("tcp", proxyServer.Listener.Addr().String())
+	require.NoError(t, err)
+
+	_, err = fmt.Fprint(conn, "GET /ws HTTP/1.1\r\nHost: example\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n")
+	require.NoError(t, err)
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+
+	_, err = fmt.Fprint(conn, "ping\n")
+	require.NoError(t, err)
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "ping\n", line)
+
+	assert.Equal(t, before+1, testutil.ToFloat64(gauge))
+
+	_ = conn.Close()
+
+	assert.Eventually(t, func() bool {
+		return testutil.ToFloat64(gauge) == before
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestProxyHandler_StreamClientDisconnect(t *testing.T) {
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		for {
+			_, _ = fmt.Fprint(w, "data: tick\n\n")
+			_ = http.NewResponseController(w).Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}))
+	defer targetServer.Close()
+
+	handler, err := NewProxyHandler(testConfig(targetServer.Listener.Addr().String(), []string{"x-request-id"}))
+	require.NoError(t, err)
+	proxyServer := httptest.NewServer(handler)
+	defer proxyServer.Close()
+
+	gauge := metrics.ActiveStreams.WithLabelValues(StreamTypeSSE)
+	before := testutil.ToFloat64(gauge)
+	requests := testutil.ToFloat64(metrics.RequestsTotal.WithLabelValues(http.MethodGet, "200"))
+
+	resp, err := http.Get(proxyServer.URL + "/events")
+	require.NoError(t, err)
+	_, err = bufio.NewReader(resp.Body).ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, before+1, testutil.ToFloat64(gauge))
+
+	// The reverse proxy aborts the handler once the client is gone
+	_ = resp.Body.Close()
+
+	assert.Eventually(t, func() bool {
+		return testutil.ToFloat64(gauge) == before
+	}, 5*time.Second, 10*time.Millisecond, "the stream is no longer counted")
+	assert.Eventually(t, func() bool {
+		return testutil.ToFloat64(metrics.RequestsTotal.WithLabelValues(http.MethodGet, "200")) == requests+1
+	}, 5*time.Second, 10*time.Millisecond, "the aborted request is still recorded")
+}
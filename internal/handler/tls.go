@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/bgruszka/contextforge/internal/config"
+	"github.com/bgruszka/contextforge/internal/spiffe"
+)
+
+// targetTransport returns the transport used to reach the target application.
+// With TLS to the target it trusts the system roots plus TargetCAFile, or
+// with SPIFFEMTLS authenticates both ends with the identity's SVIDs.
+func targetTransport(cfg *config.ProxyConfig, identity *spiffe.Source) (http.RoundTripper, error) {
+	if !cfg.TargetTLS {
+		return http.DefaultTransport, nil
+	}
+	if cfg.SPIFFEMTLS {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = identity.ClientTLSConfig()
+		return transport, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: cfg.TargetServerName,
+	}
+	if cfg.TargetCAFile != "" {
+		pem, err := os.ReadFile(cfg.TargetCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read target CA file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in target CA file %q", cfg.TargetCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
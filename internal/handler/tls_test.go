@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyHandler_TargetTLS(t *testing.T) {
+	targetServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "abc123", r.Header.Get("X-Request-Id"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer targetServer.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: targetServer.Certificate().Raw})
+	require.NoError(t, os.WriteFile(caFile, certPEM, 0o600))
+
+	cfg := testConfig(targetServer.Listener.Addr().String(), []string{"x-request-id"})
+	cfg.TargetTLS = true
+
+	serve := func(t *testing.T) int {
+		handler, err := NewProxyHandler(cfg)
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.Header.Set("X-Request-Id", "abc123")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	// The test certificate isn't signed by a system root
+	assert.Equal(t, http.StatusBadGateway, serve(t))
+
+	cfg.TargetCAFile = caFile
+	assert.Equal(t, http.StatusOK, serve(t))
+}
+
+func TestNewProxyHandler_InvalidTargetCA(t *testing.T) {
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	require.NoError(t, os.WriteFile(caFile, []byte("not a certificate"), 0o600))
+
+	cfg := testConfig("localhost:8080", []string{"x-request-id"})
+	cfg.TargetTLS = true
+	cfg.TargetCAFile = caFile
+	_, err := NewProxyHandler(cfg)
+	assert.ErrorContains(t, err, "no certificates found")
+
+	cfg.TargetCAFile = filepath.Join(t.TempDir(), "missing.crt")
+	_, err = NewProxyHandler(cfg)
+	assert.ErrorContains(t, err, "failed to read target CA file")
+}
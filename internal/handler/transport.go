@@ -2,15 +2,41 @@ package handler
 
 import (
 	"net/http"
+	"sync/atomic"
+	"time"
 
-	"github.com/rs/zerolog/log"
+	"github.com/bgruszka/contextforge/internal/audit"
+	"github.com/bgruszka/contextforge/internal/config"
+	"github.com/bgruszka/contextforge/internal/metrics"
 )
 
+// workloadIdentity supplies the proxy's SPIFFE ID, or "" while it is unknown;
+// implemented by spiffe.Source.
+type workloadIdentity interface {
+	ID() string
+}
+
 // HeaderPropagatingTransport wraps an http.RoundTripper to inject propagated headers
 // from the request context into outbound HTTP requests.
 type HeaderPropagatingTransport struct {
 	headers       []string
 	baseTransport http.RoundTripper
+	audit         *audit.Logger
+	ruleIndex     map[string]int
+	// rules, when set, supersedes ruleIndex with the handler's current rules
+	rules *atomic.Pointer[ruleSet]
+	// allowCredentials lets config.CredentialHeaders through
+	allowCredentials bool
+	// cluster, when set, restricts propagation to in-cluster hosts
+	cluster *config.ClusterHosts
+	redact  redactor
+	// identity, when set, supplies the SPIFFE ID sent as identityHeader
+	identity       workloadIdentity
+	identityHeader string
+	// signer, when set, signs the propagated headers sent to in-cluster hosts
+	signer *contextSigner
+
+	requestIDHeader string
 }
 
 // NewHeaderPropagatingTransport creates a new HeaderPropagatingTransport.
@@ -21,6 +47,9 @@ func NewHeaderPropagatingTransport(headers []string, base http.RoundTripper) *He
 	return &HeaderPropagatingTransport{
 		headers:       headers,
 		baseTransport: base,
+		redact:        newRedactor(nil),
+
+		requestIDHeader: config.DefaultRequestIDHeader,
 	}
 }
 
@@ -28,19 +57,103 @@ func NewHeaderPropagatingTransport(headers []string, base http.RoundTripper) *He
 // It retrieves headers from the request context and injects them into the outbound request.
 func (t *HeaderPropagatingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	headerMap := GetHeadersFromContext(req.Context())
+	destinations := getDestinationsFromContext(req.Context())
+	// Match the destination the request was addressed to; the URL only
+	// names the local target it is forwarded to
+	host := destinationHost(req)
+	external := t.cluster != nil && !t.cluster.Contains(host)
 
 	for name, value := range headerMap {
+		// Credentials are never added to outbound requests, whatever the rules
+		if !t.allowCredentials && config.IsCredentialHeader(name) {
+			t.block(req, name, audit.ReasonCredential)
+			continue
+		}
+		// Withhold the header, including the copy forwarded from the
+		// incoming request, from destinations its rule doesn't allow, and
+		// from external hosts its rule doesn't name
+		d := destinations[name]
+		if d != nil && !d.Allows(host) {
+			t.block(req, name, audit.ReasonDestination)
+			continue
+		}
+		if external && (d == nil || !d.AllowsExplicitly(host)) {
+			t.block(req, name, audit.ReasonExternalHost)
+			continue
+		}
 		if req.Header.Get(name) == "" {
 			req.Header.Set(name, value)
-			if log.Debug().Enabled() {
-				log.Debug().
+			t.auditHeader(req, audit.ActionAdded, name, "")
+			if logger := loggerFromContext(req.Context()); logger.Debug().Enabled() {
+				logger.Debug().
 					Str("header", name).
-					Str("value", value).
+					Str("value", t.redact.value(name, value)).
 					Str("url", req.URL.String()).
 					Msg("Injecting header into outbound request")
 			}
 		}
 	}
 
-	return t.baseTransport.RoundTrip(req)
+	// Only the proxy sets the identity header: a copy sent by the caller or
+	// propagated by a rule would let anyone claim any identity
+	if t.identityHeader != "" {
+		req.Header.Del(t.identityHeader)
+		if id := t.identity.ID(); id != "" && !external {
+			req.Header.Set(t.identityHeader, id)
+		}
+	}
+
+	if t.signer != nil {
+		t.signContext(req, headerMap, external)
+	}
+
+	start := time.Now()
+	resp, err := t.baseTransport.RoundTrip(req)
+	if timing := getUpstreamTimingFromContext(req.Context()); timing != nil {
+		timing.duration = time.Since(start)
+	}
+	return resp, err
+}
+
+// block keeps a propagated header off the outbound request, removing the
+// copy forwarded from the incoming request if any, and reports it.
+func (t *HeaderPropagatingTransport) block(req *http.Request, name, reason string) {
+	action := audit.ActionBlocked
+	if req.Header.Get(name) != "" {
+		req.Header.Del(name)
+		action = audit.ActionStripped
+	}
+	metrics.RecordHeaderBlocked(name, reason)
+	t.auditHeader(req, action, name, reason)
+	if logger := loggerFromContext(req.Context()); logger.Debug().Enabled() {
+		logger.Debug().
+			Str("header", name).
+			Str("reason", reason).
+			Str("url", req.URL.String()).
+			Msg("Not propagating header to outbound request")
+	}
+}
+
+// auditHeader records a mutation of the outbound request's headers.
+func (t *HeaderPropagatingTransport) auditHeader(req *http.Request, action audit.Action, name, reason string) {
+	if t.audit == nil {
+		return
+	}
+	index := t.ruleIndex
+	if t.rules != nil {
+		index = t.rules.Load().index
+	}
+	rule, ok := index[name]
+	if !ok {
+		rule = audit.NoRule
+	}
+	t.audit.Record(audit.Event{
+		RequestID: t.redact.value(t.requestIDHeader, req.Header.Get(t.requestIDHeader)),
+		Action:    action,
+		Header:    name,
+		Rule:      rule,
+		Reason:    reason,
+		Method:    req.Method,
+		Path:      req.URL.Path,
+	})
 }
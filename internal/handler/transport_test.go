@@ -6,6 +6,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/bgruszka/contextforge/internal/config"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -98,6 +99,39 @@ func TestHeaderPropagatingTransport_RoundTrip_DoesNotOverwriteExisting(t *testin
 	assert.Equal(t, http.StatusOK, resp.StatusCode)
 }
 
+func TestHeaderPropagatingTransport_RoundTrip_NeverAddsCredentials(t *testing.T) {
+	headerMap := map[string]string{
+		"X-Request-Id":  "abc123",
+		"Authorization": "Bearer secret",
+		"Cookie":        "session=secret",
+		"X-Api-Key":     "secret",
+	}
+	ctx := context.WithValue(context.Background(), ContextKeyHeaders, headerMap)
+
+	var forwarded http.Header
+	mockTransport := &mockRoundTripper{
+		fn: func(r *http.Request) (*http.Response, error) {
+			forwarded = r.Header.Clone()
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+		},
+	}
+	transport := NewHeaderPropagatingTransport(nil, mockTransport)
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/test", nil).WithContext(ctx)
+	_, err := transport.RoundTrip(req)
+	require.NoError(t, err)
+	assert.Equal(t, "abc123", forwarded.Get("X-Request-Id"))
+	assert.Empty(t, forwarded.Get("Authorization"))
+	assert.Empty(t, forwarded.Get("Cookie"))
+	assert.Empty(t, forwarded.Get("X-Api-Key"))
+
+	transport.allowCredentials = true
+	req = httptest.NewRequest(http.MethodGet, "http://example.com/test", nil).WithContext(ctx)
+	_, err = transport.RoundTrip(req)
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer secret", forwarded.Get("Authorization"), "the unsafe override lets them through")
+}
+
 func TestHeaderPropagatingTransport_RoundTrip_NoHeadersInContext(t *testing.T) {
 	mockTransport := &mockRoundTripper{
 		fn: func(r *http.Request) (*http.Response, error) {
@@ -184,3 +218,93 @@ func TestHeaderPropagatingTransport_RoundTrip_MultipleHeaders(t *testing.T) {
 		assert.Equal(t, expectedValue, injectedHeaders[key], "Header %s mismatch", key)
 	}
 }
+
+func TestHeaderPropagatingTransport_RoundTrip_InClusterOnly(t *testing.T) {
+	headerMap := map[string]string{
+		"X-Request-Id": "abc123",
+		"X-Tenant-Id":  "acme",
+	}
+	destinations := map[string]*config.Destinations{
+		"X-Tenant-Id": {Allow: []string{"api.partner.com"}},
+	}
+	ctx := context.WithValue(context.Background(), ContextKeyHeaders, headerMap)
+	ctx = context.WithValue(ctx, contextKeyDestinations, destinations)
+
+	var received http.Header
+	transport := NewHeaderPropagatingTransport(nil, &mockRoundTripper{
+		fn: func(r *http.Request) (*http.Response, error) {
+			received = r.Header.Clone()
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+		},
+	})
+	cluster, err := config.NewClusterHosts(config.DefaultClusterDomains, config.DefaultClusterCIDRs)
+	require.NoError(t, err)
+	transport.cluster = cluster
+
+	tests := []struct {
+		url           string
+		wantRequestID string
+		wantTenant    string
+	}{
+		{"http://orders.prod.svc.cluster.local/test", "abc123", ""},
+		{"http://10.96.0.12:8080/test", "abc123", ""},
+		{"http://api.stripe.com/test", "", ""},
+		{"http://api.partner.com/test", "", "acme"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+		// Forwarded from the incoming request
+		req.Header.Set("X-Request-Id", "abc123")
+		_, err := transport.RoundTrip(req.WithContext(ctx))
+
+		require.NoError(t, err)
+		assert.Equal(t, tt.wantRequestID, received.Get("X-Request-Id"), tt.url)
+		assert.Equal(t, tt.wantTenant, received.Get("X-Tenant-Id"), tt.url)
+	}
+}
+
+// staticIdentity is a workloadIdentity with a fixed SPIFFE ID.
+type staticIdentity string
+
+func (id staticIdentity) ID() string { return string(id) }
+
+func TestHeaderPropagatingTransport_RoundTrip_SPIFFEID(t *testing.T) {
+	headerMap := map[string]string{"X-Spiffe-Id": "spiffe://example.org/forged"}
+	ctx := context.WithValue(context.Background(), ContextKeyHeaders, headerMap)
+
+	var received http.Header
+	transport := NewHeaderPropagatingTransport(nil, &mockRoundTripper{
+		fn: func(r *http.Request) (*http.Response, error) {
+			received = r.Header.Clone()
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+		},
+	})
+	cluster, err := config.NewClusterHosts(config.DefaultClusterDomains, config.DefaultClusterCIDRs)
+	require.NoError(t, err)
+	transport.cluster = cluster
+	transport.identityHeader = config.DefaultSPIFFEIDHeader
+
+	tests := []struct {
+		name     string
+		identity staticIdentity
+		url      string
+		expected string
+	}{
+		{name: "in-cluster host", identity: "spiffe://example.org/api", url: "http://orders.svc/test", expected: "spiffe://example.org/api"},
+		{name: "external host", identity: "spiffe://example.org/api", url: "http://api.stripe.com/test"},
+		{name: "identity not received yet", url: "http://orders.svc/test"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport.identity = tt.identity
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			// Sent by the caller, and propagated by a rule
+			req.Header.Set("X-Spiffe-Id", "spiffe://example.org/forged")
+
+			_, err := transport.RoundTrip(req.WithContext(ctx))
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, received.Get("X-Spiffe-Id"))
+		})
+	}
+}
@@ -0,0 +1,256 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package istio translates header propagation policies into Istio
+// EnvoyFilters, so that pods already running an istio-proxy get the policies'
+// generated, static and response headers from it instead of a second sidecar.
+//
+// Envoy handles each request on its own: it can't tell which of the
+// application's outbound requests belong to an inbound one, so propagating the
+// headers downstream is left to the application, as with Istio tracing.
+package istio
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+	"github.com/bgruszka/contextforge/internal/config"
+	"github.com/bgruszka/contextforge/internal/generator"
+	"github.com/bgruszka/contextforge/internal/policy"
+)
+
+// EnvoyFilterGVK is the Istio EnvoyFilter, read as unstructured so the
+// operator doesn't depend on an Istio release.
+var EnvoyFilterGVK = schema.GroupVersionKind{Group: "networking.istio.io", Version: "v1alpha3", Kind: "EnvoyFilter"}
+
+// metadataNamespace is the dynamic metadata namespace carrying echoed request
+// headers to the response.
+const metadataNamespace = "ctxforge"
+
+// Translatable returns why an EnvoyFilter can't implement the policy, or nil.
+// Envoy's workload selectors only match labels, Lua has no RE2 to scope rules
+// by path, and destinations, sampling, renames and transforms only apply to
+// propagated requests. Appending is left out too, so that a pod also running
+// the ctxforge-proxy sidecar doesn't get the value appended twice.
+func Translatable(spec ctxforgev1beta1.HeaderPropagationPolicySpec) error {
+	if spec.PodSelector != nil && len(spec.PodSelector.MatchExpressions) > 0 {
+		return fmt.Errorf("podSelector.matchExpressions can't select Istio workloads")
+	}
+	if spec.Destinations != nil || spec.Sampling != nil {
+		return fmt.Errorf("destinations and sampling need the ctxforge-proxy sidecar")
+	}
+	for _, rule := range spec.PropagationRules {
+		if rule.PathRegex != "" || len(rule.ExcludePaths) > 0 {
+			return fmt.Errorf("rules scoped by pathRegex or excludePaths need the ctxforge-proxy sidecar")
+		}
+		for _, header := range rule.Headers {
+			switch {
+			case header.Rename != "" || header.Transform != nil:
+				return fmt.Errorf("header %s: rename and transform need the ctxforge-proxy sidecar", header.Name)
+			case header.OnExisting == string(config.OnExistingAppend):
+				return fmt.Errorf("header %s: onExisting append needs the ctxforge-proxy sidecar", header.Name)
+			case header.Generate && header.GeneratorType == string(generator.TypeULID):
+				return fmt.Errorf("header %s: the ulid generator needs the ctxforge-proxy sidecar", header.Name)
+			}
+		}
+	}
+	for _, rule := range spec.ResponseRules {
+		if rule.PathRegex != "" {
+			return fmt.Errorf("response rules scoped by pathRegex need the ctxforge-proxy sidecar")
+		}
+	}
+	return nil
+}
+
+// EnvoyFilterName returns the name of the EnvoyFilter of a policy.
+func EnvoyFilterName(source policy.Source) string {
+	if name, ok := strings.CutPrefix(source.Name, policy.ClusterPrefix); ok {
+		return "ctxforge-cluster-" + name
+	}
+	return "ctxforge-" + source.Name
+}
+
+// EnvoyFilter returns the EnvoyFilter implementing the policy for the pods it
+// selects in the namespace, with a Lua filter on their inbound requests. The
+// filters of several policies selecting a pod run in order of precedence, so
+// the first to set a header the request lacks wins, as with the sidecar. The
+// policy must be Translatable.
+func EnvoyFilter(source policy.Source, namespace string) *unstructured.Unstructured {
+	filter := &unstructured.Unstructured{}
+	filter.SetGroupVersionKind(EnvoyFilterGVK)
+	filter.SetNamespace(namespace)
+	filter.SetName(EnvoyFilterName(source))
+
+	spec := map[string]any{
+		"configPatches": []any{map[string]any{
+			"applyTo": "HTTP_FILTER",
+			"match": map[string]any{
+				"context": "SIDECAR_INBOUND",
+				"listener": map[string]any{"filterChain": map[string]any{"filter": map[string]any{
+					"name":      "envoy.filters.network.http_connection_manager",
+					"subFilter": map[string]any{"name": "envoy.filters.http.router"},
+				}}},
+			},
+			"patch": map[string]any{
+				"operation": "INSERT_BEFORE",
+				"value": map[string]any{
+					"name": "ctxforge." + EnvoyFilterName(source),
+					"typed_config": map[string]any{
+						"@type":          "type.googleapis.com/envoy.extensions.filters.http.lua.v3.Lua",
+						"default_source": map[string]any{"inline_string": LuaCode(policy.Merge([]policy.Source{source}).Rules)},
+					},
+				},
+			},
+		}},
+	}
+	// Patches with a lower priority are applied first
+	if source.Spec.Priority != 0 {
+		spec["priority"] = int64(-source.Spec.Priority)
+	}
+	if selector := source.Spec.PodSelector; selector != nil && len(selector.MatchLabels) > 0 {
+		matchLabels := make(map[string]any, len(selector.MatchLabels))
+		for key, value := range selector.MatchLabels {
+			matchLabels[key] = value
+		}
+		spec["workloadSelector"] = map[string]any{"labels": matchLabels}
+	}
+	filter.Object["spec"] = spec
+	return filter
+}
+
+// luaPrelude seeds Lua's generator per Envoy worker, as every worker starts
+// from the same seed, and defines the value generators.
+const luaPrelude = `math.randomseed(os.time() + math.floor(os.clock() * 1000000) + tonumber(tostring({}):match("0x(%x+)") or "0", 16))
+
+local function uuid()
+  return (string.gsub("xxxxxxxx-xxxx-4xxx-yxxx-xxxxxxxxxxxx", "[xy]", function(c)
+    return string.format("%x", c == "x" and math.random(0, 15) or math.random(8, 11))
+  end))
+end
+
+local function timestamp()
+  return os.date("!%Y-%m-%dT%H:%M:%SZ")
+end
+`
+
+// LuaCode returns the Lua filter applying the rules of a Translatable policy
+// to inbound requests and their responses.
+func LuaCode(rules []policy.Rule) string {
+	var request, response strings.Builder
+	var echoed []string
+	for _, rule := range rules {
+		name := strings.ToLower(rule.Name)
+		switch rule.Response {
+		case config.ResponseEcho:
+			echoed = append(echoed, name)
+			continue
+		case config.ResponseStrip:
+			fmt.Fprintf(&response, "  headers:remove(%s)\n", luaString(name))
+			continue
+		}
+
+		var value string
+		switch {
+		case rule.StaticValue != "":
+			value = luaString(rule.StaticValue)
+		case rule.Generate && rule.GeneratorType == string(generator.TypeTimestamp):
+			value = "timestamp()"
+		case rule.Generate:
+			value = "uuid()"
+		default:
+			continue
+		}
+		indent := "  "
+		if len(rule.Methods) > 0 {
+			var methods []string
+			for _, method := range rule.Methods {
+				methods = append(methods, "method == "+luaString(strings.ToUpper(method)))
+			}
+			fmt.Fprintf(&request, "  if %s then\n", strings.Join(methods, " or "))
+			indent = "    "
+		}
+		if rule.OnExisting == config.OnExistingOverride {
+			fmt.Fprintf(&request, "%sheaders:replace(%s, %s)\n", indent, luaString(name), value)
+		} else {
+			fmt.Fprintf(&request, "%sif headers:get(%s) == nil then\n", indent, luaString(name))
+			fmt.Fprintf(&request, "%s  headers:add(%s, %s)\n", indent, luaString(name), value)
+			fmt.Fprintf(&request, "%send\n", indent)
+		}
+		if len(rule.Methods) > 0 {
+			request.WriteString("  end\n")
+		}
+	}
+
+	for _, name := range echoed {
+		fmt.Fprintf(&request, "  local %s = headers:get(%s)\n", luaVar(name), luaString(name))
+		fmt.Fprintf(&request, "  if %s ~= nil then\n", luaVar(name))
+		fmt.Fprintf(&request, "    request_handle:streamInfo():dynamicMetadata():set(%s, %s, %s)\n",
+			luaString(metadataNamespace), luaString(name), luaVar(name))
+		request.WriteString("  end\n")
+
+		fmt.Fprintf(&response, "  if echoed[%s] ~= nil then\n", luaString(name))
+		fmt.Fprintf(&response, "    headers:replace(%s, echoed[%s])\n", luaString(name), luaString(name))
+		response.WriteString("  end\n")
+	}
+
+	var code strings.Builder
+	code.WriteString(luaPrelude)
+	code.WriteString("\nfunction envoy_on_request(request_handle)\n")
+	code.WriteString("  local headers = request_handle:headers()\n")
+	code.WriteString("  local method = headers:get(\":method\")\n")
+	code.WriteString(request.String())
+	code.WriteString("end\n")
+	if response.Len() > 0 {
+		code.WriteString("\nfunction envoy_on_response(response_handle)\n")
+		code.WriteString("  local headers = response_handle:headers()\n")
+		if len(echoed) > 0 {
+			fmt.Fprintf(&code, "  local echoed = response_handle:streamInfo():dynamicMetadata():get(%s) or {}\n",
+				luaString(metadataNamespace))
+		}
+		code.WriteString(response.String())
+		code.WriteString("end\n")
+	}
+	return code.String()
+}
+
+// luaString quotes a value as a Lua string literal.
+func luaString(value string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c >= 0x7f:
+			fmt.Fprintf(&b, "\\%03d", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// luaVar returns the name of the local holding an echoed header's value.
+// Header names are letters, digits and dashes.
+func luaVar(header string) string {
+	return "echo_" + strings.ReplaceAll(header, "-", "_")
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package istio
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+	"github.com/bgruszka/contextforge/internal/config"
+	"github.com/bgruszka/contextforge/internal/policy"
+)
+
+func TestTranslatable(t *testing.T) {
+	headers := func(h ...ctxforgev1beta1.HeaderConfig) []ctxforgev1beta1.PropagationRule {
+		return []ctxforgev1beta1.PropagationRule{{Headers: h}}
+	}
+
+	tests := []struct {
+		name string
+		spec ctxforgev1beta1.HeaderPropagationPolicySpec
+		err  string
+	}{
+		{
+			name: "generated, static and response headers",
+			spec: ctxforgev1beta1.HeaderPropagationPolicySpec{
+				PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}},
+				PropagationRules: []ctxforgev1beta1.PropagationRule{{
+					Headers: []ctxforgev1beta1.HeaderConfig{
+						{Name: "x-request-id", Generate: true},
+						{Name: "x-tenant", StaticValue: "acme", OnExisting: "override"},
+					},
+					Methods: []string{"POST"},
+				}},
+				ResponseRules: []ctxforgev1beta1.ResponseRule{{Echo: []string{"x-request-id"}, Strip: []string{"server"}}},
+			},
+		},
+		{
+			name: "match expressions",
+			spec: ctxforgev1beta1.HeaderPropagationPolicySpec{
+				PodSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "app", Operator: metav1.LabelSelectorOpExists},
+				}},
+				PropagationRules: headers(ctxforgev1beta1.HeaderConfig{Name: "x-request-id"}),
+			},
+			err: "matchExpressions",
+		},
+		{
+			name: "sampling",
+			spec: ctxforgev1beta1.HeaderPropagationPolicySpec{
+				PropagationRules: headers(ctxforgev1beta1.HeaderConfig{Name: "baggage"}),
+				Sampling:         &ctxforgev1beta1.Sampling{Percentage: 10},
+			},
+			err: "sampling",
+		},
+		{
+			name: "path scoped rule",
+			spec: ctxforgev1beta1.HeaderPropagationPolicySpec{
+				PropagationRules: []ctxforgev1beta1.PropagationRule{{
+					Headers:   []ctxforgev1beta1.HeaderConfig{{Name: "x-request-id"}},
+					PathRegex: "^/api/",
+				}},
+			},
+			err: "pathRegex",
+		},
+		{
+			name: "rename",
+			spec: ctxforgev1beta1.HeaderPropagationPolicySpec{
+				PropagationRules: headers(ctxforgev1beta1.HeaderConfig{Name: "x-tenant", Rename: "x-tenant-id"}),
+			},
+			err: "rename",
+		},
+		{
+			name: "append",
+			spec: ctxforgev1beta1.HeaderPropagationPolicySpec{
+				PropagationRules: headers(ctxforgev1beta1.HeaderConfig{Name: "baggage", StaticValue: "env=prod", OnExisting: "append"}),
+			},
+			err: "append",
+		},
+		{
+			name: "ulid generator",
+			spec: ctxforgev1beta1.HeaderPropagationPolicySpec{
+				PropagationRules: headers(ctxforgev1beta1.HeaderConfig{Name: "x-trace", Generate: true, GeneratorType: "ulid"}),
+			},
+			err: "ulid",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Translatable(tt.spec)
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestEnvoyFilter(t *testing.T) {
+	source := policy.Source{
+		Name: policy.ClusterPrefix + "tracing",
+		Spec: ctxforgev1beta1.HeaderPropagationPolicySpec{
+			PodSelector:      &metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}},
+			Priority:         10,
+			PropagationRules: []ctxforgev1beta1.PropagationRule{{Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-request-id", Generate: true}}}},
+		},
+	}
+
+	filter := EnvoyFilter(source, "shop")
+	assert.Equal(t, "shop", filter.GetNamespace())
+	assert.Equal(t, "ctxforge-cluster-tracing", filter.GetName())
+
+	priority, _, _ := unstructured.NestedInt64(filter.Object, "spec", "priority")
+	assert.Equal(t, int64(-10), priority)
+	workloadLabels, _, _ := unstructured.NestedStringMap(filter.Object, "spec", "workloadSelector", "labels")
+	assert.Equal(t, map[string]string{"app": "api"}, workloadLabels)
+
+	patches, _, err := unstructured.NestedSlice(filter.Object, "spec", "configPatches")
+	require.NoError(t, err)
+	require.Len(t, patches, 1)
+	context, _, _ := unstructured.NestedString(patches[0].(map[string]any), "match", "context")
+	assert.Equal(t, "SIDECAR_INBOUND", context)
+	code, _, _ := unstructured.NestedString(patches[0].(map[string]any),
+		"patch", "value", "typed_config", "default_source", "inline_string")
+	assert.Contains(t, code, `headers:add("x-request-id", uuid())`)
+
+	// The object must survive the deep copies of the client and cache
+	assert.NotPanics(t, func() { filter.DeepCopy() })
+
+	source.Spec.PodSelector = nil
+	source.Spec.Priority = 0
+	filter = EnvoyFilter(source, "shop")
+	assert.NotContains(t, filter.Object["spec"], "workloadSelector")
+	assert.NotContains(t, filter.Object["spec"], "priority")
+}
+
+func TestLuaCode(t *testing.T) {
+	code := LuaCode([]policy.Rule{
+		{Name: "X-Request-Id", Generate: true},
+		{Name: "x-started-at", Generate: true, GeneratorType: "timestamp"},
+		{Name: "x-tenant", StaticValue: "acme \"corp\"\n", OnExisting: config.OnExistingOverride, Methods: []string{"post", "PUT"}},
+		{Name: "x-env", StaticValue: "prod", OnExisting: config.OnExistingPreserve},
+		{Name: "x-user-id"},
+		{Name: "x-request-id", Response: config.ResponseEcho},
+		{Name: "server", Response: config.ResponseStrip},
+	})
+
+	assert.Contains(t, code, "function envoy_on_request(request_handle)")
+	assert.Contains(t, code, "  if headers:get(\"x-request-id\") == nil then\n    headers:add(\"x-request-id\", uuid())\n  end\n")
+	assert.Contains(t, code, `headers:add("x-started-at", timestamp())`)
+	assert.Contains(t, code, "  if method == \"POST\" or method == \"PUT\" then\n"+
+		"    headers:replace(\"x-tenant\", \"acme \\\"corp\\\"\\010\")\n  end\n")
+	assert.Contains(t, code, `headers:add("x-env", "prod")`)
+	assert.NotContains(t, code, "x-user-id", "propagated headers are left to the application")
+
+	assert.Contains(t, code, `request_handle:streamInfo():dynamicMetadata():set("ctxforge", "x-request-id", echo_x_request_id)`)
+	assert.Contains(t, code, "function envoy_on_response(response_handle)")
+	assert.Contains(t, code, `headers:replace("x-request-id", echoed["x-request-id"])`)
+	assert.Contains(t, code, `headers:remove("server")`)
+
+	assert.NotContains(t, LuaCode([]policy.Rule{{Name: "x-request-id", Generate: true}}), "envoy_on_response")
+}
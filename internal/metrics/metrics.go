@@ -16,6 +16,33 @@ const (
 	subsystem = "proxy"
 )
 
+// Traffic directions used as the "direction" label of BytesTotal.
+// Inbound bytes are received from callers (request bodies and upgraded
+// connection reads); outbound bytes are sent back to callers (response
+// bodies and upgraded connection writes).
+const (
+	DirectionInbound  = "inbound"
+	DirectionOutbound = "outbound"
+)
+
+// Rejection classes used as the "class" label of RequestsRejectedTotal.
+// Most name a request smuggling or header injection technique the proxy
+// refuses with 400 rather than forwarding in a form the target could parse
+// differently; RejectUnsignedContext is a request refused with 403 because
+// no valid signature covers its context headers.
+const (
+	RejectAmbiguousFraming = "ambiguous_framing"
+	RejectObsFold          = "obs_fold"
+	RejectInvalidValue     = "invalid_value"
+	RejectUnsignedContext  = "unsigned_context"
+)
+
+// Limits used as the "limit" label of HeaderLimitRejectedTotal.
+const (
+	HeaderLimitCount = "count"
+	HeaderLimitBytes = "bytes"
+)
+
 var (
 	// RequestsTotal counts the total number of HTTP requests processed.
 	RequestsTotal = promauto.NewCounterVec(
@@ -50,6 +77,42 @@ var (
 		},
 	)
 
+	// HeaderPropagatedTotal counts the requests each header was propagated
+	// on. Header names come from the proxy's rules, which bounds cardinality.
+	HeaderPropagatedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "header_propagated_total",
+			Help:      "Total number of requests a header was propagated on, by header name.",
+		},
+		[]string{"header"},
+	)
+
+	// HeaderGeneratedTotal counts the values the proxy generated per header.
+	HeaderGeneratedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "header_generated_total",
+			Help:      "Total number of header values generated by the proxy, by header name.",
+		},
+		[]string{"header"},
+	)
+
+	// HeaderBlockedTotal counts the headers the proxy stripped from or refused
+	// to propagate on requests, by header name and reason. Header names come
+	// from the proxy's rules, which bounds cardinality.
+	HeaderBlockedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "header_blocked_total",
+			Help:      "Total number of times a header was stripped or not propagated by a proxy control, by header name and reason.",
+		},
+		[]string{"header", "reason"},
+	)
+
 	// ActiveConnections tracks the number of active connections.
 	ActiveConnections = promauto.NewGauge(
 		prometheus.GaugeOpts{
@@ -59,6 +122,134 @@ var (
 			Help:      "Number of active connections being processed.",
 		},
 	)
+
+	// UpstreamErrorsTotal counts errors forwarding requests to the target, by error class.
+	UpstreamErrorsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "upstream_errors_total",
+			Help:      "Total number of errors forwarding requests to the target application, by error class.",
+		},
+		[]string{"class"},
+	)
+
+	// HeaderLimitRejectedTotal counts requests rejected with 431, by exceeded limit.
+	HeaderLimitRejectedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "header_limit_rejected_total",
+			Help:      "Total number of requests rejected for exceeding the header count or size limit, by limit.",
+		},
+		[]string{"limit"},
+	)
+
+	// RequestsRejectedTotal counts malformed requests rejected with 400, and
+	// requests with unsigned context rejected with 403, by rejection class.
+	RequestsRejectedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "requests_rejected_total",
+			Help:      "Total number of requests rejected as possible request smuggling or header injection, or for unsigned context headers, by rejection class.",
+		},
+		[]string{"class"},
+	)
+
+	// RateLimitAllowedTotal counts requests admitted by the rate limiter.
+	RateLimitAllowedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "ratelimit_allowed_total",
+			Help:      "Total number of requests allowed by the rate limiter, by limiter key type.",
+		},
+		[]string{"key_type"},
+	)
+
+	// RateLimitRejectedTotal counts requests rejected by the rate limiter with HTTP 429.
+	RateLimitRejectedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "ratelimit_rejected_total",
+			Help:      "Total number of requests rejected by the rate limiter, by limiter key type.",
+		},
+		[]string{"key_type"},
+	)
+
+	// RateLimitTokens tracks the number of tokens left in the rate limiter bucket.
+	RateLimitTokens = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "ratelimit_tokens",
+			Help:      "Number of tokens currently available in the rate limiter bucket, by limiter key type.",
+		},
+		[]string{"key_type"},
+	)
+
+	// BytesTotal counts body bytes passing through the proxy, by direction.
+	BytesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "bytes_total",
+			Help:      "Total number of body bytes transferred through the proxy, by direction.",
+		},
+		[]string{"direction"},
+	)
+
+	// ActiveStreams tracks long-lived connections (protocol upgrades such as WebSocket,
+	// and server-sent event streams) separately from ActiveConnections.
+	ActiveStreams = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "active_streams",
+			Help:      "Number of active hijacked or streaming connections, by stream type.",
+		},
+		[]string{"type"},
+	)
+
+	// StreamDuration tracks how long hijacked or streaming connections stay open.
+	// Buckets range from one second to one hour to cover typical WebSocket lifetimes.
+	StreamDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "stream_duration_seconds",
+			Help:      "Age of hijacked or streaming connections when they close, in seconds.",
+			Buckets:   []float64{1, 5, 15, 30, 60, 300, 900, 1800, 3600},
+		},
+		[]string{"type"},
+	)
+
+	// PathRequestsTotal counts requests per normalized path. It is only recorded when
+	// per-path metrics are enabled.
+	PathRequestsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "path_requests_total",
+			Help:      "Total number of HTTP requests processed by the proxy, by normalized path.",
+		},
+		[]string{"method", "path", "status"},
+	)
+
+	// PathRequestDuration tracks request duration per normalized path. It is only
+	// recorded when per-path metrics are enabled.
+	PathRequestDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "path_request_duration_seconds",
+			Help:      "Duration of HTTP requests in seconds, by normalized path.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"method", "path"},
+	)
 )
 
 // RecordRequest records metrics for a completed HTTP request.
@@ -67,20 +258,86 @@ func RecordRequest(method string, statusCode int, duration time.Duration) {
 	RequestDuration.WithLabelValues(method).Observe(duration.Seconds())
 }
 
+// RecordPathRequest records per-path metrics for a completed HTTP request.
+// The path must already be normalized to keep label cardinality bounded.
+func RecordPathRequest(method, path string, statusCode int, duration time.Duration) {
+	PathRequestsTotal.WithLabelValues(method, path, strconv.Itoa(statusCode)).Inc()
+	PathRequestDuration.WithLabelValues(method, path).Observe(duration.Seconds())
+}
+
 // RecordHeadersPropagated increments the counter for propagated headers.
 func RecordHeadersPropagated(count int) {
 	HeadersPropagatedTotal.Add(float64(count))
 }
 
+// RecordHeaderPropagated increments the propagation counter of a header.
+func RecordHeaderPropagated(header string) {
+	HeaderPropagatedTotal.WithLabelValues(header).Inc()
+}
+
+// RecordHeaderGenerated increments the generation counter of a header.
+func RecordHeaderGenerated(header string) {
+	HeaderGeneratedTotal.WithLabelValues(header).Inc()
+}
+
+// RecordHeaderBlocked increments the blocked counter of a header for the given reason.
+func RecordHeaderBlocked(header, reason string) {
+	HeaderBlockedTotal.WithLabelValues(header, reason).Inc()
+}
+
+// RecordUpstreamError increments the upstream error counter for the given error class.
+func RecordUpstreamError(class string) {
+	UpstreamErrorsTotal.WithLabelValues(class).Inc()
+}
+
+// RecordRequestRejected increments the rejected request counter for the given rejection class.
+func RecordRequestRejected(class string) {
+	RequestsRejectedTotal.WithLabelValues(class).Inc()
+}
+
+// RecordHeaderLimitRejected increments the header limit rejection counter for the given limit.
+func RecordHeaderLimitRejected(limit string) {
+	HeaderLimitRejectedTotal.WithLabelValues(limit).Inc()
+}
+
+// RecordRateLimitDecision records a rate limiter decision and the tokens remaining afterwards.
+func RecordRateLimitDecision(keyType string, allowed bool, tokens float64) {
+	if allowed {
+		RateLimitAllowedTotal.WithLabelValues(keyType).Inc()
+	} else {
+		RateLimitRejectedTotal.WithLabelValues(keyType).Inc()
+	}
+	RateLimitTokens.WithLabelValues(keyType).Set(tokens)
+}
+
+// RecordBytes adds n bytes to the byte counter for the given direction.
+func RecordBytes(direction string, n int64) {
+	if n > 0 {
+		BytesTotal.WithLabelValues(direction).Add(float64(n))
+	}
+}
+
+// RecordStreamStarted increments the active stream gauge for the given stream type.
+func RecordStreamStarted(streamType string) {
+	ActiveStreams.WithLabelValues(streamType).Inc()
+}
+
+// RecordStreamFinished decrements the active stream gauge and records the stream's age.
+func RecordStreamFinished(streamType string, duration time.Duration) {
+	ActiveStreams.WithLabelValues(streamType).Dec()
+	StreamDuration.WithLabelValues(streamType).Observe(duration.Seconds())
+}
+
 // Handler returns the Prometheus HTTP handler for exposing metrics.
 func Handler() http.Handler {
 	return promhttp.Handler()
 }
 
-// ResponseWriter wraps http.ResponseWriter to capture the status code.
+// ResponseWriter wraps http.ResponseWriter to capture the status code and body size.
 type ResponseWriter struct {
 	http.ResponseWriter
-	StatusCode int
+	StatusCode   int
+	BytesWritten int64
 }
 
 // NewResponseWriter creates a new ResponseWriter wrapper.
@@ -96,3 +353,16 @@ func (rw *ResponseWriter) WriteHeader(code int) {
 	rw.StatusCode = code
 	rw.ResponseWriter.WriteHeader(code)
 }
+
+// Write counts the body bytes written to the client.
+func (rw *ResponseWriter) Write(b []byte) (int, error) {
+	n, err := rw.ResponseWriter.Write(b)
+	rw.BytesWritten += int64(n)
+	return n, err
+}
+
+// Unwrap returns the underlying http.ResponseWriter so that http.ResponseController
+// can reach its Flush and Hijack implementations.
+func (rw *ResponseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -22,6 +23,15 @@ func TestRecordHeadersPropagated(t *testing.T) {
 	RecordHeadersPropagated(1)
 }
 
+func TestRecordHeaderCounters(t *testing.T) {
+	RecordHeaderPropagated("X-Metrics-Test")
+	RecordHeaderPropagated("X-Metrics-Test")
+	RecordHeaderGenerated("X-Metrics-Test")
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(HeaderPropagatedTotal.WithLabelValues("X-Metrics-Test")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(HeaderGeneratedTotal.WithLabelValues("X-Metrics-Test")))
+}
+
 func TestHandler(t *testing.T) {
 	handler := Handler()
 	assert.NotNil(t, handler)
@@ -94,3 +104,28 @@ func TestResponseWriter_Write(t *testing.T) {
 	assert.Equal(t, 5, n)
 	assert.Equal(t, "hello", rr.Body.String())
 }
+
+func TestResponseWriter_Unwrap(t *testing.T) {
+	rr := httptest.NewRecorder()
+	rw := NewResponseWriter(rr)
+
+	assert.Equal(t, rr, rw.Unwrap())
+	assert.NoError(t, http.NewResponseController(rw).Flush())
+	assert.True(t, rr.Flushed)
+}
+
+func TestRecordStream(t *testing.T) {
+	// Just verify it doesn't panic
+	RecordStreamStarted("websocket")
+	RecordStreamFinished("websocket", 30*time.Second)
+}
+
+func TestResponseWriter_CountsBytes(t *testing.T) {
+	rr := httptest.NewRecorder()
+	rw := NewResponseWriter(rr)
+
+	_, _ = rw.Write([]byte("hello"))
+	_, _ = rw.Write([]byte(" world"))
+
+	assert.Equal(t, int64(11), rw.BytesWritten)
+}
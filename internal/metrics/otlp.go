@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	otelprom "go.opentelemetry.io/contrib/bridges/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// OTLPConfig configures pushing metrics to an OpenTelemetry collector.
+type OTLPConfig struct {
+	// Endpoint is the collector gRPC endpoint, either host:port or a URL
+	// such as http://otel-collector:4317.
+	Endpoint string
+
+	// Interval is how often metrics are pushed.
+	Interval time.Duration
+
+	// Insecure disables TLS when Endpoint is given as host:port.
+	Insecure bool
+}
+
+// StartOTLPExporter periodically pushes all metrics registered with the default
+// Prometheus registry to an OTLP collector. Metric names and labels are the same
+// as on the /metrics endpoint.
+//
+// The returned function flushes pending metrics and stops the exporter.
+func StartOTLPExporter(ctx context.Context, cfg OTLPConfig) (func(context.Context) error, error) {
+	opts := []otlpmetricgrpc.Option{}
+	if strings.Contains(cfg.Endpoint, "://") {
+		opts = append(opts, otlpmetricgrpc.WithEndpointURL(cfg.Endpoint))
+	} else {
+		opts = append(opts, otlpmetricgrpc.WithEndpoint(cfg.Endpoint))
+		if cfg.Insecure {
+			opts = append(opts, otlpmetricgrpc.WithInsecure())
+		}
+	}
+
+	exporter, err := otlpmetricgrpc.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP metrics exporter for %q: %w", cfg.Endpoint, err)
+	}
+
+	// Resource attributes can be extended with OTEL_RESOURCE_ATTRIBUTES and OTEL_SERVICE_NAME.
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", namespace+"-"+subsystem)),
+		resource.WithFromEnv(),
+		resource.WithHost(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP resource: %w", err)
+	}
+
+	reader := sdkmetric.NewPeriodicReader(exporter,
+		sdkmetric.WithInterval(cfg.Interval),
+		sdkmetric.WithProducer(otelprom.NewMetricProducer(otelprom.WithGatherer(prometheus.DefaultGatherer))),
+	)
+	provider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(reader),
+		sdkmetric.WithResource(res),
+	)
+
+	return provider.Shutdown, nil
+}
@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	collectormetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
+)
+
+// fakeCollector records the metric names it receives.
+type fakeCollector struct {
+	collectormetrics.UnimplementedMetricsServiceServer
+
+	mu    sync.Mutex
+	names map[string]bool
+}
+
+func (c *fakeCollector) Export(_ context.Context, req *collectormetrics.ExportMetricsServiceRequest) (*collectormetrics.ExportMetricsServiceResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, rm := range req.ResourceMetrics {
+		for _, sm := range rm.ScopeMetrics {
+			for _, m := range sm.Metrics {
+				c.names[m.Name] = true
+			}
+		}
+	}
+	return &collectormetrics.ExportMetricsServiceResponse{}, nil
+}
+
+func (c *fakeCollector) received(name string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.names[name]
+}
+
+func TestStartOTLPExporter(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	collector := &fakeCollector{names: map[string]bool{}}
+	grpcServer := grpc.NewServer()
+	collectormetrics.RegisterMetricsServiceServer(grpcServer, collector)
+	go func() { _ = grpcServer.Serve(listener) }()
+	defer grpcServer.Stop()
+
+	RecordRequest("GET", 200, 10*time.Millisecond)
+
+	shutdown, err := StartOTLPExporter(context.Background(), OTLPConfig{
+		Endpoint: listener.Addr().String(),
+		Interval: time.Hour,
+		Insecure: true,
+	})
+	require.NoError(t, err)
+
+	// Shutdown flushes the pending export
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, shutdown(ctx))
+
+	assert.True(t, collector.received("ctxforge_proxy_requests_total"))
+	assert.True(t, collector.received("ctxforge_proxy_request_duration_seconds"))
+}
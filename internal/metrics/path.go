@@ -0,0 +1,94 @@
+package metrics
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// PathOther is the label used in strict mode for paths that match no template.
+const PathOther = "other"
+
+// idSegmentRegex matches path segments that look like identifiers: numbers,
+// UUIDs, ULIDs and long hexadecimal strings.
+var idSegmentRegex = regexp.MustCompile(
+	`^(\d+|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|[0-9A-HJKMNP-TV-Z]{26}|[0-9a-fA-F]{16,})$`,
+)
+
+// PathNormalizer maps request paths to a bounded set of templates so paths can be
+// used as a metric or log dimension.
+//
+// Templates use ":name" to match any single segment and a trailing "*" to match
+// any remainder, e.g. "/api/users/:id" or "/static/*". Paths that match no
+// template have identifier-like segments collapsed to ":id", or are reported as
+// PathOther in strict mode.
+type PathNormalizer struct {
+	templates [][]string
+	strict    bool
+}
+
+// NewPathNormalizer creates a PathNormalizer from the given templates, which are
+// tried in order.
+func NewPathNormalizer(templates []string, strict bool) (*PathNormalizer, error) {
+	n := &PathNormalizer{strict: strict}
+	for _, t := range templates {
+		if !strings.HasPrefix(t, "/") {
+			return nil, fmt.Errorf("path template %q must start with /", t)
+		}
+		segments := splitPath(t)
+		for i, seg := range segments {
+			if seg == "*" && i != len(segments)-1 {
+				return nil, fmt.Errorf("path template %q: * is only allowed as the last segment", t)
+			}
+		}
+		n.templates = append(n.templates, segments)
+	}
+	return n, nil
+}
+
+// Normalize returns the template matching path. If no template matches it returns
+// PathOther in strict mode, and otherwise path with identifier-like segments
+// replaced by ":id".
+func (n *PathNormalizer) Normalize(path string) string {
+	segments := splitPath(path)
+	for _, template := range n.templates {
+		if matchTemplate(template, segments) {
+			return "/" + strings.Join(template, "/")
+		}
+	}
+	if n.strict {
+		return PathOther
+	}
+
+	for i, seg := range segments {
+		if idSegmentRegex.MatchString(seg) {
+			segments[i] = ":id"
+		}
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+// matchTemplate reports whether the path segments match the template segments.
+func matchTemplate(template, segments []string) bool {
+	for i, t := range template {
+		if t == "*" {
+			return true
+		}
+		if i >= len(segments) {
+			return false
+		}
+		if !strings.HasPrefix(t, ":") && t != segments[i] {
+			return false
+		}
+	}
+	return len(template) == len(segments)
+}
+
+// splitPath splits a path into its segments, ignoring leading and trailing slashes.
+func splitPath(path string) []string {
+	trimmed := strings.Trim(path, "/")
+	if trimmed == "" {
+		return nil
+	}
+	return strings.Split(trimmed, "/")
+}
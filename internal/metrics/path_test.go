@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPathNormalizer_Normalize(t *testing.T) {
+	n, err := NewPathNormalizer([]string{
+		"/api/users/:id",
+		"/api/users/:id/orders/:order",
+		"/static/*",
+	}, false)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{name: "single parameter", path: "/api/users/123", expected: "/api/users/:id"},
+		{name: "non-numeric parameter", path: "/api/users/alice", expected: "/api/users/:id"},
+		{name: "nested parameters", path: "/api/users/123/orders/9", expected: "/api/users/:id/orders/:order"},
+		{name: "trailing slash", path: "/api/users/123/", expected: "/api/users/:id"},
+		{name: "wildcard", path: "/static/css/site.css", expected: "/static/*"},
+		{name: "numeric id collapsed", path: "/api/products/42", expected: "/api/products/:id"},
+		{name: "uuid collapsed", path: "/api/carts/550e8400-e29b-41d4-a716-446655440000/items", expected: "/api/carts/:id/items"},
+		{name: "ulid collapsed", path: "/events/01ARZ3NDEKTSV4RRFFQ69G5FAV", expected: "/events/:id"},
+		{name: "hex id collapsed", path: "/blobs/9f86d081884c7d659a2feaa0c55ad015", expected: "/blobs/:id"},
+		{name: "static path untouched", path: "/healthz", expected: "/healthz"},
+		{name: "root", path: "/", expected: "/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, n.Normalize(tt.path))
+		})
+	}
+}
+
+func TestPathNormalizer_Strict(t *testing.T) {
+	n, err := NewPathNormalizer([]string{"/api/users/:id"}, true)
+	require.NoError(t, err)
+
+	assert.Equal(t, "/api/users/:id", n.Normalize("/api/users/123"))
+	assert.Equal(t, PathOther, n.Normalize("/search/anything-goes"))
+}
+
+func TestNewPathNormalizer_InvalidTemplates(t *testing.T) {
+	_, err := NewPathNormalizer([]string{"api/users"}, false)
+	assert.Error(t, err)
+
+	_, err = NewPathNormalizer([]string{"/static/*/file"}, false)
+	assert.Error(t, err)
+}
@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// PushgatewayConfig configures pushing metrics to a Prometheus Pushgateway.
+type PushgatewayConfig struct {
+	// URL is the Pushgateway base URL, e.g. http://pushgateway.monitoring:9091.
+	URL string
+
+	// Job is the job label of the pushed metric group.
+	Job string
+
+	// Instance is the instance label of the pushed metric group, typically the pod name.
+	Instance string
+}
+
+// PushToGateway pushes all metrics registered with the default Prometheus registry
+// to a Pushgateway, replacing the metrics previously pushed for the same job and
+// instance. It is used to keep the metrics of short-lived pods, which may exit
+// before Prometheus scrapes them.
+func PushToGateway(ctx context.Context, cfg PushgatewayConfig) error {
+	pusher := push.New(cfg.URL, cfg.Job).
+		Gatherer(prometheus.DefaultGatherer).
+		Grouping("instance", cfg.Instance)
+
+	if err := pusher.PushContext(ctx); err != nil {
+		return fmt.Errorf("failed to push metrics to %q: %w", cfg.URL, err)
+	}
+	return nil
+}
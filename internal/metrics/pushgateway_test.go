@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPushToGateway(t *testing.T) {
+	var (
+		method string
+		path   string
+		body   []byte
+	)
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		path = r.URL.Path
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer gateway.Close()
+
+	RecordRequest("GET", 200, 10*time.Millisecond)
+
+	err := PushToGateway(context.Background(), PushgatewayConfig{
+		URL:      gateway.URL,
+		Job:      "ctxforge-proxy",
+		Instance: "batch-job-abc12",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, http.MethodPut, method)
+	assert.Equal(t, "/metrics/job/ctxforge-proxy/instance/batch-job-abc12", path)
+	assert.Contains(t, string(body), "ctxforge_proxy_requests_total")
+}
+
+func TestPushToGateway_Error(t *testing.T) {
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer gateway.Close()
+
+	err := PushToGateway(context.Background(), PushgatewayConfig{
+		URL:      gateway.URL,
+		Job:      "ctxforge-proxy",
+		Instance: "batch-job-abc12",
+	})
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,208 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/rs/zerolog/log"
+)
+
+// StatsD flavors supported by the StatsD emitter.
+const (
+	// StatsDFlavorDogStatsD sends labels as DogStatsD tags.
+	StatsDFlavorDogStatsD = "dogstatsd"
+	// StatsDFlavorPlain appends label values to the metric name, for servers without tag support.
+	StatsDFlavorPlain = "statsd"
+)
+
+// statsdMaxPacketSize keeps datagrams below a typical MTU to avoid fragmentation.
+const statsdMaxPacketSize = 1432
+
+// StatsDConfig configures emitting metrics to a StatsD or DogStatsD agent.
+type StatsDConfig struct {
+	// Address is the host:port of the agent's UDP listener.
+	Address string
+
+	// Interval is how often metrics are flushed to the agent.
+	Interval time.Duration
+
+	// Flavor is StatsDFlavorDogStatsD or StatsDFlavorPlain.
+	Flavor string
+}
+
+// statsdEmitter converts gathered Prometheus metrics to StatsD lines.
+// Counters are sent as the delta since the previous flush, gauges as their
+// current value, and histograms as the deltas of their count and sum.
+type statsdEmitter struct {
+	conn     net.Conn
+	gatherer prometheus.Gatherer
+	flavor   string
+
+	mu   sync.Mutex
+	last map[string]float64
+}
+
+// StartStatsDEmitter periodically sends the proxy metrics to a StatsD agent.
+// The returned function performs a final flush and stops the emitter.
+func StartStatsDEmitter(cfg StatsDConfig) (func(), error) {
+	conn, err := net.Dial("udp", cfg.Address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to StatsD agent %q: %w", cfg.Address, err)
+	}
+
+	e := newStatsDEmitter(conn, prometheus.DefaultGatherer, cfg.Flavor)
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(cfg.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				e.flush()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+		e.flush()
+		_ = conn.Close()
+	}, nil
+}
+
+// newStatsDEmitter creates an emitter writing to conn.
+func newStatsDEmitter(conn net.Conn, gatherer prometheus.Gatherer, flavor string) *statsdEmitter {
+	return &statsdEmitter{
+		conn:     conn,
+		gatherer: gatherer,
+		flavor:   flavor,
+		last:     make(map[string]float64),
+	}
+}
+
+// flush gathers the proxy metrics and sends them to the agent.
+func (e *statsdEmitter) flush() {
+	families, err := e.gatherer.Gather()
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to gather metrics for StatsD")
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var lines []string
+	for _, family := range families {
+		name := family.GetName()
+		if !strings.HasPrefix(name, namespace+"_") {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				lines = e.appendCounter(lines, name, m.GetLabel(), m.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				lines = append(lines, e.line(name, m.GetLabel(), m.GetGauge().GetValue(), "g"))
+			case dto.MetricType_HISTOGRAM:
+				h := m.GetHistogram()
+				lines = e.appendCounter(lines, name+"_count", m.GetLabel(), float64(h.GetSampleCount()))
+				lines = e.appendCounter(lines, name+"_sum", m.GetLabel(), h.GetSampleSum())
+			}
+		}
+	}
+
+	e.send(lines)
+}
+
+// appendCounter appends the increase of a cumulative counter since the previous flush.
+func (e *statsdEmitter) appendCounter(lines []string, name string, labels []*dto.LabelPair, value float64) []string {
+	key := name + labelKey(labels)
+	delta := value - e.last[key]
+	if delta < 0 {
+		// Counter was reset
+		delta = value
+	}
+	e.last[key] = value
+	if delta == 0 {
+		return lines
+	}
+	return append(lines, e.line(name, labels, delta, "c"))
+}
+
+// line formats a single StatsD line.
+func (e *statsdEmitter) line(name string, labels []*dto.LabelPair, value float64, kind string) string {
+	v := strconv.FormatFloat(value, 'f', -1, 64)
+
+	if e.flavor == StatsDFlavorPlain {
+		for _, l := range labels {
+			name += "." + sanitizeStatsD(l.GetValue())
+		}
+		return name + ":" + v + "|" + kind
+	}
+
+	if len(labels) == 0 {
+		return name + ":" + v + "|" + kind
+	}
+	tags := make([]string, 0, len(labels))
+	for _, l := range labels {
+		tags = append(tags, l.GetName()+":"+sanitizeStatsD(l.GetValue()))
+	}
+	return name + ":" + v + "|" + kind + "|#" + strings.Join(tags, ",")
+}
+
+// send writes lines to the agent, packing as many as fit into each datagram.
+func (e *statsdEmitter) send(lines []string) {
+	var buf bytes.Buffer
+	write := func() {
+		if buf.Len() == 0 {
+			return
+		}
+		if _, err := e.conn.Write(buf.Bytes()); err != nil {
+			log.Debug().Err(err).Msg("Failed to send metrics to StatsD agent")
+		}
+		buf.Reset()
+	}
+
+	for _, line := range lines {
+		if buf.Len() > 0 && buf.Len()+1+len(line) > statsdMaxPacketSize {
+			write()
+		}
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(line)
+	}
+	write()
+}
+
+// labelKey returns a stable identifier for a label set.
+func labelKey(labels []*dto.LabelPair) string {
+	pairs := make([]string, 0, len(labels))
+	for _, l := range labels {
+		pairs = append(pairs, l.GetName()+"="+l.GetValue())
+	}
+	sort.Strings(pairs)
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// statsdReplacer replaces characters that have a meaning in the StatsD line protocol.
+var statsdReplacer = strings.NewReplacer(":", "_", "|", "_", ",", "_", "#", "_", "@", "_", "\n", "_")
+
+// sanitizeStatsD makes a label value safe to use in a StatsD line.
+func sanitizeStatsD(s string) string {
+	return statsdReplacer.Replace(s)
+}
@@ -0,0 +1,109 @@
+package metrics
+
+import (
+	"net"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// statsdListener returns a UDP listener and a connection to it.
+func statsdListener(t *testing.T) (net.PacketConn, net.Conn) {
+	t.Helper()
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	conn, err := net.Dial("udp", listener.LocalAddr().String())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return listener, conn
+}
+
+// readLines reads one datagram and returns its sorted lines.
+func readLines(t *testing.T, listener net.PacketConn) []string {
+	t.Helper()
+	buf := make([]byte, 65535)
+	require.NoError(t, listener.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, _, err := listener.ReadFrom(buf)
+	require.NoError(t, err)
+	lines := strings.Split(string(buf[:n]), "\n")
+	sort.Strings(lines)
+	return lines
+}
+
+func testRegistry() (*prometheus.Registry, *prometheus.CounterVec, prometheus.Gauge) {
+	registry := prometheus.NewRegistry()
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "ctxforge_proxy_test_total"}, []string{"method"})
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "ctxforge_proxy_test_gauge"})
+	ignored := prometheus.NewCounter(prometheus.CounterOpts{Name: "go_test_total"})
+	registry.MustRegister(counter, gauge, ignored)
+	ignored.Inc()
+	return registry, counter, gauge
+}
+
+func TestStatsDEmitter_DogStatsD(t *testing.T) {
+	listener, conn := statsdListener(t)
+	registry, counter, gauge := testRegistry()
+	e := newStatsDEmitter(conn, registry, StatsDFlavorDogStatsD)
+
+	counter.WithLabelValues("GET").Add(3)
+	gauge.Set(7)
+	e.flush()
+
+	assert.Equal(t, []string{
+		"ctxforge_proxy_test_gauge:7|g",
+		"ctxforge_proxy_test_total:3|c|#method:GET",
+	}, readLines(t, listener))
+
+	// Counters are sent as deltas; unchanged counters are skipped
+	counter.WithLabelValues("GET").Add(2)
+	counter.WithLabelValues("POST").Inc()
+	e.flush()
+
+	assert.Equal(t, []string{
+		"ctxforge_proxy_test_gauge:7|g",
+		"ctxforge_proxy_test_total:1|c|#method:POST",
+		"ctxforge_proxy_test_total:2|c|#method:GET",
+	}, readLines(t, listener))
+}
+
+func TestStatsDEmitter_Plain(t *testing.T) {
+	listener, conn := statsdListener(t)
+	registry, counter, _ := testRegistry()
+	e := newStatsDEmitter(conn, registry, StatsDFlavorPlain)
+
+	counter.WithLabelValues("GET").Inc()
+	e.flush()
+
+	assert.Equal(t, []string{
+		"ctxforge_proxy_test_gauge:0|g",
+		"ctxforge_proxy_test_total.GET:1|c",
+	}, readLines(t, listener))
+}
+
+func TestStatsDEmitter_Histogram(t *testing.T) {
+	listener, conn := statsdListener(t)
+	registry := prometheus.NewRegistry()
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "ctxforge_proxy_test_seconds"})
+	registry.MustRegister(histogram)
+	e := newStatsDEmitter(conn, registry, StatsDFlavorDogStatsD)
+
+	histogram.Observe(0.5)
+	histogram.Observe(1.5)
+	e.flush()
+
+	assert.Equal(t, []string{
+		"ctxforge_proxy_test_seconds_count:2|c",
+		"ctxforge_proxy_test_seconds_sum:2|c",
+	}, readLines(t, listener))
+}
+
+func TestSanitizeStatsD(t *testing.T) {
+	assert.Equal(t, "a_b_c_d", sanitizeStatsD("a:b|c,d"))
+}
@@ -4,13 +4,19 @@ package middleware
 import (
 	"net/http"
 
+	"github.com/bgruszka/contextforge/internal/metrics"
 	"golang.org/x/time/rate"
 )
 
+// KeyTypeGlobal is the limiter key type for the single pod-wide token bucket.
+// It is used as the "key_type" label of the rate limiting metrics.
+const KeyTypeGlobal = "global"
+
 // RateLimiter is an HTTP middleware that limits requests using a token bucket algorithm.
 type RateLimiter struct {
 	limiter *rate.Limiter
 	enabled bool
+	keyType string
 }
 
 // NewRateLimiter creates a new rate limiter middleware.
@@ -20,6 +26,7 @@ func NewRateLimiter(enabled bool, rps float64, burst int) *RateLimiter {
 	return &RateLimiter{
 		limiter: rate.NewLimiter(rate.Limit(rps), burst),
 		enabled: enabled,
+		keyType: KeyTypeGlobal,
 	}
 }
 
@@ -27,12 +34,7 @@ func NewRateLimiter(enabled bool, rps float64, burst int) *RateLimiter {
 // When the rate limit is exceeded, it returns HTTP 429 Too Many Requests.
 func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !rl.enabled {
-			next.ServeHTTP(w, r)
-			return
-		}
-
-		if !rl.limiter.Allow() {
+		if !rl.Allow() {
 			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
 			return
 		}
@@ -43,9 +45,12 @@ func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
 
 // Allow checks if a request is allowed under the current rate limit.
 // Returns true if allowed, false if rate limited.
+// Decisions and the remaining token level are recorded as metrics.
 func (rl *RateLimiter) Allow() bool {
 	if !rl.enabled {
 		return true
 	}
-	return rl.limiter.Allow()
+	allowed := rl.limiter.Allow()
+	metrics.RecordRateLimitDecision(rl.keyType, allowed, rl.limiter.Tokens())
+	return allowed
 }
@@ -5,6 +5,8 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/bgruszka/contextforge/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -140,3 +142,29 @@ func TestRateLimiter_ResponseBody(t *testing.T) {
 	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
 	assert.Contains(t, rr.Body.String(), "Too Many Requests")
 }
+
+func TestRateLimiter_RecordsMetrics(t *testing.T) {
+	rl := NewRateLimiter(true, 1, 1)
+
+	allowed := testutil.ToFloat64(metrics.RateLimitAllowedTotal.WithLabelValues(KeyTypeGlobal))
+	rejected := testutil.ToFloat64(metrics.RateLimitRejectedTotal.WithLabelValues(KeyTypeGlobal))
+
+	assert.True(t, rl.Allow())
+	assert.False(t, rl.Allow())
+
+	assert.Equal(t, allowed+1, testutil.ToFloat64(metrics.RateLimitAllowedTotal.WithLabelValues(KeyTypeGlobal)))
+	assert.Equal(t, rejected+1, testutil.ToFloat64(metrics.RateLimitRejectedTotal.WithLabelValues(KeyTypeGlobal)))
+	assert.Less(t, testutil.ToFloat64(metrics.RateLimitTokens.WithLabelValues(KeyTypeGlobal)), 1.0)
+}
+
+func TestRateLimiter_DisabledRecordsNoMetrics(t *testing.T) {
+	rl := NewRateLimiter(false, 1, 1)
+
+	allowed := testutil.ToFloat64(metrics.RateLimitAllowedTotal.WithLabelValues(KeyTypeGlobal))
+
+	for i := 0; i < 5; i++ {
+		assert.True(t, rl.Allow())
+	}
+
+	assert.Equal(t, allowed, testutil.ToFloat64(metrics.RateLimitAllowedTotal.WithLabelValues(KeyTypeGlobal)))
+}
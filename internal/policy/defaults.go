@@ -0,0 +1,214 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+)
+
+// DefaultsConfigMapName is the conventional name of the ConfigMap holding the
+// organization-wide policy defaults.
+const DefaultsConfigMapName = "ctxforge-defaults"
+
+// Keys of the defaults ConfigMap.
+const (
+	// DefaultsHeadersKey holds a YAML list of headers, in the format of a
+	// propagation rule's headers.
+	DefaultsHeadersKey = "headers"
+	// DefaultsGeneratorTypeKey holds the generator of generated headers that
+	// don't set one.
+	DefaultsGeneratorTypeKey = "generatorType"
+	// DefaultsSamplingKey holds the YAML sampling of policies without one.
+	DefaultsSamplingKey = "sampling"
+)
+
+// Defaults are settings every policy gets unless it configures them itself.
+type Defaults struct {
+	// Headers are propagated on every request of the pods a policy selects,
+	// unless the policy configures them.
+	Headers []ctxforgev1beta1.HeaderConfig
+	// GeneratorType replaces DefaultGeneratorType for generated headers.
+	GeneratorType string
+	// Sampling applies to policies without sampling.
+	Sampling *ctxforgev1beta1.Sampling
+}
+
+// ParseDefaults reads the data of a defaults ConfigMap. It fails on unknown
+// keys and on defaults the proxy would reject, so that a typo doesn't go
+// unnoticed.
+func ParseDefaults(data map[string]string) (Defaults, error) {
+	var defaults Defaults
+	for key, value := range data {
+		switch key {
+		case DefaultsHeadersKey:
+			if err := yaml.UnmarshalStrict([]byte(value), &defaults.Headers); err != nil {
+				return Defaults{}, fmt.Errorf("invalid %s: %w", key, err)
+			}
+		case DefaultsGeneratorTypeKey:
+			defaults.GeneratorType = strings.TrimSpace(value)
+		case DefaultsSamplingKey:
+			defaults.Sampling = &ctxforgev1beta1.Sampling{}
+			if err := yaml.UnmarshalStrict([]byte(value), defaults.Sampling); err != nil {
+				return Defaults{}, fmt.Errorf("invalid %s: %w", key, err)
+			}
+			if p := defaults.Sampling.Percentage; p < 0 || p > 100 {
+				return Defaults{}, fmt.Errorf("invalid %s: percentage %d is outside 0 to 100", key, p)
+			}
+		default:
+			return Defaults{}, fmt.Errorf("unknown key %q", key)
+		}
+	}
+
+	// Check the headers and generator the way the proxy parses them
+	check := ctxforgev1beta1.HeaderPropagationPolicySpec{
+		PropagationRules: []ctxforgev1beta1.PropagationRule{{Headers: defaults.Headers}},
+	}
+	if defaults.GeneratorType != "" {
+		check.PropagationRules[0].Headers = append(check.PropagationRules[0].Headers,
+			ctxforgev1beta1.HeaderConfig{Name: "x-ctxforge-defaults", Generate: true})
+	}
+	if err := Validate(defaults.Apply(check)); err != nil {
+		return Defaults{}, err
+	}
+	return defaults, nil
+}
+
+// Apply returns a copy of spec with the defaults it doesn't override: the
+// default headers it doesn't configure are added as one more propagation
+// rule, generated headers without a generator get GeneratorType and Sampling
+// applies if the policy has none. Default headers thus take the policy's
+// destinations and sampling.
+func (d Defaults) Apply(spec ctxforgev1beta1.HeaderPropagationPolicySpec) ctxforgev1beta1.HeaderPropagationPolicySpec {
+	spec = *spec.DeepCopy()
+
+	configured := make(map[string]bool)
+	for _, rule := range spec.PropagationRules {
+		for _, header := range rule.Headers {
+			configured[http.CanonicalHeaderKey(header.Name)] = true
+		}
+	}
+	var missing []ctxforgev1beta1.HeaderConfig
+	for _, header := range d.Headers {
+		if key := http.CanonicalHeaderKey(header.Name); !configured[key] {
+			configured[key] = true
+			missing = append(missing, *header.DeepCopy())
+		}
+	}
+	if len(missing) > 0 {
+		spec.PropagationRules = append(spec.PropagationRules, ctxforgev1beta1.PropagationRule{Headers: missing})
+	}
+
+	if d.GeneratorType != "" {
+		for i := range spec.PropagationRules {
+			for j := range spec.PropagationRules[i].Headers {
+				header := &spec.PropagationRules[i].Headers[j]
+				if header.Generate && header.GeneratorType == "" {
+					header.GeneratorType = d.GeneratorType
+				}
+			}
+		}
+	}
+	if spec.Sampling == nil && d.Sampling != nil {
+		spec.Sampling = d.Sampling.DeepCopy()
+	}
+	return spec
+}
+
+// ApplyAll applies the defaults to the spec of each source.
+func (d Defaults) ApplyAll(sources []Source) []Source {
+	applied := make([]Source, 0, len(sources))
+	for _, source := range sources {
+		source.Spec = d.Apply(source.Spec)
+		applied = append(applied, source)
+	}
+	return applied
+}
+
+// DefaultsSource reads the Defaults from a ConfigMap on every use; Client is
+// normally the manager's cached client. A nil DefaultsSource, or a missing
+// ConfigMap, yields no defaults.
+type DefaultsSource struct {
+	Client client.Reader
+	// Key names the ConfigMap.
+	Key types.NamespacedName
+
+	mu   sync.Mutex
+	last Defaults
+}
+
+// Get returns the current defaults. If the ConfigMap is invalid, the error is
+// logged and the last valid defaults are kept.
+func (s *DefaultsSource) Get(ctx context.Context) Defaults {
+	if s == nil {
+		return Defaults{}
+	}
+	log := logf.FromContext(ctx).WithValues("configMap", s.Key.String())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cm := &corev1.ConfigMap{}
+	if err := s.Client.Get(ctx, s.Key, cm); err != nil {
+		if apierrors.IsNotFound(err) {
+			s.last = Defaults{}
+			return s.last
+		}
+		log.Error(err, "Failed to read policy defaults, keeping previous values")
+		return s.last
+	}
+	defaults, err := ParseDefaults(cm.Data)
+	if err != nil {
+		log.Error(err, "Ignoring invalid policy defaults, keeping previous values")
+		return s.last
+	}
+	s.last = defaults
+	return s.last
+}
+
+// DefaultsFromEnv returns the defaults source for POLICY_DEFAULTS_CONFIGMAP,
+// the namespace/name of the defaults ConfigMap, or nil when it is unset.
+func DefaultsFromEnv(c client.Reader) (*DefaultsSource, error) {
+	value := os.Getenv("POLICY_DEFAULTS_CONFIGMAP")
+	if value == "" {
+		return nil, nil
+	}
+	namespace, name, ok := strings.Cut(value, "/")
+	if !ok || namespace == "" || name == "" {
+		return nil, fmt.Errorf("invalid POLICY_DEFAULTS_CONFIGMAP value %q: expected namespace/name", value)
+	}
+	return &DefaultsSource{Client: c, Key: types.NamespacedName{Namespace: namespace, Name: name}}, nil
+}
+
+// IsDefaults reports whether obj is the source's ConfigMap.
+func (s *DefaultsSource) IsDefaults(obj client.Object) bool {
+	_, ok := obj.(*corev1.ConfigMap)
+	return ok && s != nil && obj.GetNamespace() == s.Key.Namespace && obj.GetName() == s.Key.Name
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+)
+
+func TestParseDefaults(t *testing.T) {
+	defaults, err := ParseDefaults(map[string]string{
+		DefaultsHeadersKey:       "- name: x-tenant-id\n- name: x-request-id\n  generate: true\n",
+		DefaultsGeneratorTypeKey: " ulid\n",
+		DefaultsSamplingKey:      "percentage: 10\n",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, Defaults{
+		Headers: []ctxforgev1beta1.HeaderConfig{
+			{Name: "x-tenant-id"},
+			{Name: "x-request-id", Generate: true},
+		},
+		GeneratorType: "ulid",
+		Sampling:      &ctxforgev1beta1.Sampling{Percentage: 10},
+	}, defaults)
+
+	defaults, err = ParseDefaults(nil)
+	require.NoError(t, err)
+	assert.Equal(t, Defaults{}, defaults)
+}
+
+func TestParseDefaults_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    map[string]string
+		wantErr string
+	}{
+		{"unknown key", map[string]string{"header": "- name: x-tenant-id"}, `unknown key "header"`},
+		{"unknown header field", map[string]string{DefaultsHeadersKey: "- name: x-tenant-id\n  propagte: true"}, "invalid headers"},
+		{"unknown generator", map[string]string{DefaultsGeneratorTypeKey: "random"}, "generator"},
+		{"sampling out of range", map[string]string{DefaultsSamplingKey: "percentage: 150"}, "outside 0 to 100"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseDefaults(tt.data)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestDefaults_Apply(t *testing.T) {
+	defaults := Defaults{
+		Headers: []ctxforgev1beta1.HeaderConfig{
+			{Name: "x-tenant-id"},
+			{Name: "x-request-id", Generate: true},
+		},
+		GeneratorType: "ulid",
+		Sampling:      &ctxforgev1beta1.Sampling{Percentage: 10},
+	}
+	spec := ctxforgev1beta1.HeaderPropagationPolicySpec{
+		PropagationRules: []ctxforgev1beta1.PropagationRule{{
+			PathRegex: "^/api/",
+			Headers: []ctxforgev1beta1.HeaderConfig{
+				{Name: "X-Request-ID", Generate: true, GeneratorType: "uuid"},
+				{Name: "x-correlation-id", Generate: true},
+			},
+		}},
+	}
+
+	applied := defaults.Apply(spec)
+	assert.Equal(t, []ctxforgev1beta1.PropagationRule{
+		{
+			PathRegex: "^/api/",
+			Headers: []ctxforgev1beta1.HeaderConfig{
+				{Name: "X-Request-ID", Generate: true, GeneratorType: "uuid"},
+				{Name: "x-correlation-id", Generate: true, GeneratorType: "ulid"},
+			},
+		},
+		{Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-tenant-id"}}},
+	}, applied.PropagationRules, "the policy's own headers win")
+	assert.Equal(t, defaults.Sampling, applied.Sampling)
+	assert.Empty(t, spec.PropagationRules[0].Headers[1].GeneratorType, "the spec is not modified")
+
+	spec.Sampling = &ctxforgev1beta1.Sampling{Percentage: 50}
+	assert.Equal(t, int32(50), defaults.Apply(spec).Sampling.Percentage)
+
+	assert.Equal(t, spec, Defaults{}.Apply(spec))
+}
+
+func TestDefaultsSource_Get(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: DefaultsConfigMapName, Namespace: "contextforge-system"},
+		Data:       map[string]string{DefaultsHeadersKey: "- name: x-tenant-id"},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cm).Build()
+	source := &DefaultsSource{Client: c, Key: types.NamespacedName{Namespace: "contextforge-system", Name: DefaultsConfigMapName}}
+	ctx := context.Background()
+
+	want := []ctxforgev1beta1.HeaderConfig{{Name: "x-tenant-id"}}
+	assert.Equal(t, want, source.Get(ctx).Headers)
+
+	cm.Data[DefaultsHeadersKey] = "- nam: x-tenant-id"
+	require.NoError(t, c.Update(ctx, cm))
+	assert.Equal(t, want, source.Get(ctx).Headers, "invalid defaults keep the last valid ones")
+
+	require.NoError(t, c.Delete(ctx, cm))
+	assert.Equal(t, Defaults{}, source.Get(ctx))
+
+	assert.Equal(t, Defaults{}, (*DefaultsSource)(nil).Get(ctx))
+	assert.True(t, source.IsDefaults(cm))
+	assert.False(t, (*DefaultsSource)(nil).IsDefaults(cm))
+}
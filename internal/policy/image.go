@@ -0,0 +1,76 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ImageAllowlist lists the proxy images policies may pin with
+// sidecar.image. An entry is an image reference, allowing exactly that image;
+// a repository, allowing any of its tags and digests; or a prefix ending in
+// "*", allowing every image that starts with it.
+type ImageAllowlist []string
+
+// ParseImageAllowlist parses a comma-separated ImageAllowlist.
+func ParseImageAllowlist(value string) (ImageAllowlist, error) {
+	var allowlist ImageAllowlist
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if strings.ContainsAny(entry, " \t") {
+			return nil, fmt.Errorf("invalid image allowlist entry %q: contains whitespace", entry)
+		}
+		if strings.Contains(strings.TrimSuffix(entry, "*"), "*") {
+			return nil, fmt.Errorf("invalid image allowlist entry %q: \"*\" is only allowed at the end", entry)
+		}
+		allowlist = append(allowlist, entry)
+	}
+	return allowlist, nil
+}
+
+// Allows reports whether image matches an entry of the allowlist.
+func (a ImageAllowlist) Allows(image string) bool {
+	repository := ImageRepository(image)
+	for _, entry := range a {
+		if prefix, ok := strings.CutSuffix(entry, "*"); ok {
+			if strings.HasPrefix(image, prefix) {
+				return true
+			}
+			continue
+		}
+		if image == entry || repository == entry {
+			return true
+		}
+	}
+	return false
+}
+
+// ImageRepository returns image without its tag or digest.
+func ImageRepository(image string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	// A colon before the last slash separates a registry's port
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	return image
+}
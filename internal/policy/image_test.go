@@ -0,0 +1,64 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageAllowlist_Allows(t *testing.T) {
+	allowlist, err := ParseImageAllowlist(" ghcr.io/bgruszka/contextforge-proxy, registry.local:5000/proxy:1.2.0 ,mirror.local/*,")
+	require.NoError(t, err)
+	assert.Len(t, allowlist, 3)
+
+	tests := []struct {
+		image string
+		want  bool
+	}{
+		{"ghcr.io/bgruszka/contextforge-proxy:0.2.0", true},
+		{"ghcr.io/bgruszka/contextforge-proxy@sha256:0123abcd", true},
+		{"ghcr.io/bgruszka/contextforge-proxy", true},
+		{"ghcr.io/bgruszka/contextforge-proxy-debug:0.2.0", false},
+		{"registry.local:5000/proxy:1.2.0", true},
+		{"registry.local:5000/proxy:1.3.0", false},
+		{"mirror.local/contextforge/proxy:0.2.0", true},
+		{"docker.io/library/nginx:latest", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			assert.Equal(t, tt.want, allowlist.Allows(tt.image))
+		})
+	}
+
+	assert.False(t, ImageAllowlist(nil).Allows("ghcr.io/bgruszka/contextforge-proxy:0.2.0"))
+}
+
+func TestParseImageAllowlist_Invalid(t *testing.T) {
+	for _, value := range []string{"ghcr.io/*/proxy", "ghcr.io/proxy latest"} {
+		_, err := ParseImageAllowlist(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestImageRepository(t *testing.T) {
+	assert.Equal(t, "ghcr.io/bgruszka/contextforge-proxy", ImageRepository("ghcr.io/bgruszka/contextforge-proxy:0.1.0"))
+	assert.Equal(t, "registry.local:5000/proxy", ImageRepository("registry.local:5000/proxy"))
+	assert.Equal(t, "registry.local:5000/proxy", ImageRepository("registry.local:5000/proxy:1.0@sha256:0123abcd"))
+}
@@ -0,0 +1,202 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"net/http"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/bgruszka/contextforge/internal/config"
+)
+
+// DefaultGeneratorType is the generator the proxy uses for a generated header
+// without a generatorType.
+const DefaultGeneratorType = "uuid"
+
+// Rule is an entry of the proxy's HEADER_RULES.
+type Rule struct {
+	Name          string   `json:"name"`
+	Generate      bool     `json:"generate,omitempty"`
+	GeneratorType string   `json:"generatorType,omitempty"`
+	Propagate     *bool    `json:"propagate,omitempty"`
+	PathRegex     string   `json:"pathRegex,omitempty"`
+	ExcludePaths  []string `json:"excludePaths,omitempty"`
+	Methods       []string `json:"methods,omitempty"`
+
+	Rename      string            `json:"rename,omitempty"`
+	StaticValue string            `json:"staticValue,omitempty"`
+	Transform   *config.Transform `json:"transform,omitempty"`
+	OnExisting  config.OnExisting `json:"onExisting,omitempty"`
+
+	Destinations *config.Destinations `json:"destinations,omitempty"`
+	Sampling     *config.Sampling     `json:"sampling,omitempty"`
+
+	Response config.ResponseAction `json:"response,omitempty"`
+}
+
+// Merged is the effective configuration of the policies selecting a pod.
+type Merged struct {
+	// Sources are the merged policies in order of precedence.
+	Sources []Source
+	// Rules are the header rules to inject, see Merge.
+	Rules []Rule
+	// Conflicts are the headers configured by more than one policy.
+	Conflicts []Conflict
+	// Image is the proxy image, see Merge. Empty keeps the operator's image.
+	Image string
+	// Resources size the proxy sidecar, see Merge. Nil keeps the
+	// webhook's defaults.
+	Resources *corev1.ResourceRequirements
+}
+
+// Merge combines the rules of the policies selecting a pod:
+//
+//   - Headers are united: every header configured by any policy is kept.
+//   - Each header is owned by the policy with the highest precedence (see
+//     Less) configuring it. All of the owner's rules for the header are kept,
+//     so it can scope the header to several paths or methods; the other
+//     policies' rules for the header are dropped and reported as conflicts.
+//     Header names are compared case-insensitively.
+//   - Rules are ordered by policy precedence, then by their position in the
+//     policy: rule by rule, header by header.
+//   - The proxy runs one generator per header, so the first of the owner's
+//     rules generating a header sets its generatorType, which defaults to
+//     uuid, for all of them.
+//   - Rules carry the destinations and sampling of their policy.
+//   - Response rules follow the propagation rules, one per header echoed or
+//     stripped. They are owned per header like propagation rules, separately
+//     from them.
+//   - The sidecar's image and resources are those of the policies with the
+//     highest precedence setting sidecar.image and sidecar.resources.
+func Merge(sources []Source) Merged {
+	sorted := append([]Source(nil), sources...)
+	Sort(sorted)
+
+	type owner struct {
+		header    string
+		source    Source
+		generator string
+	}
+	merged := Merged{Sources: sorted}
+	// own returns the owner of a header, or nil when another source owns it
+	own := func(owners map[string]*owner, reported map[string]bool, name string, source Source) *owner {
+		key := http.CanonicalHeaderKey(name)
+		headerOwner, owned := owners[key]
+		if !owned {
+			headerOwner = &owner{header: name, source: source}
+			owners[key] = headerOwner
+		}
+		if headerOwner.source.Name == source.Name && headerOwner.source.Namespace == source.Namespace {
+			return headerOwner
+		}
+		if !reported[key] {
+			reported[key] = true
+			merged.Conflicts = append(merged.Conflicts, Conflict{
+				Header: headerOwner.header,
+				Winner: headerOwner.source,
+				Loser:  source,
+			})
+		}
+		return nil
+	}
+
+	owners := make(map[string]*owner)
+	responseOwners := make(map[string]*owner)
+	var responseRules []Rule
+	for _, source := range sorted {
+		if sidecar := source.Spec.Sidecar; sidecar != nil {
+			if merged.Image == "" {
+				merged.Image = sidecar.Image
+			}
+			if merged.Resources == nil {
+				merged.Resources = sidecar.Resources
+			}
+		}
+		var destinations *config.Destinations
+		if d := source.Spec.Destinations; d != nil && (len(d.Allow) > 0 || len(d.Deny) > 0) {
+			destinations = &config.Destinations{Allow: d.Allow, Deny: d.Deny}
+		}
+		var sampling *config.Sampling
+		if s := source.Spec.Sampling; s != nil {
+			sampling = &config.Sampling{Percentage: int(s.Percentage), KeyHeader: s.KeyHeader}
+		}
+		reported := make(map[string]bool)
+		for _, propagationRule := range source.Spec.PropagationRules {
+			for _, header := range propagationRule.Headers {
+				headerOwner := own(owners, reported, header.Name, source)
+				if headerOwner == nil {
+					continue
+				}
+
+				rule := Rule{
+					Name:         header.Name,
+					Generate:     header.Generate,
+					Propagate:    header.Propagate,
+					PathRegex:    propagationRule.PathRegex,
+					ExcludePaths: propagationRule.ExcludePaths,
+					Methods:      propagationRule.Methods,
+
+					Rename:      header.Rename,
+					StaticValue: header.StaticValue,
+					OnExisting:  config.OnExisting(header.OnExisting),
+
+					Destinations: destinations,
+					Sampling:     sampling,
+				}
+				if header.Transform != nil {
+					rule.Transform = &config.Transform{
+						Type:   config.TransformType(header.Transform.Type),
+						Length: int(header.Transform.Length),
+					}
+				}
+				if header.Generate {
+					if headerOwner.generator == "" {
+						headerOwner.generator = header.GeneratorType
+						if headerOwner.generator == "" {
+							headerOwner.generator = DefaultGeneratorType
+						}
+					}
+					rule.GeneratorType = headerOwner.generator
+				}
+				merged.Rules = append(merged.Rules, rule)
+			}
+		}
+
+		reported = make(map[string]bool)
+		for _, responseRule := range source.Spec.ResponseRules {
+			for _, action := range []struct {
+				headers  []string
+				response config.ResponseAction
+			}{{responseRule.Echo, config.ResponseEcho}, {responseRule.Strip, config.ResponseStrip}} {
+				for _, header := range action.headers {
+					if own(responseOwners, reported, header, source) == nil {
+						continue
+					}
+					responseRules = append(responseRules, Rule{
+						Name:      header,
+						PathRegex: responseRule.PathRegex,
+						Methods:   responseRule.Methods,
+						Response:  action.response,
+					})
+				}
+			}
+		}
+	}
+	merged.Rules = append(merged.Rules, responseRules...)
+	return merged
+}
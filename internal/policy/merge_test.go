@@ -0,0 +1,227 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+	"github.com/bgruszka/contextforge/internal/config"
+)
+
+func TestMerge(t *testing.T) {
+	disabled := false
+
+	tests := []struct {
+		name      string
+		sources   []Source
+		want      []Rule
+		conflicts []string
+	}{
+		{
+			name: "union of headers in precedence order",
+			sources: []Source{
+				source("tracing", "default", 0, "x-request-id"),
+				source("tenant", "default", 10, "x-tenant-id"),
+				source(ClusterPrefix+"platform", "", 0, "x-trace-id"),
+			},
+			want: []Rule{
+				{Name: "x-tenant-id"},
+				{Name: "x-request-id"},
+				{Name: "x-trace-id"},
+			},
+		},
+		{
+			name: "the owner's rules replace lower policies' rules for a header",
+			sources: []Source{
+				{
+					Name:      "api",
+					Namespace: "default",
+					Spec: ctxforgev1beta1.HeaderPropagationPolicySpec{PropagationRules: []ctxforgev1beta1.PropagationRule{
+						{PathRegex: "^/api/", ExcludePaths: []string{"^/api/health$"}, Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-tenant-id"}}},
+						{Methods: []string{"POST"}, Headers: []ctxforgev1beta1.HeaderConfig{{Name: "X-Tenant-Id", Propagate: &disabled}}},
+					}},
+				},
+				source(ClusterPrefix+"platform", "", 0, "x-tenant-id", "x-request-id"),
+			},
+			want: []Rule{
+				{Name: "x-tenant-id", PathRegex: "^/api/", ExcludePaths: []string{"^/api/health$"}},
+				{Name: "X-Tenant-Id", Methods: []string{"POST"}, Propagate: &disabled},
+				{Name: "x-request-id"},
+			},
+			conflicts: []string{"x-tenant-id: api over " + ClusterPrefix + "platform"},
+		},
+		{
+			name: "a lower policy's generator is not inherited",
+			sources: []Source{
+				source("tenant", "default", 1, "x-request-id"),
+				{
+					Name:      "tracing",
+					Namespace: "default",
+					Spec: ctxforgev1beta1.HeaderPropagationPolicySpec{PropagationRules: []ctxforgev1beta1.PropagationRule{{
+						Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-request-id", Generate: true, GeneratorType: "ulid"}},
+					}}},
+				},
+			},
+			want:      []Rule{{Name: "x-request-id"}},
+			conflicts: []string{"x-request-id: tenant over tracing"},
+		},
+		{
+			name: "the first generating rule sets the header's generator",
+			sources: []Source{{
+				Name:      "tracing",
+				Namespace: "default",
+				Spec: ctxforgev1beta1.HeaderPropagationPolicySpec{PropagationRules: []ctxforgev1beta1.PropagationRule{
+					{PathRegex: "^/public/", Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-request-id"}}},
+					{PathRegex: "^/api/", Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-request-id", Generate: true}}},
+					{PathRegex: "^/batch/", Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-request-id", Generate: true, GeneratorType: "timestamp"}}},
+				}},
+			}},
+			want: []Rule{
+				{Name: "x-request-id", PathRegex: "^/public/"},
+				{Name: "x-request-id", PathRegex: "^/api/", Generate: true, GeneratorType: DefaultGeneratorType},
+				{Name: "x-request-id", PathRegex: "^/batch/", Generate: true, GeneratorType: DefaultGeneratorType},
+			},
+		},
+		{
+			name: "transformations are passed through",
+			sources: []Source{{
+				Name:      "privacy",
+				Namespace: "default",
+				Spec: ctxforgev1beta1.HeaderPropagationPolicySpec{PropagationRules: []ctxforgev1beta1.PropagationRule{{
+					Headers: []ctxforgev1beta1.HeaderConfig{
+						{Name: "x-user-email", Rename: "x-user-hash", Transform: &ctxforgev1beta1.HeaderTransform{Type: "hash", Length: 16}},
+						{Name: "x-environment", StaticValue: "production", OnExisting: "override"},
+					},
+				}}},
+			}},
+			want: []Rule{
+				{Name: "x-user-email", Rename: "x-user-hash", Transform: &config.Transform{Type: config.TransformHash, Length: 16}},
+				{Name: "x-environment", StaticValue: "production", OnExisting: config.OnExistingOverride},
+			},
+		},
+		{
+			name: "response rules follow the propagation rules",
+			sources: []Source{
+				{
+					Name:      "tracing",
+					Namespace: "default",
+					Spec: ctxforgev1beta1.HeaderPropagationPolicySpec{
+						Priority:         10,
+						PropagationRules: []ctxforgev1beta1.PropagationRule{{Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-request-id"}}}},
+						ResponseRules: []ctxforgev1beta1.ResponseRule{
+							{Echo: []string{"x-request-id"}, Strip: []string{"server"}},
+							{PathRegex: "^/api/", Strip: []string{"x-powered-by"}},
+						},
+					},
+				},
+				{
+					Name:      "platform",
+					Namespace: "default",
+					Spec: ctxforgev1beta1.HeaderPropagationPolicySpec{
+						PropagationRules: []ctxforgev1beta1.PropagationRule{{Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-tenant-id"}}}},
+						ResponseRules:    []ctxforgev1beta1.ResponseRule{{Echo: []string{"server", "x-tenant-id"}}},
+					},
+				},
+			},
+			want: []Rule{
+				{Name: "x-request-id"},
+				{Name: "x-tenant-id"},
+				{Name: "x-request-id", Response: config.ResponseEcho},
+				{Name: "server", Response: config.ResponseStrip},
+				{Name: "x-powered-by", PathRegex: "^/api/", Response: config.ResponseStrip},
+				{Name: "x-tenant-id", Response: config.ResponseEcho},
+			},
+			conflicts: []string{"server: tracing over platform"},
+		},
+		{
+			name: "rules carry their policy's destinations and sampling",
+			sources: []Source{
+				{
+					Name:      "tenant",
+					Namespace: "default",
+					Spec: ctxforgev1beta1.HeaderPropagationPolicySpec{
+						PropagationRules: []ctxforgev1beta1.PropagationRule{{Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-tenant-id"}}}},
+						ResponseRules:    []ctxforgev1beta1.ResponseRule{{Echo: []string{"x-tenant-id"}}},
+						Destinations:     &ctxforgev1beta1.Destinations{Allow: []string{"*.svc.cluster.local"}},
+						Sampling:         &ctxforgev1beta1.Sampling{Percentage: 25, KeyHeader: "x-request-id"},
+					},
+				},
+				source("tracing", "default", 0, "x-request-id"),
+			},
+			want: []Rule{
+				{
+					Name:         "x-tenant-id",
+					Destinations: &config.Destinations{Allow: []string{"*.svc.cluster.local"}},
+					Sampling:     &config.Sampling{Percentage: 25, KeyHeader: "x-request-id"},
+				},
+				{Name: "x-request-id"},
+				{Name: "x-tenant-id", Response: config.ResponseEcho},
+			},
+		},
+		{
+			name:    "no policies",
+			sources: nil,
+			want:    nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged := Merge(tt.sources)
+			assert.Equal(t, tt.want, merged.Rules)
+
+			var conflicts []string
+			for _, conflict := range merged.Conflicts {
+				conflicts = append(conflicts, conflict.Header+": "+conflict.Winner.Name+" over "+conflict.Loser.Name)
+			}
+			assert.Equal(t, tt.conflicts, conflicts)
+		})
+	}
+}
+
+func TestMerge_Sidecar(t *testing.T) {
+	small := &corev1.ResourceRequirements{Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")}}
+	large := &corev1.ResourceRequirements{Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}}
+
+	tenant := source("tenant", "default", 0, "x-tenant-id")
+	tenant.Spec.Sidecar = &ctxforgev1beta1.SidecarConfig{Resources: small}
+	tracing := source("tracing", "default", 10, "x-request-id")
+	tracing.Spec.Sidecar = &ctxforgev1beta1.SidecarConfig{Resources: large}
+	platform := source(ClusterPrefix+"platform", "", 20, "x-trace-id")
+	platform.Spec.Sidecar = &ctxforgev1beta1.SidecarConfig{Image: "ghcr.io/bgruszka/contextforge-proxy:0.2.0"}
+
+	merged := Merge([]Source{tenant, tracing, platform})
+	assert.Equal(t, "ghcr.io/bgruszka/contextforge-proxy:0.2.0", merged.Image)
+	assert.Equal(t, large, merged.Resources)
+	assert.Equal(t, small, Merge([]Source{tenant, platform}).Resources)
+	assert.Nil(t, Merge([]Source{platform}).Resources)
+	assert.Empty(t, Merge([]Source{tenant, tracing}).Image)
+}
+
+func TestMerge_DoesNotReorderInput(t *testing.T) {
+	sources := []Source{source("b", "default", 0, "x-b"), source("a", "default", 0, "x-a")}
+
+	merged := Merge(sources)
+
+	assert.Equal(t, []string{"a", "b"}, names(merged.Sources))
+	assert.Equal(t, []string{"b", "a"}, names(sources))
+}
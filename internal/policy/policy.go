@@ -0,0 +1,142 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package policy holds the rules shared by the webhook and the controllers for
+// evaluating the HeaderPropagationPolicies and ClusterHeaderPropagationPolicies
+// that select a pod: which policies select it, in which order they take
+// precedence, and how their rules merge into the proxy's configuration.
+package policy
+
+import (
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+)
+
+// ClusterPrefix qualifies ClusterHeaderPropagationPolicy names, in the form
+// kubectl accepts, wherever they are listed next to namespaced policies.
+const ClusterPrefix = "clusterheaderpropagationpolicy/"
+
+// Source is a HeaderPropagationPolicy or ClusterHeaderPropagationPolicy.
+type Source struct {
+	// Name identifies the policy; cluster policies carry ClusterPrefix.
+	Name string
+	// Namespace is the namespace of a HeaderPropagationPolicy, empty for a
+	// ClusterHeaderPropagationPolicy.
+	Namespace string
+	Spec      ctxforgev1beta1.HeaderPropagationPolicySpec
+}
+
+// FromPolicy returns the Source of a HeaderPropagationPolicy.
+func FromPolicy(policy *ctxforgev1beta1.HeaderPropagationPolicy) Source {
+	return Source{Name: policy.Name, Namespace: policy.Namespace, Spec: policy.Spec}
+}
+
+// FromClusterPolicy returns the Source of a ClusterHeaderPropagationPolicy.
+func FromClusterPolicy(policy *ctxforgev1beta1.ClusterHeaderPropagationPolicy) Source {
+	return Source{Name: ClusterPrefix + policy.Name, Spec: policy.Spec}
+}
+
+// Cluster reports whether the source is a ClusterHeaderPropagationPolicy.
+func (s Source) Cluster() bool {
+	return s.Namespace == ""
+}
+
+// Audit reports whether the policy is in Audit mode, leaving the pods it
+// selects unchanged.
+func (s Source) Audit() bool {
+	return s.Spec.Mode == ctxforgev1beta1.PolicyModeAudit
+}
+
+// Enforced returns the sources that aren't in Audit mode.
+func Enforced(sources []Source) []Source {
+	var enforced []Source
+	for _, source := range sources {
+		if !source.Audit() {
+			enforced = append(enforced, source)
+		}
+	}
+	return enforced
+}
+
+// Selects reports whether the policy applies to the pod. namespaceLabels are
+// the labels of the pod's namespace, matched by a cluster policy's
+// NamespaceSelector; a namespaced policy only applies in its own namespace.
+func (s Source) Selects(pod *corev1.Pod, namespaceLabels labels.Set) (bool, error) {
+	if s.Cluster() {
+		namespaceSelector, err := Selector(s.Spec.NamespaceSelector)
+		if err != nil {
+			return false, fmt.Errorf("invalid NamespaceSelector: %w", err)
+		}
+		if !namespaceSelector.Matches(namespaceLabels) {
+			return false, nil
+		}
+	} else if s.Namespace != pod.Namespace {
+		return false, nil
+	}
+
+	podSelector, err := Selector(s.Spec.PodSelector)
+	if err != nil {
+		return false, fmt.Errorf("invalid PodSelector: %w", err)
+	}
+	return podSelector.Matches(labels.Set(pod.Labels)), nil
+}
+
+// Selector converts a policy's label selector; nil selects everything.
+func Selector(selector *metav1.LabelSelector) (labels.Selector, error) {
+	if selector == nil {
+		return labels.Everything(), nil
+	}
+	return metav1.LabelSelectorAsSelector(selector)
+}
+
+// Less reports whether a takes precedence over b: a higher priority wins,
+// then a namespaced policy over a cluster policy, then the lower name.
+func Less(a, b Source) bool {
+	if a.Spec.Priority != b.Spec.Priority {
+		return a.Spec.Priority > b.Spec.Priority
+	}
+	if a.Cluster() != b.Cluster() {
+		return !a.Cluster()
+	}
+	return a.Name < b.Name
+}
+
+// Sort orders sources by precedence, as defined by Less.
+func Sort(sources []Source) {
+	sort.SliceStable(sources, func(i, j int) bool { return Less(sources[i], sources[j]) })
+}
+
+// Conflict is a header configured by two policies selecting the same pod.
+type Conflict struct {
+	// Header is the header name, as configured by Winner.
+	Header string
+	// Winner is the policy whose configuration of the header applies.
+	Winner Source
+	// Loser is the policy whose configuration of the header is ignored.
+	Loser Source
+}
+
+// Conflicts returns every header configured by more than one of the sources,
+// paired with the policy that owns it, as defined by Merge.
+func Conflicts(sources []Source) []Conflict {
+	return Merge(sources).Conflicts
+}
@@ -0,0 +1,273 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+)
+
+func source(name, namespace string, priority int32, headers ...string) Source {
+	rule := ctxforgev1beta1.PropagationRule{}
+	for _, header := range headers {
+		rule.Headers = append(rule.Headers, ctxforgev1beta1.HeaderConfig{Name: header})
+	}
+	return Source{
+		Name:      name,
+		Namespace: namespace,
+		Spec: ctxforgev1beta1.HeaderPropagationPolicySpec{
+			Priority:         priority,
+			PropagationRules: []ctxforgev1beta1.PropagationRule{rule},
+		},
+	}
+}
+
+func names(sources []Source) []string {
+	result := make([]string, 0, len(sources))
+	for _, source := range sources {
+		result = append(result, source.Name)
+	}
+	return result
+}
+
+func TestSort(t *testing.T) {
+	tests := []struct {
+		name    string
+		sources []Source
+		want    []string
+	}{
+		{
+			name: "higher priority first",
+			sources: []Source{
+				source("a", "default", 0),
+				source("b", "default", 10),
+				source(ClusterPrefix+"c", "", 20),
+			},
+			want: []string{ClusterPrefix + "c", "b", "a"},
+		},
+		{
+			name: "namespaced before cluster on equal priority",
+			sources: []Source{
+				source(ClusterPrefix+"a", "", 0),
+				source("z", "default", 0),
+			},
+			want: []string{"z", ClusterPrefix + "a"},
+		},
+		{
+			name: "name breaks ties",
+			sources: []Source{
+				source("tracing", "default", 5),
+				source("tenant", "default", 5),
+			},
+			want: []string{"tenant", "tracing"},
+		},
+		{
+			name: "negative priority last",
+			sources: []Source{
+				source("fallback", "default", -1),
+				source(ClusterPrefix+"platform", "", 0),
+			},
+			want: []string{ClusterPrefix + "platform", "fallback"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Sort(tt.sources)
+			assert.Equal(t, tt.want, names(tt.sources))
+		})
+	}
+}
+
+func TestConflicts(t *testing.T) {
+	tenant := source("tenant", "default", 10, "x-tenant-id", "X-Request-Id")
+	tracing := source("tracing", "default", 0, "x-request-id", "x-trace-id")
+	platform := source(ClusterPrefix+"platform", "", 0, "X-TENANT-ID", "x-trace-id")
+
+	conflicts := Conflicts([]Source{platform, tracing, tenant})
+	require.Len(t, conflicts, 3)
+
+	assert.Equal(t, "X-Request-Id", conflicts[0].Header, "the winner's spelling is reported")
+	assert.Equal(t, "tenant", conflicts[0].Winner.Name)
+	assert.Equal(t, "tracing", conflicts[0].Loser.Name)
+
+	assert.Equal(t, "x-tenant-id", conflicts[1].Header)
+	assert.Equal(t, "tenant", conflicts[1].Winner.Name)
+	assert.Equal(t, ClusterPrefix+"platform", conflicts[1].Loser.Name)
+
+	assert.Equal(t, "x-trace-id", conflicts[2].Header)
+	assert.Equal(t, "tracing", conflicts[2].Winner.Name, "namespaced policy wins the tie")
+	assert.Equal(t, ClusterPrefix+"platform", conflicts[2].Loser.Name)
+}
+
+func TestConflicts_SamePolicy(t *testing.T) {
+	single := source("api", "default", 0, "x-request-id")
+	single.Spec.PropagationRules = append(single.Spec.PropagationRules, ctxforgev1beta1.PropagationRule{
+		PathRegex: "^/admin/",
+		Headers:   []ctxforgev1beta1.HeaderConfig{{Name: "x-request-id"}},
+	})
+
+	assert.Empty(t, Conflicts([]Source{single}))
+}
+
+func TestSource_Selects(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Namespace: "payments",
+		Labels:    map[string]string{"app": "api"},
+	}}
+	paymentsLabels := labels.Set{"team": "payments"}
+
+	tests := []struct {
+		name    string
+		source  Source
+		want    bool
+		wantErr bool
+	}{
+		{name: "namespaced policy in the pod's namespace", source: source("a", "payments", 0), want: true},
+		{name: "namespaced policy in another namespace", source: source("a", "default", 0), want: false},
+		{name: "cluster policy without selectors", source: source(ClusterPrefix+"a", "", 0), want: true},
+		{
+			name: "cluster policy selecting the namespace",
+			source: func() Source {
+				s := source(ClusterPrefix+"a", "", 0)
+				s.Spec.NamespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"team": "payments"}}
+				s.Spec.PodSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}}
+				return s
+			}(),
+			want: true,
+		},
+		{
+			name: "cluster policy selecting other namespaces",
+			source: func() Source {
+				s := source(ClusterPrefix+"a", "", 0)
+				s.Spec.NamespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"team": "search"}}
+				return s
+			}(),
+			want: false,
+		},
+		{
+			name: "pod selector not matching",
+			source: func() Source {
+				s := source("a", "payments", 0)
+				s.Spec.PodSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+				return s
+			}(),
+			want: false,
+		},
+		{
+			name: "invalid selector",
+			source: func() Source {
+				s := source("a", "payments", 0)
+				s.Spec.PodSelector = &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "app", Operator: "Bogus"},
+				}}
+				return s
+			}(),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.source.Selects(pod, paymentsLabels)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    ctxforgev1beta1.PropagationRule
+		wantErr string
+	}{
+		{
+			name: "valid rule",
+			rule: ctxforgev1beta1.PropagationRule{
+				PathRegex: "^/api/",
+				Methods:   []string{"get", "POST"},
+				Headers:   []ctxforgev1beta1.HeaderConfig{{Name: "x-request-id", Generate: true, GeneratorType: "ulid"}},
+			},
+		},
+		{
+			name: "invalid path regex",
+			rule: ctxforgev1beta1.PropagationRule{
+				PathRegex: "^/api/(",
+				Headers:   []ctxforgev1beta1.HeaderConfig{{Name: "x-request-id"}},
+			},
+			wantErr: "invalid path regex",
+		},
+		{
+			name: "unknown method",
+			rule: ctxforgev1beta1.PropagationRule{
+				Methods: []string{"FETCH"},
+				Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-request-id"}},
+			},
+			wantErr: "invalid HTTP method",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(ctxforgev1beta1.HeaderPropagationPolicySpec{
+				PropagationRules: []ctxforgev1beta1.PropagationRule{tt.rule},
+			})
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestRuleErrors(t *testing.T) {
+	spec := ctxforgev1beta1.HeaderPropagationPolicySpec{
+		PropagationRules: []ctxforgev1beta1.PropagationRule{
+			{Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-request-id"}}},
+			{PathRegex: "^/api/(", Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-tenant-id"}}},
+			{Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-trace-id", Generate: true, GeneratorType: "snowflake"}}},
+		},
+		ResponseRules: []ctxforgev1beta1.ResponseRule{
+			{Echo: []string{"x-request-id"}},
+			{Methods: []string{"FETCH"}, Strip: []string{"server"}},
+		},
+	}
+
+	ruleErrors := RuleErrors(spec)
+	require.Len(t, ruleErrors, 3)
+	assert.Equal(t, int32(1), ruleErrors[0].Rule)
+	assert.Contains(t, ruleErrors[0].Message, "invalid path regex")
+	assert.Equal(t, int32(2), ruleErrors[1].Rule)
+	assert.Contains(t, ruleErrors[1].Message, "snowflake")
+	assert.Equal(t, ctxforgev1beta1.RuleError{Rule: 1, Response: true, Message: ruleErrors[2].Message}, ruleErrors[2])
+	assert.Contains(t, ruleErrors[2].Message, "invalid HTTP method")
+
+	assert.Empty(t, RuleErrors(ctxforgev1beta1.HeaderPropagationPolicySpec{PropagationRules: spec.PropagationRules[:1]}))
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"encoding/json"
+
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+	"github.com/bgruszka/contextforge/internal/config"
+)
+
+// Validate checks that the proxy accepts the policy's rules, parsing them the
+// way it parses HEADER_RULES: path regexes must compile and methods, header
+// names and generator types must be known.
+func Validate(spec ctxforgev1beta1.HeaderPropagationPolicySpec) error {
+	rules := Merge([]Source{{Spec: spec}}).Rules
+	if len(rules) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(rules)
+	if err != nil {
+		return err
+	}
+	_, err = config.ParseHeaderRules(string(data))
+	return err
+}
+
+// RuleErrors validates each propagation and response rule of the policy on
+// its own, like Validate, so that errors are reported against the rule
+// causing them.
+func RuleErrors(spec ctxforgev1beta1.HeaderPropagationPolicySpec) []ctxforgev1beta1.RuleError {
+	var ruleErrors []ctxforgev1beta1.RuleError
+	for i, rule := range spec.PropagationRules {
+		single := spec
+		single.PropagationRules = []ctxforgev1beta1.PropagationRule{rule}
+		single.ResponseRules = nil
+		if err := Validate(single); err != nil {
+			ruleErrors = append(ruleErrors, ctxforgev1beta1.RuleError{Rule: int32(i), Message: err.Error()})
+		}
+	}
+	for i, rule := range spec.ResponseRules {
+		single := spec
+		single.PropagationRules = nil
+		single.ResponseRules = []ctxforgev1beta1.ResponseRule{rule}
+		if err := Validate(single); err != nil {
+			ruleErrors = append(ruleErrors, ctxforgev1beta1.RuleError{Rule: int32(i), Response: true, Message: err.Error()})
+		}
+	}
+	return ruleErrors
+}
@@ -0,0 +1,43 @@
+package rulestream
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// Subscribe opens a rule stream for node on conn and calls apply for every
+// snapshot received, acknowledging it when apply succeeds and rejecting it
+// with the error otherwise. version is the version the proxy runs, from an
+// earlier subscription, so the operator doesn't resend it. Subscribe returns
+// when the stream ends, with the version the proxy runs by then.
+func Subscribe(ctx context.Context, conn grpc.ClientConnInterface, node Node, version string, apply func(Snapshot) error) (string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := conn.NewStream(ctx, &serviceDesc.Streams[0], "/"+ServiceName+"/"+streamRulesMethod,
+		grpc.CallContentSubtype(codecName))
+	if err != nil {
+		return version, err
+	}
+	if err := stream.SendMsg(&Request{Node: node, VersionInfo: version}); err != nil {
+		return version, err
+	}
+
+	for {
+		var snapshot Snapshot
+		if err := stream.RecvMsg(&snapshot); err != nil {
+			return version, err
+		}
+		req := Request{Node: node, ResponseNonce: snapshot.Nonce}
+		if err := apply(snapshot); err != nil {
+			req.VersionInfo, req.ErrorDetail = version, err.Error()
+		} else {
+			version = snapshot.Version
+			req.VersionInfo = version
+		}
+		if err := stream.SendMsg(&req); err != nil {
+			return version, err
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// Package rulestream implements the streaming service through which the
+// operator distributes header rules to running proxies. Each proxy subscribes
+// with the identity of its pod and receives versioned snapshots of its rules,
+// which it acknowledges (ACK) or rejects (NACK) on the same stream, in the
+// style of Envoy's xDS protocol.
+package rulestream
+
+import (
+	"encoding/json"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+
+	"github.com/bgruszka/contextforge/internal/config"
+)
+
+// ServiceName is the gRPC service name of the rule stream.
+const ServiceName = "ctxforge.rulestream.v1.RuleDiscovery"
+
+// codecName is the gRPC content subtype of rule stream messages. They are
+// encoded as JSON, so the protocol needs no generated code.
+const codecName = "json"
+
+// Node identifies the pod a proxy runs in.
+type Node struct {
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+}
+
+// String returns the node as namespace/pod.
+func (n Node) String() string {
+	return n.Namespace + "/" + n.Pod
+}
+
+// Request is sent by a proxy to subscribe, and again for every snapshot it
+// receives: as an ACK with VersionInfo set to the snapshot's version, or as a
+// NACK with ErrorDetail set and VersionInfo left at the last applied version.
+type Request struct {
+	Node Node `json:"node"`
+	// VersionInfo is the version of the last snapshot the proxy applied.
+	VersionInfo string `json:"versionInfo,omitempty"`
+	// ResponseNonce echoes the Nonce of the snapshot being answered.
+	ResponseNonce string `json:"responseNonce,omitempty"`
+	// ErrorDetail explains why the proxy rejected the snapshot.
+	ErrorDetail string `json:"errorDetail,omitempty"`
+}
+
+// Snapshot is a versioned set of header rules for one node.
+type Snapshot struct {
+	// Version is the checksum the proxy reports on /config once it applied
+	// the snapshot.
+	Version string `json:"version"`
+	// Nonce identifies this message, so requests can refer to it.
+	Nonce string `json:"nonce"`
+	// HeaderRules holds rules in HEADER_RULES format. Empty reverts the proxy
+	// to the configuration it was injected with.
+	HeaderRules string `json:"headerRules,omitempty"`
+}
+
+// Version returns the snapshot version of the given rules.
+func Version(headerRules string) string {
+	return config.ConfigChecksum("", headerRules)
+}
+
+// jsonCodec encodes rule stream messages as JSON.
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+func (jsonCodec) Name() string                       { return codecName }
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
+
+// streamRulesMethod is the bidirectional streaming method of the service.
+const streamRulesMethod = "StreamRules"
+
+// ruleDiscoveryServer is implemented by Server.
+type ruleDiscoveryServer interface {
+	streamRules(stream grpc.ServerStream) error
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*ruleDiscoveryServer)(nil),
+	Streams: []grpc.StreamDesc{{
+		StreamName: streamRulesMethod,
+		Handler: func(srv any, stream grpc.ServerStream) error {
+			return srv.(ruleDiscoveryServer).streamRules(stream)
+		},
+		ServerStreams: true,
+		ClientStreams: true,
+	}},
+}
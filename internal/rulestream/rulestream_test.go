@@ -0,0 +1,110 @@
+package rulestream
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func startServer(t *testing.T) (*Server, *grpc.ClientConn) {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	srv := NewServer()
+	g := grpc.NewServer()
+	srv.Register(g)
+	go func() { _ = g.Serve(listener) }()
+	t.Cleanup(g.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return srv, conn
+}
+
+func receive(t *testing.T, snapshots <-chan Snapshot) Snapshot {
+	t.Helper()
+	select {
+	case snapshot := <-snapshots:
+		return snapshot
+	case <-time.After(2 * time.Second):
+		t.Fatal("no snapshot received")
+		return Snapshot{}
+	}
+}
+
+func TestStreamRules(t *testing.T) {
+	srv, conn := startServer(t)
+	node := Node{Namespace: "default", Pod: "api"}
+	other := Node{Namespace: "default", Pod: "worker"}
+	srv.SetRules(node, `[{"name":"x-tenant-id"}]`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	snapshots := make(chan Snapshot, 4)
+	go func() {
+		_, _ = Subscribe(ctx, conn, node, "", func(snapshot Snapshot) error {
+			snapshots <- snapshot
+			if snapshot.HeaderRules == "invalid" {
+				return errors.New("invalid JSON")
+			}
+			return nil
+		})
+	}()
+
+	// The current snapshot is sent on subscription and acknowledged
+	snapshot := receive(t, snapshots)
+	assert.Equal(t, `[{"name":"x-tenant-id"}]`, snapshot.HeaderRules)
+	assert.Equal(t, Version(snapshot.HeaderRules), snapshot.Version)
+	assert.Eventually(t, func() bool { return srv.AckedVersion(node) == snapshot.Version },
+		2*time.Second, 10*time.Millisecond)
+
+	// Rejected rules leave the acknowledged version in place
+	srv.SetRules(node, "invalid")
+	assert.Equal(t, "invalid", receive(t, snapshots).HeaderRules)
+	srv.SetRules(other, `[{"name":"x-user-id"}]`)
+	srv.SetRules(node, "")
+	assert.Empty(t, receive(t, snapshots).HeaderRules, "only the node's own snapshots are streamed")
+	assert.Eventually(t, func() bool { return srv.AckedVersion(node) == Version("") },
+		2*time.Second, 10*time.Millisecond)
+
+	srv.Delete(node)
+	_, ok := srv.Snapshot(node)
+	assert.False(t, ok)
+	assert.Empty(t, srv.AckedVersion(node))
+}
+
+func TestSubscribe_ResumesVersion(t *testing.T) {
+	srv, conn := startServer(t)
+	node := Node{Namespace: "default", Pod: "api"}
+	srv.SetRules(node, `[{"name":"x-tenant-id"}]`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	applied := 0
+	version, err := Subscribe(ctx, conn, node, Version(`[{"name":"x-tenant-id"}]`), func(Snapshot) error {
+		applied++
+		return nil
+	})
+	require.Error(t, err)
+	assert.Zero(t, applied, "the version the proxy already runs is not resent")
+	assert.Equal(t, Version(`[{"name":"x-tenant-id"}]`), version)
+	assert.Equal(t, version, srv.AckedVersion(node))
+}
+
+func TestStreamRules_RequiresNode(t *testing.T) {
+	_, conn := startServer(t)
+	_, err := Subscribe(context.Background(), conn, Node{Pod: "api"}, "", func(Snapshot) error { return nil })
+	assert.ErrorContains(t, err, "node namespace and pod are required")
+}
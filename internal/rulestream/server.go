@@ -0,0 +1,227 @@
+package rulestream
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+
+	"github.com/go-logr/logr"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Server holds the latest rule snapshot of every node and streams it to the
+// node's subscribed proxies. Snapshots are set by the operator's live config
+// controller; a node without a snapshot keeps its injected configuration.
+type Server struct {
+	// Log receives subscription and NACK messages; the zero value discards them.
+	Log logr.Logger
+
+	mu        sync.Mutex
+	snapshots map[Node]Snapshot
+	acked     map[Node]string
+	watchers  map[Node]map[chan struct{}]struct{}
+	nonce     uint64
+}
+
+// NewServer returns a Server without snapshots.
+func NewServer() *Server {
+	return &Server{
+		snapshots: make(map[Node]Snapshot),
+		acked:     make(map[Node]string),
+		watchers:  make(map[Node]map[chan struct{}]struct{}),
+	}
+}
+
+// Register adds the rule stream service to a gRPC server.
+func (s *Server) Register(g *grpc.Server) {
+	g.RegisterService(&serviceDesc, s)
+}
+
+// Serve listens on address and serves the rule stream until ctx is done.
+func (s *Server) Serve(ctx context.Context, address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	g := grpc.NewServer()
+	s.Register(g)
+	go func() {
+		<-ctx.Done()
+		g.GracefulStop()
+	}()
+	s.Log.Info("Serving rule stream", "address", address)
+	return g.Serve(listener)
+}
+
+// SetRules publishes the node's header rules; subscribed proxies receive them
+// unless they already run this version.
+func (s *Server) SetRules(node Node, headerRules string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	version := Version(headerRules)
+	if current, ok := s.snapshots[node]; ok && current.Version == version {
+		return
+	}
+	s.snapshots[node] = Snapshot{Version: version, HeaderRules: headerRules}
+	for watcher := range s.watchers[node] {
+		select {
+		case watcher <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Delete forgets the node once its pod is gone.
+func (s *Server) Delete(node Node) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.snapshots, node)
+	delete(s.acked, node)
+}
+
+// Snapshot returns the node's latest snapshot, if one was set.
+func (s *Server) Snapshot(node Node) (Snapshot, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshot, ok := s.snapshots[node]
+	return snapshot, ok
+}
+
+// AckedVersion returns the version the node's proxy last acknowledged.
+func (s *Server) AckedVersion(node Node) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.acked[node]
+}
+
+// subscribe registers a watcher notified when the node's snapshot changes.
+func (s *Server) subscribe(node Node) (chan struct{}, func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	watcher := make(chan struct{}, 1)
+	if s.watchers[node] == nil {
+		s.watchers[node] = make(map[chan struct{}]struct{})
+	}
+	s.watchers[node][watcher] = struct{}{}
+	return watcher, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.watchers[node], watcher)
+		if len(s.watchers[node]) == 0 {
+			delete(s.watchers, node)
+		}
+	}
+}
+
+// next returns the node's snapshot with a fresh nonce, if it differs from
+// the version the proxy runs.
+func (s *Server) next(node Node, running string) (Snapshot, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshot, ok := s.snapshots[node]
+	if !ok || snapshot.Version == running {
+		return Snapshot{}, false
+	}
+	s.nonce++
+	snapshot.Nonce = strconv.FormatUint(s.nonce, 10)
+	return snapshot, true
+}
+
+// ack records the version a node's proxy applied.
+func (s *Server) ack(node Node, version string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.acked[node] = version
+}
+
+// streamRules serves one proxy: it sends the node's snapshot whenever it
+// changes and records the proxy's ACKs and NACKs.
+func (s *Server) streamRules(stream grpc.ServerStream) error {
+	var first Request
+	if err := stream.RecvMsg(&first); err != nil {
+		return err
+	}
+	node := first.Node
+	if node.Namespace == "" || node.Pod == "" {
+		return status.Error(codes.InvalidArgument, "node namespace and pod are required")
+	}
+	log := s.Log.WithValues("node", node.String())
+	log.V(1).Info("Proxy subscribed to rule stream", "version", first.VersionInfo)
+
+	watcher, unsubscribe := s.subscribe(node)
+	defer unsubscribe()
+
+	requests := make(chan Request)
+	recvErr := make(chan error, 1)
+	go func() {
+		for {
+			var req Request
+			if err := stream.RecvMsg(&req); err != nil {
+				recvErr <- err
+				return
+			}
+			select {
+			case requests <- req:
+			case <-stream.Context().Done():
+				return
+			}
+		}
+	}()
+
+	// running is the version the proxy runs, so a reconnecting proxy isn't
+	// resent it; sent is the last snapshot awaiting an answer.
+	running := first.VersionInfo
+	var sent Snapshot
+	if running != "" {
+		s.ack(node, running)
+	}
+	send := func() error {
+		snapshot, ok := s.next(node, running)
+		if !ok || (sent.Nonce != "" && snapshot.Version == sent.Version) {
+			return nil
+		}
+		sent = snapshot
+		return stream.SendMsg(&snapshot)
+	}
+	if err := send(); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case err := <-recvErr:
+			if errors.Is(err, io.EOF) || status.Code(err) == codes.Canceled {
+				return nil
+			}
+			return err
+		case <-watcher:
+			if err := send(); err != nil {
+				return err
+			}
+		case req := <-requests:
+			if req.ResponseNonce != sent.Nonce {
+				// Answers a snapshot superseded by one already sent
+				continue
+			}
+			sent = Snapshot{}
+			if req.ErrorDetail != "" {
+				log.Info("Proxy rejected header rules", "version", req.VersionInfo, "error", req.ErrorDetail)
+				continue
+			}
+			running = req.VersionInfo
+			s.ack(node, running)
+			// Rules may have changed again while the proxy applied the last ones
+			if err := send(); err != nil {
+				return err
+			}
+		}
+	}
+}
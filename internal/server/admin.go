@@ -0,0 +1,79 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+)
+
+// registerPprof exposes the net/http/pprof handlers on the admin mux, guarded by
+// the admin auth token.
+func registerPprof(mux *http.ServeMux, token string) {
+	mux.Handle("/debug/pprof/", requireAdminToken(token, http.HandlerFunc(pprof.Index)))
+	mux.Handle("/debug/pprof/cmdline", requireAdminToken(token, http.HandlerFunc(pprof.Cmdline)))
+	mux.Handle("/debug/pprof/profile", requireAdminToken(token, http.HandlerFunc(pprof.Profile)))
+	mux.Handle("/debug/pprof/symbol", requireAdminToken(token, http.HandlerFunc(pprof.Symbol)))
+	mux.Handle("/debug/pprof/trace", requireAdminToken(token, http.HandlerFunc(pprof.Trace)))
+}
+
+// requireAdminToken rejects requests that do not carry the admin token as a
+// bearer token. An empty token rejects every request.
+func requireAdminToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !hasBearerToken(r, token) {
+			unauthorized(w)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireAdminAccess guards the introspection and control endpoints of the
+// admin port, which any pod able to reach the pod IP could otherwise probe.
+// Requests from inside the pod, such as the preStop hook or kubectl
+// port-forward, are allowed unless trustLocal is false; others must carry one of
+// tokens as a bearer token.
+func requireAdminAccess(tokens []string, trustLocal bool, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !(trustLocal && isLocalCaller(r)) && !hasBearerToken(r, tokens...) {
+			unauthorized(w)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// hasBearerToken reports whether the request carries one of the non-empty
+// tokens as a bearer token.
+func hasBearerToken(r *http.Request, tokens ...string) bool {
+	provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	for _, token := range tokens {
+		if token != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(token)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// isLocalCaller reports whether the request comes from 127.0.0.1 or ::1. The
+// rest of the loopback range is not trusted: service mesh sidecars forward
+// inbound traffic from addresses such as 127.0.0.6, so remote callers reach
+// the admin port from there.
+func isLocalCaller(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && (ip.Equal(net.IPv4(127, 0, 0, 1)) || ip.Equal(net.IPv6loopback))
+}
+
+func unauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="ctxforge-admin"`)
+	http.Error(w, "unauthorized", http.StatusUnauthorized)
+}
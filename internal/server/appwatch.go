@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ignoredProcesses are processes in a shared process namespace that are not the
+// app: the pod's pause container and the proxies of service meshes, which
+// keep running until the pod is torn down.
+var ignoredProcesses = map[string]bool{
+	"pause":          true,
+	"envoy":          true,
+	"pilot-agent":    true,
+	"linkerd2-proxy": true,
+}
+
+// WatchAppExit returns a channel that is closed once the app processes in the
+// pod's shared process namespace have exited. The app counts as exited only
+// after at least one of its processes was seen, so a proxy that starts first
+// does not stop right away.
+func WatchAppExit(ctx context.Context, procRoot string, interval time.Duration) <-chan struct{} {
+	exited := make(chan struct{})
+	self := os.Getpid()
+	if self == 1 {
+		log.Warn().Msg("Process namespace is not shared with the app, the proxy cannot detect when the app exits")
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		seen := false
+		for {
+			count := countAppProcesses(procRoot, self)
+			if count > 0 && !seen {
+				seen = true
+				log.Info().Int("processes", count).Msg("Watching app processes")
+			}
+			if seen && count == 0 {
+				log.Info().Msg("App processes exited")
+				close(exited)
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return exited
+}
+
+// countAppProcesses counts the processes under procRoot other than PID 1,
+// ignoredProcesses and those in the proxy's own container, such as lifecycle
+// hooks, which share its mount namespace.
+func countAppProcesses(procRoot string, self int) int {
+	entries, err := os.ReadDir(procRoot)
+	if err != nil {
+		log.Debug().Err(err).Str("proc", procRoot).Msg("Failed to list processes")
+		return 0
+	}
+	selfMnt, _ := os.Readlink(filepath.Join(procRoot, strconv.Itoa(self), "ns", "mnt"))
+
+	count := 0
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || !entry.IsDir() || pid == 1 || pid == self {
+			continue
+		}
+		comm, err := os.ReadFile(filepath.Join(procRoot, entry.Name(), "comm"))
+		if err != nil {
+			// The process exited while listing
+			continue
+		}
+		if ignoredProcesses[strings.TrimSpace(string(comm))] {
+			continue
+		}
+		// Unreadable for processes of other users, which are never the proxy's own
+		if mnt, err := os.Readlink(filepath.Join(procRoot, entry.Name(), "ns", "mnt")); err == nil && mnt == selfMnt {
+			continue
+		}
+		count++
+	}
+	return count
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func addProcess(t *testing.T, root, pid, comm string) {
+	t.Helper()
+	dir := filepath.Join(root, pid)
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "comm"), []byte(comm+"\n"), 0o644))
+}
+
+func TestCountAppProcesses(t *testing.T) {
+	root := t.TempDir()
+	addProcess(t, root, "1", "pause")
+	addProcess(t, root, "7", "contextforge-proxy")
+	addProcess(t, root, "12", "python")
+	addProcess(t, root, "13", "envoy")
+	addProcess(t, root, "14", "sh")
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "sys"), 0o755))
+
+	assert.Equal(t, 2, countAppProcesses(root, 7))
+}
+
+func TestWatchAppExit(t *testing.T) {
+	root := t.TempDir()
+	addProcess(t, root, "1", "pause")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	exited := WatchAppExit(ctx, root, 10*time.Millisecond)
+
+	// No app process seen yet: the proxy keeps running
+	select {
+	case <-exited:
+		t.Fatal("exited before the app started")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	addProcess(t, root, "12", "python")
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case <-exited:
+		t.Fatal("exited while the app was running")
+	default:
+	}
+
+	require.NoError(t, os.RemoveAll(filepath.Join(root, "12")))
+	select {
+	case <-exited:
+	case <-time.After(time.Second):
+		t.Fatal("app exit was not detected")
+	}
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// drainPollInterval is how often /drain checks the number of in-flight requests.
+const drainPollInterval = 100 * time.Millisecond
+
+// DrainResponse represents the JSON response of the drain endpoint.
+type DrainResponse struct {
+	Status   string `json:"status"`
+	InFlight int64  `json:"inFlight"`
+}
+
+// drainer counts in-flight proxied requests and lets a preStop hook wait for
+// them before the proxy receives SIGTERM.
+type drainer struct {
+	inFlight atomic.Int64
+	draining atomic.Bool
+	delay    time.Duration
+	timeout  time.Duration
+}
+
+// track counts requests served by next as in flight until they complete.
+func (d *drainer) track(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		d.inFlight.Add(1)
+		defer d.inFlight.Add(-1)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ready wraps the readiness handler so a draining proxy reports not ready and
+// is taken out of service endpoints.
+func (d *drainer) ready(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !d.draining.Load() {
+			next(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_ = json.NewEncoder(w).Encode(ReadyResponse{
+			Status:    "draining",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+	}
+}
+
+// ServeHTTP puts the proxy into draining mode and blocks until in-flight
+// requests finish or the timeout expires. The proxy keeps serving new requests
+// meanwhile, since the app may still be completing work that calls out.
+// Only loopback callers are accepted: the endpoint is meant for the preStop
+// hook running inside the proxy container.
+func (d *drainer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !isLocalCaller(r) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	if d.draining.CompareAndSwap(false, true) {
+		log.Info().Dur("delay", d.delay).Dur("timeout", d.timeout).Msg("Draining proxy")
+	}
+
+	status := "drained"
+	select {
+	case <-time.After(d.delay):
+		deadline := time.After(d.timeout)
+		ticker := time.NewTicker(drainPollInterval)
+		defer ticker.Stop()
+	wait:
+		for d.inFlight.Load() > 0 {
+			select {
+			case <-ticker.C:
+			case <-deadline:
+				status = "timeout"
+				break wait
+			case <-r.Context().Done():
+				return
+			}
+		}
+	case <-r.Context().Done():
+		return
+	}
+
+	inFlight := d.inFlight.Load()
+	log.Info().Str("status", status).Int64("inFlight", inFlight).Msg("Drain finished")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(DrainResponse{Status: status, InFlight: inFlight})
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bgruszka/contextforge/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func drainRequest(remoteAddr string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/drain", nil)
+	req.RemoteAddr = remoteAddr
+	return req
+}
+
+func TestDrainer_WaitsForInFlightRequests(t *testing.T) {
+	d := &drainer{timeout: 5 * time.Second}
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	handler := d.track(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		close(started)
+		<-release
+	}))
+	go handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api", nil))
+	<-started
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		rr := httptest.NewRecorder()
+		d.ServeHTTP(rr, drainRequest("127.0.0.1:41000"))
+		done <- rr
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("drain returned while a request was still in flight")
+	case <-time.After(3 * drainPollInterval):
+	}
+
+	close(release)
+	rr := <-done
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var response DrainResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+	assert.Equal(t, "drained", response.Status)
+	assert.Zero(t, response.InFlight)
+}
+
+func TestDrainer_Timeout(t *testing.T) {
+	d := &drainer{timeout: 2 * drainPollInterval}
+	d.inFlight.Add(1)
+
+	rr := httptest.NewRecorder()
+	d.ServeHTTP(rr, drainRequest("[::1]:41000"))
+
+	var response DrainResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+	assert.Equal(t, "timeout", response.Status)
+	assert.Equal(t, int64(1), response.InFlight)
+}
+
+func TestDrainer_RejectsRemoteCallers(t *testing.T) {
+	d := &drainer{}
+
+	for _, addr := range []string{"10.0.0.5:41000", "127.0.0.6:41000"} {
+		rr := httptest.NewRecorder()
+		d.ServeHTTP(rr, drainRequest(addr))
+
+		assert.Equal(t, http.StatusForbidden, rr.Code, addr)
+	}
+	assert.False(t, d.draining.Load())
+}
+
+func TestServer_DrainMarksNotReady(t *testing.T) {
+	cfg := &config.ProxyConfig{
+		HeadersToPropagate: []string{"x-request-id"},
+		TargetHost:         "127.0.0.1:59999",
+		ProxyPort:          9090,
+		MetricsPort:        9091,
+	}
+	srv := NewServer(cfg, &mockHandler{})
+
+	rr := httptest.NewRecorder()
+	srv.adminMux.ServeHTTP(rr, drainRequest("127.0.0.1:41000"))
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	rr = httptest.NewRecorder()
+	srv.mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+
+	var response ReadyResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+	assert.Equal(t, "draining", response.Status)
+
+	// Requests are still proxied while draining
+	rr = httptest.NewRecorder()
+	srv.mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/test", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
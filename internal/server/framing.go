@@ -0,0 +1,281 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/bgruszka/contextforge/internal/metrics"
+)
+
+// maxScannedLine bounds the bytes buffered for a single request or header
+// line; longer lines are left to the HTTP server, which rejects them.
+const maxScannedLine = 64 << 10
+
+// framingListener wraps accepted connections in a framingConn.
+//
+// Go's HTTP server normalizes some ambiguous requests instead of rejecting
+// them: it drops Content-Length when Transfer-Encoding is also set and joins
+// obs-fold continuation lines. A target or intermediary that parses the raw
+// bytes differently could then see a different request than the proxy did, so
+// the raw request heads are inspected before the server parses them.
+type framingListener struct {
+	net.Listener
+}
+
+// Accept returns the next connection, wrapped to inspect its request heads.
+func (l framingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &framingConn{Conn: conn}, nil
+}
+
+// framingConn inspects the request heads read from a client connection and
+// records the rejection class of the first malformed one.
+type framingConn struct {
+	net.Conn
+	scanner  headScanner
+	rejected atomic.Pointer[string]
+}
+
+// Read reads from the connection, inspecting the bytes before the HTTP server
+// parses them.
+func (c *framingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 && c.rejected.Load() == nil {
+		if class := c.scanner.feed(p[:n]); class != "" {
+			c.rejected.Store(&class)
+			metrics.RecordRequestRejected(class)
+		}
+	}
+	return n, err
+}
+
+// rejection returns the rejection class of the connection, if any.
+func (c *framingConn) rejection() string {
+	if class := c.rejected.Load(); class != nil {
+		return *class
+	}
+	return ""
+}
+
+type contextKey string
+
+const contextKeyConn contextKey = "conn"
+
+// connContext stores the connection in the context of its requests.
+func connContext(ctx context.Context, c net.Conn) context.Context {
+	return context.WithValue(ctx, contextKeyConn, c)
+}
+
+// rejectMalformed answers requests arriving on a connection that sent a
+// malformed request head with 400 and closes the connection, as its remaining
+// bytes can no longer be framed reliably.
+func rejectMalformed(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, _ := r.Context().Value(contextKeyConn).(*framingConn)
+		if conn == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if class := conn.rejection(); class != "" {
+			log.Warn().
+				Str("class", class).
+				Str("remote_addr", r.RemoteAddr).
+				Msg("Rejecting malformed request")
+			w.Header().Set("Connection", "close")
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Scanner states.
+const (
+	scanRequestLine = iota
+	scanHeaders
+	scanBody
+	scanChunkSize
+	scanChunkData
+	scanChunkEnd
+	scanTrailers
+	scanDone
+)
+
+// headScanner follows the HTTP/1.1 framing of a client connection, skipping
+// request bodies, and reports the first request head that could be framed or
+// parsed differently by another implementation.
+type headScanner struct {
+	state int
+	line  []byte
+	// remaining is the number of body or chunk bytes left to skip
+	remaining int64
+
+	contentLength    []string
+	transferEncoding []string
+	upgrade          bool
+	connect          bool
+}
+
+// feed consumes the next bytes read from the connection and returns a
+// rejection class once a malformed request head is found.
+func (s *headScanner) feed(p []byte) string {
+	for len(p) > 0 && s.state != scanDone {
+		if s.state == scanBody || s.state == scanChunkData {
+			skip := min(int64(len(p)), s.remaining)
+			p = p[skip:]
+			s.remaining -= skip
+			if s.remaining == 0 {
+				if s.state == scanBody {
+					s.state = scanRequestLine
+				} else {
+					s.state = scanChunkEnd
+				}
+			}
+			continue
+		}
+
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			s.line = append(s.line, p...)
+			if len(s.line) > maxScannedLine {
+				s.state = scanDone
+			}
+			return ""
+		}
+		s.line = append(s.line, p[:i]...)
+		p = p[i+1:]
+		line := bytes.TrimSuffix(s.line, []byte("\r"))
+		class := s.scanLine(line)
+		s.line = s.line[:0]
+		if class != "" {
+			s.state = scanDone
+			return class
+		}
+	}
+	return ""
+}
+
+// scanLine processes a complete line, without its line terminator.
+func (s *headScanner) scanLine(line []byte) string {
+	switch s.state {
+	case scanRequestLine:
+		// Clients may send empty lines between requests
+		if len(line) == 0 {
+			return ""
+		}
+		// The HTTP/2 connection preface; the rest is not HTTP/1.1 framing
+		if bytes.HasPrefix(line, []byte("PRI * HTTP/2")) {
+			s.state = scanDone
+			return ""
+		}
+		s.contentLength = s.contentLength[:0]
+		s.transferEncoding = s.transferEncoding[:0]
+		s.upgrade = false
+		s.connect = bytes.HasPrefix(line, []byte("CONNECT "))
+		s.state = scanHeaders
+
+	case scanHeaders:
+		if len(line) == 0 {
+			return s.endHead()
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			return metrics.RejectObsFold
+		}
+		name, value, ok := bytes.Cut(line, []byte(":"))
+		if !ok {
+			// The HTTP server rejects it
+			s.state = scanDone
+			return ""
+		}
+		if bytes.ContainsAny(value, "\x00\r") {
+			return metrics.RejectInvalidValue
+		}
+		value = bytes.TrimSpace(value)
+		switch {
+		case bytes.EqualFold(name, []byte("Content-Length")):
+			s.contentLength = append(s.contentLength, string(value))
+		case bytes.EqualFold(name, []byte("Transfer-Encoding")):
+			s.transferEncoding = append(s.transferEncoding, string(value))
+		case bytes.EqualFold(name, []byte("Upgrade")):
+			s.upgrade = true
+		}
+
+	case scanChunkSize:
+		size, _, _ := bytes.Cut(line, []byte(";"))
+		n, err := strconv.ParseInt(string(bytes.TrimSpace(size)), 16, 64)
+		if err != nil || n < 0 {
+			// The HTTP server fails reading the body
+			s.state = scanDone
+			return ""
+		}
+		if n == 0 {
+			s.state = scanTrailers
+			return ""
+		}
+		s.remaining = n
+		s.state = scanChunkData
+
+	case scanChunkEnd:
+		if len(line) != 0 {
+			s.state = scanDone
+			return ""
+		}
+		s.state = scanChunkSize
+
+	case scanTrailers:
+		if len(line) == 0 {
+			s.state = scanRequestLine
+		}
+	}
+	return ""
+}
+
+// endHead decides how the body of the request whose head just ended is
+// framed.
+func (s *headScanner) endHead() string {
+	if len(s.transferEncoding) > 0 && len(s.contentLength) > 0 {
+		return metrics.RejectAmbiguousFraming
+	}
+	for i := 1; i < len(s.contentLength); i++ {
+		if s.contentLength[i] != s.contentLength[0] {
+			return metrics.RejectAmbiguousFraming
+		}
+	}
+
+	// After a protocol switch the connection no longer carries HTTP/1.1
+	// requests, and the server itself rejects codings other than chunked
+	chunked := len(s.transferEncoding) == 1 && strings.EqualFold(s.transferEncoding[0], "chunked")
+	if s.upgrade || s.connect || len(s.transferEncoding) > 0 && !chunked {
+		s.state = scanDone
+		return ""
+	}
+
+	switch {
+	case chunked:
+		s.state = scanChunkSize
+	case len(s.contentLength) > 0:
+		n, err := strconv.ParseInt(s.contentLength[0], 10, 64)
+		if err != nil || n < 0 {
+			s.state = scanDone
+			return ""
+		}
+		s.remaining = n
+		s.state = scanBody
+		if n == 0 {
+			s.state = scanRequestLine
+		}
+	default:
+		s.state = scanRequestLine
+	}
+	return ""
+}
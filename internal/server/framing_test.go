@@ -0,0 +1,92 @@
+package server
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bgruszka/contextforge/internal/config"
+	"github.com/bgruszka/contextforge/internal/metrics"
+)
+
+func TestHeadScanner(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{"plain requests", "GET / HTTP/1.1\r\nHost: x\r\n\r\nGET /b HTTP/1.1\r\nHost: x\r\n\r\n", ""},
+		{"content length and transfer encoding", "POST / HTTP/1.1\r\nHost: x\r\nContent-Length: 5\r\nTransfer-Encoding: chunked\r\n\r\n0\r\n\r\n", metrics.RejectAmbiguousFraming},
+		{"conflicting content lengths", "POST / HTTP/1.1\r\nHost: x\r\nContent-Length: 5\r\nContent-Length: 6\r\n\r\nhello", metrics.RejectAmbiguousFraming},
+		{"obs-fold", "GET / HTTP/1.1\r\nHost: x\r\nX-Request-Id: a\r\n b\r\n\r\n", metrics.RejectObsFold},
+		{"NUL in value", "GET / HTTP/1.1\r\nHost: x\r\nX-Request-Id: a\x00b\r\n\r\n", metrics.RejectInvalidValue},
+		{"bare CR in value", "GET / HTTP/1.1\r\nHost: x\r\nX-Request-Id: a\rX-Injected: 1\r\n\r\n", metrics.RejectInvalidValue},
+		{
+			"body looking like a malformed head",
+			"POST / HTTP/1.1\r\nHost: x\r\nContent-Length: 21\r\n\r\n\r\n folded: header\r\n\r\n" +
+				"GET / HTTP/1.1\r\nHost: x\r\n\r\n",
+			"",
+		},
+		{
+			"chunked body followed by a smuggled request",
+			"POST / HTTP/1.1\r\nHost: x\r\nTransfer-Encoding: chunked\r\n\r\n4;ext=1\r\n\r\n x\r\n0\r\nTrailer: 1\r\n\r\n" +
+				"GET / HTTP/1.1\r\nHost: x\r\nContent-Length: 0\r\nTransfer-Encoding: chunked\r\n\r\n",
+			metrics.RejectAmbiguousFraming,
+		},
+		{"upgraded connection", "GET / HTTP/1.1\r\nHost: x\r\nUpgrade: websocket\r\n\r\n\x00 raw\r\n frame", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var s headScanner
+			assert.Equal(t, tt.want, s.feed([]byte(tt.raw)))
+
+			// The outcome doesn't depend on how the bytes are split across reads
+			s = headScanner{}
+			var got string
+			for i := 0; i < len(tt.raw) && got == ""; i++ {
+				got = s.feed([]byte{tt.raw[i]})
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestServer_RejectsSmuggledRequests(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := NewServer(&config.ProxyConfig{}, &mockHandler{})
+	go func() { _ = srv.httpServer.Serve(framingListener{Listener: listener}) }()
+	defer func() { _ = srv.httpServer.Close() }()
+
+	send := func(raw string) *http.Response {
+		conn, err := net.Dial("tcp", listener.Addr().String())
+		require.NoError(t, err)
+		defer func() { _ = conn.Close() }()
+		_, err = conn.Write([]byte(raw))
+		require.NoError(t, err)
+		resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		return resp
+	}
+
+	assert.Equal(t, http.StatusOK, send("GET /api HTTP/1.1\r\nHost: x\r\n\r\n").StatusCode)
+
+	rejected := testutil.ToFloat64(metrics.RequestsRejectedTotal.WithLabelValues(metrics.RejectAmbiguousFraming))
+	resp := send("POST /api HTTP/1.1\r\nHost: x\r\nContent-Length: 5\r\nTransfer-Encoding: chunked\r\n\r\n0\r\n\r\n")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.True(t, resp.Close, "the connection is closed")
+	assert.Equal(t, rejected+1, testutil.ToFloat64(metrics.RequestsRejectedTotal.WithLabelValues(metrics.RejectAmbiguousFraming)))
+
+	resp = send("GET /api HTTP/1.1\r\nHost: x\r\nX-Request-Id: a\r\n\tb\r\n\r\n")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// Health checks on the proxy port aren't proxied and stay available
+	resp = send("GET /healthz HTTP/1.1\r\nHost: x\r\nX-Request-Id: a\r\n\tb\r\n\r\n")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
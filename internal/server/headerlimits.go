@@ -0,0 +1,83 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/bgruszka/contextforge/internal/audit"
+	"github.com/bgruszka/contextforge/internal/config"
+	"github.com/bgruszka/contextforge/internal/generator"
+	"github.com/bgruszka/contextforge/internal/metrics"
+)
+
+// headerLineOverhead is the ": " and CRLF framing each header field takes on
+// the wire.
+const headerLineOverhead = 4
+
+// limitHeaders answers requests with more header fields than maxCount, or
+// whose header fields take more than maxBytes, with 431 before they reach the
+// app. The response carries the request ID, generated if the client sent none,
+// so rejected requests can still be correlated, and is recorded in auditLog
+// with the largest header field. A zero limit is disabled.
+func limitHeaders(maxCount, maxBytes int, requestIDHeader string, auditLog *audit.Logger, next http.Handler) http.Handler {
+	if maxCount == 0 && maxBytes == 0 {
+		return next
+	}
+	requestIDHeader = http.CanonicalHeaderKey(requestIDHeader)
+	if requestIDHeader == "" {
+		requestIDHeader = config.DefaultRequestIDHeader
+	}
+	ids := &generator.UUIDGenerator{}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count, size := 0, 0
+		largest, largestSize := "", 0
+		for name, values := range r.Header {
+			count += len(values)
+			for _, value := range values {
+				field := len(name) + len(value) + headerLineOverhead
+				size += field
+				if field > largestSize {
+					largest, largestSize = name, field
+				}
+			}
+		}
+
+		var limit, reason string
+		switch {
+		case maxCount > 0 && count > maxCount:
+			limit, reason = metrics.HeaderLimitCount, audit.ReasonHeaderCount
+		case maxBytes > 0 && size > maxBytes:
+			limit, reason = metrics.HeaderLimitBytes, audit.ReasonHeaderBytes
+		default:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		requestID := r.Header.Get(requestIDHeader)
+		if requestID == "" {
+			requestID = ids.Generate()
+		}
+		metrics.RecordHeaderLimitRejected(limit)
+		auditLog.Record(audit.Event{
+			RequestID: requestID,
+			Action:    audit.ActionRejected,
+			Header:    largest,
+			Rule:      audit.NoRule,
+			Reason:    reason,
+			Method:    r.Method,
+			Path:      r.URL.Path,
+		})
+		log.Warn().
+			Str("request_id", requestID).
+			Str("limit", limit).
+			Int("header_count", count).
+			Int("header_bytes", size).
+			Str("largest_header", largest).
+			Str("remote_addr", r.RemoteAddr).
+			Msg("Rejecting request: header limit exceeded")
+		w.Header().Set(requestIDHeader, requestID)
+		http.Error(w, "Request Header Fields Too Large", http.StatusRequestHeaderFieldsTooLarge)
+	})
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bgruszka/contextforge/internal/audit"
+	"github.com/bgruszka/contextforge/internal/config"
+	"github.com/bgruszka/contextforge/internal/metrics"
+)
+
+func TestServer_HeaderLimits(t *testing.T) {
+	cfg := &config.ProxyConfig{
+		HeadersToPropagate: []string{"x-request-id"},
+		TargetHost:         "localhost:8080",
+		MaxHeaderCount:     5,
+		MaxHeaderBytes:     256,
+	}
+	srv := NewServer(cfg, &mockHandler{})
+
+	tests := []struct {
+		name    string
+		headers map[string][]string
+		limit   string
+	}{
+		{name: "within limits", headers: map[string][]string{"X-Request-Id": {"abc"}, "X-Tenant-Id": {"t1"}}},
+		{name: "too many fields", headers: map[string][]string{"X-Request-Id": {"abc"}, "X-Forwarded-For": {"a", "b", "c", "d", "e"}}, limit: metrics.HeaderLimitCount},
+		{name: "too large", headers: map[string][]string{"X-Request-Id": {"abc"}, "Cookie": {strings.Repeat("a", 256)}}, limit: metrics.HeaderLimitBytes},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api", nil)
+			for name, values := range tt.headers {
+				req.Header[name] = values
+			}
+			before := testutil.ToFloat64(metrics.HeaderLimitRejectedTotal.WithLabelValues(tt.limit))
+			rr := httptest.NewRecorder()
+
+			srv.mux.ServeHTTP(rr, req)
+
+			if tt.limit == "" {
+				assert.Equal(t, http.StatusOK, rr.Code)
+				return
+			}
+			assert.Equal(t, http.StatusRequestHeaderFieldsTooLarge, rr.Code)
+			assert.Equal(t, "abc", rr.Header().Get("X-Request-Id"))
+			assert.Equal(t, before+1, testutil.ToFloat64(metrics.HeaderLimitRejectedTotal.WithLabelValues(tt.limit)))
+		})
+	}
+}
+
+func TestServer_HeaderLimitGeneratesRequestID(t *testing.T) {
+	cfg := &config.ProxyConfig{
+		HeadersToPropagate: []string{"x-request-id"},
+		TargetHost:         "localhost:8080",
+		MaxHeaderCount:     1,
+		RequestIDHeader:    "x-correlation-id",
+	}
+	srv := NewServer(cfg, &mockHandler{})
+
+	req := httptest.NewRequest(http.MethodGet, "/api", nil)
+	req.Header.Set("X-Tenant-Id", "t1")
+	req.Header.Set("X-User-Id", "u1")
+	rr := httptest.NewRecorder()
+	srv.mux.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusRequestHeaderFieldsTooLarge, rr.Code)
+	assert.Len(t, rr.Header().Get("X-Correlation-Id"), 36)
+}
+
+func TestServer_HeaderLimitsDisabled(t *testing.T) {
+	srv := NewServer(&config.ProxyConfig{TargetHost: "localhost:8080"}, &mockHandler{})
+
+	req := httptest.NewRequest(http.MethodGet, "/api", nil)
+	req.Header.Set("Cookie", strings.Repeat("a", 100<<10))
+	rr := httptest.NewRecorder()
+	srv.mux.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Zero(t, srv.httpServer.MaxHeaderBytes, "Go's default limit applies")
+}
+
+// auditedHandler is a proxy handler keeping an audit log.
+type auditedHandler struct {
+	mockHandler
+	log *audit.Logger
+}
+
+func (h *auditedHandler) AuditLog() *audit.Logger {
+	return h.log
+}
+
+func TestServer_HeaderLimitAudited(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := &config.ProxyConfig{
+		TargetHost:     "localhost:8080",
+		MaxHeaderBytes: 256,
+	}
+	srv := NewServer(cfg, &auditedHandler{log: audit.NewWithWriter(&buf, nil)})
+
+	req := httptest.NewRequest(http.MethodPost, "/api", nil)
+	req.Header.Set("X-Request-Id", "abc")
+	req.Header.Set("Cookie", strings.Repeat("a", 256))
+	rr := httptest.NewRecorder()
+	srv.mux.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusRequestHeaderFieldsTooLarge, rr.Code)
+
+	var event map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &event))
+	assert.Equal(t, "rejected", event["action"])
+	assert.Equal(t, "Cookie", event["header"])
+	assert.Equal(t, audit.ReasonHeaderBytes, event["reason"])
+	assert.Equal(t, "abc", event["request_id"])
+	assert.Equal(t, http.MethodPost, event["method"])
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/bgruszka/contextforge/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+// WatchLiveConfig polls the downward API annotations file at path and calls
+// apply whenever the header rules pushed by the operator change. An empty
+// value means the operator withdrew its rules and the proxy should return to
+// its injected configuration. The file is read once before WatchLiveConfig
+// returns, so rules already present at startup apply before serving.
+func WatchLiveConfig(ctx context.Context, path string, interval time.Duration, apply func(rules string)) {
+	watchRules(ctx, path, interval, config.ReadLiveHeaderRules, apply)
+}
+
+// WatchRulesFile polls the rules file at path, mounted from the operator's
+// rules ConfigMap, and calls apply like WatchLiveConfig whenever its rules
+// change. A missing file applies as empty.
+func WatchRulesFile(ctx context.Context, path string, interval time.Duration, apply func(rules string)) {
+	watchRules(ctx, path, interval, config.ReadRulesFile, apply)
+}
+
+// watchRules reads rules from path with read, once synchronously and then
+// every interval, and calls apply when they change.
+func watchRules(ctx context.Context, path string, interval time.Duration, read func(string) (string, error), apply func(rules string)) {
+	current := ""
+	poll := func() {
+		rules, err := read(path)
+		if err != nil {
+			log.Warn().Err(err).Str("file", path).Msg("Failed to read live header rules")
+			return
+		}
+		if rules == current {
+			return
+		}
+		current = rules
+		apply(rules)
+	}
+	poll()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				poll()
+			}
+		}
+	}()
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeAnnotations(t *testing.T, path, rules string) {
+	t.Helper()
+	data := `ctxforge.io/injected="true"` + "\n"
+	if rules != "" {
+		data += `ctxforge.io/live-header-rules=` + strconv.Quote(rules) + "\n"
+	}
+	// Rename like the kubelet's atomic writer so the watcher never sees a partial file
+	tmp := path + ".tmp"
+	require.NoError(t, os.WriteFile(tmp, []byte(data), 0o644))
+	require.NoError(t, os.Rename(tmp, path))
+}
+
+func TestWatchLiveConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "annotations")
+	writeAnnotations(t, path, `[{"name":"x-tenant-id"}]`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	applied := make(chan string, 4)
+	WatchLiveConfig(ctx, path, 10*time.Millisecond, func(rules string) { applied <- rules })
+
+	// Rules present at startup apply synchronously
+	require.Len(t, applied, 1)
+	assert.Equal(t, `[{"name":"x-tenant-id"}]`, <-applied)
+
+	writeAnnotations(t, path, `[{"name":"x-user-id"}]`)
+	select {
+	case rules := <-applied:
+		assert.Equal(t, `[{"name":"x-user-id"}]`, rules)
+	case <-time.After(2 * time.Second):
+		t.Fatal("updated rules were not applied")
+	}
+
+	writeAnnotations(t, path, "")
+	select {
+	case rules := <-applied:
+		assert.Empty(t, rules)
+	case <-time.After(2 * time.Second):
+		t.Fatal("removed rules were not applied")
+	}
+
+	select {
+	case rules := <-applied:
+		t.Fatalf("unchanged rules applied again: %q", rules)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestWatchRulesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api-7d9f")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	applied := make(chan string, 4)
+	WatchRulesFile(ctx, path, 10*time.Millisecond, func(rules string) { applied <- rules })
+
+	// Without a key for the pod the injected rules stay in place
+	assert.Empty(t, applied)
+
+	require.NoError(t, os.WriteFile(path, []byte(`[{"name":"x-user-id"}]`), 0o644))
+	select {
+	case rules := <-applied:
+		assert.Equal(t, `[{"name":"x-user-id"}]`, rules)
+	case <-time.After(2 * time.Second):
+		t.Fatal("rules were not applied")
+	}
+
+	require.NoError(t, os.Remove(path))
+	select {
+	case rules := <-applied:
+		assert.Empty(t, rules)
+	case <-time.After(2 * time.Second):
+		t.Fatal("removed rules were not applied")
+	}
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/bgruszka/contextforge/internal/rulestream"
+)
+
+// maxRuleStreamBackoff caps the delay between rule stream reconnects.
+const maxRuleStreamBackoff = 30 * time.Second
+
+// StreamRules subscribes to the operator's rule stream at address as node and
+// calls apply with the header rules of every snapshot. An error from apply
+// rejects the snapshot. The stream is re-established with backoff until ctx
+// is done, resuming from the last applied version.
+func StreamRules(ctx context.Context, address string, node rulestream.Node, apply func(rules string) error) error {
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		defer func() { _ = conn.Close() }()
+
+		version := ""
+		backoff := time.Second
+		for {
+			received := false
+			var err error
+			version, err = rulestream.Subscribe(ctx, conn, node, version, func(snapshot rulestream.Snapshot) error {
+				received = true
+				return apply(snapshot.HeaderRules)
+			})
+			if ctx.Err() != nil {
+				return
+			}
+			if received {
+				backoff = time.Second
+			}
+			log.Warn().Err(err).Str("address", address).Dur("retry", backoff).Msg("Rule stream disconnected")
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(2*backoff, maxRuleStreamBackoff)
+		}
+	}()
+	return nil
+}
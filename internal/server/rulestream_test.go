@@ -0,0 +1,45 @@
+package server
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/bgruszka/contextforge/internal/rulestream"
+)
+
+func TestStreamRules(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	operator := rulestream.NewServer()
+	g := grpc.NewServer()
+	operator.Register(g)
+	go func() { _ = g.Serve(listener) }()
+	defer g.Stop()
+
+	node := rulestream.Node{Namespace: "default", Pod: "api"}
+	operator.SetRules(node, `[{"name":"x-tenant-id"}]`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	applied := make(chan string, 4)
+	require.NoError(t, StreamRules(ctx, listener.Addr().String(), node, func(rules string) error {
+		applied <- rules
+		return nil
+	}))
+
+	select {
+	case rules := <-applied:
+		assert.Equal(t, `[{"name":"x-tenant-id"}]`, rules)
+	case <-time.After(5 * time.Second):
+		t.Fatal("streamed rules were not applied")
+	}
+	assert.Eventually(t, func() bool {
+		return operator.AckedVersion(node) == rulestream.Version(`[{"name":"x-tenant-id"}]`)
+	}, 5*time.Second, 10*time.Millisecond)
+}
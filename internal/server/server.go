@@ -7,8 +7,10 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"sync/atomic"
 	"time"
 
+	"github.com/bgruszka/contextforge/internal/audit"
 	"github.com/bgruszka/contextforge/internal/config"
 	"github.com/bgruszka/contextforge/internal/metrics"
 	"github.com/bgruszka/contextforge/internal/middleware"
@@ -17,9 +19,13 @@ import (
 
 // Server represents the HTTP server for the proxy.
 type Server struct {
-	config     *config.ProxyConfig
-	httpServer *http.Server
-	mux        *http.ServeMux
+	config      *config.ProxyConfig
+	httpServer  *http.Server
+	mux         *http.ServeMux
+	adminServer *http.Server
+	adminMux    *http.ServeMux
+	drainer     *drainer
+	checksum    *atomic.Value
 }
 
 // HealthResponse represents the JSON response for health check endpoints.
@@ -36,17 +42,31 @@ type ReadyResponse struct {
 	Timestamp       string `json:"timestamp"`
 }
 
+// auditor is implemented by proxy handlers keeping an audit log, which
+// requests rejected before reaching them are recorded in too.
+type auditor interface {
+	AuditLog() *audit.Logger
+}
+
 // NewServer creates a new Server with the given configuration and proxy handler.
 func NewServer(cfg *config.ProxyConfig, proxyHandler http.Handler) *Server {
 	mux := http.NewServeMux()
+	drain := &drainer{delay: cfg.DrainDelay, timeout: cfg.DrainTimeout}
+	checksum := &atomic.Value{}
+	checksum.Store(cfg.ConfigChecksum)
 
 	mux.HandleFunc("/healthz", healthHandler)
-	mux.HandleFunc("/ready", readyHandler(cfg.TargetHost, cfg.TargetDialTimeout))
+	mux.HandleFunc("/ready", drain.ready(readyHandler(cfg.TargetHost, cfg.TargetDialTimeout)))
 	mux.Handle("/metrics", metrics.Handler())
 
 	// Apply rate limiting middleware if enabled
 	rateLimiter := middleware.NewRateLimiter(cfg.RateLimitEnabled, cfg.RateLimitRPS, cfg.RateLimitBurst)
-	handler := rateLimiter.Middleware(proxyHandler)
+	var auditLog *audit.Logger
+	if a, ok := proxyHandler.(auditor); ok {
+		auditLog = a.AuditLog()
+	}
+	handler := rejectMalformed(limitHeaders(cfg.MaxHeaderCount, cfg.MaxHeaderBytes, cfg.RequestIDHeader, auditLog,
+		rateLimiter.Middleware(proxyHandler)))
 
 	if cfg.RateLimitEnabled {
 		log.Info().
@@ -55,7 +75,7 @@ func NewServer(cfg *config.ProxyConfig, proxyHandler http.Handler) *Server {
 			Msg("Rate limiting enabled")
 	}
 
-	mux.Handle("/", handler)
+	mux.Handle("/", drain.track(handler))
 
 	httpServer := &http.Server{
 		Addr:              fmt.Sprintf(":%d", cfg.ProxyPort),
@@ -64,15 +84,62 @@ func NewServer(cfg *config.ProxyConfig, proxyHandler http.Handler) *Server {
 		WriteTimeout:      cfg.WriteTimeout,
 		IdleTimeout:       cfg.IdleTimeout,
 		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ConnContext:       connContext,
+	}
+	// Go's own limit answers 431 before the handler runs, without the request
+	// ID or the metric; keep it out of the way of a larger configured limit
+	if cfg.MaxHeaderBytes > http.DefaultMaxHeaderBytes {
+		httpServer.MaxHeaderBytes = cfg.MaxHeaderBytes
+	}
+
+	// The admin server exposes metrics and debugging endpoints on a separate
+	// port so they are not reachable through the proxied service port.
+	adminMux := http.NewServeMux()
+	adminMux.HandleFunc("/healthz", healthHandler)
+	adminMux.Handle("/metrics", metrics.Handler())
+	adminMux.Handle("/drain", drain)
+	// The operator may read the checksum with its own token, but nothing else
+	adminMux.Handle("/config", requireAdminAccess([]string{cfg.AdminAuthToken, cfg.AdminOperatorToken},
+		!cfg.Strict(), configHandler(checksum)))
+	if cfg.PprofEnabled {
+		registerPprof(adminMux, cfg.AdminAuthToken)
+		log.Warn().Int("port", cfg.MetricsPort).Msg("pprof endpoints enabled on admin port")
+	}
+
+	// No write timeout: CPU profiles and traces stream for longer than a
+	// typical proxied response.
+	adminServer := &http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.MetricsPort),
+		Handler:           adminMux,
+		ReadTimeout:       cfg.ReadTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
 	}
 
 	return &Server{
-		config:     cfg,
-		httpServer: httpServer,
-		mux:        mux,
+		config:      cfg,
+		httpServer:  httpServer,
+		mux:         mux,
+		adminServer: adminServer,
+		adminMux:    adminMux,
+		drainer:     drain,
+		checksum:    checksum,
 	}
 }
 
+// SetConfigChecksum replaces the checksum reported by the /config endpoint
+// after the header configuration is reloaded at runtime.
+func (s *Server) SetConfigChecksum(checksum string) {
+	s.checksum.Store(checksum)
+}
+
+// HandleAdmin registers a handler for the given pattern on the admin port,
+// restricted to local callers and holders of the admin token, or to the
+// latter alone in strict secure mode. It must be called before Start.
+func (s *Server) HandleAdmin(pattern string, handler http.Handler) {
+	s.adminMux.Handle(pattern, requireAdminAccess([]string{s.config.AdminAuthToken}, !s.config.Strict(), handler))
+}
+
 // Start begins listening for HTTP requests.
 // This method blocks until the server is shut down or an error occurs.
 func (s *Server) Start() error {
@@ -82,12 +149,27 @@ func (s *Server) Start() error {
 		Strs("headers", s.config.HeadersToPropagate).
 		Msg("Starting HTTP server")
 
-	return s.httpServer.ListenAndServe()
+	// A failing admin server must not take down request proxying
+	go func() {
+		log.Info().Str("addr", s.adminServer.Addr).Msg("Starting admin server")
+		if err := s.adminServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Error().Err(err).Str("addr", s.adminServer.Addr).Msg("Admin server failed")
+		}
+	}()
+
+	listener, err := net.Listen("tcp", s.httpServer.Addr)
+	if err != nil {
+		return err
+	}
+	return s.httpServer.Serve(framingListener{Listener: listener})
 }
 
 // Shutdown gracefully shuts down the server with the given context.
 func (s *Server) Shutdown(ctx context.Context) error {
 	log.Info().Msg("Shutting down HTTP server")
+	if err := s.adminServer.Shutdown(ctx); err != nil {
+		log.Warn().Err(err).Msg("Admin server did not shut down cleanly")
+	}
 	return s.httpServer.Shutdown(ctx)
 }
 
@@ -103,6 +185,20 @@ func healthHandler(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(response)
 }
 
+// ConfigResponse represents the JSON response of the config endpoint.
+type ConfigResponse struct {
+	Checksum string `json:"checksum"`
+}
+
+// configHandler reports the checksum of the loaded header configuration, which
+// the operator compares with the value it injected.
+func configHandler(checksum *atomic.Value) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(ConfigResponse{Checksum: checksum.Load().(string)})
+	}
+}
+
 // readyHandler returns a handler that checks if the target host is reachable.
 func readyHandler(targetHost string, dialTimeout time.Duration) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -217,3 +217,209 @@ func TestCheckTargetReachable(t *testing.T) {
 
 	assert.False(t, checkTargetReachable("127.0.0.1:59999", 2*time.Second))
 }
+
+func TestServer_AdminRoutes(t *testing.T) {
+	cfg := &config.ProxyConfig{
+		HeadersToPropagate: []string{"x-request-id"},
+		TargetHost:         "localhost:8080",
+		ProxyPort:          9090,
+		LogLevel:           "info",
+		MetricsPort:        9091,
+	}
+
+	srv := NewServer(cfg, &mockHandler{})
+	srv.HandleAdmin("/debug/requests", &mockHandler{})
+
+	assert.Equal(t, ":9091", srv.adminServer.Addr)
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+	}{
+		{name: "metrics", path: "/metrics", expectedStatus: http.StatusOK},
+		{name: "registered handler", path: "/debug/requests", expectedStatus: http.StatusOK},
+		{name: "config", path: "/config", expectedStatus: http.StatusOK},
+		{name: "proxy traffic is not served", path: "/api/v1/test", expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.RemoteAddr = "127.0.0.1:41000"
+			rr := httptest.NewRecorder()
+			srv.adminMux.ServeHTTP(rr, req)
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+		})
+	}
+}
+
+func TestServer_AdminAccess(t *testing.T) {
+	cfg := &config.ProxyConfig{
+		HeadersToPropagate: []string{"x-request-id"},
+		TargetHost:         "localhost:8080",
+		MetricsPort:        9091,
+		AdminAuthToken:     "s3cret",
+		AdminOperatorToken: "operator",
+	}
+	srv := NewServer(cfg, &mockHandler{})
+	srv.HandleAdmin("/debug/requests", &mockHandler{})
+
+	tests := []struct {
+		name           string
+		path           string
+		remoteAddr     string
+		authorization  string
+		expectedStatus int
+	}{
+		{name: "remote caller without token", path: "/config", remoteAddr: "10.0.0.5:41000", expectedStatus: http.StatusUnauthorized},
+		{name: "remote caller with wrong token", path: "/debug/requests", remoteAddr: "10.0.0.5:41000", authorization: "Bearer nope", expectedStatus: http.StatusUnauthorized},
+		{name: "remote caller with admin token", path: "/debug/requests", remoteAddr: "10.0.0.5:41000", authorization: "Bearer s3cret", expectedStatus: http.StatusOK},
+		{name: "operator token reads config", path: "/config", remoteAddr: "10.0.0.5:41000", authorization: "Bearer operator", expectedStatus: http.StatusOK},
+		{name: "operator token grants nothing else", path: "/debug/requests", remoteAddr: "10.0.0.5:41000", authorization: "Bearer operator", expectedStatus: http.StatusUnauthorized},
+		{name: "local caller", path: "/debug/requests", remoteAddr: "127.0.0.1:41000", expectedStatus: http.StatusOK},
+		{name: "local IPv6 caller", path: "/config", remoteAddr: "[::1]:41000", expectedStatus: http.StatusOK},
+		{name: "mesh inbound address is not local", path: "/config", remoteAddr: "127.0.0.6:41000", expectedStatus: http.StatusUnauthorized},
+		{name: "metrics stay open", path: "/metrics", remoteAddr: "10.0.0.5:41000", expectedStatus: http.StatusOK},
+		{name: "health stays open", path: "/healthz", remoteAddr: "10.0.0.5:41000", expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rr := httptest.NewRecorder()
+
+			srv.adminMux.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+		})
+	}
+}
+
+func TestServer_AdminAccessStrict(t *testing.T) {
+	cfg := &config.ProxyConfig{
+		HeadersToPropagate: []string{"x-request-id"},
+		TargetHost:         "localhost:8080",
+		MetricsPort:        9091,
+		AdminAuthToken:     "s3cret",
+		AdminOperatorToken: "operator",
+		SecureMode:         config.SecureModeStrict,
+	}
+	srv := NewServer(cfg, &mockHandler{})
+	srv.HandleAdmin("/debug/requests", &mockHandler{})
+
+	tests := []struct {
+		name           string
+		path           string
+		authorization  string
+		expectedStatus int
+	}{
+		{name: "local caller without token", path: "/debug/requests", expectedStatus: http.StatusUnauthorized},
+		{name: "local caller reading config", path: "/config", expectedStatus: http.StatusUnauthorized},
+		{name: "local caller with admin token", path: "/debug/requests", authorization: "Bearer s3cret", expectedStatus: http.StatusOK},
+		{name: "operator token reads config", path: "/config", authorization: "Bearer operator", expectedStatus: http.StatusOK},
+		{name: "health stays open", path: "/healthz", expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.RemoteAddr = "127.0.0.1:41000"
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rr := httptest.NewRecorder()
+
+			srv.adminMux.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+		})
+	}
+}
+
+func TestServer_Pprof(t *testing.T) {
+	cfg := &config.ProxyConfig{
+		HeadersToPropagate: []string{"x-request-id"},
+		TargetHost:         "localhost:8080",
+		ProxyPort:          9090,
+		LogLevel:           "info",
+		MetricsPort:        9091,
+		PprofEnabled:       true,
+		AdminAuthToken:     "s3cret",
+	}
+
+	srv := NewServer(cfg, &mockHandler{})
+
+	tests := []struct {
+		name           string
+		authorization  string
+		expectedStatus int
+	}{
+		{name: "missing token", expectedStatus: http.StatusUnauthorized},
+		{name: "wrong token", authorization: "Bearer nope", expectedStatus: http.StatusUnauthorized},
+		{name: "valid token", authorization: "Bearer s3cret", expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/debug/pprof/heap?debug=1", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rr := httptest.NewRecorder()
+
+			srv.adminMux.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+		})
+	}
+}
+
+func TestServer_PprofDisabledByDefault(t *testing.T) {
+	cfg := &config.ProxyConfig{
+		HeadersToPropagate: []string{"x-request-id"},
+		TargetHost:         "localhost:8080",
+		ProxyPort:          9090,
+		LogLevel:           "info",
+		MetricsPort:        9091,
+		AdminAuthToken:     "s3cret",
+	}
+
+	srv := NewServer(cfg, &mockHandler{})
+
+	req := httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	rr := httptest.NewRecorder()
+	srv.adminMux.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestServer_ConfigChecksum(t *testing.T) {
+	cfg := &config.ProxyConfig{
+		HeadersToPropagate: []string{"x-request-id"},
+		TargetHost:         "localhost:8080",
+		MetricsPort:        9091,
+		ConfigChecksum:     config.ConfigChecksum("x-request-id", ""),
+	}
+	srv := NewServer(cfg, &mockHandler{})
+
+	req := httptest.NewRequest(http.MethodGet, "/config", nil)
+	req.RemoteAddr = "127.0.0.1:41000"
+	rr := httptest.NewRecorder()
+	srv.adminMux.ServeHTTP(rr, req)
+
+	var response ConfigResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, cfg.ConfigChecksum, response.Checksum)
+
+	srv.SetConfigChecksum("live")
+	rr = httptest.NewRecorder()
+	srv.adminMux.ServeHTTP(rr, req)
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, "live", response.Checksum)
+}
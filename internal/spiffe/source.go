@@ -0,0 +1,183 @@
+package spiffe
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+// maxWatchBackoff caps the delay between Workload API reconnects.
+const maxWatchBackoff = 30 * time.Second
+
+// errNoSVID is returned while no SVID has been received yet.
+var errNoSVID = errors.New("no SVID received from the workload API yet")
+
+// WatchX509SVID opens an X.509 SVID stream on conn and calls update with the
+// workload's default SVID on every rotation. It returns when the stream ends.
+func WatchX509SVID(ctx context.Context, conn grpc.ClientConnInterface, update func(*SVID)) error {
+	ctx, cancel := context.WithCancel(metadata.AppendToOutgoingContext(ctx, securityHeader, "true"))
+	defer cancel()
+
+	desc := &grpc.StreamDesc{StreamName: fetchX509SVIDMethod, ServerStreams: true}
+	stream, err := conn.NewStream(ctx, desc, "/"+ServiceName+"/"+fetchX509SVIDMethod, grpc.ForceCodec(protoCodec{}))
+	if err != nil {
+		return err
+	}
+	if err := stream.SendMsg(&x509SVIDRequest{}); err != nil {
+		return err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+	for {
+		var resp x509SVIDResponse
+		if err := stream.RecvMsg(&resp); err != nil {
+			return err
+		}
+		update(resp.svids[0])
+	}
+}
+
+// Source keeps the workload's current X.509 SVID, watching the Workload API
+// for rotations. A nil Source has no SVID.
+type Source struct {
+	svid   atomic.Pointer[SVID]
+	conn   *grpc.ClientConn
+	cancel context.CancelFunc
+}
+
+// NewSource watches the Workload API at endpoint, a SPIFFE_ENDPOINT_SOCKET
+// address, until Close. The stream is re-established with backoff, keeping
+// the last SVID meanwhile.
+func NewSource(endpoint string) (*Source, error) {
+	target, err := Target(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Source{conn: conn, cancel: cancel}
+	go func() {
+		backoff := time.Second
+		for {
+			received := false
+			err := WatchX509SVID(ctx, conn, func(svid *SVID) {
+				if previous := s.svid.Swap(svid); previous == nil || previous.ID != svid.ID {
+					log.Info().Str("spiffe_id", svid.ID).Time("expiry", svid.Certificate.Leaf.NotAfter).
+						Msg("Received workload SVID")
+				}
+				received = true
+			})
+			if ctx.Err() != nil {
+				return
+			}
+			if received {
+				backoff = time.Second
+			}
+			log.Warn().Err(err).Str("endpoint", endpoint).Dur("retry", backoff).Msg("Workload API stream disconnected")
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(2*backoff, maxWatchBackoff)
+		}
+	}()
+	return s, nil
+}
+
+// SVID returns the current SVID, or nil before the first one is received.
+func (s *Source) SVID() *SVID {
+	if s == nil {
+		return nil
+	}
+	return s.svid.Load()
+}
+
+// ID returns the current SPIFFE ID, or "" before the first SVID is received.
+func (s *Source) ID() string {
+	if svid := s.SVID(); svid != nil {
+		return svid.ID
+	}
+	return ""
+}
+
+// Close stops watching the Workload API.
+func (s *Source) Close() error {
+	if s == nil {
+		return nil
+	}
+	s.cancel()
+	return s.conn.Close()
+}
+
+// ClientTLSConfig returns a TLS configuration presenting the current SVID as
+// client certificate and accepting servers with an SVID of the same trust
+// domain, verified against the current bundle. Host names are not checked:
+// SVIDs identify workloads by SPIFFE ID, not by DNS name.
+func (s *Source) ClientTLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			svid := s.SVID()
+			if svid == nil {
+				return nil, errNoSVID
+			}
+			return &svid.Certificate, nil
+		},
+		// The chain is verified against the bundle in VerifyPeerCertificate
+		InsecureSkipVerify: true, // nolint:gosec
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			return s.verifyPeer(rawCerts)
+		},
+	}
+}
+
+// verifyPeer checks that a peer's chain leads to the current bundle and that
+// its leaf carries a SPIFFE ID of the source's trust domain.
+func (s *Source) verifyPeer(rawCerts [][]byte) error {
+	svid := s.SVID()
+	if svid == nil {
+		return errNoSVID
+	}
+	if len(rawCerts) == 0 {
+		return errors.New("peer presented no certificate")
+	}
+	certs := make([]*x509.Certificate, len(rawCerts))
+	for i, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return fmt.Errorf("invalid peer certificate: %w", err)
+		}
+		certs[i] = cert
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	if _, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         svid.Bundle,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}); err != nil {
+		return fmt.Errorf("peer SVID not issued by trust domain %s: %w", svid.TrustDomain(), err)
+	}
+	if len(certs[0].URIs) != 1 || trustDomain(certs[0].URIs[0].String()) != svid.TrustDomain() {
+		return fmt.Errorf("peer certificate has no SPIFFE ID of trust domain %s", svid.TrustDomain())
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+// Package spiffe is a client of the SPIFFE Workload API, through which an
+// agent such as SPIRE's hands a workload its X.509 SVID: its SPIFFE ID, the
+// certificate and key proving it, and the bundle of its trust domain. The
+// few messages used are encoded by hand, so the client needs no generated
+// code.
+package spiffe
+
+import (
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// ServiceName is the gRPC service name of the Workload API.
+const ServiceName = "SpiffeWorkloadAPI"
+
+// fetchX509SVIDMethod streams the workload's X.509 SVIDs, a new response on
+// every rotation.
+const fetchX509SVIDMethod = "FetchX509SVID"
+
+// securityHeader is the gRPC metadata every Workload API call must carry, so
+// that agents can tell workload calls from requests forwarded by a proxy.
+const securityHeader = "workload.spiffe.io"
+
+// SVID is an X.509 SVID with the bundle of its trust domain.
+type SVID struct {
+	// ID is the SPIFFE ID, e.g. spiffe://example.org/ns/default/sa/api.
+	ID string
+	// Certificate holds the SVID's chain, leaf first, and private key.
+	Certificate tls.Certificate
+	// Bundle holds the trust domain's root certificates.
+	Bundle *x509.CertPool
+}
+
+// TrustDomain returns the trust domain of the SVID's ID, e.g. example.org.
+func (s *SVID) TrustDomain() string {
+	return trustDomain(s.ID)
+}
+
+// trustDomain returns the host of a SPIFFE ID, or "" if id isn't one.
+func trustDomain(id string) string {
+	u, err := url.Parse(id)
+	if err != nil || u.Scheme != "spiffe" {
+		return ""
+	}
+	return u.Host
+}
+
+// x509SVID mirrors the X509SVID message of the Workload API.
+type x509SVID struct {
+	spiffeID string // 1
+	certs    []byte // 2, ASN.1 DER certificates, leaf first
+	key      []byte // 3, PKCS#8 DER private key
+	bundle   []byte // 4, ASN.1 DER certificates
+}
+
+// parseX509SVIDResponse returns the SVIDs of an X509SVIDResponse, the
+// workload's default one first.
+func parseX509SVIDResponse(b []byte) ([]*SVID, error) {
+	var svids []*SVID
+	err := consumeFields(b, func(num protowire.Number, typ protowire.Type, value []byte) error {
+		if num != 1 || typ != protowire.BytesType {
+			return nil
+		}
+		var raw x509SVID
+		err := consumeFields(value, func(num protowire.Number, typ protowire.Type, value []byte) error {
+			if typ != protowire.BytesType {
+				return nil
+			}
+			switch num {
+			case 1:
+				raw.spiffeID = string(value)
+			case 2:
+				raw.certs = value
+			case 3:
+				raw.key = value
+			case 4:
+				raw.bundle = value
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		svid, err := raw.parse()
+		if err != nil {
+			return err
+		}
+		svids = append(svids, svid)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(svids) == 0 {
+		return nil, errors.New("workload API response holds no SVID")
+	}
+	return svids, nil
+}
+
+// parse checks and decodes the SVID's certificates and key.
+func (raw x509SVID) parse() (*SVID, error) {
+	if trustDomain(raw.spiffeID) == "" {
+		return nil, fmt.Errorf("invalid SPIFFE ID %q", raw.spiffeID)
+	}
+	certs, err := x509.ParseCertificates(raw.certs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid certificates of %s: %w", raw.spiffeID, err)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificate for %s", raw.spiffeID)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(raw.key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key of %s: %w", raw.spiffeID, err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("private key of %s can't sign", raw.spiffeID)
+	}
+	roots, err := x509.ParseCertificates(raw.bundle)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bundle of %s: %w", raw.spiffeID, err)
+	}
+
+	svid := &SVID{
+		ID:     raw.spiffeID,
+		Bundle: x509.NewCertPool(),
+		Certificate: tls.Certificate{
+			PrivateKey: signer,
+			Leaf:       certs[0],
+		},
+	}
+	for _, cert := range certs {
+		svid.Certificate.Certificate = append(svid.Certificate.Certificate, cert.Raw)
+	}
+	for _, root := range roots {
+		svid.Bundle.AddCert(root)
+	}
+	return svid, nil
+}
+
+// consumeFields calls fn with every field of a protobuf message, the value
+// of length-delimited ones included.
+func consumeFields(b []byte, fn func(num protowire.Number, typ protowire.Type, value []byte) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		var value []byte
+		if typ == protowire.BytesType {
+			value, n = protowire.ConsumeBytes(b)
+		} else {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		if err := fn(num, typ, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// x509SVIDRequest is the empty X509SVIDRequest message.
+type x509SVIDRequest struct{}
+
+// x509SVIDResponse holds the SVIDs of an X509SVIDResponse message.
+type x509SVIDResponse struct {
+	svids []*SVID
+}
+
+// protoCodec encodes the Workload API messages in the protobuf wire format.
+type protoCodec struct{}
+
+func (protoCodec) Marshal(v any) ([]byte, error) {
+	switch v.(type) {
+	case *x509SVIDRequest:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("cannot marshal %T", v)
+	}
+}
+
+func (protoCodec) Unmarshal(data []byte, v any) error {
+	switch m := v.(type) {
+	case *x509SVIDResponse:
+		svids, err := parseX509SVIDResponse(data)
+		m.svids = svids
+		return err
+	default:
+		return fmt.Errorf("cannot unmarshal %T", v)
+	}
+}
+
+func (protoCodec) Name() string { return "proto" }
+
+// Target returns the gRPC target of a SPIFFE_ENDPOINT_SOCKET address, which
+// is either unix:///path/to/socket or tcp://ip:port.
+func Target(endpoint string) (string, error) {
+	switch {
+	case strings.HasPrefix(endpoint, "unix:///"):
+		return endpoint, nil
+	case strings.HasPrefix(endpoint, "tcp://"):
+		host := strings.TrimPrefix(endpoint, "tcp://")
+		if host == "" || strings.Contains(host, "/") {
+			return "", fmt.Errorf("invalid workload API address %q (e.g., tcp://127.0.0.1:8081)", endpoint)
+		}
+		return "passthrough:///" + host, nil
+	default:
+		return "", fmt.Errorf("invalid workload API address %q (must be unix:///path or tcp://ip:port)", endpoint)
+	}
+}
@@ -0,0 +1,270 @@
+package spiffe
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// testCA issues SVIDs of a trust domain.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T, trustDomain string) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: trustDomain},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		URIs:                  []*url.URL{{Scheme: "spiffe", Host: trustDomain}},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key}
+}
+
+// issue returns the X509SVID message of a new SVID for id.
+func (ca *testCA) issue(t *testing.T, id string) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	uri, err := url.Parse(id)
+	require.NoError(t, err)
+	der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		URIs:         []*url.URL{uri},
+	}, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	var svid []byte
+	svid = protowire.AppendTag(svid, 1, protowire.BytesType)
+	svid = protowire.AppendString(svid, id)
+	svid = protowire.AppendTag(svid, 2, protowire.BytesType)
+	svid = protowire.AppendBytes(svid, der)
+	svid = protowire.AppendTag(svid, 3, protowire.BytesType)
+	svid = protowire.AppendBytes(svid, pkcs8)
+	svid = protowire.AppendTag(svid, 4, protowire.BytesType)
+	svid = protowire.AppendBytes(svid, ca.cert.Raw)
+	svid = protowire.AppendTag(svid, 5, protowire.BytesType)
+	svid = protowire.AppendString(svid, "internal")
+	return svid
+}
+
+// response wraps X509SVID messages into an X509SVIDResponse.
+func response(svids ...[]byte) []byte {
+	var b []byte
+	for _, svid := range svids {
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, svid)
+	}
+	// federated_bundles, which the client ignores
+	b = protowire.AppendTag(b, 3, protowire.BytesType)
+	b = protowire.AppendBytes(b, []byte{})
+	return b
+}
+
+// rawCodec passes the fake agent's messages through unchanged.
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v any) ([]byte, error)      { return *v.(*[]byte), nil }
+func (rawCodec) Unmarshal(data []byte, v any) error { *v.(*[]byte) = data; return nil }
+func (rawCodec) Name() string                       { return "proto" }
+
+// startAgent serves the Workload API on a unix socket, streaming the
+// responses sent on the returned channel, and returns its address.
+func startAgent(t *testing.T) (string, chan<- []byte) {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+
+	responses := make(chan []byte, 4)
+	g := grpc.NewServer(grpc.ForceServerCodec(rawCodec{}))
+	g.RegisterService(&grpc.ServiceDesc{
+		ServiceName: ServiceName,
+		HandlerType: (*any)(nil),
+		Streams: []grpc.StreamDesc{{
+			StreamName:    fetchX509SVIDMethod,
+			ServerStreams: true,
+			Handler: func(_ any, stream grpc.ServerStream) error {
+				md, _ := metadata.FromIncomingContext(stream.Context())
+				if len(md.Get(securityHeader)) != 1 || md.Get(securityHeader)[0] != "true" {
+					return status.Error(codes.InvalidArgument, "security header missing from request")
+				}
+				var req []byte
+				if err := stream.RecvMsg(&req); err != nil {
+					return err
+				}
+				for {
+					select {
+					case resp := <-responses:
+						if err := stream.SendMsg(&resp); err != nil {
+							return err
+						}
+					case <-stream.Context().Done():
+						return nil
+					}
+				}
+			},
+		}},
+	}, struct{}{})
+	go func() { _ = g.Serve(listener) }()
+	t.Cleanup(g.Stop)
+	return "unix://" + socket, responses
+}
+
+func TestSource(t *testing.T) {
+	ca := newTestCA(t, "example.org")
+	endpoint, responses := startAgent(t)
+
+	source, err := NewSource(endpoint)
+	require.NoError(t, err)
+	defer func() { _ = source.Close() }()
+
+	assert.Empty(t, source.ID(), "no SVID before the agent answers")
+
+	responses <- response(ca.issue(t, "spiffe://example.org/ns/default/sa/api"), ca.issue(t, "spiffe://example.org/other"))
+	assert.Eventually(t, func() bool { return source.ID() == "spiffe://example.org/ns/default/sa/api" },
+		5*time.Second, 10*time.Millisecond, "the default SVID is the first one")
+	svid := source.SVID()
+	assert.Equal(t, "example.org", svid.TrustDomain())
+	require.NotNil(t, svid.Certificate.Leaf)
+	assert.Len(t, svid.Certificate.Certificate, 1)
+
+	// Rotations replace the SVID
+	responses <- response(ca.issue(t, "spiffe://example.org/ns/default/sa/api-v2"))
+	assert.Eventually(t, func() bool { return source.ID() == "spiffe://example.org/ns/default/sa/api-v2" },
+		5*time.Second, 10*time.Millisecond)
+
+	var nilSource *Source
+	assert.Empty(t, nilSource.ID())
+	assert.NoError(t, nilSource.Close())
+}
+
+func TestParseX509SVIDResponse(t *testing.T) {
+	ca := newTestCA(t, "example.org")
+
+	svids, err := parseX509SVIDResponse(response(ca.issue(t, "spiffe://example.org/api")))
+	require.NoError(t, err)
+	require.Len(t, svids, 1)
+	assert.Equal(t, "spiffe://example.org/api", svids[0].ID)
+
+	_, err = parseX509SVIDResponse(response())
+	assert.ErrorContains(t, err, "no SVID")
+
+	_, err = parseX509SVIDResponse(response(ca.issue(t, "https://example.org/api")))
+	assert.ErrorContains(t, err, "invalid SPIFFE ID")
+
+	_, err = parseX509SVIDResponse([]byte{0x0a, 0x05})
+	assert.Error(t, err, "truncated messages are rejected")
+}
+
+func TestTarget(t *testing.T) {
+	tests := []struct {
+		endpoint    string
+		expected    string
+		expectedErr string
+	}{
+		{endpoint: "unix:///run/spire/agent.sock", expected: "unix:///run/spire/agent.sock"},
+		{endpoint: "tcp://127.0.0.1:8081", expected: "passthrough:///127.0.0.1:8081"},
+		{endpoint: "tcp://", expectedErr: "invalid workload API address"},
+		{endpoint: "/run/spire/agent.sock", expectedErr: "must be unix:///path or tcp://ip:port"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.endpoint, func(t *testing.T) {
+			target, err := Target(tt.endpoint)
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, target)
+		})
+	}
+}
+
+func TestClientTLSConfig(t *testing.T) {
+	ca := newTestCA(t, "example.org")
+	endpoint, responses := startAgent(t)
+	source, err := NewSource(endpoint)
+	require.NoError(t, err)
+	defer func() { _ = source.Close() }()
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: source.ClientTLSConfig()}}
+	responses <- response(ca.issue(t, "spiffe://example.org/proxy"))
+	require.Eventually(t, func() bool { return source.SVID() != nil }, 5*time.Second, 10*time.Millisecond)
+
+	// startTarget serves HTTPS with an SVID for id issued by issuer, and
+	// requires a client certificate chaining to the example.org bundle.
+	startTarget := func(issuer *testCA, id string) *httptest.Server {
+		svids, err := parseX509SVIDResponse(response(issuer.issue(t, id)))
+		require.NoError(t, err)
+		roots := x509.NewCertPool()
+		roots.AddCert(ca.cert)
+		srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].URIs[0].String()))
+		}))
+		srv.TLS = &tls.Config{
+			Certificates: []tls.Certificate{svids[0].Certificate},
+			ClientCAs:    roots,
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+		}
+		srv.StartTLS()
+		t.Cleanup(srv.Close)
+		return srv
+	}
+
+	target := startTarget(ca, "spiffe://example.org/app")
+	resp, err := client.Get(target.URL)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var body [64]byte
+	n, _ := resp.Body.Read(body[:])
+	assert.Equal(t, "spiffe://example.org/proxy", string(body[:n]), "the proxy presents its SVID")
+
+	other := startTarget(newTestCA(t, "example.org"), "spiffe://example.org/app")
+	_, err = client.Get(other.URL)
+	assert.ErrorContains(t, err, "not issued by trust domain example.org")
+
+	foreign := startTarget(ca, "spiffe://evil.org/app")
+	_, err = client.Get(foreign.URL)
+	assert.ErrorContains(t, err, "no SPIFFE ID of trust domain example.org")
+
+	assert.ErrorContains(t, source.verifyPeer(nil), "peer presented no certificate")
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/bgruszka/contextforge/internal/config"
+)
+
+// AnnotationAdminAuth enables ("true") or disables ("false") the shared admin
+// token for a pod, overriding the operator default.
+const AnnotationAdminAuth = "ctxforge.io/admin-auth"
+
+const (
+	// adminTokenVolume is the in-memory volume shared by the proxy and the apps
+	adminTokenVolume = "ctxforge-admin-token"
+	// AdminTokenDir is where the admin token volume is mounted in all containers
+	AdminTokenDir = "/var/run/ctxforge/admin"
+	// AdminTokenFile is the file the proxy writes its generated admin token to
+	AdminTokenFile = AdminTokenDir + "/token"
+	// AdminTokenFileEnv tells app containers where to read the admin token
+	AdminTokenFileEnv = "CTXFORGE_ADMIN_TOKEN_FILE"
+	// OperatorTokenEnv holds the token the operator reads the proxy's /config with
+	OperatorTokenEnv = "ADMIN_OPERATOR_TOKEN"
+)
+
+// wantsAdminAuth reports whether the pod gets a shared admin token.
+func (d *PodCustomDefaulter) wantsAdminAuth(pod *corev1.Pod) bool {
+	if value, ok := pod.Annotations[AnnotationAdminAuth]; ok {
+		return value == AnnotationValueTrue
+	}
+	return d.AdminAuth || d.SecureMode == config.SecureModeStrict
+}
+
+// addAdminToken gives the sidecar an in-memory volume to write its generated
+// admin token to. The token never appears in the pod spec; app containers
+// mount the same volume read-only in addAppAdminToken.
+func (d *PodCustomDefaulter) addAdminToken(pod *corev1.Pod, sidecar *corev1.Container) {
+	if !d.wantsAdminAuth(pod) {
+		return
+	}
+	sizeLimit := resource.MustParse("1Mi")
+	pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+		Name: adminTokenVolume,
+		VolumeSource: corev1.VolumeSource{
+			EmptyDir: &corev1.EmptyDirVolumeSource{Medium: corev1.StorageMediumMemory, SizeLimit: &sizeLimit},
+		},
+	})
+	sidecar.VolumeMounts = append(sidecar.VolumeMounts, corev1.VolumeMount{
+		Name:      adminTokenVolume,
+		MountPath: AdminTokenDir,
+	})
+	sidecar.Env = append(sidecar.Env, corev1.EnvVar{Name: "ADMIN_AUTH_TOKEN_FILE", Value: AdminTokenFile})
+}
+
+// addAppAdminToken mounts the admin token volume read-only into an app
+// container and points it at the token file.
+func (d *PodCustomDefaulter) addAppAdminToken(pod *corev1.Pod, container *corev1.Container) {
+	if !d.wantsAdminAuth(pod) {
+		return
+	}
+	for _, mount := range container.VolumeMounts {
+		if mount.Name == adminTokenVolume || mount.MountPath == AdminTokenDir {
+			return
+		}
+	}
+	container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+		Name:      adminTokenVolume,
+		MountPath: AdminTokenDir,
+		ReadOnly:  true,
+	})
+	if findEnv(container.Env, AdminTokenFileEnv) < 0 {
+		container.Env = append(container.Env, corev1.EnvVar{Name: AdminTokenFileEnv, Value: AdminTokenFile})
+	}
+}
+
+// addOperatorToken gives the sidecar a random token that lets the operator
+// read its /config endpoint from outside the pod. The token is only valid for
+// /config, so reading it from the pod spec grants nothing else.
+func addOperatorToken(sidecar *corev1.Container) {
+	if findEnv(sidecar.Env, OperatorTokenEnv) >= 0 {
+		return
+	}
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	sidecar.Env = append(sidecar.Env, corev1.EnvVar{Name: OperatorTokenEnv, Value: hex.EncodeToString(buf)})
+}
+
+// OperatorToken returns the token the operator presents to the pod's proxy,
+// or "" for pods injected without one.
+func OperatorToken(pod *corev1.Pod) string {
+	sidecar := proxyContainer(pod)
+	if sidecar == nil {
+		return ""
+	}
+	if i := findEnv(sidecar.Env, OperatorTokenEnv); i >= 0 {
+		return sidecar.Env[i].Value
+	}
+	return ""
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestPodCustomDefaulter_AdminToken(t *testing.T) {
+	tests := []struct {
+		name        string
+		operator    bool
+		secureMode  string
+		annotation  string
+		expectToken bool
+	}{
+		{name: "disabled by default"},
+		{name: "operator default", operator: true, expectToken: true},
+		{name: "pod enables", annotation: "true", expectToken: true},
+		{name: "pod disables", operator: true, annotation: "false"},
+		{name: "strict secure mode", secureMode: "strict", expectToken: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage, AdminAuth: tt.operator, SecureMode: tt.secureMode}
+			pod := orderingPod("")
+			pod.Annotations[AnnotationSkipContainers] = "worker"
+			if tt.annotation != "" {
+				pod.Annotations[AnnotationAdminAuth] = tt.annotation
+			}
+
+			require.NoError(t, defaulter.Default(context.Background(), pod))
+
+			sidecar := proxyContainer(pod)
+			require.NotNil(t, sidecar)
+			app, worker := pod.Spec.Containers[0], pod.Spec.Containers[1]
+			if tt.secureMode != "" {
+				assert.Equal(t, tt.secureMode, sidecarEnv(t, pod, "SECURE_MODE"))
+			} else {
+				assert.Negative(t, findEnv(sidecar.Env, "SECURE_MODE"))
+			}
+			if !tt.expectToken {
+				assert.Negative(t, findEnv(sidecar.Env, "ADMIN_AUTH_TOKEN_FILE"))
+				assert.Empty(t, app.VolumeMounts)
+				return
+			}
+
+			assert.Equal(t, AdminTokenFile, sidecarEnv(t, pod, "ADMIN_AUTH_TOKEN_FILE"))
+			assert.Contains(t, sidecar.VolumeMounts, corev1.VolumeMount{Name: adminTokenVolume, MountPath: AdminTokenDir})
+			require.Len(t, pod.Spec.Volumes, 1)
+			assert.Equal(t, corev1.StorageMediumMemory, pod.Spec.Volumes[0].EmptyDir.Medium)
+
+			assert.Equal(t, []corev1.VolumeMount{{Name: adminTokenVolume, MountPath: AdminTokenDir, ReadOnly: true}},
+				app.VolumeMounts)
+			assert.Contains(t, app.Env, corev1.EnvVar{Name: AdminTokenFileEnv, Value: AdminTokenFile})
+			assert.Empty(t, worker.VolumeMounts)
+		})
+	}
+}
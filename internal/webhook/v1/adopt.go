@@ -0,0 +1,112 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/bgruszka/contextforge/internal/policy"
+)
+
+// LabelAdoptedBy is set on pods whose ctxforge-proxy container was added by
+// hand instead of by the webhook, naming the policy that took the pod over
+// (see AdoptedByLabelValue).
+const LabelAdoptedBy = "ctxforge.io/adopted-by"
+
+// adoptedByClusterPrefix marks a ClusterHeaderPropagationPolicy in the value
+// of LabelAdoptedBy. Policy names can't contain "_", so it can't be taken for
+// a HeaderPropagationPolicy.
+const adoptedByClusterPrefix = "cluster_"
+
+// AdoptedByLabelValue returns the LabelAdoptedBy value naming a policy, given
+// as its policy.Source name. Names longer than a label value allows are
+// shortened and suffixed with a hash to stay unique.
+func AdoptedByLabelValue(name string) string {
+	if cluster, ok := strings.CutPrefix(name, policy.ClusterPrefix); ok {
+		name = adoptedByClusterPrefix + cluster
+	}
+	if len(name) > validation.LabelValueMaxLength {
+		sum := sha256.Sum256([]byte(name))
+		name = name[:54] + "-" + hex.EncodeToString(sum[:4])
+	}
+	return name
+}
+
+// manuallyInjected reports whether the pod has the proxy container without
+// the webhook having injected it.
+func manuallyInjected(pod *corev1.Pod) bool {
+	return proxyContainer(pod) != nil && pod.Annotations[AnnotationInjected] == ""
+}
+
+// adopt takes a manually injected pod over on behalf of the policies
+// selecting it, unless the pod configures its headers itself: the pod is
+// labeled with the policy of highest precedence, and its sidecar's header env
+// rewritten to the policies' when patch is set, or reported as drift
+// otherwise. The label is removed once no policy selects the pod. It reports
+// whether the pod is adopted.
+func (d *PodCustomDefaulter) adopt(ctx context.Context, pod *corev1.Pod, ns *corev1.Namespace, patch bool) (bool, error) {
+	var policies *appliedPolicies
+	if len(d.extractHeaders(pod)) == 0 && d.extractHeaderRules(pod) == "" && pod.Annotations[AnnotationProfile] == "" {
+		var err error
+		if _, policies, err = d.headerRulesFromPolicies(ctx, pod, ns); err != nil {
+			return false, err
+		}
+	}
+	if policies == nil {
+		if _, ok := pod.Labels[LabelAdoptedBy]; ok {
+			podLogger(pod).Info("Releasing manually injected pod: no policy selects it")
+			delete(pod.Labels, LabelAdoptedBy)
+		}
+		return false, nil
+	}
+
+	owner := AdoptedByLabelValue(policies.Names[0])
+	if pod.Labels[LabelAdoptedBy] != owner {
+		podLogger(pod).Info("Adopting manually injected pod", "policy", policies.Names[0])
+		if pod.Labels == nil {
+			pod.Labels = make(map[string]string)
+		}
+		pod.Labels[LabelAdoptedBy] = owner
+	}
+	d.reconcileDrift(ctx, pod, patch)
+	return true, nil
+}
+
+// AdoptPod adopts a running, manually injected pod (see adopt) and reports
+// whether its labels or annotations changed. The sidecar of a running pod
+// can't be changed, so drift from the policies is only reported.
+func (d *PodCustomDefaulter) AdoptPod(ctx context.Context, pod *corev1.Pod) (bool, error) {
+	if !manuallyInjected(pod) {
+		return false, nil
+	}
+	ns := d.lookupNamespace(ctx, pod)
+	if !d.ownsPod(pod, ns) {
+		return false, nil
+	}
+
+	owner, drift := pod.Labels[LabelAdoptedBy], pod.Annotations[AnnotationConfigDrift]
+	if _, err := d.adopt(ctx, pod, ns, false); err != nil {
+		return false, err
+	}
+	return pod.Labels[LabelAdoptedBy] != owner || pod.Annotations[AnnotationConfigDrift] != drift, nil
+}
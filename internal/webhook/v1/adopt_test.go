@@ -0,0 +1,100 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+)
+
+// manualPod returns a pod with a hand-written proxy container
+func manualPod() *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "default", Labels: map[string]string{"app": "api"}},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{
+			{Name: "app", Image: "nginx"},
+			{Name: ProxyContainerName, Image: DefaultProxyImage, Env: []corev1.EnvVar{{Name: "HEADERS_TO_PROPAGATE", Value: "x-old"}}},
+		}},
+	}
+}
+
+func TestPodCustomDefaulter_AdoptsManualSidecarAtAdmission(t *testing.T) {
+	policy := newPolicy("tracing", map[string]string{"app": "api"}, ctxforgev1beta1.PropagationRule{
+		Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-request-id"}},
+	})
+	defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage, Client: newFakeClient(t, policy)}
+
+	pod := manualPod()
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+
+	assert.Equal(t, "tracing", pod.Labels[LabelAdoptedBy])
+	assert.Len(t, pod.Spec.Containers, 2, "no second sidecar is injected")
+	assert.Empty(t, sidecarEnv(t, pod, "HEADERS_TO_PROPAGATE"))
+	assert.Contains(t, sidecarEnv(t, pod, "HEADER_RULES"), "x-request-id")
+	assert.NotContains(t, pod.Annotations, AnnotationInjected)
+}
+
+func TestPodCustomDefaulter_AdoptPod(t *testing.T) {
+	ctx := context.Background()
+	policy := newPolicy("tracing", map[string]string{"app": "api"}, ctxforgev1beta1.PropagationRule{
+		Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-request-id"}},
+	})
+	c := newFakeClient(t, policy)
+	syncer := NewLiveConfigSyncer(c)
+
+	pod := manualPod()
+	changed, err := syncer.AdoptPod(ctx, pod)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, "tracing", pod.Labels[LabelAdoptedBy])
+	assert.Contains(t, pod.Annotations[AnnotationConfigDrift], "HEADERS_TO_PROPAGATE, HEADER_RULES")
+	assert.Equal(t, "x-old", sidecarEnv(t, pod, "HEADERS_TO_PROPAGATE"), "a running sidecar is left as is")
+
+	changed, err = syncer.AdoptPod(ctx, pod)
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	require.NoError(t, c.Delete(ctx, policy))
+	changed, err = syncer.AdoptPod(ctx, pod)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.NotContains(t, pod.Labels, LabelAdoptedBy)
+
+	injected := manualPod()
+	injected.Annotations = map[string]string{AnnotationInjected: AnnotationValueTrue}
+	changed, err = syncer.AdoptPod(ctx, injected)
+	require.NoError(t, err)
+	assert.False(t, changed, "pods injected by the webhook are not adopted")
+}
+
+func TestAdoptedByLabelValue(t *testing.T) {
+	assert.Equal(t, "tracing", AdoptedByLabelValue("tracing"))
+	assert.Equal(t, "cluster_tenant", AdoptedByLabelValue("clusterheaderpropagationpolicy/tenant"))
+
+	long := AdoptedByLabelValue(strings.Repeat("a", 70))
+	assert.Empty(t, validation.IsValidLabelValue(long))
+	assert.NotEqual(t, long, AdoptedByLabelValue(strings.Repeat("a", 71)))
+}
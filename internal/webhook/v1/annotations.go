@@ -0,0 +1,178 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"net"
+	"slices"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/bgruszka/contextforge/internal/config"
+)
+
+// annotationPrefix is the prefix shared by all ctxforge pod annotations
+const annotationPrefix = "ctxforge.io/"
+
+// knownPodAnnotations are the ctxforge.io/* annotations a pod may carry, either
+// set by users or written by the operator itself.
+var knownPodAnnotations = map[string]bool{
+	AnnotationEnabled:              true,
+	AnnotationHeaders:              true,
+	AnnotationHeaderRules:          true,
+	AnnotationTargetPort:           true,
+	AnnotationSkipContainers:       true,
+	AnnotationNoProxy:              true,
+	AnnotationRedirectMode:         true,
+	AnnotationRedirectExcludePorts: true,
+	AnnotationRedirectExcludeCIDRs: true,
+	AnnotationSidecarOrder:         true,
+	AnnotationTargetTLS:            true,
+	AnnotationTargetCA:             true,
+	AnnotationInjected:             true,
+	AnnotationPolicies:             true,
+	AnnotationConfigDrift:          true,
+	AnnotationDryRun:               true,
+	AnnotationDryRunResult:         true,
+	AnnotationConfigChecksum:       true,
+	AnnotationProfile:              true,
+	AnnotationMutateAppEnv:         true,
+	AnnotationAdminAuth:            true,
+	AnnotationSPIFFE:               true,
+	AnnotationContextSigningSecret: true,
+	AnnotationUnsignedContext:      true,
+	AnnotationProxyEphemeral:       true,
+	AnnotationLiveHeaderRules:      true,
+	AnnotationRestartedAt:          true,
+	AnnotationPolicyManaged:        true,
+}
+
+// validatePodAnnotations checks the ctxforge.io/* annotations of a pod so that
+// mistakes are rejected at admission instead of surfacing as a misconfigured
+// sidecar at runtime.
+func validatePodAnnotations(annotations map[string]string) field.ErrorList {
+	var allErrs field.ErrorList
+	fldPath := field.NewPath("metadata", "annotations")
+
+	keys := make([]string, 0, len(annotations))
+	for key := range annotations {
+		if strings.HasPrefix(key, annotationPrefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := annotations[key]
+		path := fldPath.Key(key)
+
+		switch key {
+		case AnnotationHeaders:
+			for _, header := range splitAnnotationList(value) {
+				if err := validateHeaderName(header); err != nil {
+					allErrs = append(allErrs, field.Invalid(path, value, err.Error()))
+				}
+			}
+		case AnnotationHeaderRules, AnnotationLiveHeaderRules:
+			if strings.TrimSpace(value) == "" {
+				continue
+			}
+			if err := validateHeaderRulesJSON(value); err != nil {
+				allErrs = append(allErrs, field.Invalid(path, value, err.Error()))
+			}
+		case AnnotationProfile:
+			names := splitAnnotationList(value)
+			if len(names) == 0 {
+				allErrs = append(allErrs, field.Invalid(path, value, "must list at least one profile"))
+			}
+			for _, name := range names {
+				if errs := validation.IsConfigMapKey(sidecarDefaultsProfilePrefix + name); len(errs) > 0 {
+					allErrs = append(allErrs, field.Invalid(path, value, "invalid profile name "+name))
+				}
+			}
+		case AnnotationTargetPort:
+			if value == "" {
+				continue
+			}
+			if err := validateTargetPort(value); err != nil {
+				allErrs = append(allErrs, field.Invalid(path, value, err.Error()))
+			}
+		case AnnotationRedirectMode:
+			if value != "" && value != RedirectModeIptables {
+				allErrs = append(allErrs, field.NotSupported(path, value, []string{RedirectModeIptables}))
+			}
+		case AnnotationSidecarOrder:
+			if value != SidecarOrderFirst && value != SidecarOrderLast {
+				allErrs = append(allErrs, field.NotSupported(path, value, []string{SidecarOrderFirst, SidecarOrderLast}))
+			}
+		case AnnotationTargetTLS, AnnotationDryRun, AnnotationMutateAppEnv, AnnotationAdminAuth,
+			AnnotationProxyEphemeral, AnnotationSPIFFE:
+			if value != AnnotationValueTrue && value != AnnotationValueFalse {
+				allErrs = append(allErrs, field.NotSupported(path, value, []string{AnnotationValueTrue, AnnotationValueFalse}))
+			}
+		case AnnotationTargetCA:
+			if _, _, err := parseTargetCA(value); err != nil {
+				allErrs = append(allErrs, field.Invalid(path, value, err.Error()))
+			}
+		case AnnotationContextSigningSecret:
+			if err := validateContextSigningSecret(value); err != nil {
+				allErrs = append(allErrs, field.Invalid(path, value, err.Error()))
+			}
+		case AnnotationUnsignedContext:
+			modes := []string{config.UnsignedContextAllow, config.UnsignedContextStrip, config.UnsignedContextReject}
+			if !slices.Contains(modes, value) {
+				allErrs = append(allErrs, field.NotSupported(path, value, modes))
+			}
+		case AnnotationRedirectExcludePorts:
+			for _, port := range splitAnnotationList(value) {
+				if err := validatePortNumber(port); err != nil {
+					allErrs = append(allErrs, field.Invalid(path, value, err.Error()))
+				}
+			}
+		case AnnotationRedirectExcludeCIDRs:
+			for _, cidr := range splitAnnotationList(value) {
+				if _, _, err := net.ParseCIDR(cidr); err != nil {
+					allErrs = append(allErrs, field.Invalid(path, value, "invalid CIDR "+cidr))
+				}
+			}
+		default:
+			if !knownPodAnnotations[key] {
+				allErrs = append(allErrs, field.Invalid(path, key, "unknown ctxforge.io annotation"))
+			}
+		}
+	}
+
+	return allErrs
+}
+
+// ctxforgeAnnotationsChanged checks if any ctxforge.io/* annotation differs between two sets
+func ctxforgeAnnotationsChanged(oldAnnotations, newAnnotations map[string]string) bool {
+	for key, value := range newAnnotations {
+		if strings.HasPrefix(key, annotationPrefix) && oldAnnotations[key] != value {
+			return true
+		}
+	}
+	for key := range oldAnnotations {
+		if _, ok := newAnnotations[key]; strings.HasPrefix(key, annotationPrefix) && !ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidatePodAnnotations(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		errorMsg    string
+	}{
+		{
+			name: "valid configuration",
+			annotations: map[string]string{
+				AnnotationEnabled:              "true",
+				AnnotationHeaders:              "x-request-id, x-tenant-id",
+				AnnotationTargetPort:           "3000",
+				AnnotationRedirectMode:         RedirectModeIptables,
+				AnnotationRedirectExcludePorts: "5432",
+				AnnotationRedirectExcludeCIDRs: "10.0.0.0/8",
+				AnnotationInjected:             "true",
+				"app.kubernetes.io/name":       "api",
+			},
+		},
+		{
+			name:        "invalid header name",
+			annotations: map[string]string{AnnotationHeaders: "x-request-id,x request"},
+			errorMsg:    `metadata.annotations[ctxforge.io/headers]: Invalid value: "x-request-id,x request"`,
+		},
+		{
+			name:        "malformed header rules",
+			annotations: map[string]string{AnnotationHeaderRules: `[{"name":`},
+			errorMsg:    "invalid JSON",
+		},
+		{
+			name:        "header rule with bad generator",
+			annotations: map[string]string{AnnotationHeaderRules: `[{"name":"x-request-id","generate":true,"generatorType":"random"}]`},
+			errorMsg:    `header "x-request-id": unknown generator type: random`,
+		},
+		{
+			name:        "header rule with unknown onExisting",
+			annotations: map[string]string{AnnotationHeaderRules: `[{"name":"x-request-id","onExisting":"bogus"}]`},
+			errorMsg:    `header "x-request-id": unknown onExisting "bogus"`,
+		},
+		{
+			name:        "header rule with incomplete transform",
+			annotations: map[string]string{AnnotationHeaderRules: `[{"name":"x-user-id","transform":{"type":"truncate"}}]`},
+			errorMsg:    `header "x-user-id"`,
+		},
+		{
+			name:        "header rule with invalid rename",
+			annotations: map[string]string{AnnotationHeaderRules: `[{"name":"x-user-id","rename":"x user"}]`},
+			errorMsg:    `header "x-user-id": rename`,
+		},
+		{
+			name:        "header rule with generated static value",
+			annotations: map[string]string{AnnotationHeaderRules: `[{"name":"x-env","staticValue":"prod","generate":true}]`},
+			errorMsg:    "staticValue and generate are mutually exclusive",
+		},
+		{
+			name:        "target port not a number",
+			annotations: map[string]string{AnnotationTargetPort: "http"},
+			errorMsg:    "must be a number",
+		},
+		{
+			name:        "target port is the proxy port",
+			annotations: map[string]string{AnnotationTargetPort: "9090"},
+			errorMsg:    "cannot be the same as proxy port",
+		},
+		{
+			name:        "target TLS not a boolean",
+			annotations: map[string]string{AnnotationTargetTLS: "yes"},
+			errorMsg:    `Unsupported value: "yes"`,
+		},
+		{
+			name:        "malformed target CA",
+			annotations: map[string]string{AnnotationTargetCA: "kube-root-ca.crt"},
+			errorMsg:    "must be configmap/<name> or secret/<name>",
+		},
+		{
+			name:        "invalid context signing secret",
+			annotations: map[string]string{AnnotationContextSigningSecret: "Signing_Keys"},
+			errorMsg:    `invalid Secret name "Signing_Keys"`,
+		},
+		{
+			name:        "unsupported unsigned context mode",
+			annotations: map[string]string{AnnotationUnsignedContext: "drop"},
+			errorMsg:    `Unsupported value: "drop"`,
+		},
+		{
+			name:        "unsupported redirect mode",
+			annotations: map[string]string{AnnotationRedirectMode: "ebpf"},
+			errorMsg:    `Unsupported value: "ebpf"`,
+		},
+		{
+			name:        "invalid redirect exclude CIDR",
+			annotations: map[string]string{AnnotationRedirectExcludeCIDRs: "10.0.0.0"},
+			errorMsg:    "invalid CIDR 10.0.0.0",
+		},
+		{
+			name:        "misspelled annotation",
+			annotations: map[string]string{"ctxforge.io/header": "x-request-id"},
+			errorMsg:    "unknown ctxforge.io annotation",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := validatePodAnnotations(tt.annotations)
+			if tt.errorMsg == "" {
+				assert.Empty(t, errs)
+				return
+			}
+			require.Len(t, errs, 1)
+			assert.Contains(t, errs.ToAggregate().Error(), tt.errorMsg)
+		})
+	}
+}
+
+func TestPodCustomValidator_RejectsInvalidAnnotations(t *testing.T) {
+	validator := &PodCustomValidator{}
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name: "api",
+		Annotations: map[string]string{
+			AnnotationTargetPort:  "abc",
+			"ctxforge.io/enable":  "true",
+			AnnotationHeaderRules: "{}",
+		},
+	}}
+
+	_, err := validator.ValidateCreate(context.Background(), pod)
+	require.Error(t, err)
+	assert.True(t, apierrors.IsInvalid(err))
+	assert.Contains(t, err.Error(), "ctxforge.io/target-port")
+	assert.Contains(t, err.Error(), "ctxforge.io/enable")
+	assert.Contains(t, err.Error(), "ctxforge.io/header-rules")
+}
+
+func TestPodCustomValidator_ValidateUpdate(t *testing.T) {
+	validator := &PodCustomValidator{}
+
+	oldPod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:        "api",
+		Annotations: map[string]string{"ctxforge.io/legacy": "true"},
+	}}
+
+	// Unrelated updates of pods admitted earlier are not blocked
+	newPod := oldPod.DeepCopy()
+	newPod.Finalizers = nil
+	newPod.Labels = map[string]string{"version": "2"}
+	_, err := validator.ValidateUpdate(context.Background(), oldPod, newPod)
+	assert.NoError(t, err)
+
+	// Changing ctxforge annotations validates all of them
+	newPod.Annotations[AnnotationHeaders] = "x-request-id"
+	_, err = validator.ValidateUpdate(context.Background(), oldPod, newPod)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "ctxforge.io/legacy")
+}
+
+func TestCtxforgeAnnotationsChanged(t *testing.T) {
+	base := map[string]string{AnnotationHeaders: "x-request-id", "other": "a"}
+
+	assert.False(t, ctxforgeAnnotationsChanged(base, map[string]string{AnnotationHeaders: "x-request-id", "other": "b"}))
+	assert.True(t, ctxforgeAnnotationsChanged(base, map[string]string{AnnotationHeaders: "x-tenant-id"}))
+	assert.True(t, ctxforgeAnnotationsChanged(base, map[string]string{"other": "a"}))
+	assert.True(t, ctxforgeAnnotationsChanged(nil, map[string]string{AnnotationEnabled: "true"}))
+}
@@ -0,0 +1,265 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+)
+
+// Reasons used on the Degraded condition reported by Capabilities.
+const (
+	CapabilityReasonPermissionsGranted = "PermissionsGranted"
+	CapabilityReasonMissingPermissions = "MissingPermissions"
+)
+
+const defaultCapabilityRetryInterval = 30 * time.Second
+
+// Permission is an access to the API that optional features depend on.
+type Permission struct {
+	// Name identifies the permission in metrics, e.g. patch_pods.
+	Name        string
+	Group       string
+	Resource    string
+	Subresource string
+	Verbs       []string
+	// Namespaced permissions are checked in every watched namespace.
+	Namespaced bool
+}
+
+// String describes the permission as RBAC rules do, e.g. "patch pods".
+func (p Permission) String() string {
+	resource := p.Resource
+	if p.Subresource != "" {
+		resource += "/" + p.Subresource
+	}
+	if p.Group != "" {
+		resource += "." + p.Group
+	}
+	return strings.Join(p.Verbs, "/") + " " + resource
+}
+
+var (
+	// PermissionPatchPods lets the operator push live configuration and
+	// policy annotations to running pods.
+	PermissionPatchPods = Permission{
+		Name: "patch_pods", Resource: "pods", Verbs: []string{"patch"}, Namespaced: true,
+	}
+	// PermissionUpdatePodStatus lets the operator set the proxy readiness gate.
+	PermissionUpdatePodStatus = Permission{
+		Name: "update_pod_status", Resource: "pods", Subresource: "status", Verbs: []string{"update"}, Namespaced: true,
+	}
+	// PermissionReadNamespaces lets the webhook and controllers use namespace
+	// labels and annotations.
+	PermissionReadNamespaces = Permission{
+		Name: "read_namespaces", Resource: "namespaces", Verbs: []string{"get", "list", "watch"},
+	}
+)
+
+var (
+	// permissionGranted is 1 for each permission the operator was found to hold.
+	permissionGranted = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ctxforge_operator_permission_granted",
+		Help: "Whether the operator holds a permission optional features depend on (1) or not (0), checked at startup.",
+	}, []string{"permission"})
+
+	// featureEnabled is 0 for each feature disabled for lack of permissions.
+	featureEnabled = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ctxforge_operator_feature_enabled",
+		Help: "Whether a feature depending on optional permissions is enabled (1) or disabled (0).",
+	}, []string{"feature"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(permissionGranted, featureEnabled)
+}
+
+// Capabilities records which optional permissions the operator holds, so
+// that features needing a missing one are disabled instead of failing at
+// runtime, e.g. the controllers waiting forever for a cache they may not
+// list. While any is missing, the operator reports itself Degraded through
+// the ctxforge_operator_degraded metric and the Degraded condition of the
+// OperatorHealth named contextforge.
+//
+// It implements manager.Runnable and runs on the leader only, the single
+// writer of the OperatorHealth. A nil Capabilities grants every permission.
+type Capabilities struct {
+	// Client writes the OperatorHealth.
+	Client client.Client
+	// Interval is how often a failed OperatorHealth update is retried.
+	// Defaults to 30 seconds.
+	Interval time.Duration
+
+	mu       sync.Mutex
+	missing  []Permission
+	disabled []string
+}
+
+// DetectCapabilities checks the permissions with SelfSubjectAccessReviews.
+// Namespaced permissions must be granted in each of namespaces, or cluster
+// wide when namespaces is empty. A permission whose review fails is assumed
+// to be granted, so that an unreachable API server doesn't disable features.
+func DetectCapabilities(ctx context.Context, c client.Client, namespaces []string, permissions ...Permission) *Capabilities {
+	log := logf.FromContext(ctx).WithName("capabilities")
+	caps := &Capabilities{Client: c}
+	for _, permission := range permissions {
+		scopes := []string{""}
+		if permission.Namespaced && len(namespaces) > 0 {
+			scopes = namespaces
+		}
+		granted := true
+	check:
+		for _, namespace := range scopes {
+			for _, verb := range permission.Verbs {
+				review := &authorizationv1.SelfSubjectAccessReview{
+					Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+						ResourceAttributes: &authorizationv1.ResourceAttributes{
+							Namespace:   namespace,
+							Verb:        verb,
+							Group:       permission.Group,
+							Resource:    permission.Resource,
+							Subresource: permission.Subresource,
+						},
+					},
+				}
+				if err := c.Create(ctx, review); err != nil {
+					log.Error(err, "Failed to check permission, assuming it is granted", "permission", permission.String())
+					continue
+				}
+				if !review.Status.Allowed {
+					granted = false
+					break check
+				}
+			}
+		}
+		if granted {
+			permissionGranted.WithLabelValues(permission.Name).Set(1)
+			continue
+		}
+		permissionGranted.WithLabelValues(permission.Name).Set(0)
+		caps.missing = append(caps.missing, permission)
+		log.Info("Operator lacks permission, features depending on it are disabled", "permission", permission.String())
+	}
+	setDegraded("permissions", len(caps.missing) > 0)
+	return caps
+}
+
+// Allowed reports whether the operator holds the permission.
+func (c *Capabilities) Allowed(permission Permission) bool {
+	if c == nil {
+		return true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return !slices.ContainsFunc(c.missing, func(p Permission) bool { return p.Name == permission.Name })
+}
+
+// Require reports whether the feature can run with the operator's
+// permissions, recording it as disabled otherwise.
+func (c *Capabilities) Require(feature string, permissions ...Permission) bool {
+	for _, permission := range permissions {
+		if !c.Allowed(permission) {
+			featureEnabled.WithLabelValues(feature).Set(0)
+			logf.Log.WithName("capabilities").Info("Disabling feature for lack of permission",
+				"feature", feature, "permission", permission.String())
+			c.mu.Lock()
+			if !slices.Contains(c.disabled, feature) {
+				c.disabled = append(c.disabled, feature)
+			}
+			c.mu.Unlock()
+			return false
+		}
+	}
+	featureEnabled.WithLabelValues(feature).Set(1)
+	return true
+}
+
+// Condition returns the Degraded condition describing the missing
+// permissions and the features disabled for them.
+func (c *Capabilities) Condition() metav1.Condition {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.missing) == 0 {
+		return metav1.Condition{
+			Type:    ConditionDegraded,
+			Status:  metav1.ConditionFalse,
+			Reason:  CapabilityReasonPermissionsGranted,
+			Message: "The operator holds every permission its features need",
+		}
+	}
+	missing := make([]string, len(c.missing))
+	for i, permission := range c.missing {
+		missing[i] = permission.String()
+	}
+	message := "missing permissions: " + strings.Join(missing, ", ")
+	if len(c.disabled) > 0 {
+		message += fmt.Sprintf("; disabled features: %s", strings.Join(c.disabled, ", "))
+	}
+	return metav1.Condition{
+		Type:    ConditionDegraded,
+		Status:  metav1.ConditionTrue,
+		Reason:  CapabilityReasonMissingPermissions,
+		Message: message,
+	}
+}
+
+// Start sets the Degraded condition on the OperatorHealth, retrying every
+// Interval until it succeeds or ctx is done.
+func (c *Capabilities) Start(ctx context.Context) error {
+	log := logf.FromContext(ctx).WithName("capabilities")
+	interval := c.Interval
+	if interval <= 0 {
+		interval = defaultCapabilityRetryInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		cond := c.Condition()
+		_, err := updateOperatorHealth(ctx, c.Client, func(status *ctxforgev1beta1.OperatorHealthStatus) bool {
+			return meta.SetStatusCondition(&status.Conditions, cond)
+		})
+		if err == nil {
+			return nil
+		}
+		log.Error(err, "Failed to update OperatorHealth", "name", ctxforgev1beta1.OperatorHealthName)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection reports that only the leader writes the OperatorHealth.
+func (c *Capabilities) NeedLeaderElection() bool {
+	return true
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+)
+
+// newAccessReviewClient returns a fake client answering
+// SelfSubjectAccessReviews, keyed by "namespace verb resource": denied ones
+// are not allowed, errored ones fail and all others are allowed.
+func newAccessReviewClient(t *testing.T, denied map[string]bool, errored map[string]bool) client.Client {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, ctxforgev1beta1.AddToScheme(scheme))
+	return fake.NewClientBuilder().WithScheme(scheme).
+		WithStatusSubresource(&ctxforgev1beta1.OperatorHealth{}).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				review, ok := obj.(*authorizationv1.SelfSubjectAccessReview)
+				if !ok {
+					return c.Create(ctx, obj, opts...)
+				}
+				attrs := review.Spec.ResourceAttributes
+				resource := attrs.Resource
+				if attrs.Subresource != "" {
+					resource += "/" + attrs.Subresource
+				}
+				key := attrs.Namespace + " " + attrs.Verb + " " + resource
+				if errored[key] {
+					return errors.New("connection refused")
+				}
+				review.Status.Allowed = !denied[key]
+				return nil
+			},
+		}).Build()
+}
+
+func TestDetectCapabilities(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("all granted", func(t *testing.T) {
+		c := newAccessReviewClient(t, nil, nil)
+		caps := DetectCapabilities(ctx, c, nil, PermissionPatchPods, PermissionUpdatePodStatus, PermissionReadNamespaces)
+		assert.True(t, caps.Allowed(PermissionPatchPods))
+		assert.True(t, caps.Allowed(PermissionUpdatePodStatus))
+		assert.True(t, caps.Allowed(PermissionReadNamespaces))
+		assert.True(t, caps.Require("ReadinessGate", PermissionUpdatePodStatus))
+
+		cond := caps.Condition()
+		assert.Equal(t, metav1.ConditionFalse, cond.Status)
+		assert.Equal(t, CapabilityReasonPermissionsGranted, cond.Reason)
+		assert.Equal(t, float64(1), testutil.ToFloat64(permissionGranted.WithLabelValues("patch_pods")))
+		assert.Equal(t, float64(0), testutil.ToFloat64(operatorDegraded))
+	})
+
+	t.Run("missing permissions disable features", func(t *testing.T) {
+		c := newAccessReviewClient(t, map[string]bool{" watch namespaces": true, " update pods/status": true}, nil)
+		caps := DetectCapabilities(ctx, c, nil, PermissionPatchPods, PermissionUpdatePodStatus, PermissionReadNamespaces)
+		assert.True(t, caps.Allowed(PermissionPatchPods))
+		assert.False(t, caps.Allowed(PermissionUpdatePodStatus))
+		assert.False(t, caps.Allowed(PermissionReadNamespaces))
+
+		assert.True(t, caps.Require("PolicyAnnotation", PermissionPatchPods))
+		assert.False(t, caps.Require("ReadinessGate", PermissionUpdatePodStatus))
+		assert.False(t, caps.Require("RulesConfigMap", PermissionPatchPods, PermissionReadNamespaces))
+		assert.False(t, caps.Require("RulesConfigMap", PermissionReadNamespaces))
+
+		cond := caps.Condition()
+		assert.Equal(t, metav1.ConditionTrue, cond.Status)
+		assert.Equal(t, CapabilityReasonMissingPermissions, cond.Reason)
+		assert.Equal(t, "missing permissions: update pods/status, get/list/watch namespaces; "+
+			"disabled features: ReadinessGate, RulesConfigMap", cond.Message)
+		assert.Equal(t, float64(0), testutil.ToFloat64(permissionGranted.WithLabelValues("read_namespaces")))
+		assert.Equal(t, float64(0), testutil.ToFloat64(featureEnabled.WithLabelValues("ReadinessGate")))
+		assert.Equal(t, float64(1), testutil.ToFloat64(featureEnabled.WithLabelValues("PolicyAnnotation")))
+		assert.Equal(t, float64(1), testutil.ToFloat64(operatorDegraded))
+
+		// The cert monitor turning healthy leaves the operator degraded
+		setDegraded("certificate", false)
+		assert.Equal(t, float64(1), testutil.ToFloat64(operatorDegraded))
+		setDegraded("permissions", false)
+	})
+
+	t.Run("namespaced permissions are checked in every watched namespace", func(t *testing.T) {
+		c := newAccessReviewClient(t, map[string]bool{"team-b patch pods": true}, nil)
+		caps := DetectCapabilities(ctx, c, []string{"team-a", "team-b"}, PermissionPatchPods, PermissionReadNamespaces)
+		assert.False(t, caps.Allowed(PermissionPatchPods))
+		assert.True(t, caps.Allowed(PermissionReadNamespaces), "namespaces are cluster-scoped")
+		setDegraded("permissions", false)
+	})
+
+	t.Run("failed reviews assume the permission is granted", func(t *testing.T) {
+		c := newAccessReviewClient(t, nil, map[string]bool{" patch pods": true})
+		caps := DetectCapabilities(ctx, c, nil, PermissionPatchPods)
+		assert.True(t, caps.Allowed(PermissionPatchPods))
+	})
+
+	t.Run("nil grants everything", func(t *testing.T) {
+		var caps *Capabilities
+		assert.True(t, caps.Allowed(PermissionPatchPods))
+		assert.True(t, caps.Require("ReadinessGate", PermissionUpdatePodStatus))
+	})
+}
+
+func TestCapabilities_Start(t *testing.T) {
+	c := newAccessReviewClient(t, map[string]bool{" patch pods": true}, nil)
+	caps := DetectCapabilities(context.Background(), c, nil, PermissionPatchPods)
+	defer setDegraded("permissions", false)
+	caps.Require("LiveConfigAnnotation", PermissionPatchPods)
+
+	// The self-check's condition is kept
+	require.NoError(t, c.Create(context.Background(), &ctxforgev1beta1.OperatorHealth{
+		ObjectMeta: metav1.ObjectMeta{Name: ctxforgev1beta1.OperatorHealthName},
+	}))
+	health := &ctxforgev1beta1.OperatorHealth{}
+	require.NoError(t, c.Get(context.Background(), types.NamespacedName{Name: ctxforgev1beta1.OperatorHealthName}, health))
+	meta.SetStatusCondition(&health.Status.Conditions, metav1.Condition{
+		Type: ConditionWebhookHealthy, Status: metav1.ConditionTrue, Reason: SelfCheckReasonPassed,
+	})
+	require.NoError(t, c.Status().Update(context.Background(), health))
+
+	require.NoError(t, caps.Start(context.Background()))
+
+	require.NoError(t, c.Get(context.Background(), types.NamespacedName{Name: ctxforgev1beta1.OperatorHealthName}, health))
+	degraded := meta.FindStatusCondition(health.Status.Conditions, ConditionDegraded)
+	require.NotNil(t, degraded)
+	assert.Equal(t, metav1.ConditionTrue, degraded.Status)
+	assert.Equal(t, CapabilityReasonMissingPermissions, degraded.Reason)
+	assert.Contains(t, degraded.Message, "LiveConfigAnnotation")
+	assert.True(t, meta.IsStatusConditionTrue(health.Status.Conditions, ConditionWebhookHealthy))
+}
+
+func TestPodCustomDefaulter_IgnoreNamespaces(t *testing.T) {
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:   "default",
+		Labels: map[string]string{LabelNamespaceInjection: NamespaceInjectionEnabled},
+	}}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"}}
+
+	d := &PodCustomDefaulter{Client: newFakeClient(t, ns)}
+	assert.NotNil(t, d.lookupNamespace(context.Background(), pod))
+
+	d.IgnoreNamespaces = true
+	assert.Nil(t, d.lookupNamespace(context.Background(), pod))
+}
@@ -0,0 +1,255 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// ConditionDegraded is set on the operator when it keeps running but cannot
+// guarantee admission will keep working, e.g. because its serving certificate
+// is about to expire.
+const ConditionDegraded = "Degraded"
+
+// Reasons used on the Degraded condition reported by CertExpiryMonitor.
+const (
+	CertReasonValid        = "CertificateValid"
+	CertReasonExpiringSoon = "CertificateExpiringSoon"
+	CertReasonExpired      = "CertificateExpired"
+	CertReasonUnreadable   = "CertificateUnreadable"
+)
+
+const (
+	// DefaultCertExpiryThreshold is how close to notAfter the serving
+	// certificate may get before the operator reports itself Degraded.
+	DefaultCertExpiryThreshold = 7 * 24 * time.Hour
+
+	defaultCertCheckInterval = time.Minute
+)
+
+var (
+	// certExpiryTimestamp exposes the serving certificate's notAfter.
+	certExpiryTimestamp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "ctxforge_webhook_cert_expiry_timestamp_seconds",
+		Help: "Unix timestamp at which the webhook serving certificate expires.",
+	})
+
+	// operatorDegraded is 1 while any Degraded condition is true.
+	operatorDegraded = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "ctxforge_operator_degraded",
+		Help: "Whether the operator is Degraded (1) or not (0).",
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(certExpiryTimestamp, operatorDegraded)
+}
+
+var (
+	degradedMu sync.Mutex
+	// degradedBy holds what currently degrades the operator, so that
+	// operatorDegraded stays 1 until none does.
+	degradedBy = map[string]bool{}
+)
+
+// setDegraded records whether source degrades the operator and updates
+// operatorDegraded.
+func setDegraded(source string, degraded bool) {
+	degradedMu.Lock()
+	defer degradedMu.Unlock()
+	if degraded {
+		degradedBy[source] = true
+	} else {
+		delete(degradedBy, source)
+	}
+	if len(degradedBy) > 0 {
+		operatorDegraded.Set(1)
+	} else {
+		operatorDegraded.Set(0)
+	}
+}
+
+// CertExpiryMonitor periodically reads the webhook serving certificate,
+// exports its expiry as a metric and maintains a Degraded condition that
+// turns true once the certificate is within Threshold of expiring.
+//
+// It implements manager.Runnable and does not require leader election, since
+// every replica serves the webhook with its own copy of the certificate.
+type CertExpiryMonitor struct {
+	// CertFile is the path to the PEM-encoded serving certificate.
+	CertFile string
+	// Threshold is the remaining validity below which the operator is Degraded.
+	Threshold time.Duration
+	// Interval is how often the certificate is re-read. Defaults to one minute.
+	Interval time.Duration
+
+	now func() time.Time
+
+	mu        sync.RWMutex
+	condition metav1.Condition
+}
+
+// Start checks the certificate immediately and then every Interval until ctx is done.
+func (m *CertExpiryMonitor) Start(ctx context.Context) error {
+	interval := m.Interval
+	if interval <= 0 {
+		interval = defaultCertCheckInterval
+	}
+
+	m.check(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			m.check(ctx)
+		}
+	}
+}
+
+// NeedLeaderElection reports that the monitor runs on every replica.
+func (m *CertExpiryMonitor) NeedLeaderElection() bool {
+	return false
+}
+
+// Condition returns the current Degraded condition.
+func (m *CertExpiryMonitor) Condition() metav1.Condition {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.condition
+}
+
+// check reads the certificate and updates the metrics and condition.
+func (m *CertExpiryMonitor) check(ctx context.Context) {
+	log := logf.FromContext(ctx).WithName("cert-expiry")
+	now := time.Now
+	if m.now != nil {
+		now = m.now
+	}
+
+	cond := metav1.Condition{
+		Type:   ConditionDegraded,
+		Status: metav1.ConditionFalse,
+		Reason: CertReasonValid,
+	}
+
+	notAfter, err := readCertNotAfter(m.CertFile)
+	switch {
+	case err != nil:
+		cond.Status = metav1.ConditionTrue
+		cond.Reason = CertReasonUnreadable
+		cond.Message = err.Error()
+	default:
+		certExpiryTimestamp.Set(float64(notAfter.Unix()))
+		remaining := notAfter.Sub(now())
+		switch {
+		case remaining <= 0:
+			cond.Status = metav1.ConditionTrue
+			cond.Reason = CertReasonExpired
+			cond.Message = fmt.Sprintf("webhook serving certificate expired at %s", notAfter.UTC().Format(time.RFC3339))
+		case remaining <= m.Threshold:
+			cond.Status = metav1.ConditionTrue
+			cond.Reason = CertReasonExpiringSoon
+			cond.Message = fmt.Sprintf("webhook serving certificate expires at %s (in %s)",
+				notAfter.UTC().Format(time.RFC3339), remaining.Truncate(time.Minute))
+		default:
+			cond.Message = fmt.Sprintf("webhook serving certificate valid until %s", notAfter.UTC().Format(time.RFC3339))
+		}
+	}
+
+	setDegraded("certificate", cond.Status == metav1.ConditionTrue)
+
+	m.mu.Lock()
+	var conditions []metav1.Condition
+	if m.condition.Type != "" {
+		conditions = append(conditions, m.condition)
+	}
+	changed := meta.SetStatusCondition(&conditions, cond)
+	m.condition = conditions[0]
+	m.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	if cond.Status == metav1.ConditionTrue {
+		log.Info("Operator is degraded", "reason", cond.Reason, "message", cond.Message, "certFile", m.CertFile)
+	} else {
+		log.Info("Webhook serving certificate is healthy", "message", cond.Message)
+	}
+}
+
+// readCertNotAfter returns the notAfter of the first certificate in a PEM file.
+func readCertNotAfter(path string) (time.Time, error) {
+	certs, err := readCerts(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return certs[0].NotAfter, nil
+}
+
+// readCerts returns the certificates of a PEM file, serving certificate
+// first, or an error if it has none.
+func readCerts(path string) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhook certificate: %w", err)
+	}
+	certs, err := parseCerts(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no certificate found in " + path)
+	}
+	return certs, nil
+}
+
+// parseCerts returns the certificates of PEM data, skipping other blocks.
+func parseCerts(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return certs, nil
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse webhook certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// writeTestCert writes a self-signed certificate expiring at notAfter and returns its path.
+func writeTestCert(t *testing.T, notAfter time.Time) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "ctxforge-webhook"},
+		NotBefore:    notAfter.Add(-90 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "tls.crt")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	return path
+}
+
+func TestCertExpiryMonitor_Check(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		notAfter time.Time
+		status   metav1.ConditionStatus
+		reason   string
+	}{
+		{name: "valid", notAfter: now.Add(30 * 24 * time.Hour), status: metav1.ConditionFalse, reason: CertReasonValid},
+		{name: "expiring soon", notAfter: now.Add(48 * time.Hour), status: metav1.ConditionTrue, reason: CertReasonExpiringSoon},
+		{name: "expired", notAfter: now.Add(-time.Hour), status: metav1.ConditionTrue, reason: CertReasonExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &CertExpiryMonitor{
+				CertFile:  writeTestCert(t, tt.notAfter),
+				Threshold: DefaultCertExpiryThreshold,
+				now:       func() time.Time { return now },
+			}
+			m.check(context.Background())
+
+			cond := m.Condition()
+			assert.Equal(t, ConditionDegraded, cond.Type)
+			assert.Equal(t, tt.status, cond.Status)
+			assert.Equal(t, tt.reason, cond.Reason)
+			assert.Equal(t, float64(tt.notAfter.Unix()), testutil.ToFloat64(certExpiryTimestamp))
+			if tt.status == metav1.ConditionTrue {
+				assert.Equal(t, float64(1), testutil.ToFloat64(operatorDegraded))
+			} else {
+				assert.Equal(t, float64(0), testutil.ToFloat64(operatorDegraded))
+			}
+		})
+	}
+}
+
+func TestCertExpiryMonitor_UnreadableCert(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tls.crt")
+	require.NoError(t, os.WriteFile(path, []byte("not a certificate"), 0o600))
+
+	m := &CertExpiryMonitor{CertFile: path, Threshold: DefaultCertExpiryThreshold}
+	m.check(context.Background())
+
+	cond := m.Condition()
+	assert.Equal(t, metav1.ConditionTrue, cond.Status)
+	assert.Equal(t, CertReasonUnreadable, cond.Reason)
+	assert.Equal(t, float64(1), testutil.ToFloat64(operatorDegraded))
+}
+
+func TestCertExpiryMonitor_TransitionTime(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	m := &CertExpiryMonitor{
+		CertFile:  writeTestCert(t, now.Add(30*24*time.Hour)),
+		Threshold: DefaultCertExpiryThreshold,
+		now:       func() time.Time { return now },
+	}
+
+	m.check(context.Background())
+	first := m.Condition()
+	m.check(context.Background())
+	assert.Equal(t, first.LastTransitionTime, m.Condition().LastTransitionTime,
+		"an unchanged status must not bump the transition time")
+
+	m.CertFile = writeTestCert(t, now.Add(time.Hour))
+	m.check(context.Background())
+	assert.Equal(t, CertReasonExpiringSoon, m.Condition().Reason)
+	assert.Equal(t, metav1.ConditionTrue, m.Condition().Status)
+}
@@ -0,0 +1,451 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Keys of the Secret CertRotator stores the webhook's certificate material in.
+const (
+	CertSecretCACert = "ca.crt"
+	CertSecretCAKey  = "ca.key"
+)
+
+const (
+	// DefaultCertValidity is the lifetime of the serving certificates CertRotator issues.
+	DefaultCertValidity = 365 * 24 * time.Hour
+
+	// caValidityFactor makes the CA outlive many serving certificates, so the
+	// caBundle rarely changes.
+	caValidityFactor = 10
+
+	defaultCertRotationInterval = time.Hour
+)
+
+// CertRotator runs the webhook without cert-manager. It generates a CA and a
+// serving certificate for Service, stores them in Secret so every replica
+// serves the same certificate, writes them to CertDir for the webhook server
+// and sets the caBundle of every webhook configuration entry that calls
+// Service. The serving certificate is reissued once two thirds of its
+// validity have passed, and the CA before it would expire within a serving
+// certificate's validity; the previous CA stays in the caBundle until it
+// expires, so replicas still serving the old certificate remain trusted.
+//
+// Call Ensure before the manager starts so the webhook server finds a
+// certificate, then add it to the manager. It implements manager.Runnable and
+// runs on every replica; replicas racing to rotate are serialized by the
+// Secret's resourceVersion.
+type CertRotator struct {
+	// Client must read directly from the API server: Secrets and webhook
+	// configurations aren't in the manager's cache.
+	Client client.Client
+	// Secret holds the CA and serving certificate.
+	Secret types.NamespacedName
+	// Service is the Service in front of the webhook server. The serving
+	// certificate is issued for its DNS names.
+	Service types.NamespacedName
+	// CertDir is the directory the webhook server loads CertName and KeyName
+	// from; the CA bundle is written next to them as ca.crt.
+	CertDir  string
+	CertName string
+	KeyName  string
+	// Validity is the lifetime of serving certificates. Defaults to DefaultCertValidity.
+	Validity time.Duration
+	// Interval is how often rotation is checked. Defaults to one hour.
+	Interval time.Duration
+
+	now func() time.Time
+}
+
+// certMaterial is the content of the certificate Secret.
+type certMaterial struct {
+	// caCerts is the CA bundle, current CA first
+	caCerts []*x509.Certificate
+	caKey   crypto.Signer
+	cert    *x509.Certificate
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// Start checks the certificate every Interval until ctx is done. The first
+// check is left to Ensure, called before the manager starts.
+func (r *CertRotator) Start(ctx context.Context) error {
+	log := logf.FromContext(ctx).WithName("cert-rotator")
+	interval := r.Interval
+	if interval <= 0 {
+		interval = defaultCertRotationInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := r.Ensure(ctx); err != nil {
+				log.Error(err, "Failed to rotate the webhook certificate", "secret", r.Secret)
+			}
+		}
+	}
+}
+
+// NeedLeaderElection reports that every replica keeps its certificate files current.
+func (r *CertRotator) NeedLeaderElection() bool {
+	return false
+}
+
+// Ensure makes sure the Secret holds a CA and a serving certificate that
+// don't need rotating, that the webhook configurations trust the CA and that
+// CertDir holds the serving certificate.
+func (r *CertRotator) Ensure(ctx context.Context) error {
+	material, err := r.ensureSecret(ctx)
+	if err != nil {
+		return err
+	}
+	caBundle := encodeCerts(material.caCerts)
+	if err := r.injectCABundle(ctx, caBundle); err != nil {
+		return err
+	}
+	return r.writeFiles(map[string][]byte{
+		r.certName():     material.certPEM,
+		r.keyName():      material.keyPEM,
+		CertSecretCACert: caBundle,
+	})
+}
+
+// ensureSecret reads the Secret and rotates what is due, creating the Secret
+// if it doesn't exist.
+func (r *CertRotator) ensureSecret(ctx context.Context) (*certMaterial, error) {
+	log := logf.FromContext(ctx).WithName("cert-rotator")
+	secret := &corev1.Secret{}
+	err := r.Client.Get(ctx, r.Secret, secret)
+	exists := err == nil
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to read webhook certificate secret %s: %w", r.Secret, err)
+	}
+
+	material := parseCertMaterial(secret.Data)
+	changed, err := r.rotate(material)
+	if err != nil {
+		return nil, err
+	}
+	if !changed {
+		return material, nil
+	}
+
+	secret.Name = r.Secret.Name
+	secret.Namespace = r.Secret.Namespace
+	secret.Type = corev1.SecretTypeTLS
+	caKey, err := x509.MarshalPKCS8PrivateKey(material.caKey)
+	if err != nil {
+		return nil, err
+	}
+	secret.Data = map[string][]byte{
+		corev1.TLSCertKey:       material.certPEM,
+		corev1.TLSPrivateKeyKey: material.keyPEM,
+		CertSecretCACert:        encodeCerts(material.caCerts),
+		CertSecretCAKey:         pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: caKey}),
+	}
+	// Another replica rotating at the same time makes this fail; the next
+	// check picks up its certificate
+	if exists {
+		err = r.Client.Update(ctx, secret)
+	} else {
+		err = r.Client.Create(ctx, secret)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to store webhook certificate secret %s: %w", r.Secret, err)
+	}
+	log.Info("Issued webhook serving certificate", "secret", r.Secret,
+		"notAfter", material.cert.NotAfter.UTC().Format(time.RFC3339),
+		"caNotAfter", material.caCerts[0].NotAfter.UTC().Format(time.RFC3339))
+	return material, nil
+}
+
+// rotate replaces the CA and the serving certificate of material when they are
+// missing, invalid or about to expire, and reports whether it did.
+func (r *CertRotator) rotate(material *certMaterial) (bool, error) {
+	now := time.Now()
+	if r.now != nil {
+		now = r.now()
+	}
+	validity := r.Validity
+	if validity <= 0 {
+		validity = DefaultCertValidity
+	}
+
+	changed := false
+	if material.caKey == nil || len(material.caCerts) == 0 || material.caCerts[0].NotAfter.Sub(now) < validity {
+		ca, key, err := newCA(now, caValidityFactor*validity)
+		if err != nil {
+			return false, err
+		}
+		material.caCerts = append([]*x509.Certificate{ca}, material.caCerts...)
+		material.caKey = key
+		material.cert = nil
+		changed = true
+	}
+	// Expired CAs no longer verify anything
+	material.caCerts = slices.DeleteFunc(material.caCerts, func(c *x509.Certificate) bool {
+		return !now.Before(c.NotAfter)
+	})
+
+	if material.cert == nil || material.cert.NotAfter.Sub(now) < validity/3 ||
+		!slices.Equal(material.cert.DNSNames, r.dnsNames()) || material.cert.CheckSignatureFrom(material.caCerts[0]) != nil {
+		certPEM, keyPEM, cert, err := newServingCert(now, validity, r.dnsNames(), material.caCerts[0], material.caKey)
+		if err != nil {
+			return false, err
+		}
+		material.cert, material.certPEM, material.keyPEM = cert, certPEM, keyPEM
+		changed = true
+	}
+	return changed, nil
+}
+
+// injectCABundle sets caBundle on every webhook entry that calls Service.
+func (r *CertRotator) injectCABundle(ctx context.Context, caBundle []byte) error {
+	log := logf.FromContext(ctx).WithName("cert-rotator")
+	inject := func(config *admissionregistrationv1.WebhookClientConfig) bool {
+		if config.Service == nil || config.Service.Namespace != r.Service.Namespace ||
+			config.Service.Name != r.Service.Name || bytes.Equal(config.CABundle, caBundle) {
+			return false
+		}
+		config.CABundle = caBundle
+		return true
+	}
+
+	mutating := &admissionregistrationv1.MutatingWebhookConfigurationList{}
+	if err := r.Client.List(ctx, mutating); err != nil {
+		return fmt.Errorf("failed to list mutating webhook configurations: %w", err)
+	}
+	for i := range mutating.Items {
+		config := &mutating.Items[i]
+		changed := false
+		for j := range config.Webhooks {
+			changed = inject(&config.Webhooks[j].ClientConfig) || changed
+		}
+		if !changed {
+			continue
+		}
+		log.Info("Updating webhook caBundle", "configuration", config.Name)
+		if err := r.Client.Update(ctx, config); err != nil {
+			return fmt.Errorf("failed to update caBundle of %s: %w", config.Name, err)
+		}
+	}
+
+	validating := &admissionregistrationv1.ValidatingWebhookConfigurationList{}
+	if err := r.Client.List(ctx, validating); err != nil {
+		return fmt.Errorf("failed to list validating webhook configurations: %w", err)
+	}
+	for i := range validating.Items {
+		config := &validating.Items[i]
+		changed := false
+		for j := range config.Webhooks {
+			changed = inject(&config.Webhooks[j].ClientConfig) || changed
+		}
+		if !changed {
+			continue
+		}
+		log.Info("Updating webhook caBundle", "configuration", config.Name)
+		if err := r.Client.Update(ctx, config); err != nil {
+			return fmt.Errorf("failed to update caBundle of %s: %w", config.Name, err)
+		}
+	}
+	return nil
+}
+
+// writeFiles writes the files whose content changed to CertDir. Each file is
+// replaced atomically so the webhook server never loads a partial one.
+func (r *CertRotator) writeFiles(files map[string][]byte) error {
+	if err := os.MkdirAll(r.CertDir, 0o700); err != nil {
+		return err
+	}
+	for name, data := range files {
+		path := filepath.Join(r.CertDir, name)
+		if current, err := os.ReadFile(path); err == nil && bytes.Equal(current, data) {
+			continue
+		}
+		tmp, err := os.CreateTemp(r.CertDir, "."+name+"-*")
+		if err != nil {
+			return err
+		}
+		_, err = tmp.Write(data)
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Rename(tmp.Name(), path)
+		}
+		if err != nil {
+			_ = os.Remove(tmp.Name())
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// dnsNames returns the names the API server reaches Service by.
+func (r *CertRotator) dnsNames() []string {
+	name := r.Service.Name + "." + r.Service.Namespace + ".svc"
+	return []string{name, name + ".cluster.local"}
+}
+
+func (r *CertRotator) certName() string {
+	if r.CertName == "" {
+		return corev1.TLSCertKey
+	}
+	return r.CertName
+}
+
+func (r *CertRotator) keyName() string {
+	if r.KeyName == "" {
+		return corev1.TLSPrivateKeyKey
+	}
+	return r.KeyName
+}
+
+// parseCertMaterial reads the Secret's data, leaving out whatever is missing
+// or invalid so that it is regenerated.
+func parseCertMaterial(data map[string][]byte) *certMaterial {
+	material := &certMaterial{}
+	if certs, err := parseCerts(data[CertSecretCACert]); err == nil {
+		material.caCerts = certs
+	}
+	if block, _ := pem.Decode(data[CertSecretCAKey]); block != nil {
+		if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+			material.caKey, _ = key.(crypto.Signer)
+		}
+	}
+	// The CA key must belong to the current CA
+	if material.caKey != nil && len(material.caCerts) > 0 {
+		ca := material.caCerts[0]
+		if pub, ok := ca.PublicKey.(interface{ Equal(crypto.PublicKey) bool }); !ok || !pub.Equal(material.caKey.Public()) {
+			material.caKey = nil
+		}
+	}
+	if certs, err := parseCerts(data[corev1.TLSCertKey]); err == nil && len(certs) > 0 && len(data[corev1.TLSPrivateKeyKey]) > 0 {
+		material.cert = certs[0]
+		material.certPEM = data[corev1.TLSCertKey]
+		material.keyPEM = data[corev1.TLSPrivateKeyKey]
+	}
+	return material
+}
+
+// newCA returns a self-signed CA certificate and its key.
+func newCA(now time.Time, validity time.Duration) (*x509.Certificate, crypto.Signer, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := newSerial()
+	if err != nil {
+		return nil, nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: fmt.Sprintf("contextforge-webhook-ca@%d", now.Unix())},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, nil, err
+	}
+	ca, err := x509.ParseCertificate(der)
+	return ca, key, err
+}
+
+// newServingCert returns a serving certificate for dnsNames signed by ca, PEM
+// encoded with its key.
+func newServingCert(now time.Time, validity time.Duration, dnsNames []string,
+	ca *x509.Certificate, caKey crypto.Signer) ([]byte, []byte, *x509.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	serial, err := newSerial()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	notAfter := now.Add(validity)
+	if notAfter.After(ca.NotAfter) {
+		notAfter = ca.NotAfter
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:     dnsNames,
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, key.Public(), caKey)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), cert, nil
+}
+
+// newSerial returns a random certificate serial number.
+func newSerial() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+// encodeCerts PEM-encodes certs in order.
+func encodeCerts(certs []*x509.Certificate) []byte {
+	var buf bytes.Buffer
+	for _, cert := range certs {
+		_ = pem.Encode(&buf, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	}
+	return buf.Bytes()
+}
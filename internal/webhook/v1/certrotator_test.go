@@ -0,0 +1,183 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func newTestCertRotator(t *testing.T, c client.Client, now *time.Time) *CertRotator {
+	t.Helper()
+	return &CertRotator{
+		Client:   c,
+		Secret:   types.NamespacedName{Namespace: "contextforge-system", Name: "webhook-certs"},
+		Service:  types.NamespacedName{Namespace: "contextforge-system", Name: "webhook-service"},
+		CertDir:  t.TempDir(),
+		Validity: 90 * 24 * time.Hour,
+		now:      func() time.Time { return *now },
+	}
+}
+
+// verifyServingCert checks that the files in CertDir form a serving
+// certificate for the webhook Service that verifies against caBundle.
+func verifyServingCert(t *testing.T, r *CertRotator, caBundle []byte, now time.Time) *x509.Certificate {
+	t.Helper()
+	pair, err := tls.LoadX509KeyPair(filepath.Join(r.CertDir, "tls.crt"), filepath.Join(r.CertDir, "tls.key"))
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	require.NoError(t, err)
+
+	roots := x509.NewCertPool()
+	require.True(t, roots.AppendCertsFromPEM(caBundle))
+	_, err = cert.Verify(x509.VerifyOptions{
+		DNSName:     "webhook-service.contextforge-system.svc",
+		Roots:       roots,
+		CurrentTime: now,
+	})
+	require.NoError(t, err)
+	return cert
+}
+
+func TestCertRotator_Ensure(t *testing.T) {
+	service := &admissionregistrationv1.ServiceReference{Namespace: "contextforge-system", Name: "webhook-service"}
+	other := &admissionregistrationv1.ServiceReference{Namespace: "other", Name: "webhook-service"}
+	mutating := &admissionregistrationv1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "contextforge-mutating"},
+		Webhooks: []admissionregistrationv1.MutatingWebhook{
+			{Name: "pods.contextforge.io", ClientConfig: admissionregistrationv1.WebhookClientConfig{Service: service}},
+			{Name: "other.example.com", ClientConfig: admissionregistrationv1.WebhookClientConfig{Service: other}},
+		},
+	}
+	validating := &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "contextforge-validating"},
+		Webhooks: []admissionregistrationv1.ValidatingWebhook{
+			{Name: "policies.contextforge.io", ClientConfig: admissionregistrationv1.WebhookClientConfig{Service: service}},
+		},
+	}
+	c := newFakeClient(t, mutating, validating)
+	start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	r := newTestCertRotator(t, c, &now)
+	ctx := context.Background()
+
+	caBundle := func() []byte {
+		t.Helper()
+		got := &admissionregistrationv1.MutatingWebhookConfiguration{}
+		require.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(mutating), got))
+		assert.Empty(t, got.Webhooks[1].ClientConfig.CABundle, "webhooks of other services are left alone")
+		return got.Webhooks[0].ClientConfig.CABundle
+	}
+
+	// Bootstrap creates the Secret, the files and the caBundles
+	require.NoError(t, r.Ensure(ctx))
+	secret := &corev1.Secret{}
+	require.NoError(t, c.Get(ctx, r.Secret, secret))
+	assert.Equal(t, corev1.SecretTypeTLS, secret.Type)
+	bundle := caBundle()
+	require.NotEmpty(t, bundle)
+	assert.Equal(t, secret.Data[CertSecretCACert], bundle)
+	first := verifyServingCert(t, r, bundle, now)
+	assert.Equal(t, []string{"webhook-service.contextforge-system.svc", "webhook-service.contextforge-system.svc.cluster.local"}, first.DNSNames)
+	caFile, err := os.ReadFile(filepath.Join(r.CertDir, "ca.crt"))
+	require.NoError(t, err)
+	assert.Equal(t, bundle, caFile)
+
+	gotValidating := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+	require.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(validating), gotValidating))
+	assert.Equal(t, bundle, gotValidating.Webhooks[0].ClientConfig.CABundle)
+
+	// Another replica reuses the stored certificate
+	replica := newTestCertRotator(t, c, &now)
+	require.NoError(t, replica.Ensure(ctx))
+	assert.Equal(t, first.SerialNumber, verifyServingCert(t, replica, bundle, now).SerialNumber)
+	unchanged := &corev1.Secret{}
+	require.NoError(t, c.Get(ctx, r.Secret, unchanged))
+	assert.Equal(t, secret.ResourceVersion, unchanged.ResourceVersion, "nothing is due, the Secret is untouched")
+
+	// Two thirds into its validity the serving certificate is reissued by the same CA
+	now = start.Add(61 * 24 * time.Hour)
+	require.NoError(t, r.Ensure(ctx))
+	second := verifyServingCert(t, r, bundle, now)
+	assert.NotEqual(t, first.SerialNumber, second.SerialNumber)
+	assert.Equal(t, bundle, caBundle())
+
+	// Shortly before the CA would expire within a serving certificate's
+	// validity, the serving certificate is still issued by the current CA
+	day := func(n int) time.Time { return start.Add(time.Duration(n) * 24 * time.Hour) }
+	now = day(800)
+	require.NoError(t, r.Ensure(ctx))
+	third := verifyServingCert(t, r, bundle, now)
+	assert.Equal(t, bundle, caBundle())
+
+	// Once it would, a new CA is issued; the old one stays trusted for
+	// replicas that still serve the previous certificate
+	now = day(830)
+	require.NoError(t, r.Ensure(ctx))
+	rotated := caBundle()
+	roots, err := parseCerts(rotated)
+	require.NoError(t, err)
+	require.Len(t, roots, 2)
+	verifyServingCert(t, r, rotated, now)
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(rotated)
+	_, err = third.Verify(x509.VerifyOptions{
+		DNSName:     "webhook-service.contextforge-system.svc",
+		Roots:       pool,
+		CurrentTime: now,
+	})
+	assert.NoError(t, err)
+
+	// Once expired, the old CA is dropped from the bundle
+	now = day(901)
+	require.NoError(t, r.Ensure(ctx))
+	roots, err = parseCerts(caBundle())
+	require.NoError(t, err)
+	assert.Len(t, roots, 1)
+}
+
+func TestCertRotator_ReplacesInvalidMaterial(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "contextforge-system", Name: "webhook-certs"},
+		Type:       corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       []byte("not a certificate"),
+			corev1.TLSPrivateKeyKey: []byte("not a key"),
+		},
+	}
+	c := newFakeClient(t, secret)
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	r := newTestCertRotator(t, c, &now)
+
+	require.NoError(t, r.Ensure(context.Background()))
+	got := &corev1.Secret{}
+	require.NoError(t, c.Get(context.Background(), r.Secret, got))
+	verifyServingCert(t, r, got.Data[CertSecretCACert], now)
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/bgruszka/contextforge/internal/config"
+)
+
+const (
+	// AnnotationContextSigningSecret names the Secret holding the keys the
+	// proxy signs and verifies propagated headers with, overriding the
+	// operator default. "none" disables signing for the pod.
+	AnnotationContextSigningSecret = "ctxforge.io/context-signing-secret"
+	// AnnotationUnsignedContext sets what the proxy does with inbound context
+	// headers no valid signature covers: "allow", "strip" or "reject".
+	AnnotationUnsignedContext = "ctxforge.io/unsigned-context"
+
+	// contextSigningKey is the Secret key holding the signing keys, one per line
+	contextSigningKey = "keys"
+	// contextSigningVolume is the name of the volume mounted into the proxy
+	contextSigningVolume = "ctxforge-context-signing"
+	// contextSigningMountPath is where the signing keys are mounted in the proxy container
+	contextSigningMountPath = "/etc/ctxforge/context-signing"
+
+	contextSigningDisabled = "none"
+)
+
+// validateContextSigningSecret checks a signing Secret reference.
+func validateContextSigningSecret(name string) error {
+	if name == contextSigningDisabled {
+		return nil
+	}
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return fmt.Errorf("invalid Secret name %q: %s", name, errs[0])
+	}
+	return nil
+}
+
+// addContextSigning mounts the context signing keys from the pod annotation
+// or the operator default into the sidecar, and passes on the pod's handling
+// of unsigned context. The mode needs keys to check against, so it is ignored
+// for pods without them.
+func (d *PodCustomDefaulter) addContextSigning(pod *corev1.Pod, sidecar *corev1.Container) {
+	secret := d.ContextSigningSecret
+	if value, ok := pod.Annotations[AnnotationContextSigningSecret]; ok {
+		secret = value
+	}
+	mode, hasMode := pod.Annotations[AnnotationUnsignedContext]
+	if secret == "" || secret == contextSigningDisabled {
+		if hasMode && mode != config.UnsignedContextAllow {
+			podLogger(pod).Info("Ignoring unsigned context mode: the pod has no context signing keys",
+				"mode", mode)
+		}
+		return
+	}
+
+	pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+		Name: contextSigningVolume,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: secret,
+				Items:      []corev1.KeyToPath{{Key: contextSigningKey, Path: contextSigningKey}},
+			},
+		},
+	})
+	sidecar.VolumeMounts = append(sidecar.VolumeMounts, corev1.VolumeMount{
+		Name:      contextSigningVolume,
+		MountPath: contextSigningMountPath,
+		ReadOnly:  true,
+	})
+	sidecar.Env = append(sidecar.Env, corev1.EnvVar{
+		Name:  "CONTEXT_SIGNING_KEY_FILE",
+		Value: contextSigningMountPath + "/" + contextSigningKey,
+	})
+
+	if !hasMode {
+		return
+	}
+	if i := findEnv(sidecar.Env, "UNSIGNED_CONTEXT"); i >= 0 {
+		sidecar.Env[i] = corev1.EnvVar{Name: "UNSIGNED_CONTEXT", Value: mode}
+		return
+	}
+	sidecar.Env = append(sidecar.Env, corev1.EnvVar{Name: "UNSIGNED_CONTEXT", Value: mode})
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestPodCustomDefaulter_ContextSigning(t *testing.T) {
+	tests := []struct {
+		name         string
+		operator     string
+		annotations  map[string]string
+		expectSecret string
+		expectMode   string
+	}{
+		{name: "disabled by default"},
+		{name: "operator default", operator: "ctxforge-signing", expectSecret: "ctxforge-signing"},
+		{
+			name:         "pod secret overrides the operator default",
+			operator:     "ctxforge-signing",
+			annotations:  map[string]string{AnnotationContextSigningSecret: "team-signing"},
+			expectSecret: "team-signing",
+		},
+		{
+			name:        "pod opts out",
+			operator:    "ctxforge-signing",
+			annotations: map[string]string{AnnotationContextSigningSecret: "none"},
+		},
+		{
+			name:         "strict pod",
+			operator:     "ctxforge-signing",
+			annotations:  map[string]string{AnnotationUnsignedContext: "reject"},
+			expectSecret: "ctxforge-signing",
+			expectMode:   "reject",
+		},
+		{
+			name:        "mode without keys is ignored",
+			annotations: map[string]string{AnnotationUnsignedContext: "reject"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage, ContextSigningSecret: tt.operator}
+			pod := orderingPod("")
+			for key, value := range tt.annotations {
+				pod.Annotations[key] = value
+			}
+
+			require.NoError(t, defaulter.Default(context.Background(), pod))
+
+			sidecar := proxyContainer(pod)
+			require.NotNil(t, sidecar)
+			if tt.expectMode == "" {
+				assert.Negative(t, findEnv(sidecar.Env, "UNSIGNED_CONTEXT"))
+			} else {
+				assert.Equal(t, tt.expectMode, sidecarEnv(t, pod, "UNSIGNED_CONTEXT"))
+			}
+			if tt.expectSecret == "" {
+				assert.Negative(t, findEnv(sidecar.Env, "CONTEXT_SIGNING_KEY_FILE"))
+				assert.Empty(t, pod.Spec.Volumes)
+				return
+			}
+
+			assert.Equal(t, "/etc/ctxforge/context-signing/keys", sidecarEnv(t, pod, "CONTEXT_SIGNING_KEY_FILE"))
+			assert.Contains(t, sidecar.VolumeMounts,
+				corev1.VolumeMount{Name: contextSigningVolume, MountPath: contextSigningMountPath, ReadOnly: true})
+			require.Len(t, pod.Spec.Volumes, 1)
+			require.NotNil(t, pod.Spec.Volumes[0].Secret)
+			assert.Equal(t, tt.expectSecret, pod.Spec.Volumes[0].Secret.SecretName)
+			for _, app := range pod.Spec.Containers[:2] {
+				assert.Empty(t, app.VolumeMounts, "only the proxy gets the keys")
+			}
+		})
+	}
+}
+
+func TestPodCustomDefaulter_UnsignedContextOverridesDefaults(t *testing.T) {
+	dir := t.TempDir()
+	writeDefaults(t, dir, map[string]string{"UNSIGNED_CONTEXT": "strip"})
+	defaulter := &PodCustomDefaulter{
+		ProxyImage:           DefaultProxyImage,
+		ContextSigningSecret: "ctxforge-signing",
+		Defaults:             &SidecarDefaultsSource{Dir: dir},
+	}
+	pod := orderingPod("")
+	pod.Annotations[AnnotationUnsignedContext] = "allow"
+
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+
+	var modes []string
+	for _, env := range proxyContainer(pod).Env {
+		if env.Name == "UNSIGNED_CONTEXT" {
+			modes = append(modes, env.Value)
+		}
+	}
+	assert.Equal(t, []string{"allow"}, modes)
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// DefaultDrainTimeout is how long the preStop hook waits for in-flight requests
+	DefaultDrainTimeout = 20 * time.Second
+
+	// ProxyAdminPort is the proxy's admin port serving /drain and /config
+	ProxyAdminPort = 9091
+	// proxyDrainDelay gives endpoint removal time to propagate before draining.
+	proxyDrainDelay = 5 * time.Second
+	// drainShutdownBuffer is left for the proxy's own shutdown after the hook.
+	drainShutdownBuffer = 5 * time.Second
+)
+
+// addDrainHook makes the sidecar drain in-flight requests before it receives
+// SIGTERM and raises the pod's termination grace period to fit the drain.
+// It does nothing when draining is disabled on the operator.
+func (d *PodCustomDefaulter) addDrainHook(pod *corev1.Pod, sidecar *corev1.Container) {
+	if d.DrainTimeout <= 0 {
+		return
+	}
+
+	sidecar.Env = append(sidecar.Env,
+		corev1.EnvVar{Name: "DRAIN_DELAY", Value: proxyDrainDelay.String()},
+		corev1.EnvVar{Name: "DRAIN_TIMEOUT", Value: d.DrainTimeout.String()},
+	)
+
+	// The hook never fails: a proxy that is already gone has nothing to drain.
+	wait := int((proxyDrainDelay + d.DrainTimeout).Seconds()) + 1
+	script := fmt.Sprintf("wget -q -T %d -O /dev/null http://127.0.0.1:%d/drain || true", wait, ProxyAdminPort)
+	if sidecar.Lifecycle == nil {
+		sidecar.Lifecycle = &corev1.Lifecycle{}
+	}
+	sidecar.Lifecycle.PreStop = &corev1.LifecycleHandler{
+		Exec: &corev1.ExecAction{Command: []string{"sh", "-c", script}},
+	}
+
+	needed := int64((proxyDrainDelay + d.DrainTimeout + drainShutdownBuffer).Seconds())
+	current := int64(corev1.DefaultTerminationGracePeriodSeconds)
+	if pod.Spec.TerminationGracePeriodSeconds != nil {
+		current = *pod.Spec.TerminationGracePeriodSeconds
+	}
+	if current < needed {
+		podLogger(pod).Info("Raising termination grace period to fit the proxy drain",
+			"from", current, "to", needed)
+		pod.Spec.TerminationGracePeriodSeconds = int64Ptr(needed)
+	}
+}
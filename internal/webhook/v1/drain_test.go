@@ -0,0 +1,102 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPodCustomDefaulter_DrainHook(t *testing.T) {
+	tests := []struct {
+		name          string
+		drainTimeout  time.Duration
+		podGrace      *int64
+		expectedGrace *int64
+	}{
+		{
+			name:          "default grace period fits the default drain",
+			drainTimeout:  DefaultDrainTimeout,
+			expectedGrace: nil,
+		},
+		{
+			name:          "short grace period is raised",
+			drainTimeout:  DefaultDrainTimeout,
+			podGrace:      int64Ptr(10),
+			expectedGrace: int64Ptr(30),
+		},
+		{
+			name:          "long drain raises the default grace period",
+			drainTimeout:  time.Minute,
+			expectedGrace: int64Ptr(70),
+		},
+		{
+			name:          "longer grace period is kept",
+			drainTimeout:  DefaultDrainTimeout,
+			podGrace:      int64Ptr(120),
+			expectedGrace: int64Ptr(120),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage, DrainTimeout: tt.drainTimeout}
+			pod := orderingPod("")
+			pod.Spec.TerminationGracePeriodSeconds = tt.podGrace
+
+			require.NoError(t, defaulter.Default(context.Background(), pod))
+
+			sidecar := proxyContainer(pod)
+			require.NotNil(t, sidecar)
+			require.NotNil(t, sidecar.Lifecycle)
+			require.NotNil(t, sidecar.Lifecycle.PreStop)
+			assert.Contains(t, sidecar.Lifecycle.PreStop.Exec.Command[2], "http://127.0.0.1:9091/drain")
+			assert.Equal(t, tt.drainTimeout.String(), sidecarEnv(t, pod, "DRAIN_TIMEOUT"))
+			assert.Equal(t, tt.expectedGrace, pod.Spec.TerminationGracePeriodSeconds)
+		})
+	}
+}
+
+func TestPodCustomDefaulter_DrainHookWithSidecarFirst(t *testing.T) {
+	defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage, DrainTimeout: DefaultDrainTimeout}
+	pod := orderingPod(SidecarOrderFirst)
+
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+
+	lifecycle := pod.Spec.Containers[0].Lifecycle
+	require.NotNil(t, lifecycle)
+	assert.NotNil(t, lifecycle.PostStart)
+	assert.NotNil(t, lifecycle.PreStop)
+}
+
+func TestPodCustomDefaulter_DrainDisabled(t *testing.T) {
+	defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage}
+	pod := orderingPod("")
+	pod.Spec.TerminationGracePeriodSeconds = int64Ptr(5)
+
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+
+	sidecar := proxyContainer(pod)
+	require.NotNil(t, sidecar)
+	assert.Nil(t, sidecar.Lifecycle)
+	assert.Equal(t, int64(5), *pod.Spec.TerminationGracePeriodSeconds)
+	assert.Equal(t, -1, findEnv(sidecar.Env, "DRAIN_TIMEOUT"))
+}
@@ -0,0 +1,174 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	// AnnotationConfigDrift describes how a running pod's sidecar differs from its
+	// current configuration. It is removed once the two match again.
+	AnnotationConfigDrift = "ctxforge.io/config-drift"
+
+	// EventReasonConfigDrift is the reason of the Warning event emitted on drift
+	EventReasonConfigDrift = "ConfigDrift"
+)
+
+// Actions used as the "action" label of configDriftTotal.
+const (
+	DriftActionPatched  = "patched"
+	DriftActionReported = "reported"
+)
+
+// isDryRunRequest checks if the admission request in ctx won't be persisted,
+// as with kubectl apply --dry-run=server. The webhook declares no side
+// effects, so such requests must not emit events or count in metrics.
+func isDryRunRequest(ctx context.Context) bool {
+	req, err := admission.RequestFromContext(ctx)
+	return err == nil && req.DryRun != nil && *req.DryRun
+}
+
+// proxyEnvKeys are the sidecar env vars derived from the pod's header configuration.
+var proxyEnvKeys = []string{"HEADERS_TO_PROPAGATE", "HEADER_RULES"}
+
+// isUpdateRequest checks if the admission request in ctx is a pod UPDATE
+func isUpdateRequest(ctx context.Context) bool {
+	req, err := admission.RequestFromContext(ctx)
+	return err == nil && req.Operation == admissionv1.Update
+}
+
+// proxyContainer returns the injected sidecar, whether regular or native, or nil.
+func proxyContainer(pod *corev1.Pod) *corev1.Container {
+	for i := range pod.Spec.Containers {
+		if pod.Spec.Containers[i].Name == ProxyContainerName {
+			return &pod.Spec.Containers[i]
+		}
+	}
+	for i := range pod.Spec.InitContainers {
+		if pod.Spec.InitContainers[i].Name == ProxyContainerName {
+			return &pod.Spec.InitContainers[i]
+		}
+	}
+	return nil
+}
+
+// desiredProxyEnv returns the values the header env vars should have; an empty
+// value means the variable should not be set.
+func desiredProxyEnv(headers []string, headerRules string) map[string]string {
+	return map[string]string{
+		"HEADERS_TO_PROPAGATE": strings.Join(headers, ","),
+		"HEADER_RULES":         headerRules,
+	}
+}
+
+// driftedEnv returns the names of the header env vars on the sidecar that
+// differ from the desired values.
+func driftedEnv(sidecar *corev1.Container, desired map[string]string) []string {
+	var drifted []string
+	for _, key := range proxyEnvKeys {
+		var current string
+		if i := findEnv(sidecar.Env, key); i >= 0 {
+			current = sidecar.Env[i].Value
+		}
+		if current != desired[key] {
+			drifted = append(drifted, key)
+		}
+	}
+	return drifted
+}
+
+// syncProxyEnv rewrites the header env vars on the sidecar to the desired values.
+func syncProxyEnv(sidecar *corev1.Container, desired map[string]string) {
+	for _, key := range proxyEnvKeys {
+		i := findEnv(sidecar.Env, key)
+		switch {
+		case desired[key] == "" && i >= 0:
+			sidecar.Env = append(sidecar.Env[:i], sidecar.Env[i+1:]...)
+		case desired[key] != "" && i >= 0:
+			sidecar.Env[i].Value = desired[key]
+		case desired[key] != "":
+			sidecar.Env = append(sidecar.Env, corev1.EnvVar{Name: key, Value: desired[key]})
+		}
+	}
+}
+
+// reconcileDrift compares an already injected sidecar against the pod's current
+// configuration. When patch is set, e.g. on pod CREATE from a spec that still
+// carries an old sidecar, the env is rewritten in place. Otherwise (pod UPDATE,
+// where the container spec is immutable) the drift is recorded in the
+// ctxforge.io/config-drift annotation and a Warning event instead.
+func (d *PodCustomDefaulter) reconcileDrift(ctx context.Context, pod *corev1.Pod, patch bool) {
+	sidecar := proxyContainer(pod)
+	if sidecar == nil {
+		return
+	}
+
+	headers, headerRules, _, err := d.resolveHeaders(ctx, pod, d.lookupNamespace(ctx, pod))
+	if err != nil {
+		// Invalid configuration is reported by the validating webhook
+		podLogger(pod).Info("Skipping drift check: configuration is invalid", "error", err.Error())
+		return
+	}
+	desired := desiredProxyEnv(headers, headerRules)
+	drifted := driftedEnv(sidecar, desired)
+
+	if len(drifted) == 0 {
+		delete(pod.Annotations, AnnotationConfigDrift)
+		return
+	}
+
+	// Running sidecars that watch for pushed rules are updated by SyncLiveConfig
+	if !patch && usesLiveConfig(sidecar) {
+		return
+	}
+
+	if patch {
+		podLogger(pod).Info("Updating stale sidecar configuration", "env", drifted)
+		syncProxyEnv(sidecar, desired)
+		if _, ok := pod.Annotations[AnnotationConfigChecksum]; ok {
+			pod.Annotations[AnnotationConfigChecksum] = sidecarConfigChecksum(sidecar)
+		}
+		delete(pod.Annotations, AnnotationConfigDrift)
+		if !isDryRunRequest(ctx) {
+			configDriftTotal.WithLabelValues(DriftActionPatched).Inc()
+		}
+		return
+	}
+
+	message := "sidecar " + strings.Join(drifted, ", ") + " out of date with pod configuration; recreate the pod to apply"
+	if pod.Annotations[AnnotationConfigDrift] == message {
+		return
+	}
+	if pod.Annotations == nil {
+		pod.Annotations = make(map[string]string)
+	}
+	pod.Annotations[AnnotationConfigDrift] = message
+	if isDryRunRequest(ctx) {
+		return
+	}
+	configDriftTotal.WithLabelValues(DriftActionReported).Inc()
+	podLogger(pod).Info("Detected sidecar configuration drift", "env", drifted)
+	if d.Recorder != nil {
+		d.Recorder.Event(pod, corev1.EventTypeWarning, EventReasonConfigDrift, message)
+	}
+}
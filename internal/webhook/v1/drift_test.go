@@ -0,0 +1,156 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// injectedPod returns a pod as it looks after injection with the given headers.
+func injectedPod(t *testing.T, headers string) *corev1.Pod {
+	t.Helper()
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "api",
+			Namespace: "default",
+			Annotations: map[string]string{
+				AnnotationEnabled: "true",
+				AnnotationHeaders: headers,
+			},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+	}
+	require.NoError(t, (&PodCustomDefaulter{ProxyImage: DefaultProxyImage}).Default(context.Background(), pod))
+	require.NotNil(t, proxyContainer(pod))
+	return pod
+}
+
+func updateContext() context.Context {
+	return admission.NewContextWithRequest(context.Background(), admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{Operation: admissionv1.Update, Namespace: "default"},
+	})
+}
+
+func TestPodCustomDefaulter_UpdateReportsDrift(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage, Recorder: recorder}
+	before := testutil.ToFloat64(configDriftTotal.WithLabelValues(DriftActionReported))
+
+	pod := injectedPod(t, "x-request-id")
+	pod.Annotations[AnnotationHeaders] = "x-request-id,x-tenant-id"
+
+	require.NoError(t, defaulter.Default(updateContext(), pod))
+
+	assert.Equal(t, "x-request-id", sidecarEnv(t, pod, "HEADERS_TO_PROPAGATE"), "container spec must not change on update")
+	assert.Contains(t, pod.Annotations[AnnotationConfigDrift], "HEADERS_TO_PROPAGATE")
+	assert.Equal(t, before+1, testutil.ToFloat64(configDriftTotal.WithLabelValues(DriftActionReported)))
+	require.Len(t, recorder.Events, 1)
+	assert.Contains(t, <-recorder.Events, "Warning ConfigDrift")
+
+	// Reporting the same drift again must not emit another event
+	require.NoError(t, defaulter.Default(updateContext(), pod))
+	assert.Empty(t, recorder.Events)
+
+	// Reverting the annotation clears the drift marker
+	pod.Annotations[AnnotationHeaders] = "x-request-id"
+	require.NoError(t, defaulter.Default(updateContext(), pod))
+	assert.NotContains(t, pod.Annotations, AnnotationConfigDrift)
+}
+
+func TestPodCustomDefaulter_DryRunHasNoSideEffects(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage, Recorder: recorder}
+	before := testutil.ToFloat64(configDriftTotal.WithLabelValues(DriftActionReported))
+
+	pod := injectedPod(t, "x-request-id")
+	pod.Annotations[AnnotationHeaders] = "x-request-id,x-tenant-id"
+	dryRun := true
+	ctx := admission.NewContextWithRequest(context.Background(), admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{Operation: admissionv1.Update, Namespace: "default", DryRun: &dryRun},
+	})
+
+	require.NoError(t, defaulter.Default(ctx, pod))
+
+	assert.Contains(t, pod.Annotations[AnnotationConfigDrift], "HEADERS_TO_PROPAGATE", "the response still shows the drift")
+	assert.Equal(t, before, testutil.ToFloat64(configDriftTotal.WithLabelValues(DriftActionReported)))
+	assert.Empty(t, recorder.Events)
+
+	// Creates are injected, or skipped, without being counted
+	injected := testutil.ToFloat64(injectionsTotal)
+	skipped := testutil.ToFloat64(injectionsSkippedTotal.WithLabelValues(SkipReasonNotEnabled))
+	ctx = admission.NewContextWithRequest(context.Background(), admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{Operation: admissionv1.Create, Namespace: "default", DryRun: &dryRun},
+	})
+	pod = &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "api",
+			Annotations: map[string]string{AnnotationEnabled: "true", AnnotationHeaders: "x-request-id"},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+	}
+	require.NoError(t, defaulter.Default(ctx, pod))
+	assert.Len(t, pod.Spec.Containers, 2, "the response still shows the injection")
+	require.NoError(t, defaulter.Default(ctx, &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+	}))
+	assert.Equal(t, injected, testutil.ToFloat64(injectionsTotal))
+	assert.Equal(t, skipped, testutil.ToFloat64(injectionsSkippedTotal.WithLabelValues(SkipReasonNotEnabled)))
+}
+
+func TestPodCustomDefaulter_UpdateWithoutSidecar(t *testing.T) {
+	defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "api",
+			Annotations: map[string]string{AnnotationEnabled: "true", AnnotationHeaders: "x-request-id"},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+	}
+
+	require.NoError(t, defaulter.Default(updateContext(), pod))
+	assert.Len(t, pod.Spec.Containers, 1, "sidecars are never added on update")
+	assert.NotContains(t, pod.Annotations, AnnotationConfigDrift)
+}
+
+func TestPodCustomDefaulter_CreatePatchesStaleSidecar(t *testing.T) {
+	defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage}
+	before := testutil.ToFloat64(configDriftTotal.WithLabelValues(DriftActionPatched))
+
+	// A pod recreated from a spec that still carries the old sidecar
+	pod := injectedPod(t, "x-request-id")
+	pod.Annotations[AnnotationHeaderRules] = `[{"name":"x-request-id","generate":true}]`
+	pod.Annotations[AnnotationHeaders] = ""
+
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+
+	assert.Len(t, pod.Spec.Containers, 2)
+	assert.Equal(t, `[{"name":"x-request-id","generate":true}]`, sidecarEnv(t, pod, "HEADER_RULES"))
+	assert.Empty(t, sidecarEnv(t, pod, "HEADERS_TO_PROPAGATE"))
+	assert.Equal(t, before+1, testutil.ToFloat64(configDriftTotal.WithLabelValues(DriftActionPatched)))
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"encoding/json"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+)
+
+const (
+	// AnnotationDryRun previews injection without changing the pod ("true"/"false")
+	AnnotationDryRun = "ctxforge.io/dry-run"
+	// AnnotationDryRunResult is written by the webhook in dry-run mode with a
+	// JSON description of what would have been injected.
+	AnnotationDryRunResult = "ctxforge.io/dry-run-result"
+)
+
+// dryRunResult describes the changes injection would make to a pod.
+type dryRunResult struct {
+	// Sidecar is the proxy container that would be added.
+	Sidecar dryRunContainer `json:"sidecar"`
+	// InitContainers lists other init containers that would be added.
+	InitContainers []string `json:"initContainers,omitempty"`
+	// AppEnv lists, per app container, env vars that would be added or changed.
+	AppEnv map[string]map[string]string `json:"appEnv,omitempty"`
+	// Volumes lists pod volumes that would be added.
+	Volumes []string `json:"volumes,omitempty"`
+	// Labels lists pod labels that would be added or changed.
+	Labels map[string]string `json:"labels,omitempty"`
+	// Annotations lists pod annotations that would be added or changed.
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// dryRunContainer summarizes the injected proxy container.
+type dryRunContainer struct {
+	Image string `json:"image"`
+	// Placement is "container", "first container" or "native sidecar".
+	Placement string            `json:"placement"`
+	Env       map[string]string `json:"env"`
+}
+
+// isDryRun checks if the pod asks for an injection preview
+func isDryRun(pod *corev1.Pod) bool {
+	return pod.Annotations[AnnotationDryRun] == AnnotationValueTrue
+}
+
+// recordDryRun injects into a copy of the pod and stores the differences in the
+// ctxforge.io/dry-run-result annotation, leaving the pod otherwise unchanged.
+func (d *PodCustomDefaulter) recordDryRun(pod *corev1.Pod, ns *corev1.Namespace, headers []string, headerRules string, policies *appliedPolicies) {
+	injected := pod.DeepCopy()
+	d.applyInjection(injected, ns, headers, headerRules, policies)
+
+	result, err := json.Marshal(describeInjection(pod, injected))
+	if err != nil {
+		podLogger(pod).Error(err, "Failed to describe dry-run injection")
+		return
+	}
+	podLogger(pod).Info("Dry run: not injecting sidecar", "result", string(result))
+	if pod.Annotations == nil {
+		pod.Annotations = make(map[string]string)
+	}
+	pod.Annotations[AnnotationDryRunResult] = string(result)
+}
+
+// describeInjection compares a pod before and after injection.
+func describeInjection(before, after *corev1.Pod) dryRunResult {
+	var result dryRunResult
+
+	for i, container := range after.Spec.Containers {
+		if container.Name == ProxyContainerName {
+			result.Sidecar = summarizeSidecar(container)
+			result.Sidecar.Placement = "container"
+			if i == 0 && len(after.Spec.Containers) > 1 {
+				result.Sidecar.Placement = "first container"
+			}
+		}
+	}
+
+	existingInit := make(map[string]bool, len(before.Spec.InitContainers))
+	for _, container := range before.Spec.InitContainers {
+		existingInit[container.Name] = true
+	}
+	for _, container := range after.Spec.InitContainers {
+		switch {
+		case existingInit[container.Name]:
+		case container.Name == ProxyContainerName:
+			result.Sidecar = summarizeSidecar(container)
+			result.Sidecar.Placement = "native sidecar"
+		default:
+			result.InitContainers = append(result.InitContainers, container.Name)
+		}
+	}
+
+	for i, container := range after.Spec.Containers {
+		if container.Name == ProxyContainerName {
+			continue
+		}
+		var original []corev1.EnvVar
+		for _, c := range before.Spec.Containers {
+			if c.Name == container.Name {
+				original = c.Env
+			}
+		}
+		changed := map[string]string{}
+		for _, env := range after.Spec.Containers[i].Env {
+			if j := findEnv(original, env.Name); j >= 0 && equality.Semantic.DeepEqual(original[j], env) {
+				continue
+			}
+			changed[env.Name] = envValue(env)
+		}
+		if len(changed) > 0 {
+			if result.AppEnv == nil {
+				result.AppEnv = make(map[string]map[string]string)
+			}
+			result.AppEnv[container.Name] = changed
+		}
+	}
+
+	existingVolumes := make(map[string]bool, len(before.Spec.Volumes))
+	for _, volume := range before.Spec.Volumes {
+		existingVolumes[volume.Name] = true
+	}
+	for _, volume := range after.Spec.Volumes {
+		if !existingVolumes[volume.Name] {
+			result.Volumes = append(result.Volumes, volume.Name)
+		}
+	}
+
+	for key, value := range after.Labels {
+		if before.Labels[key] != value {
+			if result.Labels == nil {
+				result.Labels = make(map[string]string)
+			}
+			result.Labels[key] = value
+		}
+	}
+
+	for key, value := range after.Annotations {
+		if before.Annotations[key] != value {
+			if result.Annotations == nil {
+				result.Annotations = make(map[string]string)
+			}
+			result.Annotations[key] = value
+		}
+	}
+
+	return result
+}
+
+// summarizeSidecar lists the proxy's image and env
+func summarizeSidecar(container corev1.Container) dryRunContainer {
+	summary := dryRunContainer{Image: container.Image, Env: make(map[string]string, len(container.Env))}
+	for _, env := range container.Env {
+		summary.Env[env.Name] = envValue(env)
+	}
+	return summary
+}
+
+// envValue renders an env var's value, showing references by their source
+func envValue(env corev1.EnvVar) string {
+	switch {
+	case env.ValueFrom == nil:
+		return env.Value
+	case env.ValueFrom.FieldRef != nil:
+		return "<field " + env.ValueFrom.FieldRef.FieldPath + ">"
+	default:
+		return "<reference>"
+	}
+}
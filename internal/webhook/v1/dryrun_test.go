@@ -0,0 +1,75 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestPodCustomDefaulter_DryRun(t *testing.T) {
+	defaulter := &PodCustomDefaulter{
+		ProxyImage:        DefaultProxyImage,
+		RedirectInitImage: "ctxforge-init:test",
+		DrainTimeout:      DefaultDrainTimeout,
+		SidecarOrder:      SidecarOrderFirst,
+	}
+	pod := orderingPod("")
+	pod.Annotations[AnnotationDryRun] = "true"
+	pod.Annotations[AnnotationRedirectMode] = RedirectModeIptables
+	pod.Spec.Containers[0].Env = []corev1.EnvVar{{Name: "APP_MODE", Value: "prod"}}
+	original := pod.DeepCopy()
+
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+
+	// Only the result annotation is added
+	raw := pod.Annotations[AnnotationDryRunResult]
+	require.NotEmpty(t, raw)
+	delete(pod.Annotations, AnnotationDryRunResult)
+	assert.Equal(t, original, pod)
+
+	var result dryRunResult
+	require.NoError(t, json.Unmarshal([]byte(raw), &result))
+	assert.Equal(t, DefaultProxyImage, result.Sidecar.Image)
+	assert.Equal(t, "first container", result.Sidecar.Placement)
+	assert.Equal(t, "x-request-id", result.Sidecar.Env["HEADERS_TO_PROPAGATE"])
+	assert.Equal(t, "<field metadata.name>", result.Sidecar.Env["POD_NAME"])
+	assert.Equal(t, []string{RedirectInitContainerName}, result.InitContainers)
+	assert.Equal(t, "http://localhost:9090", result.AppEnv["app"]["HTTP_PROXY"])
+	assert.NotContains(t, result.AppEnv["app"], "APP_MODE")
+	assert.Contains(t, result.AppEnv, "worker")
+	assert.Equal(t, "true", result.Annotations[AnnotationInjected])
+}
+
+func TestPodCustomDefaulter_DryRunNativeSidecar(t *testing.T) {
+	defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage, NativeSidecar: true}
+	pod := orderingPod("")
+	pod.Annotations[AnnotationDryRun] = "true"
+
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+
+	assert.Empty(t, pod.Spec.InitContainers)
+	var result dryRunResult
+	require.NoError(t, json.Unmarshal([]byte(pod.Annotations[AnnotationDryRunResult]), &result))
+	assert.Equal(t, "native sidecar", result.Sidecar.Placement)
+	assert.Empty(t, result.InitContainers)
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"encoding/json"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// AnnotationProxyEphemeral set to "true" points ephemeral debug containers
+// (kubectl debug) at the proxy too. By default they reach the network directly.
+const AnnotationProxyEphemeral = "ctxforge.io/proxy-ephemeral-containers"
+
+// ephemeralContainersSubresource is the pod subresource kubectl debug updates
+const ephemeralContainersSubresource = "ephemeralcontainers"
+
+// isEphemeralContainersRequest checks if the admission request adds ephemeral
+// containers rather than updating the pod itself.
+func isEphemeralContainersRequest(ctx context.Context) bool {
+	req, err := admission.RequestFromContext(ctx)
+	return err == nil && req.SubResource == ephemeralContainersSubresource
+}
+
+// proxyEphemeralContainers sets HTTP_PROXY and NO_PROXY on ephemeral containers
+// added by the request, for injected pods that opt in. Existing ephemeral
+// containers are immutable and left alone.
+func (d *PodCustomDefaulter) proxyEphemeralContainers(ctx context.Context, pod *corev1.Pod) {
+	if pod.Annotations[AnnotationProxyEphemeral] != AnnotationValueTrue || proxyContainer(pod) == nil {
+		return
+	}
+
+	existing := make(map[string]bool)
+	if req, err := admission.RequestFromContext(ctx); err == nil && len(req.OldObject.Raw) > 0 {
+		old := &corev1.Pod{}
+		if err := json.Unmarshal(req.OldObject.Raw, old); err != nil {
+			podLogger(pod).Error(err, "Failed to decode old pod, leaving ephemeral containers unchanged")
+			return
+		}
+		for _, container := range old.Spec.EphemeralContainers {
+			existing[container.Name] = true
+		}
+	}
+	skip := make(map[string]bool)
+	for _, name := range splitAnnotationList(pod.Annotations[AnnotationSkipContainers]) {
+		skip[name] = true
+	}
+
+	for i := range pod.Spec.EphemeralContainers {
+		ephemeral := &pod.Spec.EphemeralContainers[i]
+		if existing[ephemeral.Name] || skip[ephemeral.Name] {
+			continue
+		}
+		container := corev1.Container(ephemeral.EphemeralContainerCommon)
+		d.mergeProxyEnv(pod, &container)
+		ephemeral.Env = container.Env
+		podLogger(pod).Info("Pointing ephemeral container at the proxy", "container", ephemeral.Name)
+	}
+}
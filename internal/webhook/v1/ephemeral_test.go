@@ -0,0 +1,96 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func ephemeralContext(t *testing.T, old *corev1.Pod) context.Context {
+	t.Helper()
+	raw, err := json.Marshal(old)
+	require.NoError(t, err)
+	return admission.NewContextWithRequest(context.Background(), admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation:   admissionv1.Update,
+			SubResource: ephemeralContainersSubresource,
+			Namespace:   "default",
+			OldObject:   runtime.RawExtension{Raw: raw},
+		},
+	})
+}
+
+func addDebugContainer(pod *corev1.Pod, name string) {
+	pod.Spec.EphemeralContainers = append(pod.Spec.EphemeralContainers, corev1.EphemeralContainer{
+		EphemeralContainerCommon: corev1.EphemeralContainerCommon{Name: name, Image: "busybox"},
+		TargetContainerName:      "app",
+	})
+}
+
+func TestPodCustomDefaulter_EphemeralContainers(t *testing.T) {
+	t.Run("left alone by default", func(t *testing.T) {
+		defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage}
+		pod := orderingPod("")
+		require.NoError(t, defaulter.Default(context.Background(), pod))
+		old := pod.DeepCopy()
+		addDebugContainer(pod, "debugger")
+
+		require.NoError(t, defaulter.Default(ephemeralContext(t, old), pod))
+
+		assert.Empty(t, pod.Spec.EphemeralContainers[0].Env)
+		assert.NotContains(t, pod.Annotations, AnnotationConfigDrift)
+	})
+
+	t.Run("opted in pods proxy new debug containers", func(t *testing.T) {
+		defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage}
+		pod := orderingPod("")
+		pod.Annotations[AnnotationProxyEphemeral] = AnnotationValueTrue
+		require.NoError(t, defaulter.Default(context.Background(), pod))
+		addDebugContainer(pod, "first")
+		old := pod.DeepCopy()
+		addDebugContainer(pod, "second")
+
+		require.NoError(t, defaulter.Default(ephemeralContext(t, old), pod))
+
+		assert.Empty(t, pod.Spec.EphemeralContainers[0].Env, "existing ephemeral containers are immutable")
+		second := corev1.Container(pod.Spec.EphemeralContainers[1].EphemeralContainerCommon)
+		assert.GreaterOrEqual(t, findEnv(second.Env, "HTTP_PROXY"), 0)
+		assert.GreaterOrEqual(t, findEnv(second.Env, "NO_PROXY"), 0)
+	})
+
+	t.Run("pods without the proxy are left alone", func(t *testing.T) {
+		defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage}
+		pod := orderingPod("")
+		pod.Annotations[AnnotationProxyEphemeral] = AnnotationValueTrue
+		old := pod.DeepCopy()
+		addDebugContainer(pod, "debugger")
+
+		require.NoError(t, defaulter.Default(ephemeralContext(t, old), pod))
+
+		assert.Empty(t, pod.Spec.EphemeralContainers[0].Env)
+		assert.Nil(t, proxyContainer(pod))
+	})
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// isJobPod checks if the pod is owned by a Job, including Jobs created by CronJobs.
+func isJobPod(pod *corev1.Pod) bool {
+	for _, ref := range pod.OwnerReferences {
+		if ref.Kind == "Job" && ref.APIVersion == "batch/v1" {
+			return true
+		}
+	}
+	return false
+}
+
+// useNativeSidecar decides whether the proxy goes into initContainers. Job pods
+// get a native sidecar whenever the cluster supports it, because a regular
+// sidecar keeps running after the Job's containers finish.
+func (d *PodCustomDefaulter) useNativeSidecar(pod *corev1.Pod) bool {
+	return d.NativeSidecar || (d.JobNativeSidecar && isJobPod(pod))
+}
+
+// enableExitOnAppExit lets a regular sidecar in a Job pod see the app processes
+// and stop once they are gone, so the pod can reach Completed.
+func enableExitOnAppExit(pod *corev1.Pod, sidecar *corev1.Container) {
+	if pod.Spec.ShareProcessNamespace == nil || !*pod.Spec.ShareProcessNamespace {
+		podLogger(pod).Info("Sharing the process namespace so the proxy stops with the Job")
+		pod.Spec.ShareProcessNamespace = boolPtr(true)
+	}
+	sidecar.Env = append(sidecar.Env, corev1.EnvVar{Name: "EXIT_ON_APP_EXIT", Value: AnnotationValueTrue})
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func jobPod() *corev1.Pod {
+	pod := orderingPod("")
+	pod.OwnerReferences = []metav1.OwnerReference{{APIVersion: "batch/v1", Kind: "Job", Name: "migrate"}}
+	pod.Spec.RestartPolicy = corev1.RestartPolicyNever
+	return pod
+}
+
+func TestIsJobPod(t *testing.T) {
+	assert.True(t, isJobPod(jobPod()))
+	assert.False(t, isJobPod(orderingPod("")))
+
+	pod := orderingPod("")
+	pod.OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "api-6d4b"}}
+	assert.False(t, isJobPod(pod))
+}
+
+func TestPodCustomDefaulter_JobNativeSidecar(t *testing.T) {
+	defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage, JobNativeSidecar: true}
+
+	pod := jobPod()
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+
+	require.Len(t, pod.Spec.InitContainers, 1)
+	assert.Equal(t, ProxyContainerName, pod.Spec.InitContainers[0].Name)
+	assert.Len(t, pod.Spec.Containers, 2)
+	assert.Nil(t, pod.Spec.ShareProcessNamespace)
+
+	// Other pods keep the regular sidecar
+	pod = orderingPod("")
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+	assert.Empty(t, pod.Spec.InitContainers)
+	assert.Len(t, pod.Spec.Containers, 3)
+}
+
+func TestPodCustomDefaulter_JobExitWatcher(t *testing.T) {
+	defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage}
+
+	pod := jobPod()
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+
+	require.Len(t, pod.Spec.Containers, 3)
+	require.NotNil(t, pod.Spec.ShareProcessNamespace)
+	assert.True(t, *pod.Spec.ShareProcessNamespace)
+	assert.Equal(t, "true", sidecarEnv(t, pod, "EXIT_ON_APP_EXIT"))
+
+	// Regular pods are left alone
+	pod = orderingPod("")
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+	assert.Nil(t, pod.Spec.ShareProcessNamespace)
+	assert.Equal(t, -1, findEnv(proxyContainer(pod).Env, "EXIT_ON_APP_EXIT"))
+}
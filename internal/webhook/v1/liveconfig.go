@@ -0,0 +1,237 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/bgruszka/contextforge/internal/config"
+	"github.com/bgruszka/contextforge/internal/policy"
+)
+
+// AnnotationLiveHeaderRules carries the header rules the operator pushes to a
+// running sidecar when the pod's configuration changes after injection.
+const AnnotationLiveHeaderRules = config.LiveHeaderRulesAnnotation
+
+const (
+	// podInfoVolume exposes the pod's annotations to the sidecar
+	podInfoVolume = "ctxforge-podinfo"
+	// podInfoDir is where the pod info volume is mounted in the sidecar
+	podInfoDir = "/etc/ctxforge/podinfo"
+	// LiveConfigFile is the downward API file the sidecar watches for pushed rules
+	LiveConfigFile = podInfoDir + "/annotations"
+
+	// RulesConfigMapName is the ConfigMap the operator renders each
+	// namespace's header rules into, keyed by pod name
+	RulesConfigMapName = "ctxforge-rules"
+	// rulesVolume mounts the rules ConfigMap into the sidecar
+	rulesVolume = "ctxforge-rules"
+	// rulesDir is where the rules ConfigMap is mounted in the sidecar
+	rulesDir = "/etc/ctxforge/rules"
+)
+
+// NewLiveConfigSyncer returns a PodCustomDefaulter that resolves header
+// configuration the same way the webhook does, for use by the operator's pod
+// controllers through SyncLiveConfig, SyncPolicyAnnotations and AdoptPod.
+func NewLiveConfigSyncer(c client.Reader) *PodCustomDefaulter {
+	// The operator doesn't start with an invalid POLICY_DEFAULTS_CONFIGMAP
+	policyDefaults, _ := policy.DefaultsFromEnv(c)
+	return &PodCustomDefaulter{
+		Client:         c,
+		Defaults:       sidecarDefaultsFromEnv(),
+		PolicyDefaults: policyDefaults,
+		Revision:       os.Getenv("INJECTION_REVISION"),
+		LiveConfig:     true,
+	}
+}
+
+// addLiveConfig lets the sidecar pick up header rules at runtime: from the
+// operator's rule stream when RuleStreamAddress is set, from its key of the
+// rules ConfigMap with RulesConfigMap, otherwise from the
+// ctxforge.io/live-header-rules annotation, mounted through the downward API.
+func (d *PodCustomDefaulter) addLiveConfig(pod *corev1.Pod, sidecar *corev1.Container) {
+	if d.RuleStreamAddress != "" {
+		sidecar.Env = append(sidecar.Env,
+			corev1.EnvVar{Name: "RULE_STREAM_ADDRESS", Value: d.RuleStreamAddress},
+			corev1.EnvVar{
+				Name: "POD_NAMESPACE",
+				ValueFrom: &corev1.EnvVarSource{
+					FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.namespace"},
+				},
+			})
+		return
+	}
+	if d.RulesConfigMap {
+		optional := true
+		pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+			Name: rulesVolume,
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: RulesConfigMapName},
+					// Pods start before the operator first renders the ConfigMap
+					Optional: &optional,
+				},
+			},
+		})
+		sidecar.VolumeMounts = append(sidecar.VolumeMounts, corev1.VolumeMount{
+			Name:      rulesVolume,
+			MountPath: rulesDir,
+			ReadOnly:  true,
+		})
+		// POD_NAME is set earlier in the sidecar's env, so the kubelet expands it
+		sidecar.Env = append(sidecar.Env, corev1.EnvVar{Name: "RULES_FILE", Value: rulesDir + "/$(POD_NAME)"})
+		return
+	}
+	if !d.LiveConfig {
+		return
+	}
+	pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+		Name: podInfoVolume,
+		VolumeSource: corev1.VolumeSource{
+			DownwardAPI: &corev1.DownwardAPIVolumeSource{
+				Items: []corev1.DownwardAPIVolumeFile{{
+					Path:     "annotations",
+					FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.annotations"},
+				}},
+			},
+		},
+	})
+	sidecar.VolumeMounts = append(sidecar.VolumeMounts, corev1.VolumeMount{
+		Name:      podInfoVolume,
+		MountPath: podInfoDir,
+		ReadOnly:  true,
+	})
+	sidecar.Env = append(sidecar.Env, corev1.EnvVar{Name: "LIVE_CONFIG_FILE", Value: LiveConfigFile})
+}
+
+// usesLiveConfig reports whether the sidecar picks up header rules at
+// runtime, through the annotation, the rule stream or the rules ConfigMap.
+func usesLiveConfig(sidecar *corev1.Container) bool {
+	return findEnv(sidecar.Env, "LIVE_CONFIG_FILE") >= 0 || findEnv(sidecar.Env, "RULE_STREAM_ADDRESS") >= 0 ||
+		findEnv(sidecar.Env, "RULES_FILE") >= 0
+}
+
+// liveHeaderRules converts the resolved header configuration to the
+// HEADER_RULES format the sidecar accepts at runtime.
+func liveHeaderRules(headers []string, headerRules string) (string, error) {
+	if headerRules != "" {
+		return headerRules, nil
+	}
+	rules := make([]config.HeaderRule, 0, len(headers))
+	for _, header := range headers {
+		rules = append(rules, config.HeaderRule{Name: header, Propagate: true})
+	}
+	data, err := json.Marshal(rules)
+	return string(data), err
+}
+
+// LiveHeaderRules returns the header rules to push to the pod's running
+// sidecar, in HEADER_RULES format. It returns "" while the sidecar's env
+// matches the pod's current configuration, and for pods that don't pick up
+// rules at runtime or belong to another revision.
+func (d *PodCustomDefaulter) LiveHeaderRules(ctx context.Context, pod *corev1.Pod) (string, error) {
+	sidecar := proxyContainer(pod)
+	if sidecar == nil || !usesLiveConfig(sidecar) {
+		return "", nil
+	}
+	ns := d.lookupNamespace(ctx, pod)
+	if !d.ownsPod(pod, ns) {
+		return "", nil
+	}
+
+	headers, headerRules, _, err := d.resolveHeaders(ctx, pod, ns)
+	if err != nil {
+		return "", err
+	}
+	drifted := len(driftedEnv(sidecar, desiredProxyEnv(headers, headerRules))) > 0
+	if !drifted || (len(headers) == 0 && headerRules == "") {
+		return "", nil
+	}
+	return liveHeaderRules(headers, headerRules)
+}
+
+// SyncLiveConfig brings the ctxforge.io/live-header-rules annotation of a
+// running pod in line with LiveHeaderRules: it holds the rules while they
+// differ from the sidecar's env and is removed once they match again. The
+// config checksum annotation follows, so the proxy's /config endpoint can
+// confirm the push. It reports whether the pod was modified.
+func (d *PodCustomDefaulter) SyncLiveConfig(ctx context.Context, pod *corev1.Pod) (bool, error) {
+	sidecar := proxyContainer(pod)
+	if sidecar == nil || findEnv(sidecar.Env, "LIVE_CONFIG_FILE") < 0 {
+		return false, nil
+	}
+	if !d.ownsPod(pod, d.lookupNamespace(ctx, pod)) {
+		return false, nil
+	}
+	live, err := d.LiveHeaderRules(ctx, pod)
+	if err != nil {
+		return false, err
+	}
+	checksum := sidecarConfigChecksum(sidecar)
+	if live != "" {
+		checksum = config.ConfigChecksum("", live)
+	}
+
+	changed := false
+	if pod.Annotations[AnnotationLiveHeaderRules] != live {
+		if pod.Annotations == nil {
+			pod.Annotations = make(map[string]string)
+		}
+		if live == "" {
+			delete(pod.Annotations, AnnotationLiveHeaderRules)
+		} else {
+			pod.Annotations[AnnotationLiveHeaderRules] = live
+		}
+		changed = true
+	}
+	if current, ok := pod.Annotations[AnnotationConfigChecksum]; ok && current != checksum {
+		pod.Annotations[AnnotationConfigChecksum] = checksum
+		changed = true
+	}
+	if _, ok := pod.Annotations[AnnotationConfigDrift]; ok {
+		delete(pod.Annotations, AnnotationConfigDrift)
+		changed = true
+	}
+	if changed {
+		podLogger(pod).Info("Updated live header rules", "live", live != "")
+	}
+	return changed, nil
+}
+
+// EffectiveHeaderRules returns the header rules the pod's current
+// configuration resolves to, in HEADER_RULES format, for the operator's rules
+// ConfigMap. It returns "" for pods without the sidecar, of another revision,
+// or without any header configuration.
+func (d *PodCustomDefaulter) EffectiveHeaderRules(ctx context.Context, pod *corev1.Pod) (string, error) {
+	if proxyContainer(pod) == nil {
+		return "", nil
+	}
+	ns := d.lookupNamespace(ctx, pod)
+	if !d.ownsPod(pod, ns) {
+		return "", nil
+	}
+	headers, headerRules, _, err := d.resolveHeaders(ctx, pod, ns)
+	if err != nil || (len(headers) == 0 && headerRules == "") {
+		return "", err
+	}
+	return liveHeaderRules(headers, headerRules)
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/bgruszka/contextforge/internal/config"
+)
+
+func livePod(t *testing.T, headers string) *corev1.Pod {
+	t.Helper()
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "api",
+			Namespace:   "default",
+			Annotations: map[string]string{AnnotationEnabled: "true", AnnotationHeaders: headers},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+	}
+	defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage, LiveConfig: true, ReadinessGate: true}
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+	return pod
+}
+
+func TestPodCustomDefaulter_LiveConfigVolume(t *testing.T) {
+	pod := livePod(t, "x-request-id")
+
+	assert.Equal(t, LiveConfigFile, sidecarEnv(t, pod, "LIVE_CONFIG_FILE"))
+	var volume *corev1.Volume
+	for i := range pod.Spec.Volumes {
+		if pod.Spec.Volumes[i].Name == podInfoVolume {
+			volume = &pod.Spec.Volumes[i]
+		}
+	}
+	require.NotNil(t, volume)
+	require.NotNil(t, volume.DownwardAPI)
+	assert.Equal(t, "metadata.annotations", volume.DownwardAPI.Items[0].FieldRef.FieldPath)
+	assert.Contains(t, proxyContainer(pod).VolumeMounts, corev1.VolumeMount{
+		Name: podInfoVolume, MountPath: podInfoDir, ReadOnly: true,
+	})
+
+	// Without the option the sidecar is not given the pod's annotations
+	pod = orderingPod("")
+	require.NoError(t, (&PodCustomDefaulter{ProxyImage: DefaultProxyImage}).Default(context.Background(), pod))
+	assert.Negative(t, findEnv(proxyContainer(pod).Env, "LIVE_CONFIG_FILE"))
+}
+
+func TestPodCustomDefaulter_SyncLiveConfig(t *testing.T) {
+	syncer := NewLiveConfigSyncer(newFakeClient(t))
+	pod := livePod(t, "x-request-id")
+	injected := pod.Annotations[AnnotationConfigChecksum]
+
+	changed, err := syncer.SyncLiveConfig(context.Background(), pod)
+	require.NoError(t, err)
+	assert.False(t, changed, "an up-to-date pod is left alone")
+
+	// The pod update changing the headers is not reported as drift
+	pod.Annotations[AnnotationHeaders] = "x-request-id,x-tenant-id"
+	require.NoError(t, syncer.Default(updateContext(), pod))
+	assert.NotContains(t, pod.Annotations, AnnotationConfigDrift)
+
+	changed, err = syncer.SyncLiveConfig(context.Background(), pod)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	live := pod.Annotations[AnnotationLiveHeaderRules]
+	rules, err := config.ParseHeaderRules(live)
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	assert.Equal(t, "x-tenant-id", rules[1].Name)
+	assert.Equal(t, config.ConfigChecksum("", live), pod.Annotations[AnnotationConfigChecksum])
+	assert.Equal(t, "x-request-id", sidecarEnv(t, pod, "HEADERS_TO_PROPAGATE"), "container spec must not change")
+
+	// Reverting the annotation withdraws the pushed rules
+	pod.Annotations[AnnotationHeaders] = "x-request-id"
+	changed, err = syncer.SyncLiveConfig(context.Background(), pod)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.NotContains(t, pod.Annotations, AnnotationLiveHeaderRules)
+	assert.Equal(t, injected, pod.Annotations[AnnotationConfigChecksum])
+
+	// Pods injected without live config keep the restart-to-apply behavior
+	pod = injectedPod(t, "x-request-id")
+	pod.Annotations[AnnotationHeaders] = "x-tenant-id"
+	changed, err = syncer.SyncLiveConfig(context.Background(), pod)
+	require.NoError(t, err)
+	assert.False(t, changed)
+}
+
+func TestPodCustomDefaulter_RuleStream(t *testing.T) {
+	defaulter := &PodCustomDefaulter{
+		ProxyImage:        DefaultProxyImage,
+		LiveConfig:        true,
+		RuleStreamAddress: "contextforge-rule-stream.contextforge-system.svc:18000",
+	}
+	pod := orderingPod("")
+	pod.Namespace = "default"
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+
+	sidecar := proxyContainer(pod)
+	assert.Equal(t, defaulter.RuleStreamAddress, sidecarEnv(t, pod, "RULE_STREAM_ADDRESS"))
+	i := findEnv(sidecar.Env, "POD_NAMESPACE")
+	require.GreaterOrEqual(t, i, 0)
+	assert.Equal(t, "metadata.namespace", sidecar.Env[i].ValueFrom.FieldRef.FieldPath)
+	assert.Negative(t, findEnv(sidecar.Env, "LIVE_CONFIG_FILE"), "the stream replaces the annotation")
+
+	syncer := NewLiveConfigSyncer(newFakeClient(t))
+	rules, err := syncer.LiveHeaderRules(context.Background(), pod)
+	require.NoError(t, err)
+	assert.Empty(t, rules)
+
+	pod.Annotations[AnnotationHeaders] = "x-tenant-id"
+	rules, err = syncer.LiveHeaderRules(context.Background(), pod)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"name":"x-tenant-id","propagate":true}]`, rules)
+
+	changed, err := syncer.SyncLiveConfig(context.Background(), pod)
+	require.NoError(t, err)
+	assert.False(t, changed, "streamed pods don't get the annotation")
+}
+
+func TestPodCustomDefaulter_RulesConfigMap(t *testing.T) {
+	defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage, LiveConfig: true, RulesConfigMap: true}
+	pod := orderingPod("")
+	pod.Namespace = "default"
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+
+	sidecar := proxyContainer(pod)
+	assert.Equal(t, rulesDir+"/$(POD_NAME)", sidecarEnv(t, pod, "RULES_FILE"))
+	assert.Less(t, findEnv(sidecar.Env, "POD_NAME"), findEnv(sidecar.Env, "RULES_FILE"),
+		"POD_NAME must precede RULES_FILE to be expanded")
+	assert.Negative(t, findEnv(sidecar.Env, "LIVE_CONFIG_FILE"), "the ConfigMap replaces the annotation")
+	var volume *corev1.Volume
+	for i := range pod.Spec.Volumes {
+		if pod.Spec.Volumes[i].Name == rulesVolume {
+			volume = &pod.Spec.Volumes[i]
+		}
+	}
+	require.NotNil(t, volume)
+	require.NotNil(t, volume.ConfigMap)
+	assert.Equal(t, RulesConfigMapName, volume.ConfigMap.Name)
+	assert.True(t, *volume.ConfigMap.Optional)
+	assert.Contains(t, sidecar.VolumeMounts, corev1.VolumeMount{Name: rulesVolume, MountPath: rulesDir, ReadOnly: true})
+
+	// The ConfigMap carries the full rules, not only changes
+	syncer := NewLiveConfigSyncer(newFakeClient(t))
+	rules, err := syncer.EffectiveHeaderRules(context.Background(), pod)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"name":"x-request-id","propagate":true}]`, rules)
+
+	pod.Annotations[AnnotationHeaders] = "x-tenant-id"
+	rules, err = syncer.EffectiveHeaderRules(context.Background(), pod)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"name":"x-tenant-id","propagate":true}]`, rules)
+}
+
+func TestValidatePodAnnotations_LiveHeaderRules(t *testing.T) {
+	assert.Empty(t, validatePodAnnotations(map[string]string{
+		AnnotationLiveHeaderRules: `[{"name":"x-request-id"}]`,
+	}))
+	assert.NotEmpty(t, validatePodAnnotations(map[string]string{
+		AnnotationLiveHeaderRules: `[{"name":"x request"}]`,
+	}))
+}
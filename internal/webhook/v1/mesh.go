@@ -0,0 +1,311 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/bgruszka/contextforge/internal/istio"
+	"github.com/bgruszka/contextforge/internal/policy"
+)
+
+// Service meshes whose sidecar injection can interfere with ours.
+const (
+	MeshIstio   = "istio"
+	MeshLinkerd = "linkerd"
+)
+
+// Values accepted by the ISTIO_MODE env var.
+const (
+	// IstioModeSidecar injects the proxy into Istio pods like any other pod.
+	IstioModeSidecar = "sidecar"
+	// IstioModeEnvoyFilter leaves the proxy out of Istio pods whose policies
+	// the operator implements with EnvoyFilters instead.
+	IstioModeEnvoyFilter = "envoyfilter"
+)
+
+// meshProxyContainers maps the sidecar and iptables init containers of each mesh to the mesh.
+var meshProxyContainers = map[string]string{
+	"istio-proxy":      MeshIstio,
+	"istio-init":       MeshIstio,
+	"istio-validation": MeshIstio,
+	"linkerd-proxy":    MeshLinkerd,
+	"linkerd-init":     MeshLinkerd,
+}
+
+// meshInitContainers are the mesh init containers that program iptables
+var meshInitContainers = map[string]bool{
+	"istio-init":   true,
+	"linkerd-init": true,
+}
+
+// meshReservedPorts are the ports the mesh proxies listen on inside the pod.
+var meshReservedPorts = map[int]string{
+	15000: MeshIstio, 15001: MeshIstio, 15004: MeshIstio, 15006: MeshIstio, 15008: MeshIstio,
+	15020: MeshIstio, 15021: MeshIstio, 15053: MeshIstio, 15090: MeshIstio,
+	LinkerdOutboundPort: MeshLinkerd, LinkerdInboundPort: MeshLinkerd, 4190: MeshLinkerd, 4191: MeshLinkerd,
+}
+
+// Linkerd proxy defaults, as set by the linkerd proxy injector.
+//
+// Both sidecars work together in these orders:
+//   - regular containers: linkerd-proxy first, whose postStart await holds the
+//     other containers until it is ready, then ctxforge-proxy wherever
+//     ctxforge.io/sidecar-order puts it among the rest;
+//   - native sidecars: linkerd-init, linkerd-proxy, then ctxforge-proxy in
+//     initContainers.
+//
+// A native ctxforge-proxy next to a regular linkerd-proxy deadlocks: linkerd-init
+// redirects the kubelet's probes to the linkerd inbound port, so the proxy's
+// startup probe can't pass before linkerd-proxy runs, which the kubelet only
+// starts once the probe passed. The webhook injects a regular container instead.
+const (
+	// LinkerdProxyContainerName is the linkerd sidecar container.
+	LinkerdProxyContainerName = "linkerd-proxy"
+	// LinkerdInboundPort receives the pod's redirected inbound traffic,
+	// including requests to the ctxforge proxy.
+	LinkerdInboundPort = 4143
+	// LinkerdOutboundPort receives the pod's redirected outbound traffic,
+	// including the ctxforge proxy's requests to their destinations.
+	LinkerdOutboundPort = 4140
+
+	// annotationLinkerdNativeSidecar makes linkerd inject its proxy as a native
+	// sidecar, on the pod or its namespace.
+	annotationLinkerdNativeSidecar = "config.alpha.linkerd.io/proxy-enable-native-sidecar"
+)
+
+// linkerdPortAnnotations move the linkerd proxy's ports away from their defaults.
+var linkerdPortAnnotations = []string{
+	"config.linkerd.io/proxy-inbound-port",
+	"config.linkerd.io/proxy-outbound-port",
+	"config.linkerd.io/proxy-admin-port",
+	"config.linkerd.io/proxy-control-port",
+}
+
+// meshPorts returns the ports the mesh proxies listen on in the pod:
+// meshReservedPorts plus the ports its linkerd annotations move the linkerd
+// proxy to.
+func meshPorts(pod *corev1.Pod) map[int]string {
+	ports := make(map[int]string, len(meshReservedPorts)+len(linkerdPortAnnotations))
+	for port, mesh := range meshReservedPorts {
+		ports[port] = mesh
+	}
+	for _, annotation := range linkerdPortAnnotations {
+		if port, err := strconv.Atoi(pod.Annotations[annotation]); err == nil && port > 0 {
+			ports[port] = MeshLinkerd
+		}
+	}
+	return ports
+}
+
+// linkerdNativeSidecar reports whether linkerd runs, or is going to run, its
+// proxy as a native sidecar in the pod.
+func linkerdNativeSidecar(pod *corev1.Pod, ns *corev1.Namespace) bool {
+	for _, c := range pod.Spec.InitContainers {
+		if c.Name == LinkerdProxyContainerName {
+			return true
+		}
+	}
+	for _, c := range pod.Spec.Containers {
+		if c.Name == LinkerdProxyContainerName {
+			return false
+		}
+	}
+	if value, ok := pod.Annotations[annotationLinkerdNativeSidecar]; ok {
+		return value == "true"
+	}
+	return ns != nil && ns.Annotations[annotationLinkerdNativeSidecar] == "true"
+}
+
+// nativeSidecarBlocked reports whether the proxy must be injected as a regular
+// container although native sidecars are enabled, because the pod's linkerd
+// proxy is a regular container.
+func nativeSidecarBlocked(pod *corev1.Pod, ns *corev1.Namespace) bool {
+	return detectMesh(pod, ns) == MeshLinkerd && !linkerdNativeSidecar(pod, ns)
+}
+
+// leadingMeshProxies returns how many of the pod's first containers are mesh
+// proxies, which a proxy ordered first is placed after.
+func leadingMeshProxies(pod *corev1.Pod) int {
+	n := 0
+	for n < len(pod.Spec.Containers) && meshProxyContainers[pod.Spec.Containers[n].Name] != "" {
+		n++
+	}
+	return n
+}
+
+// detectMesh returns the mesh that injects, or is going to inject, a sidecar into
+// the pod, or "" if there is none. Mesh webhooks may run before or after ours, so
+// both existing containers and the mesh's opt-in labels and annotations count.
+func detectMesh(pod *corev1.Pod, ns *corev1.Namespace) string {
+	for _, containers := range [][]corev1.Container{pod.Spec.InitContainers, pod.Spec.Containers} {
+		for _, c := range containers {
+			if mesh, ok := meshProxyContainers[c.Name]; ok {
+				return mesh
+			}
+		}
+	}
+
+	istioInject := pod.Labels["sidecar.istio.io/inject"]
+	if istioInject == "" {
+		istioInject = pod.Annotations["sidecar.istio.io/inject"]
+	}
+	if istioInject == "true" {
+		return MeshIstio
+	}
+	if istioInject != "false" {
+		if pod.Labels["istio.io/rev"] != "" {
+			return MeshIstio
+		}
+		if ns != nil && (ns.Labels["istio-injection"] == "enabled" || ns.Labels["istio.io/rev"] != "") {
+			return MeshIstio
+		}
+	}
+
+	switch pod.Annotations["linkerd.io/inject"] {
+	case "enabled", "ingress":
+		return MeshLinkerd
+	case "disabled":
+		return ""
+	}
+	if ns != nil && ns.Annotations["linkerd.io/inject"] == "enabled" {
+		return MeshLinkerd
+	}
+
+	return ""
+}
+
+// servedByEnvoyFilters reports whether the pod's headers are left to the
+// EnvoyFilters the operator keeps for its policies instead of the sidecar: the
+// pod runs an istio-proxy and is configured by policies alone, all of which an
+// EnvoyFilter implements without their headers conflicting. headers are the
+// pod's resolved HEADERS_TO_PROPAGATE.
+func (d *PodCustomDefaulter) servedByEnvoyFilters(pod *corev1.Pod, ns *corev1.Namespace, headers []string, policies *appliedPolicies) bool {
+	if !d.IstioEnvoyFilters || policies == nil || len(headers) > 0 || len(namespaceDefaultHeaders(ns)) > 0 {
+		return false
+	}
+	if detectMesh(pod, ns) != MeshIstio {
+		return false
+	}
+	for _, source := range policies.Sources {
+		if istio.Translatable(source.Spec) != nil {
+			return false
+		}
+	}
+	// The sidecar resolves conflicting headers by precedence; the filters of
+	// all the policies would each apply theirs
+	return len(policy.Conflicts(policies.Sources)) == 0
+}
+
+// meshConflictWarnings describes problems between an injected ctxforge sidecar and
+// other proxies in the final pod spec, as seen by the validating webhook after all
+// mutating webhooks ran. It returns nothing for pods without the ctxforge sidecar.
+func meshConflictWarnings(pod *corev1.Pod) []string {
+	if proxyContainer(pod) == nil {
+		return nil
+	}
+
+	var warnings []string
+	mesh := detectMesh(pod, nil)
+
+	// Port conflicts with the ctxforge proxy or the mesh proxy
+	for _, containers := range [][]corev1.Container{pod.Spec.InitContainers, pod.Spec.Containers} {
+		for _, c := range containers {
+			if c.Name == ProxyContainerName {
+				continue
+			}
+			for _, port := range c.Ports {
+				if port.ContainerPort == ProxyPort {
+					warnings = append(warnings, fmt.Sprintf(
+						"container %q also uses port %d, which the ctxforge proxy listens on", c.Name, ProxyPort))
+				}
+			}
+		}
+	}
+	reserved := meshPorts(pod)
+	if port, err := strconv.Atoi(pod.Annotations[AnnotationTargetPort]); err == nil {
+		if owner, ok := reserved[port]; ok && owner == mesh {
+			warnings = append(warnings, fmt.Sprintf(
+				"ctxforge.io/target-port %d is reserved by the %s proxy; set it to the application port", port, mesh))
+		}
+	}
+	if mesh == MeshLinkerd {
+		for _, annotation := range linkerdPortAnnotations {
+			if pod.Annotations[annotation] == strconv.Itoa(ProxyPort) {
+				warnings = append(warnings, fmt.Sprintf(
+					"%s moves the linkerd proxy to port %d, which the ctxforge proxy listens on", annotation, ProxyPort))
+			}
+		}
+	}
+
+	// Two init containers rewriting the same nat table
+	var hasRedirectInit bool
+	var meshInit string
+	for _, c := range pod.Spec.InitContainers {
+		if c.Name == RedirectInitContainerName {
+			hasRedirectInit = true
+		}
+		if meshInitContainers[c.Name] {
+			meshInit = c.Name
+		}
+	}
+	if hasRedirectInit && meshInit != "" {
+		warnings = append(warnings, fmt.Sprintf(
+			"both %q and %q program iptables redirects; remove ctxforge.io/redirect-mode and rely on HTTP_PROXY",
+			RedirectInitContainerName, meshInit))
+	}
+
+	// A native ctxforge sidecar started before a native mesh sidecar cannot reach the network yet
+	ctxforgeIndex := -1
+	for i, c := range pod.Spec.InitContainers {
+		if c.Name == ProxyContainerName {
+			ctxforgeIndex = i
+		}
+		if _, isMesh := meshProxyContainers[c.Name]; isMesh && !meshInitContainers[c.Name] &&
+			ctxforgeIndex >= 0 && c.RestartPolicy != nil && *c.RestartPolicy == corev1.ContainerRestartPolicyAlways {
+			warnings = append(warnings, fmt.Sprintf(
+				"%q starts before the mesh sidecar %q; outbound requests may fail until %q is ready",
+				ProxyContainerName, c.Name, c.Name))
+		}
+	}
+
+	// Containers whose own HTTP_PROXY sends traffic past the ctxforge proxy
+	httpProxy := fmt.Sprintf("http://localhost:%d", ProxyPort)
+	skipped := make(map[string]bool)
+	for _, name := range splitAnnotationList(pod.Annotations[AnnotationSkipContainers]) {
+		skipped[name] = true
+	}
+	for _, c := range pod.Spec.Containers {
+		if c.Name == ProxyContainerName || skipped[c.Name] {
+			continue
+		}
+		if _, isMesh := meshProxyContainers[c.Name]; isMesh {
+			continue
+		}
+		if i := findEnv(c.Env, "HTTP_PROXY", "http_proxy"); i >= 0 &&
+			(c.Env[i].ValueFrom != nil || c.Env[i].Value != httpProxy) {
+			warnings = append(warnings, fmt.Sprintf(
+				"container %q sets its own %s; its outbound requests bypass the ctxforge proxy and headers are not propagated",
+				c.Name, c.Env[i].Name))
+		}
+	}
+
+	return warnings
+}
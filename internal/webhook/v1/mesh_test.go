@@ -0,0 +1,388 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+)
+
+func TestDetectMesh(t *testing.T) {
+	tests := []struct {
+		name     string
+		pod      *corev1.Pod
+		ns       *corev1.Namespace
+		expected string
+	}{
+		{
+			name:     "no mesh",
+			pod:      &corev1.Pod{},
+			expected: "",
+		},
+		{
+			name: "istio sidecar already injected",
+			pod: &corev1.Pod{Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "app"}, {Name: "istio-proxy"}},
+			}},
+			expected: MeshIstio,
+		},
+		{
+			name: "istio injection label on pod",
+			pod: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{"sidecar.istio.io/inject": "true"},
+			}},
+			expected: MeshIstio,
+		},
+		{
+			name: "istio namespace injection",
+			pod:  &corev1.Pod{},
+			ns: &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{"istio-injection": "enabled"},
+			}},
+			expected: MeshIstio,
+		},
+		{
+			name: "istio namespace injection with pod opt-out",
+			pod: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{"sidecar.istio.io/inject": "false"},
+			}},
+			ns: &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{"istio-injection": "enabled"},
+			}},
+			expected: "",
+		},
+		{
+			name: "linkerd pod annotation",
+			pod: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{"linkerd.io/inject": "enabled"},
+			}},
+			expected: MeshLinkerd,
+		},
+		{
+			name: "linkerd namespace with pod opt-out",
+			pod: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{"linkerd.io/inject": "disabled"},
+			}},
+			ns: &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{"linkerd.io/inject": "enabled"},
+			}},
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, detectMesh(tt.pod, tt.ns))
+		})
+	}
+}
+
+func TestPodCustomDefaulter_RedirectSkippedInMesh(t *testing.T) {
+	defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage, RedirectInitImage: "busybox"}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "api",
+			Labels: map[string]string{"sidecar.istio.io/inject": "true"},
+			Annotations: map[string]string{
+				AnnotationEnabled:      "true",
+				AnnotationHeaders:      "x-request-id",
+				AnnotationRedirectMode: RedirectModeIptables,
+			},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+	}
+
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+	assert.Empty(t, pod.Spec.InitContainers, "the mesh owns the iptables rules")
+	assert.Len(t, pod.Spec.Containers, 2)
+}
+
+func TestPodCustomDefaulter_IstioEnvoyFilterMode(t *testing.T) {
+	tracing := newPolicy("tracing", map[string]string{"app": "api"}, ctxforgev1beta1.PropagationRule{
+		Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-request-id", Generate: true}},
+	})
+	istioPod := func(labels map[string]string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "api",
+				Namespace:   "default",
+				Labels:      labels,
+				Annotations: map[string]string{AnnotationEnabled: "true"},
+			},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}, {Name: "istio-proxy"}}},
+		}
+	}
+
+	t.Run("istio pod served by EnvoyFilters", func(t *testing.T) {
+		defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage, Client: newFakeClient(t, tracing), IstioEnvoyFilters: true}
+		pod := istioPod(map[string]string{"app": "api"})
+		require.NoError(t, defaulter.Default(context.Background(), pod))
+		assert.Nil(t, proxyContainer(pod), "the istio-proxy applies the policy")
+	})
+
+	t.Run("sidecar mode", func(t *testing.T) {
+		defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage, Client: newFakeClient(t, tracing)}
+		pod := istioPod(map[string]string{"app": "api"})
+		require.NoError(t, defaulter.Default(context.Background(), pod))
+		assert.NotNil(t, proxyContainer(pod))
+	})
+
+	t.Run("pod outside the mesh", func(t *testing.T) {
+		defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage, Client: newFakeClient(t, tracing), IstioEnvoyFilters: true}
+		pod := istioPod(map[string]string{"app": "api"})
+		pod.Spec.Containers = pod.Spec.Containers[:1]
+		require.NoError(t, defaulter.Default(context.Background(), pod))
+		assert.NotNil(t, proxyContainer(pod))
+	})
+
+	t.Run("policy needing the sidecar", func(t *testing.T) {
+		sampled := newPolicy("baggage", nil, ctxforgev1beta1.PropagationRule{
+			Headers: []ctxforgev1beta1.HeaderConfig{{Name: "baggage"}},
+		})
+		sampled.Spec.Sampling = &ctxforgev1beta1.Sampling{Percentage: 10}
+		defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage, Client: newFakeClient(t, tracing, sampled), IstioEnvoyFilters: true}
+		pod := istioPod(map[string]string{"app": "api"})
+		require.NoError(t, defaulter.Default(context.Background(), pod))
+		assert.NotNil(t, proxyContainer(pod))
+	})
+
+	t.Run("conflicting policies", func(t *testing.T) {
+		override := newPolicy("override", nil, ctxforgev1beta1.PropagationRule{
+			Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-request-id", StaticValue: "fixed", OnExisting: "override"}},
+		})
+		defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage, Client: newFakeClient(t, tracing, override), IstioEnvoyFilters: true}
+		pod := istioPod(map[string]string{"app": "api"})
+		require.NoError(t, defaulter.Default(context.Background(), pod))
+		assert.NotNil(t, proxyContainer(pod))
+	})
+
+	t.Run("pod annotations", func(t *testing.T) {
+		defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage, Client: newFakeClient(t, tracing), IstioEnvoyFilters: true}
+		pod := istioPod(map[string]string{"app": "api"})
+		pod.Annotations[AnnotationHeaders] = "x-request-id"
+		require.NoError(t, defaulter.Default(context.Background(), pod))
+		assert.NotNil(t, proxyContainer(pod))
+	})
+}
+
+func TestPodCustomDefaulter_LinkerdCoexistence(t *testing.T) {
+	linkerdNS := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:        "default",
+		Annotations: map[string]string{"linkerd.io/inject": "enabled"},
+	}}
+	newPod := func(annotations map[string]string, containers ...corev1.Container) *corev1.Pod {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "default", Annotations: map[string]string{
+				AnnotationEnabled: "true",
+				AnnotationHeaders: "x-request-id",
+			}},
+			Spec: corev1.PodSpec{Containers: append(containers, corev1.Container{Name: "app"})},
+		}
+		for key, value := range annotations {
+			pod.Annotations[key] = value
+		}
+		return pod
+	}
+	names := func(containers []corev1.Container) []string {
+		var names []string
+		for _, c := range containers {
+			names = append(names, c.Name)
+		}
+		return names
+	}
+
+	t.Run("native sidecar next to a regular linkerd proxy", func(t *testing.T) {
+		defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage, NativeSidecar: true, Client: newFakeClient(t, linkerdNS)}
+		pod := newPod(nil)
+		require.NoError(t, defaulter.Default(context.Background(), pod))
+		assert.Empty(t, pod.Spec.InitContainers, "the startup probe would wait for the linkerd proxy")
+		assert.Equal(t, []string{"app", ProxyContainerName}, names(pod.Spec.Containers))
+	})
+
+	t.Run("native sidecar next to a native linkerd proxy", func(t *testing.T) {
+		defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage, NativeSidecar: true, Client: newFakeClient(t, linkerdNS)}
+		pod := newPod(map[string]string{"config.alpha.linkerd.io/proxy-enable-native-sidecar": "true"})
+		require.NoError(t, defaulter.Default(context.Background(), pod))
+		assert.Equal(t, []string{ProxyContainerName}, names(pod.Spec.InitContainers))
+	})
+
+	t.Run("proxy ordered first stays behind the linkerd proxy", func(t *testing.T) {
+		defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage, SidecarOrder: SidecarOrderFirst}
+		pod := newPod(nil, corev1.Container{Name: LinkerdProxyContainerName})
+		require.NoError(t, defaulter.Default(context.Background(), pod))
+		assert.Equal(t, []string{LinkerdProxyContainerName, ProxyContainerName, "app"}, names(pod.Spec.Containers))
+	})
+
+	t.Run("linkerd proxy env left alone", func(t *testing.T) {
+		defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage}
+		pod := newPod(nil, corev1.Container{Name: LinkerdProxyContainerName})
+		require.NoError(t, defaulter.Default(context.Background(), pod))
+		assert.Empty(t, pod.Spec.Containers[0].Env)
+		assert.GreaterOrEqual(t, findEnv(pod.Spec.Containers[1].Env, "HTTP_PROXY"), 0)
+	})
+
+	t.Run("moved linkerd ports are not target ports", func(t *testing.T) {
+		pod := newPod(map[string]string{"config.linkerd.io/proxy-inbound-port": "8443"})
+		pod.Spec.Containers[0].Ports = []corev1.ContainerPort{{ContainerPort: 8443}, {ContainerPort: 3000}}
+		port, ok := detectTargetPort(pod)
+		assert.True(t, ok)
+		assert.Equal(t, "3000", port)
+	})
+}
+
+func TestMeshConflictWarnings(t *testing.T) {
+	always := corev1.ContainerRestartPolicyAlways
+	sidecar := corev1.Container{Name: ProxyContainerName}
+
+	tests := []struct {
+		name     string
+		pod      *corev1.Pod
+		expected []string
+	}{
+		{
+			name: "no ctxforge sidecar",
+			pod: &corev1.Pod{Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "app", Ports: []corev1.ContainerPort{{ContainerPort: ProxyPort}}}},
+			}},
+		},
+		{
+			name: "clean istio pod",
+			pod: &corev1.Pod{Spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{{Name: "istio-init"}},
+				Containers:     []corev1.Container{{Name: "app"}, sidecar, {Name: "istio-proxy"}},
+			}},
+		},
+		{
+			name: "port conflict",
+			pod: &corev1.Pod{Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{Name: "app", Ports: []corev1.ContainerPort{{ContainerPort: ProxyPort}}},
+					sidecar,
+				},
+			}},
+			expected: []string{`container "app" also uses port 9090, which the ctxforge proxy listens on`},
+		},
+		{
+			name: "target port reserved by mesh",
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{AnnotationTargetPort: "15090"}},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "app"}, sidecar, {Name: "istio-proxy"}},
+				},
+			},
+			expected: []string{"ctxforge.io/target-port 15090 is reserved by the istio proxy; set it to the application port"},
+		},
+		{
+			name: "linkerd port moved onto the proxy port",
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+					"config.linkerd.io/proxy-admin-port":   "9090",
+					AnnotationTargetPort:                   "4243",
+					"config.linkerd.io/proxy-inbound-port": "4243",
+				}},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: LinkerdProxyContainerName}, {Name: "app"}, sidecar},
+				},
+			},
+			expected: []string{
+				"ctxforge.io/target-port 4243 is reserved by the linkerd proxy; set it to the application port",
+				"config.linkerd.io/proxy-admin-port moves the linkerd proxy to port 9090, which the ctxforge proxy listens on",
+			},
+		},
+		{
+			name: "competing iptables init containers",
+			pod: &corev1.Pod{Spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{{Name: "linkerd-init"}, {Name: RedirectInitContainerName}},
+				Containers:     []corev1.Container{{Name: "app"}, sidecar, {Name: "linkerd-proxy"}},
+			}},
+			expected: []string{`both "ctxforge-init" and "linkerd-init" program iptables redirects; remove ctxforge.io/redirect-mode and rely on HTTP_PROXY`},
+		},
+		{
+			name: "native sidecar ordered before mesh",
+			pod: &corev1.Pod{Spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{
+					{Name: ProxyContainerName, RestartPolicy: &always},
+					{Name: "istio-proxy", RestartPolicy: &always},
+				},
+				Containers: []corev1.Container{{Name: "app"}},
+			}},
+			expected: []string{`"ctxforge-proxy" starts before the mesh sidecar "istio-proxy"; outbound requests may fail until "istio-proxy" is ready`},
+		},
+		{
+			name: "native sidecar ordered after mesh",
+			pod: &corev1.Pod{Spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{
+					{Name: "istio-proxy", RestartPolicy: &always},
+					{Name: ProxyContainerName, RestartPolicy: &always},
+				},
+				Containers: []corev1.Container{{Name: "app"}},
+			}},
+		},
+		{
+			name: "user HTTP_PROXY",
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{AnnotationSkipContainers: "vault-agent"}},
+				Spec: corev1.PodSpec{Containers: []corev1.Container{
+					{Name: "app", Env: []corev1.EnvVar{{Name: "http_proxy", Value: "http://corp-proxy:3128"}}},
+					{Name: "vault-agent", Env: []corev1.EnvVar{{Name: "HTTP_PROXY", Value: "http://corp-proxy:3128"}}},
+					sidecar,
+				}},
+			},
+			expected: []string{`container "app" sets its own http_proxy; its outbound requests bypass the ctxforge proxy and headers are not propagated`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, meshConflictWarnings(tt.pod))
+		})
+	}
+}
+
+func TestPodCustomValidator_WarnsOnMeshConflicts(t *testing.T) {
+	validator := &PodCustomValidator{}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+			AnnotationEnabled: "true",
+			AnnotationHeaders: "x-request-id",
+		}},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{
+			{Name: "app", Ports: []corev1.ContainerPort{{ContainerPort: ProxyPort}}},
+			{Name: ProxyContainerName},
+		}},
+	}
+
+	warnings, err := validator.ValidateCreate(context.Background(), pod)
+	require.NoError(t, err)
+	assert.Len(t, warnings, 1)
+
+	// Injected for a policy, without headers of its own
+	delete(pod.Annotations, AnnotationHeaders)
+	warnings, err = validator.ValidateCreate(context.Background(), pod)
+	require.NoError(t, err)
+	require.Len(t, warnings, 2)
+	assert.Contains(t, warnings[1], "which the ctxforge proxy listens on")
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Reasons used as the "reason" label of injectionsSkippedTotal.
+const (
+	SkipReasonNotEnabled      = "not_enabled"
+	SkipReasonNoHeaders       = "no_headers"
+	SkipReasonAlreadyInjected = "already_injected"
+	SkipReasonOptedOut        = "opted_out"
+	SkipReasonDryRun          = "dry_run"
+	SkipReasonOtherRevision   = "other_revision"
+	SkipReasonEnvoyFilter     = "envoy_filter"
+)
+
+// Webhook names used as the "webhook" label of admissionDuration.
+const (
+	webhookMutating   = "mutating"
+	webhookValidating = "validating"
+	webhookWorkload   = "workload"
+)
+
+var (
+	// injectionsTotal counts pods that received the proxy sidecar.
+	injectionsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "ctxforge_webhook_injections_total",
+		Help: "Total number of pods the proxy sidecar was injected into.",
+	})
+
+	// injectionsSkippedTotal counts pods admitted without injection, by reason.
+	injectionsSkippedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ctxforge_webhook_injections_skipped_total",
+		Help: "Total number of pods admitted without sidecar injection, by reason.",
+	}, []string{"reason"})
+
+	// injectionErrorsTotal counts admission requests the defaulter could not handle.
+	injectionErrorsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "ctxforge_webhook_injection_errors_total",
+		Help: "Total number of pod admission requests that failed during sidecar injection.",
+	})
+
+	// configDriftTotal counts injected pods whose sidecar env no longer matched
+	// their configuration, by whether it was patched or only reported.
+	configDriftTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ctxforge_webhook_config_drift_total",
+		Help: "Total number of injected pods found with a stale sidecar configuration, by action.",
+	}, []string{"action"})
+
+	// admissionDuration tracks how long the pod webhooks take to handle a request.
+	admissionDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ctxforge_webhook_admission_duration_seconds",
+		Help:    "Time spent handling pod admission requests in seconds, by webhook.",
+		Buckets: []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1},
+	}, []string{"webhook"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		injectionsTotal,
+		injectionsSkippedTotal,
+		injectionErrorsTotal,
+		configDriftTotal,
+		admissionDuration,
+	)
+}
+
+// observeAdmission records the duration of an admission request.
+func observeAdmission(webhook string, start time.Time) {
+	admissionDuration.WithLabelValues(webhook).Observe(time.Since(start).Seconds())
+}
+
+// recordInjection counts a pod the sidecar was injected into. Dry-run
+// admissions aren't counted, as nothing is persisted.
+func recordInjection(ctx context.Context) {
+	if !isDryRunRequest(ctx) {
+		injectionsTotal.Inc()
+	}
+}
+
+// recordSkippedInjection counts a pod admitted without injection for reason.
+// Dry-run admissions aren't counted, as nothing is persisted.
+func recordSkippedInjection(ctx context.Context, reason string) {
+	if !isDryRunRequest(ctx) {
+		injectionsSkippedTotal.WithLabelValues(reason).Inc()
+	}
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPodCustomDefaulter_InjectionMetrics(t *testing.T) {
+	defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage}
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		containers  []corev1.Container
+		skipReason  string
+	}{
+		{
+			name: "injected",
+			annotations: map[string]string{
+				AnnotationEnabled: "true",
+				AnnotationHeaders: "x-request-id",
+			},
+		},
+		{
+			name:        "not enabled",
+			annotations: map[string]string{},
+			skipReason:  SkipReasonNotEnabled,
+		},
+		{
+			name:        "opted out",
+			annotations: map[string]string{AnnotationEnabled: "false"},
+			skipReason:  SkipReasonOptedOut,
+		},
+		{
+			name:        "no headers",
+			annotations: map[string]string{AnnotationEnabled: "true"},
+			skipReason:  SkipReasonNoHeaders,
+		},
+		{
+			name: "already injected",
+			annotations: map[string]string{
+				AnnotationEnabled: "true",
+				AnnotationHeaders: "x-request-id",
+			},
+			containers: []corev1.Container{{Name: ProxyContainerName}},
+			skipReason: SkipReasonAlreadyInjected,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-pod", Annotations: tt.annotations},
+				Spec: corev1.PodSpec{
+					Containers: append([]corev1.Container{{Name: "app"}}, tt.containers...),
+				},
+			}
+
+			injectedBefore := testutil.ToFloat64(injectionsTotal)
+			var skippedBefore float64
+			if tt.skipReason != "" {
+				skippedBefore = testutil.ToFloat64(injectionsSkippedTotal.WithLabelValues(tt.skipReason))
+			}
+
+			require.NoError(t, defaulter.Default(context.Background(), pod))
+
+			if tt.skipReason == "" {
+				assert.Equal(t, injectedBefore+1, testutil.ToFloat64(injectionsTotal))
+			} else {
+				assert.Equal(t, injectedBefore, testutil.ToFloat64(injectionsTotal))
+				assert.Equal(t, skippedBefore+1, testutil.ToFloat64(injectionsSkippedTotal.WithLabelValues(tt.skipReason)))
+			}
+		})
+	}
+}
+
+func TestPodCustomDefaulter_InjectionErrorMetric(t *testing.T) {
+	defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage}
+	before := testutil.ToFloat64(injectionErrorsTotal)
+
+	err := defaulter.Default(context.Background(), &corev1.Service{})
+
+	assert.Error(t, err)
+	assert.Equal(t, before+1, testutil.ToFloat64(injectionErrorsTotal))
+}
+
+func TestAdmissionDurationMetric(t *testing.T) {
+	defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage}
+	validator := &PodCustomValidator{}
+
+	require.NoError(t, defaulter.Default(context.Background(), &corev1.Pod{}))
+	_, err := validator.ValidateCreate(context.Background(), &corev1.Pod{})
+	require.NoError(t, err)
+
+	// One series per webhook
+	assert.Equal(t, 2, testutil.CollectAndCount(admissionDuration))
+}
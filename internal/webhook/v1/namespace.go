@@ -0,0 +1,133 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// AnnotationDefaultHeaders is the namespace annotation listing headers every
+// injected pod in the namespace propagates, in addition to its own.
+const AnnotationDefaultHeaders = "ctxforge.io/default-headers"
+
+// lookupNamespace fetches the pod's namespace. It returns nil when the
+// namespace cannot be determined or read; namespace-level settings are then
+// ignored rather than failing admission.
+func (d *PodCustomDefaulter) lookupNamespace(ctx context.Context, pod *corev1.Pod) *corev1.Namespace {
+	if d.Client == nil || d.IgnoreNamespaces {
+		return nil
+	}
+	namespace := podNamespace(ctx, pod)
+	if namespace == "" {
+		return nil
+	}
+
+	ns := &corev1.Namespace{}
+	if err := d.Client.Get(ctx, types.NamespacedName{Name: namespace}, ns); err != nil {
+		podlog.Error(err, "Failed to read namespace, ignoring namespace-level settings", "namespace", namespace)
+		return nil
+	}
+	return ns
+}
+
+// podNamespace returns the namespace of the pod, falling back to the admission
+// request since pods created through a controller may not carry it yet.
+func podNamespace(ctx context.Context, pod *corev1.Pod) string {
+	if pod.Namespace != "" {
+		return pod.Namespace
+	}
+	if req, err := admission.RequestFromContext(ctx); err == nil {
+		return req.Namespace
+	}
+	return ""
+}
+
+// namespaceInjectionEnabled checks if the namespace is labeled for namespace-wide injection
+func namespaceInjectionEnabled(ns *corev1.Namespace) bool {
+	return ns != nil && ns.Labels[LabelNamespaceInjection] == NamespaceInjectionEnabled
+}
+
+// namespaceDefaultHeaders parses the namespace's ctxforge.io/default-headers
+// annotation. Invalid header names are logged and dropped.
+func namespaceDefaultHeaders(ns *corev1.Namespace) []string {
+	if ns == nil {
+		return nil
+	}
+	var headers []string
+	for _, header := range splitAnnotationList(ns.Annotations[AnnotationDefaultHeaders]) {
+		if err := validateHeaderName(header); err != nil {
+			podlog.Error(err, "Ignoring invalid namespace default header", "namespace", ns.Name)
+			continue
+		}
+		headers = append(headers, header)
+	}
+	return headers
+}
+
+// mergeDefaultHeaders adds the namespace default headers to the pod's
+// configuration. In simple mode they are added to the header list; when header
+// rules are in use, a plain propagation rule is appended for each default the
+// rules don't already cover. Pod-level settings for the same header win.
+func mergeDefaultHeaders(defaults, headers []string, headerRules string) ([]string, string, error) {
+	if headerRules == "" {
+		seen := make(map[string]bool, len(headers))
+		for _, header := range headers {
+			seen[http.CanonicalHeaderKey(header)] = true
+		}
+		merged := append([]string{}, headers...)
+		for _, header := range defaults {
+			if !seen[http.CanonicalHeaderKey(header)] {
+				seen[http.CanonicalHeaderKey(header)] = true
+				merged = append(merged, header)
+			}
+		}
+		return merged, "", nil
+	}
+
+	var rules []headerRule
+	if err := json.Unmarshal([]byte(headerRules), &rules); err != nil {
+		return nil, "", fmt.Errorf("failed to merge namespace default headers: %w", err)
+	}
+	seen := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		seen[http.CanonicalHeaderKey(rule.Name)] = true
+	}
+	added := false
+	for _, header := range defaults {
+		if !seen[http.CanonicalHeaderKey(header)] {
+			seen[http.CanonicalHeaderKey(header)] = true
+			rules = append(rules, headerRule{Name: header})
+			added = true
+		}
+	}
+	if !added {
+		return headers, headerRules, nil
+	}
+
+	data, err := json.Marshal(rules)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to merge namespace default headers: %w", err)
+	}
+	return headers, string(data), nil
+}
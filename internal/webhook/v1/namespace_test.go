@@ -0,0 +1,167 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMergeDefaultHeaders(t *testing.T) {
+	tests := []struct {
+		name            string
+		defaults        []string
+		headers         []string
+		headerRules     string
+		expectedHeaders []string
+		expectedRules   []string
+	}{
+		{
+			name:            "defaults only",
+			defaults:        []string{"x-request-id"},
+			expectedHeaders: []string{"x-request-id"},
+		},
+		{
+			name:            "union with pod headers",
+			defaults:        []string{"x-request-id", "x-tenant-id"},
+			headers:         []string{"X-Tenant-Id", "x-user-id"},
+			expectedHeaders: []string{"X-Tenant-Id", "x-user-id", "x-request-id"},
+		},
+		{
+			name:          "appended to header rules",
+			defaults:      []string{"x-request-id", "x-tenant-id"},
+			headerRules:   `[{"name":"X-Request-ID","generate":true}]`,
+			expectedRules: []string{"X-Request-ID", "x-tenant-id"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers, headerRules, err := mergeDefaultHeaders(tt.defaults, tt.headers, tt.headerRules)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedHeaders, headers)
+
+			if tt.expectedRules == nil {
+				assert.Empty(t, headerRules)
+				return
+			}
+			var rules []headerRule
+			require.NoError(t, json.Unmarshal([]byte(headerRules), &rules))
+			names := make([]string, 0, len(rules))
+			for _, rule := range rules {
+				names = append(names, rule.Name)
+			}
+			assert.Equal(t, tt.expectedRules, names)
+			assert.True(t, rules[0].Generate, "pod-level rule settings must be preserved")
+		})
+	}
+}
+
+func TestMergeDefaultHeaders_RulesAlreadyCovered(t *testing.T) {
+	rules := `[{"name":"x-request-id","generate":true}]`
+	_, merged, err := mergeDefaultHeaders([]string{"X-Request-Id"}, nil, rules)
+	require.NoError(t, err)
+	assert.Equal(t, rules, merged, "rules must be passed through untouched")
+}
+
+func TestMergeHeaderRules_KeepsRuleFields(t *testing.T) {
+	for name, rule := range map[string]string{
+		"onExisting":           `{"name":"x-user-id","onExisting":"override"}`,
+		"rename and transform": `{"name":"x-user-id","rename":"x-owner-id","transform":{"type":"hash"}}`,
+		"staticValue":          `{"name":"x-env","staticValue":"prod"}`,
+		"response":             `{"name":"x-powered-by","response":"strip"}`,
+		"excludePaths":         `{"name":"x-user-id","excludePaths":["^/healthz$","^/metrics$"]}`,
+		"destinations":         `{"name":"x-user-id","destinations":{"allow":["*.svc.cluster.local"],"deny":["billing.shop.svc.cluster.local"]}}`,
+		"sampling":             `{"name":"baggage","sampling":{"percentage":10,"keyHeader":"x-request-id"}}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, merged, err := mergeDefaultHeaders([]string{"x-request-id"}, nil, "["+rule+"]")
+			require.NoError(t, err)
+			assert.JSONEq(t, "["+rule+`,{"name":"x-request-id"}]`, merged, "namespace defaults")
+
+			profile, err := parseHeaderProfile(name, "rules: ["+rule+"]")
+			require.NoError(t, err)
+			merged, err = mergeProfileRules(profile.Rules, []string{"x-request-id"}, "")
+			require.NoError(t, err)
+			assert.JSONEq(t, `[{"name":"x-request-id"},`+rule+"]", merged, "profile")
+		})
+	}
+}
+
+func TestPodCustomDefaulter_NamespaceDefaultHeaders(t *testing.T) {
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:        "payments",
+		Annotations: map[string]string{AnnotationDefaultHeaders: "x-request-id, not a header"},
+	}}
+	defaulter := &PodCustomDefaulter{
+		ProxyImage: DefaultProxyImage,
+		Client:     newFakeClient(t, ns),
+	}
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		expected    string
+	}{
+		{
+			name:        "defaults only",
+			annotations: map[string]string{AnnotationEnabled: "true"},
+			expected:    "x-request-id",
+		},
+		{
+			name:        "merged with pod headers",
+			annotations: map[string]string{AnnotationEnabled: "true", AnnotationHeaders: "x-tenant-id"},
+			expected:    "x-tenant-id,x-request-id",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "payments", Annotations: tt.annotations},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+			}
+			require.NoError(t, defaulter.Default(context.Background(), pod))
+			require.Len(t, pod.Spec.Containers, 2)
+			assert.Equal(t, tt.expected, sidecarEnv(t, pod, "HEADERS_TO_PROPAGATE"))
+		})
+	}
+}
+
+func TestPodCustomDefaulter_NamespaceDefaultHeaders_NotEnabled(t *testing.T) {
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:        "payments",
+		Annotations: map[string]string{AnnotationDefaultHeaders: "x-request-id"},
+	}}
+	defaulter := &PodCustomDefaulter{
+		ProxyImage: DefaultProxyImage,
+		Client:     newFakeClient(t, ns),
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "payments"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+	}
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+	assert.Len(t, pod.Spec.Containers, 1, "default headers alone must not enable injection")
+}
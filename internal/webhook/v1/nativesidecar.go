@@ -0,0 +1,62 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/version"
+	apimachineryversion "k8s.io/apimachinery/pkg/version"
+)
+
+// Values accepted by the NATIVE_SIDECAR env var.
+const (
+	NativeSidecarAuto     = "auto"
+	NativeSidecarEnabled  = "true"
+	NativeSidecarDisabled = "false"
+)
+
+// minNativeSidecarVersion is the first Kubernetes release with the
+// SidecarContainers feature gate on by default. 1.28 supports native sidecars
+// behind the gate, so "auto" leaves it alone and NATIVE_SIDECAR=true must be
+// set explicitly there.
+var minNativeSidecarVersion = version.MustParseGeneric("1.29.0")
+
+// resolveNativeSidecar decides whether the proxy is injected as a native sidecar.
+// In "auto" mode the API server version is queried through serverVersion.
+func resolveNativeSidecar(mode string, serverVersion func() (*apimachineryversion.Info, error)) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "", NativeSidecarDisabled:
+		return false, nil
+	case NativeSidecarEnabled:
+		return true, nil
+	case NativeSidecarAuto:
+		info, err := serverVersion()
+		if err != nil {
+			return false, fmt.Errorf("failed to detect Kubernetes version: %w", err)
+		}
+		v, err := version.ParseGeneric(info.GitVersion)
+		if err != nil {
+			return false, fmt.Errorf("failed to parse Kubernetes version %q: %w", info.GitVersion, err)
+		}
+		return v.AtLeast(minNativeSidecarVersion), nil
+	default:
+		return false, fmt.Errorf("invalid NATIVE_SIDECAR value %q: must be one of %s, %s, %s",
+			mode, NativeSidecarAuto, NativeSidecarEnabled, NativeSidecarDisabled)
+	}
+}
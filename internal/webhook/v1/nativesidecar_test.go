@@ -0,0 +1,120 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apimachineryversion "k8s.io/apimachinery/pkg/version"
+)
+
+func TestResolveNativeSidecar(t *testing.T) {
+	serverVersion := func(gitVersion string) func() (*apimachineryversion.Info, error) {
+		return func() (*apimachineryversion.Info, error) {
+			return &apimachineryversion.Info{GitVersion: gitVersion}, nil
+		}
+	}
+
+	tests := []struct {
+		name     string
+		mode     string
+		version  string
+		expected bool
+		wantErr  bool
+	}{
+		{name: "default", mode: "", expected: false},
+		{name: "disabled", mode: "false", version: "v1.30.0", expected: false},
+		{name: "forced on", mode: "true", version: "v1.28.0", expected: true},
+		{name: "auto on 1.29", mode: "auto", version: "v1.29.0", expected: true},
+		{name: "auto on vendor build", mode: "auto", version: "v1.31.4-eks-2d5f260", expected: true},
+		{name: "auto on 1.28", mode: "auto", version: "v1.28.9", expected: false},
+		{name: "auto on 1.27", mode: "auto", version: "v1.27.3", expected: false},
+		{name: "auto with unparsable version", mode: "auto", version: "banana", wantErr: true},
+		{name: "invalid mode", mode: "sometimes", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := resolveNativeSidecar(tt.mode, serverVersion(tt.version))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestResolveNativeSidecar_DiscoveryError(t *testing.T) {
+	result, err := resolveNativeSidecar(NativeSidecarAuto, func() (*apimachineryversion.Info, error) {
+		return nil, errors.New("connection refused")
+	})
+	assert.Error(t, err)
+	assert.False(t, result)
+}
+
+func TestPodCustomDefaulter_NativeSidecar(t *testing.T) {
+	defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage, NativeSidecar: true}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "job-pod",
+			Annotations: map[string]string{
+				AnnotationEnabled: "true",
+				AnnotationHeaders: "x-request-id",
+			},
+		},
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{{Name: "migrate"}},
+			Containers:     []corev1.Container{{Name: "app"}},
+		},
+	}
+
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+
+	require.Len(t, pod.Spec.Containers, 1, "proxy must not be a regular container")
+	require.Len(t, pod.Spec.InitContainers, 2)
+	assert.Equal(t, "migrate", pod.Spec.InitContainers[0].Name)
+
+	proxy := pod.Spec.InitContainers[1]
+	assert.Equal(t, ProxyContainerName, proxy.Name)
+	require.NotNil(t, proxy.RestartPolicy)
+	assert.Equal(t, corev1.ContainerRestartPolicyAlways, *proxy.RestartPolicy)
+
+	var hasProxyEnv bool
+	for _, env := range pod.Spec.Containers[0].Env {
+		if env.Name == "HTTP_PROXY" {
+			hasProxyEnv = true
+		}
+	}
+	assert.True(t, hasProxyEnv, "app container must still be pointed at the proxy")
+	for _, env := range pod.Spec.InitContainers[0].Env {
+		assert.NotEqual(t, "HTTP_PROXY", env.Name, "init containers run before the proxy and must not use it")
+	}
+
+	// A second pass must recognise the native sidecar.
+	delete(pod.Annotations, AnnotationInjected)
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+	assert.Len(t, pod.Spec.InitContainers, 2)
+}
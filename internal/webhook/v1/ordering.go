@@ -0,0 +1,80 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// AnnotationSidecarOrder overrides where the proxy is placed among the pod's
+	// regular containers: SidecarOrderFirst or SidecarOrderLast.
+	AnnotationSidecarOrder = "ctxforge.io/sidecar-order"
+
+	// SidecarOrderFirst starts the proxy before the app containers and holds
+	// them until it accepts connections.
+	SidecarOrderFirst = "first"
+	// SidecarOrderLast appends the proxy after the app containers (the default).
+	SidecarOrderLast = "last"
+
+	// proxyStartTimeoutSeconds bounds how long app containers wait for the proxy.
+	proxyStartTimeoutSeconds = 30
+)
+
+// sidecarFirst checks if the proxy should start before the app containers,
+// using the pod annotation if set and the operator default otherwise.
+func (d *PodCustomDefaulter) sidecarFirst(pod *corev1.Pod) bool {
+	order := strings.TrimSpace(pod.Annotations[AnnotationSidecarOrder])
+	if order == "" {
+		order = d.SidecarOrder
+	}
+	return order == SidecarOrderFirst
+}
+
+// proxyStartupProbe marks a native sidecar as started once the proxy listens.
+// The kubelet does not start the following containers before that, so early
+// app requests can't go past the proxy. /healthz is used rather than /ready
+// because /ready checks the app, which isn't running yet.
+func proxyStartupProbe() *corev1.Probe {
+	return &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Path: "/healthz",
+				Port: intstr.FromInt(ProxyPort),
+			},
+		},
+		PeriodSeconds:    1,
+		FailureThreshold: proxyStartTimeoutSeconds,
+	}
+}
+
+// waitForProxyHook blocks in postStart until the proxy listens. The kubelet
+// starts regular containers in order and waits for each postStart hook, so a
+// proxy placed first holds back the app containers the same way.
+func waitForProxyHook() *corev1.LifecycleHandler {
+	script := fmt.Sprintf(
+		"i=0; until wget -q -T 1 -O /dev/null http://127.0.0.1:%d/healthz; do "+
+			"i=$((i+1)); [ $i -ge %d ] && exit 1; sleep 1; done",
+		ProxyPort, proxyStartTimeoutSeconds)
+	return &corev1.LifecycleHandler{
+		Exec: &corev1.ExecAction{Command: []string{"sh", "-c", script}},
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func orderingPod(order string) *corev1.Pod {
+	annotations := map[string]string{
+		AnnotationEnabled: "true",
+		AnnotationHeaders: "x-request-id",
+	}
+	if order != "" {
+		annotations[AnnotationSidecarOrder] = order
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Annotations: annotations},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}, {Name: "worker"}}},
+	}
+}
+
+func TestPodCustomDefaulter_SidecarOrder(t *testing.T) {
+	tests := []struct {
+		name          string
+		operatorOrder string
+		podOrder      string
+		expectFirst   bool
+	}{
+		{name: "default appends", expectFirst: false},
+		{name: "operator default first", operatorOrder: SidecarOrderFirst, expectFirst: true},
+		{name: "pod annotation first", podOrder: SidecarOrderFirst, expectFirst: true},
+		{name: "pod annotation overrides operator", operatorOrder: SidecarOrderFirst, podOrder: SidecarOrderLast, expectFirst: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage, SidecarOrder: tt.operatorOrder}
+			pod := orderingPod(tt.podOrder)
+
+			require.NoError(t, defaulter.Default(context.Background(), pod))
+			require.Len(t, pod.Spec.Containers, 3)
+
+			if tt.expectFirst {
+				sidecar := pod.Spec.Containers[0]
+				assert.Equal(t, ProxyContainerName, sidecar.Name)
+				require.NotNil(t, sidecar.Lifecycle)
+				require.NotNil(t, sidecar.Lifecycle.PostStart)
+				assert.Contains(t, sidecar.Lifecycle.PostStart.Exec.Command[2], "http://127.0.0.1:9090/healthz")
+				assert.Equal(t, "app", pod.Spec.Containers[1].Name)
+			} else {
+				assert.Equal(t, ProxyContainerName, pod.Spec.Containers[2].Name)
+				assert.Nil(t, pod.Spec.Containers[2].Lifecycle)
+			}
+
+			// App containers get the proxy env regardless of position
+			for _, c := range pod.Spec.Containers {
+				if c.Name != ProxyContainerName {
+					assert.GreaterOrEqual(t, findEnv(c.Env, "HTTP_PROXY"), 0, c.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestPodCustomDefaulter_NativeSidecarStartupProbe(t *testing.T) {
+	defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage, NativeSidecar: true}
+	pod := orderingPod(SidecarOrderFirst)
+
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+
+	require.Len(t, pod.Spec.InitContainers, 1)
+	sidecar := pod.Spec.InitContainers[0]
+	require.NotNil(t, sidecar.StartupProbe, "app containers must wait until the proxy listens")
+	assert.Equal(t, "/healthz", sidecar.StartupProbe.HTTPGet.Path)
+	assert.Nil(t, sidecar.Lifecycle, "native sidecars are gated by the startup probe")
+}
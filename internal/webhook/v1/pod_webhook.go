@@ -18,21 +18,33 @@ package v1
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	apimachineryversion "k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/bgruszka/contextforge/internal/config"
+	"github.com/bgruszka/contextforge/internal/policy"
 )
 
 const (
@@ -46,6 +58,17 @@ const (
 	AnnotationTargetPort = "ctxforge.io/target-port"
 	// AnnotationInjected marks a pod as already injected
 	AnnotationInjected = "ctxforge.io/injected"
+	// AnnotationSkipContainers lists containers that must not be pointed at the proxy
+	AnnotationSkipContainers = "ctxforge.io/skip-containers"
+	// AnnotationPolicies lists the HeaderPropagationPolicies the injected header rules were derived from
+	AnnotationPolicies = "ctxforge.io/policies"
+
+	// LabelExclude opts a pod out of injection, even in an injection-enabled namespace
+	LabelExclude = "ctxforge.io/exclude"
+	// LabelNamespaceInjection is the namespace label that enables or disables injection namespace-wide
+	LabelNamespaceInjection = "ctxforge.io/injection"
+	// NamespaceInjectionEnabled enables injection for every pod in a namespace
+	NamespaceInjectionEnabled = "enabled"
 
 	// ProxyContainerName is the name of the injected sidecar container
 	ProxyContainerName = "ctxforge-proxy"
@@ -58,65 +81,431 @@ const (
 
 	// AnnotationValueTrue is the value "true" used in annotations
 	AnnotationValueTrue = "true"
+	// AnnotationValueFalse is the value "false" used in annotations
+	AnnotationValueFalse = "false"
 )
 
 var podlog = logf.Log.WithName("pod-webhook")
 
-// SetupPodWebhookWithManager registers the webhook for Pod in the manager.
-func SetupPodWebhookWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewWebhookManagedBy(mgr).For(&corev1.Pod{}).
-		WithValidator(&PodCustomValidator{}).
-		WithDefaulter(&PodCustomDefaulter{
-			ProxyImage: getEnvOrDefault("PROXY_IMAGE", DefaultProxyImage),
-		}).
-		Complete()
+// SetupPodWebhookWithManager registers the webhooks for Pod and for the
+// workloads whose pod templates can be injected in the manager. Features the
+// operator lacks permissions for in caps are disabled; nil grants them all.
+func SetupPodWebhookWithManager(mgr ctrl.Manager, caps *Capabilities) error {
+	serverVersion := func() (*apimachineryversion.Info, error) {
+		dc, err := discovery.NewDiscoveryClientForConfig(mgr.GetConfig())
+		if err != nil {
+			return nil, err
+		}
+		return dc.ServerVersion()
+	}
+	mode := getEnvOrDefault("NATIVE_SIDECAR", NativeSidecarDisabled)
+	nativeSidecar, err := resolveNativeSidecar(mode, serverVersion)
+	if err != nil {
+		if mode != NativeSidecarAuto {
+			return err
+		}
+		podlog.Error(err, "Native sidecar detection failed, injecting as a regular container")
+	}
+	jobNativeSidecar := nativeSidecar
+	if !jobNativeSidecar && mode != NativeSidecarAuto {
+		if jobNativeSidecar, err = resolveNativeSidecar(NativeSidecarAuto, serverVersion); err != nil {
+			podlog.Error(err, "Native sidecar detection failed, Job pods use the app exit watcher")
+		}
+	}
+	sidecarOrder := getEnvOrDefault("SIDECAR_ORDER", SidecarOrderLast)
+	if sidecarOrder != SidecarOrderFirst && sidecarOrder != SidecarOrderLast {
+		return fmt.Errorf("invalid SIDECAR_ORDER value %q: must be one of %s, %s",
+			sidecarOrder, SidecarOrderFirst, SidecarOrderLast)
+	}
+	security, err := proxySecurityFromEnv()
+	if err != nil {
+		return err
+	}
+	drainTimeout, err := time.ParseDuration(getEnvOrDefault("PROXY_DRAIN_TIMEOUT", DefaultDrainTimeout.String()))
+	if err != nil || drainTimeout < 0 {
+		return fmt.Errorf("invalid PROXY_DRAIN_TIMEOUT value %q: must be a non-negative duration such as 20s",
+			os.Getenv("PROXY_DRAIN_TIMEOUT"))
+	}
+	targetCA := os.Getenv("PROXY_TARGET_CA")
+	if targetCA != "" {
+		if _, _, err := parseTargetCA(targetCA); err != nil {
+			return fmt.Errorf("invalid PROXY_TARGET_CA: %w", err)
+		}
+	}
+	pullSecrets, err := proxyImagePullSecretsFromEnv()
+	if err != nil {
+		return err
+	}
+	defaults := sidecarDefaultsFromEnv()
+	revision := os.Getenv("INJECTION_REVISION")
+	if errs := validation.IsValidLabelValue(revision); len(errs) > 0 {
+		return fmt.Errorf("invalid INJECTION_REVISION value %q: %s", revision, strings.Join(errs, "; "))
+	}
+	targetPortValidation := getEnvOrDefault("TARGET_PORT_VALIDATION", TargetPortValidationWarn)
+	if targetPortValidation != TargetPortValidationWarn && targetPortValidation != TargetPortValidationDeny {
+		return fmt.Errorf("invalid TARGET_PORT_VALIDATION value %q: must be one of %s, %s",
+			targetPortValidation, TargetPortValidationWarn, TargetPortValidationDeny)
+	}
+	denyUndeclaredTargetPort := targetPortValidation == TargetPortValidationDeny
+	istioMode := getEnvOrDefault("ISTIO_MODE", IstioModeSidecar)
+	if istioMode != IstioModeSidecar && istioMode != IstioModeEnvoyFilter {
+		return fmt.Errorf("invalid ISTIO_MODE value %q: must be one of %s, %s",
+			istioMode, IstioModeSidecar, IstioModeEnvoyFilter)
+	}
+	secureMode := os.Getenv("PROXY_SECURE_MODE")
+	if secureMode != "" && secureMode != config.SecureModeStrict {
+		return fmt.Errorf("invalid PROXY_SECURE_MODE value %q: must be empty or %s", secureMode, config.SecureModeStrict)
+	}
+	contextSigningSecret := os.Getenv("PROXY_CONTEXT_SIGNING_SECRET")
+	if contextSigningSecret != "" {
+		if err := validateContextSigningSecret(contextSigningSecret); err != nil {
+			return fmt.Errorf("invalid PROXY_CONTEXT_SIGNING_SECRET: %w", err)
+		}
+	}
+	spiffeIDHeader := os.Getenv("PROXY_SPIFFE_ID_HEADER")
+	if spiffeIDHeader != "" {
+		if err := validateHeaderName(spiffeIDHeader); err != nil {
+			return fmt.Errorf("invalid PROXY_SPIFFE_ID_HEADER value %q: %w", spiffeIDHeader, err)
+		}
+	}
+	allowedImages, err := policy.ParseImageAllowlist(os.Getenv("PROXY_IMAGE_ALLOWLIST"))
+	if err != nil {
+		return fmt.Errorf("invalid PROXY_IMAGE_ALLOWLIST: %w", err)
+	}
+	policyDefaults, err := policy.DefaultsFromEnv(mgr.GetClient())
+	if err != nil {
+		return err
+	}
+	// The rules ConfigMaps are rendered by a controller watching namespaces
+	rulesConfigMap := getEnvOrDefault("PROXY_RULES_CONFIGMAP", AnnotationValueFalse) == AnnotationValueTrue &&
+		caps.Require("RulesConfigMap", PermissionReadNamespaces)
+	// Without the operator setting the condition, gated pods never become Ready
+	readinessGate := getEnvOrDefault("PROXY_READINESS_GATE", AnnotationValueFalse) == AnnotationValueTrue &&
+		caps.Require("ReadinessGate", PermissionUpdatePodStatus)
+	podlog.Info("Configured sidecar injection",
+		"nativeSidecar", nativeSidecar, "jobNativeSidecar", jobNativeSidecar,
+		"sidecarOrder", sidecarOrder, "drainTimeout", drainTimeout, "revision", revision, "istioMode", istioMode)
+
+	defaulter := &PodCustomDefaulter{
+		ProxyImage:           getEnvOrDefault("PROXY_IMAGE", DefaultProxyImage),
+		Client:               mgr.GetClient(),
+		NativeSidecar:        nativeSidecar,
+		JobNativeSidecar:     jobNativeSidecar,
+		RedirectInitImage:    os.Getenv("REDIRECT_INIT_IMAGE"),
+		NoProxy:              operatorNoProxy(),
+		Recorder:             mgr.GetEventRecorderFor("ctxforge-webhook"),
+		SidecarOrder:         sidecarOrder,
+		DrainTimeout:         drainTimeout,
+		Security:             security,
+		TargetCA:             targetCA,
+		ImagePullSecrets:     pullSecrets,
+		AdminAuth:            getEnvOrDefault("PROXY_ADMIN_AUTH", AnnotationValueFalse) == AnnotationValueTrue,
+		SecureMode:           secureMode,
+		SPIFFE:               getEnvOrDefault("PROXY_SPIFFE", AnnotationValueFalse) == AnnotationValueTrue,
+		SPIFFEIDHeader:       spiffeIDHeader,
+		ContextSigningSecret: contextSigningSecret,
+		Defaults:             defaults,
+		PolicyDefaults:       policyDefaults,
+		Revision:             revision,
+		ReadinessGate:        readinessGate,
+		LiveConfig:           getEnvOrDefault("PROXY_LIVE_CONFIG", AnnotationValueFalse) == AnnotationValueTrue,
+		RuleStreamAddress:    os.Getenv("PROXY_RULE_STREAM_ADDRESS"),
+		RulesConfigMap:       rulesConfigMap,
+		AllowedImages:        allowedImages,
+		IstioEnvoyFilters:    istioMode == IstioModeEnvoyFilter,
+		IgnoreNamespaces:     !caps.Require("NamespaceSettings", PermissionReadNamespaces),
+	}
+
+	if err := ctrl.NewWebhookManagedBy(mgr).For(&corev1.Pod{}).
+		WithValidator(&PodCustomValidator{DenyUndeclaredTargetPort: denyUndeclaredTargetPort}).
+		WithDefaulter(defaulter).
+		Complete(); err != nil {
+		return err
+	}
+
+	return setupWorkloadWebhooksWithManager(mgr, &WorkloadCustomDefaulter{
+		Pods:    defaulter,
+		Enabled: getEnvOrDefault("WORKLOAD_INJECTION", AnnotationValueFalse) == AnnotationValueTrue,
+	})
 }
 
-// +kubebuilder:webhook:path=/mutate--v1-pod,mutating=true,failurePolicy=fail,sideEffects=None,groups="",resources=pods,verbs=create,versions=v1,name=mpod-v1.kb.io,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/mutate--v1-pod,mutating=true,failurePolicy=fail,sideEffects=None,groups="",resources=pods;pods/ephemeralcontainers,verbs=create;update,versions=v1,name=mpod-v1.kb.io,admissionReviewVersions=v1
+
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // PodCustomDefaulter handles sidecar injection for pods
 type PodCustomDefaulter struct {
 	ProxyImage string
+	// Client reads namespace labels for namespace-wide injection and the
+	// HeaderPropagationPolicies that supply headers for pods without header
+	// annotations. When nil, only pod annotations are used.
+	Client client.Reader
+	// NativeSidecar injects the proxy as an init container with restartPolicy
+	// Always, so it starts before and stops after the app containers.
+	NativeSidecar bool
+	// JobNativeSidecar injects the proxy as a native sidecar into Job pods even
+	// when NativeSidecar is off; set when the cluster supports native sidecars.
+	JobNativeSidecar bool
+	// RedirectInitImage is the image (providing sh and iptables) used for the
+	// transparent redirect init container. Redirection is disabled when empty.
+	RedirectInitImage string
+	// NoProxy lists destinations added to every app container's NO_PROXY,
+	// such as cluster CIDRs, .svc suffixes or the cloud metadata IP.
+	NoProxy []string
+	// Recorder emits events on pods whose sidecar drifted from their configuration.
+	Recorder record.EventRecorder
+	// DrainTimeout is how long the sidecar's preStop hook waits for in-flight
+	// requests before the proxy is stopped. Zero disables the hook.
+	DrainTimeout time.Duration
+	// Security configures the proxy's securityContext, e.g. to let OpenShift
+	// assign the UID.
+	Security ProxySecurityContext
+	// TargetCA is the default CA bundle (configmap/<name> or secret/<name>)
+	// mounted for pods that enable ctxforge.io/target-tls. Empty trusts the
+	// system roots only.
+	TargetCA string
+	// AdminAuth shares a generated proxy admin token with the app containers
+	// of every injected pod. Pods override it with ctxforge.io/admin-auth.
+	AdminAuth bool
+	// SecureMode is passed to sidecars as SECURE_MODE. The strict profile
+	// also turns AdminAuth on, since local callers then need the token too.
+	SecureMode string
+	// SPIFFE mounts the SPIRE agent socket into every sidecar through the
+	// SPIFFE CSI driver, so proxies send their SPIFFE ID as a header. Pods
+	// override it with ctxforge.io/spiffe.
+	SPIFFE bool
+	// SPIFFEIDHeader replaces the proxy's default SPIFFE ID header when set.
+	SPIFFEIDHeader string
+	// ContextSigningSecret names the Secret whose keys every sidecar signs and
+	// verifies propagated headers with. Pods override it with
+	// ctxforge.io/context-signing-secret; empty disables signing.
+	ContextSigningSecret string
+	// ImagePullSecrets names Secrets added to injected pods' imagePullSecrets,
+	// for proxy images served from a private registry.
+	ImagePullSecrets []string
+	// Defaults supplies fleet-wide proxy image and env from a ConfigMap.
+	// When nil, only the operator's built-in settings are used.
+	Defaults *SidecarDefaultsSource
+	// PolicyDefaults supplies the headers, generator and sampling every
+	// policy gets unless it configures them. When nil, policies apply as
+	// written.
+	PolicyDefaults *policy.DefaultsSource
+	// LiveConfig lets the sidecar pick up header changes at runtime through
+	// the ctxforge.io/live-header-rules annotation, set by SyncLiveConfig.
+	LiveConfig bool
+	// RuleStreamAddress is the operator's rule stream service (host:port).
+	// When set, sidecars subscribe to it for runtime header changes instead of
+	// watching the live header rules annotation.
+	RuleStreamAddress string
+	// RulesConfigMap makes sidecars load their header rules at runtime from
+	// the operator's per-namespace rules ConfigMap, mounted into the pod,
+	// instead of the live header rules annotation.
+	RulesConfigMap bool
+	// ReadinessGate adds the ctxforge.io/proxy-config-synced readiness gate,
+	// which the operator sets once the sidecar reports the injected config.
+	ReadinessGate bool
+	// Revision names this operator's injection revision, so that several
+	// operators with different proxy images can run side by side. Empty is
+	// DefaultRevision.
+	Revision string
+	// AllowedImages are the proxy images policies may pin with
+	// sidecar.image. Images outside it, from policies admitted before the
+	// allowlist changed, are ignored.
+	AllowedImages policy.ImageAllowlist
+	// IstioEnvoyFilters leaves the sidecar out of Istio pods whose policies
+	// the operator implements with EnvoyFilters (ISTIO_MODE=envoyfilter).
+	IstioEnvoyFilters bool
+	// IgnoreNamespaces skips namespace labels and annotations, for operators
+	// not allowed to read namespaces.
+	IgnoreNamespaces bool
+	// SidecarOrder is the default placement of a regular (non-native) proxy
+	// container: SidecarOrderLast, or SidecarOrderFirst to start it before the
+	// app containers. Pods override it with ctxforge.io/sidecar-order.
+	SidecarOrder string
 }
 
 var _ webhook.CustomDefaulter = &PodCustomDefaulter{}
 
 // Default implements webhook.CustomDefaulter to inject the sidecar
-func (d *PodCustomDefaulter) Default(_ context.Context, obj runtime.Object) error {
+func (d *PodCustomDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	defer observeAdmission(webhookMutating, time.Now())
+
 	pod, ok := obj.(*corev1.Pod)
 	if !ok {
+		injectionErrorsTotal.Inc()
 		return fmt.Errorf("expected a Pod object but got %T", obj)
 	}
 
-	if !d.shouldInject(pod) {
+	// Ephemeral debug containers only go through the proxy if the pod asks for it
+	if isEphemeralContainersRequest(ctx) {
+		d.proxyEphemeralContainers(ctx, pod)
 		return nil
 	}
 
-	headers := d.extractHeaders(pod)
-	headerRules := d.extractHeaderRules(pod)
+	// Containers can't be added to an existing pod; updates only check for drift
+	if isUpdateRequest(ctx) {
+		if d.ownsPod(pod, d.lookupNamespace(ctx, pod)) {
+			d.reconcileDrift(ctx, pod, false)
+		}
+		return nil
+	}
+
+	return d.inject(ctx, pod)
+}
+
+// inject adds the sidecar to a pod that is being created, or to a workload's
+// pod template, unless the pod opted out or has nothing to propagate.
+func (d *PodCustomDefaulter) inject(ctx context.Context, pod *corev1.Pod) error {
+	if d.isOptedOut(pod) {
+		podLogger(pod).Info("Skipping injection: pod opted out")
+		recordSkippedInjection(ctx, SkipReasonOptedOut)
+		return nil
+	}
+
+	ns := d.lookupNamespace(ctx, pod)
+	if !d.ownsPod(pod, ns) {
+		podLogger(pod).Info("Skipping injection: pod belongs to another revision",
+			"revision", requestedRevision(pod, ns))
+		recordSkippedInjection(ctx, SkipReasonOtherRevision)
+		return nil
+	}
+
+	// A hand-written sidecar is converged toward the policies selecting the pod
+	if manuallyInjected(pod) {
+		adopted, err := d.adopt(ctx, pod, ns, true)
+		if err != nil {
+			podLogger(pod).Info("Skipping adoption: configuration is invalid", "error", err.Error())
+		} else if adopted {
+			recordSkippedInjection(ctx, SkipReasonAlreadyInjected)
+			return nil
+		}
+	}
+
+	if !d.shouldInject(pod) && !namespaceInjectionEnabled(ns) {
+		recordSkippedInjection(ctx, SkipReasonNotEnabled)
+		return nil
+	}
+
+	headers, headerRules, policies, err := d.resolveHeaders(ctx, pod, ns)
+	if err != nil {
+		injectionErrorsTotal.Inc()
+		return err
+	}
 
 	// Need either headers or header-rules to inject
 	if len(headers) == 0 && headerRules == "" {
-		podlog.Info("Skipping injection: no headers or header-rules specified", "pod", pod.Name)
+		podLogger(pod).Info("Skipping injection: no headers, header-rules or matching policies")
+		recordSkippedInjection(ctx, SkipReasonNoHeaders)
 		return nil
 	}
 
 	if d.isAlreadyInjected(pod) {
-		podlog.Info("Skipping injection: already injected", "pod", pod.Name)
+		podLogger(pod).Info("Skipping injection: already injected")
+		d.reconcileDrift(ctx, pod, true)
+		recordSkippedInjection(ctx, SkipReasonAlreadyInjected)
 		return nil
 	}
 
-	podlog.Info("Injecting sidecar", "pod", pod.Name, "headers", headers, "hasHeaderRules", headerRules != "")
+	if d.servedByEnvoyFilters(pod, ns, headers, policies) {
+		podLogger(pod).Info("Skipping injection: the policies' EnvoyFilters configure the istio-proxy",
+			"policies", policies.Names)
+		recordSkippedInjection(ctx, SkipReasonEnvoyFilter)
+		return nil
+	}
 
-	d.injectSidecar(pod, headers, headerRules)
+	if isDryRun(pod) {
+		d.recordDryRun(pod, ns, headers, headerRules, policies)
+		recordSkippedInjection(ctx, SkipReasonDryRun)
+		return nil
+	}
+
+	d.applyInjection(pod, ns, headers, headerRules, policies)
+	recordInjection(ctx)
+
+	return nil
+}
+
+// applyInjection adds the sidecar, the optional redirect init container and the
+// app container env to the pod.
+func (d *PodCustomDefaulter) applyInjection(pod *corev1.Pod, ns *corev1.Namespace, headers []string, headerRules string, policies *appliedPolicies) {
+	if policies != nil {
+		podLogger(pod).Info("Using header rules from policies", "policies", policies.Names)
+		if pod.Annotations == nil {
+			pod.Annotations = make(map[string]string)
+		}
+		pod.Annotations[AnnotationPolicies] = strings.Join(policies.Names, ",")
+	}
+
+	podLogger(pod).Info("Injecting sidecar", "headers", headers, "hasHeaderRules", headerRules != "")
+
+	if wantsRedirect(pod) {
+		if d.RedirectInitImage == "" {
+			podLogger(pod).Info("Ignoring redirect mode: transparent redirect is not enabled on the operator")
+		} else if mesh := detectMesh(pod, ns); mesh != "" {
+			// The mesh owns the pod's nat rules; HTTP_PROXY still routes through the sidecar
+			podLogger(pod).Info("Ignoring redirect mode: pod is part of a service mesh", "mesh", mesh)
+		} else if uid, ok := d.Security.uid(); !ok {
+			// The proxy's own traffic is exempted by UID, which isn't known here
+			podLogger(pod).Info("Ignoring redirect mode: the proxy UID is assigned by the platform")
+		} else {
+			d.injectRedirectInit(pod, uid)
+		}
+	}
+	d.injectSidecar(pod, ns, headers, headerRules, policies)
+	d.addImagePullSecrets(pod)
 	d.modifyAppContainers(pod)
+	d.addReadinessGate(pod)
 	d.markAsInjected(pod)
+	d.stampRevision(pod)
+}
 
-	return nil
+// resolveHeaders computes the headers and header rules the sidecar should be
+// configured with, from the pod annotations, matching policies and namespace
+// defaults. It also returns the policies that were used, if any.
+func (d *PodCustomDefaulter) resolveHeaders(ctx context.Context, pod *corev1.Pod, ns *corev1.Namespace) ([]string, string, *appliedPolicies, error) {
+	headers := d.extractHeaders(pod)
+	headerRules := d.extractHeaderRules(pod)
+
+	// Invalid rules would crash-loop the proxy, so refuse the pod up front
+	if headerRules != "" {
+		if err := validateHeaderRulesJSON(headerRules); err != nil {
+			return nil, "", nil, fmt.Errorf("invalid %s annotation: %w", AnnotationHeaderRules, err)
+		}
+	}
+
+	// Profiles selected by the pod count as pod-level configuration
+	headers, headerRules, err := d.applyProfiles(pod, headers, headerRules)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	// Pod annotations take precedence; otherwise fall back to matching policies
+	var policies *appliedPolicies
+	if len(headers) == 0 && headerRules == "" {
+		headerRules, policies, err = d.headerRulesFromPolicies(ctx, pod, ns)
+		if err != nil {
+			podLogger(pod).Error(err, "Failed to derive header rules from policies")
+		}
+	}
+
+	// Namespace defaults are added on top of whatever the pod or its policies configured
+	if defaults := namespaceDefaultHeaders(ns); len(defaults) > 0 {
+		headers, headerRules, err = mergeDefaultHeaders(defaults, headers, headerRules)
+		if err != nil {
+			return nil, "", nil, err
+		}
+	}
+
+	return headers, headerRules, policies, nil
 }
 
 // shouldInject checks if the pod should have sidecar injection
 func (d *PodCustomDefaulter) shouldInject(pod *corev1.Pod) bool {
+	// The label form lets webhook objectSelectors match opted-in pods
+	if pod.Labels[AnnotationEnabled] == AnnotationValueTrue {
+		return true
+	}
 	if pod.Annotations == nil {
 		return false
 	}
@@ -124,9 +513,31 @@ func (d *PodCustomDefaulter) shouldInject(pod *corev1.Pod) bool {
 	return ok && enabled == AnnotationValueTrue
 }
 
-// extractHeaders parses the headers annotation
+// isOptedOut checks if the pod explicitly disabled injection via the
+// ctxforge.io/enabled annotation or the ctxforge.io/exclude label.
+// An opt-out always wins over namespace-wide injection.
+func (d *PodCustomDefaulter) isOptedOut(pod *corev1.Pod) bool {
+	if pod.Labels[LabelExclude] == AnnotationValueTrue {
+		return true
+	}
+	return pod.Annotations[AnnotationEnabled] == AnnotationValueFalse
+}
+
+// headerRulesFromPolicies derives HEADER_RULES from the HeaderPropagationPolicies
+// and ClusterHeaderPropagationPolicies selecting the pod and returns them with
+// the rest of the configuration the policies apply.
+func (d *PodCustomDefaulter) headerRulesFromPolicies(ctx context.Context, pod *corev1.Pod, ns *corev1.Namespace) (string, *appliedPolicies, error) {
+	policies, err := d.matchingPolicies(ctx, pod, ns, podNamespace(ctx, pod))
+	if err != nil || len(policies) == 0 {
+		return "", nil, err
+	}
+	return policyHeaderRules(policies, d.PolicyDefaults.Get(ctx))
+}
+
+// extractHeaders parses the headers annotation. Headers the operator wrote
+// from policies are not pod configuration and are ignored.
 func (d *PodCustomDefaulter) extractHeaders(pod *corev1.Pod) []string {
-	if pod.Annotations == nil {
+	if pod.Annotations == nil || policyManaged(pod) {
 		return nil
 	}
 	headersStr, ok := pod.Annotations[AnnotationHeaders]
@@ -145,9 +556,10 @@ func (d *PodCustomDefaulter) extractHeaders(pod *corev1.Pod) []string {
 	return headers
 }
 
-// extractHeaderRules extracts the header-rules annotation for advanced configuration
+// extractHeaderRules extracts the header-rules annotation for advanced
+// configuration, unless the operator wrote it from policies
 func (d *PodCustomDefaulter) extractHeaderRules(pod *corev1.Pod) string {
-	if pod.Annotations == nil {
+	if pod.Annotations == nil || policyManaged(pod) {
 		return ""
 	}
 	headerRules, ok := pod.Annotations[AnnotationHeaderRules]
@@ -169,22 +581,17 @@ func (d *PodCustomDefaulter) isAlreadyInjected(pod *corev1.Pod) bool {
 			return true
 		}
 	}
+	for _, container := range pod.Spec.InitContainers {
+		if container.Name == ProxyContainerName {
+			return true
+		}
+	}
 	return false
 }
 
 // injectSidecar adds the proxy container to the pod
-func (d *PodCustomDefaulter) injectSidecar(pod *corev1.Pod, headers []string, headerRules string) {
-	targetPort := DefaultTargetPort
-	if pod.Annotations != nil {
-		if port, ok := pod.Annotations[AnnotationTargetPort]; ok && port != "" {
-			if err := validateTargetPort(port); err != nil {
-				podlog.Error(err, "Invalid target port annotation, using default",
-					"pod", pod.Name, "port", port, "default", DefaultTargetPort)
-			} else {
-				targetPort = port
-			}
-		}
-	}
+func (d *PodCustomDefaulter) injectSidecar(pod *corev1.Pod, ns *corev1.Namespace, headers []string, headerRules string, policies *appliedPolicies) {
+	targetPort := resolveTargetPort(pod)
 
 	// Build environment variables
 	envVars := []corev1.EnvVar{
@@ -204,6 +611,19 @@ func (d *PodCustomDefaulter) injectSidecar(pod *corev1.Pod, headers []string, he
 			Name:  "LOG_FORMAT",
 			Value: "json",
 		},
+		{
+			Name: "POD_NAME",
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"},
+			},
+		},
+	}
+
+	if d.SecureMode != "" {
+		envVars = append(envVars, corev1.EnvVar{
+			Name:  "SECURE_MODE",
+			Value: d.SecureMode,
+		})
 	}
 
 	// Add HEADER_RULES if specified (takes precedence for advanced config)
@@ -237,7 +657,7 @@ func (d *PodCustomDefaulter) injectSidecar(pod *corev1.Pod, headers []string, he
 		// Resource limits sized for typical API proxy workloads (~100-500 RPS per pod).
 		// Memory: 64Mi request handles Go runtime + connection pools; 256Mi limit provides headroom for traffic spikes.
 		// CPU: 50m request for baseline; 500m limit allows burst during high concurrency.
-		// For high-traffic deployments (>1000 RPS), increase limits via Helm values or annotations.
+		// For high-traffic deployments (>1000 RPS), increase limits via Helm values or a policy's sidecar.resources.
 		Resources: corev1.ResourceRequirements{
 			Requests: corev1.ResourceList{
 				corev1.ResourceMemory: resource.MustParse("64Mi"),
@@ -248,15 +668,7 @@ func (d *PodCustomDefaulter) injectSidecar(pod *corev1.Pod, headers []string, he
 				corev1.ResourceCPU:    resource.MustParse("500m"),
 			},
 		},
-		SecurityContext: &corev1.SecurityContext{
-			RunAsNonRoot:             boolPtr(true),
-			RunAsUser:                int64Ptr(65532),
-			AllowPrivilegeEscalation: boolPtr(false),
-			Capabilities: &corev1.Capabilities{
-				Drop: []corev1.Capability{"ALL"},
-			},
-			ReadOnlyRootFilesystem: boolPtr(true),
-		},
+		SecurityContext: d.Security.container(),
 		LivenessProbe: &corev1.Probe{
 			ProbeHandler: corev1.ProbeHandler{
 				HTTPGet: &corev1.HTTPGetAction{
@@ -279,29 +691,73 @@ func (d *PodCustomDefaulter) injectSidecar(pod *corev1.Pod, headers []string, he
 		},
 	}
 
+	d.applySidecarDefaults(&sidecar)
+	d.applyPolicySidecar(pod, &sidecar, policies)
+	d.Security.applyPod(pod)
+	d.addDrainHook(pod, &sidecar)
+	d.addTargetTLS(pod, &sidecar)
+	d.addAdminToken(pod, &sidecar)
+	d.addSPIFFE(pod, &sidecar)
+	d.addContextSigning(pod, &sidecar)
+	d.addLiveConfig(pod, &sidecar)
+	d.applySidecarTemplate(pod, &sidecar)
+
+	native := d.useNativeSidecar(pod)
+	if native && nativeSidecarBlocked(pod, ns) {
+		podLogger(pod).Info("Injecting the proxy as a regular container next to the regular linkerd proxy")
+		native = false
+	}
+	if native {
+		// Appended after existing init containers so they complete first,
+		// while the proxy still starts before any app container.
+		restartAlways := corev1.ContainerRestartPolicyAlways
+		sidecar.RestartPolicy = &restartAlways
+		sidecar.StartupProbe = proxyStartupProbe()
+		pod.Spec.InitContainers = append(pod.Spec.InitContainers, sidecar)
+		return
+	}
+
+	if isJobPod(pod) {
+		enableExitOnAppExit(pod, &sidecar)
+	}
+
+	if d.sidecarFirst(pod) {
+		if sidecar.Lifecycle == nil {
+			sidecar.Lifecycle = &corev1.Lifecycle{}
+		}
+		sidecar.Lifecycle.PostStart = waitForProxyHook()
+		// Mesh proxies stay first, so the proxy can reach the network once it starts
+		pod.Spec.Containers = slices.Insert(pod.Spec.Containers, leadingMeshProxies(pod), sidecar)
+		return
+	}
+
 	pod.Spec.Containers = append(pod.Spec.Containers, sidecar)
 }
 
-// modifyAppContainers adds HTTP_PROXY env vars to application containers
+// modifyAppContainers points application containers at the proxy, except mesh
+// proxies and those listed in ctxforge.io/skip-containers (e.g. other sidecars
+// that must dial out directly).
+// Pods with ctxforge.io/mutate-app-env: "false" keep all app containers unchanged.
 // Note: HTTPS_PROXY is intentionally not set because the proxy only handles HTTP traffic.
 // HTTPS requests use CONNECT tunneling where encrypted headers cannot be inspected or propagated.
 func (d *PodCustomDefaulter) modifyAppContainers(pod *corev1.Pod) {
-	proxyEnvVars := []corev1.EnvVar{
-		{
-			Name:  "HTTP_PROXY",
-			Value: fmt.Sprintf("http://localhost:%d", ProxyPort),
-		},
-		{
-			Name:  "NO_PROXY",
-			Value: "localhost,127.0.0.1",
-		},
+	if pod.Annotations[AnnotationMutateAppEnv] == AnnotationValueFalse {
+		podLogger(pod).Info("Leaving app container env unchanged")
+		return
+	}
+
+	skip := make(map[string]bool)
+	for _, name := range splitAnnotationList(pod.Annotations[AnnotationSkipContainers]) {
+		skip[name] = true
 	}
 
 	for i := range pod.Spec.Containers {
-		if pod.Spec.Containers[i].Name == ProxyContainerName {
+		name := pod.Spec.Containers[i].Name
+		if name == ProxyContainerName || skip[name] || meshProxyContainers[name] != "" {
 			continue
 		}
-		pod.Spec.Containers[i].Env = append(pod.Spec.Containers[i].Env, proxyEnvVars...)
+		d.mergeProxyEnv(pod, &pod.Spec.Containers[i])
+		d.addAppAdminToken(pod, &pod.Spec.Containers[i])
 	}
 }
 
@@ -316,63 +772,74 @@ func (d *PodCustomDefaulter) markAsInjected(pod *corev1.Pod) {
 // +kubebuilder:webhook:path=/validate--v1-pod,mutating=false,failurePolicy=fail,sideEffects=None,groups="",resources=pods,verbs=create;update,versions=v1,name=vpod-v1.kb.io,admissionReviewVersions=v1
 
 // PodCustomValidator validates Pod resources
-type PodCustomValidator struct{}
+type PodCustomValidator struct {
+	// DenyUndeclaredTargetPort rejects pods whose ctxforge.io/target-port is
+	// not among the app containers' declared ports, instead of warning.
+	DenyUndeclaredTargetPort bool
+}
+
+// podGroupKind identifies pods in validation errors
+var podGroupKind = schema.GroupKind{Kind: "Pod"}
 
 var _ webhook.CustomValidator = &PodCustomValidator{}
 
 // ValidateCreate validates pod creation
 func (v *PodCustomValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	defer observeAdmission(webhookValidating, time.Now())
+
 	pod, ok := obj.(*corev1.Pod)
 	if !ok {
 		return nil, fmt.Errorf("expected a Pod object but got %T", obj)
 	}
 
-	if pod.Annotations == nil {
-		return nil, nil
-	}
-
-	if enabled, ok := pod.Annotations[AnnotationEnabled]; ok && enabled == AnnotationValueTrue {
-		headersStr, hasHeaders := pod.Annotations[AnnotationHeaders]
-		headerRulesStr, hasHeaderRules := pod.Annotations[AnnotationHeaderRules]
-
-		// Need either headers or header-rules
-		if (!hasHeaders || strings.TrimSpace(headersStr) == "") && (!hasHeaderRules || strings.TrimSpace(headerRulesStr) == "") {
-			return admission.Warnings{
-				"ctxforge.io/enabled is set but no headers specified in ctxforge.io/headers or ctxforge.io/header-rules",
-			}, nil
-		}
-
-		// Validate header names if using simple mode
-		if hasHeaders && strings.TrimSpace(headersStr) != "" {
-			parts := strings.Split(headersStr, ",")
-			for _, part := range parts {
-				header := strings.TrimSpace(part)
-				if header != "" {
-					if err := validateHeaderName(header); err != nil {
-						return nil, fmt.Errorf("invalid header in ctxforge.io/headers annotation: %w", err)
-					}
-				}
-			}
+	errs := validatePodAnnotations(pod.Annotations)
+	var warnings admission.Warnings
+	if message := undeclaredTargetPort(pod); message != "" {
+		if v.DenyUndeclaredTargetPort {
+			errs = append(errs, field.Invalid(
+				field.NewPath("metadata", "annotations").Key(AnnotationTargetPort),
+				pod.Annotations[AnnotationTargetPort], message))
+		} else {
+			warnings = append(warnings, AnnotationTargetPort+": "+message)
 		}
+	}
+	if len(errs) > 0 {
+		return nil, apierrors.NewInvalid(podGroupKind, podRef(pod), errs)
+	}
 
-		// Validate header-rules is valid JSON if specified
-		if hasHeaderRules && strings.TrimSpace(headerRulesStr) != "" {
-			if err := validateHeaderRulesJSON(headerRulesStr); err != nil {
-				return nil, fmt.Errorf("invalid ctxforge.io/header-rules annotation: %w", err)
-			}
-		}
+	if pod.Annotations[AnnotationEnabled] == AnnotationValueTrue &&
+		strings.TrimSpace(pod.Annotations[AnnotationHeaders]) == "" &&
+		strings.TrimSpace(pod.Annotations[AnnotationHeaderRules]) == "" {
+		warnings = append(warnings,
+			"ctxforge.io/enabled is set but no headers specified in ctxforge.io/headers or ctxforge.io/header-rules; "+
+				"the sidecar is only injected if a HeaderPropagationPolicy selects this pod",
+		)
 	}
 
-	return nil, nil
+	return append(warnings, meshConflictWarnings(pod)...), nil
 }
 
-// ValidateUpdate validates pod updates
-func (v *PodCustomValidator) ValidateUpdate(_ context.Context, _, newObj runtime.Object) (admission.Warnings, error) {
+// ValidateUpdate validates pod updates. Annotations are only checked when a
+// ctxforge.io/* annotation changed, so pods admitted before a stricter check
+// was introduced can still be updated (e.g. to remove finalizers).
+func (v *PodCustomValidator) ValidateUpdate(_ context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	defer observeAdmission(webhookValidating, time.Now())
+
 	pod, ok := newObj.(*corev1.Pod)
 	if !ok {
 		return nil, fmt.Errorf("expected a Pod object but got %T", newObj)
 	}
-	_ = pod
+	oldPod, ok := oldObj.(*corev1.Pod)
+	if !ok {
+		return nil, fmt.Errorf("expected a Pod object but got %T", oldObj)
+	}
+
+	if !ctxforgeAnnotationsChanged(oldPod.Annotations, pod.Annotations) {
+		return nil, nil
+	}
+	if errs := validatePodAnnotations(pod.Annotations); len(errs) > 0 {
+		return nil, apierrors.NewInvalid(podGroupKind, podRef(pod), errs)
+	}
 	return nil, nil
 }
 
@@ -431,51 +898,35 @@ func validateHeaderName(name string) error {
 	return nil
 }
 
-// headerRule represents a single header rule for validation
+// headerRule is a HEADER_RULES entry as the webhook merges them into the
+// sidecar's configuration. Merging namespace defaults and profiles keeps only
+// the fields declared here, so it mirrors config.HeaderRule.
 type headerRule struct {
-	Name          string   `json:"name"`
-	Generate      bool     `json:"generate,omitempty"`
-	GeneratorType string   `json:"generatorType,omitempty"`
-	Propagate     *bool    `json:"propagate,omitempty"`
-	PathRegex     string   `json:"pathRegex,omitempty"`
-	Methods       []string `json:"methods,omitempty"`
+	Name          string               `json:"name"`
+	Generate      bool                 `json:"generate,omitempty"`
+	GeneratorType string               `json:"generatorType,omitempty"`
+	Propagate     *bool                `json:"propagate,omitempty"`
+	PathRegex     string               `json:"pathRegex,omitempty"`
+	ExcludePaths  []string             `json:"excludePaths,omitempty"`
+	Methods       []string             `json:"methods,omitempty"`
+	Rename        string               `json:"rename,omitempty"`
+	StaticValue   string               `json:"staticValue,omitempty"`
+	Transform     *config.Transform    `json:"transform,omitempty"`
+	OnExisting    string               `json:"onExisting,omitempty"`
+	Destinations  *config.Destinations `json:"destinations,omitempty"`
+	Sampling      *config.Sampling     `json:"sampling,omitempty"`
+	Response      string               `json:"response,omitempty"`
 }
 
-// validateHeaderRulesJSON validates that the header-rules annotation is valid JSON
-// and contains properly structured header rules.
+// validateHeaderRulesJSON checks header rules with the proxy's own parser, so
+// that rules the proxy would refuse to start with are rejected on admission.
 func validateHeaderRulesJSON(rulesJSON string) error {
-	var rules []headerRule
-	if err := json.Unmarshal([]byte(rulesJSON), &rules); err != nil {
-		return fmt.Errorf("invalid JSON: %w", err)
+	rules, err := config.ParseHeaderRules(rulesJSON)
+	if err != nil {
+		return err
 	}
-
 	if len(rules) == 0 {
 		return fmt.Errorf("header-rules array cannot be empty")
 	}
-
-	validGeneratorTypes := map[string]bool{
-		"":          true, // empty is valid (defaults to uuid)
-		"uuid":      true,
-		"ulid":      true,
-		"timestamp": true,
-	}
-
-	for i, rule := range rules {
-		if rule.Name == "" {
-			return fmt.Errorf("rule[%d]: name is required", i)
-		}
-		if err := validateHeaderName(rule.Name); err != nil {
-			return fmt.Errorf("rule[%d]: %w", i, err)
-		}
-		if rule.Generate && !validGeneratorTypes[rule.GeneratorType] {
-			return fmt.Errorf("rule[%d]: invalid generatorType %q, must be one of: uuid, ulid, timestamp", i, rule.GeneratorType)
-		}
-		if rule.PathRegex != "" {
-			if _, err := regexp.Compile(rule.PathRegex); err != nil {
-				return fmt.Errorf("rule[%d]: invalid pathRegex %q: %w", i, rule.PathRegex, err)
-			}
-		}
-	}
-
 	return nil
 }
@@ -22,8 +22,11 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
 func TestPodCustomDefaulter_ShouldInject(t *testing.T) {
@@ -82,6 +85,108 @@ func TestPodCustomDefaulter_ShouldInject(t *testing.T) {
 	}
 }
 
+func TestPodCustomDefaulter_NamespaceInjection(t *testing.T) {
+	enabledNs := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:   "enabled-ns",
+		Labels: map[string]string{LabelNamespaceInjection: NamespaceInjectionEnabled},
+	}}
+	plainNs := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "plain-ns"}}
+	defaulter := &PodCustomDefaulter{
+		ProxyImage: DefaultProxyImage,
+		Client:     fake.NewClientBuilder().WithObjects(enabledNs, plainNs).Build(),
+	}
+
+	tests := []struct {
+		name        string
+		namespace   string
+		labels      map[string]string
+		annotations map[string]string
+		injected    bool
+	}{
+		{
+			name:        "enabled namespace",
+			namespace:   "enabled-ns",
+			annotations: map[string]string{AnnotationHeaders: "x-request-id"},
+			injected:    true,
+		},
+		{
+			name:        "enabled namespace, pod opts out via annotation",
+			namespace:   "enabled-ns",
+			annotations: map[string]string{AnnotationEnabled: "false", AnnotationHeaders: "x-request-id"},
+		},
+		{
+			name:        "enabled namespace, pod opts out via label",
+			namespace:   "enabled-ns",
+			labels:      map[string]string{LabelExclude: "true"},
+			annotations: map[string]string{AnnotationHeaders: "x-request-id"},
+		},
+		{
+			name:        "exclude label wins over enabled annotation",
+			namespace:   "plain-ns",
+			labels:      map[string]string{LabelExclude: "true"},
+			annotations: map[string]string{AnnotationEnabled: "true", AnnotationHeaders: "x-request-id"},
+		},
+		{
+			name:        "plain namespace",
+			namespace:   "plain-ns",
+			annotations: map[string]string{AnnotationHeaders: "x-request-id"},
+		},
+		{
+			name:        "unknown namespace",
+			namespace:   "missing-ns",
+			annotations: map[string]string{AnnotationHeaders: "x-request-id"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "test-pod",
+					Namespace:   tt.namespace,
+					Labels:      tt.labels,
+					Annotations: tt.annotations,
+				},
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+			}
+
+			require.NoError(t, defaulter.Default(context.Background(), pod))
+
+			if tt.injected {
+				assert.Len(t, pod.Spec.Containers, 2)
+			} else {
+				assert.Len(t, pod.Spec.Containers, 1)
+			}
+		})
+	}
+}
+
+func TestPodCustomDefaulter_NamespaceInjection_FromAdmissionRequest(t *testing.T) {
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:   "enabled-ns",
+		Labels: map[string]string{LabelNamespaceInjection: NamespaceInjectionEnabled},
+	}}
+	defaulter := &PodCustomDefaulter{
+		ProxyImage: DefaultProxyImage,
+		Client:     fake.NewClientBuilder().WithObjects(ns).Build(),
+	}
+
+	// Pods created by a ReplicaSet arrive without metadata.namespace set.
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "app-",
+			Annotations:  map[string]string{AnnotationHeaders: "x-request-id"},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+	}
+	ctx := admission.NewContextWithRequest(context.Background(), admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{Namespace: "enabled-ns"},
+	})
+
+	require.NoError(t, defaulter.Default(ctx, pod))
+	assert.Len(t, pod.Spec.Containers, 2)
+}
+
 func TestPodCustomDefaulter_ExtractHeaders(t *testing.T) {
 	defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage}
 
@@ -216,7 +321,7 @@ func TestPodCustomDefaulter_InjectSidecar(t *testing.T) {
 	}
 
 	headers := []string{"x-request-id", "x-dev-id"}
-	defaulter.injectSidecar(pod, headers, "")
+	defaulter.injectSidecar(pod, nil, headers, "", nil)
 
 	assert.Len(t, pod.Spec.Containers, 2)
 
@@ -267,7 +372,7 @@ func TestPodCustomDefaulter_InjectSidecar_CustomTargetPort(t *testing.T) {
 		},
 	}
 
-	defaulter.injectSidecar(pod, []string{"x-request-id"}, "")
+	defaulter.injectSidecar(pod, nil, []string{"x-request-id"}, "", nil)
 
 	var sidecar *corev1.Container
 	for i := range pod.Spec.Containers {
@@ -289,6 +394,35 @@ func TestPodCustomDefaulter_InjectSidecar_CustomTargetPort(t *testing.T) {
 	assert.Equal(t, "localhost:3000", targetHostEnv.Value)
 }
 
+func TestPodCustomDefaulter_InjectSidecar_PodName(t *testing.T) {
+	defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-pod"},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "app"},
+			},
+		},
+	}
+
+	defaulter.injectSidecar(pod, nil, []string{"x-request-id"}, "", nil)
+
+	sidecar := pod.Spec.Containers[len(pod.Spec.Containers)-1]
+	require.Equal(t, ProxyContainerName, sidecar.Name)
+
+	var podNameEnv *corev1.EnvVar
+	for i := range sidecar.Env {
+		if sidecar.Env[i].Name == "POD_NAME" {
+			podNameEnv = &sidecar.Env[i]
+			break
+		}
+	}
+	require.NotNil(t, podNameEnv)
+	require.NotNil(t, podNameEnv.ValueFrom)
+	assert.Equal(t, "metadata.name", podNameEnv.ValueFrom.FieldRef.FieldPath)
+}
+
 func TestPodCustomDefaulter_ModifyAppContainers(t *testing.T) {
 	defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage}
 
@@ -331,6 +465,29 @@ func TestPodCustomDefaulter_ModifyAppContainers(t *testing.T) {
 	}
 }
 
+func TestPodCustomDefaulter_ModifyAppContainers_SkipContainers(t *testing.T) {
+	defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{AnnotationSkipContainers: "istio-proxy, vault-agent"},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "app"},
+				{Name: "istio-proxy"},
+				{Name: "vault-agent"},
+			},
+		},
+	}
+
+	defaulter.modifyAppContainers(pod)
+
+	assert.NotEmpty(t, pod.Spec.Containers[0].Env, "app container should get proxy env vars")
+	assert.Empty(t, pod.Spec.Containers[1].Env, "istio-proxy should be skipped")
+	assert.Empty(t, pod.Spec.Containers[2].Env, "vault-agent should be skipped")
+}
+
 func TestPodCustomDefaulter_Default_FullInjection(t *testing.T) {
 	defaulter := &PodCustomDefaulter{ProxyImage: "test-proxy:v1"}
 
@@ -365,6 +522,47 @@ func TestPodCustomDefaulter_Default_FullInjection(t *testing.T) {
 	assert.True(t, foundProxy)
 }
 
+func TestPodCustomDefaulter_Default_HeaderRules(t *testing.T) {
+	defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage}
+	rules := `[{"name":"x-request-id","generate":true,"generatorType":"ulid"},{"name":"x-tenant-id","pathRegex":"^/api/"}]`
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-pod",
+			Annotations: map[string]string{
+				AnnotationEnabled:     "true",
+				AnnotationHeaderRules: "  " + rules + "\n",
+			},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+	}
+
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+	require.Len(t, pod.Spec.Containers, 2)
+	assert.Equal(t, rules, sidecarEnv(t, pod, "HEADER_RULES"))
+	assert.Empty(t, sidecarEnv(t, pod, "HEADERS_TO_PROPAGATE"))
+}
+
+func TestPodCustomDefaulter_Default_RejectsInvalidHeaderRules(t *testing.T) {
+	defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-pod",
+			Annotations: map[string]string{
+				AnnotationEnabled:     "true",
+				AnnotationHeaderRules: `[{"name":"x-request-id","generate":true,"generatorType":"sequence"}]`,
+			},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+	}
+
+	err := defaulter.Default(context.Background(), pod)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "ctxforge.io/header-rules")
+	assert.Len(t, pod.Spec.Containers, 1)
+}
+
 func TestPodCustomDefaulter_Default_SkipsWhenNotEnabled(t *testing.T) {
 	defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage}
 
@@ -526,7 +724,7 @@ func TestPodCustomDefaulter_InjectSidecar_InvalidTargetPort(t *testing.T) {
 				},
 			}
 
-			defaulter.injectSidecar(pod, []string{"x-request-id"}, "")
+			defaulter.injectSidecar(pod, nil, []string{"x-request-id"}, "", nil)
 
 			var sidecar *corev1.Container
 			for i := range pod.Spec.Containers {
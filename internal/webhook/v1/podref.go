@@ -0,0 +1,46 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// podRef names a pod in logs and errors. Pods created by controllers have no
+// name yet at admission, only a generateName prefix, which is shown as "<prefix>*".
+func podRef(pod *corev1.Pod) string {
+	if pod.Name == "" && pod.GenerateName != "" {
+		return pod.GenerateName + "*"
+	}
+	return pod.Name
+}
+
+// podLogger returns the webhook logger with the pod's name, namespace and
+// controlling owner, so injection decisions about pods that are still unnamed
+// can be traced back to their ReplicaSet, Job or StatefulSet.
+func podLogger(pod *corev1.Pod) logr.Logger {
+	keysAndValues := []any{"pod", podRef(pod)}
+	if pod.Namespace != "" {
+		keysAndValues = append(keysAndValues, "namespace", pod.Namespace)
+	}
+	if owner := metav1.GetControllerOf(pod); owner != nil {
+		keysAndValues = append(keysAndValues, "owner", owner.Kind+"/"+owner.Name)
+	}
+	return podlog.WithValues(keysAndValues...)
+}
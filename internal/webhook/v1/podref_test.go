@@ -0,0 +1,46 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPodRef(t *testing.T) {
+	assert.Equal(t, "api", podRef(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "api", GenerateName: "api-"}}))
+	assert.Equal(t, "api-7d9f8c-*", podRef(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{GenerateName: "api-7d9f8c-"}}))
+	assert.Empty(t, podRef(&corev1.Pod{}))
+}
+
+func TestValidateCreate_NamesUnnamedPods(t *testing.T) {
+	controller := true
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		GenerateName: "api-7d9f8c-",
+		Annotations:  map[string]string{AnnotationEnabled: "true", AnnotationTargetPort: "0"},
+		OwnerReferences: []metav1.OwnerReference{{
+			APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "api-7d9f8c", UID: "uid", Controller: &controller,
+		}},
+	}}
+
+	_, err := (&PodCustomValidator{}).ValidateCreate(t.Context(), pod)
+
+	assert.ErrorContains(t, err, `Pod "api-7d9f8c-*" is invalid`)
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+	"github.com/bgruszka/contextforge/internal/policy"
+)
+
+// +kubebuilder:rbac:groups=ctxforge.ctxforge.io,resources=headerpropagationpolicies,verbs=get;list;watch
+// +kubebuilder:rbac:groups=ctxforge.ctxforge.io,resources=clusterheaderpropagationpolicies,verbs=get;list;watch
+
+// matchingPolicies returns the HeaderPropagationPolicies in the pod's namespace
+// and the ClusterHeaderPropagationPolicies selecting the pod, in order of
+// precedence (see policy.Less). Policies in Audit mode are left out.
+func (d *PodCustomDefaulter) matchingPolicies(ctx context.Context, pod *corev1.Pod, ns *corev1.Namespace, namespace string) ([]policy.Source, error) {
+	if d.Client == nil || namespace == "" {
+		return nil, nil
+	}
+
+	policyList := &ctxforgev1beta1.HeaderPropagationPolicyList{}
+	if err := d.Client.List(ctx, policyList, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list HeaderPropagationPolicies: %w", err)
+	}
+	clusterPolicyList := &ctxforgev1beta1.ClusterHeaderPropagationPolicyList{}
+	if err := d.Client.List(ctx, clusterPolicyList); err != nil {
+		return nil, fmt.Errorf("failed to list ClusterHeaderPropagationPolicies: %w", err)
+	}
+
+	sources := make([]policy.Source, 0, len(policyList.Items)+len(clusterPolicyList.Items))
+	for i := range policyList.Items {
+		sources = append(sources, policy.FromPolicy(&policyList.Items[i]))
+	}
+	for i := range clusterPolicyList.Items {
+		sources = append(sources, policy.FromClusterPolicy(&clusterPolicyList.Items[i]))
+	}
+
+	// Without the namespace object only its name label is known
+	nsLabels := labels.Set{corev1.LabelMetadataName: namespace}
+	if ns != nil {
+		nsLabels = labels.Set(ns.Labels)
+	}
+	// The pod may not carry its namespace yet
+	selected := pod.DeepCopy()
+	selected.Namespace = namespace
+
+	var matched []policy.Source
+	for _, source := range policy.Enforced(sources) {
+		ok, err := source.Selects(selected, nsLabels)
+		if err != nil {
+			podlog.Error(err, "Ignoring invalid policy", "policy", source.Name, "namespace", source.Namespace)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		// The proxy would fail to start with rules it rejects
+		if err := policy.Validate(source.Spec); err != nil {
+			podlog.Error(err, "Ignoring policy with invalid propagation rules", "policy", source.Name, "namespace", source.Namespace)
+			continue
+		}
+		matched = append(matched, source)
+	}
+	policy.Sort(matched)
+	return matched, nil
+}
+
+// appliedPolicies is the configuration a pod takes from the policies
+// selecting it, besides their header rules.
+type appliedPolicies struct {
+	// Names are the policies that contributed to the pod's header rules.
+	Names []string
+	// Sources are those policies, in order of precedence.
+	Sources []policy.Source
+	// Image replaces the proxy image when a policy pins one.
+	Image string
+	// Resources size the proxy sidecar when a policy sets them.
+	Resources *corev1.ResourceRequirements
+}
+
+// policyHeaderRules merges the rules of the given policies, with the defaults
+// folded into each of them (see policy.Merge and policy.Defaults.Apply), into
+// the JSON accepted by the proxy's HEADER_RULES env var.
+func policyHeaderRules(policies []policy.Source, defaults policy.Defaults) (string, *appliedPolicies, error) {
+	merged := policy.Merge(defaults.ApplyAll(policies))
+	if len(merged.Rules) == 0 {
+		return "", nil, nil
+	}
+
+	data, err := json.Marshal(merged.Rules)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to encode header rules: %w", err)
+	}
+	// Every policy carries the default headers; only the policies' own
+	// headers can conflict
+	sources := append([]policy.Source(nil), policies...)
+	policy.Sort(sources)
+	return string(data), &appliedPolicies{
+		Names:     policyNames(policies),
+		Sources:   sources,
+		Image:     merged.Image,
+		Resources: merged.Resources,
+	}, nil
+}
+
+// applyPolicySidecar sets the proxy image and resources the pod's policies
+// pin. The operator's sidecar template is applied afterwards and still has
+// the final say.
+func (d *PodCustomDefaulter) applyPolicySidecar(pod *corev1.Pod, sidecar *corev1.Container, policies *appliedPolicies) {
+	if policies == nil {
+		return
+	}
+	if policies.Image != "" {
+		if d.AllowedImages.Allows(policies.Image) {
+			sidecar.Image = policies.Image
+		} else {
+			podLogger(pod).Info("Ignoring proxy image of policies: not in the proxy image allowlist",
+				"image", policies.Image, "policies", policies.Names)
+		}
+	}
+	if policies.Resources != nil {
+		sidecar.Resources = *policies.Resources.DeepCopy()
+	}
+}
+
+// policyNames returns the names of the given policies.
+func policyNames(policies []policy.Source) []string {
+	names := make([]string, 0, len(policies))
+	for _, source := range policies {
+		names = append(names, source.Name)
+	}
+	return names
+}
@@ -0,0 +1,495 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+	"github.com/bgruszka/contextforge/internal/policy"
+)
+
+// newFakeClient returns a fake client that knows about core and ctxforge types.
+func newFakeClient(t *testing.T, objs ...client.Object) client.Client {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, ctxforgev1beta1.AddToScheme(scheme))
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+}
+
+func newPolicy(name string, selector map[string]string, rules ...ctxforgev1beta1.PropagationRule) *ctxforgev1beta1.HeaderPropagationPolicy {
+	policy := &ctxforgev1beta1.HeaderPropagationPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec:       ctxforgev1beta1.HeaderPropagationPolicySpec{PropagationRules: rules},
+	}
+	if selector != nil {
+		policy.Spec.PodSelector = &metav1.LabelSelector{MatchLabels: selector}
+	}
+	return policy
+}
+
+func sidecarEnv(t *testing.T, pod *corev1.Pod, name string) string {
+	t.Helper()
+	for _, c := range pod.Spec.Containers {
+		if c.Name != ProxyContainerName {
+			continue
+		}
+		for _, env := range c.Env {
+			if env.Name == name {
+				return env.Value
+			}
+		}
+	}
+	return ""
+}
+
+func TestPodCustomDefaulter_PolicyDrivenInjection(t *testing.T) {
+	tracing := newPolicy("tracing", map[string]string{"app": "api"}, ctxforgev1beta1.PropagationRule{
+		Headers: []ctxforgev1beta1.HeaderConfig{
+			{Name: "x-request-id", Generate: true, GeneratorType: "uuid"},
+		},
+	})
+	tenant := newPolicy("tenant", nil, ctxforgev1beta1.PropagationRule{
+		Headers:   []ctxforgev1beta1.HeaderConfig{{Name: "x-tenant-id"}, {Name: "X-Request-Id"}},
+		PathRegex: "^/api/",
+		Methods:   []string{"GET"},
+	})
+	other := newPolicy("other", map[string]string{"app": "web"}, ctxforgev1beta1.PropagationRule{
+		Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-web-only"}},
+	})
+
+	defaulter := &PodCustomDefaulter{
+		ProxyImage: DefaultProxyImage,
+		Client:     newFakeClient(t, tracing, tenant, other),
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "api-pod",
+			Namespace:   "default",
+			Labels:      map[string]string{"app": "api"},
+			Annotations: map[string]string{AnnotationEnabled: "true"},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+	}
+
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+	require.Len(t, pod.Spec.Containers, 2)
+	assert.Equal(t, "tenant,tracing", pod.Annotations[AnnotationPolicies])
+
+	var rules []headerRule
+	require.NoError(t, json.Unmarshal([]byte(sidecarEnv(t, pod, "HEADER_RULES")), &rules))
+	require.Len(t, rules, 2, "duplicate x-request-id from the later policy must be dropped")
+
+	assert.Equal(t, "x-tenant-id", rules[0].Name)
+	assert.Equal(t, "^/api/", rules[0].PathRegex)
+	assert.Equal(t, []string{"GET"}, rules[0].Methods)
+	assert.Equal(t, "X-Request-Id", rules[1].Name, "policies are applied in name order")
+	assert.NoError(t, validateHeaderRulesJSON(sidecarEnv(t, pod, "HEADER_RULES")))
+}
+
+func TestPodCustomDefaulter_AuditPoliciesChangeNothing(t *testing.T) {
+	tracing := newPolicy("tracing", nil, ctxforgev1beta1.PropagationRule{
+		Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-request-id"}},
+	})
+	tenant := newPolicy("tenant", nil, ctxforgev1beta1.PropagationRule{
+		Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-tenant-id"}},
+	})
+	tenant.Spec.Mode = ctxforgev1beta1.PolicyModeAudit
+	newPod := func() *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "api-pod",
+				Namespace:   "default",
+				Annotations: map[string]string{AnnotationEnabled: "true"},
+			},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+		}
+	}
+
+	defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage, Client: newFakeClient(t, tracing, tenant)}
+	pod := newPod()
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+	assert.Equal(t, "tracing", pod.Annotations[AnnotationPolicies])
+	assert.NotContains(t, sidecarEnv(t, pod, "HEADER_RULES"), "x-tenant-id")
+
+	defaulter = &PodCustomDefaulter{ProxyImage: DefaultProxyImage, Client: newFakeClient(t, tenant)}
+	pod = newPod()
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+	assert.Len(t, pod.Spec.Containers, 1, "an Audit policy alone doesn't inject the sidecar")
+}
+
+func TestPodCustomDefaulter_AnnotationsOverridePolicies(t *testing.T) {
+	policy := newPolicy("tracing", nil, ctxforgev1beta1.PropagationRule{
+		Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-request-id"}},
+	})
+	defaulter := &PodCustomDefaulter{
+		ProxyImage: DefaultProxyImage,
+		Client:     newFakeClient(t, policy),
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "api-pod",
+			Namespace: "default",
+			Annotations: map[string]string{
+				AnnotationEnabled: "true",
+				AnnotationHeaders: "x-tenant-id",
+			},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+	}
+
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+	require.Len(t, pod.Spec.Containers, 2)
+	assert.Equal(t, "x-tenant-id", sidecarEnv(t, pod, "HEADERS_TO_PROPAGATE"))
+	assert.Empty(t, sidecarEnv(t, pod, "HEADER_RULES"))
+	assert.NotContains(t, pod.Annotations, AnnotationPolicies)
+}
+
+func TestPodCustomDefaulter_NoMatchingPolicy(t *testing.T) {
+	policy := newPolicy("web", map[string]string{"app": "web"}, ctxforgev1beta1.PropagationRule{
+		Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-request-id"}},
+	})
+	defaulter := &PodCustomDefaulter{
+		ProxyImage: DefaultProxyImage,
+		Client:     newFakeClient(t, policy),
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "api-pod",
+			Namespace:   "default",
+			Labels:      map[string]string{"app": "api"},
+			Annotations: map[string]string{AnnotationEnabled: "true"},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+	}
+
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+	assert.Len(t, pod.Spec.Containers, 1)
+}
+
+func TestPodCustomDefaulter_ClusterPolicies(t *testing.T) {
+	namespaced := newPolicy("tenant", nil, ctxforgev1beta1.PropagationRule{
+		Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-tenant-id"}},
+	})
+	payments := &ctxforgev1beta1.ClusterHeaderPropagationPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "payments"},
+		Spec: ctxforgev1beta1.HeaderPropagationPolicySpec{
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "payments"}},
+			PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}},
+			PropagationRules: []ctxforgev1beta1.PropagationRule{{
+				Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-payment-id"}, {Name: "X-Tenant-Id"}},
+			}},
+		},
+	}
+	everywhere := &ctxforgev1beta1.ClusterHeaderPropagationPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "tracing"},
+		Spec: ctxforgev1beta1.HeaderPropagationPolicySpec{
+			PropagationRules: []ctxforgev1beta1.PropagationRule{{
+				Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-request-id"}},
+			}},
+		},
+	}
+	search := &ctxforgev1beta1.ClusterHeaderPropagationPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "search"},
+		Spec: ctxforgev1beta1.HeaderPropagationPolicySpec{
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "search"}},
+			PropagationRules: []ctxforgev1beta1.PropagationRule{{
+				Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-search-id"}},
+			}},
+		},
+	}
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:   "default",
+		Labels: map[string]string{"team": "payments"},
+	}}
+
+	defaulter := &PodCustomDefaulter{
+		ProxyImage: DefaultProxyImage,
+		Client:     newFakeClient(t, ns, namespaced, payments, everywhere, search),
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "api-pod",
+			Namespace:   "default",
+			Labels:      map[string]string{"app": "api"},
+			Annotations: map[string]string{AnnotationEnabled: "true"},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+	}
+
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+	require.Len(t, pod.Spec.Containers, 2)
+	assert.Equal(t, "tenant,clusterheaderpropagationpolicy/payments,clusterheaderpropagationpolicy/tracing",
+		pod.Annotations[AnnotationPolicies], "namespaced policies come before cluster policies")
+
+	var rules []headerRule
+	require.NoError(t, json.Unmarshal([]byte(sidecarEnv(t, pod, "HEADER_RULES")), &rules))
+	names := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		names = append(names, rule.Name)
+	}
+	assert.Equal(t, []string{"x-tenant-id", "x-payment-id", "x-request-id"}, names)
+}
+
+func TestPodCustomDefaulter_PolicyPriority(t *testing.T) {
+	tenant := newPolicy("tenant", nil, ctxforgev1beta1.PropagationRule{
+		Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-request-id"}},
+	})
+	tracing := newPolicy("tracing", nil, ctxforgev1beta1.PropagationRule{
+		Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-request-id", Generate: true, GeneratorType: "uuid"}},
+	})
+	tracing.Spec.Priority = 10
+	platform := &ctxforgev1beta1.ClusterHeaderPropagationPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "platform"},
+		Spec: ctxforgev1beta1.HeaderPropagationPolicySpec{
+			Priority: 5,
+			PropagationRules: []ctxforgev1beta1.PropagationRule{{
+				Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-request-id"}},
+			}},
+		},
+	}
+
+	defaulter := &PodCustomDefaulter{
+		ProxyImage: DefaultProxyImage,
+		Client:     newFakeClient(t, tenant, tracing, platform),
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "api-pod",
+			Namespace:   "default",
+			Annotations: map[string]string{AnnotationEnabled: "true"},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+	}
+
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+	assert.Equal(t, "tracing,clusterheaderpropagationpolicy/platform,tenant", pod.Annotations[AnnotationPolicies],
+		"policies are ordered by priority before scope and name")
+
+	var rules []headerRule
+	require.NoError(t, json.Unmarshal([]byte(sidecarEnv(t, pod, "HEADER_RULES")), &rules))
+	require.Len(t, rules, 1)
+	assert.True(t, rules[0].Generate, "the highest priority policy wins the header")
+}
+
+func TestPodCustomDefaulter_PolicySidecarResources(t *testing.T) {
+	large := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("200m")},
+		Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+	}
+	tenant := newPolicy("tenant", nil, ctxforgev1beta1.PropagationRule{
+		Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-tenant-id"}},
+	})
+	tenant.Spec.Sidecar = &ctxforgev1beta1.SidecarConfig{Resources: &corev1.ResourceRequirements{
+		Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+	}}
+	tracing := newPolicy("tracing", nil, ctxforgev1beta1.PropagationRule{
+		Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-request-id"}},
+	})
+	tracing.Spec.Priority = 10
+	tracing.Spec.Sidecar = &ctxforgev1beta1.SidecarConfig{Resources: &large}
+
+	defaulter := &PodCustomDefaulter{
+		ProxyImage: DefaultProxyImage,
+		Client:     newFakeClient(t, tenant, tracing),
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "api-pod",
+			Namespace:   "default",
+			Annotations: map[string]string{AnnotationEnabled: "true"},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+	}
+
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+	sidecar := proxyContainer(pod)
+	require.NotNil(t, sidecar)
+	assert.Equal(t, large, sidecar.Resources, "the highest priority policy sizes the proxy")
+
+	// Pods configured by their own annotations keep the default resources
+	pod = &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "api-pod",
+			Namespace: "default",
+			Annotations: map[string]string{
+				AnnotationEnabled: "true",
+				AnnotationHeaders: "x-request-id",
+			},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+	}
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+	sidecar = proxyContainer(pod)
+	require.NotNil(t, sidecar)
+	assert.Equal(t, resource.MustParse("64Mi"), sidecar.Resources.Requests[corev1.ResourceMemory])
+}
+
+func TestPodCustomDefaulter_PolicySidecarImage(t *testing.T) {
+	canary := newPolicy("canary", map[string]string{"track": "canary"}, ctxforgev1beta1.PropagationRule{
+		Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-request-id"}},
+	})
+	canary.Spec.Sidecar = &ctxforgev1beta1.SidecarConfig{Image: "ghcr.io/bgruszka/contextforge-proxy:0.2.0-rc.1"}
+	untrusted := newPolicy("untrusted", map[string]string{"track": "untrusted"}, ctxforgev1beta1.PropagationRule{
+		Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-request-id"}},
+	})
+	untrusted.Spec.Sidecar = &ctxforgev1beta1.SidecarConfig{Image: "docker.io/someone/proxy:latest"}
+
+	defaulter := &PodCustomDefaulter{
+		ProxyImage:    DefaultProxyImage,
+		Client:        newFakeClient(t, canary, untrusted),
+		AllowedImages: policy.ImageAllowlist{"ghcr.io/bgruszka/contextforge-proxy"},
+	}
+	injectedImage := func(track string) string {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "api-pod",
+				Namespace:   "default",
+				Labels:      map[string]string{"track": track},
+				Annotations: map[string]string{AnnotationEnabled: "true"},
+			},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+		}
+		require.NoError(t, defaulter.Default(context.Background(), pod))
+		sidecar := proxyContainer(pod)
+		require.NotNil(t, sidecar)
+		return sidecar.Image
+	}
+
+	assert.Equal(t, "ghcr.io/bgruszka/contextforge-proxy:0.2.0-rc.1", injectedImage("canary"))
+	assert.Equal(t, DefaultProxyImage, injectedImage("untrusted"), "images outside the allowlist are ignored")
+}
+
+func TestPodCustomDefaulter_PolicyScopesHeaderByPath(t *testing.T) {
+	tenant := newPolicy("tenant", nil,
+		ctxforgev1beta1.PropagationRule{
+			PathRegex: "^/api/",
+			Headers:   []ctxforgev1beta1.HeaderConfig{{Name: "x-tenant-id"}},
+		},
+		ctxforgev1beta1.PropagationRule{
+			PathRegex: "^/admin/",
+			Headers:   []ctxforgev1beta1.HeaderConfig{{Name: "x-tenant-id"}},
+		},
+	)
+	defaulter := &PodCustomDefaulter{
+		ProxyImage: DefaultProxyImage,
+		Client:     newFakeClient(t, tenant),
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "api-pod",
+			Namespace:   "default",
+			Annotations: map[string]string{AnnotationEnabled: "true"},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+	}
+
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+
+	var rules []headerRule
+	require.NoError(t, json.Unmarshal([]byte(sidecarEnv(t, pod, "HEADER_RULES")), &rules))
+	require.Len(t, rules, 2, "a policy may scope a header to several paths")
+	assert.Equal(t, "^/api/", rules[0].PathRegex)
+	assert.Equal(t, "^/admin/", rules[1].PathRegex)
+}
+
+func TestPodCustomDefaulter_PolicyDefaults(t *testing.T) {
+	tenant := newPolicy("tenant", nil, ctxforgev1beta1.PropagationRule{
+		PathRegex: "^/api/",
+		Headers: []ctxforgev1beta1.HeaderConfig{
+			{Name: "x-tenant-id"},
+			{Name: "x-correlation-id", Generate: true},
+		},
+	})
+	defaults := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: policy.DefaultsConfigMapName, Namespace: "contextforge-system"},
+		Data: map[string]string{
+			policy.DefaultsHeadersKey:       "- name: x-request-id\n  generate: true\n- name: X-Tenant-Id\n",
+			policy.DefaultsGeneratorTypeKey: "ulid",
+		},
+	}
+	c := newFakeClient(t, tenant, defaults)
+	defaulter := &PodCustomDefaulter{
+		ProxyImage: DefaultProxyImage,
+		Client:     c,
+		PolicyDefaults: &policy.DefaultsSource{
+			Client: c,
+			Key:    client.ObjectKeyFromObject(defaults),
+		},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "api-pod",
+			Namespace:   "default",
+			Annotations: map[string]string{AnnotationEnabled: "true"},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+	}
+
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+
+	var rules []headerRule
+	require.NoError(t, json.Unmarshal([]byte(sidecarEnv(t, pod, "HEADER_RULES")), &rules))
+	require.Len(t, rules, 3, "the policy's own x-tenant-id wins over the default")
+	assert.Equal(t, "x-tenant-id", rules[0].Name)
+	assert.Equal(t, headerRule{Name: "x-correlation-id", Generate: true, GeneratorType: "ulid", PathRegex: "^/api/"}, rules[1])
+	assert.Equal(t, headerRule{Name: "x-request-id", Generate: true, GeneratorType: "ulid"}, rules[2])
+	assert.Equal(t, "tenant", pod.Annotations[AnnotationPolicies])
+}
+
+func TestPodCustomDefaulter_SkipsInvalidPolicy(t *testing.T) {
+	broken := newPolicy("broken", nil, ctxforgev1beta1.PropagationRule{
+		PathRegex: "^/api/(",
+		Headers:   []ctxforgev1beta1.HeaderConfig{{Name: "x-tenant-id"}},
+	})
+	tracing := newPolicy("tracing", nil, ctxforgev1beta1.PropagationRule{
+		Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-request-id"}},
+	})
+	defaulter := &PodCustomDefaulter{
+		ProxyImage: DefaultProxyImage,
+		Client:     newFakeClient(t, broken, tracing),
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "api-pod",
+			Namespace:   "default",
+			Annotations: map[string]string{AnnotationEnabled: "true"},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+	}
+
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+	assert.Equal(t, "tracing", pod.Annotations[AnnotationPolicies])
+	assert.Equal(t, `[{"name":"x-request-id"}]`, sidecarEnv(t, pod, "HEADER_RULES"))
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/bgruszka/contextforge/internal/config"
+)
+
+// AnnotationPolicyManaged marks the ctxforge.io/headers and
+// ctxforge.io/header-rules annotations of a pod as written by the operator from
+// the policies selecting it. They record the pod's effective configuration and
+// are not read back as pod configuration.
+const AnnotationPolicyManaged = "ctxforge.io/policy-managed"
+
+// policyManaged reports whether the pod's header annotations were written by
+// the operator from policies.
+func policyManaged(pod *corev1.Pod) bool {
+	return pod.Annotations[AnnotationPolicyManaged] == AnnotationValueTrue
+}
+
+// policyAnnotations returns the annotations recording the effective policy
+// configuration of a pod, or nil when no policy configures it.
+func (d *PodCustomDefaulter) policyAnnotations(ctx context.Context, pod *corev1.Pod, ns *corev1.Namespace) (map[string]string, error) {
+	headerRules, policies, err := d.headerRulesFromPolicies(ctx, pod, ns)
+	if err != nil || headerRules == "" {
+		return nil, err
+	}
+
+	rules, err := config.ParseHeaderRules(headerRules)
+	if err != nil {
+		return nil, err
+	}
+	var headers []string
+	seen := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		key := http.CanonicalHeaderKey(rule.Name)
+		if rule.Response != "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		headers = append(headers, rule.Name)
+	}
+
+	return map[string]string{
+		AnnotationHeaders:       strings.Join(headers, ","),
+		AnnotationHeaderRules:   headerRules,
+		AnnotationPolicies:      strings.Join(policies.Names, ","),
+		AnnotationPolicyManaged: AnnotationValueTrue,
+	}, nil
+}
+
+// SyncPolicyAnnotations writes the header configuration that the policies
+// selecting an injected pod resolve to onto the pod, as the
+// ctxforge.io/headers and ctxforge.io/header-rules annotations marked with
+// ctxforge.io/policy-managed, and removes them along with ctxforge.io/policies
+// once no policy selects the pod, so they don't outlive the policies as stale
+// configuration. Pods configured by their own annotations or header profiles are left alone.
+// It reports whether the pod was modified.
+func (d *PodCustomDefaulter) SyncPolicyAnnotations(ctx context.Context, pod *corev1.Pod) (bool, error) {
+	if proxyContainer(pod) == nil {
+		return false, nil
+	}
+	ns := d.lookupNamespace(ctx, pod)
+	if !d.ownsPod(pod, ns) {
+		return false, nil
+	}
+	managed := policyManaged(pod)
+	if !managed && (pod.Annotations[AnnotationHeaders] != "" || pod.Annotations[AnnotationHeaderRules] != "") {
+		return false, nil
+	}
+
+	var desired map[string]string
+	if pod.Annotations[AnnotationProfile] == "" {
+		var err error
+		if desired, err = d.policyAnnotations(ctx, pod, ns); err != nil {
+			return false, err
+		}
+	}
+
+	changed := false
+	if desired == nil {
+		if !managed {
+			return false, nil
+		}
+		for _, key := range []string{AnnotationHeaders, AnnotationHeaderRules, AnnotationPolicies, AnnotationPolicyManaged} {
+			delete(pod.Annotations, key)
+		}
+		changed = true
+	} else {
+		if pod.Annotations == nil {
+			pod.Annotations = make(map[string]string)
+		}
+		for key, value := range desired {
+			if pod.Annotations[key] != value {
+				pod.Annotations[key] = value
+				changed = true
+			}
+		}
+	}
+	if changed {
+		podLogger(pod).Info("Updated policy annotations", "policies", pod.Annotations[AnnotationPolicies])
+	}
+	return changed, nil
+}
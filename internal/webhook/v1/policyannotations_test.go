@@ -0,0 +1,122 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+)
+
+func TestPodCustomDefaulter_SyncPolicyAnnotations(t *testing.T) {
+	ctx := context.Background()
+	policy := newPolicy("tracing", map[string]string{"app": "api"}, ctxforgev1beta1.PropagationRule{
+		Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-request-id", Generate: true}},
+	})
+	c := newFakeClient(t, policy)
+	syncer := NewLiveConfigSyncer(c)
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "api",
+			Namespace:   "default",
+			Labels:      map[string]string{"app": "api"},
+			Annotations: map[string]string{AnnotationEnabled: "true"},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+	}
+	require.NoError(t, (&PodCustomDefaulter{ProxyImage: DefaultProxyImage, Client: c}).Default(ctx, pod))
+	require.NotNil(t, proxyContainer(pod))
+
+	changed, err := syncer.SyncPolicyAnnotations(ctx, pod)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, "x-request-id", pod.Annotations[AnnotationHeaders])
+	assert.Equal(t, sidecarEnv(t, pod, "HEADER_RULES"), pod.Annotations[AnnotationHeaderRules])
+	assert.Equal(t, "tracing", pod.Annotations[AnnotationPolicies])
+	assert.Equal(t, AnnotationValueTrue, pod.Annotations[AnnotationPolicyManaged])
+	assert.Empty(t, validatePodAnnotations(pod.Annotations))
+
+	changed, err = syncer.SyncPolicyAnnotations(ctx, pod)
+	require.NoError(t, err)
+	assert.False(t, changed, "an up-to-date pod is left alone")
+
+	// The written annotations follow the policy instead of overriding it
+	policy.Spec.PropagationRules[0].Headers = append(policy.Spec.PropagationRules[0].Headers,
+		ctxforgev1beta1.HeaderConfig{Name: "x-tenant-id"})
+	policy.Spec.ResponseRules = []ctxforgev1beta1.ResponseRule{{Strip: []string{"server"}}}
+	require.NoError(t, c.Update(ctx, policy))
+	changed, err = syncer.SyncPolicyAnnotations(ctx, pod)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, "x-request-id,x-tenant-id", pod.Annotations[AnnotationHeaders], "response rules aren't propagated headers")
+	assert.Contains(t, pod.Annotations[AnnotationHeaderRules], `"response":"strip"`)
+
+	require.NoError(t, c.Delete(ctx, policy))
+	changed, err = syncer.SyncPolicyAnnotations(ctx, pod)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.NotContains(t, pod.Annotations, AnnotationHeaders)
+	assert.NotContains(t, pod.Annotations, AnnotationHeaderRules)
+	assert.NotContains(t, pod.Annotations, AnnotationPolicies)
+	assert.NotContains(t, pod.Annotations, AnnotationPolicyManaged)
+}
+
+func TestPodCustomDefaulter_SyncPolicyAnnotationsKeepsPodConfig(t *testing.T) {
+	policy := newPolicy("tracing", nil, ctxforgev1beta1.PropagationRule{
+		Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-tenant-id"}},
+	})
+	syncer := NewLiveConfigSyncer(newFakeClient(t, policy))
+
+	pod := injectedPod(t, "x-request-id")
+	changed, err := syncer.SyncPolicyAnnotations(context.Background(), pod)
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, "x-request-id", pod.Annotations[AnnotationHeaders])
+	assert.NotContains(t, pod.Annotations, AnnotationPolicyManaged)
+}
+
+func TestPodCustomDefaulter_PolicyManagedAnnotationsIgnored(t *testing.T) {
+	policy := newPolicy("tracing", nil, ctxforgev1beta1.PropagationRule{
+		Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-request-id"}},
+	})
+	defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage, Client: newFakeClient(t, policy)}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "api",
+			Namespace: "default",
+			Annotations: map[string]string{
+				AnnotationEnabled:       "true",
+				AnnotationHeaders:       "x-stale",
+				AnnotationPolicyManaged: AnnotationValueTrue,
+			},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+	}
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+
+	assert.Equal(t, "tracing", pod.Annotations[AnnotationPolicies])
+	assert.Contains(t, sidecarEnv(t, pod, "HEADER_RULES"), "x-request-id")
+	assert.Empty(t, sidecarEnv(t, pod, "HEADERS_TO_PROPAGATE"))
+}
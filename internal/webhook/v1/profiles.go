@@ -0,0 +1,138 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// AnnotationProfile selects operator-defined header profiles for a pod, as a
+// comma-separated list of profile names.
+const AnnotationProfile = "ctxforge.io/profile"
+
+// sidecarDefaultsProfilePrefix marks header profiles in the defaults
+// ConfigMap: key profile.<name> defines profile <name>.
+const sidecarDefaultsProfilePrefix = "profile."
+
+// headerProfile is a named bundle of headers and header rules.
+type headerProfile struct {
+	Headers []string     `json:"headers,omitempty"`
+	Rules   []headerRule `json:"rules,omitempty"`
+}
+
+// parseHeaderProfile parses a profile definition in YAML or JSON.
+func parseHeaderProfile(name, data string) (headerProfile, error) {
+	var profile headerProfile
+	if err := yaml.UnmarshalStrict([]byte(data), &profile); err != nil {
+		return profile, fmt.Errorf("invalid header profile %q: %w", name, err)
+	}
+	if len(profile.Headers) == 0 && len(profile.Rules) == 0 {
+		return profile, fmt.Errorf("invalid header profile %q: no headers or rules", name)
+	}
+	for _, header := range profile.Headers {
+		if err := validateHeaderName(header); err != nil {
+			return profile, fmt.Errorf("invalid header profile %q: %w", name, err)
+		}
+	}
+	if len(profile.Rules) > 0 {
+		rules, err := json.Marshal(profile.Rules)
+		if err != nil {
+			return profile, fmt.Errorf("invalid header profile %q: %w", name, err)
+		}
+		if err := validateHeaderRulesJSON(string(rules)); err != nil {
+			return profile, fmt.Errorf("invalid header profile %q: %w", name, err)
+		}
+	}
+	return profile, nil
+}
+
+// applyProfiles adds the headers and rules of the pod's ctxforge.io/profile
+// profiles to its own configuration. Settings the pod makes for a header win,
+// then those of profiles listed earlier. Unknown profiles are an error.
+func (d *PodCustomDefaulter) applyProfiles(pod *corev1.Pod, headers []string, headerRules string) ([]string, string, error) {
+	names := splitAnnotationList(pod.Annotations[AnnotationProfile])
+	if len(names) == 0 {
+		return headers, headerRules, nil
+	}
+
+	var profiles map[string]headerProfile
+	if d.Defaults != nil {
+		profiles = d.Defaults.Get().Profiles
+	}
+
+	for _, name := range names {
+		profile, ok := profiles[name]
+		if !ok {
+			return nil, "", fmt.Errorf("unknown header profile %q in %s annotation", name, AnnotationProfile)
+		}
+		var err error
+		if len(profile.Rules) > 0 {
+			if headerRules, err = mergeProfileRules(profile.Rules, headers, headerRules); err != nil {
+				return nil, "", err
+			}
+		}
+		if headers, headerRules, err = mergeDefaultHeaders(profile.Headers, headers, headerRules); err != nil {
+			return nil, "", err
+		}
+	}
+	return headers, headerRules, nil
+}
+
+// mergeProfileRules appends the profile's rules for headers the pod's
+// configuration doesn't cover. A pod using a plain header list is converted to
+// rules first, since the proxy ignores HEADERS_TO_PROPAGATE when HEADER_RULES is set.
+func mergeProfileRules(profileRules []headerRule, headers []string, headerRules string) (string, error) {
+	var rules []headerRule
+	if headerRules != "" {
+		if err := json.Unmarshal([]byte(headerRules), &rules); err != nil {
+			return "", fmt.Errorf("failed to merge header profile: %w", err)
+		}
+	} else {
+		for _, header := range headers {
+			rules = append(rules, headerRule{Name: header})
+		}
+	}
+
+	seen := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		seen[http.CanonicalHeaderKey(rule.Name)] = true
+	}
+	for _, rule := range profileRules {
+		if !seen[http.CanonicalHeaderKey(rule.Name)] {
+			seen[http.CanonicalHeaderKey(rule.Name)] = true
+			rules = append(rules, rule)
+		}
+	}
+
+	data, err := json.Marshal(rules)
+	if err != nil {
+		return "", fmt.Errorf("failed to merge header profile: %w", err)
+	}
+	return string(data), nil
+}
+
+// profileName returns the profile name of a defaults ConfigMap key.
+func profileName(key string) (string, bool) {
+	name, ok := strings.CutPrefix(key, sidecarDefaultsProfilePrefix)
+	return name, ok && name != ""
+}
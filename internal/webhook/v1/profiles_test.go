@@ -0,0 +1,107 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func profileDefaulter(t *testing.T) *PodCustomDefaulter {
+	t.Helper()
+	dir := t.TempDir()
+	writeDefaults(t, dir, map[string]string{
+		"profile.tracing":        "headers: [traceparent, tracestate]\n",
+		"profile.tenant-context": `{"headers":["x-tenant-id"],"rules":[{"name":"x-request-id","generate":true}]}`,
+	})
+	return &PodCustomDefaulter{ProxyImage: DefaultProxyImage, Defaults: &SidecarDefaultsSource{Dir: dir}}
+}
+
+func TestParseHeaderProfile(t *testing.T) {
+	profile, err := parseHeaderProfile("tracing", "headers: [traceparent]\nrules:\n- name: x-request-id\n  generate: true\n")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"traceparent"}, profile.Headers)
+	assert.Equal(t, []headerRule{{Name: "x-request-id", Generate: true}}, profile.Rules)
+
+	for name, data := range map[string]string{
+		"empty":          "{}",
+		"unknown field":  "headers: [traceparent]\nheader: [x-tenant-id]\n",
+		"invalid header": "headers: [\"bad header\"]\n",
+		"invalid rule":   "rules:\n- name: x-request-id\n  generate: true\n  generatorType: random\n",
+		"bad onExisting": "rules:\n- name: x-request-id\n  onExisting: bogus\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := parseHeaderProfile(name, data)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestPodCustomDefaulter_Profiles(t *testing.T) {
+	t.Run("profile supplies the headers", func(t *testing.T) {
+		pod := orderingPod("")
+		delete(pod.Annotations, AnnotationHeaders)
+		pod.Annotations[AnnotationProfile] = "tracing"
+
+		require.NoError(t, profileDefaulter(t).Default(context.Background(), pod))
+
+		assert.Equal(t, "traceparent,tracestate", sidecarEnv(t, pod, "HEADERS_TO_PROPAGATE"))
+	})
+
+	t.Run("pod headers are kept first", func(t *testing.T) {
+		pod := orderingPod("")
+		pod.Annotations[AnnotationHeaders] = "x-request-id,traceparent"
+		pod.Annotations[AnnotationProfile] = "tracing"
+
+		require.NoError(t, profileDefaulter(t).Default(context.Background(), pod))
+
+		assert.Equal(t, "x-request-id,traceparent,tracestate", sidecarEnv(t, pod, "HEADERS_TO_PROPAGATE"))
+	})
+
+	t.Run("profile rules convert pod headers to rules", func(t *testing.T) {
+		pod := orderingPod("")
+		pod.Annotations[AnnotationProfile] = "tenant-context, tracing"
+
+		require.NoError(t, profileDefaulter(t).Default(context.Background(), pod))
+
+		assert.JSONEq(t, `[
+			{"name":"x-request-id"},
+			{"name":"x-tenant-id"},
+			{"name":"traceparent"},
+			{"name":"tracestate"}
+		]`, sidecarEnv(t, pod, "HEADER_RULES"))
+	})
+
+	t.Run("unknown profile is rejected", func(t *testing.T) {
+		pod := orderingPod("")
+		pod.Annotations[AnnotationProfile] = "full"
+
+		err := profileDefaulter(t).Default(context.Background(), pod)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `unknown header profile "full"`)
+		assert.Nil(t, proxyContainer(pod))
+	})
+}
+
+func TestValidatePodAnnotations_Profile(t *testing.T) {
+	assert.Empty(t, validatePodAnnotations(map[string]string{AnnotationProfile: "tracing,tenant-context"}))
+	assert.Len(t, validatePodAnnotations(map[string]string{AnnotationProfile: " , "}), 1)
+	assert.Len(t, validatePodAnnotations(map[string]string{AnnotationProfile: "tracing,full/ctx"}), 1)
+}
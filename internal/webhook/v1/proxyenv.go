@@ -0,0 +1,124 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// AnnotationNoProxy lists extra NO_PROXY entries for a pod, merged with the operator defaults
+const AnnotationNoProxy = "ctxforge.io/no-proxy"
+
+// AnnotationMutateAppEnv set to "false" injects only the proxy and leaves the
+// app containers' HTTP_PROXY and NO_PROXY untouched
+const AnnotationMutateAppEnv = "ctxforge.io/mutate-app-env"
+
+// defaultNoProxy lists destinations that always bypass the proxy.
+var defaultNoProxy = []string{"localhost", "127.0.0.1"}
+
+// operatorNoProxy returns the NO_PROXY entries configured on the operator via the
+// comma-separated NO_PROXY_DEFAULTS env var. The API server address the operator
+// itself uses is added so in-cluster clients in app containers never go through
+// the proxy.
+func operatorNoProxy() []string {
+	entries := splitAnnotationList(os.Getenv("NO_PROXY_DEFAULTS"))
+	if host := os.Getenv("KUBERNETES_SERVICE_HOST"); host != "" {
+		entries = append(entries, host)
+	}
+	return entries
+}
+
+// noProxyFor returns the NO_PROXY entries for a pod: the built-in defaults, the
+// operator-level entries and the pod's ctxforge.io/no-proxy annotation.
+func (d *PodCustomDefaulter) noProxyFor(pod *corev1.Pod) []string {
+	entries := append([]string{}, defaultNoProxy...)
+	entries = append(entries, d.NoProxy...)
+	entries = append(entries, splitAnnotationList(pod.Annotations[AnnotationNoProxy])...)
+	return splitAnnotationList(mergeNoProxy("", entries))
+}
+
+// mergeProxyEnv sets HTTP_PROXY and NO_PROXY on the container without creating
+// duplicate keys. An HTTP_PROXY the user already set (in either case) is kept,
+// and existing NO_PROXY entries are merged with the pod's NO_PROXY list.
+func (d *PodCustomDefaulter) mergeProxyEnv(pod *corev1.Pod, container *corev1.Container) {
+	httpProxy := fmt.Sprintf("http://localhost:%d", ProxyPort)
+	noProxy := d.noProxyFor(pod)
+
+	if i := findEnv(container.Env, "HTTP_PROXY", "http_proxy"); i >= 0 {
+		existing := container.Env[i]
+		if existing.ValueFrom != nil || existing.Value != httpProxy {
+			podLogger(pod).Info("Keeping user-defined HTTP_PROXY, outbound requests will bypass header propagation",
+				"container", container.Name, "env", existing.Name)
+		}
+	} else {
+		container.Env = append(container.Env, corev1.EnvVar{Name: "HTTP_PROXY", Value: httpProxy})
+	}
+
+	merged := false
+	for i := range container.Env {
+		env := &container.Env[i]
+		if env.Name != "NO_PROXY" && env.Name != "no_proxy" {
+			continue
+		}
+		merged = true
+		if env.ValueFrom != nil {
+			podLogger(pod).Info("Cannot merge NO_PROXY set from a reference, leaving it unchanged",
+				"container", container.Name, "env", env.Name)
+			continue
+		}
+		env.Value = mergeNoProxy(env.Value, noProxy)
+	}
+	if !merged {
+		container.Env = append(container.Env, corev1.EnvVar{
+			Name:  "NO_PROXY",
+			Value: strings.Join(noProxy, ","),
+		})
+	}
+}
+
+// findEnv returns the index of the first env var with one of the given names, or -1.
+func findEnv(env []corev1.EnvVar, names ...string) int {
+	for i, e := range env {
+		for _, name := range names {
+			if e.Name == name {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// mergeNoProxy appends the entries missing from a comma-separated NO_PROXY value,
+// keeping the existing order.
+func mergeNoProxy(existing string, entries []string) string {
+	list := splitAnnotationList(existing)
+	seen := make(map[string]bool, len(list))
+	for _, entry := range list {
+		seen[entry] = true
+	}
+	for _, entry := range entries {
+		if !seen[entry] {
+			seen[entry] = true
+			list = append(list, entry)
+		}
+	}
+	return strings.Join(list, ",")
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMergeProxyEnv(t *testing.T) {
+	configMapRef := &corev1.EnvVarSource{
+		ConfigMapKeyRef: &corev1.ConfigMapKeySelector{Key: "no_proxy"},
+	}
+
+	tests := []struct {
+		name     string
+		env      []corev1.EnvVar
+		expected []corev1.EnvVar
+	}{
+		{
+			name: "no existing env",
+			expected: []corev1.EnvVar{
+				{Name: "HTTP_PROXY", Value: "http://localhost:9090"},
+				{Name: "NO_PROXY", Value: "localhost,127.0.0.1"},
+			},
+		},
+		{
+			name: "user-set HTTP_PROXY is kept",
+			env:  []corev1.EnvVar{{Name: "HTTP_PROXY", Value: "http://corp-proxy:3128"}},
+			expected: []corev1.EnvVar{
+				{Name: "HTTP_PROXY", Value: "http://corp-proxy:3128"},
+				{Name: "NO_PROXY", Value: "localhost,127.0.0.1"},
+			},
+		},
+		{
+			name: "lowercase http_proxy is kept",
+			env:  []corev1.EnvVar{{Name: "http_proxy", Value: "http://corp-proxy:3128"}},
+			expected: []corev1.EnvVar{
+				{Name: "http_proxy", Value: "http://corp-proxy:3128"},
+				{Name: "NO_PROXY", Value: "localhost,127.0.0.1"},
+			},
+		},
+		{
+			name: "NO_PROXY lists are merged",
+			env:  []corev1.EnvVar{{Name: "NO_PROXY", Value: "internal.example.com, localhost"}},
+			expected: []corev1.EnvVar{
+				{Name: "NO_PROXY", Value: "internal.example.com,localhost,127.0.0.1"},
+				{Name: "HTTP_PROXY", Value: "http://localhost:9090"},
+			},
+		},
+		{
+			name: "both NO_PROXY spellings are merged",
+			env: []corev1.EnvVar{
+				{Name: "NO_PROXY", Value: ".svc"},
+				{Name: "no_proxy", Value: ".cluster.local"},
+			},
+			expected: []corev1.EnvVar{
+				{Name: "NO_PROXY", Value: ".svc,localhost,127.0.0.1"},
+				{Name: "no_proxy", Value: ".cluster.local,localhost,127.0.0.1"},
+				{Name: "HTTP_PROXY", Value: "http://localhost:9090"},
+			},
+		},
+		{
+			name: "NO_PROXY from a reference is left alone",
+			env:  []corev1.EnvVar{{Name: "NO_PROXY", ValueFrom: configMapRef}},
+			expected: []corev1.EnvVar{
+				{Name: "NO_PROXY", ValueFrom: configMapRef},
+				{Name: "HTTP_PROXY", Value: "http://localhost:9090"},
+			},
+		},
+		{
+			name: "already injected values are not duplicated",
+			env: []corev1.EnvVar{
+				{Name: "HTTP_PROXY", Value: "http://localhost:9090"},
+				{Name: "NO_PROXY", Value: "localhost,127.0.0.1"},
+			},
+			expected: []corev1.EnvVar{
+				{Name: "HTTP_PROXY", Value: "http://localhost:9090"},
+				{Name: "NO_PROXY", Value: "localhost,127.0.0.1"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			container := &corev1.Container{Name: "app", Env: tt.env}
+			(&PodCustomDefaulter{}).mergeProxyEnv(&corev1.Pod{}, container)
+			assert.Equal(t, tt.expected, container.Env)
+		})
+	}
+}
+
+func TestPodCustomDefaulter_NoProxyFor(t *testing.T) {
+	defaulter := &PodCustomDefaulter{NoProxy: []string{".svc", ".cluster.local", "10.96.0.0/12", "localhost"}}
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Annotations: map[string]string{AnnotationNoProxy: "payments.internal, .svc"},
+	}}
+
+	assert.Equal(t,
+		[]string{"localhost", "127.0.0.1", ".svc", ".cluster.local", "10.96.0.0/12", "payments.internal"},
+		defaulter.noProxyFor(pod))
+
+	container := &corev1.Container{Name: "app", Env: []corev1.EnvVar{{Name: "NO_PROXY", Value: "legacy.example.com"}}}
+	defaulter.mergeProxyEnv(pod, container)
+	assert.Equal(t,
+		"legacy.example.com,localhost,127.0.0.1,.svc,.cluster.local,10.96.0.0/12,payments.internal",
+		container.Env[0].Value)
+}
+
+func TestOperatorNoProxy(t *testing.T) {
+	t.Setenv("NO_PROXY_DEFAULTS", ".svc, .cluster.local,,169.254.169.254")
+	t.Setenv("KUBERNETES_SERVICE_HOST", "10.96.0.1")
+
+	assert.Equal(t, []string{".svc", ".cluster.local", "169.254.169.254", "10.96.0.1"}, operatorNoProxy())
+}
+
+func TestPodCustomDefaulter_MutateAppEnvDisabled(t *testing.T) {
+	defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage}
+	pod := orderingPod("")
+	pod.Annotations[AnnotationMutateAppEnv] = AnnotationValueFalse
+
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+
+	require.NotNil(t, proxyContainer(pod))
+	for _, container := range pod.Spec.Containers {
+		if container.Name != ProxyContainerName {
+			assert.Empty(t, container.Env, container.Name)
+		}
+	}
+	assert.Empty(t, validatePodAnnotations(pod.Annotations))
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// proxyImagePullSecretsFromEnv reads the comma-separated PROXY_IMAGE_PULL_SECRETS
+// env var naming the Secrets injected pods use to pull the proxy image.
+func proxyImagePullSecretsFromEnv() ([]string, error) {
+	names := splitAnnotationList(os.Getenv("PROXY_IMAGE_PULL_SECRETS"))
+	for _, name := range names {
+		if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid PROXY_IMAGE_PULL_SECRETS entry %q: %s", name, strings.Join(errs, "; "))
+		}
+	}
+	return names, nil
+}
+
+// addImagePullSecrets appends the operator's proxy image pull secrets the pod
+// doesn't already reference. The Secrets must exist in the pod's namespace.
+func (d *PodCustomDefaulter) addImagePullSecrets(pod *corev1.Pod) {
+	for _, name := range d.ImagePullSecrets {
+		found := false
+		for _, ref := range pod.Spec.ImagePullSecrets {
+			if ref.Name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			pod.Spec.ImagePullSecrets = append(pod.Spec.ImagePullSecrets, corev1.LocalObjectReference{Name: name})
+		}
+	}
+}
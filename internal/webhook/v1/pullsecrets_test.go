@@ -0,0 +1,61 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestPodCustomDefaulter_ImagePullSecrets(t *testing.T) {
+	defaulter := &PodCustomDefaulter{
+		ProxyImage:       "registry.internal/ctxforge/proxy:0.1.1",
+		ImagePullSecrets: []string{"mirror-creds", "team-creds"},
+	}
+	pod := orderingPod("")
+	pod.Spec.ImagePullSecrets = []corev1.LocalObjectReference{{Name: "team-creds"}}
+
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+
+	assert.Equal(t, []corev1.LocalObjectReference{{Name: "team-creds"}, {Name: "mirror-creds"}},
+		pod.Spec.ImagePullSecrets)
+}
+
+func TestPodCustomDefaulter_ImagePullSecretsSkippedPods(t *testing.T) {
+	defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage, ImagePullSecrets: []string{"mirror-creds"}}
+	pod := orderingPod("")
+	pod.Annotations[AnnotationEnabled] = "false"
+
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+
+	assert.Empty(t, pod.Spec.ImagePullSecrets)
+}
+
+func TestProxyImagePullSecretsFromEnv(t *testing.T) {
+	t.Setenv("PROXY_IMAGE_PULL_SECRETS", "mirror-creds, team-creds")
+	names, err := proxyImagePullSecretsFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"mirror-creds", "team-creds"}, names)
+
+	t.Setenv("PROXY_IMAGE_PULL_SECRETS", "Mirror_Creds")
+	_, err = proxyImagePullSecretsFromEnv()
+	assert.Error(t, err)
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/bgruszka/contextforge/internal/config"
+)
+
+const (
+	// AnnotationConfigChecksum holds the checksum of the header configuration
+	// injected into the sidecar, which the proxy reports on its /config endpoint.
+	AnnotationConfigChecksum = "ctxforge.io/config-checksum"
+
+	// ConditionProxyConfigSynced is the readiness gate condition set by the
+	// operator once the sidecar reports the injected configuration checksum.
+	ConditionProxyConfigSynced corev1.PodConditionType = "ctxforge.io/proxy-config-synced"
+)
+
+// sidecarConfigChecksum computes the config checksum from the sidecar's env
+func sidecarConfigChecksum(sidecar *corev1.Container) string {
+	var headers, rules string
+	if i := findEnv(sidecar.Env, "HEADERS_TO_PROPAGATE"); i >= 0 {
+		headers = sidecar.Env[i].Value
+	}
+	if i := findEnv(sidecar.Env, "HEADER_RULES"); i >= 0 {
+		rules = sidecar.Env[i].Value
+	}
+	return config.ConfigChecksum(headers, rules)
+}
+
+// addReadinessGate records the injected configuration's checksum and makes
+// the pod's readiness wait for the operator to confirm the sidecar loaded it.
+func (d *PodCustomDefaulter) addReadinessGate(pod *corev1.Pod) {
+	sidecar := proxyContainer(pod)
+	if !d.ReadinessGate || sidecar == nil {
+		return
+	}
+
+	if pod.Annotations == nil {
+		pod.Annotations = make(map[string]string)
+	}
+	pod.Annotations[AnnotationConfigChecksum] = sidecarConfigChecksum(sidecar)
+	addOperatorToken(sidecar)
+
+	for _, gate := range pod.Spec.ReadinessGates {
+		if gate.ConditionType == ConditionProxyConfigSynced {
+			return
+		}
+	}
+	pod.Spec.ReadinessGates = append(pod.Spec.ReadinessGates, corev1.PodReadinessGate{
+		ConditionType: ConditionProxyConfigSynced,
+	})
+}
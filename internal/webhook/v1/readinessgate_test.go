@@ -0,0 +1,63 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/bgruszka/contextforge/internal/config"
+)
+
+func TestPodCustomDefaulter_ReadinessGate(t *testing.T) {
+	t.Run("disabled by default", func(t *testing.T) {
+		defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage}
+		pod := orderingPod("")
+		require.NoError(t, defaulter.Default(context.Background(), pod))
+
+		assert.Empty(t, pod.Spec.ReadinessGates)
+		assert.NotContains(t, pod.Annotations, AnnotationConfigChecksum)
+		assert.Empty(t, OperatorToken(pod))
+	})
+
+	t.Run("gate and checksum of the sidecar config", func(t *testing.T) {
+		defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage, ReadinessGate: true}
+		pod := orderingPod("")
+		pod.Spec.ReadinessGates = []corev1.PodReadinessGate{{ConditionType: ConditionProxyConfigSynced}}
+		require.NoError(t, defaulter.Default(context.Background(), pod))
+
+		assert.Equal(t, []corev1.PodReadinessGate{{ConditionType: ConditionProxyConfigSynced}}, pod.Spec.ReadinessGates)
+		assert.Equal(t, config.ConfigChecksum(sidecarEnv(t, pod, "HEADERS_TO_PROPAGATE"), ""),
+			pod.Annotations[AnnotationConfigChecksum])
+	})
+
+	t.Run("per-pod token for reading /config", func(t *testing.T) {
+		defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage, ReadinessGate: true}
+		pod := orderingPod("")
+		require.NoError(t, defaulter.Default(context.Background(), pod))
+		other := orderingPod("")
+		require.NoError(t, defaulter.Default(context.Background(), other))
+
+		assert.Len(t, OperatorToken(pod), 32)
+		assert.Equal(t, OperatorToken(pod), sidecarEnv(t, pod, OperatorTokenEnv))
+		assert.NotEqual(t, OperatorToken(pod), OperatorToken(other))
+	})
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// AnnotationRedirectMode selects how outbound traffic reaches the proxy.
+	// Only RedirectModeIptables is supported; without it, apps rely on HTTP_PROXY.
+	AnnotationRedirectMode = "ctxforge.io/redirect-mode"
+	// AnnotationRedirectExcludePorts lists outbound TCP ports that bypass the redirect
+	AnnotationRedirectExcludePorts = "ctxforge.io/redirect-exclude-ports"
+	// AnnotationRedirectExcludeCIDRs lists destination CIDRs that bypass the redirect
+	AnnotationRedirectExcludeCIDRs = "ctxforge.io/redirect-exclude-cidrs"
+
+	// RedirectModeIptables redirects outbound TCP traffic to the proxy with iptables
+	RedirectModeIptables = "iptables"
+
+	// RedirectInitContainerName is the name of the injected iptables init container
+	RedirectInitContainerName = "ctxforge-init"
+
+	// redirectChain is the nat chain holding the ctxforge rules.
+	redirectChain = "CTXFORGE_OUTPUT"
+)
+
+// defaultRedirectExcludePorts are never redirected: the proxy only speaks plain
+// HTTP, so TLS traffic must go out directly.
+var defaultRedirectExcludePorts = []string{"443"}
+
+// wantsRedirect checks if the pod requested transparent redirection
+func wantsRedirect(pod *corev1.Pod) bool {
+	return pod.Annotations[AnnotationRedirectMode] == RedirectModeIptables
+}
+
+// injectRedirectInit adds an init container that redirects the pod's outbound
+// TCP traffic to the proxy port. It is appended after existing init containers
+// so they can still reach the network directly while the proxy is not running.
+// Traffic of proxyUID, the proxy's user, is never redirected.
+func (d *PodCustomDefaulter) injectRedirectInit(pod *corev1.Pod, proxyUID int64) {
+	excludePorts := append([]string{}, defaultRedirectExcludePorts...)
+	for _, port := range splitAnnotationList(pod.Annotations[AnnotationRedirectExcludePorts]) {
+		if err := validatePortNumber(port); err != nil {
+			podLogger(pod).Error(err, "Ignoring invalid redirect exclude port", "port", port)
+			continue
+		}
+		excludePorts = append(excludePorts, port)
+	}
+
+	var excludeCIDRs []string
+	for _, cidr := range splitAnnotationList(pod.Annotations[AnnotationRedirectExcludeCIDRs]) {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			podLogger(pod).Error(err, "Ignoring invalid redirect exclude CIDR", "cidr", cidr)
+			continue
+		}
+		excludeCIDRs = append(excludeCIDRs, cidr)
+	}
+
+	container := corev1.Container{
+		Name:            RedirectInitContainerName,
+		Image:           d.RedirectInitImage,
+		ImagePullPolicy: corev1.PullIfNotPresent,
+		Command:         []string{"sh", "-c", redirectScript(ProxyPort, proxyUID, excludePorts, excludeCIDRs)},
+		SecurityContext: &corev1.SecurityContext{
+			RunAsUser:                int64Ptr(0),
+			RunAsNonRoot:             boolPtr(false),
+			AllowPrivilegeEscalation: boolPtr(false),
+			Capabilities: &corev1.Capabilities{
+				Add:  []corev1.Capability{"NET_ADMIN", "NET_RAW"},
+				Drop: []corev1.Capability{"ALL"},
+			},
+			ReadOnlyRootFilesystem: boolPtr(true),
+		},
+	}
+
+	pod.Spec.InitContainers = append(pod.Spec.InitContainers, container)
+}
+
+// redirectScript builds the shell script that programs the nat OUTPUT rules.
+// Loopback traffic and the proxy's own connections are always left alone.
+func redirectScript(proxyPort int, proxyUID int64, excludePorts, excludeCIDRs []string) string {
+	rules := []string{
+		"set -e",
+		fmt.Sprintf("iptables -t nat -N %s", redirectChain),
+		fmt.Sprintf("iptables -t nat -A OUTPUT -p tcp -j %s", redirectChain),
+		fmt.Sprintf("iptables -t nat -A %s -m owner --uid-owner %d -j RETURN", redirectChain, proxyUID),
+		fmt.Sprintf("iptables -t nat -A %s -d 127.0.0.0/8 -j RETURN", redirectChain),
+	}
+	for _, cidr := range excludeCIDRs {
+		rules = append(rules, fmt.Sprintf("iptables -t nat -A %s -d %s -j RETURN", redirectChain, cidr))
+	}
+	for _, port := range excludePorts {
+		rules = append(rules, fmt.Sprintf("iptables -t nat -A %s -p tcp --dport %s -j RETURN", redirectChain, port))
+	}
+	rules = append(rules, fmt.Sprintf("iptables -t nat -A %s -p tcp -j REDIRECT --to-ports %d", redirectChain, proxyPort))
+	return strings.Join(rules, "\n")
+}
+
+// validatePortNumber checks that port is a number between 1 and 65535
+func validatePortNumber(port string) error {
+	portNum, err := strconv.Atoi(port)
+	if err != nil {
+		return fmt.Errorf("invalid port %q: must be a number", port)
+	}
+	if portNum < 1 || portNum > 65535 {
+		return fmt.Errorf("invalid port %d: must be between 1 and 65535", portNum)
+	}
+	return nil
+}
+
+// splitAnnotationList splits a comma-separated annotation value, dropping empty entries
+func splitAnnotationList(value string) []string {
+	var items []string
+	for _, part := range strings.Split(value, ",") {
+		if item := strings.TrimSpace(part); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
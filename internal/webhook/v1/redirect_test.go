@@ -0,0 +1,119 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func redirectPod(annotations map[string]string) *corev1.Pod {
+	base := map[string]string{
+		AnnotationEnabled:      "true",
+		AnnotationHeaders:      "x-request-id",
+		AnnotationRedirectMode: RedirectModeIptables,
+	}
+	for k, v := range annotations {
+		base[k] = v
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-pod", Annotations: base},
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{{Name: "setup"}},
+			Containers:     []corev1.Container{{Name: "app"}},
+		},
+	}
+}
+
+func TestPodCustomDefaulter_RedirectInit(t *testing.T) {
+	defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage, RedirectInitImage: "ctxforge-init:test"}
+
+	pod := redirectPod(map[string]string{
+		AnnotationRedirectExcludePorts: "5432, 6379,not-a-port",
+		AnnotationRedirectExcludeCIDRs: "10.96.0.0/12,bogus",
+	})
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+
+	require.Len(t, pod.Spec.InitContainers, 2)
+	assert.Equal(t, "setup", pod.Spec.InitContainers[0].Name, "existing init containers run first")
+
+	init := pod.Spec.InitContainers[1]
+	assert.Equal(t, RedirectInitContainerName, init.Name)
+	assert.Equal(t, "ctxforge-init:test", init.Image)
+	require.NotNil(t, init.SecurityContext)
+	assert.ElementsMatch(t, []corev1.Capability{"NET_ADMIN", "NET_RAW"}, init.SecurityContext.Capabilities.Add)
+
+	require.Len(t, init.Command, 3)
+	script := init.Command[2]
+	assert.Contains(t, script, "--uid-owner 65532 -j RETURN")
+	assert.Contains(t, script, "-d 127.0.0.0/8 -j RETURN")
+	assert.Contains(t, script, "-d 10.96.0.0/12 -j RETURN")
+	assert.Contains(t, script, "--dport 443 -j RETURN")
+	assert.Contains(t, script, "--dport 5432 -j RETURN")
+	assert.Contains(t, script, "--dport 6379 -j RETURN")
+	assert.Contains(t, script, "REDIRECT --to-ports 9090")
+	assert.NotContains(t, script, "not-a-port")
+	assert.NotContains(t, script, "bogus")
+}
+
+func TestPodCustomDefaulter_RedirectInit_WithNativeSidecar(t *testing.T) {
+	defaulter := &PodCustomDefaulter{
+		ProxyImage:        DefaultProxyImage,
+		NativeSidecar:     true,
+		RedirectInitImage: "ctxforge-init:test",
+	}
+
+	pod := redirectPod(nil)
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+
+	require.Len(t, pod.Spec.InitContainers, 3)
+	assert.Equal(t, RedirectInitContainerName, pod.Spec.InitContainers[1].Name)
+	assert.Equal(t, ProxyContainerName, pod.Spec.InitContainers[2].Name,
+		"rules must be in place before the proxy starts")
+}
+
+func TestPodCustomDefaulter_RedirectInit_Disabled(t *testing.T) {
+	tests := []struct {
+		name      string
+		defaulter *PodCustomDefaulter
+		pod       *corev1.Pod
+	}{
+		{
+			name:      "operator has no init image",
+			defaulter: &PodCustomDefaulter{ProxyImage: DefaultProxyImage},
+			pod:       redirectPod(nil),
+		},
+		{
+			name:      "pod did not request redirect",
+			defaulter: &PodCustomDefaulter{ProxyImage: DefaultProxyImage, RedirectInitImage: "ctxforge-init:test"},
+			pod:       redirectPod(map[string]string{AnnotationRedirectMode: ""}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, tt.defaulter.Default(context.Background(), tt.pod))
+			assert.Len(t, tt.pod.Spec.InitContainers, 1)
+			assert.Len(t, tt.pod.Spec.Containers, 2, "the sidecar is still injected")
+		})
+	}
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// LabelRevision selects the operator revision that injects a namespace's or
+	// pod's sidecars, and is stamped on injected pods with the revision that did.
+	LabelRevision = "ctxforge.io/revision"
+
+	// DefaultRevision is the revision of an operator without INJECTION_REVISION
+	DefaultRevision = "default"
+)
+
+// revision returns the operator's injection revision
+func (d *PodCustomDefaulter) revision() string {
+	if d.Revision == "" {
+		return DefaultRevision
+	}
+	return d.Revision
+}
+
+// requestedRevision returns the revision a pod should be injected by: the
+// namespace's ctxforge.io/revision label, then the pod's, then the default.
+func requestedRevision(pod *corev1.Pod, ns *corev1.Namespace) string {
+	if ns != nil && ns.Labels[LabelRevision] != "" {
+		return ns.Labels[LabelRevision]
+	}
+	if pod.Labels[LabelRevision] != "" {
+		return pod.Labels[LabelRevision]
+	}
+	return DefaultRevision
+}
+
+// ownsPod checks if this operator's revision is responsible for the pod, so
+// that the webhooks of other revisions leave it alone.
+func (d *PodCustomDefaulter) ownsPod(pod *corev1.Pod, ns *corev1.Namespace) bool {
+	return requestedRevision(pod, ns) == d.revision()
+}
+
+// stampRevision labels an injected pod with the revision that injected it
+func (d *PodCustomDefaulter) stampRevision(pod *corev1.Pod) {
+	if pod.Labels == nil {
+		pod.Labels = make(map[string]string)
+	}
+	pod.Labels[LabelRevision] = d.revision()
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPodCustomDefaulter_Revision(t *testing.T) {
+	tests := []struct {
+		name             string
+		revision         string
+		namespaceLabel   string
+		podLabel         string
+		expectInjected   bool
+		expectedRevision string
+	}{
+		{
+			name:             "default operator injects unlabeled pods",
+			expectInjected:   true,
+			expectedRevision: DefaultRevision,
+		},
+		{
+			name:     "default operator skips canary pods",
+			podLabel: "canary",
+		},
+		{
+			name:             "canary operator injects canary pods",
+			revision:         "canary",
+			podLabel:         "canary",
+			expectInjected:   true,
+			expectedRevision: "canary",
+		},
+		{
+			name:     "canary operator skips unlabeled pods",
+			revision: "canary",
+		},
+		{
+			name:             "namespace label wins over the pod label",
+			revision:         "canary",
+			namespaceLabel:   "canary",
+			podLabel:         DefaultRevision,
+			expectInjected:   true,
+			expectedRevision: "canary",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}}
+			if tt.namespaceLabel != "" {
+				ns.Labels = map[string]string{LabelRevision: tt.namespaceLabel}
+			}
+			defaulter := &PodCustomDefaulter{
+				ProxyImage: DefaultProxyImage,
+				Client:     newFakeClient(t, ns),
+				Revision:   tt.revision,
+			}
+			pod := orderingPod("")
+			pod.Namespace = "default"
+			if tt.podLabel != "" {
+				pod.Labels = map[string]string{LabelRevision: tt.podLabel}
+			}
+
+			require.NoError(t, defaulter.Default(context.Background(), pod))
+
+			assert.Equal(t, tt.expectInjected, proxyContainer(pod) != nil)
+			if tt.expectInjected {
+				assert.Equal(t, tt.expectedRevision, pod.Labels[LabelRevision])
+			}
+		})
+	}
+}
+
+func TestPodCustomDefaulter_RevisionDrift(t *testing.T) {
+	defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage}
+	pod := orderingPod("")
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+	pod.Annotations[AnnotationHeaders] = "x-tenant-id"
+
+	// Another revision leaves the drift of pods it didn't inject alone
+	canary := &PodCustomDefaulter{ProxyImage: DefaultProxyImage, Revision: "canary"}
+	require.NoError(t, canary.Default(updateContext(), pod))
+	assert.NotContains(t, pod.Annotations, AnnotationConfigDrift)
+
+	require.NoError(t, defaulter.Default(updateContext(), pod))
+	assert.Contains(t, pod.Annotations, AnnotationConfigDrift)
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// DefaultProxyUID is the user the proxy image runs as
+	DefaultProxyUID = 65532
+
+	// RunAsUserOmit in PROXY_RUN_AS_USER leaves the proxy's UID to the platform,
+	// e.g. OpenShift's restricted SCC, which assigns one from the namespace range.
+	RunAsUserOmit = "omit"
+
+	// SeccompProfileNone in PROXY_SECCOMP_PROFILE leaves the seccomp profile unset
+	SeccompProfileNone = "none"
+	// seccompLocalhostPrefix selects a node-local profile, e.g. localhost/profiles/proxy.json
+	seccompLocalhostPrefix = "localhost/"
+)
+
+// ProxySecurityContext configures the security settings of the injected proxy.
+// The zero value complies with the restricted Pod Security Standard: UID 65532,
+// RuntimeDefault seccomp profile and no pod-level changes.
+type ProxySecurityContext struct {
+	// RunAsUser overrides the proxy's UID; nil uses DefaultProxyUID.
+	RunAsUser *int64
+	// OmitRunAsUser leaves the UID unset so the platform picks one.
+	OmitRunAsUser bool
+	// SeccompProfile is "" (RuntimeDefault), SeccompProfileNone, or
+	// "localhost/<path>" for a profile installed on the nodes.
+	SeccompProfile string
+	// FSGroup is set on pods that don't define an fsGroup themselves.
+	FSGroup *int64
+}
+
+// uid returns the UID the proxy runs as, or false if the platform assigns it.
+func (s ProxySecurityContext) uid() (int64, bool) {
+	if s.OmitRunAsUser {
+		return 0, false
+	}
+	if s.RunAsUser != nil {
+		return *s.RunAsUser, true
+	}
+	return DefaultProxyUID, true
+}
+
+// container builds the proxy container's security context.
+func (s ProxySecurityContext) container() *corev1.SecurityContext {
+	sc := &corev1.SecurityContext{
+		RunAsNonRoot:             boolPtr(true),
+		AllowPrivilegeEscalation: boolPtr(false),
+		Capabilities: &corev1.Capabilities{
+			Drop: []corev1.Capability{"ALL"},
+		},
+		ReadOnlyRootFilesystem: boolPtr(true),
+	}
+	if uid, ok := s.uid(); ok {
+		sc.RunAsUser = int64Ptr(uid)
+	}
+
+	switch {
+	case s.SeccompProfile == SeccompProfileNone:
+	case strings.HasPrefix(s.SeccompProfile, seccompLocalhostPrefix):
+		profile := strings.TrimPrefix(s.SeccompProfile, seccompLocalhostPrefix)
+		sc.SeccompProfile = &corev1.SeccompProfile{
+			Type:             corev1.SeccompProfileTypeLocalhost,
+			LocalhostProfile: &profile,
+		}
+	default:
+		sc.SeccompProfile = &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault}
+	}
+	return sc
+}
+
+// applyPod sets pod-level settings the proxy needs without overriding the pod's own.
+func (s ProxySecurityContext) applyPod(pod *corev1.Pod) {
+	if s.FSGroup == nil {
+		return
+	}
+	if pod.Spec.SecurityContext == nil {
+		pod.Spec.SecurityContext = &corev1.PodSecurityContext{}
+	}
+	if pod.Spec.SecurityContext.FSGroup == nil {
+		pod.Spec.SecurityContext.FSGroup = int64Ptr(*s.FSGroup)
+	}
+}
+
+// proxySecurityFromEnv reads the proxy security settings from PROXY_RUN_AS_USER,
+// PROXY_SECCOMP_PROFILE and PROXY_FS_GROUP.
+func proxySecurityFromEnv() (ProxySecurityContext, error) {
+	var s ProxySecurityContext
+
+	switch value := strings.TrimSpace(os.Getenv("PROXY_RUN_AS_USER")); value {
+	case "":
+	case RunAsUserOmit:
+		s.OmitRunAsUser = true
+	default:
+		uid, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || uid <= 0 {
+			return s, fmt.Errorf("invalid PROXY_RUN_AS_USER value %q: must be a non-root UID or %q", value, RunAsUserOmit)
+		}
+		s.RunAsUser = &uid
+	}
+
+	switch value := strings.TrimSpace(os.Getenv("PROXY_SECCOMP_PROFILE")); {
+	case value == "", value == string(corev1.SeccompProfileTypeRuntimeDefault):
+	case value == SeccompProfileNone:
+		s.SeccompProfile = SeccompProfileNone
+	case strings.HasPrefix(value, seccompLocalhostPrefix) && len(value) > len(seccompLocalhostPrefix):
+		s.SeccompProfile = value
+	default:
+		return s, fmt.Errorf("invalid PROXY_SECCOMP_PROFILE value %q: must be %s, %s or localhost/<profile>",
+			value, corev1.SeccompProfileTypeRuntimeDefault, SeccompProfileNone)
+	}
+
+	if value := strings.TrimSpace(os.Getenv("PROXY_FS_GROUP")); value != "" {
+		gid, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || gid < 0 {
+			return s, fmt.Errorf("invalid PROXY_FS_GROUP value %q: must be a GID", value)
+		}
+		s.FSGroup = &gid
+	}
+
+	return s, nil
+}
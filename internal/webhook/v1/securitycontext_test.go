@@ -0,0 +1,149 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestProxySecurityContext_RestrictedByDefault(t *testing.T) {
+	sc := ProxySecurityContext{}.container()
+
+	require.NotNil(t, sc.RunAsUser)
+	assert.Equal(t, int64(DefaultProxyUID), *sc.RunAsUser)
+	assert.True(t, *sc.RunAsNonRoot)
+	assert.False(t, *sc.AllowPrivilegeEscalation)
+	assert.Equal(t, []corev1.Capability{"ALL"}, sc.Capabilities.Drop)
+	require.NotNil(t, sc.SeccompProfile)
+	assert.Equal(t, corev1.SeccompProfileTypeRuntimeDefault, sc.SeccompProfile.Type)
+}
+
+func TestProxySecurityFromEnv(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       map[string]string
+		expectErr string
+		check     func(t *testing.T, s ProxySecurityContext)
+	}{
+		{
+			name: "defaults",
+			check: func(t *testing.T, s ProxySecurityContext) {
+				assert.Equal(t, ProxySecurityContext{}, s)
+			},
+		},
+		{
+			name: "openshift",
+			env:  map[string]string{"PROXY_RUN_AS_USER": "omit", "PROXY_FS_GROUP": "1000680000"},
+			check: func(t *testing.T, s ProxySecurityContext) {
+				sc := s.container()
+				assert.Nil(t, sc.RunAsUser)
+				assert.True(t, *sc.RunAsNonRoot)
+				require.NotNil(t, s.FSGroup)
+				assert.Equal(t, int64(1000680000), *s.FSGroup)
+			},
+		},
+		{
+			name: "custom uid and localhost profile",
+			env:  map[string]string{"PROXY_RUN_AS_USER": "1001", "PROXY_SECCOMP_PROFILE": "localhost/profiles/proxy.json"},
+			check: func(t *testing.T, s ProxySecurityContext) {
+				sc := s.container()
+				assert.Equal(t, int64(1001), *sc.RunAsUser)
+				assert.Equal(t, corev1.SeccompProfileTypeLocalhost, sc.SeccompProfile.Type)
+				assert.Equal(t, "profiles/proxy.json", *sc.SeccompProfile.LocalhostProfile)
+			},
+		},
+		{
+			name: "no seccomp profile",
+			env:  map[string]string{"PROXY_SECCOMP_PROFILE": "none"},
+			check: func(t *testing.T, s ProxySecurityContext) {
+				assert.Nil(t, s.container().SeccompProfile)
+			},
+		},
+		{
+			name:      "root uid",
+			env:       map[string]string{"PROXY_RUN_AS_USER": "0"},
+			expectErr: "PROXY_RUN_AS_USER",
+		},
+		{
+			name:      "unconfined seccomp",
+			env:       map[string]string{"PROXY_SECCOMP_PROFILE": "Unconfined"},
+			expectErr: "PROXY_SECCOMP_PROFILE",
+		},
+		{
+			name:      "invalid fsGroup",
+			env:       map[string]string{"PROXY_FS_GROUP": "wheel"},
+			expectErr: "PROXY_FS_GROUP",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"PROXY_RUN_AS_USER", "PROXY_SECCOMP_PROFILE", "PROXY_FS_GROUP"} {
+				t.Setenv(key, tt.env[key])
+			}
+
+			s, err := proxySecurityFromEnv()
+			if tt.expectErr != "" {
+				assert.ErrorContains(t, err, tt.expectErr)
+				return
+			}
+			require.NoError(t, err)
+			tt.check(t, s)
+		})
+	}
+}
+
+func TestPodCustomDefaulter_FSGroup(t *testing.T) {
+	defaulter := &PodCustomDefaulter{
+		ProxyImage: DefaultProxyImage,
+		Security:   ProxySecurityContext{FSGroup: int64Ptr(2000)},
+	}
+
+	pod := orderingPod("")
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+	require.NotNil(t, pod.Spec.SecurityContext)
+	assert.Equal(t, int64(2000), *pod.Spec.SecurityContext.FSGroup)
+
+	// The pod's own fsGroup wins
+	pod = orderingPod("")
+	pod.Spec.SecurityContext = &corev1.PodSecurityContext{FSGroup: int64Ptr(3000)}
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+	assert.Equal(t, int64(3000), *pod.Spec.SecurityContext.FSGroup)
+}
+
+func TestPodCustomDefaulter_RedirectFollowsProxyUID(t *testing.T) {
+	pod := redirectPod(nil)
+	defaulter := &PodCustomDefaulter{
+		ProxyImage:        DefaultProxyImage,
+		RedirectInitImage: "ctxforge-init:test",
+		Security:          ProxySecurityContext{RunAsUser: int64Ptr(1001)},
+	}
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+	require.Len(t, pod.Spec.InitContainers, 2)
+	assert.Contains(t, pod.Spec.InitContainers[1].Command[2], "--uid-owner 1001 -j RETURN")
+
+	// Without a known UID the proxy's own traffic can't be exempted
+	pod = redirectPod(nil)
+	defaulter.Security = ProxySecurityContext{OmitRunAsUser: true}
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+	assert.Len(t, pod.Spec.InitContainers, 1)
+}
@@ -0,0 +1,216 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// +kubebuilder:rbac:groups=admissionregistration.k8s.io,resources=mutatingwebhookconfigurations;validatingwebhookconfigurations,verbs=get;list;watch;update;patch
+
+const (
+	// namespaceNameLabel is set on every namespace by the API server
+	namespaceNameLabel = "kubernetes.io/metadata.name"
+
+	// AlwaysExcludedNamespace is never sent to the webhooks
+	AlwaysExcludedNamespace = "kube-system"
+
+	defaultSelectorSyncInterval = 5 * time.Minute
+)
+
+// WebhookSelectorManager keeps the namespaceSelector and objectSelector of the
+// operator's webhook configurations in line with its flags, so that excluded
+// namespaces and unlabeled pods never reach the webhook. It re-applies the
+// selectors every Interval, undoing manual edits and chart upgrades.
+//
+// It implements manager.Runnable and runs on the leader only.
+type WebhookSelectorManager struct {
+	Client client.Client
+	// MutatingWebhookConfiguration and ValidatingWebhookConfiguration name the
+	// configurations to manage; empty names are skipped.
+	MutatingWebhookConfiguration   string
+	ValidatingWebhookConfiguration string
+	// ExcludedNamespaces are excluded by name, in addition to kube-system.
+	ExcludedNamespaces []string
+	// WatchNamespaces, when set, restricts the webhooks to these namespaces,
+	// those an operator deployed with WATCH_NAMESPACES serves.
+	WatchNamespaces []string
+	// LabeledPodsOnly restricts the pod webhooks to pods labeled
+	// ctxforge.io/enabled=true.
+	LabeledPodsOnly bool
+	// Interval is how often the selectors are re-applied. Defaults to five minutes.
+	Interval time.Duration
+}
+
+// Start applies the selectors immediately and then every Interval until ctx is done.
+func (m *WebhookSelectorManager) Start(ctx context.Context) error {
+	interval := m.Interval
+	if interval <= 0 {
+		interval = defaultSelectorSyncInterval
+	}
+
+	m.sync(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			m.sync(ctx)
+		}
+	}
+}
+
+// NeedLeaderElection reports that only the leader writes the configurations.
+func (m *WebhookSelectorManager) NeedLeaderElection() bool {
+	return true
+}
+
+// sync updates both webhook configurations, logging failures for the next attempt.
+func (m *WebhookSelectorManager) sync(ctx context.Context) {
+	log := logf.FromContext(ctx).WithName("webhook-selectors")
+
+	if m.MutatingWebhookConfiguration != "" {
+		config := &admissionregistrationv1.MutatingWebhookConfiguration{}
+		if err := m.update(ctx, m.MutatingWebhookConfiguration, config, func() bool {
+			changed := false
+			for i := range config.Webhooks {
+				w := &config.Webhooks[i]
+				changed = m.applySelectors(&w.NamespaceSelector, &w.ObjectSelector, w.Rules) || changed
+			}
+			return changed
+		}); err != nil {
+			log.Error(err, "Failed to update webhook selectors", "configuration", m.MutatingWebhookConfiguration)
+		}
+	}
+
+	if m.ValidatingWebhookConfiguration != "" {
+		config := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+		if err := m.update(ctx, m.ValidatingWebhookConfiguration, config, func() bool {
+			changed := false
+			for i := range config.Webhooks {
+				w := &config.Webhooks[i]
+				changed = m.applySelectors(&w.NamespaceSelector, &w.ObjectSelector, w.Rules) || changed
+			}
+			return changed
+		}); err != nil {
+			log.Error(err, "Failed to update webhook selectors", "configuration", m.ValidatingWebhookConfiguration)
+		}
+	}
+}
+
+// update reads a webhook configuration into obj and writes it back if mutate changed it.
+func (m *WebhookSelectorManager) update(ctx context.Context, name string, obj client.Object, mutate func() bool) error {
+	if err := m.Client.Get(ctx, types.NamespacedName{Name: name}, obj); err != nil {
+		return err
+	}
+	if !mutate() {
+		return nil
+	}
+	logf.FromContext(ctx).Info("Updating webhook selectors", "configuration", name)
+	return m.Client.Update(ctx, obj)
+}
+
+// excludedNamespaces returns the sorted, de-duplicated namespaces to exclude.
+func (m *WebhookSelectorManager) excludedNamespaces() []string {
+	return sortedNamespaces(append([]string{AlwaysExcludedNamespace}, m.ExcludedNamespaces...))
+}
+
+// sortedNamespaces returns the non-empty namespaces, trimmed, sorted and
+// de-duplicated.
+func sortedNamespaces(names []string) []string {
+	var namespaces []string
+	for _, ns := range names {
+		ns = strings.TrimSpace(ns)
+		if ns != "" && !slices.Contains(namespaces, ns) {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
+
+// applySelectors sets the managed expressions on a webhook entry's selectors
+// and reports whether anything changed. Expressions not managed here, such as
+// the ctxforge.io/injection and ctxforge.io/exclude ones, are kept.
+func (m *WebhookSelectorManager) applySelectors(nsSelector, objSelector **metav1.LabelSelector, rules []admissionregistrationv1.RuleWithOperations) bool {
+	before := []*metav1.LabelSelector{(*nsSelector).DeepCopy(), (*objSelector).DeepCopy()}
+
+	setExpression(nsSelector, metav1.LabelSelectorRequirement{
+		Key:      namespaceNameLabel,
+		Operator: metav1.LabelSelectorOpNotIn,
+		Values:   m.excludedNamespaces(),
+	}, true)
+
+	watched := sortedNamespaces(m.WatchNamespaces)
+	setExpression(nsSelector, metav1.LabelSelectorRequirement{
+		Key:      namespaceNameLabel,
+		Operator: metav1.LabelSelectorOpIn,
+		Values:   watched,
+	}, len(watched) > 0)
+
+	// Workload objects don't carry their pods' labels, so only pod entries are restricted
+	setExpression(objSelector, metav1.LabelSelectorRequirement{
+		Key:      AnnotationEnabled,
+		Operator: metav1.LabelSelectorOpIn,
+		Values:   []string{AnnotationValueTrue},
+	}, m.LabeledPodsOnly && matchesPods(rules))
+
+	return !equality.Semantic.DeepEqual(before, []*metav1.LabelSelector{*nsSelector, *objSelector})
+}
+
+// setExpression replaces the selector's expression with the same key and
+// operator by req, or removes it when present is false.
+func setExpression(selector **metav1.LabelSelector, req metav1.LabelSelectorRequirement, present bool) {
+	if *selector == nil {
+		if !present {
+			return
+		}
+		*selector = &metav1.LabelSelector{}
+	}
+	s := *selector
+
+	s.MatchExpressions = slices.DeleteFunc(s.MatchExpressions, func(existing metav1.LabelSelectorRequirement) bool {
+		return existing.Key == req.Key && existing.Operator == req.Operator
+	})
+	if present {
+		s.MatchExpressions = append(s.MatchExpressions, req)
+	}
+}
+
+// matchesPods checks if a webhook entry's rules cover pods
+func matchesPods(rules []admissionregistrationv1.RuleWithOperations) bool {
+	for _, rule := range rules {
+		if slices.Contains(rule.APIGroups, "") && slices.Contains(rule.Resources, "pods") {
+			return true
+		}
+	}
+	return false
+}
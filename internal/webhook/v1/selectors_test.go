@@ -0,0 +1,144 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func webhookRule(group, resource string) []admissionregistrationv1.RuleWithOperations {
+	return []admissionregistrationv1.RuleWithOperations{{
+		Rule: admissionregistrationv1.Rule{APIGroups: []string{group}, Resources: []string{resource}},
+	}}
+}
+
+func TestWebhookSelectorManager_Sync(t *testing.T) {
+	injectionExpr := metav1.LabelSelectorRequirement{
+		Key: "ctxforge.io/injection", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"disabled"},
+	}
+	mutating := &admissionregistrationv1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "ctxforge-mutating"},
+		Webhooks: []admissionregistrationv1.MutatingWebhook{
+			{
+				Name:              "mpod.ctxforge.io",
+				Rules:             webhookRule("", "pods"),
+				NamespaceSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{injectionExpr}},
+			},
+			{
+				Name:  "mdeployment.ctxforge.io",
+				Rules: webhookRule("apps", "deployments"),
+			},
+		},
+	}
+	validating := &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "ctxforge-validating"},
+		Webhooks: []admissionregistrationv1.ValidatingWebhook{
+			{Name: "vpod.ctxforge.io", Rules: webhookRule("", "pods")},
+		},
+	}
+	c := newFakeClient(t, mutating, validating)
+
+	m := &WebhookSelectorManager{
+		Client:                         c,
+		MutatingWebhookConfiguration:   "ctxforge-mutating",
+		ValidatingWebhookConfiguration: "ctxforge-validating",
+		ExcludedNamespaces:             []string{"monitoring", " kube-system", ""},
+		LabeledPodsOnly:                true,
+	}
+	ctx := context.Background()
+	m.sync(ctx)
+
+	excluded := metav1.LabelSelectorRequirement{
+		Key: namespaceNameLabel, Operator: metav1.LabelSelectorOpNotIn, Values: []string{"kube-system", "monitoring"},
+	}
+	labeled := metav1.LabelSelectorRequirement{
+		Key: AnnotationEnabled, Operator: metav1.LabelSelectorOpIn, Values: []string{"true"},
+	}
+
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Name: "ctxforge-mutating"}, mutating))
+	pod := mutating.Webhooks[0]
+	assert.Equal(t, []metav1.LabelSelectorRequirement{injectionExpr, excluded}, pod.NamespaceSelector.MatchExpressions)
+	assert.Equal(t, []metav1.LabelSelectorRequirement{labeled}, pod.ObjectSelector.MatchExpressions)
+	deployment := mutating.Webhooks[1]
+	assert.Equal(t, []metav1.LabelSelectorRequirement{excluded}, deployment.NamespaceSelector.MatchExpressions)
+	assert.Nil(t, deployment.ObjectSelector)
+
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Name: "ctxforge-validating"}, validating))
+	assert.Equal(t, []metav1.LabelSelectorRequirement{labeled}, validating.Webhooks[0].ObjectSelector.MatchExpressions)
+
+	// Re-applying is a no-op, and turning the label requirement off removes it
+	version := mutating.ResourceVersion
+	m.sync(ctx)
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Name: "ctxforge-mutating"}, mutating))
+	assert.Equal(t, version, mutating.ResourceVersion)
+
+	m.LabeledPodsOnly = false
+	m.sync(ctx)
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Name: "ctxforge-mutating"}, mutating))
+	assert.Empty(t, mutating.Webhooks[0].ObjectSelector.MatchExpressions)
+	assert.Equal(t, []metav1.LabelSelectorRequirement{injectionExpr, excluded}, mutating.Webhooks[0].NamespaceSelector.MatchExpressions)
+}
+
+func TestPodCustomDefaulter_EnabledLabel(t *testing.T) {
+	defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage}
+	pod := orderingPod("")
+	delete(pod.Annotations, AnnotationEnabled)
+	pod.Labels = map[string]string{AnnotationEnabled: "true"}
+
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+	assert.NotNil(t, proxyContainer(pod))
+}
+
+func TestWebhookSelectorManager_WatchNamespaces(t *testing.T) {
+	mutating := &admissionregistrationv1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "ctxforge-mutating"},
+		Webhooks: []admissionregistrationv1.MutatingWebhook{
+			{Name: "mpod.ctxforge.io", Rules: webhookRule("", "pods")},
+		},
+	}
+	c := newFakeClient(t, mutating)
+
+	m := &WebhookSelectorManager{
+		Client:                       c,
+		MutatingWebhookConfiguration: "ctxforge-mutating",
+		WatchNamespaces:              []string{"team-b", " team-a", "team-b"},
+	}
+	ctx := context.Background()
+	m.sync(ctx)
+
+	excluded := metav1.LabelSelectorRequirement{
+		Key: namespaceNameLabel, Operator: metav1.LabelSelectorOpNotIn, Values: []string{"kube-system"},
+	}
+	watched := metav1.LabelSelectorRequirement{
+		Key: namespaceNameLabel, Operator: metav1.LabelSelectorOpIn, Values: []string{"team-a", "team-b"},
+	}
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Name: "ctxforge-mutating"}, mutating))
+	assert.Equal(t, []metav1.LabelSelectorRequirement{excluded, watched}, mutating.Webhooks[0].NamespaceSelector.MatchExpressions)
+
+	// Watching all namespaces again removes the restriction
+	m.WatchNamespaces = nil
+	m.sync(ctx)
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Name: "ctxforge-mutating"}, mutating))
+	assert.Equal(t, []metav1.LabelSelectorRequirement{excluded}, mutating.Webhooks[0].NamespaceSelector.MatchExpressions)
+}
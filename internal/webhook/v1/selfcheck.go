@@ -0,0 +1,286 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+)
+
+// +kubebuilder:rbac:groups=ctxforge.ctxforge.io,resources=operatorhealths,verbs=get;list;watch;create
+// +kubebuilder:rbac:groups=ctxforge.ctxforge.io,resources=operatorhealths/status,verbs=get;update;patch
+
+// ConditionWebhookHealthy is set on the OperatorHealth by WebhookSelfCheck.
+const ConditionWebhookHealthy = "WebhookHealthy"
+
+// Reasons used on the WebhookHealthy condition, in the order the checks run.
+// The condition carries the reason of the first failing check.
+const (
+	SelfCheckReasonPassed               = "SelfCheckPassed"
+	SelfCheckReasonConfigurationMissing = "WebhookConfigurationMissing"
+	SelfCheckReasonCABundleMismatch     = "CABundleMismatch"
+	SelfCheckReasonAdmissionFailed      = "AdmissionFailed"
+)
+
+// Values of the "check" label of selfCheckSuccess.
+const (
+	selfCheckConfiguration = "configuration"
+	selfCheckCABundle      = "ca_bundle"
+	selfCheckAdmission     = "admission"
+)
+
+const (
+	defaultSelfCheckInterval = 5 * time.Minute
+
+	// selfCheckHeader is the header of the dry-run policy, in mixed case for
+	// the policy defaulter to lowercase.
+	selfCheckHeader       = "X-Ctxforge-Self-Check"
+	selfCheckPolicyPrefix = "ctxforge-self-check-"
+)
+
+var (
+	// selfCheckSuccess is 1 for each self-check that passed on its last run.
+	selfCheckSuccess = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ctxforge_webhook_self_check_success",
+		Help: "Whether the last webhook self-check passed (1) or failed (0), by check.",
+	}, []string{"check"})
+
+	// selfCheckTimestamp exposes when the self-check last ran.
+	selfCheckTimestamp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "ctxforge_webhook_self_check_timestamp_seconds",
+		Help: "Unix timestamp of the last webhook self-check.",
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(selfCheckSuccess, selfCheckTimestamp)
+}
+
+// WebhookSelfCheck periodically verifies that pods can actually be injected:
+// the MutatingWebhookConfiguration exists, every caBundle of its entries
+// trusts the serving certificate, and a dry-run create of a
+// HeaderPropagationPolicy comes back mutated by the operator's webhook. The
+// result is exported as metrics and as the WebhookHealthy condition of the
+// OperatorHealth named contextforge, so that a webhook silently skipped by
+// the API server under failurePolicy Ignore is detected before pods are
+// found running without their sidecar.
+//
+// It implements manager.Runnable and runs on the leader only, the single
+// writer of the OperatorHealth.
+type WebhookSelfCheck struct {
+	Client client.Client
+	// MutatingWebhookConfiguration names the configuration to check.
+	MutatingWebhookConfiguration string
+	// CertFile is the path to the PEM-encoded serving certificate, followed by
+	// its intermediates if any.
+	CertFile string
+	// Namespace is where the dry-run HeaderPropagationPolicy is created.
+	Namespace string
+	// Interval is how often the checks run. Defaults to five minutes.
+	Interval time.Duration
+
+	now func() time.Time
+}
+
+// Start runs the checks immediately and then every Interval until ctx is done.
+func (s *WebhookSelfCheck) Start(ctx context.Context) error {
+	interval := s.Interval
+	if interval <= 0 {
+		interval = defaultSelfCheckInterval
+	}
+
+	s.check(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			s.check(ctx)
+		}
+	}
+}
+
+// NeedLeaderElection reports that only the leader writes the OperatorHealth.
+func (s *WebhookSelfCheck) NeedLeaderElection() bool {
+	return true
+}
+
+// check runs every check and publishes the result.
+func (s *WebhookSelfCheck) check(ctx context.Context) {
+	log := logf.FromContext(ctx).WithName("webhook-self-check")
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+
+	cond := metav1.Condition{
+		Type:    ConditionWebhookHealthy,
+		Status:  metav1.ConditionTrue,
+		Reason:  SelfCheckReasonPassed,
+		Message: "Webhook configuration, CA bundle and dry-run admission are healthy",
+	}
+	var failures []string
+	record := func(check, reason string, err error) {
+		if err == nil {
+			selfCheckSuccess.WithLabelValues(check).Set(1)
+			return
+		}
+		selfCheckSuccess.WithLabelValues(check).Set(0)
+		if cond.Status == metav1.ConditionTrue {
+			cond.Status, cond.Reason = metav1.ConditionFalse, reason
+		}
+		failures = append(failures, err.Error())
+	}
+
+	config := &admissionregistrationv1.MutatingWebhookConfiguration{}
+	err := s.Client.Get(ctx, types.NamespacedName{Name: s.MutatingWebhookConfiguration}, config)
+	if err != nil {
+		err = fmt.Errorf("MutatingWebhookConfiguration %s: %w", s.MutatingWebhookConfiguration, err)
+		record(selfCheckConfiguration, SelfCheckReasonConfigurationMissing, err)
+		record(selfCheckCABundle, SelfCheckReasonCABundleMismatch, errors.New("CA bundle not checked"))
+	} else {
+		record(selfCheckConfiguration, SelfCheckReasonConfigurationMissing, nil)
+		record(selfCheckCABundle, SelfCheckReasonCABundleMismatch, s.checkCABundles(config, now()))
+	}
+	record(selfCheckAdmission, SelfCheckReasonAdmissionFailed, s.checkAdmission(ctx))
+	if len(failures) > 0 {
+		cond.Message = strings.Join(failures, "; ")
+	}
+	selfCheckTimestamp.Set(float64(now().Unix()))
+
+	changed, err := s.updateHealth(ctx, cond, metav1.NewTime(now()))
+	if err != nil {
+		log.Error(err, "Failed to update OperatorHealth", "name", ctxforgev1beta1.OperatorHealthName)
+	}
+	if !changed {
+		return
+	}
+	if cond.Status == metav1.ConditionFalse {
+		log.Info("Webhook self-check failed", "reason", cond.Reason, "message", cond.Message)
+	} else {
+		log.Info("Webhook self-check passed")
+	}
+}
+
+// checkCABundles verifies the serving certificate against the caBundle of
+// every entry of the configuration that calls a Service, as the API server
+// does.
+func (s *WebhookSelfCheck) checkCABundles(config *admissionregistrationv1.MutatingWebhookConfiguration, now time.Time) error {
+	certs, err := readCerts(s.CertFile)
+	if err != nil {
+		return err
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	for _, webhook := range config.Webhooks {
+		if webhook.ClientConfig.Service == nil {
+			continue
+		}
+		roots, err := parseCerts(webhook.ClientConfig.CABundle)
+		if err != nil {
+			return fmt.Errorf("webhook %s: caBundle: %w", webhook.Name, err)
+		}
+		if len(roots) == 0 {
+			return fmt.Errorf("webhook %s has no caBundle", webhook.Name)
+		}
+		pool := x509.NewCertPool()
+		for _, root := range roots {
+			pool.AddCert(root)
+		}
+		if _, err := certs[0].Verify(x509.VerifyOptions{
+			Roots:         pool,
+			Intermediates: intermediates,
+			CurrentTime:   now,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		}); err != nil {
+			return fmt.Errorf("webhook %s: caBundle doesn't trust the serving certificate: %w", webhook.Name, err)
+		}
+	}
+	return nil
+}
+
+// checkAdmission creates a HeaderPropagationPolicy in dry-run mode and checks
+// the policy defaulter lowercased its header name, which only happens when the
+// API server reached the webhook.
+func (s *WebhookSelfCheck) checkAdmission(ctx context.Context) error {
+	policy := &ctxforgev1beta1.HeaderPropagationPolicy{
+		ObjectMeta: metav1.ObjectMeta{GenerateName: selfCheckPolicyPrefix, Namespace: s.Namespace},
+		Spec: ctxforgev1beta1.HeaderPropagationPolicySpec{
+			PropagationRules: []ctxforgev1beta1.PropagationRule{{
+				Headers: []ctxforgev1beta1.HeaderConfig{{Name: selfCheckHeader}},
+			}},
+		},
+	}
+	if err := s.Client.Create(ctx, policy, client.DryRunAll); err != nil {
+		return fmt.Errorf("dry-run admission: %w", err)
+	}
+	if name := policy.Spec.PropagationRules[0].Headers[0].Name; name != strings.ToLower(selfCheckHeader) {
+		return errors.New("dry-run admission: the webhook did not mutate the request")
+	}
+	return nil
+}
+
+// updateHealth sets the condition on the OperatorHealth, creating it if
+// needed, and reports whether the condition changed.
+func (s *WebhookSelfCheck) updateHealth(ctx context.Context, cond metav1.Condition, checked metav1.Time) (bool, error) {
+	return updateOperatorHealth(ctx, s.Client, func(status *ctxforgev1beta1.OperatorHealthStatus) bool {
+		status.LastSelfCheckTime = &checked
+		return meta.SetStatusCondition(&status.Conditions, cond)
+	})
+}
+
+// updateOperatorHealth applies update to the status of the OperatorHealth,
+// creating it if needed, and returns what update reported.
+func updateOperatorHealth(ctx context.Context, c client.Client,
+	update func(*ctxforgev1beta1.OperatorHealthStatus) bool) (bool, error) {
+	health := &ctxforgev1beta1.OperatorHealth{}
+	err := c.Get(ctx, types.NamespacedName{Name: ctxforgev1beta1.OperatorHealthName}, health)
+	if apierrors.IsNotFound(err) {
+		health = &ctxforgev1beta1.OperatorHealth{
+			ObjectMeta: metav1.ObjectMeta{Name: ctxforgev1beta1.OperatorHealthName},
+		}
+		err = c.Create(ctx, health)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	changed := update(&health.Status)
+	return changed, c.Status().Update(ctx, health)
+}
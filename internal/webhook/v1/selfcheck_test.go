@@ -0,0 +1,243 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+)
+
+// newTestCA returns a self-signed CA certificate and its key.
+func newTestCA(t *testing.T, name string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert, key
+}
+
+// writeServingCert writes a serving certificate signed by ca and returns its path.
+func writeServingCert(t *testing.T, ca *x509.Certificate, caKey *ecdsa.PrivateKey) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "ctxforge-webhook"},
+		DNSNames:     []string{"contextforge-webhook.contextforge-system.svc"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "tls.crt")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	return path
+}
+
+func mutatingConfig(caBundle []byte) *admissionregistrationv1.MutatingWebhookConfiguration {
+	return &admissionregistrationv1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "contextforge-mutating-webhook"},
+		Webhooks: []admissionregistrationv1.MutatingWebhook{{
+			Name: "mpod.ctxforge.io",
+			ClientConfig: admissionregistrationv1.WebhookClientConfig{
+				Service:  &admissionregistrationv1.ServiceReference{Namespace: "contextforge-system", Name: "contextforge-webhook"},
+				CABundle: caBundle,
+			},
+		}},
+	}
+}
+
+// newSelfCheckClient returns a fake client whose policy creates are mutated
+// like the policy defaulter does when mutate is set.
+func newSelfCheckClient(t *testing.T, mutate bool, objs ...client.Object) client.Client {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, ctxforgev1beta1.AddToScheme(scheme))
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).
+		WithStatusSubresource(&ctxforgev1beta1.OperatorHealth{}).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				if policy, ok := obj.(*ctxforgev1beta1.HeaderPropagationPolicy); ok && mutate {
+					for i := range policy.Spec.PropagationRules {
+						for j := range policy.Spec.PropagationRules[i].Headers {
+							header := &policy.Spec.PropagationRules[i].Headers[j]
+							header.Name = strings.ToLower(header.Name)
+						}
+					}
+				}
+				return c.Create(ctx, obj, opts...)
+			},
+		}).Build()
+}
+
+func TestWebhookSelfCheck(t *testing.T) {
+	ca, caKey := newTestCA(t, "contextforge-ca")
+	otherCA, _ := newTestCA(t, "other-ca")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw})
+	otherPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: otherCA.Raw})
+	certFile := writeServingCert(t, ca, caKey)
+
+	tests := []struct {
+		name    string
+		config  *admissionregistrationv1.MutatingWebhookConfiguration
+		mutate  bool
+		status  metav1.ConditionStatus
+		reason  string
+		success map[string]float64
+	}{
+		{
+			name:    "healthy",
+			config:  mutatingConfig(caPEM),
+			mutate:  true,
+			status:  metav1.ConditionTrue,
+			reason:  SelfCheckReasonPassed,
+			success: map[string]float64{selfCheckConfiguration: 1, selfCheckCABundle: 1, selfCheckAdmission: 1},
+		},
+		{
+			name:    "configuration missing",
+			mutate:  true,
+			status:  metav1.ConditionFalse,
+			reason:  SelfCheckReasonConfigurationMissing,
+			success: map[string]float64{selfCheckConfiguration: 0, selfCheckCABundle: 0, selfCheckAdmission: 1},
+		},
+		{
+			name:    "caBundle of another CA",
+			config:  mutatingConfig(otherPEM),
+			mutate:  true,
+			status:  metav1.ConditionFalse,
+			reason:  SelfCheckReasonCABundleMismatch,
+			success: map[string]float64{selfCheckConfiguration: 1, selfCheckCABundle: 0, selfCheckAdmission: 1},
+		},
+		{
+			name:    "empty caBundle",
+			config:  mutatingConfig(nil),
+			mutate:  true,
+			status:  metav1.ConditionFalse,
+			reason:  SelfCheckReasonCABundleMismatch,
+			success: map[string]float64{selfCheckConfiguration: 1, selfCheckCABundle: 0, selfCheckAdmission: 1},
+		},
+		{
+			name:    "webhook skipped",
+			config:  mutatingConfig(caPEM),
+			status:  metav1.ConditionFalse,
+			reason:  SelfCheckReasonAdmissionFailed,
+			success: map[string]float64{selfCheckConfiguration: 1, selfCheckCABundle: 1, selfCheckAdmission: 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var objs []client.Object
+			if tt.config != nil {
+				objs = append(objs, tt.config)
+			}
+			c := newSelfCheckClient(t, tt.mutate, objs...)
+			s := &WebhookSelfCheck{
+				Client:                       c,
+				MutatingWebhookConfiguration: "contextforge-mutating-webhook",
+				CertFile:                     certFile,
+				Namespace:                    "default",
+			}
+			s.check(context.Background())
+
+			health := &ctxforgev1beta1.OperatorHealth{}
+			require.NoError(t, c.Get(context.Background(), types.NamespacedName{Name: ctxforgev1beta1.OperatorHealthName}, health))
+			cond := meta.FindStatusCondition(health.Status.Conditions, ConditionWebhookHealthy)
+			require.NotNil(t, cond)
+			assert.Equal(t, tt.status, cond.Status)
+			assert.Equal(t, tt.reason, cond.Reason)
+			assert.NotNil(t, health.Status.LastSelfCheckTime)
+			for check, value := range tt.success {
+				assert.Equal(t, value, testutil.ToFloat64(selfCheckSuccess.WithLabelValues(check)), check)
+			}
+
+			// The dry-run policy is never stored
+			policies := &ctxforgev1beta1.HeaderPropagationPolicyList{}
+			require.NoError(t, c.List(context.Background(), policies))
+			assert.Empty(t, policies.Items)
+		})
+	}
+}
+
+func TestWebhookSelfCheck_Rerun(t *testing.T) {
+	ca, caKey := newTestCA(t, "contextforge-ca")
+	c := newSelfCheckClient(t, true, mutatingConfig(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw})))
+	now := time.Now().Truncate(time.Second)
+	s := &WebhookSelfCheck{
+		Client:                       c,
+		MutatingWebhookConfiguration: "contextforge-mutating-webhook",
+		CertFile:                     writeServingCert(t, ca, caKey),
+		Namespace:                    "default",
+		now:                          func() time.Time { return now },
+	}
+
+	s.check(context.Background())
+	health := &ctxforgev1beta1.OperatorHealth{}
+	require.NoError(t, c.Get(context.Background(), types.NamespacedName{Name: ctxforgev1beta1.OperatorHealthName}, health))
+	first := *meta.FindStatusCondition(health.Status.Conditions, ConditionWebhookHealthy)
+
+	now = now.Add(5 * time.Minute)
+	s.check(context.Background())
+	require.NoError(t, c.Get(context.Background(), types.NamespacedName{Name: ctxforgev1beta1.OperatorHealthName}, health))
+	cond := meta.FindStatusCondition(health.Status.Conditions, ConditionWebhookHealthy)
+	require.NotNil(t, cond)
+	assert.Equal(t, first.LastTransitionTime, cond.LastTransitionTime,
+		"an unchanged result must not move the transition time")
+	assert.True(t, health.Status.LastSelfCheckTime.Time.Equal(now), "the last check time moves on every run")
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// sidecarDefaultsImageKey overrides the proxy image in the defaults ConfigMap
+	sidecarDefaultsImageKey = "image"
+
+	// DefaultSidecarDefaultsRefresh is how often the mounted ConfigMap is re-read.
+	// The kubelet itself only syncs ConfigMap volumes about once a minute.
+	DefaultSidecarDefaultsRefresh = 10 * time.Second
+)
+
+// envNamePattern matches the keys treated as proxy environment variables
+var envNamePattern = regexp.MustCompile(`^[A-Z_][A-Z0-9_]*$`)
+
+// webhookManagedEnv are sidecar env vars the webhook derives per pod; the
+// defaults ConfigMap can't set them.
+var webhookManagedEnv = map[string]bool{
+	"TARGET_HOST":              true,
+	"PROXY_PORT":               true,
+	"POD_NAME":                 true,
+	"HEADERS_TO_PROPAGATE":     true,
+	"HEADER_RULES":             true,
+	"DRAIN_DELAY":              true,
+	"DRAIN_TIMEOUT":            true,
+	"EXIT_ON_APP_EXIT":         true,
+	"TARGET_TLS":               true,
+	"TARGET_CA_FILE":           true,
+	"ADMIN_AUTH_TOKEN_FILE":    true,
+	"CONTEXT_SIGNING_KEY_FILE": true,
+	"LIVE_CONFIG_FILE":         true,
+	"RULE_STREAM_ADDRESS":      true,
+	"RULES_FILE":               true,
+	"POD_NAMESPACE":            true,
+	"SECURE_MODE":              true,
+}
+
+// SidecarDefaults are fleet-wide settings for injected proxies.
+type SidecarDefaults struct {
+	// Image replaces the operator's proxy image when set.
+	Image string
+	// Env is merged into the proxy's environment, replacing the webhook's
+	// static defaults such as LOG_LEVEL.
+	Env map[string]string
+	// Template customizes the built-in sidecar; see DefaultSidecarTemplate.
+	Template *template.Template
+	// Profiles are the header profiles pods select with ctxforge.io/profile.
+	Profiles map[string]headerProfile
+}
+
+// SidecarDefaultsSource reads SidecarDefaults from a mounted ConfigMap
+// directory, one file per key, and re-reads it at most every Refresh so edits
+// to the ConfigMap reach new pods without restarting the operator.
+type SidecarDefaultsSource struct {
+	Dir     string
+	Refresh time.Duration
+
+	mu       sync.Mutex
+	loadedAt time.Time
+	current  SidecarDefaults
+}
+
+// Get returns the current defaults. If the directory can't be read, the last
+// good defaults are kept.
+func (s *SidecarDefaultsSource) Get() SidecarDefaults {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.loadedAt.IsZero() && time.Since(s.loadedAt) < s.Refresh {
+		return s.current
+	}
+	s.loadedAt = time.Now()
+
+	defaults, err := loadSidecarDefaults(s.Dir)
+	if err != nil {
+		podlog.Error(err, "Failed to read sidecar defaults, keeping previous values", "dir", s.Dir)
+		return s.current
+	}
+	s.current = defaults
+	return s.current
+}
+
+// loadSidecarDefaults reads a ConfigMap volume. Keys that are neither "image",
+// "template", a "profile.<name>" nor an upper-case env var name, and webhook-managed env vars, are skipped
+// with a log message. A missing directory yields empty defaults.
+func loadSidecarDefaults(dir string) (SidecarDefaults, error) {
+	defaults := SidecarDefaults{Env: map[string]string{}, Profiles: map[string]headerProfile{}}
+
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return defaults, nil
+	}
+	if err != nil {
+		return defaults, err
+	}
+
+	for _, entry := range entries {
+		key := entry.Name()
+		// ConfigMap volumes keep their data in ..data and timestamped dirs
+		if strings.HasPrefix(key, ".") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, key))
+		if err != nil {
+			return defaults, fmt.Errorf("failed to read sidecar default %q: %w", key, err)
+		}
+		value := strings.TrimSpace(string(data))
+
+		name, isProfile := profileName(key)
+		switch {
+		case isProfile:
+			if defaults.Profiles[name], err = parseHeaderProfile(name, string(data)); err != nil {
+				return defaults, err
+			}
+		case key == sidecarDefaultsImageKey:
+			defaults.Image = value
+		case key == sidecarDefaultsTemplateKey:
+			if defaults.Template, err = parseSidecarTemplate(string(data)); err != nil {
+				return defaults, err
+			}
+		case !envNamePattern.MatchString(key):
+			podlog.Info("Ignoring sidecar default that is not an env var name", "key", key)
+		case webhookManagedEnv[key]:
+			podlog.Info("Ignoring sidecar default for a webhook-managed env var", "key", key)
+		default:
+			defaults.Env[key] = value
+		}
+	}
+	return defaults, nil
+}
+
+// sidecarDefaultsFromEnv returns the defaults source for SIDECAR_DEFAULTS_DIR,
+// or nil when no defaults ConfigMap is mounted.
+func sidecarDefaultsFromEnv() *SidecarDefaultsSource {
+	if dir := os.Getenv("SIDECAR_DEFAULTS_DIR"); dir != "" {
+		return &SidecarDefaultsSource{Dir: dir, Refresh: DefaultSidecarDefaultsRefresh}
+	}
+	return nil
+}
+
+// applySidecarDefaults sets the operator-level image and env on the sidecar.
+func (d *PodCustomDefaulter) applySidecarDefaults(sidecar *corev1.Container) {
+	if d.Defaults == nil {
+		return
+	}
+	defaults := d.Defaults.Get()
+
+	if defaults.Image != "" {
+		sidecar.Image = defaults.Image
+	}
+
+	keys := make([]string, 0, len(defaults.Env))
+	for key := range defaults.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if i := findEnv(sidecar.Env, key); i >= 0 {
+			sidecar.Env[i].Value = defaults.Env[key]
+			continue
+		}
+		sidecar.Env = append(sidecar.Env, corev1.EnvVar{Name: key, Value: defaults.Env[key]})
+	}
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeDefaults lays out a directory the way the kubelet mounts a ConfigMap.
+func writeDefaults(t *testing.T, dir string, data map[string]string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "..data"), 0o755))
+	for key, value := range data {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, key), []byte(value), 0o644))
+	}
+}
+
+func TestLoadSidecarDefaults(t *testing.T) {
+	dir := t.TempDir()
+	writeDefaults(t, dir, map[string]string{
+		"image":          "registry.example.com/contextforge-proxy:0.2.0\n",
+		"LOG_LEVEL":      "debug",
+		"RATE_LIMIT_RPS": "100",
+		"HEADER_RULES":   `[{"name":"x-request-id"}]`,
+		"log-format":     "json",
+		"TARGET_HOST":    "localhost:1",
+		"WRITE_TIMEOUT":  "30s",
+	})
+
+	defaults, err := loadSidecarDefaults(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "registry.example.com/contextforge-proxy:0.2.0", defaults.Image)
+	assert.Equal(t, map[string]string{
+		"LOG_LEVEL":      "debug",
+		"RATE_LIMIT_RPS": "100",
+		"WRITE_TIMEOUT":  "30s",
+	}, defaults.Env)
+
+	defaults, err = loadSidecarDefaults(filepath.Join(dir, "missing"))
+	require.NoError(t, err)
+	assert.Empty(t, defaults.Image)
+	assert.Empty(t, defaults.Env)
+}
+
+func TestSidecarDefaultsSource_Refresh(t *testing.T) {
+	dir := t.TempDir()
+	writeDefaults(t, dir, map[string]string{"LOG_LEVEL": "debug"})
+
+	source := &SidecarDefaultsSource{Dir: dir, Refresh: time.Hour}
+	assert.Equal(t, "debug", source.Get().Env["LOG_LEVEL"])
+
+	// Cached until the refresh interval passes
+	writeDefaults(t, dir, map[string]string{"LOG_LEVEL": "warn"})
+	assert.Equal(t, "debug", source.Get().Env["LOG_LEVEL"])
+
+	source.Refresh = 0
+	assert.Equal(t, "warn", source.Get().Env["LOG_LEVEL"])
+}
+
+func TestPodCustomDefaulter_SidecarDefaults(t *testing.T) {
+	dir := t.TempDir()
+	writeDefaults(t, dir, map[string]string{
+		"image":          "registry.example.com/contextforge-proxy:0.2.0",
+		"LOG_LEVEL":      "debug",
+		"RATE_LIMIT_RPS": "100",
+	})
+	defaulter := &PodCustomDefaulter{
+		ProxyImage: DefaultProxyImage,
+		Defaults:   &SidecarDefaultsSource{Dir: dir},
+	}
+
+	pod := orderingPod("")
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+
+	sidecar := proxyContainer(pod)
+	require.NotNil(t, sidecar)
+	assert.Equal(t, "registry.example.com/contextforge-proxy:0.2.0", sidecar.Image)
+	assert.Equal(t, "debug", sidecarEnv(t, pod, "LOG_LEVEL"))
+	assert.Equal(t, "100", sidecarEnv(t, pod, "RATE_LIMIT_RPS"))
+	assert.Equal(t, "x-request-id", sidecarEnv(t, pod, "HEADERS_TO_PROPAGATE"))
+
+	count := 0
+	for _, env := range sidecar.Env {
+		if env.Name == "LOG_LEVEL" {
+			count++
+		}
+	}
+	assert.Equal(t, 1, count)
+}
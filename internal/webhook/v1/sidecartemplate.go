@@ -0,0 +1,159 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"sigs.k8s.io/yaml"
+)
+
+// sidecarDefaultsTemplateKey holds the sidecar template in the defaults ConfigMap
+const sidecarDefaultsTemplateKey = "template"
+
+// DefaultSidecarTemplate renders the built-in sidecar unchanged. Custom
+// templates typically start from it and add fields below the container.
+const DefaultSidecarTemplate = `container:
+{{ toYaml .Sidecar | indent 2 }}
+`
+
+// sidecarTemplateData is passed to sidecar templates.
+type sidecarTemplateData struct {
+	// Pod is the pod being injected, before the sidecar is added.
+	Pod *corev1.Pod
+	// Sidecar is the built-in proxy container.
+	Sidecar   *corev1.Container
+	ProxyPort int
+	AdminPort int
+}
+
+// sidecarTemplateOutput is what a sidecar template renders.
+type sidecarTemplateOutput struct {
+	// Container is strategic-merged onto the built-in proxy container, so env,
+	// ports and volumeMounts merge by name and other fields are replaced.
+	Container json.RawMessage `json:"container,omitempty"`
+	// Volumes are added to the pod unless it has a volume of the same name.
+	Volumes []corev1.Volume `json:"volumes,omitempty"`
+}
+
+var sidecarTemplateFuncs = template.FuncMap{
+	"toYaml": func(v any) (string, error) {
+		out, err := yaml.Marshal(v)
+		return strings.TrimSuffix(string(out), "\n"), err
+	},
+	"indent": func(spaces int, s string) string {
+		pad := strings.Repeat(" ", spaces)
+		return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+	},
+	"quote": func(s string) string {
+		return fmt.Sprintf("%q", s)
+	},
+	"default": func(fallback, value string) string {
+		if value == "" {
+			return fallback
+		}
+		return value
+	},
+	"annotation": func(pod *corev1.Pod, key string) string {
+		return pod.Annotations[key]
+	},
+}
+
+// parseSidecarTemplate parses a sidecar template from the defaults ConfigMap.
+func parseSidecarTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New(sidecarDefaultsTemplateKey).
+		Funcs(sidecarTemplateFuncs).
+		Option("missingkey=error").
+		Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sidecar template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// renderSidecarTemplate renders tmpl for the pod and merges the result onto
+// the built-in sidecar. The sidecar is left unchanged on error.
+func renderSidecarTemplate(tmpl *template.Template, pod *corev1.Pod, sidecar *corev1.Container) ([]corev1.Volume, error) {
+	var buf bytes.Buffer
+	data := sidecarTemplateData{Pod: pod, Sidecar: sidecar, ProxyPort: ProxyPort, AdminPort: ProxyAdminPort}
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to render sidecar template: %w", err)
+	}
+
+	var out sidecarTemplateOutput
+	if err := yaml.UnmarshalStrict(buf.Bytes(), &out); err != nil {
+		return nil, fmt.Errorf("sidecar template rendered invalid YAML: %w", err)
+	}
+	if len(out.Container) == 0 {
+		return out.Volumes, nil
+	}
+
+	original, err := json.Marshal(sidecar)
+	if err != nil {
+		return nil, err
+	}
+	merged, err := strategicpatch.StrategicMergePatch(original, out.Container, corev1.Container{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge sidecar template: %w", err)
+	}
+	var result corev1.Container
+	if err := json.Unmarshal(merged, &result); err != nil {
+		return nil, fmt.Errorf("sidecar template produced an invalid container: %w", err)
+	}
+	if result.Image == "" {
+		return nil, fmt.Errorf("sidecar template removed the proxy image")
+	}
+
+	// The webhook and drift detection find the sidecar by name
+	result.Name = ProxyContainerName
+	*sidecar = result
+	return out.Volumes, nil
+}
+
+// applySidecarTemplate customizes the sidecar with the operator's template, if
+// any. A broken template is logged and the built-in sidecar is injected.
+func (d *PodCustomDefaulter) applySidecarTemplate(pod *corev1.Pod, sidecar *corev1.Container) {
+	if d.Defaults == nil {
+		return
+	}
+	tmpl := d.Defaults.Get().Template
+	if tmpl == nil {
+		return
+	}
+
+	volumes, err := renderSidecarTemplate(tmpl, pod, sidecar)
+	if err != nil {
+		podLogger(pod).Error(err, "Injecting the built-in sidecar instead")
+		return
+	}
+
+	existing := make(map[string]bool, len(pod.Spec.Volumes))
+	for _, volume := range pod.Spec.Volumes {
+		existing[volume.Name] = true
+	}
+	for _, volume := range volumes {
+		if !existing[volume.Name] {
+			pod.Spec.Volumes = append(pod.Spec.Volumes, volume)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// templatedPod injects orderingPod with the given sidecar template configured.
+func templatedPod(t *testing.T, text string) *corev1.Pod {
+	t.Helper()
+	dir := t.TempDir()
+	writeDefaults(t, dir, map[string]string{sidecarDefaultsTemplateKey: text})
+	defaulter := &PodCustomDefaulter{
+		ProxyImage: DefaultProxyImage,
+		Defaults:   &SidecarDefaultsSource{Dir: dir},
+	}
+
+	pod := orderingPod("")
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+	require.NotNil(t, proxyContainer(pod))
+	return pod
+}
+
+func TestSidecarTemplate_DefaultIsBuiltIn(t *testing.T) {
+	builtIn := orderingPod("")
+	defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage}
+	require.NoError(t, defaulter.Default(context.Background(), builtIn))
+
+	pod := templatedPod(t, DefaultSidecarTemplate)
+	assert.Equal(t, builtIn.Spec, pod.Spec)
+}
+
+func TestSidecarTemplate_Customizes(t *testing.T) {
+	pod := templatedPod(t, `
+container:
+  args: ["--log-format", "json"]
+  env:
+    - name: LOG_LEVEL
+      value: {{ annotation .Pod "example.com/log-level" | default "warn" | quote }}
+    - name: OWNER
+      value: {{ .Pod.Name }}
+  volumeMounts:
+    - name: proxy-tmp
+      mountPath: /tmp
+  readinessProbe:
+    httpGet:
+      path: /healthz
+      port: {{ .ProxyPort }}
+volumes:
+  - name: proxy-tmp
+    emptyDir: {}
+`)
+
+	sidecar := proxyContainer(pod)
+	assert.Equal(t, ProxyContainerName, sidecar.Name)
+	assert.Equal(t, DefaultProxyImage, sidecar.Image)
+	assert.Equal(t, []string{"--log-format", "json"}, sidecar.Args)
+	assert.Equal(t, "warn", sidecarEnv(t, pod, "LOG_LEVEL"))
+	assert.Equal(t, "api", sidecarEnv(t, pod, "OWNER"))
+	// Built-in env vars are kept
+	assert.Equal(t, "x-request-id", sidecarEnv(t, pod, "HEADERS_TO_PROPAGATE"))
+	assert.Equal(t, "/healthz", sidecar.ReadinessProbe.HTTPGet.Path)
+	require.Len(t, sidecar.VolumeMounts, 1)
+	require.Len(t, pod.Spec.Volumes, 1)
+	assert.Equal(t, "proxy-tmp", pod.Spec.Volumes[0].Name)
+}
+
+func TestSidecarTemplate_ErrorsFallBackToBuiltIn(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{name: "unknown field", text: "container:\n  image: x\nvolume: []\n"},
+		{name: "missing key", text: "container:\n  image: {{ .Image }}\n"},
+		{name: "removes image", text: "container:\n  image: null\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := templatedPod(t, tt.text)
+			assert.Equal(t, DefaultProxyImage, proxyContainer(pod).Image)
+			assert.Empty(t, pod.Spec.Volumes)
+		})
+	}
+}
+
+func TestLoadSidecarDefaults_InvalidTemplate(t *testing.T) {
+	dir := t.TempDir()
+	writeDefaults(t, dir, map[string]string{sidecarDefaultsTemplateKey: "container: {{ .Sidecar"})
+
+	_, err := loadSidecarDefaults(dir)
+	assert.ErrorContains(t, err, "invalid sidecar template")
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+)
+
+// AnnotationSPIFFE enables ("true") or disables ("false") the SPIFFE ID
+// header for a pod, overriding the operator default.
+const AnnotationSPIFFE = "ctxforge.io/spiffe"
+
+const (
+	// spiffeVolume is the SPIFFE CSI driver volume holding the Workload API socket
+	spiffeVolume = "ctxforge-spiffe-workload-api"
+	// SPIFFECSIDriver is the SPIFFE CSI driver, which mounts the node's SPIRE
+	// agent socket into pods
+	SPIFFECSIDriver = "csi.spiffe.io"
+	// SPIFFESocketDir is where the Workload API socket is mounted in the sidecar
+	SPIFFESocketDir = "/spiffe-workload-api"
+	// SPIFFEEndpointSocket is the Workload API address given to the sidecar
+	SPIFFEEndpointSocket = "unix://" + SPIFFESocketDir + "/spire-agent.sock"
+)
+
+// wantsSPIFFE reports whether the pod's proxy sends its SPIFFE ID.
+func (d *PodCustomDefaulter) wantsSPIFFE(pod *corev1.Pod) bool {
+	if value, ok := pod.Annotations[AnnotationSPIFFE]; ok {
+		return value == AnnotationValueTrue
+	}
+	return d.SPIFFE
+}
+
+// addSPIFFE mounts the SPIRE agent's Workload API socket into the sidecar
+// through the SPIFFE CSI driver, so the proxy can fetch its SVID. Only the
+// sidecar mounts it: the pod's SPIFFE ID is the proxy's to vouch for. A
+// socket address set through the sidecar defaults, e.g. for a hostPath
+// mount, is kept.
+func (d *PodCustomDefaulter) addSPIFFE(pod *corev1.Pod, sidecar *corev1.Container) {
+	if !d.wantsSPIFFE(pod) || findEnv(sidecar.Env, "SPIFFE_ENDPOINT_SOCKET") >= 0 {
+		return
+	}
+	pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+		Name: spiffeVolume,
+		VolumeSource: corev1.VolumeSource{
+			CSI: &corev1.CSIVolumeSource{Driver: SPIFFECSIDriver, ReadOnly: ptr.To(true)},
+		},
+	})
+	sidecar.VolumeMounts = append(sidecar.VolumeMounts, corev1.VolumeMount{
+		Name:      spiffeVolume,
+		MountPath: SPIFFESocketDir,
+		ReadOnly:  true,
+	})
+	sidecar.Env = append(sidecar.Env, corev1.EnvVar{Name: "SPIFFE_ENDPOINT_SOCKET", Value: SPIFFEEndpointSocket})
+	if d.SPIFFEIDHeader != "" && findEnv(sidecar.Env, "SPIFFE_ID_HEADER") < 0 {
+		sidecar.Env = append(sidecar.Env, corev1.EnvVar{Name: "SPIFFE_ID_HEADER", Value: d.SPIFFEIDHeader})
+	}
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestPodCustomDefaulter_SPIFFE(t *testing.T) {
+	tests := []struct {
+		name         string
+		operator     bool
+		annotation   string
+		expectSocket bool
+	}{
+		{name: "disabled by default"},
+		{name: "operator default", operator: true, expectSocket: true},
+		{name: "pod enables", annotation: "true", expectSocket: true},
+		{name: "pod disables", operator: true, annotation: "false"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defaulter := &PodCustomDefaulter{
+				ProxyImage:     DefaultProxyImage,
+				SPIFFE:         tt.operator,
+				SPIFFEIDHeader: "X-Caller-Spiffe-Id",
+			}
+			pod := orderingPod("")
+			if tt.annotation != "" {
+				pod.Annotations[AnnotationSPIFFE] = tt.annotation
+			}
+
+			require.NoError(t, defaulter.Default(context.Background(), pod))
+
+			sidecar := proxyContainer(pod)
+			require.NotNil(t, sidecar)
+			if !tt.expectSocket {
+				assert.Negative(t, findEnv(sidecar.Env, "SPIFFE_ENDPOINT_SOCKET"))
+				assert.Empty(t, pod.Spec.Volumes)
+				return
+			}
+
+			assert.Equal(t, SPIFFEEndpointSocket, sidecarEnv(t, pod, "SPIFFE_ENDPOINT_SOCKET"))
+			assert.Equal(t, "X-Caller-Spiffe-Id", sidecarEnv(t, pod, "SPIFFE_ID_HEADER"))
+			assert.Contains(t, sidecar.VolumeMounts,
+				corev1.VolumeMount{Name: spiffeVolume, MountPath: SPIFFESocketDir, ReadOnly: true})
+			require.Len(t, pod.Spec.Volumes, 1)
+			require.NotNil(t, pod.Spec.Volumes[0].CSI)
+			assert.Equal(t, SPIFFECSIDriver, pod.Spec.Volumes[0].CSI.Driver)
+			for _, app := range pod.Spec.Containers[:2] {
+				assert.Empty(t, app.VolumeMounts, "only the proxy gets the socket")
+			}
+		})
+	}
+}
+
+func TestPodCustomDefaulter_SPIFFE_SocketFromDefaults(t *testing.T) {
+	dir := t.TempDir()
+	writeDefaults(t, dir, map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///run/spire/sockets/agent.sock"})
+	defaulter := &PodCustomDefaulter{
+		ProxyImage: DefaultProxyImage,
+		SPIFFE:     true,
+		Defaults:   &SidecarDefaultsSource{Dir: dir},
+	}
+	pod := orderingPod("")
+
+	require.NoError(t, defaulter.Default(context.Background(), pod))
+
+	assert.Equal(t, "unix:///run/spire/sockets/agent.sock", sidecarEnv(t, pod, "SPIFFE_ENDPOINT_SOCKET"))
+	assert.Empty(t, pod.Spec.Volumes, "the CSI volume is left out for a socket mounted otherwise")
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// targetPortName is the containerPort name preferred when detecting the target port
+const targetPortName = "http"
+
+// Values of the operator's TARGET_PORT_VALIDATION env var.
+const (
+	// TargetPortValidationWarn admits pods with an undeclared target port with a warning
+	TargetPortValidationWarn = "warn"
+	// TargetPortValidationDeny rejects pods with an undeclared target port
+	TargetPortValidationDeny = "deny"
+)
+
+// appContainerPorts returns the TCP ports declared by the pod's app
+// containers, leaving out the proxy, mesh sidecars and skipped containers.
+func appContainerPorts(pod *corev1.Pod) []corev1.ContainerPort {
+	skip := make(map[string]bool)
+	for _, name := range splitAnnotationList(pod.Annotations[AnnotationSkipContainers]) {
+		skip[name] = true
+	}
+
+	reserved := meshPorts(pod)
+	var ports []corev1.ContainerPort
+	for _, container := range pod.Spec.Containers {
+		if container.Name == ProxyContainerName || skip[container.Name] || meshProxyContainers[container.Name] != "" {
+			continue
+		}
+		for _, port := range container.Ports {
+			if port.Protocol != "" && port.Protocol != corev1.ProtocolTCP {
+				continue
+			}
+			if port.ContainerPort == ProxyPort || reserved[int(port.ContainerPort)] != "" {
+				continue
+			}
+			ports = append(ports, port)
+		}
+	}
+	return ports
+}
+
+// detectTargetPort derives the app's port from its containerPorts when
+// ctxforge.io/target-port is not set: the port named "http", or else the only
+// declared port. It returns false when neither identifies a single port.
+func detectTargetPort(pod *corev1.Pod) (string, bool) {
+	ports := appContainerPorts(pod)
+	for _, port := range ports {
+		if port.Name == targetPortName {
+			return strconv.Itoa(int(port.ContainerPort)), true
+		}
+	}
+	if len(ports) == 1 {
+		return strconv.Itoa(int(ports[0].ContainerPort)), true
+	}
+	return "", false
+}
+
+// resolveTargetPort returns the port the proxy forwards to: the
+// ctxforge.io/target-port annotation, the detected port, or DefaultTargetPort.
+func resolveTargetPort(pod *corev1.Pod) string {
+	if port := pod.Annotations[AnnotationTargetPort]; port != "" {
+		if err := validateTargetPort(port); err != nil {
+			podLogger(pod).Error(err, "Invalid target port annotation, using default",
+				"port", port, "default", DefaultTargetPort)
+			return DefaultTargetPort
+		}
+		return port
+	}
+
+	if port, ok := detectTargetPort(pod); ok {
+		podLogger(pod).Info("Detected target port from container ports", "port", port)
+		return port
+	}
+	if len(appContainerPorts(pod)) > 1 {
+		podLogger(pod).Info("Several container ports and none named http, using default target port; set "+
+			AnnotationTargetPort+" to choose one", "default", DefaultTargetPort)
+	}
+	return DefaultTargetPort
+}
+
+// undeclaredTargetPort describes why the pod's ctxforge.io/target-port doesn't
+// match its app containers' declared ports. It returns "" when the port is
+// declared, when no app container declares ports, or when the annotation is
+// unset or invalid (which the annotation checks report).
+func undeclaredTargetPort(pod *corev1.Pod) string {
+	port := pod.Annotations[AnnotationTargetPort]
+	if port == "" || validateTargetPort(port) != nil {
+		return ""
+	}
+	ports := appContainerPorts(pod)
+	if len(ports) == 0 {
+		return ""
+	}
+
+	declared := make([]string, 0, len(ports))
+	for _, p := range ports {
+		if strconv.Itoa(int(p.ContainerPort)) == port {
+			return ""
+		}
+		declared = append(declared, strconv.Itoa(int(p.ContainerPort)))
+	}
+	return fmt.Sprintf("port %s is not declared by any app container (declared: %s); "+
+		"the proxy's readiness probe will fail if the app doesn't listen on it", port, strings.Join(declared, ", "))
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestPodCustomDefaulter_DetectTargetPort(t *testing.T) {
+	tests := []struct {
+		name       string
+		annotation string
+		skip       string
+		app        []corev1.ContainerPort
+		worker     []corev1.ContainerPort
+		expected   string
+	}{
+		{
+			name:     "no ports",
+			expected: DefaultTargetPort,
+		},
+		{
+			name:     "single port",
+			app:      []corev1.ContainerPort{{ContainerPort: 3000}},
+			expected: "3000",
+		},
+		{
+			name:     "port named http",
+			app:      []corev1.ContainerPort{{Name: "grpc", ContainerPort: 9000}, {Name: "http", ContainerPort: 3000}},
+			worker:   []corev1.ContainerPort{{Name: "metrics", ContainerPort: 9102}},
+			expected: "3000",
+		},
+		{
+			name:     "ambiguous ports",
+			app:      []corev1.ContainerPort{{ContainerPort: 3000}},
+			worker:   []corev1.ContainerPort{{ContainerPort: 4000}},
+			expected: DefaultTargetPort,
+		},
+		{
+			name:     "UDP and mesh ports are ignored",
+			app:      []corev1.ContainerPort{{ContainerPort: 3000}, {ContainerPort: 53, Protocol: corev1.ProtocolUDP}},
+			worker:   []corev1.ContainerPort{{ContainerPort: 15090}},
+			expected: "3000",
+		},
+		{
+			name:     "skipped containers are ignored",
+			skip:     "worker",
+			app:      []corev1.ContainerPort{{ContainerPort: 3000}},
+			worker:   []corev1.ContainerPort{{ContainerPort: 4000}},
+			expected: "3000",
+		},
+		{
+			name:       "annotation wins",
+			annotation: "5000",
+			app:        []corev1.ContainerPort{{ContainerPort: 3000}},
+			expected:   "5000",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := orderingPod("")
+			if tt.annotation != "" {
+				pod.Annotations[AnnotationTargetPort] = tt.annotation
+			}
+			if tt.skip != "" {
+				pod.Annotations[AnnotationSkipContainers] = tt.skip
+			}
+			pod.Spec.Containers[0].Ports = tt.app
+			pod.Spec.Containers[1].Ports = tt.worker
+
+			defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage}
+			require.NoError(t, defaulter.Default(context.Background(), pod))
+			assert.Equal(t, "localhost:"+tt.expected, sidecarEnv(t, pod, "TARGET_HOST"))
+		})
+	}
+}
+
+func TestPodCustomValidator_UndeclaredTargetPort(t *testing.T) {
+	tests := []struct {
+		name          string
+		targetPort    string
+		ports         []corev1.ContainerPort
+		deny          bool
+		expectWarning bool
+		expectErr     bool
+	}{
+		{
+			name:       "declared port",
+			targetPort: "3000",
+			ports:      []corev1.ContainerPort{{ContainerPort: 3000}},
+		},
+		{
+			name:       "no declared ports",
+			targetPort: "3000",
+		},
+		{
+			name:          "undeclared port warns",
+			targetPort:    "3000",
+			ports:         []corev1.ContainerPort{{ContainerPort: 8080}},
+			expectWarning: true,
+		},
+		{
+			name:       "undeclared port denied",
+			targetPort: "3000",
+			ports:      []corev1.ContainerPort{{ContainerPort: 8080}},
+			deny:       true,
+			expectErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := orderingPod("")
+			pod.Annotations[AnnotationTargetPort] = tt.targetPort
+			pod.Spec.Containers[0].Ports = tt.ports
+			validator := &PodCustomValidator{DenyUndeclaredTargetPort: tt.deny}
+
+			warnings, err := validator.ValidateCreate(context.Background(), pod)
+			if tt.expectErr {
+				assert.ErrorContains(t, err, "port 3000 is not declared by any app container (declared: 8080)")
+				return
+			}
+			require.NoError(t, err)
+			if tt.expectWarning {
+				require.Len(t, warnings, 1)
+				assert.Contains(t, warnings[0], "ctxforge.io/target-port: port 3000 is not declared")
+			} else {
+				assert.Empty(t, warnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// AnnotationTargetTLS makes the proxy forward to the app over HTTPS ("true"/"false")
+	AnnotationTargetTLS = "ctxforge.io/target-tls"
+	// AnnotationTargetCA names the CA bundle the proxy trusts for the app's
+	// certificate, as configmap/<name> or secret/<name> holding a ca.crt key.
+	AnnotationTargetCA = "ctxforge.io/target-ca"
+
+	// targetCAKey is the key holding the PEM bundle, as in kube-root-ca.crt
+	// and cert-manager Secrets.
+	targetCAKey = "ca.crt"
+	// targetCAVolume is the name of the volume mounted into the proxy
+	targetCAVolume = "ctxforge-target-ca"
+	// targetCAMountPath is where the CA bundle is mounted in the proxy container
+	targetCAMountPath = "/etc/ctxforge/target-ca"
+
+	targetCAConfigMap = "configmap"
+	targetCASecret    = "secret"
+)
+
+// parseTargetCA splits a configmap/<name> or secret/<name> reference.
+func parseTargetCA(ref string) (kind, name string, err error) {
+	kind, name, ok := strings.Cut(strings.TrimSpace(ref), "/")
+	if !ok || name == "" || strings.Contains(name, "/") ||
+		(kind != targetCAConfigMap && kind != targetCASecret) {
+		return "", "", fmt.Errorf("invalid CA reference %q: must be configmap/<name> or secret/<name>", ref)
+	}
+	return kind, name, nil
+}
+
+// addTargetTLS configures the sidecar to reach the app over HTTPS when the pod
+// sets ctxforge.io/target-tls, mounting the CA bundle from the pod annotation
+// or the operator default. Without a CA only the system roots are trusted.
+func (d *PodCustomDefaulter) addTargetTLS(pod *corev1.Pod, sidecar *corev1.Container) {
+	if pod.Annotations[AnnotationTargetTLS] != AnnotationValueTrue {
+		return
+	}
+	sidecar.Env = append(sidecar.Env, corev1.EnvVar{Name: "TARGET_TLS", Value: AnnotationValueTrue})
+
+	ref := d.TargetCA
+	if value, ok := pod.Annotations[AnnotationTargetCA]; ok {
+		ref = value
+	}
+	if ref == "" {
+		return
+	}
+	kind, name, err := parseTargetCA(ref)
+	if err != nil {
+		podLogger(pod).Error(err, "Ignoring target CA, trusting system roots only")
+		return
+	}
+
+	volume := corev1.Volume{Name: targetCAVolume}
+	items := []corev1.KeyToPath{{Key: targetCAKey, Path: targetCAKey}}
+	if kind == targetCASecret {
+		volume.Secret = &corev1.SecretVolumeSource{SecretName: name, Items: items}
+	} else {
+		volume.ConfigMap = &corev1.ConfigMapVolumeSource{
+			LocalObjectReference: corev1.LocalObjectReference{Name: name},
+			Items:                items,
+		}
+	}
+	pod.Spec.Volumes = append(pod.Spec.Volumes, volume)
+
+	sidecar.VolumeMounts = append(sidecar.VolumeMounts, corev1.VolumeMount{
+		Name:      targetCAVolume,
+		MountPath: targetCAMountPath,
+		ReadOnly:  true,
+	})
+	sidecar.Env = append(sidecar.Env, corev1.EnvVar{
+		Name:  "TARGET_CA_FILE",
+		Value: targetCAMountPath + "/" + targetCAKey,
+	})
+}
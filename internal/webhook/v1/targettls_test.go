@@ -0,0 +1,95 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestPodCustomDefaulter_TargetTLS(t *testing.T) {
+	tests := []struct {
+		name        string
+		operatorCA  string
+		annotations map[string]string
+		check       func(t *testing.T, pod *corev1.Pod)
+	}{
+		{
+			name: "disabled by default",
+			check: func(t *testing.T, pod *corev1.Pod) {
+				assert.Equal(t, -1, findEnv(proxyContainer(pod).Env, "TARGET_TLS"))
+				assert.Empty(t, pod.Spec.Volumes)
+			},
+		},
+		{
+			name:        "system roots only",
+			annotations: map[string]string{AnnotationTargetTLS: "true"},
+			check: func(t *testing.T, pod *corev1.Pod) {
+				assert.Equal(t, "true", sidecarEnv(t, pod, "TARGET_TLS"))
+				assert.Equal(t, -1, findEnv(proxyContainer(pod).Env, "TARGET_CA_FILE"))
+				assert.Empty(t, pod.Spec.Volumes)
+			},
+		},
+		{
+			name:        "operator default CA",
+			operatorCA:  "configmap/kube-root-ca.crt",
+			annotations: map[string]string{AnnotationTargetTLS: "true"},
+			check: func(t *testing.T, pod *corev1.Pod) {
+				require.Len(t, pod.Spec.Volumes, 1)
+				require.NotNil(t, pod.Spec.Volumes[0].ConfigMap)
+				assert.Equal(t, "kube-root-ca.crt", pod.Spec.Volumes[0].ConfigMap.Name)
+				assert.Equal(t, "/etc/ctxforge/target-ca/ca.crt", sidecarEnv(t, pod, "TARGET_CA_FILE"))
+
+				mounts := proxyContainer(pod).VolumeMounts
+				require.Len(t, mounts, 1)
+				assert.Equal(t, targetCAVolume, mounts[0].Name)
+				assert.True(t, mounts[0].ReadOnly)
+			},
+		},
+		{
+			name:       "pod CA overrides the operator default",
+			operatorCA: "configmap/kube-root-ca.crt",
+			annotations: map[string]string{
+				AnnotationTargetTLS: "true",
+				AnnotationTargetCA:  "secret/api-tls",
+			},
+			check: func(t *testing.T, pod *corev1.Pod) {
+				require.Len(t, pod.Spec.Volumes, 1)
+				require.NotNil(t, pod.Spec.Volumes[0].Secret)
+				assert.Equal(t, "api-tls", pod.Spec.Volumes[0].Secret.SecretName)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defaulter := &PodCustomDefaulter{ProxyImage: DefaultProxyImage, TargetCA: tt.operatorCA}
+			pod := orderingPod("")
+			for key, value := range tt.annotations {
+				pod.Annotations[key] = value
+			}
+
+			require.NoError(t, defaulter.Default(context.Background(), pod))
+			require.NotNil(t, proxyContainer(pod))
+			tt.check(t, pod)
+		})
+	}
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// AnnotationRestartedAt is set on a workload's pod template by the operator to
+// roll it out, the way kubectl rollout restart does with its own annotation.
+const AnnotationRestartedAt = "ctxforge.io/restartedAt"
+
+// setupWorkloadWebhooksWithManager registers the workload webhooks in the manager.
+func setupWorkloadWebhooksWithManager(mgr ctrl.Manager, defaulter *WorkloadCustomDefaulter) error {
+	for _, obj := range []client.Object{&appsv1.Deployment{}, &appsv1.StatefulSet{}, &appsv1.DaemonSet{}} {
+		if err := ctrl.NewWebhookManagedBy(mgr).For(obj).WithDefaulter(defaulter).Complete(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// +kubebuilder:webhook:path=/mutate-apps-v1-deployment,mutating=true,failurePolicy=ignore,sideEffects=None,groups=apps,resources=deployments,verbs=create;update,versions=v1,name=mdeployment-v1.kb.io,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/mutate-apps-v1-statefulset,mutating=true,failurePolicy=ignore,sideEffects=None,groups=apps,resources=statefulsets,verbs=create;update,versions=v1,name=mstatefulset-v1.kb.io,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/mutate-apps-v1-daemonset,mutating=true,failurePolicy=ignore,sideEffects=None,groups=apps,resources=daemonsets,verbs=create;update,versions=v1,name=mdaemonset-v1.kb.io,admissionReviewVersions=v1
+
+// WorkloadCustomDefaulter injects the sidecar into the pod template of
+// Deployments, StatefulSets and DaemonSets, so the injected spec is visible in
+// the workload object instead of only on its pods. Pods created from an
+// injected template are recognised as already injected by the pod webhook.
+type WorkloadCustomDefaulter struct {
+	// Pods performs the actual injection on the pod template.
+	Pods *PodCustomDefaulter
+	// Enabled turns template injection on. When false the webhook admits
+	// workloads unchanged and injection happens at pod creation only.
+	Enabled bool
+}
+
+var _ webhook.CustomDefaulter = &WorkloadCustomDefaulter{}
+
+// Default implements webhook.CustomDefaulter to inject the sidecar into the pod template
+func (d *WorkloadCustomDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	if !d.Enabled {
+		return nil
+	}
+	defer observeAdmission(webhookWorkload, time.Now())
+
+	meta, template, err := podTemplateOf(obj)
+	if err != nil {
+		injectionErrorsTotal.Inc()
+		return err
+	}
+
+	// Run pod injection on a pod built from the template. Unlike pods, templates
+	// are mutable, so create and update are handled alike.
+	pod := &corev1.Pod{
+		ObjectMeta: *template.ObjectMeta.DeepCopy(),
+		Spec:       *template.Spec.DeepCopy(),
+	}
+	pod.Name = meta.Name
+	pod.Namespace = meta.Namespace
+	if err := d.Pods.inject(ctx, pod); err != nil {
+		return err
+	}
+
+	template.Labels = pod.Labels
+	template.Annotations = pod.Annotations
+	template.Spec = pod.Spec
+	return nil
+}
+
+// podTemplateOf returns the metadata and pod template of a supported workload.
+func podTemplateOf(obj runtime.Object) (*metav1.ObjectMeta, *corev1.PodTemplateSpec, error) {
+	switch w := obj.(type) {
+	case *appsv1.Deployment:
+		return &w.ObjectMeta, &w.Spec.Template, nil
+	case *appsv1.StatefulSet:
+		return &w.ObjectMeta, &w.Spec.Template, nil
+	case *appsv1.DaemonSet:
+		return &w.ObjectMeta, &w.Spec.Template, nil
+	default:
+		return nil, nil, fmt.Errorf("expected a Deployment, StatefulSet or DaemonSet but got %T", obj)
+	}
+}
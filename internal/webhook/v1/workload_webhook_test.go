@@ -0,0 +1,131 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func enabledTemplate() corev1.PodTemplateSpec {
+	return corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				AnnotationEnabled: "true",
+				AnnotationHeaders: "x-request-id",
+			},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+	}
+}
+
+func TestWorkloadCustomDefaulter_InjectsTemplate(t *testing.T) {
+	defaulter := &WorkloadCustomDefaulter{
+		Pods:    &PodCustomDefaulter{ProxyImage: DefaultProxyImage},
+		Enabled: true,
+	}
+
+	tests := []struct {
+		name string
+		obj  runtime.Object
+	}{
+		{
+			name: "deployment",
+			obj:  &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Template: enabledTemplate()}},
+		},
+		{
+			name: "statefulset",
+			obj:  &appsv1.StatefulSet{Spec: appsv1.StatefulSetSpec{Template: enabledTemplate()}},
+		},
+		{
+			name: "daemonset",
+			obj:  &appsv1.DaemonSet{Spec: appsv1.DaemonSetSpec{Template: enabledTemplate()}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, defaulter.Default(context.Background(), tt.obj))
+
+			_, template, err := podTemplateOf(tt.obj)
+			require.NoError(t, err)
+			require.Len(t, template.Spec.Containers, 2)
+			assert.Equal(t, ProxyContainerName, template.Spec.Containers[1].Name)
+			assert.Equal(t, "true", template.Annotations[AnnotationInjected])
+
+			// Pods created from the template are left alone by the pod webhook
+			pod := &corev1.Pod{ObjectMeta: *template.ObjectMeta.DeepCopy(), Spec: *template.Spec.DeepCopy()}
+			require.NoError(t, defaulter.Pods.Default(context.Background(), pod))
+			assert.Len(t, pod.Spec.Containers, 2)
+
+			// Re-admitting the workload is idempotent
+			require.NoError(t, defaulter.Default(context.Background(), tt.obj))
+			assert.Len(t, template.Spec.Containers, 2)
+		})
+	}
+}
+
+func TestWorkloadCustomDefaulter_Disabled(t *testing.T) {
+	defaulter := &WorkloadCustomDefaulter{Pods: &PodCustomDefaulter{ProxyImage: DefaultProxyImage}}
+
+	deployment := &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Template: enabledTemplate()}}
+	require.NoError(t, defaulter.Default(context.Background(), deployment))
+	assert.Len(t, deployment.Spec.Template.Spec.Containers, 1)
+}
+
+func TestWorkloadCustomDefaulter_NotEnabledTemplate(t *testing.T) {
+	defaulter := &WorkloadCustomDefaulter{
+		Pods:    &PodCustomDefaulter{ProxyImage: DefaultProxyImage},
+		Enabled: true,
+	}
+
+	deployment := &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+	}}}
+	require.NoError(t, defaulter.Default(context.Background(), deployment))
+	assert.Len(t, deployment.Spec.Template.Spec.Containers, 1)
+	assert.Nil(t, deployment.Spec.Template.Annotations)
+}
+
+func TestWorkloadCustomDefaulter_UpdatesStaleTemplate(t *testing.T) {
+	defaulter := &WorkloadCustomDefaulter{
+		Pods:    &PodCustomDefaulter{ProxyImage: DefaultProxyImage},
+		Enabled: true,
+	}
+
+	deployment := &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Template: enabledTemplate()}}
+	require.NoError(t, defaulter.Default(context.Background(), deployment))
+
+	deployment.Spec.Template.Annotations[AnnotationHeaders] = "x-request-id,x-tenant-id"
+	require.NoError(t, defaulter.Default(updateContext(), deployment))
+
+	pod := &corev1.Pod{Spec: deployment.Spec.Template.Spec}
+	assert.Equal(t, "x-request-id,x-tenant-id", sidecarEnv(t, pod, "HEADERS_TO_PROPAGATE"),
+		"templates are mutable, so updates patch the sidecar instead of only reporting drift")
+}
+
+func TestWorkloadCustomDefaulter_UnsupportedType(t *testing.T) {
+	defaulter := &WorkloadCustomDefaulter{Pods: &PodCustomDefaulter{}, Enabled: true}
+	assert.Error(t, defaulter.Default(context.Background(), &corev1.Service{}))
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// +kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,resourceNames=headerpropagationpolicies.ctxforge.ctxforge.io;clusterheaderpropagationpolicies.ctxforge.ctxforge.io,verbs=patch
+
+const (
+	// ConversionPath is where the webhook server serves CRD conversion
+	ConversionPath = "/convert"
+
+	defaultConversionSyncInterval = 5 * time.Minute
+)
+
+// PolicyCRDs are the CRDs whose versions the conversion webhook converts between
+var PolicyCRDs = []string{
+	"headerpropagationpolicies.ctxforge.ctxforge.io",
+	"clusterheaderpropagationpolicies.ctxforge.ctxforge.io",
+}
+
+var crdGVK = schema.GroupVersionKind{Group: "apiextensions.k8s.io", Version: "v1", Kind: "CustomResourceDefinition"}
+
+// CRDConversionManager points the policy CRDs at the operator's conversion
+// webhook. Helm installs CRDs unrendered, so they can't name the release's
+// Service; the operator fills it in at runtime instead, together with the CA
+// bundle when the certificate directory has one. It re-applies the
+// configuration every Interval, which also picks up a rotated CA.
+//
+// It implements manager.Runnable and runs on the leader only.
+type CRDConversionManager struct {
+	Client client.Client
+	// Service is the Service in front of the webhook server, listening on port 443.
+	Service types.NamespacedName
+	// CAFile is the PEM bundle the API server verifies the webhook with. When
+	// it doesn't exist the CRDs' caBundle is left to cert-manager or the admin.
+	CAFile string
+	// Interval is how often the configuration is re-applied. Defaults to five minutes.
+	Interval time.Duration
+}
+
+// Start applies the configuration immediately and then every Interval until ctx is done.
+func (m *CRDConversionManager) Start(ctx context.Context) error {
+	interval := m.Interval
+	if interval <= 0 {
+		interval = defaultConversionSyncInterval
+	}
+
+	m.sync(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			m.sync(ctx)
+		}
+	}
+}
+
+// NeedLeaderElection reports that only the leader writes the CRDs.
+func (m *CRDConversionManager) NeedLeaderElection() bool {
+	return true
+}
+
+// sync patches the conversion configuration of every policy CRD, logging
+// failures for the next attempt.
+func (m *CRDConversionManager) sync(ctx context.Context) {
+	log := logf.FromContext(ctx).WithName("crd-conversion")
+
+	patch, err := m.conversionPatch()
+	if err != nil {
+		log.Error(err, "Failed to build the CRD conversion configuration")
+		return
+	}
+	for _, name := range PolicyCRDs {
+		crd := &unstructured.Unstructured{}
+		crd.SetGroupVersionKind(crdGVK)
+		crd.SetName(name)
+		if err := m.Client.Patch(ctx, crd, client.RawPatch(types.MergePatchType, patch)); err != nil {
+			log.Error(err, "Failed to update the CRD conversion webhook", "crd", name)
+		}
+	}
+}
+
+// conversionPatch returns the merge patch setting the CRDs' conversion to the
+// webhook. The caBundle is only set when CAFile exists.
+func (m *CRDConversionManager) conversionPatch() ([]byte, error) {
+	clientConfig := map[string]any{
+		"service": map[string]any{
+			"namespace": m.Service.Namespace,
+			"name":      m.Service.Name,
+			"path":      ConversionPath,
+			"port":      443,
+		},
+	}
+	if m.CAFile != "" {
+		ca, err := os.ReadFile(m.CAFile)
+		switch {
+		case err == nil:
+			// encoding/json base64-encodes the bytes the way caBundle expects
+			clientConfig["caBundle"] = ca
+		case !errors.Is(err, fs.ErrNotExist):
+			return nil, err
+		}
+	}
+	return json.Marshal(map[string]any{
+		"spec": map[string]any{
+			"conversion": map[string]any{
+				"strategy": "Webhook",
+				"webhook": map[string]any{
+					"clientConfig":             clientConfig,
+					"conversionReviewVersions": []string{"v1"},
+				},
+			},
+		},
+	})
+}
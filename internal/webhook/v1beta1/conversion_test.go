@@ -0,0 +1,102 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func policyCRD(name string) *unstructured.Unstructured {
+	crd := &unstructured.Unstructured{Object: map[string]any{
+		"spec": map[string]any{"conversion": map[string]any{"strategy": "None"}},
+	}}
+	crd.SetGroupVersionKind(crdGVK)
+	crd.SetName(name)
+	return crd
+}
+
+func conversionOf(t *testing.T, c client.Client, name string) map[string]any {
+	t.Helper()
+	crd := &unstructured.Unstructured{}
+	crd.SetGroupVersionKind(crdGVK)
+	require.NoError(t, c.Get(context.Background(), types.NamespacedName{Name: name}, crd))
+	conversion, _, err := unstructured.NestedMap(crd.Object, "spec", "conversion")
+	require.NoError(t, err)
+	return conversion
+}
+
+func TestCRDConversionManager_Sync(t *testing.T) {
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	require.NoError(t, os.WriteFile(caFile, []byte("test-ca"), 0o600))
+	c := fake.NewClientBuilder().WithScheme(runtime.NewScheme()).
+		WithObjects(policyCRD(PolicyCRDs[0]), policyCRD(PolicyCRDs[1])).Build()
+
+	m := &CRDConversionManager{
+		Client:  c,
+		Service: types.NamespacedName{Namespace: "contextforge-system", Name: "contextforge-webhook"},
+		CAFile:  caFile,
+	}
+	m.sync(context.Background())
+
+	for _, name := range PolicyCRDs {
+		assert.Equal(t, map[string]any{
+			"strategy": "Webhook",
+			"webhook": map[string]any{
+				"clientConfig": map[string]any{
+					"service": map[string]any{
+						"namespace": "contextforge-system",
+						"name":      "contextforge-webhook",
+						"path":      "/convert",
+						"port":      int64(443),
+					},
+					"caBundle": "dGVzdC1jYQ==",
+				},
+				"conversionReviewVersions": []any{"v1"},
+			},
+		}, conversionOf(t, c, name), name)
+	}
+}
+
+func TestCRDConversionManager_KeepsCABundleWithoutCAFile(t *testing.T) {
+	crd := policyCRD(PolicyCRDs[0])
+	require.NoError(t, unstructured.SetNestedField(crd.Object, "aW5qZWN0ZWQ=",
+		"spec", "conversion", "webhook", "clientConfig", "caBundle"))
+	c := fake.NewClientBuilder().WithScheme(runtime.NewScheme()).WithObjects(crd).Build()
+
+	m := &CRDConversionManager{
+		Client:  c,
+		Service: types.NamespacedName{Namespace: "contextforge-system", Name: "contextforge-webhook"},
+		CAFile:  filepath.Join(t.TempDir(), "ca.crt"),
+	}
+	m.sync(context.Background())
+
+	conversion := conversionOf(t, c, PolicyCRDs[0])
+	assert.Equal(t, "Webhook", conversion["strategy"])
+	caBundle, _, _ := unstructured.NestedString(conversion, "webhook", "clientConfig", "caBundle")
+	assert.Equal(t, "aW5qZWN0ZWQ=", caBundle)
+}
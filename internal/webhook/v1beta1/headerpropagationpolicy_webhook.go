@@ -0,0 +1,356 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+	"github.com/bgruszka/contextforge/internal/config"
+	ctxforgepolicy "github.com/bgruszka/contextforge/internal/policy"
+)
+
+// allMethods are the HTTP methods the proxy matches rules against, in the
+// order a rule without methods is defaulted to.
+var allMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace,
+}
+
+const (
+	// MaxPropagationRules is the number of propagation rules a policy may have
+	MaxPropagationRules = 50
+	// MaxHeadersPerRule is the number of headers a propagation rule may list
+	MaxHeadersPerRule = 50
+)
+
+// SetupPolicyWebhookWithManager registers the webhooks for
+// HeaderPropagationPolicy and ClusterHeaderPropagationPolicy in the manager.
+func SetupPolicyWebhookWithManager(mgr ctrl.Manager) error {
+	allowedImages, err := ctxforgepolicy.ParseImageAllowlist(os.Getenv("PROXY_IMAGE_ALLOWLIST"))
+	if err != nil {
+		return fmt.Errorf("invalid PROXY_IMAGE_ALLOWLIST: %w", err)
+	}
+	defaults, err := ctxforgepolicy.DefaultsFromEnv(mgr.GetClient())
+	if err != nil {
+		return err
+	}
+	defaulter := &PolicyCustomDefaulter{Defaults: defaults}
+	validator := &PolicyCustomValidator{AllowedImages: allowedImages}
+	if err := ctrl.NewWebhookManagedBy(mgr).For(&ctxforgev1beta1.HeaderPropagationPolicy{}).
+		WithDefaulter(defaulter).
+		WithValidator(validator).
+		Complete(); err != nil {
+		return err
+	}
+	return ctrl.NewWebhookManagedBy(mgr).For(&ctxforgev1beta1.ClusterHeaderPropagationPolicy{}).
+		WithDefaulter(defaulter).
+		WithValidator(validator).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-ctxforge-ctxforge-io-v1beta1-headerpropagationpolicy,mutating=true,failurePolicy=ignore,sideEffects=None,groups=ctxforge.ctxforge.io,resources=headerpropagationpolicies,verbs=create;update,versions=v1beta1,name=mheaderpropagationpolicy-v1beta1.kb.io,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/mutate-ctxforge-ctxforge-io-v1beta1-clusterheaderpropagationpolicy,mutating=true,failurePolicy=ignore,sideEffects=None,groups=ctxforge.ctxforge.io,resources=clusterheaderpropagationpolicies,verbs=create;update,versions=v1beta1,name=mclusterheaderpropagationpolicy-v1beta1.kb.io,admissionReviewVersions=v1
+
+// PolicyCustomDefaulter fills in the defaults the proxy would otherwise
+// assume, so a stored policy shows the configuration it results in:
+// generated headers get the uuid generator, header names and renames are
+// lowercased, propagate is set to true and rules without methods list every
+// method.
+type PolicyCustomDefaulter struct {
+	// Defaults supplies the generator of generated headers, in place of uuid.
+	// The other policy defaults apply at injection and are not written out.
+	Defaults *ctxforgepolicy.DefaultsSource
+}
+
+var _ webhook.CustomDefaulter = &PolicyCustomDefaulter{}
+
+// Default implements webhook.CustomDefaulter for both policy kinds
+func (d *PolicyCustomDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	spec, _, _, err := specOf(obj)
+	if err != nil {
+		return err
+	}
+	generator := d.Defaults.Get(ctx).GeneratorType
+	if generator == "" {
+		generator = ctxforgepolicy.DefaultGeneratorType
+	}
+	defaultSpec(spec, generator)
+	return nil
+}
+
+// specOf returns the spec of either policy kind with the kind and name that
+// identify it in validation errors.
+func specOf(obj runtime.Object) (*ctxforgev1beta1.HeaderPropagationPolicySpec, schema.GroupKind, string, error) {
+	switch p := obj.(type) {
+	case *ctxforgev1beta1.HeaderPropagationPolicy:
+		return &p.Spec, ctxforgev1beta1.GroupVersion.WithKind("HeaderPropagationPolicy").GroupKind(), p.Name, nil
+	case *ctxforgev1beta1.ClusterHeaderPropagationPolicy:
+		return &p.Spec, ctxforgev1beta1.GroupVersion.WithKind("ClusterHeaderPropagationPolicy").GroupKind(), p.Name, nil
+	default:
+		return nil, schema.GroupKind{}, "", fmt.Errorf(
+			"expected a HeaderPropagationPolicy or ClusterHeaderPropagationPolicy but got %T", obj)
+	}
+}
+
+// defaultSpec applies the defaults to every rule and header of spec, with
+// generator for generated headers.
+func defaultSpec(spec *ctxforgev1beta1.HeaderPropagationPolicySpec, generator string) {
+	for i := range spec.PropagationRules {
+		rule := &spec.PropagationRules[i]
+		if len(rule.Methods) == 0 {
+			rule.Methods = append([]string(nil), allMethods...)
+		}
+		for j := range rule.Headers {
+			header := &rule.Headers[j]
+			header.Name = strings.ToLower(header.Name)
+			header.Rename = strings.ToLower(header.Rename)
+			if header.Generate && header.GeneratorType == "" {
+				header.GeneratorType = generator
+			}
+			if header.Propagate == nil {
+				propagate := true
+				header.Propagate = &propagate
+			}
+			if header.OnExisting == "" {
+				header.OnExisting = string(config.OnExistingPreserve)
+			}
+		}
+	}
+	for i := range spec.ResponseRules {
+		rule := &spec.ResponseRules[i]
+		if len(rule.Methods) == 0 {
+			rule.Methods = append([]string(nil), allMethods...)
+		}
+		for j := range rule.Echo {
+			rule.Echo[j] = strings.ToLower(rule.Echo[j])
+		}
+		for j := range rule.Strip {
+			rule.Strip[j] = strings.ToLower(rule.Strip[j])
+		}
+	}
+}
+
+// +kubebuilder:webhook:path=/validate-ctxforge-ctxforge-io-v1beta1-headerpropagationpolicy,mutating=false,failurePolicy=fail,sideEffects=None,groups=ctxforge.ctxforge.io,resources=headerpropagationpolicies,verbs=create;update,versions=v1beta1,name=vheaderpropagationpolicy-v1beta1.kb.io,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/validate-ctxforge-ctxforge-io-v1beta1-clusterheaderpropagationpolicy,mutating=false,failurePolicy=fail,sideEffects=None,groups=ctxforge.ctxforge.io,resources=clusterheaderpropagationpolicies,verbs=create;update,versions=v1beta1,name=vclusterheaderpropagationpolicy-v1beta1.kb.io,admissionReviewVersions=v1
+
+// PolicyCustomValidator rejects policies the proxy can't apply as written,
+// reporting the offending field of each problem.
+type PolicyCustomValidator struct {
+	// AllowedImages are the proxy images a policy may pin with
+	// sidecar.image. When empty, policies can't set it.
+	AllowedImages ctxforgepolicy.ImageAllowlist
+}
+
+var _ webhook.CustomValidator = &PolicyCustomValidator{}
+
+// ValidateCreate validates policy creation
+func (v *PolicyCustomValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	spec, groupKind, name, err := specOf(obj)
+	if err != nil {
+		return nil, err
+	}
+	if errs := v.validateSpec(spec, field.NewPath("spec")); len(errs) > 0 {
+		return nil, apierrors.NewInvalid(groupKind, name, errs)
+	}
+	return credentialWarnings(spec, field.NewPath("spec")), nil
+}
+
+// ValidateUpdate validates policy updates. The spec is only checked when it
+// changed, so policies admitted before the webhook existed can still have
+// their metadata updated, e.g. to remove finalizers.
+func (v *PolicyCustomValidator) ValidateUpdate(_ context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	spec, groupKind, name, err := specOf(newObj)
+	if err != nil {
+		return nil, err
+	}
+	oldSpec, _, _, err := specOf(oldObj)
+	if err != nil {
+		return nil, err
+	}
+	if equality.Semantic.DeepEqual(oldSpec, spec) {
+		return nil, nil
+	}
+	if errs := v.validateSpec(spec, field.NewPath("spec")); len(errs) > 0 {
+		return nil, apierrors.NewInvalid(groupKind, name, errs)
+	}
+	return credentialWarnings(spec, field.NewPath("spec")), nil
+}
+
+// ValidateDelete validates policy deletion
+func (v *PolicyCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validateSpec checks the propagation rules and sidecar settings of spec.
+func (v *PolicyCustomValidator) validateSpec(spec *ctxforgev1beta1.HeaderPropagationPolicySpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	rulesPath := fldPath.Child("propagationRules")
+	if len(spec.PropagationRules) > MaxPropagationRules {
+		allErrs = append(allErrs, field.TooMany(rulesPath, len(spec.PropagationRules), MaxPropagationRules))
+	}
+	for i, rule := range spec.PropagationRules {
+		allErrs = append(allErrs, validateRule(rule, rulesPath.Index(i))...)
+	}
+	for i, rule := range spec.ResponseRules {
+		allErrs = append(allErrs, validateResponseRule(rule, fldPath.Child("responseRules").Index(i))...)
+	}
+	if spec.Sampling != nil && (spec.Sampling.Percentage < 0 || spec.Sampling.Percentage > 100) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("sampling", "percentage"),
+			spec.Sampling.Percentage, "must be between 0 and 100"))
+	}
+	if spec.Sidecar != nil && spec.Sidecar.Resources != nil {
+		allErrs = append(allErrs, validateResources(spec.Sidecar.Resources, fldPath.Child("sidecar", "resources"))...)
+	}
+	if spec.Sidecar != nil && spec.Sidecar.Image != "" && !v.AllowedImages.Allows(spec.Sidecar.Image) {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("sidecar", "image"),
+			fmt.Sprintf("%s is not in the operator's proxy image allowlist", spec.Sidecar.Image)))
+	}
+	return allErrs
+}
+
+// credentialWarnings warns about headers of spec that sidecars refuse to
+// propagate, as config.CredentialHeaders, unless UNSAFE_PROPAGATE_CREDENTIALS
+// is set on them. The policy is still admitted so that the override stays
+// possible.
+func credentialWarnings(spec *ctxforgev1beta1.HeaderPropagationPolicySpec, fldPath *field.Path) admission.Warnings {
+	var warnings admission.Warnings
+	for i, rule := range spec.PropagationRules {
+		for j, header := range rule.Headers {
+			headerPath := fldPath.Child("propagationRules").Index(i).Child("headers").Index(j)
+			for _, f := range []struct{ name, value string }{{"name", header.Name}, {"rename", header.Rename}} {
+				if config.IsCredentialHeader(f.value) {
+					warnings = append(warnings, fmt.Sprintf("%s: %s is a credential header; sidecars don't propagate it "+
+						"unless UNSAFE_PROPAGATE_CREDENTIALS is set", headerPath.Child(f.name), http.CanonicalHeaderKey(f.value)))
+				}
+			}
+		}
+	}
+	return warnings
+}
+
+// validateResources checks the sidecar's resources, whose requests can't
+// exceed their limits or every pod the policy selects would be rejected.
+func validateResources(resources *corev1.ResourceRequirements, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	names := make([]string, 0, len(resources.Requests))
+	for name := range resources.Requests {
+		names = append(names, string(name))
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		request := resources.Requests[corev1.ResourceName(name)]
+		limit, ok := resources.Limits[corev1.ResourceName(name)]
+		if ok && request.Cmp(limit) > 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("requests").Key(name), request.String(),
+				fmt.Sprintf("must be less than or equal to the %s limit", name)))
+		}
+	}
+	return allErrs
+}
+
+// validateMatch checks the path and method filters of a rule.
+func validateMatch(pathRegex string, methods []string, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if pathRegex != "" {
+		if _, err := regexp.Compile(pathRegex); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("pathRegex"), pathRegex, err.Error()))
+		}
+	}
+	for i, method := range methods {
+		if !slices.Contains(allMethods, strings.ToUpper(method)) {
+			allErrs = append(allErrs, field.NotSupported(fldPath.Child("methods").Index(i), method, allMethods))
+		}
+	}
+	return allErrs
+}
+
+// validateResponseRule checks a response rule, which can't both echo and
+// strip a header.
+func validateResponseRule(rule ctxforgev1beta1.ResponseRule, fldPath *field.Path) field.ErrorList {
+	allErrs := validateMatch(rule.PathRegex, rule.Methods, fldPath)
+	if len(rule.Echo) == 0 && len(rule.Strip) == 0 {
+		allErrs = append(allErrs, field.Required(fldPath, "must echo or strip at least one header"))
+	}
+	echoed := make(map[string]bool, len(rule.Echo))
+	for _, name := range rule.Echo {
+		echoed[http.CanonicalHeaderKey(name)] = true
+	}
+	for i, name := range rule.Strip {
+		if echoed[http.CanonicalHeaderKey(name)] {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("strip").Index(i), name, "is also echoed"))
+		}
+	}
+	return allErrs
+}
+
+// validateRule checks a propagation rule and its headers.
+func validateRule(rule ctxforgev1beta1.PropagationRule, fldPath *field.Path) field.ErrorList {
+	allErrs := validateMatch(rule.PathRegex, rule.Methods, fldPath)
+	for i, exclude := range rule.ExcludePaths {
+		if _, err := regexp.Compile(exclude); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("excludePaths").Index(i), exclude, err.Error()))
+		}
+	}
+
+	headersPath := fldPath.Child("headers")
+	if len(rule.Headers) > MaxHeadersPerRule {
+		allErrs = append(allErrs, field.TooMany(headersPath, len(rule.Headers), MaxHeadersPerRule))
+	}
+	seen := make(map[string]bool, len(rule.Headers))
+	for i, header := range rule.Headers {
+		key := http.CanonicalHeaderKey(header.Name)
+		if seen[key] {
+			allErrs = append(allErrs, field.Duplicate(headersPath.Index(i).Child("name"), header.Name))
+		}
+		seen[key] = true
+		if header.Generate && header.GeneratorType == "" {
+			allErrs = append(allErrs, field.Required(headersPath.Index(i).Child("generatorType"),
+				"must be set when generate is true"))
+		}
+		if header.Generate && header.StaticValue != "" {
+			allErrs = append(allErrs, field.Invalid(headersPath.Index(i).Child("staticValue"),
+				header.StaticValue, "can't be combined with generate"))
+		}
+		if strings.ContainsAny(header.StaticValue, "\r\n\x00") {
+			allErrs = append(allErrs, field.Invalid(headersPath.Index(i).Child("staticValue"),
+				header.StaticValue, "must not contain CR, LF or NUL"))
+		}
+		if header.Transform != nil && header.Transform.Type == string(config.TransformTruncate) &&
+			header.Transform.Length == 0 {
+			allErrs = append(allErrs, field.Required(headersPath.Index(i).Child("transform", "length"),
+				"must be set for truncate"))
+		}
+	}
+	return allErrs
+}
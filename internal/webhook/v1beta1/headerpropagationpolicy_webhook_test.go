@@ -0,0 +1,280 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	ctxforgev1beta1 "github.com/bgruszka/contextforge/api/v1beta1"
+	ctxforgepolicy "github.com/bgruszka/contextforge/internal/policy"
+)
+
+func TestPolicyCustomDefaulter_Default(t *testing.T) {
+	policy := &ctxforgev1beta1.HeaderPropagationPolicy{
+		Spec: ctxforgev1beta1.HeaderPropagationPolicySpec{
+			PropagationRules: []ctxforgev1beta1.PropagationRule{
+				{Headers: []ctxforgev1beta1.HeaderConfig{
+					{Name: "X-Request-ID", Generate: true},
+					{Name: "x-trace-id", Generate: true, GeneratorType: "ulid", OnExisting: "override"},
+				}},
+				{
+					Headers: []ctxforgev1beta1.HeaderConfig{{Name: "X-Tenant-ID", Propagate: ptr.To(false)}},
+					Methods: []string{"POST"},
+				},
+			},
+			ResponseRules: []ctxforgev1beta1.ResponseRule{{Echo: []string{"X-Request-ID"}, Strip: []string{"Server"}}},
+		},
+	}
+
+	require.NoError(t, (&PolicyCustomDefaulter{}).Default(context.Background(), policy))
+
+	assert.Equal(t, []ctxforgev1beta1.PropagationRule{
+		{
+			Headers: []ctxforgev1beta1.HeaderConfig{
+				{Name: "x-request-id", Generate: true, GeneratorType: "uuid", Propagate: ptr.To(true), OnExisting: "preserve"},
+				{Name: "x-trace-id", Generate: true, GeneratorType: "ulid", Propagate: ptr.To(true), OnExisting: "override"},
+			},
+			Methods: []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "CONNECT", "OPTIONS", "TRACE"},
+		},
+		{
+			Headers: []ctxforgev1beta1.HeaderConfig{{Name: "x-tenant-id", Propagate: ptr.To(false), OnExisting: "preserve"}},
+			Methods: []string{"POST"},
+		},
+	}, policy.Spec.PropagationRules)
+	assert.Equal(t, []ctxforgev1beta1.ResponseRule{{
+		Echo:    []string{"x-request-id"},
+		Strip:   []string{"server"},
+		Methods: []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "CONNECT", "OPTIONS", "TRACE"},
+	}}, policy.Spec.ResponseRules)
+}
+
+func TestPolicyCustomDefaulter_ClusterPolicy(t *testing.T) {
+	policy := &ctxforgev1beta1.ClusterHeaderPropagationPolicy{
+		Spec: ctxforgev1beta1.HeaderPropagationPolicySpec{
+			PropagationRules: []ctxforgev1beta1.PropagationRule{
+				{Headers: []ctxforgev1beta1.HeaderConfig{{Name: "X-Request-ID", Generate: true}}},
+			},
+		},
+	}
+
+	require.NoError(t, (&PolicyCustomDefaulter{}).Default(context.Background(), policy))
+
+	header := policy.Spec.PropagationRules[0].Headers[0]
+	assert.Equal(t, "x-request-id", header.Name)
+	assert.Equal(t, "uuid", header.GeneratorType)
+}
+
+func TestPolicyCustomDefaulter_DefaultsGeneratorType(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	defaults := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: ctxforgepolicy.DefaultsConfigMapName, Namespace: "contextforge-system"},
+		Data:       map[string]string{ctxforgepolicy.DefaultsGeneratorTypeKey: "ulid"},
+	}
+	defaulter := &PolicyCustomDefaulter{Defaults: &ctxforgepolicy.DefaultsSource{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(defaults).Build(),
+		Key:    client.ObjectKeyFromObject(defaults),
+	}}
+	policy := &ctxforgev1beta1.HeaderPropagationPolicy{
+		Spec: ctxforgev1beta1.HeaderPropagationPolicySpec{
+			PropagationRules: []ctxforgev1beta1.PropagationRule{
+				{Headers: []ctxforgev1beta1.HeaderConfig{
+					{Name: "x-request-id", Generate: true},
+					{Name: "x-trace-id", Generate: true, GeneratorType: "timestamp"},
+				}},
+			},
+		},
+	}
+
+	require.NoError(t, defaulter.Default(context.Background(), policy))
+
+	headers := policy.Spec.PropagationRules[0].Headers
+	assert.Equal(t, "ulid", headers[0].GeneratorType)
+	assert.Equal(t, "timestamp", headers[1].GeneratorType)
+}
+
+func TestPolicyCustomDefaulter_RejectsOtherObjects(t *testing.T) {
+	assert.Error(t, (&PolicyCustomDefaulter{}).Default(context.Background(), &corev1.Pod{}))
+}
+
+func validPolicy() *ctxforgev1beta1.HeaderPropagationPolicy {
+	return &ctxforgev1beta1.HeaderPropagationPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "tracing", Namespace: "default"},
+		Spec: ctxforgev1beta1.HeaderPropagationPolicySpec{
+			PropagationRules: []ctxforgev1beta1.PropagationRule{{
+				Headers:   []ctxforgev1beta1.HeaderConfig{{Name: "x-request-id", Generate: true, GeneratorType: "uuid"}},
+				PathRegex: "^/api/.*",
+				Methods:   []string{"GET", "post"},
+			}},
+		},
+	}
+}
+
+// invalidFields returns the field paths of the validation error's causes.
+func invalidFields(t *testing.T, err error) []string {
+	t.Helper()
+	require.Error(t, err)
+	statusErr, ok := err.(*apierrors.StatusError)
+	require.True(t, ok, "expected a StatusError but got %T", err)
+	var fields []string
+	for _, cause := range statusErr.ErrStatus.Details.Causes {
+		fields = append(fields, cause.Field)
+	}
+	return fields
+}
+
+func TestPolicyCustomValidator_ValidateCreate(t *testing.T) {
+	validator := &PolicyCustomValidator{}
+
+	_, err := validator.ValidateCreate(context.Background(), validPolicy())
+	assert.NoError(t, err)
+
+	policy := validPolicy()
+	policy.Spec.PropagationRules = append(policy.Spec.PropagationRules, ctxforgev1beta1.PropagationRule{
+		Headers: []ctxforgev1beta1.HeaderConfig{
+			{Name: "x-tenant-id"},
+			{Name: "X-Tenant-ID"},
+			{Name: "x-request-id", Generate: true},
+			{Name: "x-session-id", Generate: true, GeneratorType: "uuid", StaticValue: "none"},
+			{Name: "x-user-id", Transform: &ctxforgev1beta1.HeaderTransform{Type: "truncate"}},
+		},
+		PathRegex:    "^/api/(",
+		ExcludePaths: []string{"^/api/health$", "^/api/(internal"},
+		Methods:      []string{"GET", "FETCH"},
+	})
+	policy.Spec.ResponseRules = []ctxforgev1beta1.ResponseRule{
+		{Echo: []string{"x-request-id"}, Strip: []string{"server"}},
+		{PathRegex: "^/api/(", Methods: []string{"FETCH"}},
+		{Echo: []string{"x-request-id"}, Strip: []string{"X-Request-ID"}},
+	}
+	policy.Spec.Sampling = &ctxforgev1beta1.Sampling{Percentage: 150}
+	policy.Spec.Sidecar = &ctxforgev1beta1.SidecarConfig{Resources: &corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("1"),
+			corev1.ResourceMemory: resource.MustParse("64Mi"),
+		},
+		Limits: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("500m"),
+			corev1.ResourceMemory: resource.MustParse("128Mi"),
+		},
+	}}
+	_, err = validator.ValidateCreate(context.Background(), policy)
+	assert.Equal(t, []string{
+		"spec.propagationRules[1].pathRegex",
+		"spec.propagationRules[1].methods[1]",
+		"spec.propagationRules[1].excludePaths[1]",
+		"spec.propagationRules[1].headers[1].name",
+		"spec.propagationRules[1].headers[2].generatorType",
+		"spec.propagationRules[1].headers[3].staticValue",
+		"spec.propagationRules[1].headers[4].transform.length",
+		"spec.responseRules[1].pathRegex",
+		"spec.responseRules[1].methods[0]",
+		"spec.responseRules[1]",
+		"spec.responseRules[2].strip[0]",
+		"spec.sampling.percentage",
+		"spec.sidecar.resources.requests[cpu]",
+	}, invalidFields(t, err))
+	assert.True(t, apierrors.IsInvalid(err))
+}
+
+func TestPolicyCustomValidator_WarnsAboutCredentialHeaders(t *testing.T) {
+	policy := validPolicy()
+	policy.Spec.PropagationRules[0].Headers = append(policy.Spec.PropagationRules[0].Headers,
+		ctxforgev1beta1.HeaderConfig{Name: "authorization"},
+		ctxforgev1beta1.HeaderConfig{Name: "x-session", Rename: "cookie"},
+	)
+
+	warnings, err := (&PolicyCustomValidator{}).ValidateCreate(context.Background(), policy)
+	require.NoError(t, err, "the policy is admitted")
+	assert.Equal(t, admission.Warnings{
+		"spec.propagationRules[0].headers[1].name: Authorization is a credential header; " +
+			"sidecars don't propagate it unless UNSAFE_PROPAGATE_CREDENTIALS is set",
+		"spec.propagationRules[0].headers[2].rename: Cookie is a credential header; " +
+			"sidecars don't propagate it unless UNSAFE_PROPAGATE_CREDENTIALS is set",
+	}, warnings)
+
+	warnings, err = (&PolicyCustomValidator{}).ValidateCreate(context.Background(), validPolicy())
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+}
+
+func TestPolicyCustomValidator_SidecarImage(t *testing.T) {
+	policy := validPolicy()
+	policy.Spec.Sidecar = &ctxforgev1beta1.SidecarConfig{Image: "ghcr.io/bgruszka/contextforge-proxy:0.2.0-rc.1"}
+
+	_, err := (&PolicyCustomValidator{}).ValidateCreate(context.Background(), policy)
+	assert.Equal(t, []string{"spec.sidecar.image"}, invalidFields(t, err), "no image is allowed by default")
+
+	validator := &PolicyCustomValidator{AllowedImages: ctxforgepolicy.ImageAllowlist{"ghcr.io/bgruszka/contextforge-proxy"}}
+	_, err = validator.ValidateCreate(context.Background(), policy)
+	assert.NoError(t, err)
+
+	policy.Spec.Sidecar.Image = "docker.io/someone/proxy:latest"
+	_, err = validator.ValidateCreate(context.Background(), policy)
+	assert.Equal(t, []string{"spec.sidecar.image"}, invalidFields(t, err))
+}
+
+func TestPolicyCustomValidator_RuleCounts(t *testing.T) {
+	policy := &ctxforgev1beta1.ClusterHeaderPropagationPolicy{ObjectMeta: metav1.ObjectMeta{Name: "tracing"}}
+	headers := make([]ctxforgev1beta1.HeaderConfig, MaxHeadersPerRule+1)
+	for i := range headers {
+		headers[i].Name = fmt.Sprintf("x-header-%d", i)
+	}
+	policy.Spec.PropagationRules = make([]ctxforgev1beta1.PropagationRule, MaxPropagationRules+1)
+	for i := range policy.Spec.PropagationRules {
+		policy.Spec.PropagationRules[i].Headers = []ctxforgev1beta1.HeaderConfig{{Name: "x-request-id"}}
+	}
+	policy.Spec.PropagationRules[0].Headers = headers
+
+	_, err := (&PolicyCustomValidator{}).ValidateCreate(context.Background(), policy)
+	assert.Equal(t, []string{"spec.propagationRules", "spec.propagationRules[0].headers"}, invalidFields(t, err))
+}
+
+func TestPolicyCustomValidator_ValidateUpdate(t *testing.T) {
+	validator := &PolicyCustomValidator{}
+	stored := validPolicy()
+	stored.Spec.PropagationRules[0].PathRegex = "("
+
+	// Policies admitted before the webhook can still have their metadata updated
+	updated := stored.DeepCopy()
+	updated.Labels = map[string]string{"team": "payments"}
+	_, err := validator.ValidateUpdate(context.Background(), stored, updated)
+	assert.NoError(t, err)
+
+	updated.Spec.Priority = 10
+	_, err = validator.ValidateUpdate(context.Background(), stored, updated)
+	assert.Equal(t, []string{"spec.propagationRules[0].pathRegex"}, invalidFields(t, err))
+
+	updated.Spec.PropagationRules[0].PathRegex = "^/api/.*"
+	_, err = validator.ValidateUpdate(context.Background(), stored, updated)
+	assert.NoError(t, err)
+}
@@ -252,7 +252,7 @@ var _ = Describe("Path-Based Header Filtering", Ordered, func() {
 						// Only propagate for /api/* paths
 						PathRegex: "^/api/.*",
 						Headers: []ctxforgev1alpha1.HeaderConfig{
-							{Name: "x-api-key"},
+							{Name: "x-client-id"},
 							{Name: "x-request-id"},
 						},
 					},
@@ -296,12 +296,12 @@ var _ = Describe("Path-Based Header Filtering", Ordered, func() {
 	})
 
 	Context("when request path matches /api/*", func() {
-		It("should propagate x-api-key header", func() {
+		It("should propagate x-client-id header", func() {
 			serviceURL := fmt.Sprintf("http://%s:8080", serviceName)
 
 			cmd := exec.Command("kubectl", "exec", "-n", testNamespace, curlPodName, "--",
 				"curl", "-s",
-				"-H", "x-api-key: secret-api-key-123",
+				"-H", "x-client-id: client-123",
 				"-H", "x-request-id: req-123",
 				"-H", "x-tenant-id: tenant-abc",
 				serviceURL+"/api/v1/users",
@@ -318,20 +318,20 @@ var _ = Describe("Path-Based Header Filtering", Ordered, func() {
 			GinkgoWriter.Printf("Response for /api/v1/users: %s\n", body)
 
 			// API headers should be propagated for /api/* path
-			Expect(body).To(ContainSubstring("x-api-key"))
-			Expect(body).To(ContainSubstring("secret-api-key-123"))
+			Expect(body).To(ContainSubstring("x-client-id"))
+			Expect(body).To(ContainSubstring("client-123"))
 			Expect(body).To(ContainSubstring("x-request-id"))
 			Expect(body).To(ContainSubstring("x-tenant-id"))
 		})
 	})
 
 	Context("when request path is /health", func() {
-		It("should NOT propagate x-api-key header", func() {
+		It("should NOT propagate x-client-id header", func() {
 			serviceURL := fmt.Sprintf("http://%s:8080", serviceName)
 
 			cmd := exec.Command("kubectl", "exec", "-n", testNamespace, curlPodName, "--",
 				"curl", "-s",
-				"-H", "x-api-key: secret-api-key-456",
+				"-H", "x-client-id: client-456",
 				"-H", "x-tenant-id: tenant-xyz",
 				serviceURL+"/health",
 			)
@@ -350,7 +350,7 @@ var _ = Describe("Path-Based Header Filtering", Ordered, func() {
 			Expect(body).To(ContainSubstring("x-tenant-id"))
 			Expect(body).To(ContainSubstring("tenant-xyz"))
 
-			// Note: x-api-key may still appear in response because curl sends it directly
+			// Note: x-client-id may still appear in response because curl sends it directly
 			// The filtering applies to what the PROXY propagates to outgoing requests
 			// This test documents the expected behavior
 		})
@@ -658,7 +658,7 @@ func deployPathFilterService(ctx context.Context, name string) error {
 
 	// Path-based header rules
 	headerRules := []map[string]interface{}{
-		{"name": "x-api-key", "pathRegex": "^/api/.*"},
+		{"name": "x-client-id", "pathRegex": "^/api/.*"},
 		{"name": "x-request-id", "pathRegex": "^/api/.*"},
 		{"name": "x-tenant-id"}, // No path filter - applies to all
 	}
@@ -801,7 +801,7 @@ func deployHeaderRulesService(ctx context.Context, name string) error {
 	headerRules := []map[string]interface{}{
 		{"name": "x-request-id", "generate": true, "generatorType": "uuid"},
 		{"name": "x-tenant-id"},
-		{"name": "x-api-key", "pathRegex": "^/api/.*"},
+		{"name": "x-client-id", "pathRegex": "^/api/.*"},
 	}
 	headerRulesJSON, _ := json.Marshal(headerRules)
 